@@ -24,17 +24,20 @@ import { IntegrationManager, getIntegrationManager } from '@pkg/integrations/int
 import { PathManagementStrategy, PathManager } from '@pkg/integrations/pathManager';
 import { getPathManagerFor } from '@pkg/integrations/pathManagerImpl';
 import { BackendState, CommandWorkerInterface, HttpCommandServer } from '@pkg/main/commandServer/httpCommandServer';
+import { containersToRemove, resetContainerListFormat } from '@pkg/main/commandServer/resetOptions';
 import SettingsValidator from '@pkg/main/commandServer/settingsValidator';
 import { HttpCredentialHelperServer } from '@pkg/main/credentialServer/httpCredentialHelperServer';
 import { DashboardServer } from '@pkg/main/dashboardServer';
 import { DeploymentProfileError, readDeploymentProfiles } from '@pkg/main/deploymentProfiles';
 import { DiagnosticsManager, DiagnosticsResultCollection } from '@pkg/main/diagnostics/diagnostics';
 import { ExtensionErrorCode, isExtensionError } from '@pkg/main/extensions';
+import { getCatalogExtensions } from '@pkg/main/extensions/catalog';
 import { ImageEventHandler } from '@pkg/main/imageEvents';
 import { getIpcMainProxy } from '@pkg/main/ipcMain';
 import mainEvents from '@pkg/main/mainEvents';
 import buildApplicationMenu from '@pkg/main/mainmenu';
 import setupNetworking from '@pkg/main/networking';
+import { SnapshotScheduler } from '@pkg/main/snapshots/scheduler';
 import { Snapshots } from '@pkg/main/snapshots/snapshots';
 import { Snapshot, SnapshotDialog } from '@pkg/main/snapshots/types';
 import { Tray } from '@pkg/main/tray';
@@ -79,6 +82,7 @@ const ipcMainProxy = getIpcMainProxy(console);
 const dockerDirManager = new DockerDirManager(path.join(os.homedir(), '.docker'));
 const k8smanager = newK8sManager();
 const diagnostics: DiagnosticsManager = new DiagnosticsManager();
+const snapshotScheduler = new SnapshotScheduler();
 
 let cfg: settings.Settings;
 let firstRunDialogComplete = false;
@@ -604,6 +608,7 @@ Electron.app.on('before-quit', async(event) => {
     return;
   }
   event.preventDefault();
+  snapshotScheduler.stop();
   httpCommandServer?.closeServer();
   httpCredentialHelperServer.closeServer();
 
@@ -699,13 +704,21 @@ ipcMainProxy.on('get-debugging-statuses', () => {
   window.send('is-debugging', settingsImpl.runInDebugMode(cfg.application.debug));
   window.send('always-debugging', settingsImpl.runInDebugMode(false));
 });
-function writeSettings(arg: RecursivePartial<RecursiveReadonly<settings.Settings>>) {
+function writeSettings(arg: RecursivePartial<RecursiveReadonly<settings.Settings>>, source: 'api' | 'ui' | 'application' = 'application') {
+  const oldSettings = _.cloneDeep(cfg);
+
   settingsImpl.save(settingsImpl.merge(cfg, arg));
   mainEvents.emit('settings-update', cfg);
+
+  const changes = settingsImpl.settingsChanges(oldSettings, cfg);
+
+  if (changes.length > 0) {
+    mainEvents.emit('settings-changed', source, changes);
+  }
 }
 
 ipcMainProxy.handle('settings-write', (event, arg) => {
-  writeSettings(arg);
+  writeSettings(arg, 'ui');
 
   // dashboard requires kubernetes, so we want to close it if kubernetes is disabled
   if (arg?.kubernetes?.enabled === false) {
@@ -715,7 +728,7 @@ ipcMainProxy.handle('settings-write', (event, arg) => {
   event.sender.sendToFrame(event.frameId, 'settings-update', cfg);
 });
 
-mainEvents.on('settings-write', writeSettings);
+mainEvents.on('settings-write', arg => writeSettings(arg));
 
 mainEvents.on('extensions/ui/uninstall', (id) => {
   window.send('ok:extensions/uninstall', id);
@@ -799,6 +812,46 @@ async function doK8sReset(arg: 'fast' | 'wipe' | 'fullRestart', context: Command
   }
 }
 
+/**
+ * Remove the selected container engine data, and reset Kubernetes (keeping
+ * cached images) if requested.  Snapshots are never affected.
+ */
+async function doSelectiveReset(options: CommandWorkerInterface.ResetOptions, context: CommandWorkerInterface.CommandContext): Promise<void> {
+  const client = k8smanager.containerEngineClient;
+  const namespace = cfg.containerEngine.name === settings.ContainerEngine.CONTAINERD ? cfg.containers.namespace : undefined;
+
+  try {
+    if (options.images) {
+      // Kubernetes containers are kept unless Kubernetes is also being reset;
+      // as they're kept, so are the images they use.
+      const { stdout } = await client.runClient(['container', 'ls', '--all', '--format', resetContainerListFormat], 'pipe', { namespace });
+      const containers = containersToRemove(stdout, options);
+
+      if (containers.length > 0) {
+        console.log(`Reset: removing ${ containers.length } containers`);
+        await client.runClient(['container', 'rm', '--force', ...containers], console, { namespace });
+      }
+      console.log('Reset: removing container images');
+      await client.runClient(['image', 'prune', '--all', '--force'], console, { namespace });
+    }
+    if (options.volumes) {
+      console.log('Reset: removing container volumes');
+      await client.runClient(['volume', 'prune', '--all', '--force'], console, { namespace });
+    }
+  } catch (ex) {
+    if (context.interactive) {
+      handleFailure(ex);
+    } else {
+      console.error('Reset: failed to remove container data:', ex);
+    }
+
+    return;
+  }
+  if (options.kubernetes) {
+    await doK8sReset('fast', context);
+  }
+}
+
 ipcMainProxy.on('k8s-restart', async() => {
   if (cfg.kubernetes.port !== k8smanager.kubeBackend.desiredPort) {
     // On port change, we need to wipe the VM.
@@ -870,7 +923,7 @@ ipcMainProxy.on('k8s-integrations', async() => {
 });
 
 ipcMainProxy.on('k8s-integration-set', (event, name, newState) => {
-  writeSettings({ WSL: { integrations: { [name]: newState } } });
+  writeSettings({ WSL: { integrations: { [name]: newState } } }, 'ui');
 });
 
 mainEvents.on('integration-update', (state) => {
@@ -1374,6 +1427,18 @@ class BackgroundCommandWorker implements CommandWorkerInterface {
     doFactoryReset(keepSystemImages);
   }
 
+  async reset(context: CommandWorkerInterface.CommandContext, options: CommandWorkerInterface.ResetOptions) {
+    if (![K8s.State.STARTED, K8s.State.DISABLED].includes(k8smanager.state)) {
+      return `Can't reset while the backend is ${ k8smanager.state.toLowerCase() }`;
+    }
+    if (options.kubernetes && !cfg.kubernetes.enabled) {
+      return `Can't reset Kubernetes, as it is not enabled`;
+    }
+    setImmediate(() => {
+      doSelectiveReset(options, context);
+    });
+  }
+
   async forwardPort(namespace: string, service: string, k8sPort: string | number, hostPort: number) {
     return await doForwardPort(namespace, service, k8sPort, hostPort);
   }
@@ -1428,7 +1493,7 @@ class BackgroundCommandWorker implements CommandWorkerInterface {
       return ['', `errors in attempt to update settings:\n${ errors.join('\n') }`];
     }
     if (needToUpdate) {
-      writeSettings(newSettings);
+      writeSettings(newSettings, 'api');
       // cfg is a global, and at this point newConfig has been merged into it :(
       window.send('settings-update', cfg);
       window.send('preferences/changed');
@@ -1519,6 +1584,16 @@ class BackgroundCommandWorker implements CommandWorkerInterface {
     return Object.fromEntries(entries);
   }
 
+  listCatalogExtensions() {
+    return Promise.resolve(getCatalogExtensions(cfg));
+  }
+
+  async listExtensionVersions(image: string) {
+    const extensionManager = await getExtensionManager();
+
+    return await extensionManager?.getVersions(image);
+  }
+
   async installExtension(image: string, state: 'install' | 'uninstall'): Promise<{status: number, data?: any}> {
     const em = await getExtensionManager();
 
@@ -15,13 +15,13 @@ BOGUS_CONTAINERD_NAMESPACE=change-to-k8s.io
 @test 'complains when no output type is specified' {
     run rdctl create-profile --from-settings
     assert_failure
-    assert_output --partial 'an "--output FORMAT" option of either "plist" or "reg" must be specified'
+    assert_output --partial 'an "--output FORMAT" option of either "plist", "reg" or "json" must be specified'
 }
 
 @test 'complains when an invalid output type is specified' {
     run rdctl create-profile --from-settings --output=cabbage
     assert_failure
-    assert_output --partial 'received unrecognized "--output FORMAT" option of "cabbage"; "plist" or "reg" must be specified'
+    assert_output --partial 'received unrecognized "--output FORMAT" option of "cabbage"; "plist", "reg" or "json" must be specified'
 }
 
 @test 'complains when no input source is specified' {
@@ -76,7 +76,7 @@ too_many_input_formats() {
 @test 'report unrecognized output-options' {
     run rdctl create-profile --output=pickle
     assert_failure
-    assert_output --partial 'received unrecognized "--output FORMAT" option of "pickle"; "plist" or "reg" must be specified'
+    assert_output --partial 'received unrecognized "--output FORMAT" option of "pickle"; "plist", "reg" or "json" must be specified'
 }
 
 @test 'report unrecognized registry type sub-option' {
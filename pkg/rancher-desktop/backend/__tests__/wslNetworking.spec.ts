@@ -0,0 +1,31 @@
+import { getNetworkingMode } from '../wslNetworking';
+
+import type { VMExecutor } from '@pkg/backend/backend';
+
+describe(getNetworkingMode, () => {
+  function executor(result: Promise<string>): Pick<VMExecutor, 'execCommand'> {
+    return { execCommand: jest.fn(() => result) } as any;
+  }
+
+  it('runs wslinfo', async() => {
+    const exec = executor(Promise.resolve('nat\n'));
+
+    await getNetworkingMode(exec);
+    expect(exec.execCommand).toHaveBeenCalledWith({ capture: true }, 'wslinfo', '-n', '--networking-mode');
+  });
+
+  it.each([
+    ['nat', 'nat'],
+    ['mirrored\n', 'mirrored'],
+    ['virtioproxy\n', 'virtioproxy'],
+    ['', 'nat'],
+  ])('reports %j as %j', async(output, expected) => {
+    await expect(getNetworkingMode(executor(Promise.resolve(output)))).resolves.toEqual(expected);
+  });
+
+  it('falls back to NAT mode when wslinfo is missing', async() => {
+    const exec = executor(Promise.reject(new Error('wslinfo: not found')));
+
+    await expect(getNetworkingMode(exec)).resolves.toEqual('nat');
+  });
+});
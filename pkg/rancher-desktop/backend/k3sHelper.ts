@@ -934,50 +934,108 @@ export default class K3sHelper extends events.EventEmitter {
     throw new Error(`Could not find a kubeconfig`);
   }
 
+  /**
+   * Take the lock for the given kubeconfig file.  This uses the same lock file
+   * as kubectl, so that we don't race with `kubectl config` commands; lock
+   * files left behind by processes that exited without unlocking are removed.
+   * @param configPath The kubeconfig file to lock.
+   * @returns A function that releases the lock.
+   */
+  protected static async lockKubeconfig(configPath: string): Promise<() => Promise<void>> {
+    const lockPath = `${ configPath }.lock`;
+    const deadline = Date.now() + 10_000;
+    const staleAge = 60_000;
+
+    while (true) {
+      try {
+        await (await fs.promises.open(lockPath, 'wx', 0o600)).close();
+
+        return () => fs.promises.rm(lockPath, { force: true });
+      } catch (ex) {
+        if ((ex as NodeJS.ErrnoException).code !== 'EEXIST') {
+          throw ex;
+        }
+      }
+      try {
+        const { mtimeMs } = await fs.promises.stat(lockPath);
+
+        if (Date.now() - mtimeMs > staleAge) {
+          console.log(`Removing stale kubeconfig lock ${ lockPath }`);
+          await fs.promises.rm(lockPath, { force: true });
+          continue;
+        }
+      } catch {
+        // The lock was released while we looked at it; try again.
+        continue;
+      }
+      if (Date.now() > deadline) {
+        throw new Error(`Timed out waiting for kubeconfig lock ${ lockPath }`);
+      }
+      await util.promisify(setTimeout)(100);
+    }
+  }
+
   /**
    * Update the user's kubeconfig such that the K3s context is available and
    * set as the current context.  This assumes that K3s is already running.
    *
+   * The file is updated while holding the kubectl lock, and is replaced
+   * atomically so that concurrent kubectl or helm runs never see a partially
+   * written file.  If only the server address has changed (because the VM
+   * address or port changed), only the server address is updated.
+   *
    * @param configReader A function that returns the kubeconfig from the K3s VM.
    */
   async updateKubeconfig(configReader: () => Promise<string>): Promise<void> {
     const contextName = 'rancher-desktop';
-    const workDir = await fs.promises.mkdtemp(path.join(os.tmpdir(), 'rancher-desktop-kubeconfig-'));
 
-    try {
-      const workPath = path.join(workDir, 'kubeconfig');
-
-      // For some reason, using KubeConfig.loadFromFile presents permissions
-      // errors; doing the same ourselves seems to work better.  Since the file
-      // comes from the WSL container, it must not contain any paths, so there
-      // is no need to fix it up.  This also lets us use an external function to
-      // read the kubeconfig.
-      const workConfig = new KubeConfig();
-      const workContents = await configReader();
-
-      workConfig.loadFromString(workContents);
-      // @kubernetes/client-node doesn't have an API to modify the configs...
-      const contextIndex = workConfig.contexts.findIndex(context => context.name === workConfig.currentContext);
-
-      if (contextIndex >= 0) {
-        const context = workConfig.contexts[contextIndex];
-        const userIndex = workConfig.users.findIndex(user => user.name === context.user);
-        const clusterIndex = workConfig.clusters.findIndex(cluster => cluster.name === context.cluster);
-
-        if (userIndex >= 0) {
-          workConfig.users[userIndex] = { ...workConfig.users[userIndex], name: contextName };
-        }
-        if (clusterIndex >= 0) {
-          workConfig.clusters[clusterIndex] = { ...workConfig.clusters[clusterIndex], name: contextName };
-        }
-        workConfig.contexts[contextIndex] = {
-          ...context, name: contextName, user: contextName, cluster: contextName,
-        };
+    // For some reason, using KubeConfig.loadFromFile presents permissions
+    // errors; doing the same ourselves seems to work better.  Since the file
+    // comes from the WSL container, it must not contain any paths, so there
+    // is no need to fix it up.  This also lets us use an external function to
+    // read the kubeconfig.
+    const workConfig = new KubeConfig();
+    const workContents = await configReader();
+
+    workConfig.loadFromString(workContents);
+    // @kubernetes/client-node doesn't have an API to modify the configs...
+    const contextIndex = workConfig.contexts.findIndex(context => context.name === workConfig.currentContext);
+
+    if (contextIndex >= 0) {
+      const context = workConfig.contexts[contextIndex];
+      const userIndex = workConfig.users.findIndex(user => user.name === context.user);
+      const clusterIndex = workConfig.clusters.findIndex(cluster => cluster.name === context.cluster);
+
+      if (userIndex >= 0) {
+        workConfig.users[userIndex] = { ...workConfig.users[userIndex], name: contextName };
+      }
+      if (clusterIndex >= 0) {
+        workConfig.clusters[clusterIndex] = { ...workConfig.clusters[clusterIndex], name: contextName };
+      }
+      workConfig.contexts[contextIndex] = {
+        ...context, name: contextName, user: contextName, cluster: contextName,
+      };
 
-        workConfig.currentContext = contextName;
+      workConfig.currentContext = contextName;
+    }
+    let userPath = await K3sHelper.findKubeConfigToUpdate(contextName);
+
+    try {
+      // Update the target of a symbolic link, rather than replacing the link.
+      userPath = await fs.promises.realpath(userPath);
+    } catch (err) {
+      if ((err as NodeJS.ErrnoException).code !== 'ENOENT') {
+        throw err;
       }
-      const userPath = await K3sHelper.findKubeConfigToUpdate(contextName);
+    }
+
+    const unlock = await K3sHelper.lockKubeconfig(userPath);
+    // Write the new file next to the existing one, so the rename is atomic.
+    const workPath = path.join(path.dirname(userPath), `.${ path.basename(userPath) }.rancher-desktop-${ process.pid }`);
+
+    try {
       const userConfig = new KubeConfig();
+      let mode = 0o600;
 
       // @kubernetes/client-node throws when merging things that already exist
       const merge = <T extends { name: string }>(list: T[], additions: T[]) => {
@@ -997,30 +1055,50 @@ export default class K3sHelper extends events.EventEmitter {
         // Don't use loadFromFile() because it calls MakePathsAbsolute().
         // Use custom loadFromString() that supports the `proxy-url` cluster field.
         loadFromString(userConfig, fs.readFileSync(userPath, 'utf8'), { onInvalidEntry: ActionOnInvalid.FILTER });
+        mode = (await fs.promises.stat(userPath)).mode & 0o777;
       } catch (err) {
         if ((err as NodeJS.ErrnoException).code !== 'ENOENT') {
           console.log(`Error trying to load kubernetes config file ${ userPath }:`, err);
         }
         // continue to merge into an empty userConfig == `{ contexts: [], clusters: [], users: [] }`
       }
-      merge(userConfig.contexts, workConfig.contexts);
-      merge(userConfig.users, workConfig.users);
-      merge(userConfig.clusters, workConfig.clusters);
+
+      const find = <T extends { name: string }>(list: T[]) => list.find(item => item.name === contextName);
+      const [userContext, workContext] = [find(userConfig.contexts), find(workConfig.contexts)];
+      const [userCluster, workCluster] = [find(userConfig.clusters), find(workConfig.clusters)];
+      const sameExceptServer = userContext && workContext && userCluster && workCluster &&
+        userContext.cluster === workContext.cluster && userContext.user === workContext.user &&
+        _.isEqual(find(userConfig.users), find(workConfig.users)) &&
+        _.isEqual({ ...userCluster, server: workCluster.server }, workCluster);
+
+      if (sameExceptServer) {
+        if (userCluster.server === workCluster.server) {
+          console.log(`Kubeconfig ${ userPath } is up to date.`);
+
+          return;
+        }
+        // Only the server changed; keep anything else the user modified.
+        Object.assign(userCluster, { server: workCluster.server });
+      } else {
+        merge(userConfig.contexts, workConfig.contexts);
+        merge(userConfig.users, workConfig.users);
+        merge(userConfig.clusters, workConfig.clusters);
+      }
       userConfig.currentContext ||= contextName;
       // Use custom exportConfig() that supports the `proxy-url` cluster field.
       const userYAML = this.ensureContentsAreYAML(exportConfig(userConfig));
-      const writeStream = fs.createWriteStream(workPath, { mode: 0o600 });
+      const file = await fs.promises.open(workPath, 'w', mode);
 
-      await new Promise((resolve, reject) => {
-        writeStream.on('error', reject);
-        writeStream.on('finish', resolve);
-        writeStream.end(userYAML, 'utf-8');
-      });
-      await safeRename(workPath, userPath);
+      try {
+        await file.writeFile(userYAML, 'utf-8');
+        await file.sync();
+      } finally {
+        await file.close();
+      }
+      await fs.promises.rename(workPath, userPath);
     } finally {
-      await fs.promises.rm(workDir, {
-        recursive: true, force: true, maxRetries: 10,
-      });
+      await fs.promises.rm(workPath, { force: true, maxRetries: 10 });
+      await unlock();
     }
   }
 
@@ -18,6 +18,7 @@ import {
 import BackendHelper from './backendHelper';
 import { ContainerEngineClient, MobyClient, NerdctlClient } from './containerClient';
 import ProgressTracker, { getProgressErrorDescription } from './progressTracker';
+import { getNetworkingMode } from './wslNetworking';
 
 import DEPENDENCY_VERSIONS from '@pkg/assets/dependencies.yaml';
 import FLANNEL_CONFLIST from '@pkg/assets/scripts/10-flannel.conflist';
@@ -108,7 +109,9 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
         const stream = await Logging['host-switch'].fdStream;
         const args: string[] = [];
 
-        if (this.cfg?.kubernetes.enabled) {
+        // In mirrored networking mode, WSL exposes the Kubernetes API port
+        // that wsl-proxy listens on, so forwarding it here would conflict.
+        if (this.cfg?.kubernetes.enabled && !this.mirroredNetworking) {
           const k8sPort = 6443;
           const eth0IP = '192.168.127.2';
           const k8sPortForwarding = `127.0.0.1:${ k8sPort }=${ eth0IP }:${ k8sPort }`;
@@ -178,6 +181,16 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
    */
   protected hostSwitchProcess: BackgroundProcess;
 
+  /**
+   * Whether WSL is using mirrored networking mode; in that mode, ports bound
+   * on localhost in the VM are mirrored onto the host by WSL itself, so we
+   * must not relay them through host-switch as well.  DNS is not affected:
+   * the Rancher Desktop network namespace resolves names through the
+   * host-switch gateway over vsock, which does not use WSL networking.  This
+   * is updated on start.
+   */
+  protected mirroredNetworking = false;
+
   readonly kubeBackend: KubernetesBackend;
   readonly executor = this;
   #containerEngineClient: ContainerEngineClient | undefined;
@@ -708,6 +721,39 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
     await this.runInstallScript(INSTALL_WSL_HELPERS_SCRIPT, 'install-wsl-helpers', nerdctlPath);
   }
 
+  /**
+   * Check that Windows drives are mounted in the distribution, remounting them
+   * if needed; otherwise bind mounts from Windows paths fail obscurely.  The
+   * outcome is reported to diagnostics.
+   */
+  protected async checkDriveMounts() {
+    type DrivesReport = {
+      healthy: boolean;
+      drives: { letter: string; accessible: boolean }[];
+      restart_required?: boolean;
+    };
+
+    try {
+      const output = await this.execCommand({ capture: true }, await this.getWSLHelperPath(), 'wsl', 'drives', '--mode=repair');
+      const report: DrivesReport = JSON.parse(output);
+      const unmounted = report.drives.filter(drive => !drive.accessible).map(drive => drive.letter);
+
+      if (!report.healthy) {
+        console.error(`Windows drives are not mounted: ${ output }`);
+      }
+      mainEvents.emit('diagnostics-event', {
+        id: 'wsl-drives', healthy: report.healthy, unmounted, restartRequired: !!report.restart_required,
+      });
+    } catch (ex) {
+      // The helper lives on a Windows drive; failing to run it usually means
+      // that drive is not mounted either.
+      console.error('Failed to check Windows drive mounts:', ex);
+      mainEvents.emit('diagnostics-event', {
+        id: 'wsl-drives', healthy: false, unmounted: [], restartRequired: true,
+      });
+    }
+  }
+
   protected async installCredentialHelper() {
     const credsPath = getServerCredentialsPath();
 
@@ -789,12 +835,13 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
 
     const guestAgentConfig: Record<string, string> = {
       LOG_DIR:                  await this.wslify(paths.logs),
-      GUESTAGENT_ADMIN_INSTALL: isAdminInstall ? 'true' : 'false',
-      GUESTAGENT_KUBERNETES:    enableKubernetes ? 'true' : 'false',
-      GUESTAGENT_CONTAINERD:    cfg?.containerEngine.name === ContainerEngine.CONTAINERD ? 'true' : 'false',
-      GUESTAGENT_DOCKER:        cfg?.containerEngine.name === ContainerEngine.MOBY ? 'true' : 'false',
-      GUESTAGENT_DEBUG:         this.debug ? 'true' : 'false',
-      GUESTAGENT_K8S_SVC_ADDR:  isAdminInstall && !cfg?.kubernetes.ingress.localhostOnly ? '0.0.0.0' : '127.0.0.1',
+      GUESTAGENT_ADMIN_INSTALL:       isAdminInstall ? 'true' : 'false',
+      GUESTAGENT_MIRRORED_NETWORKING: this.mirroredNetworking ? 'true' : 'false',
+      GUESTAGENT_KUBERNETES:          enableKubernetes ? 'true' : 'false',
+      GUESTAGENT_CONTAINERD:          cfg?.containerEngine.name === ContainerEngine.CONTAINERD ? 'true' : 'false',
+      GUESTAGENT_DOCKER:              cfg?.containerEngine.name === ContainerEngine.MOBY ? 'true' : 'false',
+      GUESTAGENT_DEBUG:               this.debug ? 'true' : 'false',
+      GUESTAGENT_K8S_SVC_ADDR:        isAdminInstall && !cfg?.kubernetes.ingress.localhostOnly ? '0.0.0.0' : '127.0.0.1',
     };
 
     await Promise.all([
@@ -940,14 +987,8 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
     return (async() => {
       // When using mirrored-mode networking, 127.0.0.1 works just fine
       // ...also, there may not even be an `eth0` to find the IP of!
-      try {
-        const networkModeString = await this.captureCommand('wslinfo', '-n', '--networking-mode');
-
-        if (networkModeString === 'mirrored') {
-          return '127.0.0.1';
-        }
-      } catch {
-        // wslinfo is missing (wsl < 2.0.4) - fall back to old behavior
+      if (await getNetworkingMode(this) === 'mirrored') {
+        return '127.0.0.1';
       }
 
       // We need to locate the _local_ route (netmask) for eth0, and then
@@ -1258,7 +1299,15 @@ export default class WSLBackend extends events.EventEmitter implements VMBackend
                 .replace('/var/log', logPath);
               const configureWASM = !!this.cfg?.experimental?.containerEngine?.webAssembly?.enabled;
 
+              // This must be known before host-switch and the guest agent are
+              // configured, as it changes how ports are forwarded to the host.
+              this.mirroredNetworking = await getNetworkingMode(this) === 'mirrored';
+              if (this.mirroredNetworking) {
+                console.log('WSL is using mirrored networking mode; relying on WSL to forward ports to the host.');
+              }
+
               await Promise.all([
+                this.progressTracker.action('Checking Windows drive mounts', 50, this.checkDriveMounts()),
                 this.progressTracker.action('Installing the docker-credential helper', 10, async() => {
                   // This must run after /etc/rancher is mounted
                   await this.installCredentialHelper();
@@ -0,0 +1,24 @@
+/**
+ * Helpers for working out how WSL networking is configured.
+ */
+
+import type { VMExecutor } from './backend';
+
+/**
+ * The WSL networking mode, as configured by `networkingMode` in `.wslconfig`.
+ */
+export type WSLNetworkingMode = 'nat' | 'mirrored' | string;
+
+/**
+ * Get the WSL networking mode (e.g. `nat` or `mirrored`) of a running WSL
+ * distribution.
+ * @param executor Runs commands in the distribution.
+ */
+export async function getNetworkingMode(executor: Pick<VMExecutor, 'execCommand'>): Promise<WSLNetworkingMode> {
+  try {
+    return (await executor.execCommand({ capture: true }, 'wslinfo', '-n', '--networking-mode')).trim() || 'nat';
+  } catch {
+    // wslinfo is missing (wsl < 2.0.4), which only supports NAT mode.
+    return 'nat';
+  }
+}
@@ -369,6 +369,32 @@ describe('settings', () => {
     });
   });
 
+  describe('settingsChanges', () => {
+    it('reports changed leaves', () => {
+      const before = {
+        application:     { debug: false, extensions: { installed: { a: '1' } } },
+        kubernetes:      { enabled: true, version: '1.29.1' },
+        portForwarding:  { includeKubernetesServices: false },
+        containerEngine: { allowedImages: { patterns: ['nginx'] } },
+      };
+      const after = _.merge(_.cloneDeep(before), {
+        application:     { extensions: { installed: { b: '2' } } },
+        kubernetes:      { enabled: false },
+        containerEngine: { allowedImages: { patterns: ['nginx', 'alpine'] } },
+      });
+
+      expect(settingsImpl.settingsChanges(before, after)).toEqual([
+        { path: 'application.extensions.installed.b', old: undefined, new: '2' },
+        { path: 'containerEngine.allowedImages.patterns', old: ['nginx'], new: ['nginx', 'alpine'] },
+        { path: 'kubernetes.enabled', old: true, new: false },
+      ]);
+    });
+
+    it('reports nothing for identical settings', () => {
+      expect(settingsImpl.settingsChanges(settings.defaultSettings, _.cloneDeep(settings.defaultSettings))).toEqual([]);
+    });
+  });
+
   describe('lockableFields', () => {
     test('flattens an object with only allowed-image settings', () => {
       const lockedSettings = {
@@ -111,6 +111,17 @@ export const defaultSettings = {
     showMuted:   false,
     mutedChecks: {} as Record<string, boolean>,
   },
+  snapshots: {
+    schedule: {
+      enabled:         false,
+      /** How often to create a scheduled snapshot. */
+      intervalInHours: 24,
+      /** The number of scheduled snapshots to keep; older ones are deleted. */
+      retention:       5,
+      /** Don't create a snapshot if nothing changed since the last scheduled one. */
+      skipIfUnchanged: true,
+    },
+  },
   /**
    * Experimental settings
    */
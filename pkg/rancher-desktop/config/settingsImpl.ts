@@ -200,6 +200,34 @@ export function merge<T = Settings>(cfg: T, changes: RecursivePartial<RecursiveR
   return _.mergeWith(cfg, changes, customizer);
 }
 
+/**
+ * A single setting that was changed, as reported to settings watchers.
+ */
+export type SettingsChange = {
+  /** The dotted path of the setting, e.g. `kubernetes.enabled`. */
+  path: string;
+  /** The previous value; undefined if the setting was added. */
+  old: any;
+  /** The new value; undefined if the setting was removed. */
+  new: any;
+};
+
+/**
+ * Compare two sets of settings, returning the changed leaf settings (arrays
+ * are compared as a whole), sorted by path.
+ */
+export function settingsChanges(oldSettings: any, newSettings: any, prefix = ''): SettingsChange[] {
+  const isObject = (value: any) => typeof value === 'object' && value !== null && !Array.isArray(value);
+
+  if (!isObject(oldSettings) || !isObject(newSettings)) {
+    return _.isEqual(oldSettings, newSettings) ? [] : [{ path: prefix, old: oldSettings, new: newSettings }];
+  }
+
+  return _.union(Object.keys(oldSettings), Object.keys(newSettings)).sort().flatMap((key) => {
+    return settingsChanges(oldSettings[key], newSettings[key], prefix ? `${ prefix }.${ key }` : key);
+  });
+}
+
 export function getLockedSettings(): LockedSettingsType {
   return lockedSettings;
 }
@@ -61,4 +61,45 @@ describe('WindowsIntegrationManager', () => {
       );
     });
   });
+
+  describe('syncDistroSystemdUnits', () => {
+    let execCommandMock: jest.SpyInstance<Promise<void>, any>;
+
+    beforeEach(() => {
+      jest.spyOn(integrationManager as any, 'getLinuxToolPath').mockResolvedValue('/wsl-helper');
+      execCommandMock = jest.spyOn(integrationManager as any, 'execCommand').mockResolvedValue(undefined);
+    });
+
+    it('should install the units if systemd is enabled', async() => {
+      captureCommandMock.mockResolvedValue('true\n');
+
+      await expect(integrationManager['syncDistroSystemdUnits']('Ubuntu', true)).resolves.toBe(true);
+      expect(captureCommandMock).toHaveBeenCalledWith(
+        { distro: 'Ubuntu' }, '/wsl-helper', 'wsl', 'integration', 'systemd', '--mode=show');
+      expect(execCommandMock).toHaveBeenCalledWith(
+        { distro: 'Ubuntu' }, '/wsl-helper', 'wsl', 'integration', 'systemd', '--mode=install', '--');
+    });
+
+    it('should remove the units if systemd is not enabled', async() => {
+      captureCommandMock.mockResolvedValue('false\n');
+
+      await expect(integrationManager['syncDistroSystemdUnits']('Ubuntu', true)).resolves.toBe(false);
+      expect(execCommandMock).toHaveBeenCalledWith(
+        { distro: 'Ubuntu' }, '/wsl-helper', 'wsl', 'integration', 'systemd', '--mode=remove');
+    });
+
+    it('should remove the units if integration is disabled', async() => {
+      await expect(integrationManager['syncDistroSystemdUnits']('Ubuntu', false)).resolves.toBe(false);
+      expect(captureCommandMock).not.toHaveBeenCalled();
+      expect(execCommandMock).toHaveBeenCalledWith(
+        { distro: 'Ubuntu' }, '/wsl-helper', 'wsl', 'integration', 'systemd', '--mode=remove');
+    });
+
+    it('should fall back if the units can not be installed', async() => {
+      captureCommandMock.mockResolvedValue('true\n');
+      execCommandMock.mockRejectedValue(new Error('failed'));
+
+      await expect(integrationManager['syncDistroSystemdUnits']('Ubuntu', true)).resolves.toBe(false);
+    });
+  });
 });
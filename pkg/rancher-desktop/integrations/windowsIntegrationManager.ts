@@ -359,9 +359,11 @@ export default class WindowsIntegrationManager implements IntegrationManager {
   protected async syncDistroSocketProxy(distro: string, state: boolean) {
     try {
       const shouldRun = state && !this.dockerSocketProxyReason;
+      // With systemd, the socket is provided by systemd units instead.
+      const useSystemd = await this.syncDistroSystemdUnits(distro, shouldRun);
 
-      console.debug(`Syncing ${ distro } socket proxy: ${ shouldRun ? 'should' : 'should not' } run.`);
-      if (shouldRun) {
+      console.debug(`Syncing ${ distro } socket proxy: ${ shouldRun ? 'should' : 'should not' } run${ useSystemd ? ' via systemd' : '' }.`);
+      if (shouldRun && !useSystemd) {
         const linuxExecutable = await this.getLinuxToolPath(distro, executable('wsl-helper-linux'));
         const logStream = Logging[`wsl-helper.${ distro }`];
 
@@ -398,6 +400,40 @@ export default class WindowsIntegrationManager implements IntegrationManager {
     }
   }
 
+  /**
+   * syncDistroSystemdUnits installs the systemd user units providing the docker
+   * socket in the given distribution if it has systemd enabled, and removes
+   * them otherwise.
+   * @param distro The distribution to manage.
+   * @param state Whether the docker socket should be provided.
+   * @returns Whether the units are installed.
+   * @note this function must not throw.
+   */
+  protected async syncDistroSystemdUnits(distro: string, state: boolean): Promise<boolean> {
+    try {
+      const wslHelper = await this.getLinuxToolPath(distro, executable('wsl-helper-linux'));
+      let enabled = false;
+
+      if (state) {
+        const stdout = await this.captureCommand({ distro }, wslHelper, 'wsl', 'integration', 'systemd', '--mode=show');
+
+        enabled = stdout.trim() === 'true';
+      }
+      if (enabled) {
+        await this.execCommand({ distro }, wslHelper, 'wsl', 'integration', 'systemd', '--mode=install',
+          '--', ...this.wslHelperDebugArgs);
+      } else {
+        await this.execCommand({ distro }, wslHelper, 'wsl', 'integration', 'systemd', '--mode=remove');
+      }
+
+      return enabled;
+    } catch (error) {
+      console.error(`Error syncing ${ distro } systemd units: ${ error }`);
+
+      return false;
+    }
+  }
+
   protected async syncHostDockerPluginConfig() {
     const configPath = path.join(os.homedir(), '.docker', 'config.json');
     let config: { cliPluginsExtraDirs?: string[] } = {};
@@ -442,7 +478,7 @@ export default class WindowsIntegrationManager implements IntegrationManager {
         path.join(paths.resources, 'linux', 'docker-cli-plugins'));
       const wslHelper = await this.getLinuxToolPath(distro, executable('wsl-helper-linux'));
       const args = ['wsl', 'integration', 'docker-plugin',
-        `--plugin-dir=${ srcPath }`, `--bin-dir=${ binDir }`, `--state=${ state }`];
+        `--plugin-dir=${ srcPath }`, `--bin-dir=${ binDir }`, `--state=${ state }`, '--install'];
 
       if (this.settings.application?.debug) {
         args.push('--verbose');
@@ -482,7 +518,7 @@ export default class WindowsIntegrationManager implements IntegrationManager {
       // so that the diagnostic is very specific to this issue. Any other errors
       // are captured as log messages.
       if (err && 'code' in err && err.code === 1) {
-        throw new Error(`The kubeConfig contains non-Rancher Desktop configuration in distro ${ distro }`);
+        throw new Error(`The kubeConfig in distro ${ distro } can not be merged with Rancher Desktop configuration`);
       } else {
         console.error(`Verifying kubeconfig in distro ${ distro } failed: ${ err }`);
       }
@@ -491,21 +527,21 @@ export default class WindowsIntegrationManager implements IntegrationManager {
   }
 
   protected async syncDistroKubeconfig(distro: string, kubeconfigPath: string, state: boolean) {
+    const options = {
+      distro,
+      env: {
+        ...process.env,
+        KUBECONFIG: kubeconfigPath,
+        WSLENV:     `${ process.env.WSLENV }:KUBECONFIG/up`,
+      },
+    };
+    const enable = `--enable=${ state && this.settings.kubernetes?.enabled }`;
+
     try {
       console.debug(`Syncing ${ distro } kubeconfig`);
-      await this.execCommand(
-        {
-          distro,
-          env: {
-            ...process.env,
-            KUBECONFIG: kubeconfigPath,
-            WSLENV:     `${ process.env.WSLENV }:KUBECONFIG/up`,
-          },
-        },
-        await this.getLinuxToolPath(distro, executable('wsl-helper-linux')),
-        'kubeconfig',
-        `--enable=${ state && this.settings.kubernetes?.enabled }`,
-      );
+      const wslHelper = await this.getLinuxToolPath(distro, executable('wsl-helper-linux'));
+
+      await this.execCommand(options, wslHelper, 'kubeconfig', enable);
     } catch (error: any) {
       if (typeof error?.stdout === 'string') {
         error.stdout = error.stdout.replace(/\0/g, '');
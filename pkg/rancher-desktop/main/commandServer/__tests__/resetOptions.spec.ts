@@ -0,0 +1,79 @@
+import { containersToRemove, parseResetOptions } from '../resetOptions';
+
+// The reset API responds with status 400 and the error message if
+// parseResetOptions returns an error.
+describe('parseResetOptions', () => {
+  it('should reset only Kubernetes for an empty body', () => {
+    const expected = {
+      kubernetes: true, images: false, volumes: false,
+    };
+
+    expect(parseResetOptions('')).toEqual([expected, '']);
+    expect(parseResetOptions('{}')).toEqual([expected, '']);
+  });
+
+  it('should reset only what is selected', () => {
+    expect(parseResetOptions('{"images": true, "volumes": true}')).toEqual([
+      {
+        kubernetes: false, images: true, volumes: true,
+      }, '']);
+    expect(parseResetOptions('{"kubernetes": true, "images": false}')).toEqual([
+      {
+        kubernetes: true, images: false, volumes: false,
+      }, '']);
+  });
+
+  it('should reject a body that selects nothing', () => {
+    const [, error] = parseResetOptions('{"kubernetes": false, "images": false, "volumes": false}');
+
+    expect(error).toEqual('Nothing to reset');
+  });
+
+  it('should reject unknown options', () => {
+    const [, error] = parseResetOptions('{"kubernetes": true, "snapshots": true}');
+
+    expect(error).toEqual('Unknown reset option "snapshots"');
+    expect(parseResetOptions('{"constructor": true}')[1]).toEqual('Unknown reset option "constructor"');
+  });
+
+  it('should reject non-boolean values', () => {
+    const [, error] = parseResetOptions('{"images": "yes"}');
+
+    expect(error).toEqual('Invalid value for reset option "images": "yes" is not a boolean');
+  });
+
+  it.each([
+    ['invalid JSON', '{images', 'error processing JSON request block'],
+    ['an array', '[true]', 'Reset options must be an object'],
+    ['null', 'null', 'Reset options must be an object'],
+  ])('should reject %s', (_, body, message) => {
+    expect(parseResetOptions(body)[1]).toEqual(message);
+  });
+});
+
+describe('containersToRemove', () => {
+  const listing = [
+    'abc123\tweb\tcom.example=1',
+    'def456\tk8s_POD_coredns-1_kube-system_0\tio.kubernetes.pod.name=coredns-1,io.kubernetes.pod.namespace=kube-system',
+    'ghi789\tworker\tio.kubernetes.container.name=worker',
+    '',
+  ].join('\n');
+
+  it('should keep Kubernetes containers when not resetting Kubernetes', () => {
+    expect(containersToRemove(listing, {
+      kubernetes: false, images: true, volumes: false,
+    })).toEqual(['abc123']);
+  });
+
+  it('should remove all containers when resetting Kubernetes', () => {
+    expect(containersToRemove(listing, {
+      kubernetes: true, images: true, volumes: false,
+    })).toEqual(['abc123', 'def456', 'ghi789']);
+  });
+
+  it('should handle an empty listing', () => {
+    expect(containersToRemove('', {
+      kubernetes: false, images: true, volumes: false,
+    })).toEqual([]);
+  });
+});
@@ -801,6 +801,19 @@ describe(SettingsValidator, () => {
     ]);
   });
 
+  describe('snapshots.schedule', () => {
+    test.each(['intervalInHours', 'retention'])('should reject fractional %s', (key) => {
+      const [needToUpdate, errors, isFatal] = subject.validateSettings(cfg,
+        { snapshots: { schedule: { [key]: 1.5 } } });
+
+      expect({ needToUpdate, errors, isFatal }).toEqual({
+        needToUpdate: false,
+        errors:       [`Invalid value for "snapshots.schedule.${ key }": <1.5>`],
+        isFatal:      false,
+      });
+    });
+  });
+
   describe('experimental.virtualMachine.type', () => {
     let spyArch: jest.SpiedFunction<typeof os.arch>;
     let spyMacOsVersion: jest.SpiedFunction<typeof osVersion.getMacOsVersion>;
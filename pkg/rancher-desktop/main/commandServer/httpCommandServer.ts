@@ -8,8 +8,11 @@ import _ from 'lodash';
 
 import { State } from '@pkg/backend/backend';
 import type { Settings } from '@pkg/config/settings';
+import type { SettingsChange } from '@pkg/config/settingsImpl';
 import type { TransientSettings } from '@pkg/config/transientSettings';
+import { parseResetOptions, ResetOptions as resetOptions } from '@pkg/main/commandServer/resetOptions';
 import type { DiagnosticsResultCollection } from '@pkg/main/diagnostics/diagnostics';
+import type { CatalogExtension } from '@pkg/main/extensions/catalog';
 import { ExtensionMetadata } from '@pkg/main/extensions/types';
 import mainEvents from '@pkg/main/mainEvents';
 import * as serverHelper from '@pkg/main/serverHelper';
@@ -73,6 +76,7 @@ export class HttpCommandServer {
         '/v1/diagnostic_checks':     [0, this.diagnosticChecks],
         '/v1/settings':              [0, this.listSettings],
         '/v1/settings/locked':       [0, this.listLockedSettings],
+        '/v1/settings/watch':        [1, this.watchSettings],
         '/v1/transient_settings':    [0, this.listTransientSettings],
         '/v1/backend_state':         [1, this.getBackendState],
       },
@@ -80,6 +84,7 @@ export class HttpCommandServer {
       put:  {
         '/v1/factory_reset':      [0, this.factoryReset],
         '/v1/propose_settings':   [0, this.proposeSettings],
+        '/v1/reset':              [1, this.reset],
         '/v1/settings':           [0, this.updateSettings],
         '/v1/shutdown':           [0, this.wrapShutdown],
         '/v1/transient_settings': [0, this.updateTransientSettings],
@@ -87,7 +92,11 @@ export class HttpCommandServer {
       },
     } as const,
     {
-      get:  { '/v1/extensions': [1, this.listExtensions] },
+      get:  {
+        '/v1/extensions':          [1, this.listExtensions],
+        '/v1/extensions/catalog':  [1, this.listCatalogExtensions],
+        '/v1/extensions/versions': [1, this.listExtensionVersions],
+      },
       post: {
         '/v1/extensions/install':   [1, this.installExtension],
         '/v1/extensions/uninstall': [1, this.uninstallExtension],
@@ -344,6 +353,32 @@ export class HttpCommandServer {
     return Promise.resolve();
   }
 
+  /**
+   * Stream settings changes as newline-delimited JSON, one object per changed
+   * setting, until the client disconnects.
+   */
+  protected watchSettings(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
+    const listener = (source: string, changes: SettingsChange[]) => {
+      const time = new Date().toISOString();
+
+      for (const change of changes) {
+        response.write(`${ JSON.stringify({ time, source, ...change }) }\n`);
+      }
+    };
+
+    console.debug('watchSettings: streaming 200');
+    response.status(200).type('application/x-ndjson');
+    response.flushHeaders();
+    mainEvents.on('settings-changed', listener);
+
+    return new Promise((resolve) => {
+      request.on('close', () => {
+        mainEvents.off('settings-changed', listener);
+        resolve();
+      });
+    });
+  }
+
   protected listLockedSettings(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
     const settings = this.commandWorker.getLockedSettings(context);
 
@@ -537,6 +572,40 @@ export class HttpCommandServer {
     }
   }
 
+  /**
+   * Reset parts of the backend, keeping the rest.  The request body selects
+   * what to remove; with no options, only the Kubernetes state is removed.
+   */
+  protected async reset(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
+    const [data, payloadError, payloadErrorCode] = await serverHelper.getRequestBody(request, MAX_REQUEST_BODY_LENGTH);
+
+    if (payloadError) {
+      response.status(payloadErrorCode).type('txt').send(payloadError);
+
+      return;
+    }
+    const [options, error] = parseResetOptions(data);
+
+    if (error) {
+      console.log(`reset: ${ error }\n${ data }\n`);
+      response.status(400).type('txt').send(error);
+
+      return;
+    }
+
+    const resetError = await this.commandWorker.reset(context, options);
+
+    if (resetError) {
+      console.debug(`reset: write back status 503, error: ${ resetError }`);
+      response.status(503).type('txt').send(resetError);
+    } else {
+      const parts = Object.entries(options).filter(([, v]) => v).map(([k]) => k);
+
+      console.debug('reset: succeeded 202');
+      response.status(202).type('txt').send(`Resetting ${ parts.join(', ') }...`);
+    }
+  }
+
   protected async createPortForwarding(request: express.Request, response: express.Response, _: commandContext): Promise<void> {
     let values: Record<string, any> = {};
     const [data, payloadError] = await serverHelper.getRequestBody(request, MAX_REQUEST_BODY_LENGTH);
@@ -690,6 +759,33 @@ export class HttpCommandServer {
     }
   }
 
+  protected async listCatalogExtensions(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
+    response.status(200).type('json').send(await this.commandWorker.listCatalogExtensions());
+  }
+
+  protected async listExtensionVersions(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
+    const id = request.query.id ?? '';
+
+    if (!id) {
+      response.status(400).type('txt').send('Extension ID is required in the id= parameter.');
+    } else if (typeof id !== 'string') {
+      response.status(400).type('txt').send(`Invalid extension id ${ JSON.stringify(id) }: not a string.`);
+    } else {
+      try {
+        const versions = await this.commandWorker.listExtensionVersions(id);
+
+        if (!versions) {
+          response.status(503).type('txt').send('Extension manager is not ready yet.');
+        } else {
+          response.status(200).type('json').send(versions);
+        }
+      } catch (ex) {
+        console.error(`listExtensionVersions: failed to get versions of ${ id }:`, ex);
+        response.status(500).type('txt').send(`Failed to get versions of ${ id }: ${ ex }`);
+      }
+    }
+  }
+
   protected async installExtension(request: express.Request, response: express.Response, context: commandContext): Promise<void> {
     const id = request.query.id ?? '';
 
@@ -870,6 +966,11 @@ interface commandContext {
  */
 export interface CommandWorkerInterface {
   factoryReset: (keepSystemImages: boolean) => void;
+  /**
+   * Start resetting parts of the backend.
+   * @returns An error message if the reset can't be done now.
+   */
+  reset: (context: commandContext, options: resetOptions) => Promise<string | undefined>;
   getSettings: (context: commandContext) => string;
   getLockedSettings: (context: commandContext) => string;
   updateSettings: (context: commandContext, newSettings: RecursivePartial<Settings>) => Promise<[string, string]>;
@@ -898,6 +999,16 @@ export interface CommandWorkerInterface {
    * @returns The HTTP status code, possibly with arbitrary response body data.
    */
   installExtension(id: string, state: 'install' | 'uninstall'): Promise<{status: number, data?: any}>;
+  /**
+   * List the extensions in the marketplace catalog, with their compatibility
+   * with the current settings.
+   */
+  listCatalogExtensions(): Promise<CatalogExtension[]>;
+  /**
+   * List the available versions of the given extension image, newest first.
+   * If the extension manager is not ready, returns undefined.
+   */
+  listExtensionVersions(id: string): Promise<string[] | undefined>;
   // #endregion
   listSnapshots: (context: commandContext) => Promise<Snapshot[]>;
   createSnapshot: (context: commandContext, snapshot: Snapshot) => Promise<void>;
@@ -915,4 +1026,5 @@ export interface CommandWorkerInterface {
 // eslint-disable-next-line @typescript-eslint/no-namespace
 export namespace CommandWorkerInterface {
   export type CommandContext = commandContext;
+  export type ResetOptions = resetOptions;
 }
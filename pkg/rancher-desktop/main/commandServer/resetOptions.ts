@@ -0,0 +1,80 @@
+/**
+ * What to remove for a reset; anything not selected is kept.
+ */
+export interface ResetOptions {
+  /** Remove the Kubernetes state (the cluster, and its workloads). */
+  kubernetes: boolean;
+  /** Remove all containers and container images. */
+  images: boolean;
+  /** Remove all container volumes (including named volumes). */
+  volumes: boolean;
+}
+
+/**
+ * Parse the body of a reset request.  An empty body (or an empty object)
+ * resets only Kubernetes; otherwise, the body must select at least one thing
+ * to reset.
+ * @param data The request body.
+ * @returns The reset options, and an error message if the body is invalid.
+ */
+export function parseResetOptions(data: string): [ResetOptions, string] {
+  const options: ResetOptions = {
+    kubernetes: false, images: false, volumes: false,
+  };
+  let values: any;
+
+  try {
+    values = data ? JSON.parse(data) : {};
+  } catch {
+    return [options, 'error processing JSON request block'];
+  }
+  if (typeof values !== 'object' || values === null || Array.isArray(values)) {
+    return [options, 'Reset options must be an object'];
+  }
+  for (const [key, value] of Object.entries(values)) {
+    if (!Object.keys(options).includes(key)) {
+      return [options, `Unknown reset option "${ key }"`];
+    }
+    if (typeof value !== 'boolean') {
+      return [options, `Invalid value for reset option "${ key }": ${ JSON.stringify(value) } is not a boolean`];
+    }
+    options[key as keyof ResetOptions] = value;
+  }
+  if (Object.keys(values).length === 0) {
+    options.kubernetes = true;
+  } else if (!Object.values(options).some(v => v)) {
+    return [options, 'Nothing to reset'];
+  }
+
+  return [options, ''];
+}
+
+/**
+ * The `container ls` format used to list containers for a reset; the output is
+ * parsed by `containersToRemove`.
+ */
+export const resetContainerListFormat = '{{.ID}}\t{{.Names}}\t{{.Labels}}';
+
+/**
+ * Work out which containers to remove when resetting images.  Containers that
+ * belong to Kubernetes (which, with moby, runs through cri-dockerd in the same
+ * daemon) are kept unless Kubernetes is being reset too; this also keeps the
+ * images they use.
+ * @param listing The output of `container ls --all` using `resetContainerListFormat`.
+ * @param options What is being reset.
+ * @returns The IDs of the containers to remove.
+ */
+export function containersToRemove(listing: string, options: ResetOptions): string[] {
+  return listing.split(/\r?\n/).flatMap((line) => {
+    const [id, names = '', labels = ''] = line.trim().split('\t');
+
+    if (!id) {
+      return [];
+    }
+    if (!options.kubernetes && (names.startsWith('k8s_') || labels.includes('io.kubernetes.'))) {
+      return [];
+    }
+
+    return [id];
+  });
+}
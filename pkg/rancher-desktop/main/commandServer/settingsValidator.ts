@@ -158,6 +158,14 @@ export default class SettingsValidator {
         mutedChecks: this.checkBooleanMapping,
         showMuted:   this.checkBoolean,
       },
+      snapshots: {
+        schedule: {
+          enabled:         this.checkBoolean,
+          intervalInHours: this.checkInteger(1, Number.POSITIVE_INFINITY),
+          retention:       this.checkInteger(1, Number.POSITIVE_INFINITY),
+          skipIfUnchanged: this.checkBoolean,
+        },
+      },
     };
     this.canonicalizeSynonyms(newSettings);
     const errors: Array<string> = [];
@@ -464,6 +472,24 @@ export default class SettingsValidator {
     };
   }
 
+  /**
+   * checkInteger returns a checker for a whole number in the given range,
+   * inclusive.
+   */
+  protected checkInteger(min: number, max: number) {
+    const checkNumber = this.checkNumber(min, max);
+
+    return <S>(mergedSettings: S, currentValue: number, desiredValue: number, errors: string[], fqname: string) => {
+      if (typeof desiredValue === 'number' && !Number.isInteger(desiredValue)) {
+        errors.push(this.invalidSettingMessage(fqname, desiredValue));
+
+        return false;
+      }
+
+      return checkNumber(mergedSettings, currentValue, desiredValue, errors, fqname);
+    };
+  }
+
   protected checkEnum(...validValues: string[]) {
     return <S>(mergedSettings: S, currentValue: string, desiredValue: string, errors: string[], fqname: string) => {
       const explanation = `must be one of ${ JSON.stringify(validValues) }`;
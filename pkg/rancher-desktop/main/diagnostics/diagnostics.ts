@@ -56,6 +56,7 @@ export class DiagnosticsManager {
         import('./pathManagement'),
         import('./rdBinInShell'),
         import('./testCheckers'),
+        import('./wslDrives'),
         import('./wslFromStore'),
       ])).map(obj => obj.default);
 
@@ -20,8 +20,8 @@ async function verifyKubeConfigSymlink(): Promise<boolean> {
 }
 
 /**
- * CheckKubeConfigSymlink checks the symlinked kubeConfig in WSL integration
- * enabled distro for non-rancher desktop configuration.
+ * CheckKubeConfigSymlink checks that the Rancher Desktop configuration can be
+ * merged into the kubeConfig in WSL integration enabled distros.
  */
 const CheckKubeConfigSymlink: DiagnosticsChecker = {
   id:       'VERIFY_WSL_INTEGRATION_KUBECONFIG',
@@ -31,10 +31,9 @@ const CheckKubeConfigSymlink: DiagnosticsChecker = {
   },
   async check() {
     return Promise.resolve({
-      description: 'Rancher Desktop cannot automatically convert the provided kubeconfig file to a symlink' +
-        ' due to existing configurations within that file. To resolve this issue, you will need to ' +
-        'manually create the symlink to ensure existing configurations are preserved and to prevent ' +
-        'any loss of configuration.',
+      description: 'Rancher Desktop cannot merge its configuration into the kubeconfig file in a WSL ' +
+        'distribution because the existing file could not be read. To resolve this issue, you will need ' +
+        'to fix or remove the existing kubeconfig file in that distribution.',
       passed: await verifyKubeConfigSymlink(),
       fixes:  [],
     });
@@ -0,0 +1,57 @@
+import mainEvents from '../mainEvents';
+import { DiagnosticsCategory, DiagnosticsChecker, DiagnosticsCheckerResult } from './types';
+
+let drivesHealthy = true;
+let unmountedDrives: string[] = [];
+let restartRequired = false;
+
+mainEvents.on('diagnostics-event', (payload) => {
+  if (payload.id !== 'wsl-drives') {
+    return;
+  }
+  drivesHealthy = payload.healthy;
+  unmountedDrives = payload.unmounted;
+  restartRequired = payload.restartRequired;
+  mainEvents.invoke('diagnostics-trigger', instance.id);
+});
+
+/**
+ * WSLDrives is a diagnostic that is emitted when Windows drives are not
+ * mounted in the WSL distribution (and could not be remounted); when this
+ * happens, containers can't bind mount Windows paths.  The state is updated
+ * each time the backend starts.
+ */
+class WSLDrives implements DiagnosticsChecker {
+  readonly id = 'WSL_DRIVES_MOUNTED';
+  readonly category = DiagnosticsCategory.ContainerEngine;
+  applicable(): Promise<boolean> {
+    return Promise.resolve(process.platform === 'win32');
+  }
+
+  check(): Promise<DiagnosticsCheckerResult> {
+    if (drivesHealthy) {
+      return Promise.resolve({
+        passed: true, description: 'Windows drives are mounted in WSL.', fixes: [],
+      });
+    }
+
+    const drives = unmountedDrives.map(letter => `${ letter.toUpperCase() }:`);
+    const subject = drives.length === 0 ? 'Windows drives are' : drives.length === 1 ? `${ drives[0] } is` : `${ drives.join(', ') } are`;
+    const fixes = [];
+
+    if (restartRequired) {
+      fixes.push({ description: 'Restart WSL with `wsl --shutdown`, then restart Rancher Desktop.' });
+    }
+    fixes.push({ description: 'Check that `automount` is enabled in `/etc/wsl.conf` in the `rancher-desktop` distribution.' });
+
+    return Promise.resolve({
+      passed:      false,
+      description: `${ subject } not mounted in WSL; containers can't use volumes from Windows paths.`,
+      fixes,
+    });
+  }
+}
+
+const instance = new WSLDrives();
+
+export default instance;
@@ -0,0 +1,52 @@
+import _ from 'lodash';
+
+import { getCatalogExtensions } from '../catalog';
+
+import { ContainerEngine, defaultSettings } from '@pkg/config/settings';
+import { demoMarketplace } from '@pkg/utils/_demo_marketplace_items';
+
+describe('getCatalogExtensions', () => {
+  const makeSettings = (overrides: any) => _.merge({}, defaultSettings, overrides);
+
+  it('should list every extension in the catalog', () => {
+    const catalog = getCatalogExtensions(makeSettings({}), 'linux', 'x64');
+
+    expect(catalog.map(ext => ext.id)).toEqual(demoMarketplace.summaries.map(item => item.slug));
+    expect(catalog.every(ext => ext.compatible && ext.allowed)).toBeTruthy();
+  });
+
+  it('should mark extensions that need moby as incompatible with containerd', () => {
+    const cfg = makeSettings({ containerEngine: { name: ContainerEngine.CONTAINERD } });
+    const catalog = getCatalogExtensions(cfg, 'linux', 'x64');
+    const diveIn = catalog.find(ext => ext.id === 'prakhar1989/dive-in');
+    const epinio = catalog.find(ext => ext.id === 'ghcr.io/rancher-sandbox/epinio-desktop-extension');
+
+    expect(diveIn).toMatchObject({
+      compatible:          false,
+      incompatibleReasons: ['requires the dockerd (moby) container engine'],
+    });
+    expect(epinio).toMatchObject({ compatible: true, incompatibleReasons: [] });
+  });
+
+  it('should apply the allow list', () => {
+    const cfg = makeSettings({
+      application: {
+        extensions: {
+          allowed: { enabled: true, list: ['docker/logs-explorer-extension'] },
+        },
+      },
+    });
+    const catalog = getCatalogExtensions(cfg, 'linux', 'x64');
+
+    expect(catalog.find(ext => ext.id === 'docker/logs-explorer-extension')?.allowed).toBeTruthy();
+    expect(catalog.find(ext => ext.id === 'prakhar1989/dive-in')?.allowed).toBeFalsy();
+  });
+
+  it('should report installed versions', () => {
+    const cfg = makeSettings({ application: { extensions: { installed: { 'julianb90/tachometer': '1.2.3' } } } });
+    const catalog = getCatalogExtensions(cfg, 'linux', 'x64');
+
+    expect(catalog.find(ext => ext.id === 'julianb90/tachometer')?.installedVersion).toEqual('1.2.3');
+    expect(catalog.find(ext => ext.id === 'prakhar1989/dive-in')).not.toHaveProperty('installedVersion');
+  });
+});
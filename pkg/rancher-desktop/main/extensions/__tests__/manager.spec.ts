@@ -36,4 +36,28 @@ describe('ExtensionManagerImpl', () => {
       }
     });
   });
+
+  describe('getVersions', () => {
+    let subject: ExtensionManagerImpl;
+
+    beforeEach(() => {
+      subject = new ExtensionManagerImpl({ getTags: jest.fn() } as any, false);
+    });
+
+    test.each<[string[], string[]]>([
+      // Newest semver first
+      [['0.0.1', '0.0.3', '0.0.2'], ['0.0.3', '0.0.2', '0.0.1']],
+      // Prefixes are ignored for ordering
+      [['v0.1.0', 'v.0.2.0', '0.0.1'], ['v.0.2.0', 'v0.1.0', '0.0.1']],
+      // Other tags come last
+      [['latest', '1.0.0', 'edge'], ['1.0.0', 'edge', 'latest']],
+      // No tags available
+      [[], []],
+    ])('%s => %s', async(versions, expected) => {
+      jest.spyOn(subject.client, 'getTags').mockImplementation(() => {
+        return Promise.resolve(new Set(versions));
+      });
+      await expect(subject.getVersions('')).resolves.toEqual(expected);
+    });
+  });
 });
@@ -0,0 +1,92 @@
+import { ExtensionImpl } from './extensions';
+
+import { ContainerEngine, Settings } from '@pkg/config/settings';
+import { demoMarketplace } from '@pkg/utils/_demo_marketplace_items';
+import { RecursiveReadonly } from '@pkg/utils/typeUtils';
+
+/**
+ * CatalogExtension describes an extension in the marketplace catalog, as
+ * returned by the `/v1/extensions/catalog` API.
+ */
+export interface CatalogExtension {
+  /** The image reference of the extension, without a tag. */
+  id: string;
+  name: string;
+  publisher: string;
+  description: string;
+  /** Whether the extension works with the containerd container engine. */
+  containerdCompatible: boolean;
+  /** The operating systems the extension supports; empty means all. */
+  operatingSystems: string[];
+  /** The CPU architectures the extension supports; empty means all. */
+  architectures: string[];
+  /** Whether the extension can be used with the current configuration. */
+  compatible: boolean;
+  /** If not compatible, the reasons why. */
+  incompatibleReasons: string[];
+  /** Whether the extension allow list (if enabled) permits installing it. */
+  allowed: boolean;
+  /** The installed version (tag), if the extension is installed. */
+  installedVersion?: string;
+}
+
+/** Map process.platform to the names used in the catalog. */
+const platformNames: Partial<Record<NodeJS.Platform, string>> = { win32: 'windows' };
+/** Map process.arch to the names used in the catalog. */
+const archNames: Partial<Record<NodeJS.Architecture, string>> = { x64: 'amd64' };
+
+/**
+ * Get the extensions in the marketplace catalog, with their compatibility with
+ * the given settings on this machine.
+ */
+export function getCatalogExtensions(
+  cfg: RecursiveReadonly<Settings>,
+  platform: NodeJS.Platform = process.platform,
+  arch: NodeJS.Architecture = process.arch,
+): CatalogExtension[] {
+  const { enabled, list } = cfg.application.extensions.allowed;
+  const installed = cfg.application.extensions.installed;
+  const platformName = platformNames[platform] ?? platform;
+  const archName = archNames[arch] ?? arch;
+
+  return demoMarketplace.summaries.map((item) => {
+    const operatingSystems = item.operating_systems as string[];
+    const architectures = item.architectures as string[];
+    const incompatibleReasons: string[] = [];
+    let allowed = true;
+
+    if (cfg.containerEngine.name === ContainerEngine.CONTAINERD && !item.containerd_compatible) {
+      incompatibleReasons.push('requires the dockerd (moby) container engine');
+    }
+    if (operatingSystems.length > 0 && !operatingSystems.includes(platformName)) {
+      incompatibleReasons.push(`not available on ${ platformName }`);
+    }
+    if (architectures.length > 0 && !architectures.includes(archName)) {
+      incompatibleReasons.push(`not available on ${ archName }`);
+    }
+    try {
+      ExtensionImpl.checkInstallAllowed(enabled ? list : undefined, item.slug);
+    } catch {
+      allowed = false;
+    }
+
+    const result: CatalogExtension = {
+      id:                   item.slug,
+      name:                 item.name,
+      publisher:            item.publisher.name,
+      description:          item.short_description,
+      containerdCompatible: item.containerd_compatible,
+      operatingSystems,
+      architectures,
+      compatible:           incompatibleReasons.length === 0,
+      incompatibleReasons,
+      allowed,
+    };
+
+    if (item.slug in installed) {
+      result.installedVersion = installed[item.slug];
+    }
+
+    return result;
+  });
+}
@@ -182,7 +182,7 @@ export class ExtensionImpl implements Extension {
    * extension allow list.
    * @throws If the image is not allowed to be installed.
    */
-  protected static checkInstallAllowed(allowedImages: readonly string[] | undefined, image: string) {
+  static checkInstallAllowed(allowedImages: readonly string[] | undefined, image: string) {
     const desired = parseImageReference(image);
     const code = ExtensionErrorCode.INSTALL_DENIED;
     const prefix = `Disallowing install of ${ image }:`;
@@ -389,6 +389,26 @@ export class ExtensionManagerImpl implements ExtensionManager {
       `Could not detect relevant version for image "${ imageName }"`);
   }
 
+  async getVersions(imageName: string): Promise<string[]> {
+    const tags = Array.from(await this.client.getTags(
+      imageName, { namespace: ExtensionImpl.extensionNamespace }));
+    const parse = (tag: string) => semver.parse(tag.replace(/^v\.?/i, ''));
+
+    // Semver tags come first (newest first), followed by any other tags.
+    return tags.sort((l, r) => {
+      const [lv, rv] = [parse(l), parse(r)];
+
+      if (lv && rv) {
+        return semver.rcompare(lv, rv);
+      }
+      if (lv || rv) {
+        return lv ? -1 : 1;
+      }
+
+      return l.localeCompare(r);
+    });
+  }
+
   async getInstalledExtensions() {
     // Get a list of all extensions, installed or not.
     const exts = Object.values(this.extensions).flatMap(group => Object.values(group));
@@ -143,6 +143,13 @@ export interface ExtensionManager {
    */
   getExtension(image: string, options?: { preferInstalled?: boolean }): Promise<Extension>;
 
+  /**
+   * Get the available versions (tags) of the given extension image, with the
+   * newest semver versions first.
+   * @param imageName The image reference of the extension, without a tag.
+   */
+  getVersions(imageName: string): Promise<string[]>;
+
   /**
    * Get a collection of all installed extensions.
    */
@@ -7,6 +7,7 @@ import { EventEmitter } from 'events';
 
 import type { VMBackend } from '@pkg/backend/backend';
 import type { Settings } from '@pkg/config/settings';
+import type { SettingsChange } from '@pkg/config/settingsImpl';
 import type { TransientSettings } from '@pkg/config/transientSettings';
 import { DiagnosticsCheckerResult } from '@pkg/main/diagnostics/types';
 import { RecursivePartial, RecursiveReadonly } from '@pkg/utils/typeUtils';
@@ -44,6 +45,15 @@ interface MainEventNames {
    */
   'settings-update'(settings: Settings): void;
 
+  /**
+   * Emitted after the settings have been written, describing what changed.
+   *
+   * @param source What requested the change: `api` for the command API, `ui`
+   * for the preferences window, or `application` for the app itself.
+   * @param changes The settings that changed.
+   */
+  'settings-changed'(source: 'api' | 'ui' | 'application', changes: SettingsChange[]): void;
+
   /**
    * Emitted to request that the settings be changed.
    *
@@ -167,7 +177,8 @@ interface MainEventNames {
  */
 type DiagnosticsEventPayload =
   { id: 'kube-versions-available', available: boolean } |
-  { id: 'path-management', fileName: string; error: Error | undefined };
+  { id: 'path-management', fileName: string; error: Error | undefined } |
+  { id: 'wsl-drives', healthy: boolean; unmounted: string[]; restartRequired: boolean };
 
 /**
  * Helper type definition to check if the given event name is a handler (i.e.
@@ -0,0 +1,124 @@
+import _ from 'lodash';
+
+import { Settings } from '@pkg/config/settings';
+import mainEvents from '@pkg/main/mainEvents';
+import { Snapshots } from '@pkg/main/snapshots/snapshots';
+import Logging from '@pkg/utils/logging';
+
+const console = Logging.snapshots;
+
+type ScheduleSettings = Settings['snapshots']['schedule'];
+
+/** How long to wait before trying again if a scheduled snapshot failed. */
+const RETRY_INTERVAL = 15 * 60 * 1_000;
+/** The longest delay setTimeout() supports; longer delays fire immediately. */
+const MAX_TIMEOUT = 2 ** 31 - 1;
+
+/**
+ * SnapshotScheduler creates snapshots periodically, as configured by the
+ * `snapshots.schedule` settings.  The snapshots themselves are created by
+ * `rdctl snapshot schedule run`, which also deletes old scheduled snapshots
+ * and skips the snapshot if nothing changed.
+ */
+export class SnapshotScheduler {
+  protected settings: ScheduleSettings | undefined;
+  protected timer: ReturnType<typeof setTimeout> | undefined;
+  protected running = false;
+  protected stopped = false;
+
+  constructor() {
+    mainEvents.on('settings-update', (cfg) => {
+      this.update(cfg.snapshots.schedule);
+    });
+  }
+
+  /**
+   * Update the schedule; this is called whenever the settings change.
+   */
+  update(settings: ScheduleSettings) {
+    if (_.isEqual(settings, this.settings)) {
+      return;
+    }
+    this.settings = { ...settings };
+    this.reschedule().catch((ex) => {
+      console.error('Failed to schedule the next snapshot:', ex);
+    });
+  }
+
+  /**
+   * Stop creating scheduled snapshots; this is called when the app quits.
+   */
+  stop() {
+    this.stopped = true;
+    clearTimeout(this.timer);
+    this.timer = undefined;
+  }
+
+  /**
+   * Set the timer for the next scheduled snapshot, based on when the most
+   * recent one was created.
+   */
+  protected async reschedule() {
+    clearTimeout(this.timer);
+    this.timer = undefined;
+
+    const settings = this.settings;
+
+    if (!settings?.enabled || this.running || this.stopped) {
+      return;
+    }
+
+    const snapshots = await Snapshots.list();
+    const lastCreated = Math.max(0, ...snapshots.filter(s => s.scheduled).map(s => Date.parse(s.created)));
+    const due = lastCreated + settings.intervalInHours * 60 * 60 * 1_000;
+
+    if (settings !== this.settings || this.running || this.stopped) {
+      // The settings changed while we were listing the snapshots.
+      return;
+    }
+    this.schedule(due - Date.now());
+  }
+
+  protected schedule(delay: number) {
+    delay = Math.min(Math.max(delay, 0), MAX_TIMEOUT);
+    console.log(`Next scheduled snapshot in ${ Math.round(delay / 60_000) } minutes`);
+    clearTimeout(this.timer);
+    this.timer = setTimeout(() => this.run(), delay);
+  }
+
+  protected async run() {
+    this.timer = undefined;
+    if (!this.settings?.enabled || this.stopped) {
+      return;
+    }
+    this.running = true;
+    try {
+      const result = await Snapshots.createScheduled();
+
+      if (result.skipped) {
+        console.log(`Skipped scheduled snapshot: nothing changed since "${ result.snapshot.name }"`);
+      } else {
+        console.log(`Created scheduled snapshot "${ result.snapshot.name }"`);
+      }
+      for (const name of result.deleted) {
+        console.log(`Deleted old scheduled snapshot "${ name }"`);
+      }
+    } catch (ex: any) {
+      // This can fail if the backend is busy (e.g. starting up); try again
+      // later rather than waiting for the whole interval.
+      console.error(`Failed to create scheduled snapshot: ${ ex?.message ?? ex }`);
+      this.running = false;
+      if (this.settings?.enabled && !this.stopped) {
+        this.schedule(RETRY_INTERVAL);
+      }
+
+      return;
+    }
+    this.running = false;
+    if (this.settings?.enabled) {
+      // A skipped snapshot doesn't change the time of the last scheduled
+      // snapshot, so wait for a full interval from now instead.
+      this.schedule(this.settings.intervalInHours * 60 * 60 * 1_000);
+    }
+  }
+}
@@ -1,7 +1,7 @@
 import { exec } from 'child_process';
 import util from 'util';
 
-import { Snapshot, SpawnResult } from '@pkg/main/snapshots/types';
+import { ScheduledSnapshotResult, Snapshot, SpawnResult } from '@pkg/main/snapshots/types';
 import { spawnFile } from '@pkg/utils/childProcess';
 import Logging from '@pkg/utils/logging';
 import { getRdctlPath } from '@pkg/utils/paths';
@@ -80,6 +80,21 @@ class SnapshotsImpl {
     }
   }
 
+  /**
+   * Create a scheduled snapshot, deleting old scheduled snapshots as needed.
+   * The retention settings are read by rdctl from the settings file.
+   */
+  async createScheduled(): Promise<ScheduledSnapshotResult> {
+    const args = ['snapshot', 'schedule', 'run', '--json'];
+    const response = await this.rdctl(args);
+
+    if (response.error) {
+      throw new SnapshotsError(args, response);
+    }
+
+    return JSON.parse(response.stdout);
+  }
+
   async restore(name: string) : Promise<void> {
     const args = ['snapshot', 'restore', name, '--json'];
     const response = await this.rdctl(args);
@@ -27,4 +27,15 @@ export interface Snapshot {
   name: string,
   created: string,
   description?: string,
+  /** Set for snapshots created by the snapshot scheduler. */
+  scheduled?: boolean,
+}
+
+/** The output of `rdctl snapshot schedule run --json`. */
+export interface ScheduledSnapshotResult {
+  snapshot: Snapshot,
+  /** Whether the snapshot was skipped because nothing changed. */
+  skipped: boolean,
+  /** The names of old scheduled snapshots that were deleted. */
+  deleted: string[],
 }
@@ -25,9 +25,10 @@ import fs from 'fs';
 import path from 'path';
 
 import ejs from 'ejs';
+import _ from 'lodash';
 import yaml from 'yaml';
 
-import { CURRENT_SETTINGS_VERSION } from '@pkg/config/settings';
+import { CURRENT_SETTINGS_VERSION, defaultSettings } from '@pkg/config/settings';
 
 interface commandFlagType {
   /**
@@ -123,10 +124,15 @@ class Generator {
   constructor() {
     this.commandFlags = [];
     this.settingsTree = { version: { type: 'int' } };
+    this.settingsSchema = {};
   }
 
   commandFlags: Array<commandFlagType>;
   settingsTree: settingsTreeType;
+  /**
+   * The JSON Schema of the settings document, as emitted by `rdctl settings schema`.
+   */
+  settingsSchema: Record<string, any>;
 
   protected async loadInput(inputFile: string): Promise<yamlObject> {
     const contents = (await fs.promises.readFile(inputFile)).toString();
@@ -150,6 +156,71 @@ class Generator {
     for (const propertyName of Object.keys(preferences.properties)) {
       this.walkProperty(propertyName, preferences.properties[propertyName], false, this.settingsTree);
     }
+    this.settingsSchema = this.buildSettingsSchema(preferences);
+  }
+
+  /**
+   * Convert the preferences spec into a JSON Schema document, annotated with
+   * the default value of each setting.
+   */
+  protected buildSettingsSchema(preferences: yamlObject): Record<string, any> {
+    const properties: Record<string, any> = { version: { type: 'integer', default: CURRENT_SETTINGS_VERSION } };
+
+    for (const propertyName of Object.keys(preferences.properties)) {
+      properties[propertyName] = this.convertToJSONSchema(preferences.properties[propertyName], propertyName);
+    }
+
+    return {
+      $schema: 'https://json-schema.org/draft/2020-12/schema',
+      title:   'Rancher Desktop settings',
+      type:    'object',
+      properties,
+    };
+  }
+
+  /**
+   * Convert a single preference from the spec into JSON Schema.
+   * @param preference The preference spec.
+   * @param propertyName The dotted name of the setting, used to look up its
+   * default value; not set for the items of arrays and maps.
+   */
+  protected convertToJSONSchema(preference: yamlObject, propertyName?: string): Record<string, any> {
+    const result: Record<string, any> = { type: preference.type };
+    const description = preference['x-rd-usage'] ?? preference.description;
+
+    if (description) {
+      result.description = description;
+    }
+    if (preference.enum) {
+      result.enum = preference.enum;
+    }
+    if (preference.properties) {
+      result.properties = _.mapValues(preference.properties,
+        (inner: yamlObject, innerName: string) => this.convertToJSONSchema(inner, propertyName && `${ propertyName }.${ innerName }`));
+    }
+    if (typeof preference.additionalProperties === 'object') {
+      result.additionalProperties = this.convertToJSONSchema(preference.additionalProperties);
+    } else if (preference.additionalProperties !== undefined) {
+      result.additionalProperties = preference.additionalProperties;
+    }
+    if (preference.items) {
+      result.items = this.convertToJSONSchema(preference.items);
+    }
+    // Objects with fixed properties get their defaults from those properties.
+    if (propertyName && (preference.type !== 'object' || preference.additionalProperties)) {
+      const defaultValue = _.get(defaultSettings, propertyName);
+
+      if (defaultValue !== undefined) {
+        result.default = defaultValue;
+      }
+    }
+    for (const extension of ['x-rd-platforms', 'x-rd-hidden', 'x-rd-aliases']) {
+      if (extension in preference) {
+        result[extension] = preference[extension];
+      }
+    }
+
+    return result;
   }
 
   protected async emitOutput(outputFile: string) {
@@ -158,10 +229,14 @@ class Generator {
 
     const linesForJSON = this.collectServerSettingsForJSON(this.settingsTree, true, '');
     const linesWithoutJSON = this.collectServerSettingsForJSON(this.settingsTree, false, '');
+    const settingsSchema = JSON.stringify(this.settingsSchema, undefined, 2);
+
+    assert(!settingsSchema.includes('`'), 'The settings schema must not contain backticks');
     const data = {
       commandFlags:     this.commandFlags,
       linesForJSON:     linesForJSON.join('\n'),
       linesWithoutJSON: linesWithoutJSON.join('\n'),
+      settingsSchema,
       settingsVersion:  CURRENT_SETTINGS_VERSION,
       kebabCase,
     };
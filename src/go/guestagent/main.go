@@ -54,8 +54,10 @@ var (
 		"file path for Containerd socket address")
 	k8sServiceListenerAddr = flag.String("k8sServiceListenerAddr", net.IPv4zero.String(),
 		"address to bind Kubernetes services to on the host, valid options are 0.0.0.0 or 127.0.0.1")
-	adminInstall = flag.Bool("adminInstall", false, "indicates if Rancher Desktop is installed as admin or not")
-	k8sAPIPort   = flag.String("k8sAPIPort", "6443",
+	adminInstall       = flag.Bool("adminInstall", false, "indicates if Rancher Desktop is installed as admin or not")
+	mirroredNetworking = flag.Bool("mirroredNetworking", false,
+		"indicates if WSL is using mirrored networking mode, where ports are exposed on the host by WSL")
+	k8sAPIPort = flag.String("k8sAPIPort", "6443",
 		"K8sAPI port number to forward to rancher-desktop wsl-proxy as a static portMapping event")
 	tapIfaceIP = flag.String("tap-interface-ip", "192.168.127.2",
 		"IP address for the tap interface eth0 in network namespace")
@@ -82,7 +84,8 @@ func main() {
 
 	log.Current = logger
 
-	log.Infof("Starting Rancher Desktop Agent in [AdminInstall=%t] mode", *adminInstall)
+	log.Infof("Starting Rancher Desktop Agent in [AdminInstall=%t] [MirroredNetworking=%t] mode",
+		*adminInstall, *mirroredNetworking)
 
 	if os.Geteuid() != 0 {
 		log.Fatal("agent must run as root")
@@ -113,7 +116,7 @@ func main() {
 	var portTracker tracker.Tracker
 
 	forwarder := forwarder.NewWSLProxyForwarder("/run/wsl-proxy.sock")
-	portTracker = tracker.NewAPITracker(ctx, forwarder, tracker.GatewayBaseURL, *tapIfaceIP, *adminInstall)
+	portTracker = tracker.NewAPITracker(ctx, forwarder, tracker.GatewayBaseURL, *tapIfaceIP, *adminInstall, *mirroredNetworking)
 	// Manually register the port for K8s API, we would
 	// only want to send this manual port mapping if both
 	// of the following conditions are met:
@@ -43,10 +43,16 @@ var (
 // corresponding API endpoints that is responsible for exposing
 // and unexposing the ports on the host. This should only be used when
 // the Rancher Desktop networking is enabled and the privileged service is disabled.
+//
+// When WSL is using mirrored networking mode, ports that wsl-proxy listens on
+// in the default namespace are already mirrored onto the host by WSL itself,
+// so the tracker does not call the API in that case; doing so would only
+// conflict with the ports WSL is mirroring.
 type APITracker struct {
 	context           context.Context
 	wslProxyForwarder forwarder.Forwarder
 	isAdmin           bool
+	mirrored          bool
 	baseURL           string
 	tapInterfaceIP    string
 	portStorage       *portStorage
@@ -65,11 +71,19 @@ type APITracker struct {
 //   - isAdmin: Indicates whether the application is running with administrative privileges. This flag determines
 //     whether the APITracker should use the localhost IP address (127.0.0.1) for operations if not running as an
 //     administrator.
-func NewAPITracker(ctx context.Context, wslProxyForwarder forwarder.Forwarder, baseURL, tapIfaceIP string, isAdmin bool) *APITracker {
+//   - mirrored: Indicates whether WSL is using mirrored networking mode. In that mode, the port mappings are only
+//     sent to the WSL proxy, bound to the same address that would have been used on the host.
+func NewAPITracker(
+	ctx context.Context,
+	wslProxyForwarder forwarder.Forwarder,
+	baseURL, tapIfaceIP string,
+	isAdmin, mirrored bool,
+) *APITracker {
 	return &APITracker{
 		context:           ctx,
 		wslProxyForwarder: wslProxyForwarder,
 		isAdmin:           isAdmin,
+		mirrored:          mirrored,
 		baseURL:           baseURL,
 		tapInterfaceIP:    tapIfaceIP,
 		portStorage:       newPortStorage(),
@@ -97,6 +111,12 @@ func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
 				continue
 			}
 
+			if a.mirrored {
+				tmpPortBinding = append(tmpPortBinding, portBinding)
+
+				continue
+			}
+
 			log.Debugf("exposing the following port binding: %+v", portBinding)
 
 			err = a.apiForwarder.Expose(
@@ -123,7 +143,7 @@ func (a *APITracker) Add(containerID string, portMap nat.PortMap) error {
 		a.portStorage.add(containerID, successfullyForwarded)
 		portMapping := guestagentTypes.PortMapping{
 			Remove: false,
-			Ports:  successfullyForwarded,
+			Ports:  a.wslProxyPorts(successfullyForwarded),
 		}
 		log.Debugf("forwarding to wsl-proxy to add port mapping: %+v", portMapping)
 		err := a.wslProxyForwarder.Send(portMapping)
@@ -161,6 +181,10 @@ func (a *APITracker) Remove(containerID string) error {
 				continue
 			}
 
+			if a.mirrored {
+				continue
+			}
+
 			log.Debugf("unexposing the following port binding: %+v", portBinding)
 
 			err = a.apiForwarder.Unexpose(
@@ -180,7 +204,7 @@ func (a *APITracker) Remove(containerID string) error {
 	if len(portMap) != 0 {
 		portMapping := guestagentTypes.PortMapping{
 			Remove: true,
-			Ports:  portMap,
+			Ports:  a.wslProxyPorts(portMap),
 		}
 		log.Debugf("forwarding to wsl-proxy to remove port mapping: %+v", portMapping)
 		err := a.wslProxyForwarder.Send(portMapping)
@@ -210,6 +234,10 @@ func (a *APITracker) RemoveAll() error {
 					continue
 				}
 
+				if a.mirrored {
+					continue
+				}
+
 				log.Debugf("unexposing the following port binding: %+v", portBinding)
 
 				err = a.apiForwarder.Unexpose(
@@ -227,7 +255,7 @@ func (a *APITracker) RemoveAll() error {
 
 		portMapping := guestagentTypes.PortMapping{
 			Remove: true,
-			Ports:  portMapping,
+			Ports:  a.wslProxyPorts(portMapping),
 		}
 
 		log.Debugf("forwarding to wsl-proxy to remove port mapping: %+v", portMapping)
@@ -262,6 +290,29 @@ func (a *APITracker) determineHostIP(hostIP string) string {
 	return hostIP
 }
 
+// wslProxyPorts returns the port mappings to send to the WSL proxy. In mirrored
+// networking mode, the WSL proxy listeners are what is visible on the host,
+// so they are bound to the address that would otherwise have been exposed.
+func (a *APITracker) wslProxyPorts(portMap nat.PortMap) nat.PortMap {
+	if !a.mirrored {
+		return portMap
+	}
+
+	result := make(nat.PortMap, len(portMap))
+
+	for portProto, portBindings := range portMap {
+		bindings := make([]nat.PortBinding, 0, len(portBindings))
+		for _, portBinding := range portBindings {
+			portBinding.HostIP = a.determineHostIP(portBinding.HostIP)
+			bindings = append(bindings, portBinding)
+		}
+
+		result[portProto] = bindings
+	}
+
+	return result
+}
+
 func ipPortBuilder(ip, port string) string {
 	return ip + ":" + port
 }
@@ -56,7 +56,7 @@ func TestBasicAdd(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 	portMapping := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
 			{
@@ -92,7 +92,7 @@ func TestAddOverride(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 	portMapping := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
 			{
@@ -183,7 +183,7 @@ func TestAddWithError(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 	portMapping := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
 			{
@@ -278,7 +278,7 @@ func TestGet(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 	err := apiTracker.Add(containerID, portMapping)
 	require.NoError(t, err)
 
@@ -306,7 +306,7 @@ func TestRemove(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 	portMapping1 := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
 			{
@@ -370,7 +370,7 @@ func TestRemoveWithError(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 
 	portMapping := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
@@ -430,7 +430,7 @@ func TestRemoveAll(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 
 	portMapping1 := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
@@ -494,7 +494,7 @@ func TestRemoveAllWithError(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, true, false)
 
 	portMapping1 := nat.PortMap{
 		"80/tcp": []nat.PortBinding{
@@ -574,7 +574,7 @@ func TestNonAdminInstall(t *testing.T) {
 	testSrv := httptest.NewServer(mux)
 	defer testSrv.Close()
 
-	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, false)
+	apiTracker := tracker.NewAPITracker(context.Background(), &testForwarder{}, testSrv.URL, hostSwitchIP, false, false)
 
 	portMapping := nat.PortMap{
 		"1025/tcp": []nat.PortBinding{
@@ -611,6 +611,59 @@ func TestNonAdminInstall(t *testing.T) {
 	assert.Nil(t, portMapping)
 }
 
+func TestAddRemoveMirrored(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/services/forwarder/expose", func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("expose API should not be called in mirrored networking mode")
+	})
+	mux.HandleFunc("/services/forwarder/unexpose", func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("unexpose API should not be called in mirrored networking mode")
+	})
+
+	testSrv := httptest.NewServer(mux)
+	defer testSrv.Close()
+
+	wslProxyForwarder := &testForwarder{}
+	apiTracker := tracker.NewAPITracker(context.Background(), wslProxyForwarder, testSrv.URL, hostSwitchIP, false, true)
+
+	portMapping := nat.PortMap{
+		"1025/tcp": []nat.PortBinding{
+			{
+				HostIP:   "0.0.0.0",
+				HostPort: "1025",
+			},
+		},
+	}
+
+	err := apiTracker.Add(containerID, portMapping)
+	require.NoError(t, err)
+
+	// The tracker keeps the original port mapping, but the WSL proxy listens
+	// on localhost, as the host would have for a non-admin install.
+	assert.Equal(t, portMapping, apiTracker.Get(containerID))
+
+	expectedPorts := nat.PortMap{
+		"1025/tcp": []nat.PortBinding{
+			{
+				HostIP:   "127.0.0.1",
+				HostPort: "1025",
+			},
+		},
+	}
+
+	err = apiTracker.Remove(containerID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []guestagentType.PortMapping{
+		{Remove: false, Ports: expectedPorts},
+		{Remove: true, Ports: expectedPorts},
+	}, wslProxyForwarder.receivedPortMappings)
+	assert.Nil(t, apiTracker.Get(containerID))
+}
+
 func ipPortBuilder(ip, port string) string {
 	return ip + ":" + port
 }
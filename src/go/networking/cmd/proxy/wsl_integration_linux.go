@@ -20,11 +20,13 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/log"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/portproxy"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
 )
 
 var (
@@ -33,6 +35,14 @@ var (
 	socketFile   string
 	upstreamAddr string
 	udpBuffer    int
+	dialTimeout  time.Duration
+	dialRetries  int
+	dialBackoff  time.Duration
+	proxyProto   bool
+	connBps      int
+	totalBps     int
+	idleTimeout  time.Duration
+	bufferSize   int
 )
 
 const (
@@ -41,6 +51,9 @@ const (
 	bridgeIPAddr   = "192.168.143.1"
 	// Set UDP buffer size to 8 MB
 	defaultUDPBufferSize = 8 * 1024 * 1024 // 8 MB in bytes
+	defaultDialTimeout   = 5 * time.Second
+	defaultDialRetries   = 3
+	defaultDialBackoff   = 200 * time.Millisecond
 )
 
 func main() {
@@ -49,6 +62,14 @@ func main() {
 	flag.StringVar(&socketFile, "socketFile", defaultSocket, "path to the .sock file for UNIX socket")
 	flag.StringVar(&upstreamAddr, "upstreamAddress", bridgeIPAddr, "IP address of the upstream server to forward to")
 	flag.IntVar(&udpBuffer, "udpBuffer", defaultUDPBufferSize, "max buffer size in bytes for UDP socket I/O")
+	flag.DurationVar(&dialTimeout, "dialTimeout", defaultDialTimeout, "timeout for each attempt to connect to the upstream server")
+	flag.IntVar(&dialRetries, "dialRetries", defaultDialRetries, "number of times to retry connecting to the upstream server")
+	flag.DurationVar(&dialBackoff, "dialBackoff", defaultDialBackoff, "delay before the first retry connecting to the upstream server, doubling each retry")
+	flag.BoolVar(&proxyProto, "proxyProtocol", false, "send a PROXY protocol v2 header with the original client address to the upstream server")
+	flag.IntVar(&connBps, "bandwidthLimit", 0, "maximum bytes per second for each forwarded connection (0 for no limit)")
+	flag.IntVar(&totalBps, "totalBandwidthLimit", 0, "maximum bytes per second for all forwarded connections combined (0 for no limit)")
+	flag.DurationVar(&idleTimeout, "idleTimeout", 0, "close forwarded connections with no traffic for this long (0 to never close them)")
+	flag.IntVar(&bufferSize, "copyBufferSize", utils.DefaultBufferSize, "size in bytes of the buffers used to copy forwarded TCP data")
 	flag.Parse()
 
 	setupLogging(logFile)
@@ -61,6 +82,18 @@ func main() {
 	proxyConfig := &portproxy.ProxyConfig{
 		UpstreamAddress: upstreamAddr,
 		UDPBufferSize:   udpBuffer,
+		PipeOptions: utils.PipeOptions{
+			DialTimeout:   dialTimeout,
+			DialRetries:   dialRetries,
+			DialBackoff:   dialBackoff,
+			ProxyProtocol: proxyProto,
+			RelayOptions: utils.RelayOptions{
+				BandwidthLimit:  connBps,
+				SharedBandwidth: utils.NewBandwidthLimiter(totalBps),
+				IdleTimeout:     idleTimeout,
+				BufferPool:      utils.NewBufferPool(bufferSize),
+			},
+		},
 	}
 	proxy := portproxy.NewPortProxy(socket, proxyConfig)
 
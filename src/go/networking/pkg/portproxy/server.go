@@ -15,12 +15,14 @@ limitations under the License.
 package portproxy
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	gvisorTypes "github.com/containers/gvisor-tap-vsock/pkg/types"
 	"github.com/docker/go-connections/nat"
@@ -32,12 +34,26 @@ import (
 type ProxyConfig struct {
 	UpstreamAddress string
 	UDPBufferSize   int
+	// UDPSessionTimeout is how long a UDP client may be idle before its
+	// session is discarded; if zero, utils.DefaultUDPSessionTimeout is used.
+	UDPSessionTimeout time.Duration
+	// PipeOptions control how forwarded TCP connections are made.  If no
+	// Observer is set, the proxy tracks connections for ActiveConnections.
+	PipeOptions utils.PipeOptions
 }
 
 type PortProxy struct {
 	config   *ProxyConfig
 	listener net.Listener
 	quit     chan struct{}
+	// ctx is cancelled on Close, to tear down forwarded connections.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// pipeOptions are the options used to forward TCP connections.
+	pipeOptions utils.PipeOptions
+	// connections tracks forwarded TCP connections; it is nil if the
+	// configuration has its own observer.
+	connections *utils.PipeTracker
 	// map of TCP port number as a key to associated listener
 	activeListeners map[int]net.Listener
 	listenerMutex   sync.Mutex
@@ -48,16 +64,41 @@ type PortProxy struct {
 }
 
 func NewPortProxy(listener net.Listener, cfg *ProxyConfig) *PortProxy {
+	ctx, cancel := context.WithCancel(context.Background())
 	portProxy := &PortProxy{
 		config:          cfg,
 		listener:        listener,
 		quit:            make(chan struct{}),
+		ctx:             ctx,
+		cancel:          cancel,
 		activeListeners: make(map[int]net.Listener),
 		activeUDPConns:  make(map[int]*net.UDPConn),
+		pipeOptions:     cfg.PipeOptions,
+	}
+	if portProxy.pipeOptions.Observer == nil {
+		portProxy.connections = utils.NewPipeTracker()
+		portProxy.pipeOptions.Observer = portProxy.connections
 	}
 	return portProxy
 }
 
+// ActiveConnections returns the forwarded TCP connections that are currently
+// open, oldest first.
+func (p *PortProxy) ActiveConnections() []utils.PipeInfo {
+	if p.connections == nil {
+		return nil
+	}
+	return p.connections.Active()
+}
+
+// ConnectionStats returns totals for the forwarded TCP connections.
+func (p *PortProxy) ConnectionStats() utils.PipeStats {
+	if p.connections == nil {
+		return utils.PipeStats{}
+	}
+	return p.connections.Stats()
+}
+
 func (p *PortProxy) Start() error {
 	logrus.Infof("Proxy server started accepting on %s, forwarding to %s", p.listener.Addr(), p.config.UpstreamAddress)
 	for {
@@ -144,53 +185,24 @@ func (p *PortProxy) handleUDP(portBindings []nat.PortBinding, remove bool) {
 		}
 
 		forwardAddr := net.JoinHostPort(p.config.UpstreamAddress, portBinding.HostPort)
-		targetAddr, err := net.ResolveUDPAddr("udp", forwardAddr)
-		if err != nil {
-			c.Close()
-			logrus.Errorf("failed to resolve UDP target address [%s]: %s", targetAddr, err)
-			continue
-		}
 
 		p.udpConnMutex.Lock()
 		p.activeUDPConns[port] = c
 		p.udpConnMutex.Unlock()
 		logrus.Debugf("created UDPConn for: %v", sourceAddr)
 
-		go p.acceptUDPConn(c, targetAddr)
-	}
-}
-
-func (p *PortProxy) acceptUDPConn(sourceConn *net.UDPConn, targetAddr *net.UDPAddr) {
-	targetConn, err := net.DialUDP("udp", nil, targetAddr)
-	if err != nil {
-		logrus.Errorf("failed to connect to target address: %s : %s", targetAddr, err)
-		return
-	}
-	defer targetConn.Close()
-	p.wg.Add(1)
-	for {
-		b := make([]byte, p.config.UDPBufferSize)
-		n, addr, err := sourceConn.ReadFromUDP(b)
-		if err != nil && n == 0 {
-			logrus.Errorf("error reading UDP packet from source: %s : %s", addr, err)
-			if errors.Is(err, net.ErrClosed) {
-				p.wg.Done()
-				break
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			options := utils.UDPRelayOptions{
+				SessionTimeout: p.config.UDPSessionTimeout,
+				BufferSize:     p.config.UDPBufferSize,
 			}
-			continue
-		}
-		logrus.Debugf("received %d data from %s", n, addr)
-
-		n, err = targetConn.Write(b[:n])
-		if err != nil {
-			logrus.Errorf("error forwarding UDP packet to target: %s : %s", targetAddr, err)
-			if errors.Is(err, net.ErrClosed) {
-				p.wg.Done()
-				break
+			err := utils.RelayUDP(p.ctx, c, forwardAddr, options)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logrus.Errorf("failed to relay UDP packets to %s: %s", forwardAddr, err)
 			}
-			continue
-		}
-		logrus.Debugf("sent %d data to %s", n, targetAddr)
+		}()
 	}
 }
 
@@ -245,7 +257,19 @@ func (p *PortProxy) acceptTraffic(listener net.Listener, port string) {
 		go func(conn net.Conn) {
 			defer p.wg.Done()
 			defer conn.Close()
-			utils.Pipe(conn, forwardAddr)
+			result, err := utils.PipeWithOptions(p.ctx, conn, forwardAddr, p.pipeOptions)
+			if errors.Is(err, utils.ErrIdleTimeout) {
+				logrus.Debugf("port proxy closed idle connection to %s", forwardAddr)
+			} else if err != nil && !errors.Is(err, context.Canceled) {
+				logrus.Errorf("port proxy failed to forward connection to %s: %s", forwardAddr, err)
+				return
+			}
+			logrus.Debugf("port proxy connection to %s closed after %s: %d bytes sent, %d bytes received",
+				forwardAddr, result.Duration, result.BytesUpstream, result.BytesDownstream)
+			if result.UpstreamErr != nil || result.DownstreamErr != nil {
+				logrus.Debugf("port proxy connection to %s errors: sending: %v, receiving: %v",
+					forwardAddr, result.UpstreamErr, result.DownstreamErr)
+			}
 		}(conn)
 	}
 }
@@ -266,6 +290,9 @@ func (p *PortProxy) Close() error {
 	// Signal the quit channel to stop accepting new connections.
 	close(p.quit)
 
+	// Tear down forwarded connections, rather than waiting for them to close.
+	p.cancel()
+
 	// Wait for all pending connections to finish.
 	p.wg.Wait()
 
@@ -0,0 +1,67 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"sync"
+)
+
+// DefaultBufferSize is the size of the buffers in DefaultBufferPool.
+const DefaultBufferSize = 32 * 1024
+
+// DefaultBufferPool is the buffer pool used by Relay and Pipe if
+// RelayOptions.BufferPool is not set.
+var DefaultBufferPool = NewBufferPool(DefaultBufferSize)
+
+// BufferPool is a pool of fixed-size byte buffers used for copying data, so
+// that each connection does not need to allocate its own buffers.  It is also
+// used by the docker proxy in wsl-helper, and implements httputil.BufferPool.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool creates a pool of buffers of the given size; if the size is not
+// positive, DefaultBufferSize is used.
+func NewBufferPool(size int) *BufferPool {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	p := &BufferPool{size: size}
+	p.pool.New = func() any {
+		buf := make([]byte, p.size)
+		return &buf
+	}
+	return p
+}
+
+// Size returns the size of the buffers in the pool.
+func (p *BufferPool) Size() int {
+	return p.size
+}
+
+// Get a buffer from the pool.
+func (p *BufferPool) Get() []byte {
+	return *p.pool.Get().(*[]byte)
+}
+
+// Put a buffer back in the pool; buffers of the wrong size are discarded.
+func (p *BufferPool) Put(buf []byte) {
+	if cap(buf) != p.size {
+		return
+	}
+	buf = buf[:p.size]
+	p.pool.Put(&buf)
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := utils.NewBufferPool(16)
+	require.Equal(t, 16, pool.Size())
+	buf := pool.Get()
+	require.Len(t, buf, 16)
+	pool.Put(buf[:4])
+	require.Len(t, pool.Get(), 16, "truncated buffers should be restored to full size")
+	// Buffers of the wrong size are not returned to the pool.
+	pool.Put(make([]byte, 8))
+	require.Len(t, pool.Get(), 16)
+	require.Equal(t, utils.DefaultBufferSize, utils.NewBufferPool(0).Size())
+}
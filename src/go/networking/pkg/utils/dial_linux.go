@@ -0,0 +1,45 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/linuxkit/virtsock/pkg/vsock"
+)
+
+// dialPlatform connects to addresses on networks that are specific to the
+// platform: "vsock", with an address of "cid:port".
+func dialPlatform(_ context.Context, network, address string) (net.Conn, error) {
+	if network != "vsock" {
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	cidString, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vsock address %q: %w", address, err)
+	}
+	cid, err := strconv.ParseUint(cidString, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vsock CID %q: %w", cidString, err)
+	}
+	port, err := strconv.ParseUint(portString, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vsock port %q: %w", portString, err)
+	}
+	return vsock.Dial(uint32(cid), uint32(port))
+}
@@ -0,0 +1,29 @@
+//go:build !linux && !windows
+
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// dialPlatform connects to addresses on networks that are specific to the
+// platform; there are none on this platform.
+func dialPlatform(_ context.Context, network, _ string) (net.Conn, error) {
+	return nil, fmt.Errorf("unsupported network %q", network)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// PipeInfo describes a relayed connection for a PipeObserver.
+type PipeInfo struct {
+	// ID uniquely identifies the connection within the process.
+	ID uint64
+	// Source is the address of the client.
+	Source net.Addr
+	// Upstream is the address being connected to.
+	Upstream string
+	// Opened is when the connection was accepted.
+	Opened time.Time
+}
+
+// PipeObserver receives notifications about relayed connections, for example
+// to report metrics or show the active connections.  Methods may be called
+// concurrently for different connections.
+type PipeObserver interface {
+	// PipeOpened is called before connecting upstream.
+	PipeOpened(info PipeInfo)
+	// PipeClosed is called exactly once for each PipeOpened call, once both
+	// connections are closed (or connecting upstream failed).  errorClass is
+	// the result of ErrorClass for the returned error.
+	PipeClosed(info PipeInfo, result PipeResult, errorClass string)
+}
+
+// Error classes returned by ErrorClass.
+const (
+	ErrorClassNone     = ""
+	ErrorClassCanceled = "canceled"
+	ErrorClassIdle     = "idle"
+	ErrorClassTimeout  = "timeout"
+	ErrorClassRefused  = "refused"
+	ErrorClassReset    = "reset"
+	ErrorClassOther    = "other"
+)
+
+// ErrorClass returns a coarse classification of an error from Pipe, suitable
+// for use as a metrics label.
+func ErrorClass(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, ErrIdleTimeout):
+		return ErrorClassIdle
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorClassReset
+	}
+	return ErrorClassOther
+}
+
+// pipeID is the last ID assigned to a relayed connection.
+var pipeID atomic.Uint64
+
+// PipeTracker is a PipeObserver that keeps track of the active connections and
+// totals for the closed ones.
+type PipeTracker struct {
+	mu     sync.Mutex
+	active map[uint64]PipeInfo
+	stats  PipeStats
+}
+
+// PipeStats are totals over the connections seen by a PipeTracker.
+type PipeStats struct {
+	// Opened is the number of connections opened.
+	Opened uint64
+	// Closed is the number of connections closed.
+	Closed uint64
+	// BytesUpstream is the number of bytes sent upstream by closed
+	// connections.
+	BytesUpstream int64
+	// BytesDownstream is the number of bytes received from upstream by closed
+	// connections.
+	BytesDownstream int64
+	// Errors is the number of closed connections for each error class; it
+	// does not include connections that closed without errors.
+	Errors map[string]uint64
+}
+
+// NewPipeTracker creates a new, empty, PipeTracker.
+func NewPipeTracker() *PipeTracker {
+	return &PipeTracker{active: make(map[uint64]PipeInfo)}
+}
+
+// PipeOpened implements PipeObserver.
+func (t *PipeTracker) PipeOpened(info PipeInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active[info.ID] = info
+	t.stats.Opened++
+}
+
+// PipeClosed implements PipeObserver.
+func (t *PipeTracker) PipeClosed(info PipeInfo, result PipeResult, errorClass string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.active, info.ID)
+	t.stats.Closed++
+	t.stats.BytesUpstream += result.BytesUpstream
+	t.stats.BytesDownstream += result.BytesDownstream
+	if errorClass != ErrorClassNone {
+		if t.stats.Errors == nil {
+			t.stats.Errors = make(map[string]uint64)
+		}
+		t.stats.Errors[errorClass]++
+	}
+}
+
+// Active returns the currently open connections, oldest first.
+func (t *PipeTracker) Active() []PipeInfo {
+	t.mu.Lock()
+	result := make([]PipeInfo, 0, len(t.active))
+	for _, info := range t.active {
+		result = append(result, info)
+	}
+	t.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Stats returns a snapshot of the totals.
+func (t *PipeTracker) Stats() PipeStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats
+	if t.stats.Errors != nil {
+		stats.Errors = make(map[string]uint64, len(t.stats.Errors))
+		for class, count := range t.stats.Errors {
+			stats.Errors[class] = count
+		}
+	}
+	return stats
+}
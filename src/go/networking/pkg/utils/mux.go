@@ -0,0 +1,168 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	"github.com/sirupsen/logrus"
+)
+
+// muxHeaderTimeout is how long the peer has to name the service after opening
+// a stream.
+const muxHeaderTimeout = 10 * time.Second
+
+// maxMuxServiceLength is the longest service name that can be used.
+const maxMuxServiceLength = 255
+
+// Multiplexer carries multiple streams, each for a named service (such as the
+// docker API, containerd, or log streaming), over a single transport
+// connection (such as vsock or a unix socket) between the host and the VM.
+// Either side may open streams.
+type Multiplexer struct {
+	session *yamux.Session
+	// accepted receives the streams opened by the other side, once the
+	// service they are for is known.
+	accepted chan acceptedStream
+}
+
+// acceptedStream is a stream opened by the other side, and its service.
+type acceptedStream struct {
+	service string
+	stream  net.Conn
+}
+
+// NewMultiplexer starts multiplexing over the given transport; one side of the
+// transport must be the client, and the other not.  The multiplexer takes
+// ownership of the transport, and closes it when the multiplexer is closed.
+func NewMultiplexer(transport io.ReadWriteCloser, client bool) (*Multiplexer, error) {
+	config := yamux.DefaultConfig()
+	config.LogOutput = nil
+	config.Logger = logrus.StandardLogger()
+	var session *yamux.Session
+	var err error
+	if client {
+		session, err = yamux.Client(transport, config)
+	} else {
+		session, err = yamux.Server(transport, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to start multiplexer: %w", err)
+	}
+	m := &Multiplexer{session: session, accepted: make(chan acceptedStream)}
+	go m.acceptStreams(config.AcceptBacklog)
+	return m, nil
+}
+
+// Open a new stream to the given service on the other side.
+func (m *Multiplexer) Open(ctx context.Context, service string) (net.Conn, error) {
+	if len(service) == 0 || len(service) > maxMuxServiceLength {
+		return nil, fmt.Errorf("invalid service name %q", service)
+	}
+	stream, err := m.session.OpenStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream for %s: %w", service, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = stream.SetWriteDeadline(deadline)
+		defer func() { _ = stream.SetWriteDeadline(time.Time{}) }()
+	}
+	header := append([]byte{byte(len(service))}, service...)
+	if _, err := stream.Write(header); err != nil {
+		_ = stream.Close()
+		return nil, fmt.Errorf("failed to open stream for %s: %w", service, err)
+	}
+	return stream, nil
+}
+
+// Accept the next stream opened by the other side, returning the service it
+// is for.  It returns net.ErrClosed once the multiplexer is closed.
+func (m *Multiplexer) Accept(ctx context.Context) (string, net.Conn, error) {
+	select {
+	case accepted := <-m.accepted:
+		return accepted.service, accepted.stream, nil
+	case <-m.session.CloseChan():
+		return "", nil, net.ErrClosed
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	}
+}
+
+// acceptStreams accepts streams opened by the other side until the
+// multiplexer is closed, reading the header of each in its own goroutine so
+// that a peer slow to name the service does not hold up other streams.  At
+// most backlog streams are kept waiting for their header or for Accept.
+func (m *Multiplexer) acceptStreams(backlog int) {
+	pending := make(chan struct{}, backlog)
+	for {
+		pending <- struct{}{}
+		stream, err := m.session.AcceptStream()
+		if err != nil {
+			if !errors.Is(err, yamux.ErrSessionShutdown) {
+				logrus.Debugf("failed to accept multiplexed stream: %s", err)
+			}
+			return
+		}
+		go func() {
+			defer func() { <-pending }()
+			service, err := readMuxHeader(stream)
+			if err != nil {
+				logrus.Debugf("discarding multiplexed stream: %s", err)
+				_ = stream.Close()
+				return
+			}
+			select {
+			case m.accepted <- acceptedStream{service: service, stream: stream}:
+			case <-m.session.CloseChan():
+				_ = stream.Close()
+			}
+		}()
+	}
+}
+
+// readMuxHeader reads the name of the service from a newly opened stream.
+func readMuxHeader(stream *yamux.Stream) (string, error) {
+	_ = stream.SetReadDeadline(time.Now().Add(muxHeaderTimeout))
+	defer func() { _ = stream.SetReadDeadline(time.Time{}) }()
+	var length [1]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return "", fmt.Errorf("failed to read service name: %w", err)
+	}
+	service := make([]byte, length[0])
+	if _, err := io.ReadFull(stream, service); err != nil {
+		return "", fmt.Errorf("failed to read service name: %w", err)
+	}
+	if len(service) == 0 {
+		return "", errors.New("empty service name")
+	}
+	return string(service), nil
+}
+
+// Done returns a channel that is closed once the multiplexer is closed,
+// either explicitly or because the transport failed.
+func (m *Multiplexer) Done() <-chan struct{} {
+	return m.session.CloseChan()
+}
+
+// Close the multiplexer, all of its streams, and the transport.
+func (m *Multiplexer) Close() error {
+	return m.session.Close()
+}
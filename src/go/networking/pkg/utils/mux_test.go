@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/yamux"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiplexer(t *testing.T) {
+	hostTransport, guestTransport := net.Pipe()
+	host, err := utils.NewMultiplexer(hostTransport, true)
+	require.NoError(t, err)
+	defer host.Close()
+	guest, err := utils.NewMultiplexer(guestTransport, false)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		for {
+			service, stream, err := guest.Accept(ctx)
+			if err != nil {
+				return
+			}
+			go func() {
+				defer stream.Close()
+				_, _ = stream.Write([]byte(service + ":"))
+				_, _ = io.Copy(stream, stream)
+			}()
+		}
+	}()
+
+	exchange := func(service, message string) string {
+		stream, err := host.Open(ctx, service)
+		require.NoError(t, err)
+		defer stream.Close()
+		_, err = stream.Write([]byte(message))
+		require.NoError(t, err)
+		buf := make([]byte, len(service)+1+len(message))
+		_, err = io.ReadFull(stream, buf)
+		require.NoError(t, err)
+		return string(buf)
+	}
+	require.Equal(t, "docker:ping", exchange("docker", "ping"))
+	require.Equal(t, "containerd:hello", exchange("containerd", "hello"))
+
+	_, err = host.Open(ctx, "")
+	require.Error(t, err)
+
+	require.NoError(t, guest.Close())
+	select {
+	case <-host.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("multiplexer did not notice the other side closing")
+	}
+	_, _, err = guest.Accept(ctx)
+	require.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestMultiplexerSlowHeader(t *testing.T) {
+	hostTransport, guestTransport := net.Pipe()
+	// Drive the host side directly, so that it can open a stream without
+	// naming its service.
+	host, err := yamux.Client(hostTransport, nil)
+	require.NoError(t, err)
+	defer host.Close()
+	guest, err := utils.NewMultiplexer(guestTransport, false)
+	require.NoError(t, err)
+	defer guest.Close()
+
+	stalled, err := host.OpenStream()
+	require.NoError(t, err)
+	defer stalled.Close()
+	stream, err := host.OpenStream()
+	require.NoError(t, err)
+	defer stream.Close()
+	_, err = stream.Write(append([]byte{byte(len("docker"))}, "docker"...))
+	require.NoError(t, err)
+
+	// The stalled stream must not hold up the other one.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	service, accepted, err := guest.Accept(ctx)
+	require.NoError(t, err)
+	defer accepted.Close()
+	require.Equal(t, "docker", service)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
@@ -15,31 +15,175 @@ limitations under the License.
 package utils
 
 import (
-	"io"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net"
-
-	"github.com/sirupsen/logrus"
+	"time"
 )
 
-func Pipe(conn net.Conn, upstreamAddr string) {
-	upstream, err := net.Dial("tcp", upstreamAddr)
+// PipeResult describes the data copied by Pipe.
+type PipeResult struct {
+	// BytesUpstream is the number of bytes copied from the connection to
+	// upstream.
+	BytesUpstream int64
+	// BytesDownstream is the number of bytes copied from upstream to the
+	// connection.
+	BytesDownstream int64
+	// Duration is how long the connections were open.
+	Duration time.Duration
+	// UpstreamErr is the error that stopped copying to upstream; it is nil if
+	// the copy stopped normally (at EOF, or because the other direction
+	// finished).
+	UpstreamErr error
+	// DownstreamErr is the error that stopped copying from upstream; it is nil
+	// if the copy stopped normally.
+	DownstreamErr error
+}
+
+// Err returns the errors that stopped copying in either direction, if any.
+func (r PipeResult) Err() error {
+	return errors.Join(r.UpstreamErr, r.DownstreamErr)
+}
+
+// defaultDialBackoff is the delay before the first retry connecting upstream,
+// if PipeOptions.DialBackoff is not set.
+const defaultDialBackoff = 100 * time.Millisecond
+
+// PipeOptions are optional settings for PipeWithOptions.
+type PipeOptions struct {
+	// DialTimeout limits how long each attempt to connect upstream may take;
+	// if zero, the operating system default applies.
+	DialTimeout time.Duration
+	// DialRetries is the number of times to retry connecting upstream before
+	// giving up.
+	DialRetries int
+	// DialBackoff is the delay before the first retry; it doubles on each
+	// subsequent retry.  If zero, a default is used.
+	DialBackoff time.Duration
+	// TLSConfig, if set, causes the upstream connection to use TLS with the
+	// given configuration (e.g. to set RootCAs for a private CA).  If its
+	// ServerName is empty, the host name from the upstream address is used.
+	TLSConfig *tls.Config
+	// Network is the kind of upstream address: "tcp" (the default), "unix",
+	// "npipe" (on Windows, with a pipe path as the address), or "vsock" (with
+	// an address of "cid:port" on Linux, or "vmguid:port" on Windows).
+	Network string
+	// ProxyProtocol, if set, sends a PROXY protocol version 2 header at the
+	// start of the upstream connection (before any TLS handshake), so that
+	// upstream can see the address of the original client.
+	ProxyProtocol bool
+	// RelayOptions control how data is copied once connected.
+	RelayOptions
+}
+
+// Pipe connects to the given upstream address, and copies data between it and
+// the given connection until both sides are done.  When one direction reaches
+// EOF, the write side of the other connection is closed if possible (so that
+// the peer also sees EOF) and the other direction is allowed to finish;
+// otherwise, both connections are closed.  Both connections are closed on
+// return.  An error is returned if the upstream connection could
+// not be made.
+func Pipe(conn net.Conn, upstreamAddr string) (PipeResult, error) {
+	return PipeContext(context.Background(), conn, upstreamAddr)
+}
+
+// PipeContext is like Pipe, but also closes both connections when the context
+// is cancelled; in that case, the context's error is returned together with
+// the data copied so far.
+func PipeContext(ctx context.Context, conn net.Conn, upstreamAddr string) (PipeResult, error) {
+	return PipeWithOptions(ctx, conn, upstreamAddr, PipeOptions{})
+}
+
+// PipeWithOptions is like PipeContext, with the given options.
+func PipeWithOptions(ctx context.Context, conn net.Conn, upstreamAddr string, options PipeOptions) (PipeResult, error) {
+	info := PipeInfo{Source: conn.RemoteAddr(), Upstream: upstreamAddr}
+	return observe(options.Observer, info, func() (PipeResult, error) {
+		return pipe(ctx, conn, upstreamAddr, options)
+	})
+}
+
+// pipe implements PipeWithOptions, other than notifying the observer.
+func pipe(ctx context.Context, conn net.Conn, upstreamAddr string, options PipeOptions) (PipeResult, error) {
+	var proxyHeader []byte
+	if options.ProxyProtocol {
+		proxyHeader = appendProxyHeader(nil, conn.RemoteAddr(), conn.LocalAddr())
+	}
+	upstream, err := dialUpstream(ctx, upstreamAddr, proxyHeader, options)
 	if err != nil {
-		logrus.Errorf("Failed to dial upstream %s: %s", upstreamAddr, err)
-		return
+		_ = conn.Close()
+		return PipeResult{}, fmt.Errorf("failed to dial upstream %s: %w", upstreamAddr, err)
 	}
-	go func() {
-		if _, err := io.Copy(upstream, conn); err != nil {
-			logrus.Debugf("Error copying to upstream: %s", err)
+	return relay(ctx, conn, upstream, options.RelayOptions)
+}
+
+// dialUpstream connects to the upstream address, retrying as configured.  If
+// proxyHeader is not empty, it is sent on each new connection.
+func dialUpstream(ctx context.Context, upstreamAddr string, proxyHeader []byte, options PipeOptions) (net.Conn, error) {
+	backoff := options.DialBackoff
+	if backoff <= 0 {
+		backoff = defaultDialBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		upstream, err := dialOnce(ctx, upstreamAddr, proxyHeader, options)
+		if err == nil || attempt >= options.DialRetries {
+			return upstream, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
 		}
-		if err = upstream.Close(); err != nil {
-			logrus.Debugf("error closing connection while writing to upstream: %s", err)
+		backoff *= 2
+	}
+}
+
+// dialOnce makes a single attempt to connect to the upstream address,
+// including sending the PROXY protocol header and the TLS handshake if
+// configured.
+func dialOnce(ctx context.Context, upstreamAddr string, proxyHeader []byte, options PipeOptions) (net.Conn, error) {
+	if options.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.DialTimeout)
+		defer cancel()
+	}
+	var upstream net.Conn
+	var err error
+	switch options.Network {
+	case "", "tcp":
+		upstream, err = (&net.Dialer{}).DialContext(ctx, "tcp", upstreamAddr)
+	case "unix":
+		upstream, err = (&net.Dialer{}).DialContext(ctx, "unix", upstreamAddr)
+	default:
+		upstream, err = dialPlatform(ctx, options.Network, upstreamAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(proxyHeader) > 0 {
+		if _, err := upstream.Write(proxyHeader); err != nil {
+			_ = upstream.Close()
+			return nil, fmt.Errorf("failed to send PROXY protocol header: %w", err)
 		}
-	}()
+	}
+	if options.TLSConfig == nil {
+		return upstream, nil
+	}
 
-	if _, err := io.Copy(conn, upstream); err != nil {
-		logrus.Debugf("Error copying from upstream: %s", err)
+	config := options.TLSConfig
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(upstreamAddr); err == nil {
+			config = config.Clone()
+			config.ServerName = host
+		}
 	}
-	if err = upstream.Close(); err != nil {
-		logrus.Debugf("error closing connection: %s", err)
+	tlsConn := tls.Client(upstream, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = upstream.Close()
+		return nil, err
 	}
+	return tlsConn, nil
 }
@@ -0,0 +1,391 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer starts a TCP server that echoes back everything it receives,
+// returning its address.
+func echoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveEcho(t, listener)
+	return listener.Addr().String()
+}
+
+// serveEcho echoes back everything received on connections to the listener.
+func serveEcho(t *testing.T, listener net.Listener) {
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+}
+
+func TestPipeContext(t *testing.T) {
+	upstreamAddr := echoServer(t)
+	client, server := net.Pipe()
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := utils.PipeContext(ctx, server, upstreamAddr)
+		done <- err
+	}()
+
+	_, err := client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("PipeContext did not return after the context was cancelled")
+	}
+	_, err = client.Read(buf)
+	require.Error(t, err)
+}
+
+func TestPipeResult(t *testing.T) {
+	upstreamAddr := echoServer(t)
+	client, server := net.Pipe()
+	var result utils.PipeResult
+	var pipeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, pipeErr = utils.Pipe(server, upstreamAddr)
+	}()
+
+	_, err := client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	<-done
+	require.NoError(t, pipeErr)
+	require.Equal(t, int64(5), result.BytesUpstream)
+	require.Equal(t, int64(5), result.BytesDownstream)
+	require.NoError(t, result.UpstreamErr)
+	require.NoError(t, result.DownstreamErr)
+	require.Positive(t, result.Duration)
+}
+
+func TestPipeDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	upstreamAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	client, server := net.Pipe()
+	defer client.Close()
+	_, err = utils.Pipe(server, upstreamAddr)
+	require.Error(t, err)
+}
+
+func TestPipeHalfClose(t *testing.T) {
+	// The upstream server replies only once the client has finished sending.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received, _ := io.ReadAll(conn)
+		_, _ = conn.Write(append([]byte("got "), received...))
+	}()
+
+	// Use a TCP connection on the client side, so that it can be half-closed.
+	clientListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer clientListener.Close()
+	go func() {
+		conn, err := clientListener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = utils.Pipe(conn, listener.Addr().String())
+	}()
+	client, err := net.Dial("tcp", clientListener.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, client.(*net.TCPConn).CloseWrite())
+	reply, err := io.ReadAll(client)
+	require.NoError(t, err)
+	require.Equal(t, "got hello", string(reply))
+}
+
+func TestPipeDialRetry(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	upstreamAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	// Only start listening after the first attempt has failed.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		listener, err := net.Listen("tcp", upstreamAddr)
+		if err != nil {
+			return
+		}
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte("hello"))
+		conn.Close()
+	}()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := utils.PipeWithOptions(context.Background(), server, upstreamAddr, utils.PipeOptions{
+			DialTimeout: time.Second,
+			DialRetries: 5,
+			DialBackoff: 50 * time.Millisecond,
+		})
+		done <- err
+	}()
+	reply, err := io.ReadAll(client)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(reply))
+	require.NoError(t, <-done)
+}
+
+func TestPipeTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	upstreamAddr := server.Listener.Addr().String()
+
+	pipe := func(config *tls.Config) (*http.Response, error) {
+		client, conn := net.Pipe()
+		defer client.Close()
+		go func() {
+			_, _ = utils.PipeWithOptions(context.Background(), conn, upstreamAddr, utils.PipeOptions{TLSConfig: config})
+		}()
+		req, err := http.NewRequest(http.MethodGet, "http://upstream/", http.NoBody)
+		require.NoError(t, err)
+		req.Header.Set("Connection", "close")
+		if err := req.Write(client); err != nil {
+			return nil, err
+		}
+		return http.ReadResponse(bufio.NewReader(client), req)
+	}
+
+	// The test server certificate is valid for "example.com".
+	resp, err := pipe(&tls.Config{RootCAs: rootCAs, ServerName: "example.com"})
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(body))
+
+	// Without the CA, the upstream connection fails, and the client
+	// connection is closed.
+	_, err = pipe(&tls.Config{ServerName: "example.com"})
+	require.Error(t, err)
+}
+
+func TestPipeUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "upstream.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	serveEcho(t, listener)
+
+	client, server := net.Pipe()
+	done := make(chan error)
+	go func() {
+		_, err := utils.PipeWithOptions(context.Background(), server, socketPath, utils.PipeOptions{Network: "unix"})
+		done <- err
+	}()
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	require.NoError(t, client.Close())
+	require.NoError(t, <-done)
+}
+
+func TestPipeUnsupportedNetwork(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	_, err := utils.PipeWithOptions(context.Background(), server, "upstream", utils.PipeOptions{Network: "carrier-pigeon"})
+	require.ErrorContains(t, err, "unsupported network")
+}
+
+func TestPipeBandwidthLimit(t *testing.T) {
+	upstreamAddr := echoServer(t)
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		_, _ = utils.PipeWithOptions(context.Background(), server, upstreamAddr, utils.PipeOptions{RelayOptions: utils.RelayOptions{BandwidthLimit: 1000}})
+	}()
+
+	// The first second's worth is allowed as a burst; the rest is throttled.
+	payload := make([]byte, 1500)
+	start := time.Now()
+	go func() { _, _ = client.Write(payload) }()
+	_, err := io.ReadFull(client, make([]byte, len(payload)))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestPipeIdleTimeout(t *testing.T) {
+	upstreamAddr := echoServer(t)
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error)
+	go func() {
+		_, err := utils.PipeWithOptions(context.Background(), server, upstreamAddr, utils.PipeOptions{RelayOptions: utils.RelayOptions{IdleTimeout: 200 * time.Millisecond}})
+		done <- err
+	}()
+
+	// Traffic keeps the connection open past the timeout.
+	buf := make([]byte, 5)
+	for i := 0; i < 3; i++ {
+		_, err := client.Write([]byte("hello"))
+		require.NoError(t, err)
+		_, err = io.ReadFull(client, buf)
+		require.NoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, utils.ErrIdleTimeout)
+	case <-time.After(5 * time.Second):
+		t.Fatal("PipeWithOptions did not return after the connection was idle")
+	}
+}
+
+func BenchmarkPipe(b *testing.B) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(b, err)
+	defer upstream.Close()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(b, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = utils.Pipe(conn, upstream.Addr().String())
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(b, err)
+	defer client.Close()
+
+	chunk := make([]byte, 1024*1024)
+	b.SetBytes(int64(len(chunk)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := client.Write(chunk)
+		require.NoError(b, err)
+	}
+}
+
+func TestPipeObserver(t *testing.T) {
+	upstreamAddr := echoServer(t)
+	tracker := utils.NewPipeTracker()
+	client, server := net.Pipe()
+	done := make(chan error)
+	go func() {
+		_, err := utils.PipeWithOptions(context.Background(), server, upstreamAddr, utils.PipeOptions{RelayOptions: utils.RelayOptions{Observer: tracker}})
+		done <- err
+	}()
+
+	_, err := client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	active := tracker.Active()
+	require.Len(t, active, 1)
+	require.Equal(t, upstreamAddr, active[0].Upstream)
+
+	require.NoError(t, client.Close())
+	require.NoError(t, <-done)
+	require.Empty(t, tracker.Active())
+	stats := tracker.Stats()
+	require.EqualValues(t, 1, stats.Opened)
+	require.EqualValues(t, 1, stats.Closed)
+	require.EqualValues(t, 5, stats.BytesUpstream)
+	require.EqualValues(t, 5, stats.BytesDownstream)
+
+	// Dial failures are reported too.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	client, server = net.Pipe()
+	defer client.Close()
+	_, err = utils.PipeWithOptions(context.Background(), server, closedAddr, utils.PipeOptions{RelayOptions: utils.RelayOptions{Observer: tracker}})
+	require.Error(t, err)
+	stats = tracker.Stats()
+	require.EqualValues(t, 2, stats.Closed)
+	require.Equal(t, map[string]uint64{utils.ErrorClassRefused: 1}, stats.Errors)
+}
@@ -0,0 +1,212 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// proxyProtocolSignature is the fixed prefix of a PROXY protocol version 2
+// header; see https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+var proxyProtocolSignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolVersion  = 0x20
+	proxyProtocolLocal    = 0x00
+	proxyProtocolProxy    = 0x01
+	proxyProtocolUnspec   = 0x00
+	proxyProtocolTCP4     = 0x11
+	proxyProtocolTCP6     = 0x21
+	proxyProtocolTCP4Size = 2*net.IPv4len + 4
+	proxyProtocolTCP6Size = 2*net.IPv6len + 4
+	// proxyProtocolHeaderSize is the size of the fixed part of the header.
+	proxyProtocolHeaderSize = 16
+)
+
+// ErrNotProxyProtocol is returned when reading a PROXY protocol header from a
+// connection that does not start with one.
+var ErrNotProxyProtocol = errors.New("connection does not start with a PROXY protocol header")
+
+// appendProxyHeader appends a PROXY protocol version 2 header describing a
+// connection from source to destination.  If the addresses are not both TCP
+// addresses, a LOCAL header is used, which tells the receiver to use the real
+// connection addresses instead.
+func appendProxyHeader(buf []byte, source, destination net.Addr) []byte {
+	buf = append(buf, proxyProtocolSignature...)
+	sourceTCP, sourceOK := source.(*net.TCPAddr)
+	destinationTCP, destinationOK := destination.(*net.TCPAddr)
+	if !sourceOK || !destinationOK {
+		return append(buf, proxyProtocolVersion|proxyProtocolLocal, proxyProtocolUnspec, 0, 0)
+	}
+	buf = append(buf, proxyProtocolVersion|proxyProtocolProxy)
+	sourceIP, destinationIP := sourceTCP.IP.To4(), destinationTCP.IP.To4()
+	if sourceIP != nil && destinationIP != nil {
+		buf = append(buf, proxyProtocolTCP4)
+		buf = binary.BigEndian.AppendUint16(buf, proxyProtocolTCP4Size)
+	} else {
+		sourceIP, destinationIP = sourceTCP.IP.To16(), destinationTCP.IP.To16()
+		buf = append(buf, proxyProtocolTCP6)
+		buf = binary.BigEndian.AppendUint16(buf, proxyProtocolTCP6Size)
+	}
+	buf = append(buf, sourceIP...)
+	buf = append(buf, destinationIP...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(sourceTCP.Port))
+	return binary.BigEndian.AppendUint16(buf, uint16(destinationTCP.Port))
+}
+
+// WriteProxyHeader writes a PROXY protocol version 2 header describing a
+// connection from source to destination, so that the receiver can see the
+// original client address of a relayed connection.
+func WriteProxyHeader(w io.Writer, source, destination net.Addr) error {
+	_, err := w.Write(appendProxyHeader(nil, source, destination))
+	return err
+}
+
+// ReadProxyHeader reads a PROXY protocol version 2 header, returning the
+// addresses it describes.  The addresses are nil if the header does not carry
+// any (e.g. for LOCAL connections, or unsupported address families), in which
+// case the real connection addresses should be used.  No data past the header
+// is consumed.
+func ReadProxyHeader(r io.Reader) (source, destination net.Addr, err error) {
+	header := make([]byte, proxyProtocolHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, fmt.Errorf("failed to read PROXY protocol header: %w", err)
+	}
+	if !bytes.Equal(header[:len(proxyProtocolSignature)], proxyProtocolSignature) {
+		return nil, nil, ErrNotProxyProtocol
+	}
+	versionCommand, family := header[12], header[13]
+	if versionCommand&0xF0 != proxyProtocolVersion {
+		return nil, nil, fmt.Errorf("unsupported PROXY protocol version %#x", versionCommand>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, fmt.Errorf("failed to read PROXY protocol addresses: %w", err)
+	}
+
+	switch versionCommand & 0x0F {
+	case proxyProtocolLocal:
+		return nil, nil, nil
+	case proxyProtocolProxy:
+	default:
+		return nil, nil, fmt.Errorf("unsupported PROXY protocol command %#x", versionCommand&0x0F)
+	}
+	var ipLen int
+	switch family {
+	case proxyProtocolTCP4:
+		ipLen = net.IPv4len
+	case proxyProtocolTCP6:
+		ipLen = net.IPv6len
+	default:
+		return nil, nil, nil
+	}
+	if len(payload) < 2*ipLen+4 {
+		return nil, nil, fmt.Errorf("PROXY protocol header too short for address family %#x", family)
+	}
+	// Any trailing type-length-value fields are ignored.
+	ports := payload[2*ipLen:]
+	source = &net.TCPAddr{
+		IP:   net.IP(bytes.Clone(payload[:ipLen])),
+		Port: int(binary.BigEndian.Uint16(ports)),
+	}
+	destination = &net.TCPAddr{
+		IP:   net.IP(bytes.Clone(payload[ipLen : 2*ipLen])),
+		Port: int(binary.BigEndian.Uint16(ports[2:])),
+	}
+	return source, destination, nil
+}
+
+// proxyProtocolListener wraps a listener whose connections start with a PROXY
+// protocol header.
+type proxyProtocolListener struct {
+	net.Listener
+	timeout time.Duration
+}
+
+// NewProxyProtocolListener wraps a listener so that each accepted connection
+// has its PROXY protocol version 2 header parsed, and reports the addresses
+// from the header as its remote and local addresses.  The header is read
+// lazily on first use of the connection, so a slow client does not block
+// accepting others; it must arrive within the given timeout (if non-zero).
+// Connections without a valid header fail on first use.
+func NewProxyProtocolListener(listener net.Listener, timeout time.Duration) net.Listener {
+	return &proxyProtocolListener{Listener: listener, timeout: timeout}
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{Conn: conn, timeout: l.timeout}, nil
+}
+
+// proxyProtocolConn is a connection that starts with a PROXY protocol header.
+type proxyProtocolConn struct {
+	net.Conn
+	timeout     time.Duration
+	once        sync.Once
+	err         error
+	source      net.Addr
+	destination net.Addr
+}
+
+// readHeader reads the PROXY protocol header, if it has not been read yet.
+func (c *proxyProtocolConn) readHeader() error {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+		}
+		c.source, c.destination, c.err = ReadProxyHeader(c.Conn)
+	})
+	return c.err
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	if err := c.readHeader(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	if c.readHeader() == nil && c.source != nil {
+		return c.source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	if c.readHeader() == nil && c.destination != nil {
+		return c.destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// CloseWrite forwards half-closes to the underlying connection, if supported.
+func (c *proxyProtocolConn) CloseWrite() error {
+	if writer, ok := c.Conn.(closeWriter); ok {
+		return writer.CloseWrite()
+	}
+	return fmt.Errorf("half-close is not supported by %T", c.Conn)
+}
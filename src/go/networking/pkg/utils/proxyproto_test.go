@@ -0,0 +1,114 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyHeader(t *testing.T) {
+	cases := map[string]struct {
+		source      net.Addr
+		destination net.Addr
+	}{
+		"ipv4": {
+			source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 12345},
+			destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1").To4(), Port: 80},
+		},
+		"ipv6": {
+			source:      &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 12345},
+			destination: &net.TCPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443},
+		},
+	}
+	for name, testCase := range cases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, utils.WriteProxyHeader(&buf, testCase.source, testCase.destination))
+			buf.WriteString("payload")
+			source, destination, err := utils.ReadProxyHeader(&buf)
+			require.NoError(t, err)
+			require.Equal(t, testCase.source, source)
+			require.Equal(t, testCase.destination, destination)
+			require.Equal(t, "payload", buf.String())
+		})
+	}
+	t.Run("local", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, utils.WriteProxyHeader(&buf, &net.UnixAddr{Name: "a"}, &net.UnixAddr{Name: "b"}))
+		source, destination, err := utils.ReadProxyHeader(&buf)
+		require.NoError(t, err)
+		require.Nil(t, source)
+		require.Nil(t, destination)
+	})
+	t.Run("missing header", func(t *testing.T) {
+		_, _, err := utils.ReadProxyHeader(bytes.NewBufferString("GET / HTTP/1.1\r\nHost: x\r\n\r\n"))
+		require.ErrorIs(t, err, utils.ErrNotProxyProtocol)
+	})
+}
+
+func TestPipeProxyProtocol(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener = utils.NewProxyProtocolListener(listener, 5*time.Second)
+	t.Cleanup(func() { listener.Close() })
+	remoteAddrs := make(chan net.Addr, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		remoteAddrs <- conn.RemoteAddr()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	clientAddr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1").To4(), Port: 12345}
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error)
+	go func() {
+		conn := &addrConn{Conn: server, remote: clientAddr, local: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 80}}
+		_, err := utils.PipeWithOptions(context.Background(), conn, listener.Addr().String(), utils.PipeOptions{ProxyProtocol: true})
+		done <- err
+	}()
+
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(client, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(buf))
+	require.Equal(t, clientAddr, <-remoteAddrs)
+	require.NoError(t, client.Close())
+	require.NoError(t, <-done)
+}
+
+// addrConn overrides the addresses of a connection.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+	local  net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr { return c.remote }
+func (c *addrConn) LocalAddr() net.Addr  { return c.local }
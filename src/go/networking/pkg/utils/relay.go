@@ -0,0 +1,207 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RelayOptions are optional settings for copying data between two
+// connections, for Relay and PipeWithOptions.
+type RelayOptions struct {
+	// DisableHalfClose, if set, closes both connections as soon as either
+	// direction reaches EOF, rather than propagating the half-close; this is
+	// needed for peers that never finish sending once the other side is done.
+	DisableHalfClose bool
+	// BandwidthLimit, if positive, caps the throughput of the connection (in
+	// both directions combined) to the given number of bytes per second.
+	BandwidthLimit int
+	// SharedBandwidth, if set, caps the combined throughput of all connections
+	// using the same limiter.
+	SharedBandwidth *BandwidthLimiter
+	// IdleTimeout, if positive, closes both connections once no data has been
+	// copied in either direction for the given duration; in that case,
+	// ErrIdleTimeout is returned.  Note that this, like bandwidth limits,
+	// disables the zero-copy splice(2) fast path on Linux.
+	IdleTimeout time.Duration
+	// BufferPool supplies the buffers used to copy data when the zero-copy
+	// fast path is not available; if nil, DefaultBufferPool is used.
+	BufferPool *BufferPool
+	// Observer, if set, is notified when the connection is opened and closed.
+	Observer PipeObserver
+}
+
+// ErrIdleTimeout is returned when the connections were closed because they
+// were idle for longer than RelayOptions.IdleTimeout.
+var ErrIdleTimeout = errors.New("connection idle timeout")
+
+// activityReader records the time of the last successful read.
+type activityReader struct {
+	reader       io.Reader
+	lastActivity *atomic.Int64
+}
+
+func (r *activityReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.lastActivity.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+// closeWriter is implemented by connections that can be half-closed, such as
+// *net.TCPConn and *net.UnixConn.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// ErrHalfCloseUnsupported is returned from CloseWrite for streams that can't
+// be half-closed.
+var ErrHalfCloseUnsupported = errors.New("half-close is not supported")
+
+// CloseWrite shuts down the writing side of the stream, if it supports that;
+// this is useful for wrappers to forward half-closes to the underlying stream.
+func CloseWrite(stream any) error {
+	if writer, ok := stream.(closeWriter); ok {
+		return writer.CloseWrite()
+	}
+	return ErrHalfCloseUnsupported
+}
+
+// Relay copies data between two already connected streams until both sides
+// are done, in the same way as PipeWithOptions (the second stream being
+// upstream); both streams are closed on return.  This is for cases where the
+// upstream connection is not simply dialed, such as multiplexed streams.
+func Relay(ctx context.Context, conn, upstream io.ReadWriteCloser, options RelayOptions) (PipeResult, error) {
+	var info PipeInfo
+	if netConn, ok := conn.(net.Conn); ok {
+		info.Source = netConn.RemoteAddr()
+	}
+	if netConn, ok := upstream.(net.Conn); ok && netConn.RemoteAddr() != nil {
+		info.Upstream = netConn.RemoteAddr().String()
+	}
+	return observe(options.Observer, info, func() (PipeResult, error) {
+		return relay(ctx, conn, upstream, options)
+	})
+}
+
+// observe calls the function, notifying the observer (if any) about it.
+func observe(observer PipeObserver, info PipeInfo, run func() (PipeResult, error)) (PipeResult, error) {
+	if observer == nil {
+		return run()
+	}
+	info.ID = pipeID.Add(1)
+	info.Opened = time.Now()
+	observer.PipeOpened(info)
+	result, err := run()
+	errorClass := ErrorClass(err)
+	if errorClass == ErrorClassNone {
+		// Report errors copying data too, other than those from closing.
+		errorClass = ErrorClass(result.Err())
+	}
+	observer.PipeClosed(info, result, errorClass)
+	return result, err
+}
+
+// relay implements Relay, other than notifying the observer.
+func relay(ctx context.Context, conn, upstream io.ReadWriteCloser, options RelayOptions) (PipeResult, error) {
+	start := time.Now()
+
+	// closed is set once we start closing the connections ourselves, after
+	// which errors from using closed connections are expected.
+	var closed, cancelled, idle atomic.Bool
+	closeBoth := func() {
+		closed.Store(true)
+		_ = conn.Close()
+		_ = upstream.Close()
+	}
+	stop := context.AfterFunc(ctx, func() {
+		cancelled.Store(true)
+		closeBoth()
+	})
+	defer stop()
+
+	var lastActivity atomic.Int64
+	lastActivity.Store(start.UnixNano())
+	if options.IdleTimeout > 0 {
+		var timer *time.Timer
+		timer = time.AfterFunc(options.IdleTimeout, func() {
+			remaining := options.IdleTimeout - time.Since(time.Unix(0, lastActivity.Load()))
+			if remaining > 0 {
+				timer.Reset(remaining)
+				return
+			}
+			idle.Store(true)
+			closeBoth()
+		})
+		defer timer.Stop()
+	}
+
+	connBandwidth := NewBandwidthLimiter(options.BandwidthLimit)
+	pool := options.BufferPool
+	if pool == nil {
+		pool = DefaultBufferPool
+	}
+	copyDirection := func(dst, src io.ReadWriteCloser) (int64, error) {
+		// Only wrap the source if needed: io.Copy between bare TCP or unix
+		// connections can use splice(2) on Linux, avoiding copying the data
+		// through user space.
+		var reader io.Reader = src
+		if options.IdleTimeout > 0 {
+			reader = &activityReader{reader: reader, lastActivity: &lastActivity}
+		}
+		reader = newThrottledReader(ctx, reader, connBandwidth, options.SharedBandwidth)
+		buf := pool.Get()
+		defer pool.Put(buf)
+		n, err := io.CopyBuffer(dst, reader, buf)
+		if closed.Load() && (errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) ||
+			(ctx.Err() != nil && errors.Is(err, ctx.Err()))) {
+			return n, nil
+		}
+		if err == nil && !options.DisableHalfClose {
+			if CloseWrite(dst) == nil {
+				return n, nil
+			}
+		}
+		closeBoth()
+		return n, err
+	}
+
+	var result PipeResult
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result.BytesUpstream, result.UpstreamErr = copyDirection(upstream, conn)
+	}()
+	result.BytesDownstream, result.DownstreamErr = copyDirection(conn, upstream)
+	wg.Wait()
+	closeBoth()
+	result.Duration = time.Since(start)
+
+	if cancelled.Load() {
+		return result, context.Cause(ctx)
+	}
+	if idle.Load() {
+		return result, ErrIdleTimeout
+	}
+	return result, nil
+}
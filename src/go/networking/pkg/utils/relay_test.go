@@ -0,0 +1,141 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpPair returns both ends of a loopback TCP connection.
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	server := <-accepted
+	require.NotNil(t, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("half-close", func(t *testing.T) {
+		client, conn := tcpPair(t)
+		upstream, backend := tcpPair(t)
+		done := make(chan utils.PipeResult)
+		go func() {
+			result, err := utils.Relay(context.Background(), conn, upstream, utils.RelayOptions{})
+			require.NoError(t, err)
+			done <- result
+		}()
+
+		_, err := client.Write([]byte("request"))
+		require.NoError(t, err)
+		require.NoError(t, client.(*net.TCPConn).CloseWrite())
+		request, err := io.ReadAll(backend)
+		require.NoError(t, err)
+		require.Equal(t, "request", string(request))
+		// The backend can still reply after seeing EOF.
+		_, err = backend.Write([]byte("response"))
+		require.NoError(t, err)
+		require.NoError(t, backend.Close())
+		response, err := io.ReadAll(client)
+		require.NoError(t, err)
+		require.Equal(t, "response", string(response))
+
+		result := <-done
+		require.EqualValues(t, len("request"), result.BytesUpstream)
+		require.EqualValues(t, len("response"), result.BytesDownstream)
+	})
+	t.Run("disable half-close", func(t *testing.T) {
+		client, conn := tcpPair(t)
+		upstream, backend := tcpPair(t)
+		done := make(chan error)
+		go func() {
+			_, err := utils.Relay(context.Background(), conn, upstream, utils.RelayOptions{DisableHalfClose: true})
+			done <- err
+		}()
+
+		require.NoError(t, client.(*net.TCPConn).CloseWrite())
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Relay did not return after one side finished")
+		}
+		_, err := backend.Write([]byte("response"))
+		if err == nil {
+			_, err = backend.Read(make([]byte, 1))
+		}
+		require.Error(t, err)
+	})
+	t.Run("half-close unsupported", func(t *testing.T) {
+		// Neither side can be half-closed, so both are closed once the data
+		// source reaches EOF.
+		reader, writer := io.Pipe()
+		var output bytes.Buffer
+		conn := &readWriteCloser{Reader: reader, Writer: writer}
+		upstream := &readWriteCloser{Reader: bytes.NewBufferString("some data"), Writer: &output}
+		result, err := utils.Relay(context.Background(), conn, upstream, utils.RelayOptions{})
+		require.NoError(t, err)
+		require.NoError(t, result.Err())
+		require.Equal(t, "some data", output.String())
+	})
+}
+
+// readWriteCloser combines a reader and a writer into a stream that does not
+// support half-close; closing it closes both, if they support that.
+type readWriteCloser struct {
+	io.Reader
+	io.Writer
+}
+
+func (c *readWriteCloser) Close() error {
+	var errs []error
+	if closer, ok := c.Reader.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+	if closer, ok := c.Writer.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func TestCloseWrite(t *testing.T) {
+	client, server := tcpPair(t)
+	require.NoError(t, utils.CloseWrite(client))
+	data, err := io.ReadAll(server)
+	require.NoError(t, err)
+	require.Empty(t, data)
+	require.ErrorIs(t, utils.CloseWrite(&readWriteCloser{}), utils.ErrHalfCloseUnsupported)
+}
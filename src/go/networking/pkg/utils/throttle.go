@@ -0,0 +1,84 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// BandwidthLimiter caps the combined throughput of the connections sharing it.
+// A nil *BandwidthLimiter imposes no limit.
+type BandwidthLimiter struct {
+	limiter *rate.Limiter
+}
+
+// NewBandwidthLimiter creates a limiter allowing the given number of bytes per
+// second, with bursts of up to one second's worth of data.  If bytesPerSecond
+// is not positive, nil (no limit) is returned.
+func NewBandwidthLimiter(bytesPerSecond int) *BandwidthLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &BandwidthLimiter{limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond)}
+}
+
+// throttledReader is a reader that waits on a set of bandwidth limiters after
+// each read.
+type throttledReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*BandwidthLimiter
+	// maxRead is the largest read allowed, so that a single read never needs
+	// more tokens than a limiter can hold.
+	maxRead int
+}
+
+// newThrottledReader wraps the reader to be limited by the given limiters;
+// nil limiters are ignored.  If there are no limiters, the reader is returned
+// as is.
+func newThrottledReader(ctx context.Context, reader io.Reader, limiters ...*BandwidthLimiter) io.Reader {
+	result := &throttledReader{ctx: ctx, reader: reader}
+	for _, limiter := range limiters {
+		if limiter == nil {
+			continue
+		}
+		result.limiters = append(result.limiters, limiter)
+		if burst := limiter.limiter.Burst(); result.maxRead == 0 || burst < result.maxRead {
+			result.maxRead = burst
+		}
+	}
+	if len(result.limiters) == 0 {
+		return reader
+	}
+	return result
+}
+
+func (r *throttledReader) Read(b []byte) (int, error) {
+	if len(b) > r.maxRead {
+		b = b[:r.maxRead]
+	}
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		for _, limiter := range r.limiters {
+			if waitErr := limiter.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+				err = waitErr
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,172 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultUDPSessionTimeout is how long a UDP peer may be silent (in both
+	// directions) before its session is discarded, if
+	// UDPRelayOptions.SessionTimeout is not set.
+	DefaultUDPSessionTimeout = 60 * time.Second
+	// maxUDPDatagramSize is the largest possible UDP payload, used as the
+	// default buffer size.
+	maxUDPDatagramSize = 64 * 1024
+)
+
+// UDPRelayOptions are optional settings for RelayUDP.
+type UDPRelayOptions struct {
+	// SessionTimeout is how long a peer may go without sending or receiving
+	// datagrams before its session is closed; if zero,
+	// DefaultUDPSessionTimeout is used.
+	SessionTimeout time.Duration
+	// BufferSize is the size of the buffers used to receive datagrams; larger
+	// datagrams are truncated.  If zero, the maximum UDP payload size is used.
+	BufferSize int
+}
+
+// udpSession is the upstream socket for one peer of a UDP relay.
+type udpSession struct {
+	upstream     net.Conn
+	lastActivity atomic.Int64
+}
+
+// RelayUDP forwards datagrams received on conn to the upstream address, and
+// relays the replies back to the sender.  Each peer gets its own upstream
+// socket (so that upstream can tell peers apart, and replies go back to the
+// right peer), which is closed once the peer has been idle for the session
+// timeout.  It returns once conn is closed or the context is cancelled; conn
+// is closed on return.
+func RelayUDP(ctx context.Context, conn net.PacketConn, upstreamAddr string, options UDPRelayOptions) error {
+	timeout := options.SessionTimeout
+	if timeout <= 0 {
+		timeout = DefaultUDPSessionTimeout
+	}
+	bufferSize := options.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = maxUDPDatagramSize
+	}
+
+	var closed atomic.Bool
+	stop := context.AfterFunc(ctx, func() {
+		closed.Store(true)
+		_ = conn.Close()
+	})
+	defer stop()
+	defer conn.Close()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sessions := make(map[string]*udpSession)
+	defer func() {
+		mu.Lock()
+		for _, session := range sessions {
+			_ = session.upstream.Close()
+		}
+		mu.Unlock()
+		wg.Wait()
+	}()
+
+	// relayReplies copies datagrams from upstream back to the peer until the
+	// session times out.
+	relayReplies := func(peer net.Addr, session *udpSession) {
+		defer wg.Done()
+		defer func() {
+			mu.Lock()
+			if sessions[peer.String()] == session {
+				delete(sessions, peer.String())
+			}
+			mu.Unlock()
+			_ = session.upstream.Close()
+		}()
+		buf := make([]byte, bufferSize)
+		for {
+			lastActivity := time.Unix(0, session.lastActivity.Load())
+			_ = session.upstream.SetReadDeadline(lastActivity.Add(timeout))
+			n, err := session.upstream.Read(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				if time.Since(time.Unix(0, session.lastActivity.Load())) >= timeout {
+					logrus.Debugf("UDP session for %s to %s timed out", peer, upstreamAddr)
+					return
+				}
+				continue
+			}
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logrus.Debugf("UDP session for %s to %s failed reading: %s", peer, upstreamAddr, err)
+				}
+				return
+			}
+			session.lastActivity.Store(time.Now().UnixNano())
+			if _, err := conn.WriteTo(buf[:n], peer); err != nil {
+				logrus.Debugf("UDP session for %s failed to send reply: %s", peer, err)
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+			}
+		}
+	}
+
+	buf := make([]byte, bufferSize)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			if closed.Load() {
+				return context.Cause(ctx)
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			if n == 0 {
+				logrus.Debugf("error reading UDP packet: %s", err)
+				continue
+			}
+		}
+
+		mu.Lock()
+		session, ok := sessions[peer.String()]
+		if !ok {
+			var upstream net.Conn
+			upstream, err = net.Dial("udp", upstreamAddr)
+			if err != nil {
+				mu.Unlock()
+				logrus.Errorf("failed to connect UDP session for %s to %s: %s", peer, upstreamAddr, err)
+				continue
+			}
+			session = &udpSession{upstream: upstream}
+			session.lastActivity.Store(time.Now().UnixNano())
+			sessions[peer.String()] = session
+			wg.Add(1)
+			go relayReplies(peer, session)
+			logrus.Debugf("created UDP session for %s to %s", peer, upstreamAddr)
+		}
+		mu.Unlock()
+
+		session.lastActivity.Store(time.Now().UnixNano())
+		if _, err := session.upstream.Write(buf[:n]); err != nil {
+			logrus.Debugf("UDP session for %s failed to forward packet to %s: %s", peer, upstreamAddr, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024 SUSE LLC
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// udpEchoServer starts a UDP server that replies to each datagram with its
+// contents prefixed by the sender's address, returning its address.
+func udpEchoServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(append([]byte(addr.String()+" "), buf[:n]...), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestRelayUDP(t *testing.T) {
+	upstreamAddr := udpEchoServer(t)
+	relay, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- utils.RelayUDP(ctx, relay, upstreamAddr, utils.UDPRelayOptions{SessionTimeout: time.Second})
+	}()
+
+	exchange := func(client net.Conn, message string) string {
+		_, err := client.Write([]byte(message))
+		require.NoError(t, err)
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+		buf := make([]byte, 1024)
+		n, err := client.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+	first, err := net.Dial("udp", relay.LocalAddr().String())
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := net.Dial("udp", relay.LocalAddr().String())
+	require.NoError(t, err)
+	defer second.Close()
+
+	// Each client gets replies to its own datagrams, and is seen upstream as
+	// a distinct (but stable) peer.
+	reply1 := exchange(first, "one")
+	require.Regexp(t, ` one$`, reply1)
+	reply2 := exchange(second, "two")
+	require.Regexp(t, ` two$`, reply2)
+	require.NotEqual(t, reply1[:len(reply1)-4], reply2[:len(reply2)-4])
+	require.Equal(t, reply1[:len(reply1)-4]+" again", exchange(first, "again"))
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("RelayUDP did not return after the context was cancelled")
+	}
+}
@@ -21,18 +21,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"regexp"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/websocket"
 )
 
 var apiSettings struct {
 	Method    string
 	InputFile string
 	Body      string
+	Stream    bool
+	WebSocket bool
+	DryRun    bool
 }
 
 // apiCmd represents the api command
@@ -47,6 +52,15 @@ Two ways of specifying a body:
 
 2. --body|-b string: For the 'PUT /settings' endpoint, this must be a valid JSON string.
 
+For endpoints that stream their response (such as 'settings/watch'), use --stream
+to write each value to standard output as it arrives, one JSON value per line;
+use --websocket for WebSocket endpoints, sending the body (if any) as the first
+message.  These run until the server ends the stream, or until interrupted.
+
+With --dry-run, 'PUT /settings' validates the body and lists the settings that
+would change, and whether the backend would be restarted, without applying
+anything (see 'rdctl set --dry-run').
+
 The API is currently at version 1, but is still considered internal and experimental, and
 is subject to change without any advance notice.
 `,
@@ -57,9 +71,13 @@ is subject to change without any advance notice.
 
 func init() {
 	rootCmd.AddCommand(apiCmd)
+	defineOutputFlag(apiCmd)
 	apiCmd.Flags().StringVarP(&apiSettings.Method, "method", "X", "", "method to use")
 	apiCmd.Flags().StringVarP(&apiSettings.InputFile, "input", "", "", "file containing JSON payload to upload (- for standard input)")
 	apiCmd.Flags().StringVarP(&apiSettings.Body, "body", "b", "", "string containing JSON payload to upload")
+	apiCmd.Flags().BoolVar(&apiSettings.Stream, "stream", false, "stream the response as newline-delimited JSON")
+	apiCmd.Flags().BoolVar(&apiSettings.WebSocket, "websocket", false, "connect to a WebSocket endpoint, streaming messages as newline-delimited JSON")
+	apiCmd.Flags().BoolVar(&apiSettings.DryRun, "dry-run", false, "for 'PUT /settings', only show what would change")
 }
 
 func doAPICommand(cmd *cobra.Command, args []string) error {
@@ -87,12 +105,13 @@ func doAPICommand(cmd *cobra.Command, args []string) error {
 	if apiSettings.InputFile != "" && apiSettings.Body != "" {
 		return fmt.Errorf("api command: --body and --input options cannot both be specified")
 	}
+	if apiSettings.WebSocket && apiSettings.Method != "" && apiSettings.Method != "GET" {
+		return fmt.Errorf("api command: --websocket can only be used with the GET method")
+	}
 	// No longer emit usage info on errors
 	cmd.SilenceUsage = true
+	var payload io.Reader
 	if apiSettings.InputFile != "" {
-		if apiSettings.Method == "" {
-			apiSettings.Method = "PUT"
-		}
 		if apiSettings.InputFile == "-" {
 			contents, err = io.ReadAll(os.Stdin)
 		} else {
@@ -101,25 +120,75 @@ func doAPICommand(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
-		method := apiSettings.Method
-		payload := bytes.NewBuffer(contents)
-		result, errorPacket, err = client.ProcessRequestForAPI(rdClient.DoRequestWithPayload(method, endpoint, payload))
+		payload = bytes.NewBuffer(contents)
 	} else if apiSettings.Body != "" {
+		payload = bytes.NewBufferString(apiSettings.Body)
+	}
+	if apiSettings.DryRun {
+		return doDryRunAPICommand(rdClient, endpoint, payload)
+	}
+	if apiSettings.WebSocket {
+		return doWebSocketAPICommand(rdClient, endpoint, payload)
+	}
+	var response *http.Response
+	if payload != nil {
 		if apiSettings.Method == "" {
 			apiSettings.Method = "PUT"
 		}
-		method := apiSettings.Method
-		payload := bytes.NewBufferString(apiSettings.Body)
-		result, errorPacket, err = client.ProcessRequestForAPI(rdClient.DoRequestWithPayload(method, endpoint, payload))
+		response, err = rdClient.DoRequestWithPayload(apiSettings.Method, endpoint, payload)
 	} else {
 		if apiSettings.Method == "" {
 			apiSettings.Method = "GET"
 		}
-		result, errorPacket, err = client.ProcessRequestForAPI(rdClient.DoRequest(apiSettings.Method, endpoint))
+		response, err = rdClient.DoRequest(apiSettings.Method, endpoint)
+	}
+	if apiSettings.Stream && err == nil && response.StatusCode >= 200 && response.StatusCode < 300 {
+		defer response.Body.Close()
+		return streamResponse(os.Stdout, response.Header.Get("Content-Type"), response.Body)
 	}
+	result, errorPacket, err = client.ProcessRequestForAPI(response, err)
 	return displayAPICallResult(result, errorPacket, err)
 }
 
+// settingsEndpointPattern matches the versioned settings endpoint.
+var settingsEndpointPattern = regexp.MustCompile(`^/v\d+/settings$`)
+
+func doDryRunAPICommand(rdClient client.RDClient, endpoint string, payload io.Reader) error {
+	if !settingsEndpointPattern.MatchString(endpoint) || (apiSettings.Method != "" && apiSettings.Method != "PUT") {
+		return fmt.Errorf("api command: --dry-run can only be used with 'PUT /settings'")
+	}
+	if payload == nil {
+		return fmt.Errorf("api command: --dry-run requires a body or input file")
+	}
+	proposed, err := io.ReadAll(payload)
+	if err != nil {
+		return err
+	}
+	result, err := proposeSettings(rdClient, proposed)
+	if err != nil {
+		return err
+	}
+	return showSettingsDryRun(result)
+}
+
+func doWebSocketAPICommand(rdClient *client.RDClientImpl, endpoint string, payload io.Reader) error {
+	conn, err := rdClient.DialWebSocket(endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if payload != nil {
+		message, err := io.ReadAll(payload)
+		if err != nil {
+			return err
+		}
+		if err := websocket.Message.Send(conn, string(message)); err != nil {
+			return fmt.Errorf("failed to send message: %w", err)
+		}
+	}
+	return streamWebSocket(os.Stdout, conn)
+}
+
 func displayAPICallResult(result []byte, errorPacket *client.APIError, err error) error {
 	if err != nil {
 		return err
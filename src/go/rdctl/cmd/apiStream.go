@@ -0,0 +1,168 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// streamResponse writes a streamed response body to w as newline-delimited
+// JSON, one line per value as it arrives.  Server-sent events are written as
+// their data, or as {"event": ..., "data": ...} if they have an event type;
+// other lines that aren't JSON are written as JSON strings.
+func streamResponse(w io.Writer, contentType string, body io.Reader) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = ""
+	}
+	switch mediaType {
+	case "text/event-stream":
+		return streamEvents(w, body)
+	case "application/json":
+		// A single JSON document, which may span multiple lines.
+		contents, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if contents = bytes.TrimSpace(contents); len(contents) == 0 {
+			return nil
+		}
+		return writeJSONLine(w, contents)
+	default:
+		return streamLines(w, body)
+	}
+}
+
+// streamLines writes each non-empty line of the body as JSON.
+func streamLines(w io.Writer, body io.Reader) error {
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if writeErr := writeJSONLine(w, line); writeErr != nil {
+				return writeErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// streamEvents writes each server-sent event in the body as JSON.
+func streamEvents(w io.Writer, body io.Reader) error {
+	var eventType string
+	var data []string
+	dispatch := func() error {
+		defer func() {
+			eventType, data = "", nil
+		}()
+		if data == nil {
+			return nil
+		}
+		value := []byte(strings.Join(data, "\n"))
+		if eventType == "" {
+			return writeJSONLine(w, value)
+		}
+		var buf bytes.Buffer
+		if err := writeJSONLine(&buf, value); err != nil {
+			return err
+		}
+		event := struct {
+			Event string          `json:"event"`
+			Data  json.RawMessage `json:"data"`
+		}{eventType, bytes.TrimSpace(buf.Bytes())}
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(encoded))
+		return err
+	}
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			if dispatchErr := dispatch(); dispatchErr != nil {
+				return dispatchErr
+			}
+		case field == "":
+			// A comment, used to keep the connection alive.
+		case field == "event":
+			eventType = value
+		case field == "data":
+			data = append(data, value)
+		}
+		if errors.Is(err, io.EOF) {
+			return dispatch()
+		}
+	}
+}
+
+// streamWebSocket writes each message received on the connection as JSON,
+// until the server closes it.
+func streamWebSocket(w io.Writer, conn *websocket.Conn) error {
+	for {
+		var message []byte
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to receive message: %w", err)
+		}
+		if message = bytes.TrimSpace(message); len(message) > 0 {
+			if err := writeJSONLine(w, message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeJSONLine writes the data on a single line: compacted if it is JSON,
+// otherwise as a JSON string.
+func writeJSONLine(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		buf.Reset()
+		encoded, err := json.Marshal(string(data))
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
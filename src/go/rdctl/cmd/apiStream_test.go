@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamResponse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body:        "{\"a\": 1}\n\n{ \"b\": [1, 2] }\n",
+			expected:    "{\"a\":1}\n{\"b\":[1,2]}\n",
+		},
+		{
+			name:        "text",
+			contentType: "text/plain; charset=utf-8",
+			body:        "hello\n42\r\nlast line without newline",
+			expected:    "\"hello\"\n42\n\"last line without newline\"\n",
+		},
+		{
+			name:        "json document",
+			contentType: "application/json; charset=utf-8",
+			body:        "{\n  \"a\": 1,\n  \"b\": \"c\"\n}\n",
+			expected:    "{\"a\":1,\"b\":\"c\"}\n",
+		},
+		{
+			name:        "empty json document",
+			contentType: "application/json",
+			body:        "",
+			expected:    "",
+		},
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			body:        ": keep-alive\n\ndata: {\"a\": 1}\n\nevent: progress\ndata: 50\n\ndata: first\ndata: second\n\ndata: unterminated",
+			expected:    "{\"a\":1}\n{\"event\":\"progress\",\"data\":50}\n\"first\\nsecond\"\n\"unterminated\"\n",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, streamResponse(&buf, testCase.contentType, strings.NewReader(testCase.body)))
+			assert.Equal(t, testCase.expected, buf.String())
+		})
+	}
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		user, password, _ := conn.Request().BasicAuth()
+		_ = websocket.Message.Send(conn, user+":"+password)
+		var request string
+		_ = websocket.Message.Receive(conn, &request)
+		_ = websocket.Message.Send(conn, request)
+		_ = websocket.Message.Send(conn, []byte(`{"done": true}`))
+	}))
+	defer server.Close()
+
+	host, portString, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portString)
+	require.NoError(t, err)
+	rdClient := client.NewRDClient(&config.ConnectionInfo{Host: host, Port: port, User: "user", Password: "secret"})
+
+	conn, err := rdClient.DialWebSocket("/v1/events")
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, websocket.Message.Send(conn, `{"follow": true}`))
+	var buf bytes.Buffer
+	require.NoError(t, streamWebSocket(&buf, conn))
+	assert.Equal(t, "\"user:secret\"\n{\"follow\":true}\n{\"done\":true}\n", buf.String())
+}
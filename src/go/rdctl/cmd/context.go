@@ -0,0 +1,42 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// contextCmd represents the 'rdctl context' command
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the Rancher Desktop instances rdctl connects to",
+	Long: `rdctl context - manage the Rancher Desktop instances rdctl connects to
+
+A context stores the connection settings for one Rancher Desktop instance: the
+config file of its application data directory (for example, a second test
+profile), and optionally the host, port, user and password of its API server
+(for example, on a remote machine).  Commands that talk to the application use
+the current context; this can be overridden for a single command with the
+global --context flag.  The "default" context is the local installation.
+
+Commands that work on the local installation directly, such as "rdctl shell",
+"rdctl snapshot" and "rdctl factory-reset", are not affected by the context.`,
+}
+
+func init() {
+	rootCmd.AddCommand(contextCmd)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// contextCreateCmd represents the 'rdctl context create' command
+var contextCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an rdctl context",
+	Long: `Create an rdctl context from the connection settings given with the global
+--config-path, --host, --port, --user and --password flags.  For example:
+
+  rdctl context create test-profile --config-path ~/rd-test/rd-engine.json
+  rdctl context create build-box --host 192.168.1.20 --port 6107 --user user --password secret`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return createContext(args[0], config.ContextFromFlags())
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextCreateCmd)
+}
+
+func createContext(name string, newContext config.Context) error {
+	if name == config.DefaultContextName {
+		return fmt.Errorf("context %q can't be changed", name)
+	}
+	if newContext == (config.Context{}) {
+		return errors.New("no connection settings given: use --config-path, --host, --port, --user or --password")
+	}
+	if newContext.ConfigPath != "" {
+		var err error
+		if newContext.ConfigPath, err = filepath.Abs(newContext.ConfigPath); err != nil {
+			return err
+		}
+	}
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return err
+	}
+	if _, ok := contexts.Contexts[name]; ok {
+		return fmt.Errorf("context %q already exists", name)
+	}
+	contexts.Contexts[name] = newContext
+	return contexts.Save()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// contextListCmd represents the 'rdctl context ls' command
+var contextListCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"list"},
+	Short:   "List the rdctl contexts",
+	Long:    `List the rdctl contexts; the current one is marked with an asterisk.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		contexts, err := config.LoadContexts()
+		if err != nil {
+			return err
+		}
+		return renderOutput(format, newContextTable(contexts))
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextListCmd)
+	defineOutputFlag(contextListCmd)
+}
+
+// contextEntry is a context as listed by `rdctl context ls`; the password is
+// never shown.
+type contextEntry struct {
+	Name       string `json:"name"`
+	Current    bool   `json:"current"`
+	ConfigPath string `json:"configPath,omitempty"`
+	Host       string `json:"host,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	User       string `json:"user,omitempty"`
+}
+
+type contextTable []contextEntry
+
+func newContextTable(contexts *config.Contexts) contextTable {
+	current := contexts.CurrentName()
+	table := contextTable{}
+	for _, name := range contexts.Names() {
+		entry := contexts.Contexts[name]
+		table = append(table, contextEntry{
+			Name:       name,
+			Current:    name == current,
+			ConfigPath: entry.ConfigPath,
+			Host:       entry.Host,
+			Port:       entry.Port,
+			User:       entry.User,
+		})
+	}
+	return table
+}
+
+func (t contextTable) Headers() []string {
+	return []string{"CURRENT", "NAME", "CONFIG PATH", "HOST", "PORT", "USER"}
+}
+
+func (t contextTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, entry := range t {
+		current := ""
+		if entry.Current {
+			current = "*"
+		}
+		port := ""
+		if entry.Port != 0 {
+			port = strconv.Itoa(entry.Port)
+		}
+		rows = append(rows, []string{current, entry.Name, entry.ConfigPath, entry.Host, port, entry.User})
+	}
+	return rows
+}
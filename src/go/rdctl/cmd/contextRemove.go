@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// contextRemoveCmd represents the 'rdctl context rm' command
+var contextRemoveCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"remove"},
+	Short:   "Remove an rdctl context",
+	Long:    `Remove an rdctl context; if it is the current context, the default context is used again.`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return removeContext(args[0])
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextRemoveCmd)
+}
+
+func removeContext(name string) error {
+	if name == config.DefaultContextName {
+		return fmt.Errorf("context %q can't be removed", name)
+	}
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return err
+	}
+	if _, err := contexts.Get(name); err != nil {
+		return err
+	}
+	delete(contexts.Contexts, name)
+	if contexts.Current == name {
+		contexts.Current = ""
+	}
+	return contexts.Save()
+}
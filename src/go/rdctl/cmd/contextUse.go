@@ -0,0 +1,58 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// contextUseCmd represents the 'rdctl context use' command
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the current rdctl context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := useContext(args[0]); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(cmd.OutOrStdout(), "Switched to context %q.\n", args[0])
+		return err
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextUseCmd)
+}
+
+func useContext(name string) error {
+	contexts, err := config.LoadContexts()
+	if err != nil {
+		return err
+	}
+	if _, err := contexts.Get(name); err != nil {
+		return err
+	}
+	contexts.Current = name
+	if name == config.DefaultContextName {
+		contexts.Current = ""
+	}
+	return contexts.Save()
+}
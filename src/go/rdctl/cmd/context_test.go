@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextCommands(t *testing.T) {
+	savedDefault := config.DefaultConfigPath
+	t.Cleanup(func() { config.DefaultConfigPath = savedDefault })
+	config.DefaultConfigPath = filepath.Join(t.TempDir(), "rd-engine.json")
+
+	assert.EqualError(t, createContext("test", config.Context{}), "no connection settings given: use --config-path, --host, --port, --user or --password")
+	assert.EqualError(t, createContext(config.DefaultContextName, config.Context{Host: "h"}), `context "default" can't be changed`)
+	require.NoError(t, createContext("test", config.Context{ConfigPath: "/tmp/other/rd-engine.json"}))
+	require.NoError(t, createContext("remote", config.Context{Host: "10.0.0.2", Port: 6107, User: "u", Password: "p"}))
+	assert.EqualError(t, createContext("test", config.Context{Host: "h"}), `context "test" already exists`)
+
+	assert.EqualError(t, useContext("missing"), `context "missing" does not exist`)
+	require.NoError(t, useContext("remote"))
+
+	contexts, err := config.LoadContexts()
+	require.NoError(t, err)
+	testPath, err := filepath.Abs("/tmp/other/rd-engine.json")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"", "default", "", "", "", ""},
+		{"*", "remote", "", "10.0.0.2", "6107", "u"},
+		{"", "test", testPath, "", "", ""},
+	}, newContextTable(contexts).Rows())
+
+	require.NoError(t, removeContext("remote"))
+	assert.EqualError(t, removeContext(config.DefaultContextName), `context "default" can't be removed`)
+	contexts, err = config.LoadContexts()
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultContextName, contexts.CurrentName())
+	assert.Equal(t, []string{config.DefaultContextName, "test"}, contexts.Names())
+}
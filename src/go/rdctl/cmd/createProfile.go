@@ -17,13 +17,18 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/plist"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/reg"
 	"github.com/spf13/cobra"
@@ -31,6 +36,7 @@ import (
 
 const plistFormat = "plist"
 const regFormat = "reg"
+const jsonFormat = "json"
 const defaultsType = "defaults"
 const lockedType = "locked"
 
@@ -44,10 +50,22 @@ var outputSettingsFlags struct {
 	Format              string
 	RegistryHive        string // Should be USER or SYSTEM!
 	RegistryProfileType string
+	OutputDir           string
 }
 var InputFile string
 var JSONBody string
 var UseCurrentSettings bool
+var NonDefaultSettingsOnly bool
+var LockedSettings []string
+
+// profileFileNames are the names of the defaults and locked profile files
+// written with --output-dir, for each format.  Both registry profiles go in
+// the same file.
+var profileFileNames = map[string][2]string{
+	plistFormat: {"io.rancherdesktop.profile.defaults.plist", "io.rancherdesktop.profile.locked.plist"},
+	regFormat:   {"rancher-desktop-profile.reg", "rancher-desktop-profile.reg"},
+	jsonFormat:  {"defaults.json", "locked.json"},
+}
 
 // createProfileCmd represents the createProfile command
 var createProfileCmd = &cobra.Command{
@@ -57,7 +75,17 @@ var createProfileCmd = &cobra.Command{
 You can either convert the current listings in operation, or
 specify a JSON snippet, and convert that to the desired target.
 macOS plist files can be placed in the appropriate directory, while ".reg" files
-can be imported into the Windows registry using the "eg import FILE" command.`,
+can be imported into the Windows registry using the "reg import FILE" command,
+and JSON files can be placed in /etc/rancher-desktop on Linux.
+
+Use --non-default to leave out settings that have their default values, and
+--lock to also generate a locked profile containing the given settings (with
+their current values), so users can't change them.  For plist and JSON output,
+the locked profile is a separate file, so --output-dir is required; the
+profiles are written there with the names Rancher Desktop looks for.
+
+> rdctl create-profile --from-current --non-default --lock kubernetes.enabled --output plist --output-dir .
+-- Writes io.rancherdesktop.profile.defaults.plist and io.rancherdesktop.profile.locked.plist`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.NoArgs(cmd, args); err != nil {
 			return err
@@ -73,12 +101,16 @@ can be imported into the Windows registry using the "eg import FILE" command.`,
 
 func init() {
 	rootCmd.AddCommand(createProfileCmd)
-	createProfileCmd.Flags().StringVar(&outputSettingsFlags.Format, "output", "", fmt.Sprintf("output format: %s|%s", plistFormat, regFormat))
+	createProfileCmd.Flags().StringVar(&outputSettingsFlags.Format, "output", "", fmt.Sprintf("output format: %s|%s|%s", plistFormat, regFormat, jsonFormat))
 	createProfileCmd.Flags().StringVar(&outputSettingsFlags.RegistryHive, "hive", "", fmt.Sprintf(`registry hive: %s|%s (default "%s")`, reg.HklmRegistryHive, reg.HkcuRegistryHive, reg.HklmRegistryHive))
 	createProfileCmd.Flags().StringVar(&outputSettingsFlags.RegistryProfileType, "type", "", fmt.Sprintf(`registry section: %s|%s (default "%s")`, defaultsType, lockedType, defaultsType))
 	createProfileCmd.Flags().StringVar(&InputFile, "input", "", "File containing a JSON document (- for standard input)")
 	createProfileCmd.Flags().StringVarP(&JSONBody, "body", "b", "", "Command-line option containing a JSON document")
 	createProfileCmd.Flags().BoolVar(&UseCurrentSettings, "from-settings", false, "Use current settings")
+	createProfileCmd.Flags().BoolVar(&UseCurrentSettings, "from-current", false, `Use current settings (same as "--from-settings")`)
+	createProfileCmd.Flags().BoolVar(&NonDefaultSettingsOnly, "non-default", false, "Leave out settings that have their default values")
+	createProfileCmd.Flags().StringSliceVar(&LockedSettings, "lock", nil, "Setting (such as kubernetes.enabled) to lock at its current value; may be repeated")
+	createProfileCmd.Flags().StringVar(&outputSettingsFlags.OutputDir, "output-dir", "", "Directory to write the profile files to, instead of standard output")
 }
 
 func createProfile() (string, error) {
@@ -112,24 +144,240 @@ func createProfile() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if outputSettingsFlags.Format == regFormat {
-		lines, err := reg.JsonToReg(outputSettingsFlags.RegistryHive, outputSettingsFlags.RegistryProfileType, string(output))
+	defaultsJSON, lockedJSON, err := buildProfiles(output)
+	if err != nil {
+		return "", err
+	}
+	if outputSettingsFlags.OutputDir != "" {
+		return writeProfiles(outputSettingsFlags.OutputDir, defaultsJSON, lockedJSON)
+	}
+	if lockedJSON == nil {
+		return convertProfile(outputSettingsFlags.RegistryProfileType, defaultsJSON)
+	}
+	// Only registry output can hold both profiles; see validateProfileFormatFlags.
+	defaultsText, err := convertProfile(defaultsType, defaultsJSON)
+	if err != nil {
+		return "", err
+	}
+	lockedText, err := convertProfile(lockedType, lockedJSON)
+	if err != nil {
+		return "", err
+	}
+	return combineRegProfiles(defaultsText, lockedText), nil
+}
+
+// convertProfile converts the profile JSON to the output format.
+func convertProfile(profileType string, profileJSON []byte) (string, error) {
+	switch outputSettingsFlags.Format {
+	case regFormat:
+		lines, err := reg.JsonToReg(outputSettingsFlags.RegistryHive, profileType, string(profileJSON))
 		if err != nil {
 			return "", err
 		}
 		return strings.Join(lines, "\n"), nil
-	} else if outputSettingsFlags.Format == plistFormat {
-		return plist.JsonToPlist(string(output))
+	case plistFormat:
+		return plist.JsonToPlist(string(profileJSON))
+	case jsonFormat:
+		var profile map[string]any
+		if err := json.Unmarshal(profileJSON, &profile); err != nil {
+			return "", fmt.Errorf("error in json: %s", err)
+		}
+		if _, ok := profile["version"]; !ok {
+			profile["version"] = options.CURRENT_SETTINGS_VERSION
+		}
+		result, err := json.MarshalIndent(profile, "", "  ")
+		return string(result), err
 	}
-	return "", fmt.Errorf(`internal error: expecting an output format of %q or %q, got %q`, regFormat, plistFormat, outputSettingsFlags.Format)
+	return "", fmt.Errorf(`internal error: expecting an output format of %q, %q or %q, got %q`, regFormat, plistFormat, jsonFormat, outputSettingsFlags.Format)
+}
+
+// buildProfiles returns the defaults profile, and the locked profile if any
+// settings are to be locked (otherwise nil).
+func buildProfiles(settingsJSON []byte) ([]byte, []byte, error) {
+	if !NonDefaultSettingsOnly && len(LockedSettings) == 0 {
+		return settingsJSON, nil, nil
+	}
+	var lockedJSON []byte
+	if len(LockedSettings) > 0 {
+		var settings map[string]any
+		if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+			return nil, nil, fmt.Errorf("error in json: %s", err)
+		}
+		locked, err := selectSettings(settings, LockedSettings)
+		if err != nil {
+			return nil, nil, err
+		}
+		if lockedJSON, err = json.Marshal(locked); err != nil {
+			return nil, nil, err
+		}
+	}
+	if NonDefaultSettingsOnly {
+		var settings map[string]any
+		if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+			return nil, nil, fmt.Errorf("error in json: %s", err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal([]byte(options.SettingsJSONSchema), &schema); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse settings schema: %w", err)
+		}
+		removeDefaultSettings(settings, schemaDefaults(schema))
+		var err error
+		if settingsJSON, err = json.Marshal(settings); err != nil {
+			return nil, nil, err
+		}
+	}
+	return settingsJSON, lockedJSON, nil
+}
+
+// schemaDefaults returns the default values in the settings schema, in the
+// same shape as the settings.
+func schemaDefaults(schema map[string]any) map[string]any {
+	defaults := map[string]any{}
+	properties, _ := schema["properties"].(map[string]any)
+	for name, property := range properties {
+		property, ok := property.(map[string]any)
+		if !ok {
+			continue
+		}
+		if value, ok := property["default"]; ok {
+			defaults[name] = value
+		} else if children := schemaDefaults(property); len(children) > 0 {
+			defaults[name] = children
+		}
+	}
+	return defaults
+}
+
+// removeDefaultSettings removes the settings that have their default values,
+// along with any objects that become empty as a result.  The version is kept,
+// as deployment profiles need it.
+func removeDefaultSettings(settings, defaults map[string]any) {
+	for name, value := range settings {
+		if name == "version" {
+			continue
+		}
+		defaultValue, ok := defaults[name]
+		if !ok {
+			continue
+		}
+		if reflect.DeepEqual(value, defaultValue) {
+			delete(settings, name)
+			continue
+		}
+		children, isMap := value.(map[string]any)
+		childDefaults, hasChildDefaults := defaultValue.(map[string]any)
+		if isMap && hasChildDefaults {
+			removeDefaultSettings(children, childDefaults)
+			if len(children) == 0 {
+				delete(settings, name)
+			}
+		}
+	}
+}
+
+// selectSettings returns the given settings (as dotted paths) with their
+// values, in the same shape as the settings.  Names containing dots, such as
+// image names in application.extensions.installed, are also supported.
+func selectSettings(settings map[string]any, paths []string) (map[string]any, error) {
+	selected := map[string]any{}
+	for _, path := range paths {
+		if err := selectSetting(settings, selected, strings.Split(path, ".")); err != nil {
+			return nil, fmt.Errorf("can't lock setting %q: %w", path, err)
+		}
+	}
+	if version, ok := settings["version"]; ok {
+		selected["version"] = version
+	}
+	return selected, nil
+}
+
+func selectSetting(settings, selected map[string]any, parts []string) error {
+	// Try the longest name first, so "a.b" is preferred over "a" > "b".
+	for i := len(parts); i > 0; i-- {
+		name := strings.Join(parts[:i], ".")
+		value, ok := settings[name]
+		if !ok {
+			continue
+		}
+		if i == len(parts) {
+			selected[name] = value
+			return nil
+		}
+		children, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%q is not an object", name)
+		}
+		selectedChildren, _ := selected[name].(map[string]any)
+		if selectedChildren == nil {
+			selectedChildren = map[string]any{}
+		}
+		if err := selectSetting(children, selectedChildren, parts[i:]); err != nil {
+			return err
+		}
+		selected[name] = selectedChildren
+		return nil
+	}
+	return fmt.Errorf("no such setting %q", strings.Join(parts, "."))
+}
+
+// combineRegProfiles combines two registry profiles into one file, dropping
+// the header and parent keys of the second one.
+func combineRegProfiles(first, second string) string {
+	lines := strings.Split(first, "\n")
+	for i, line := range strings.Split(second, "\n") {
+		if i == 0 || (strings.HasPrefix(line, "[") && slices.Contains(lines, line)) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeProfiles writes the profiles to files in the directory, returning a
+// description of what was written.
+func writeProfiles(dir string, defaultsJSON, lockedJSON []byte) (string, error) {
+	names := profileFileNames[outputSettingsFlags.Format]
+	profileType := outputSettingsFlags.RegistryProfileType
+	if profileType == "" {
+		profileType = defaultsType
+	}
+	defaultsText, err := convertProfile(profileType, defaultsJSON)
+	if err != nil {
+		return "", err
+	}
+	files := [][2]string{{names[0], defaultsText}}
+	if lockedJSON != nil {
+		lockedText, err := convertProfile(lockedType, lockedJSON)
+		if err != nil {
+			return "", err
+		}
+		if names[0] == names[1] {
+			files[0][1] = combineRegProfiles(defaultsText, lockedText)
+		} else {
+			files = append(files, [2]string{names[1], lockedText})
+		}
+	}
+	var written []string
+	for _, file := range files {
+		path := filepath.Join(dir, file[0])
+		contents := file[1]
+		if !strings.HasSuffix(contents, "\n") {
+			contents += "\n"
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			return "", fmt.Errorf("failed to write profile: %w", err)
+		}
+		written = append(written, fmt.Sprintf("Wrote %s", path))
+	}
+	return strings.Join(written, "\n"), nil
 }
 
 func validateProfileFormatFlags() error {
 	if outputSettingsFlags.Format == "" {
-		return fmt.Errorf(`an "--output FORMAT" option of either %q or %q must be specified`, plistFormat, regFormat)
+		return fmt.Errorf(`an "--output FORMAT" option of either %q, %q or %q must be specified`, plistFormat, regFormat, jsonFormat)
 	}
-	if outputSettingsFlags.Format != plistFormat && outputSettingsFlags.Format != regFormat {
-		return fmt.Errorf(`received unrecognized "--output FORMAT" option of %q; %q or %q must be specified`, outputSettingsFlags.Format, plistFormat, regFormat)
+	if _, ok := profileFileNames[outputSettingsFlags.Format]; !ok {
+		return fmt.Errorf(`received unrecognized "--output FORMAT" option of %q; %q, %q or %q must be specified`, outputSettingsFlags.Format, plistFormat, regFormat, jsonFormat)
 	}
 	if InputFile == "" && JSONBody == "" && !UseCurrentSettings {
 		return fmt.Errorf(`no input format specified: must specify exactly one input format of "--input FILE|-", "--body|-b STRING", or "--from-settings"`)
@@ -138,9 +386,18 @@ func validateProfileFormatFlags() error {
 		return fmt.Errorf(`too many input formats specified: must specify exactly one input format of "--input FILE|-", "--body|-b STRING", or "--from-settings"`)
 	}
 
-	if outputSettingsFlags.Format == plistFormat {
+	if len(LockedSettings) > 0 {
+		if outputSettingsFlags.RegistryProfileType != "" {
+			return fmt.Errorf(`"--type" can't be specified with "--lock", as both the defaults and locked profiles are generated`)
+		}
+		if outputSettingsFlags.Format != regFormat && outputSettingsFlags.OutputDir == "" {
+			return fmt.Errorf(`"--lock" with %q output requires "--output-dir", as the locked profile is a separate file`, outputSettingsFlags.Format)
+		}
+	}
+
+	if outputSettingsFlags.Format != regFormat {
 		if outputSettingsFlags.RegistryHive != "" || outputSettingsFlags.RegistryProfileType != "" {
-			return fmt.Errorf(`registry hive and type can't be specified with "plist"`)
+			return fmt.Errorf(`registry hive and type can't be specified with %q`, outputSettingsFlags.Format)
 		}
 		return nil
 	}
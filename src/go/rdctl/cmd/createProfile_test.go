@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDefaults(t *testing.T) {
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"version": {"type": "integer", "default": 14},
+			"kubernetes": {
+				"type": "object",
+				"properties": {
+					"enabled": {"type": "boolean", "default": true},
+					"version": {"type": "string"}
+				}
+			},
+			"noDefaults": {"type": "object", "properties": {"a": {"type": "string"}}}
+		}
+	}`), &schema))
+	assert.Equal(t, map[string]any{
+		"version":    float64(14),
+		"kubernetes": map[string]any{"enabled": true},
+	}, schemaDefaults(schema))
+}
+
+func TestRemoveDefaultSettings(t *testing.T) {
+	var settings map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"version": 14,
+		"kubernetes": {"enabled": true, "version": "1.30.1"},
+		"containerEngine": {"name": "moby", "allowedImages": {"patterns": []}},
+		"application": {"debug": true}
+	}`), &settings))
+	defaults := map[string]any{
+		"version":         float64(14),
+		"kubernetes":      map[string]any{"enabled": true, "version": ""},
+		"containerEngine": map[string]any{"name": "moby", "allowedImages": map[string]any{"patterns": []any{}}},
+		"application":     map[string]any{"debug": false},
+	}
+	removeDefaultSettings(settings, defaults)
+	assert.Equal(t, map[string]any{
+		"version":     float64(14),
+		"kubernetes":  map[string]any{"version": "1.30.1"},
+		"application": map[string]any{"debug": true},
+	}, settings)
+}
+
+func TestSelectSettings(t *testing.T) {
+	var settings map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"version": 14,
+		"kubernetes": {"enabled": true, "version": "1.30.1"},
+		"application": {"extensions": {"installed": {"example.com/ext": "1.0"}}},
+		"containerEngine": {"name": "moby"}
+	}`), &settings))
+
+	selected, err := selectSettings(settings, []string{"kubernetes.enabled", "application.extensions.installed.example.com/ext", "containerEngine"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"version":         float64(14),
+		"kubernetes":      map[string]any{"enabled": true},
+		"application":     map[string]any{"extensions": map[string]any{"installed": map[string]any{"example.com/ext": "1.0"}}},
+		"containerEngine": map[string]any{"name": "moby"},
+	}, selected)
+
+	_, err = selectSettings(settings, []string{"kubernetes.missing"})
+	assert.EqualError(t, err, `can't lock setting "kubernetes.missing": no such setting "missing"`)
+	_, err = selectSettings(settings, []string{"kubernetes.enabled.value"})
+	assert.ErrorContains(t, err, `"enabled" is not an object`)
+}
+
+func TestCombineRegProfiles(t *testing.T) {
+	first := strings.Join([]string{
+		"Windows Registry Editor Version 5.00",
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies]`,
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop]`,
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop\defaults]`,
+		`"version"=dword:e`,
+	}, "\n")
+	second := strings.Join([]string{
+		"Windows Registry Editor Version 5.00",
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies]`,
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop]`,
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop\locked]`,
+		`"version"=dword:e`,
+	}, "\n")
+	assert.Equal(t, first+"\n"+strings.Join([]string{
+		`[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop\locked]`,
+		`"version"=dword:e`,
+	}, "\n"), combineRegProfiles(first, second))
+}
+
+func TestCreateProfileWithLockedSettings(t *testing.T) {
+	saved := outputSettingsFlags
+	savedBody, savedLocked, savedNonDefault := JSONBody, LockedSettings, NonDefaultSettingsOnly
+	t.Cleanup(func() {
+		outputSettingsFlags = saved
+		JSONBody, LockedSettings, NonDefaultSettingsOnly = savedBody, savedLocked, savedNonDefault
+	})
+	JSONBody = `{"version": 14, "kubernetes": {"enabled": false, "version": "1.30.1"}}`
+	LockedSettings = []string{"kubernetes.enabled"}
+
+	outputSettingsFlags.Format = jsonFormat
+	outputSettingsFlags.OutputDir = ""
+	_, err := createProfile()
+	assert.ErrorContains(t, err, `"--lock" with "json" output requires "--output-dir"`)
+
+	dir := t.TempDir()
+	outputSettingsFlags.OutputDir = dir
+	result, err := createProfile()
+	require.NoError(t, err)
+	assert.Equal(t, "Wrote "+filepath.Join(dir, "defaults.json")+"\nWrote "+filepath.Join(dir, "locked.json"), result)
+	contents, err := os.ReadFile(filepath.Join(dir, "locked.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"version": 14, "kubernetes": {"enabled": false}}`, string(contents))
+
+	outputSettingsFlags = saved
+	outputSettingsFlags.Format = regFormat
+	result, err = createProfile()
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(result, "Windows Registry Editor Version 5.00"))
+	assert.Contains(t, result, `[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop\defaults\kubernetes]`)
+	assert.Contains(t, result, `[HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Rancher Desktop\locked\kubernetes]`)
+
+	outputSettingsFlags.RegistryProfileType = lockedType
+	_, err = createProfile()
+	assert.ErrorContains(t, err, `"--type" can't be specified with "--lock"`)
+}
@@ -0,0 +1,36 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// diskCmd represents the 'rdctl disk' command
+var diskCmd = &cobra.Command{
+	Use:   "disk",
+	Short: "Show and reclaim the disk space used by Rancher Desktop",
+	Long: `rdctl disk - show and reclaim the disk space used by Rancher Desktop
+
+"rdctl disk usage" reports the space used by images, containers, volumes, the
+build cache, Kubernetes, logs and snapshots; "rdctl disk prune" removes the
+unused ones, running the right prune commands for the current container engine.`,
+}
+
+func init() {
+	rootCmd.AddCommand(diskCmd)
+}
@@ -0,0 +1,326 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/spf13/cobra"
+)
+
+// diskPruneSelection describes what `rdctl disk prune` removes.
+type diskPruneSelection struct {
+	Containers bool
+	Images     bool
+	BuildCache bool
+	Volumes    bool
+	Logs       bool
+	All        bool
+	DryRun     bool
+}
+
+var diskPruneSettings diskPruneSelection
+
+// diskPruneCmd represents the 'rdctl disk prune' command
+var diskPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused data to reclaim disk space",
+	Long: `Remove unused data to reclaim disk space.  Select what to remove with the
+flags; for example:
+
+> rdctl disk prune --containers --images --dry-run
+-- Lists the stopped containers and unused images that would be removed
+> rdctl disk prune --all
+-- Removes stopped containers, unused images and volumes, the build cache,
+   and the application logs
+
+The prune commands are run in the VM with the CLI of the current container
+engine, so Rancher Desktop must be running.  Removing the logs requires
+Rancher Desktop to be stopped, as it keeps them open; with --all, the logs are
+skipped while it is running.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		selection := diskPruneSettings
+		if selection.All {
+			// The logs are handled separately, as they may be skipped.
+			selection.Containers, selection.Images, selection.BuildCache, selection.Volumes = true, true, true, true
+		}
+		if !selection.Containers && !selection.Images && !selection.BuildCache && !selection.Volumes && !selection.Logs && !selection.All {
+			return errors.New("nothing to prune: specify at least one of --containers, --images, --build-cache, --volumes, --logs or --all")
+		}
+		cmd.SilenceUsage = true
+		err := pruneDisk(cmd.OutOrStdout(), containerEngineCLI(), selection)
+		if errors.Is(err, errVMNotRunning) {
+			// The reason has already been reported.
+			os.Exit(1)
+		}
+		return err
+	},
+}
+
+func init() {
+	diskCmd.AddCommand(diskPruneCmd)
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.Containers, "containers", false, "Remove stopped containers")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.Images, "images", false, "Remove images not used by any container")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.BuildCache, "build-cache", false, "Remove the build cache")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.Volumes, "volumes", false, "Remove volumes not used by any container")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.Logs, "logs", false, "Remove the application logs")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.All, "all", false, "Remove all of the above")
+	diskPruneCmd.Flags().BoolVar(&diskPruneSettings.DryRun, "dry-run", false, "Only list what would be removed")
+}
+
+// pruneCommands returns the commands to run in the VM to remove the selected
+// data; containers are removed first, so that their images and volumes can be
+// removed as well.
+func pruneCommands(engineCLI []string, containers, images, buildCache, volumes bool) [][]string {
+	var commands [][]string
+	add := func(selected bool, args ...string) {
+		if selected {
+			commands = append(commands, append(append([]string{}, engineCLI...), args...))
+		}
+	}
+	add(containers, "container", "prune", "--force")
+	add(images, "image", "prune", "--all", "--force")
+	add(buildCache, "builder", "prune", "--all", "--force")
+	add(volumes, "volume", "prune", "--all", "--force")
+	return commands
+}
+
+// listLogFiles returns the files in the logs directory, and their total size.
+func listLogFiles(logsDir string) ([]string, uint64, error) {
+	var files []string
+	var total uint64
+	err := filepath.WalkDir(logsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, path)
+			total += uint64(info.Size())
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	return files, total, err
+}
+
+// isAppRunning checks if the application is running, in which case it keeps
+// its log files open.
+func isAppRunning() bool {
+	connectionInfo, err := config.GetConnectionInfo(true)
+	if err != nil || connectionInfo == nil {
+		return false
+	}
+	_, err = client.NewRDClient(connectionInfo).GetBackendState()
+	return err == nil
+}
+
+// errAppRunning is returned when the logs are explicitly selected for removal
+// while the application is running.
+var errAppRunning = errors.New("quit Rancher Desktop before removing the logs")
+
+// pruneDisk removes the selected data, or lists it if selection.DryRun is set.
+// The data in the VM is removed first, as that requires the application to be
+// running, which is not possible for the logs; if the logs were only selected
+// by --all (selection.Logs is not set), they are skipped while it is running.
+func pruneDisk(w io.Writer, engineCLI []string, selection diskPruneSelection) error {
+	var logFiles []string
+	var logSize uint64
+	appRunning := false
+	logs := selection.Logs || selection.All
+	if logs {
+		appPaths, err := paths.GetPaths()
+		if err != nil {
+			return fmt.Errorf("failed to get paths: %w", err)
+		}
+		if logFiles, logSize, err = listLogFiles(appPaths.Logs); err != nil {
+			return fmt.Errorf("failed to list log files: %w", err)
+		}
+		appRunning = isAppRunning()
+		if appRunning && selection.Logs && !selection.DryRun {
+			return errAppRunning
+		}
+	}
+	if selection.DryRun {
+		return describePrune(w, engineCLI, selection, runListCommand, len(logFiles), logSize, appRunning)
+	}
+	for _, args := range pruneCommands(engineCLI, selection.Containers, selection.Images, selection.BuildCache, selection.Volumes) {
+		command, err := newRootShellCommand(args...)
+		if err != nil {
+			return err
+		}
+		command.Stdout = w
+		command.Stderr = os.Stderr
+		if err := command.Run(); err != nil {
+			return fmt.Errorf("failed to run %q: %w", strings.Join(args, " "), err)
+		}
+	}
+	if logs {
+		if appRunning {
+			fmt.Fprintf(w, "Skipped %d log files (%s): quit Rancher Desktop to remove them\n", len(logFiles), formatBytes(logSize))
+			return nil
+		}
+		for _, file := range logFiles {
+			if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove log file: %w", err)
+			}
+		}
+		fmt.Fprintf(w, "Removed %d log files (%s)\n", len(logFiles), formatBytes(logSize))
+	}
+	return nil
+}
+
+// runListCommand runs a command in the VM that lists data, returning its
+// output.
+func runListCommand(args []string) (string, error) {
+	command, err := newRootShellCommandWithOptions(shellOptions{quiet: true}, args...)
+	if err != nil {
+		return "", err
+	}
+	command.Stderr = os.Stderr
+	rawOutput, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %q: %w", strings.Join(args, " "), err)
+	}
+	return string(rawOutput), nil
+}
+
+// outputLines returns the non-empty lines of the output of a command.
+func outputLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// unusedImages returns the images that `image prune --all` would remove: those
+// not used by any container.  Each image is given as "<ID> <repository>:<tag>",
+// and containers refer to their image by reference or by (short) ID.
+func unusedImages(images, containerImages []string) []string {
+	used := map[string]bool{}
+	for _, image := range containerImages {
+		used[image] = true
+		if !strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+			used[image+":latest"] = true
+		}
+	}
+	var unused []string
+	for _, image := range images {
+		id, ref, _ := strings.Cut(image, " ")
+		id = strings.TrimPrefix(id, "sha256:")
+		inUse := used[ref]
+		for usedImage := range used {
+			usedImage = strings.TrimPrefix(usedImage, "sha256:")
+			if len(usedImage) >= shortImageIDLength && isHex(usedImage) && strings.HasPrefix(id, usedImage) {
+				inUse = true
+			}
+		}
+		if inUse {
+			continue
+		}
+		if ref == "" || ref == "<none>:<none>" {
+			ref = id
+		}
+		unused = append(unused, ref)
+	}
+	return unused
+}
+
+// shortImageIDLength is the length of the image IDs shown by the container
+// engine CLIs.
+const shortImageIDLength = 12
+
+// isHex checks if the string only consists of lower case hexadecimal digits.
+func isHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// describePrune lists the data that pruneDisk would remove, running the
+// listing commands in the VM with the given function.
+func describePrune(w io.Writer, engineCLI []string, selection diskPruneSelection, run func([]string) (string, error), logFiles int, logSize uint64, appRunning bool) error {
+	list := func(args ...string) ([]string, error) {
+		output, err := run(append(append([]string{}, engineCLI...), args...))
+		return outputLines(output), err
+	}
+	report := func(description string, items []string) {
+		fmt.Fprintf(w, "Would remove %d %s\n", len(items), description)
+		for _, item := range items {
+			fmt.Fprintf(w, "  %s\n", item)
+		}
+	}
+	if selection.Containers {
+		containers, err := list("container", "ls", "--all", "--filter", "status=exited", "--filter", "status=created", "--format", "{{.Names}}")
+		if err != nil {
+			return err
+		}
+		report("stopped containers", containers)
+	}
+	if selection.Images {
+		images, err := list("image", "ls", "--format", "{{.ID}} {{.Repository}}:{{.Tag}}")
+		if err != nil {
+			return err
+		}
+		var containerImages []string
+		// Stopped containers are removed first, so their images are unused too.
+		if !selection.Containers {
+			if containerImages, err = list("container", "ls", "--all", "--format", "{{.Image}}"); err != nil {
+				return err
+			}
+		} else if containerImages, err = list("container", "ls", "--format", "{{.Image}}"); err != nil {
+			return err
+		}
+		report("unused images", unusedImages(images, containerImages))
+	}
+	if selection.BuildCache {
+		fmt.Fprintln(w, "Would remove the build cache")
+	}
+	if selection.Volumes {
+		volumes, err := list("volume", "ls", "--filter", "dangling=true", "--format", "{{.Name}}")
+		if err != nil {
+			return err
+		}
+		report("unused volumes", volumes)
+	}
+	if selection.Logs || selection.All {
+		if appRunning && selection.Logs {
+			fmt.Fprintf(w, "Would fail to remove %d log files (%s): %s\n", logFiles, formatBytes(logSize), errAppRunning)
+		} else if appRunning {
+			fmt.Fprintf(w, "Would skip %d log files (%s) while Rancher Desktop is running\n", logFiles, formatBytes(logSize))
+		} else {
+			fmt.Fprintf(w, "Would remove %d log files (%s)\n", logFiles, formatBytes(logSize))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/spf13/cobra"
+)
+
+// diskUsageCmd represents the 'rdctl disk usage' command
+var diskUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show the disk space used by Rancher Desktop",
+	Long: `Show the disk space used by Rancher Desktop, and how much of it can be
+reclaimed with "rdctl disk prune".
+
+With the containerd engine, the images include the layers of the containers,
+and the space that can be reclaimed is not known in advance.  Kubernetes images
+are included in the images of the container engine; the Kubernetes data can be
+deleted with "rdctl reset --kubernetes", and snapshots with
+"rdctl snapshot delete".`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		appPaths, err := paths.GetPaths()
+		if err != nil {
+			return fmt.Errorf("failed to get paths: %w", err)
+		}
+		return renderOutput(format, collectDiskUsage(containerEngineCLI(), appPaths, runDiskUsageScript))
+	},
+}
+
+func init() {
+	diskCmd.AddCommand(diskUsageCmd)
+	defineOutputFlag(diskUsageCmd)
+}
+
+// The categories of disk usage.
+const (
+	diskUsageImages     = "images"
+	diskUsageContainers = "containers"
+	diskUsageVolumes    = "volumes"
+	diskUsageBuildCache = "build-cache"
+	diskUsageKubernetes = "kubernetes"
+	diskUsageLogs       = "logs"
+	diskUsageSnapshots  = "snapshots"
+)
+
+// diskUsageEntry is the space used by one category of data.
+type diskUsageEntry struct {
+	Category string `json:"category"`
+	Location string `json:"location"`
+	Size     uint64 `json:"size"`
+	// Reclaimable is the space `rdctl disk prune` would free, if known.
+	Reclaimable *uint64 `json:"reclaimable,omitempty"`
+	// Error describes why the usage could not be determined.
+	Error string `json:"error,omitempty"`
+}
+
+type diskUsageTable []diskUsageEntry
+
+func (t diskUsageTable) Headers() []string {
+	return []string{"CATEGORY", "SIZE", "RECLAIMABLE", "LOCATION"}
+}
+
+func (t diskUsageTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, entry := range t {
+		size, reclaimable := formatBytes(entry.Size), "-"
+		if entry.Reclaimable != nil {
+			reclaimable = formatBytes(*entry.Reclaimable)
+		}
+		if entry.Error != "" {
+			size, reclaimable = fmt.Sprintf("unavailable (%s)", entry.Error), "-"
+		}
+		rows = append(rows, []string{entry.Category, size, reclaimable, entry.Location})
+	}
+	return rows
+}
+
+// The directories in the VM whose sizes are reported.
+const (
+	containerdDataDir = "/var/lib/containerd"
+	nerdctlDataDir    = "/var/lib/nerdctl"
+	buildkitDataDir   = "/var/lib/buildkit"
+	k3sDataDir        = "/var/lib/rancher/k3s"
+)
+
+// usesNerdctl returns whether the container engine CLI is nerdctl, i.e. the
+// containerd engine is used.
+func usesNerdctl(engineCLI []string) bool {
+	return engineCLI[0] == "nerdctl"
+}
+
+// diskUsageSectionSeparator separates the output of the commands run in the
+// VM.
+const diskUsageSectionSeparator = "--- rdctl disk usage ---"
+
+// diskUsageScript returns the script run in the VM to measure the disk usage:
+// the sizes of the data directories, followed by `docker system df` if the
+// moby engine is used (it knows what can be reclaimed).
+func diskUsageScript(engineCLI []string) string {
+	dirs := []string{k3sDataDir}
+	dockerDF := "true"
+	if usesNerdctl(engineCLI) {
+		dirs = append(dirs, containerdDataDir, nerdctlDataDir, buildkitDataDir)
+	} else {
+		dockerDF = strings.Join(engineCLI, " ") + " system df --format '{{json .}}'"
+	}
+	return strings.Join([]string{
+		fmt.Sprintf(`for dir in %s; do [ -d "$dir" ] && du -sk "$dir"; done`, strings.Join(dirs, " ")),
+		"echo '" + diskUsageSectionSeparator + "'",
+		dockerDF,
+	}, "; ")
+}
+
+// runDiskUsageScript runs the script in the VM, returning its output.
+func runDiskUsageScript(script string) (string, error) {
+	command, err := newRootShellCommandWithOptions(shellOptions{quiet: true}, "sh", "-c", script)
+	if err != nil {
+		return "", err
+	}
+	rawOutput, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to measure disk usage in the VM: %w", err)
+	}
+	return string(rawOutput), nil
+}
+
+// collectDiskUsage measures the disk space used by Rancher Desktop; the
+// categories that can't be measured are reported with the reason.
+func collectDiskUsage(engineCLI []string, appPaths paths.Paths, runScript func(string) (string, error)) diskUsageTable {
+	var entries diskUsageTable
+	vmEntries, err := parseDiskUsage(engineCLI, runScript)
+	if err != nil {
+		for _, category := range vmDiskUsageCategories(engineCLI) {
+			entries = append(entries, diskUsageEntry{Category: category, Location: componentInVM, Error: err.Error()})
+		}
+	} else {
+		entries = append(entries, vmEntries...)
+	}
+	logs := hostDirUsage(diskUsageLogs, appPaths.Logs)
+	if logs.Error == "" {
+		logs.Reclaimable = &logs.Size
+	}
+	return append(entries, logs, hostDirUsage(diskUsageSnapshots, appPaths.Snapshots))
+}
+
+// vmDiskUsageCategories returns the categories measured in the VM.
+func vmDiskUsageCategories(engineCLI []string) []string {
+	if usesNerdctl(engineCLI) {
+		return []string{diskUsageImages, diskUsageVolumes, diskUsageBuildCache, diskUsageKubernetes}
+	}
+	return []string{diskUsageImages, diskUsageContainers, diskUsageVolumes, diskUsageBuildCache, diskUsageKubernetes}
+}
+
+// parseDiskUsage runs the disk usage script, and parses its output.
+func parseDiskUsage(engineCLI []string, runScript func(string) (string, error)) (diskUsageTable, error) {
+	rawOutput, err := runScript(diskUsageScript(engineCLI))
+	if err != nil {
+		if errors.Is(err, errVMNotRunning) {
+			return nil, errors.New("the VM is not running")
+		}
+		return nil, err
+	}
+	duOutput, dfOutput, found := strings.Cut(rawOutput, diskUsageSectionSeparator+"\n")
+	if !found {
+		return nil, fmt.Errorf("unexpected output %q", rawOutput)
+	}
+	dirSizes := map[string]uint64{}
+	for _, line := range strings.Split(strings.TrimSpace(duOutput), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected du output %q", line)
+		}
+		dirSizes[fields[1]] = size * 1024
+	}
+	dirEntry := func(category, dir string) diskUsageEntry {
+		return diskUsageEntry{Category: category, Location: componentInVM, Size: dirSizes[dir]}
+	}
+	var entries diskUsageTable
+	if usesNerdctl(engineCLI) {
+		entries = diskUsageTable{
+			dirEntry(diskUsageImages, containerdDataDir),
+			dirEntry(diskUsageVolumes, nerdctlDataDir),
+			dirEntry(diskUsageBuildCache, buildkitDataDir),
+		}
+	} else {
+		if entries, err = parseDockerSystemDF(dfOutput); err != nil {
+			return nil, err
+		}
+	}
+	return append(entries, dirEntry(diskUsageKubernetes, k3sDataDir)), nil
+}
+
+// dockerDFCategories maps the types reported by `docker system df` to our
+// categories.
+var dockerDFCategories = map[string]string{
+	"Images":        diskUsageImages,
+	"Containers":    diskUsageContainers,
+	"Local Volumes": diskUsageVolumes,
+	"Build Cache":   diskUsageBuildCache,
+}
+
+// parseDockerSystemDF parses the output of `docker system df --format '{{json .}}'`.
+func parseDockerSystemDF(rawOutput string) (diskUsageTable, error) {
+	var entries diskUsageTable
+	for _, line := range strings.Split(strings.TrimSpace(rawOutput), "\n") {
+		if line == "" {
+			continue
+		}
+		var row struct {
+			Type        string
+			Size        string
+			Reclaimable string
+		}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return nil, fmt.Errorf("failed to parse docker system df output %q: %w", line, err)
+		}
+		category, ok := dockerDFCategories[row.Type]
+		if !ok {
+			continue
+		}
+		size, err := parseHumanSize(row.Size)
+		if err != nil {
+			return nil, err
+		}
+		// The reclaimable space may be followed by a percentage, e.g. "1.2GB (50%)".
+		reclaimableField, _, _ := strings.Cut(row.Reclaimable, " ")
+		reclaimable, err := parseHumanSize(reclaimableField)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, diskUsageEntry{Category: category, Location: componentInVM, Size: size, Reclaimable: &reclaimable})
+	}
+	return entries, nil
+}
+
+// humanSizeUnits are the (decimal) units used by docker for sizes.
+var humanSizeUnits = map[string]float64{
+	"B": 1, "kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15,
+}
+
+// parseHumanSize parses a size as formatted by docker, e.g. "1.23GB".
+func parseHumanSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	number := strings.TrimRight(value, "kKMGTPB")
+	multiplier, ok := humanSizeUnits[value[len(number):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	result, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return uint64(result * multiplier), nil
+}
+
+// hostDirUsage returns the total size of the files in a directory on the
+// host; a missing directory is empty.
+func hostDirUsage(category, dir string) diskUsageEntry {
+	entry := diskUsageEntry{Category: category, Location: componentOnHost}
+	if dir == "" {
+		return entry
+	}
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			entry.Size += uint64(info.Size())
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		entry.Error = err.Error()
+	}
+	return entry
+}
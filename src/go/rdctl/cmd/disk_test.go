@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHumanSize(t *testing.T) {
+	for input, expected := range map[string]uint64{
+		"0B":     0,
+		"512B":   512,
+		"1.5kB":  1500,
+		"2.4GB":  2_400_000_000,
+		"123MB":  123_000_000,
+		"1.01TB": 1_010_000_000_000,
+	} {
+		t.Run(input, func(t *testing.T) {
+			actual, err := parseHumanSize(input)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+	for _, input := range []string{"", "GB", "12XB", "1.2.3MB"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := parseHumanSize(input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func uint64Pointer(value uint64) *uint64 {
+	return &value
+}
+
+func TestCollectDiskUsage(t *testing.T) {
+	logsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "background.log"), make([]byte, 100), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(logsDir, "steve"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "steve", "steve.log"), make([]byte, 50), 0o644))
+	appPaths := paths.Paths{Logs: logsDir, Snapshots: filepath.Join(t.TempDir(), "missing")}
+	hostEntries := diskUsageTable{
+		{Category: diskUsageLogs, Location: componentOnHost, Size: 150, Reclaimable: uint64Pointer(150)},
+		{Category: diskUsageSnapshots, Location: componentOnHost},
+	}
+
+	t.Run("moby", func(t *testing.T) {
+		script := func(script string) (string, error) {
+			assert.Contains(t, script, "docker system df --format '{{json .}}'")
+			return "2048\t/var/lib/rancher/k3s\n" + diskUsageSectionSeparator + "\n" +
+				`{"Active":"1","Reclaimable":"1.2GB (50%)","Size":"2.4GB","TotalCount":"3","Type":"Images"}` + "\n" +
+				`{"Active":"1","Reclaimable":"0B (0%)","Size":"12kB","TotalCount":"2","Type":"Containers"}` + "\n" +
+				`{"Active":"0","Reclaimable":"1MB (100%)","Size":"1MB","TotalCount":"1","Type":"Local Volumes"}` + "\n" +
+				`{"Active":"0","Reclaimable":"300MB","Size":"300MB","TotalCount":"7","Type":"Build Cache"}` + "\n", nil
+		}
+		assert.Equal(t, append(diskUsageTable{
+			{Category: diskUsageImages, Location: componentInVM, Size: 2_400_000_000, Reclaimable: uint64Pointer(1_200_000_000)},
+			{Category: diskUsageContainers, Location: componentInVM, Size: 12_000, Reclaimable: uint64Pointer(0)},
+			{Category: diskUsageVolumes, Location: componentInVM, Size: 1_000_000, Reclaimable: uint64Pointer(1_000_000)},
+			{Category: diskUsageBuildCache, Location: componentInVM, Size: 300_000_000, Reclaimable: uint64Pointer(300_000_000)},
+			{Category: diskUsageKubernetes, Location: componentInVM, Size: 2048 * 1024},
+		}, hostEntries...), collectDiskUsage([]string{"docker"}, appPaths, script))
+	})
+	t.Run("containerd", func(t *testing.T) {
+		script := func(script string) (string, error) {
+			assert.NotContains(t, script, "system df")
+			return "10\t/var/lib/rancher/k3s\n20\t/var/lib/containerd\n30\t/var/lib/buildkit\n" + diskUsageSectionSeparator + "\n", nil
+		}
+		assert.Equal(t, append(diskUsageTable{
+			{Category: diskUsageImages, Location: componentInVM, Size: 20 * 1024},
+			{Category: diskUsageVolumes, Location: componentInVM},
+			{Category: diskUsageBuildCache, Location: componentInVM, Size: 30 * 1024},
+			{Category: diskUsageKubernetes, Location: componentInVM, Size: 10 * 1024},
+		}, hostEntries...), collectDiskUsage([]string{"nerdctl", "--namespace", "default"}, appPaths, script))
+	})
+	t.Run("VM not running", func(t *testing.T) {
+		script := func(string) (string, error) {
+			return "", errVMNotRunning
+		}
+		entries := collectDiskUsage([]string{"nerdctl"}, appPaths, script)
+		require.Len(t, entries, 6)
+		assert.Equal(t, diskUsageEntry{Category: diskUsageImages, Location: componentInVM, Error: "the VM is not running"}, entries[0])
+		assert.Equal(t, []string{"images", "unavailable (the VM is not running)", "-", "vm"}, entries.Rows()[0])
+		assert.Equal(t, []string{"logs", "150B", "150B", "host"}, entries.Rows()[4])
+	})
+}
+
+func TestPruneCommands(t *testing.T) {
+	assert.Equal(t, [][]string{
+		{"nerdctl", "--namespace", "k8s.io", "container", "prune", "--force"},
+		{"nerdctl", "--namespace", "k8s.io", "volume", "prune", "--all", "--force"},
+	}, pruneCommands([]string{"nerdctl", "--namespace", "k8s.io"}, true, false, false, true))
+	assert.Equal(t, [][]string{
+		{"docker", "container", "prune", "--force"},
+		{"docker", "image", "prune", "--all", "--force"},
+		{"docker", "builder", "prune", "--all", "--force"},
+		{"docker", "volume", "prune", "--all", "--force"},
+	}, pruneCommands([]string{"docker"}, true, true, true, true))
+	assert.Empty(t, pruneCommands([]string{"docker"}, false, false, false, false))
+}
+
+func TestUnusedImages(t *testing.T) {
+	images := []string{
+		"0123456789ab nginx:latest",
+		"123456789abc alpine:3.20",
+		"23456789abcd busybox:latest",
+		"3456789abcde <none>:<none>",
+		"456789abcdef registry.local:5000/app:v1",
+	}
+	assert.Equal(t, []string{"busybox:latest", "3456789abcde"},
+		unusedImages(images, []string{"nginx", "123456789abc", "registry.local:5000/app:v1"}))
+	assert.Equal(t, []string{"nginx:latest"}, unusedImages(images[:1], []string{"0123"}),
+		"short prefixes should not match image IDs")
+}
+
+func TestDescribePrune(t *testing.T) {
+	outputs := map[string]string{
+		"docker container ls --all --filter status=exited --filter status=created --format {{.Names}}": "old\nbroken\n",
+		"docker image ls --format {{.ID}} {{.Repository}}:{{.Tag}}":                                    "0123456789ab nginx:latest\n123456789abc alpine:latest\n",
+		"docker container ls --format {{.Image}}":                                                      "nginx\n",
+		"docker container ls --all --format {{.Image}}":                                                "nginx\nalpine\n",
+		"docker volume ls --filter dangling=true --format {{.Name}}":                                   "\n",
+	}
+	run := func(args []string) (string, error) {
+		output, ok := outputs[strings.Join(args, " ")]
+		require.True(t, ok, "unexpected command %q", args)
+		return output, nil
+	}
+	t.Run("all", func(t *testing.T) {
+		var w strings.Builder
+		selection := diskPruneSelection{Containers: true, Images: true, BuildCache: true, Volumes: true, All: true}
+		require.NoError(t, describePrune(&w, []string{"docker"}, selection, run, 3, 2048, false))
+		assert.Equal(t, `Would remove 2 stopped containers
+  old
+  broken
+Would remove 1 unused images
+  alpine:latest
+Would remove the build cache
+Would remove 0 unused volumes
+Would remove 3 log files (2.00KiB)
+`, w.String())
+	})
+	t.Run("images only", func(t *testing.T) {
+		var w strings.Builder
+		require.NoError(t, describePrune(&w, []string{"docker"}, diskPruneSelection{Images: true}, run, 0, 0, false))
+		assert.Equal(t, "Would remove 0 unused images\n", w.String(),
+			"images used by stopped containers are kept if the containers are not removed")
+	})
+	t.Run("logs while running", func(t *testing.T) {
+		var w strings.Builder
+		require.NoError(t, describePrune(&w, []string{"docker"}, diskPruneSelection{All: true}, run, 3, 2048, true))
+		assert.Equal(t, "Would skip 3 log files (2.00KiB) while Rancher Desktop is running\n", w.String())
+	})
+	t.Run("VM not running", func(t *testing.T) {
+		run := func([]string) (string, error) {
+			return "", errVMNotRunning
+		}
+		err := describePrune(io.Discard, []string{"docker"}, diskPruneSelection{Volumes: true}, run, 0, 0, false)
+		assert.ErrorIs(t, err, errVMNotRunning)
+	})
+}
+
+func TestListLogFiles(t *testing.T) {
+	logsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "a.log"), make([]byte, 10), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(logsDir, "b.log"), make([]byte, 5), 0o644))
+	files, total, err := listLogFiles(logsDir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(logsDir, "a.log"), filepath.Join(logsDir, "b.log")}, files)
+	assert.Equal(t, uint64(15), total)
+
+	files, total, err = listLogFiles(filepath.Join(logsDir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.Zero(t, total)
+}
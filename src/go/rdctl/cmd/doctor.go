@@ -0,0 +1,190 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var doctorCategories []string
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Run the Rancher Desktop diagnostics checks",
+	Long: `Run all of the Rancher Desktop diagnostics checks, and report on whether each
+one passed.  Failed checks that have been muted in the application are reported
+as warnings.  The command exits with a non-zero status if any check that is not
+muted failed, so it can be used as a pre-flight check in scripts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault("")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return runDoctor(format)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	defineOutputFlag(doctorCmd)
+	doctorCmd.Flags().StringSliceVar(&doctorCategories, "category", nil, "Only report on checks in the given categories; may be repeated")
+}
+
+// diagnosticsResult is a single result from the diagnostic_checks endpoint.
+type diagnosticsResult struct {
+	ID            string `json:"id"`
+	Category      string `json:"category"`
+	Description   string `json:"description"`
+	Documentation string `json:"documentation,omitempty"`
+	Passed        bool   `json:"passed"`
+	Mute          bool   `json:"mute"`
+	Fixes         []struct {
+		Description string `json:"description"`
+	} `json:"fixes"`
+}
+
+// status returns how the result is reported: pass, warn, or fail.
+func (r diagnosticsResult) status() string {
+	switch {
+	case r.Passed:
+		return "pass"
+	case r.Mute:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// diagnosticsReport is the list of results, in the order they are reported.
+type diagnosticsReport []diagnosticsResult
+
+func (r diagnosticsReport) Headers() []string {
+	return []string{"STATUS", "CATEGORY", "ID", "DESCRIPTION"}
+}
+
+func (r diagnosticsReport) Rows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, result := range r {
+		rows = append(rows, []string{strings.ToUpper(result.status()), result.Category, result.ID, result.Description})
+	}
+	return rows
+}
+
+// failures returns the number of failed checks that are not muted.
+func (r diagnosticsReport) failures() int {
+	count := 0
+	for _, result := range r {
+		if result.status() == "fail" {
+			count++
+		}
+	}
+	return count
+}
+
+// newDiagnosticsReport sorts the results by category and ID, keeping only
+// the given categories (or all of them, if none are given).
+func newDiagnosticsReport(results []diagnosticsResult, categories []string) diagnosticsReport {
+	report := make(diagnosticsReport, 0, len(results))
+	for _, result := range results {
+		if len(categories) > 0 && !containsFold(categories, result.Category) {
+			continue
+		}
+		report = append(report, result)
+	}
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Category != report[j].Category {
+			return report[i].Category < report[j].Category
+		}
+		return report[i].ID < report[j].ID
+	})
+	return report
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// printDiagnosticsReport writes the results in a human-readable form, with
+// the remediation steps for each check that did not pass.
+func printDiagnosticsReport(w io.Writer, report diagnosticsReport) {
+	for _, result := range report {
+		fmt.Fprintf(w, "[%s] %s: %s\n", strings.ToUpper(result.status()), result.Category, result.Description)
+		if result.Passed {
+			continue
+		}
+		for _, fix := range result.Fixes {
+			fmt.Fprintf(w, "       Fix: %s\n", fix.Description)
+		}
+		if result.Documentation != "" {
+			fmt.Fprintf(w, "       See: %s\n", result.Documentation)
+		}
+	}
+	counts := map[string]int{}
+	for _, result := range report {
+		counts[result.status()]++
+	}
+	fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", counts["pass"], counts["warn"], counts["fail"])
+}
+
+func runDoctor(format output.Format) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	endpoint := client.VersionCommand("", "diagnostic_checks")
+	result, errorPacket, err := client.ProcessRequestForAPI(rdClient.DoRequest("POST", endpoint))
+	if errorPacket != nil || err != nil {
+		return displayAPICallResult([]byte{}, errorPacket, err)
+	}
+	var collection struct {
+		Checks []diagnosticsResult `json:"checks"`
+	}
+	if err := json.Unmarshal(result, &collection); err != nil {
+		return fmt.Errorf("failed to unmarshal diagnostics API response: %w", err)
+	}
+	report := newDiagnosticsReport(collection.Checks, doctorCategories)
+	if format != "" {
+		if err := renderOutput(format, report); err != nil {
+			return err
+		}
+	} else {
+		printDiagnosticsReport(os.Stdout, report)
+	}
+	if failures := report.failures(); failures > 0 {
+		return fmt.Errorf("%d diagnostics checks failed", failures)
+	}
+	return nil
+}
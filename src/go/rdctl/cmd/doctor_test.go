@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticsReport(t *testing.T) {
+	var results []diagnosticsResult
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"id": "RD_BIN_IN_BASH_PATH", "category": "Utilities", "description": "rdctl is not in PATH", "passed": false, "mute": false,
+		 "documentation": "https://example.com/path", "fixes": [{"description": "Add ~/.rd/bin to PATH"}]},
+		{"id": "CONNECTED_TO_INTERNET", "category": "Networking", "description": "Connected to the internet", "passed": true, "mute": false, "fixes": []},
+		{"id": "MOCK_CHECKER", "category": "Testing", "description": "Muted failure", "passed": false, "mute": true, "fixes": []}
+	]`), &results))
+
+	report := newDiagnosticsReport(results, nil)
+	assert.Equal(t, []string{"CONNECTED_TO_INTERNET", "MOCK_CHECKER", "RD_BIN_IN_BASH_PATH"},
+		[]string{report[0].ID, report[1].ID, report[2].ID})
+	assert.Equal(t, 1, report.failures())
+	assert.Equal(t, []string{"WARN", "Testing", "MOCK_CHECKER", "Muted failure"}, report.Rows()[1])
+
+	var buf bytes.Buffer
+	printDiagnosticsReport(&buf, report)
+	assert.Equal(t, `[PASS] Networking: Connected to the internet
+[WARN] Testing: Muted failure
+[FAIL] Utilities: rdctl is not in PATH
+       Fix: Add ~/.rd/bin to PATH
+       See: https://example.com/path
+
+1 passed, 1 warnings, 1 failed
+`, buf.String())
+
+	filtered := newDiagnosticsReport(results, []string{"networking"})
+	assert.Len(t, filtered, 1)
+	assert.Zero(t, filtered.failures())
+}
@@ -26,9 +26,9 @@ import (
 // extensionCmd represents the extension command
 var extensionCmd = &cobra.Command{
 	Short: "Manage extensions",
-	Long: `rdctl extension - manage installed extensions
+	Long: `rdctl extension - manage installed extensions, and search the extension catalog
 `,
-	Use: "extension [install | uninstall | list] [options...]",
+	Use: "extension [install | uninstall | list | search | info] [options...]",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 		return fmt.Errorf("No subcommand given.\n\nUsage: rdctl %s", cmd.Use)
@@ -0,0 +1,183 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// maxListedVersions is the number of versions shown by `rdctl extension info`
+// (unless an output format is requested).
+const maxListedVersions = 10
+
+// infoCmd represents the 'rdctl extension info' command
+var infoCmd = &cobra.Command{
+	Use:   "info <id-or-name>",
+	Short: "Show details of an extension in the catalog",
+	Long: `Show the details of an extension in the Rancher Desktop extension catalog,
+including whether it is compatible with the current container engine and this
+platform, and which versions are available to install.  The extension can be
+given by its ID (the image reference, such as docker/logs-explorer-extension)
+or by its name.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault("")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		catalog, err := getCatalogExtensions()
+		if err != nil {
+			return err
+		}
+		ext, err := findCatalogExtension(catalog, args[0])
+		if err != nil {
+			return err
+		}
+		details := extensionDetails{catalogExtension: ext}
+		details.Versions, details.versionsErr = getExtensionVersions(ext.ID)
+		if format != "" {
+			if details.versionsErr != nil {
+				return details.versionsErr
+			}
+			return renderOutput(format, details)
+		}
+		printExtensionDetails(os.Stdout, details)
+		return nil
+	},
+}
+
+func init() {
+	extensionCmd.AddCommand(infoCmd)
+	defineOutputFlag(infoCmd)
+}
+
+// extensionDetails is a catalog entry together with its available versions.
+type extensionDetails struct {
+	catalogExtension
+	Versions    []string `json:"versions"`
+	versionsErr error
+}
+
+// findCatalogExtension finds the extension with the given ID (ignoring any
+// tag) or name (ignoring case).
+func findCatalogExtension(catalog []catalogExtension, idOrName string) (catalogExtension, error) {
+	id := idOrName
+	if index := strings.LastIndex(id, ":"); index > strings.LastIndex(id, "/") {
+		id = id[:index]
+	}
+	var byName []catalogExtension
+	for _, ext := range catalog {
+		if ext.ID == id {
+			return ext, nil
+		}
+		if strings.EqualFold(ext.Name, idOrName) {
+			byName = append(byName, ext)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return catalogExtension{}, fmt.Errorf("extension %q is not in the catalog; use `rdctl extension search` to find extensions", idOrName)
+	case 1:
+		return byName[0], nil
+	default:
+		ids := make([]string, 0, len(byName))
+		for _, ext := range byName {
+			ids = append(ids, ext.ID)
+		}
+		return catalogExtension{}, fmt.Errorf("more than one extension is named %q; use one of the IDs: %s", idOrName, strings.Join(ids, ", "))
+	}
+}
+
+// getExtensionVersions fetches the available versions of the given extension
+// image, newest first.
+func getExtensionVersions(id string) ([]string, error) {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	endpoint := client.VersionCommand("", "extensions/versions?id="+url.QueryEscape(id))
+	result, errorPacket, err := client.ProcessRequestForAPI(rdClient.DoRequest("GET", endpoint))
+	if errorPacket != nil || err != nil {
+		return nil, displayAPICallResult([]byte{}, errorPacket, err)
+	}
+	var versions []string
+	if err := json.Unmarshal(result, &versions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extension versions API response: %w", err)
+	}
+	return versions, nil
+}
+
+func printExtensionDetails(w io.Writer, details extensionDetails) {
+	yesNo := func(value bool) string {
+		if value {
+			return "yes"
+		}
+		return "no"
+	}
+	allOrList := func(values []string) string {
+		if len(values) == 0 {
+			return "all"
+		}
+		return strings.Join(values, ", ")
+	}
+	fmt.Fprintf(w, "ID:                    %s\n", details.ID)
+	fmt.Fprintf(w, "Name:                  %s\n", details.Name)
+	fmt.Fprintf(w, "Publisher:             %s\n", details.Publisher)
+	fmt.Fprintf(w, "Description:           %s\n", details.Description)
+	fmt.Fprintf(w, "Works with containerd: %s\n", yesNo(details.ContainerdCompatible))
+	fmt.Fprintf(w, "Operating systems:     %s\n", allOrList(details.OperatingSystems))
+	fmt.Fprintf(w, "Architectures:         %s\n", allOrList(details.Architectures))
+	if details.Compatible {
+		fmt.Fprintln(w, "Compatible:            yes")
+	} else {
+		fmt.Fprintf(w, "Compatible:            no (%s)\n", strings.Join(details.IncompatibleReasons, "; "))
+	}
+	fmt.Fprintf(w, "Allowed:               %s\n", yesNo(details.Allowed))
+	if details.InstalledVersion != "" {
+		fmt.Fprintf(w, "Installed:             %s\n", details.InstalledVersion)
+	} else {
+		fmt.Fprintln(w, "Installed:             no")
+	}
+	switch {
+	case details.versionsErr != nil:
+		fmt.Fprintf(w, "Versions:              unavailable (%s)\n", details.versionsErr)
+	case len(details.Versions) == 0:
+		fmt.Fprintln(w, "Versions:              none found")
+	default:
+		versions := details.Versions
+		suffix := ""
+		if len(versions) > maxListedVersions {
+			suffix = fmt.Sprintf(", and %d more", len(versions)-maxListedVersions)
+			versions = versions[:maxListedVersions]
+		}
+		fmt.Fprintf(w, "Versions:              %s%s\n", strings.Join(versions, ", "), suffix)
+		if details.Compatible && details.Allowed && details.InstalledVersion == "" {
+			fmt.Fprintf(w, "\nInstall with: rdctl extension install %s:%s\n", details.ID, details.Versions[0])
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -37,16 +38,41 @@ var listCmd = &cobra.Command{
 	Long:    `List currently installed images.`,
 	Args:    cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault("")
+		if err != nil {
+			return err
+		}
 		cmd.SilenceUsage = true
-		return listExtensions()
+		return listExtensions(format)
 	},
 }
 
+// extensionInfo describes an installed extension, for --output.
+type extensionInfo struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+}
+
+type extensionTable []extensionInfo
+
+func (t extensionTable) Headers() []string {
+	return []string{"ID", "VERSION"}
+}
+
+func (t extensionTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, info := range t {
+		rows = append(rows, []string{info.ID, info.Version})
+	}
+	return rows
+}
+
 func init() {
 	extensionCmd.AddCommand(listCmd)
+	defineOutputFlag(listCmd)
 }
 
-func listExtensions() error {
+func listExtensions(format output.Format) error {
 	connectionInfo, err := config.GetConnectionInfo(false)
 	if err != nil {
 		return fmt.Errorf("failed to get connection info: %w", err)
@@ -64,6 +90,14 @@ func listExtensions() error {
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal extension list API response: %w", err)
 	}
+	if format != "" {
+		extensions := make(extensionTable, 0, len(extensionList))
+		for id, info := range extensionList {
+			extensions = append(extensions, extensionInfo{ID: id, Version: info.Version})
+		}
+		sort.Slice(extensions, func(i, j int) bool { return strings.ToLower(extensions[i].ID) < strings.ToLower(extensions[j].ID) })
+		return renderOutput(format, extensions)
+	}
 	if len(extensionList) == 0 {
 		fmt.Println("No extensions are installed.")
 		return nil
@@ -0,0 +1,151 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// searchCmd represents the 'rdctl extension search' command
+var searchCmd = &cobra.Command{
+	Use:   "search [term]",
+	Short: "Search the extension catalog",
+	Long: `Search the Rancher Desktop extension catalog for extensions whose name, ID,
+publisher or description contain the given term; with no term, all extensions
+in the catalog are listed.  Extensions that can't be used with the current
+container engine or on this platform are marked as incompatible; use
+"rdctl extension info <id>" to see why, and which versions are available.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		term := ""
+		if len(args) > 0 {
+			term = args[0]
+		}
+		return searchExtensions(format, term)
+	},
+}
+
+func init() {
+	extensionCmd.AddCommand(searchCmd)
+	defineOutputFlag(searchCmd)
+}
+
+// catalogExtension is an entry in the extension catalog, as returned by the
+// extensions/catalog API.
+type catalogExtension struct {
+	ID                   string   `json:"id"`
+	Name                 string   `json:"name"`
+	Publisher            string   `json:"publisher"`
+	Description          string   `json:"description"`
+	ContainerdCompatible bool     `json:"containerdCompatible"`
+	OperatingSystems     []string `json:"operatingSystems"`
+	Architectures        []string `json:"architectures"`
+	Compatible           bool     `json:"compatible"`
+	IncompatibleReasons  []string `json:"incompatibleReasons"`
+	Allowed              bool     `json:"allowed"`
+	InstalledVersion     string   `json:"installedVersion,omitempty"`
+}
+
+// matches returns whether the extension matches the search term.
+func (e catalogExtension) matches(term string) bool {
+	term = strings.ToLower(term)
+	for _, field := range []string{e.ID, e.Name, e.Publisher, e.Description} {
+		if strings.Contains(strings.ToLower(field), term) {
+			return true
+		}
+	}
+	return false
+}
+
+type catalogTable []catalogExtension
+
+func (t catalogTable) Headers() []string {
+	return []string{"ID", "NAME", "PUBLISHER", "COMPATIBLE", "INSTALLED"}
+}
+
+func (t catalogTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, ext := range t {
+		compatible := "yes"
+		if !ext.Compatible {
+			compatible = "no"
+		} else if !ext.Allowed {
+			compatible = "not allowed"
+		}
+		rows = append(rows, []string{ext.ID, ext.Name, ext.Publisher, compatible, ext.InstalledVersion})
+	}
+	return rows
+}
+
+// getCatalogExtensions fetches the extension catalog from the application.
+func getCatalogExtensions() ([]catalogExtension, error) {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	endpoint := client.VersionCommand("", "extensions/catalog")
+	result, errorPacket, err := client.ProcessRequestForAPI(rdClient.DoRequest("GET", endpoint))
+	if errorPacket != nil || err != nil {
+		return nil, displayAPICallResult([]byte{}, errorPacket, err)
+	}
+	var catalog []catalogExtension
+	if err := json.Unmarshal(result, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal extension catalog API response: %w", err)
+	}
+	return catalog, nil
+}
+
+// filterCatalog returns the extensions matching the search term, sorted by
+// name.
+func filterCatalog(catalog []catalogExtension, term string) catalogTable {
+	results := make(catalogTable, 0, len(catalog))
+	for _, ext := range catalog {
+		if ext.matches(term) {
+			results = append(results, ext)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return strings.ToLower(results[i].Name) < strings.ToLower(results[j].Name)
+	})
+	return results
+}
+
+func searchExtensions(format output.Format, term string) error {
+	catalog, err := getCatalogExtensions()
+	if err != nil {
+		return err
+	}
+	results := filterCatalog(catalog, term)
+	if len(results) == 0 && format == output.FormatTable {
+		return fmt.Errorf("no extensions match %q", term)
+	}
+	return renderOutput(format, results)
+}
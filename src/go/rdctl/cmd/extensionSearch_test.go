@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCatalog = []catalogExtension{
+	{ID: "docker/logs-explorer-extension", Name: "Logs Explorer", Publisher: "Docker Inc.", Description: "View container logs", Compatible: true, Allowed: true},
+	{ID: "example/disk-usage", Name: "disk usage", Publisher: "Example", Description: "Explore docker disk usage", Compatible: false, IncompatibleReasons: []string{"requires the dockerd (moby) container engine"}, Allowed: true},
+	{ID: "example/other", Name: "Other", Publisher: "Example", Description: "Something else", Compatible: true, Allowed: false, InstalledVersion: "1.0.0"},
+}
+
+func TestFilterCatalog(t *testing.T) {
+	t.Run("no term", func(t *testing.T) {
+		results := filterCatalog(testCatalog, "")
+		require.Len(t, results, 3)
+		assert.Equal(t, "disk usage", results[0].Name, "results should be sorted by name, ignoring case")
+		assert.Equal(t, "Logs Explorer", results[1].Name)
+		assert.Equal(t, "Other", results[2].Name)
+	})
+	t.Run("matches any field", func(t *testing.T) {
+		results := filterCatalog(testCatalog, "DOCKER")
+		require.Len(t, results, 2)
+		assert.Equal(t, "example/disk-usage", results[0].ID)
+		assert.Equal(t, "docker/logs-explorer-extension", results[1].ID)
+	})
+	t.Run("no matches", func(t *testing.T) {
+		assert.Empty(t, filterCatalog(testCatalog, "nonexistent"))
+	})
+}
+
+func TestCatalogTableRows(t *testing.T) {
+	assert.Equal(t, [][]string{
+		{"docker/logs-explorer-extension", "Logs Explorer", "Docker Inc.", "yes", ""},
+		{"example/disk-usage", "disk usage", "Example", "no", ""},
+		{"example/other", "Other", "Example", "not allowed", "1.0.0"},
+	}, catalogTable(testCatalog).Rows())
+}
+
+func TestFindCatalogExtension(t *testing.T) {
+	ext, err := findCatalogExtension(testCatalog, "docker/logs-explorer-extension:0.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "Logs Explorer", ext.Name)
+
+	ext, err = findCatalogExtension(testCatalog, "Disk Usage")
+	require.NoError(t, err)
+	assert.Equal(t, "example/disk-usage", ext.ID)
+
+	_, err = findCatalogExtension(testCatalog, "missing")
+	assert.ErrorContains(t, err, "is not in the catalog")
+
+	duplicated := append([]catalogExtension{{ID: "example/another", Name: "other"}}, testCatalog...)
+	_, err = findCatalogExtension(duplicated, "OTHER")
+	assert.ErrorContains(t, err, "example/another, example/other")
+}
+
+func TestPrintExtensionDetails(t *testing.T) {
+	var buf bytes.Buffer
+	printExtensionDetails(&buf, extensionDetails{catalogExtension: testCatalog[0], Versions: []string{"0.2.3", "0.2.2"}})
+	assert.Contains(t, buf.String(), "Compatible:            yes\n")
+	assert.Contains(t, buf.String(), "Versions:              0.2.3, 0.2.2\n")
+	assert.Contains(t, buf.String(), "rdctl extension install docker/logs-explorer-extension:0.2.3")
+
+	buf.Reset()
+	printExtensionDetails(&buf, extensionDetails{catalogExtension: testCatalog[1], versionsErr: errors.New("offline")})
+	assert.Contains(t, buf.String(), "Compatible:            no (requires the dockerd (moby) container engine)\n")
+	assert.Contains(t, buf.String(), "Versions:              unavailable (offline)\n")
+	assert.NotContains(t, buf.String(), "Install with")
+}
@@ -36,7 +36,9 @@ var factoryResetCmd = &cobra.Command{
 	Use:   "factory-reset",
 	Short: "Clear all the Rancher Desktop state and shut it down.",
 	Long: `Clear all the Rancher Desktop state and shut it down.
-Use the --remove-kubernetes-cache=BOOLEAN flag to also remove the cached Kubernetes images.`,
+Use the --remove-kubernetes-cache=BOOLEAN flag to also remove the cached Kubernetes images.
+Snapshots are kept.  To reset only Kubernetes, or only remove container images
+or volumes, use "rdctl reset" instead.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.NoArgs(cmd, args); err != nil {
 			return err
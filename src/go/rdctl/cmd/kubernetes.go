@@ -0,0 +1,36 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// kubernetesCmd represents the 'rdctl kubernetes' command
+var kubernetesCmd = &cobra.Command{
+	Use:     "kubernetes",
+	Aliases: []string{"k8s"},
+	Short:   "Manage the Kubernetes version",
+	Long: `rdctl kubernetes - list the available Kubernetes versions, and select one
+
+The versions are read from the data cached by Rancher Desktop, so they can be
+listed without network access or a running application.`,
+}
+
+func init() {
+	rootCmd.AddCommand(kubernetesCmd)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/spf13/cobra"
+)
+
+// kubernetesPinCmd represents the 'rdctl kubernetes pin' command
+var kubernetesPinCmd = &cobra.Command{
+	Use:   "pin <version>",
+	Short: "Select the Kubernetes version to use",
+	Long: `Select the Kubernetes version to use, e.g. "1.30.2"; it must be one of the
+versions listed by "rdctl kubernetes versions".  Rancher Desktop must be
+running; otherwise, use "rdctl start --kubernetes.version <version>".`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		appPaths, err := paths.GetPaths()
+		if err != nil {
+			return fmt.Errorf("failed to get paths: %w", err)
+		}
+		versions, err := listKubernetesVersions(appPaths.Cache, "")
+		if err != nil {
+			return err
+		}
+		version, err := resolveKubernetesVersion(versions, args[0])
+		if err != nil {
+			return err
+		}
+		if !version.Downloaded {
+			fmt.Fprintf(os.Stderr, "Kubernetes %s has not been downloaded yet; it needs network access the first time it is used.\n", version.Version)
+		}
+		return pinKubernetesVersion(version.parsed.short())
+	},
+}
+
+func init() {
+	kubernetesCmd.AddCommand(kubernetesPinCmd)
+}
+
+// resolveKubernetesVersion finds the requested version, which may omit the
+// "v" prefix and the build; the newest matching build is returned.
+func resolveKubernetesVersion(versions kubernetesVersionTable, requested string) (kubernetesVersion, error) {
+	parsed, ok := parseK3sVersion(requested)
+	if !ok {
+		return kubernetesVersion{}, fmt.Errorf("invalid Kubernetes version %q", requested)
+	}
+	// The versions are sorted newest first.
+	for _, version := range versions {
+		if version.parsed.short() == parsed.short() && (parsed.build == 0 || version.parsed.build == parsed.build) {
+			return version, nil
+		}
+	}
+	return kubernetesVersion{}, fmt.Errorf(`unknown Kubernetes version %q; see "rdctl kubernetes versions"`, requested)
+}
+
+func pinKubernetesVersion(version string) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	var document options.ServerSettingsForJSON
+	settingsVersion := options.CURRENT_SETTINGS_VERSION
+	document.Version = &settingsVersion
+	document.Kubernetes.Version = &version
+	jsonBuffer, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	command := client.VersionCommand("", "settings")
+	if _, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("PUT", command, bytes.NewBuffer(jsonBuffer))); err != nil {
+		return err
+	}
+	fmt.Printf("Kubernetes version set to %s.\n", version)
+	return nil
+}
@@ -0,0 +1,274 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/spf13/cobra"
+)
+
+var kubernetesVersionsSettings struct {
+	Downloaded bool
+}
+
+// kubernetesVersionsCmd represents the 'rdctl kubernetes versions' command
+var kubernetesVersionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "List the available Kubernetes versions",
+	Long: `List the Kubernetes (k3s) versions Rancher Desktop can use, newest first, from
+the version data it cached the last time it had network access.  Versions that
+have already been downloaded can be used offline; the others need network
+access the first time they are used.  The version in the current settings is
+marked with an asterisk; use "rdctl kubernetes pin <version>" to select one.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		appPaths, err := paths.GetPaths()
+		if err != nil {
+			return fmt.Errorf("failed to get paths: %w", err)
+		}
+		versions, err := listKubernetesVersions(appPaths.Cache, currentKubernetesVersion(appPaths))
+		if err != nil {
+			return err
+		}
+		if kubernetesVersionsSettings.Downloaded {
+			versions = versions.downloaded()
+		}
+		if len(versions) == 0 && format == output.FormatTable {
+			return errors.New("no Kubernetes versions are known; start Rancher Desktop with network access to fetch the version list")
+		}
+		return renderOutput(format, versions)
+	},
+}
+
+func init() {
+	kubernetesCmd.AddCommand(kubernetesVersionsCmd)
+	defineOutputFlag(kubernetesVersionsCmd)
+	kubernetesVersionsCmd.Flags().BoolVar(&kubernetesVersionsSettings.Downloaded, "downloaded", false, "Only list the versions that can be used offline")
+}
+
+// kubernetesVersion is a k3s version as listed by `rdctl kubernetes versions`.
+type kubernetesVersion struct {
+	// Version is the full version, e.g. "v1.30.2+k3s2".
+	Version    string   `json:"version"`
+	Channels   []string `json:"channels"`
+	Downloaded bool     `json:"downloaded"`
+	Current    bool     `json:"current"`
+
+	parsed k3sVersion
+}
+
+type kubernetesVersionTable []kubernetesVersion
+
+func (t kubernetesVersionTable) Headers() []string {
+	return []string{"CURRENT", "VERSION", "CHANNELS", "AVAILABILITY"}
+}
+
+func (t kubernetesVersionTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, version := range t {
+		current, availability := "", "needs network"
+		if version.Current {
+			current = "*"
+		}
+		if version.Downloaded {
+			availability = "downloaded"
+		}
+		rows = append(rows, []string{current, version.Version, strings.Join(version.Channels, ", "), availability})
+	}
+	return rows
+}
+
+func (t kubernetesVersionTable) downloaded() kubernetesVersionTable {
+	result := kubernetesVersionTable{}
+	for _, version := range t {
+		if version.Downloaded {
+			result = append(result, version)
+		}
+	}
+	return result
+}
+
+// k3sVersionPattern matches k3s versions, e.g. "v1.30.2+k3s2" or
+// "v1.31.0-rc1+k3s1"; the "v" and the build are optional.
+var k3sVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+k3s(\d+))?$`)
+
+// k3sVersion is a parsed k3s version.
+type k3sVersion struct {
+	numbers    [3]int
+	prerelease string
+	build      int
+}
+
+func parseK3sVersion(value string) (k3sVersion, bool) {
+	match := k3sVersionPattern.FindStringSubmatch(value)
+	if match == nil {
+		return k3sVersion{}, false
+	}
+	var result k3sVersion
+	for i := range result.numbers {
+		result.numbers[i], _ = strconv.Atoi(match[i+1])
+	}
+	result.prerelease = match[4]
+	result.build, _ = strconv.Atoi(match[5])
+	return result, true
+}
+
+// short returns the version as stored in the settings, e.g. "1.30.2".
+func (v k3sVersion) short() string {
+	result := fmt.Sprintf("%d.%d.%d", v.numbers[0], v.numbers[1], v.numbers[2])
+	if v.prerelease != "" {
+		result += "-" + v.prerelease
+	}
+	return result
+}
+
+// less returns whether v is older than other.
+func (v k3sVersion) less(other k3sVersion) bool {
+	for i := range v.numbers {
+		if v.numbers[i] != other.numbers[i] {
+			return v.numbers[i] < other.numbers[i]
+		}
+	}
+	if v.prerelease != other.prerelease {
+		// A release is newer than its pre-releases.
+		return v.prerelease != "" && (other.prerelease == "" || v.prerelease < other.prerelease)
+	}
+	return v.build < other.build
+}
+
+// k3sVersionCache is the content of k3s-versions.json in the cache directory,
+// as written by the application (see K3sHelper in k3sHelper.ts).
+type k3sVersionCache struct {
+	CacheVersion int               `json:"cacheVersion"`
+	Versions     []string          `json:"versions"`
+	Channels     map[string]string `json:"channels"`
+}
+
+// k3sVersionCacheVersion is the supported version of the cache format.
+const k3sVersionCacheVersion = 2
+
+// listKubernetesVersions returns the known k3s versions, newest first: the
+// ones in the cached version data, and the ones that have been downloaded.
+// currentVersion is the version in the settings, if known.
+func listKubernetesVersions(cacheDir, currentVersion string) (kubernetesVersionTable, error) {
+	versions := map[string]*kubernetesVersion{}
+	add := func(name string) *kubernetesVersion {
+		if entry, ok := versions[name]; ok {
+			return entry
+		}
+		parsed, ok := parseK3sVersion(name)
+		if !ok {
+			return nil
+		}
+		versions[name] = &kubernetesVersion{Version: name, Channels: []string{}, parsed: parsed}
+		return versions[name]
+	}
+
+	content, err := os.ReadFile(filepath.Join(cacheDir, "k3s-versions.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	} else if err == nil {
+		var cache k3sVersionCache
+		if err := json.Unmarshal(content, &cache); err != nil {
+			return nil, fmt.Errorf("failed to parse the cached Kubernetes versions: %w", err)
+		}
+		if cache.CacheVersion == k3sVersionCacheVersion {
+			for _, name := range cache.Versions {
+				add(name)
+			}
+			for channel, short := range cache.Channels {
+				// Channels map to short versions; use the newest build.
+				var newest *kubernetesVersion
+				for _, entry := range versions {
+					if entry.parsed.short() == strings.TrimPrefix(short, "v") && (newest == nil || newest.parsed.less(entry.parsed)) {
+						newest = entry
+					}
+				}
+				if newest != nil {
+					newest.Channels = append(newest.Channels, channel)
+				}
+			}
+		}
+	}
+
+	dirEntries, err := os.ReadDir(filepath.Join(cacheDir, "k3s"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, dirEntry := range dirEntries {
+		// Only released versions are downloaded, into directories like "v1.30.2+k3s2".
+		if dirEntry.IsDir() && strings.HasPrefix(dirEntry.Name(), "v") {
+			if entry := add(dirEntry.Name()); entry != nil {
+				entry.Downloaded = true
+			}
+		}
+	}
+
+	result := make(kubernetesVersionTable, 0, len(versions))
+	for _, entry := range versions {
+		sort.Strings(entry.Channels)
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[j].parsed.less(result[i].parsed) })
+	if currentVersion != "" {
+		// The settings don't include the build; the highest build is used.
+		for i := range result {
+			if result[i].parsed.short() == strings.TrimPrefix(currentVersion, "v") {
+				result[i].Current = true
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// currentKubernetesVersion returns the Kubernetes version in the settings of
+// the running application, or else in the settings file; it returns an empty
+// string if it can't be determined.
+func currentKubernetesVersion(appPaths paths.Paths) string {
+	content, err := getListSettings()
+	if err != nil {
+		if content, err = os.ReadFile(filepath.Join(appPaths.Config, "settings.json")); err != nil {
+			return ""
+		}
+	}
+	var settings struct {
+		Kubernetes struct {
+			Version string `json:"version"`
+		} `json:"kubernetes"`
+	}
+	if err := json.Unmarshal(content, &settings); err != nil {
+		return ""
+	}
+	return settings.Kubernetes.Version
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseK3sVersion(t *testing.T) {
+	version, ok := parseK3sVersion("v1.30.2+k3s2")
+	require.True(t, ok)
+	assert.Equal(t, k3sVersion{numbers: [3]int{1, 30, 2}, build: 2}, version)
+	assert.Equal(t, "1.30.2", version.short())
+
+	version, ok = parseK3sVersion("1.31.0-rc1")
+	require.True(t, ok)
+	assert.Equal(t, "1.31.0-rc1", version.short())
+
+	_, ok = parseK3sVersion("latest")
+	assert.False(t, ok)
+
+	ordered := []string{"v1.29.9+k3s1", "v1.30.0-rc1+k3s1", "v1.30.0+k3s1", "v1.30.0+k3s2", "v1.30.10+k3s1"}
+	for i := 1; i < len(ordered); i++ {
+		older, _ := parseK3sVersion(ordered[i-1])
+		newer, _ := parseK3sVersion(ordered[i])
+		assert.True(t, older.less(newer), "%s < %s", ordered[i-1], ordered[i])
+		assert.False(t, newer.less(older), "%s > %s", ordered[i], ordered[i-1])
+	}
+}
+
+func writeK3sCache(t *testing.T) string {
+	cacheDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "k3s-versions.json"), []byte(`{
+		"cacheVersion": 2,
+		"versions": ["v1.29.6+k3s1", "v1.30.2+k3s1", "v1.30.2+k3s2", "v1.31.0+k3s1"],
+		"channels": {"stable": "v1.30.2", "latest": "v1.31.0", "v1.30": "v1.30.2"}
+	}`), 0o644))
+	for _, version := range []string{"v1.30.2+k3s2", "v1.28.11+k3s1", "tmp-v1.31.0+k3s1-abcdef"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "k3s", version), 0o755))
+	}
+	return cacheDir
+}
+
+func TestListKubernetesVersions(t *testing.T) {
+	cacheDir := writeK3sCache(t)
+	versions, err := listKubernetesVersions(cacheDir, "1.30.2")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"", "v1.31.0+k3s1", "latest", "needs network"},
+		{"*", "v1.30.2+k3s2", "stable, v1.30", "downloaded"},
+		{"", "v1.30.2+k3s1", "", "needs network"},
+		{"", "v1.29.6+k3s1", "", "needs network"},
+		{"", "v1.28.11+k3s1", "", "downloaded"},
+	}, versions.Rows())
+	assert.Equal(t, [][]string{
+		{"*", "v1.30.2+k3s2", "stable, v1.30", "downloaded"},
+		{"", "v1.28.11+k3s1", "", "downloaded"},
+	}, versions.downloaded().Rows())
+
+	t.Run("no cache", func(t *testing.T) {
+		versions, err := listKubernetesVersions(t.TempDir(), "")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+	t.Run("old cache format", func(t *testing.T) {
+		cacheDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "k3s-versions.json"), []byte(`{"versions":["v1.30.2+k3s1"],"channels":{}}`), 0o644))
+		versions, err := listKubernetesVersions(cacheDir, "")
+		require.NoError(t, err)
+		assert.Empty(t, versions)
+	})
+}
+
+func TestResolveKubernetesVersion(t *testing.T) {
+	versions, err := listKubernetesVersions(writeK3sCache(t), "")
+	require.NoError(t, err)
+	for requested, expected := range map[string]string{
+		"1.30.2":       "v1.30.2+k3s2",
+		"v1.30.2":      "v1.30.2+k3s2",
+		"v1.30.2+k3s1": "v1.30.2+k3s1",
+		"1.28.11":      "v1.28.11+k3s1",
+	} {
+		t.Run(requested, func(t *testing.T) {
+			version, err := resolveKubernetesVersion(versions, requested)
+			require.NoError(t, err)
+			assert.Equal(t, expected, version.Version)
+		})
+	}
+	_, err = resolveKubernetesVersion(versions, "1.27.1")
+	assert.EqualError(t, err, `unknown Kubernetes version "1.27.1"; see "rdctl kubernetes versions"`)
+	_, err = resolveKubernetesVersion(versions, "stable")
+	assert.EqualError(t, err, `invalid Kubernetes version "stable"`)
+}
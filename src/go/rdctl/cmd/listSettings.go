@@ -17,10 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
 	"github.com/spf13/cobra"
 )
 
@@ -28,23 +32,39 @@ import (
 var listSettingsCmd = &cobra.Command{
 	Use:   "list-settings",
 	Short: "Lists the current settings.",
-	Long:  `Lists the current settings in JSON format.`,
+	Long: `Lists the current settings in JSON format.  With --output=table, each
+setting is listed on its own line by its dotted path.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.NoArgs(cmd, args); err != nil {
 			return err
 		}
+		format, err := outputFormatOrDefault(output.FormatJSON)
+		if err != nil {
+			return err
+		}
 		cmd.SilenceUsage = true
 		result, err := getListSettings()
 		if err != nil {
 			return err
 		}
-		fmt.Println(string(result))
-		return nil
+		switch format {
+		case output.FormatJSON:
+			fmt.Println(string(result))
+			return nil
+		case output.FormatTable:
+			table, err := newSettingsTable(result)
+			if err != nil {
+				return err
+			}
+			return renderOutput(format, table)
+		}
+		return renderOutput(format, json.RawMessage(result))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(listSettingsCmd)
+	defineOutputFlag(listSettingsCmd)
 }
 
 func getListSettings() ([]byte, error) {
@@ -56,3 +76,59 @@ func getListSettings() ([]byte, error) {
 	command := client.VersionCommand("", "settings")
 	return client.ProcessRequestForUtility(rdClient.DoRequest("GET", command))
 }
+
+// settingsTable lists each setting by its dotted path.
+type settingsTable [][]string
+
+// newSettingsTable flattens the settings JSON into a table.
+func newSettingsTable(settingsJSON []byte) (settingsTable, error) {
+	var settings any
+	decoder := json.NewDecoder(bytes.NewReader(settingsJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	var table settingsTable
+	var walk func(path string, value any) error
+	walk = func(path string, value any) error {
+		if object, ok := value.(map[string]any); ok && len(object) > 0 {
+			keys := make([]string, 0, len(object))
+			for key := range object {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				childPath := key
+				if path != "" {
+					childPath = path + "." + key
+				}
+				if err := walk(childPath, object[key]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if text, ok := value.(string); ok {
+			table = append(table, []string{path, text})
+			return nil
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		table = append(table, []string{path, string(encoded)})
+		return nil
+	}
+	if err := walk("", settings); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t settingsTable) Headers() []string {
+	return []string{"SETTING", "VALUE"}
+}
+
+func (t settingsTable) Rows() [][]string {
+	return t
+}
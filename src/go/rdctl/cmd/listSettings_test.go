@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSettingsTable(t *testing.T) {
+	table, err := newSettingsTable([]byte(`{
+		"version": 14,
+		"kubernetes": {"version": "1.30.1", "enabled": true, "options": {}},
+		"containerEngine": {"allowedImages": {"patterns": ["a", "b"]}}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"containerEngine.allowedImages.patterns", `["a","b"]`},
+		{"kubernetes.enabled", "true"},
+		{"kubernetes.options", "{}"},
+		{"kubernetes.version", "1.30.1"},
+		{"version", "14"},
+	}, table.Rows())
+}
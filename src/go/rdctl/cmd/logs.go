@@ -0,0 +1,365 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	p "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/paths"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var logsSettings struct {
+	Follow     bool
+	Components []string
+	Since      string
+}
+
+// logComponents maps each component that can be selected with --component to
+// the names of its log files.  The files are normally in the Rancher Desktop
+// logs directory; files that are not found there are read from /var/log in
+// the VM instead (on macOS and Linux, some services log there).
+var logComponents = map[string][]string{
+	"k3s":        {"k3s.log", "cri-dockerd.log"},
+	"docker":     {"docker.log", "containerd.log", "buildkitd.log"},
+	"guestagent": {"rancher-desktop-guestagent.log"},
+	"proxy":      {"host-switch.log", "vm-switch.log", "moproxy.log"},
+}
+
+// logPollInterval is how often followed log files are checked for new data.
+var logPollInterval = 250 * time.Millisecond
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the logs of Rancher Desktop components",
+	Long: `Show the logs of Rancher Desktop components, without needing to know where on
+this platform they are stored.  For example:
+
+> rdctl logs --component k3s --since 10m
+-- Shows the k3s logs from the last ten minutes
+> rdctl logs --follow --component docker --component guestagent
+-- Shows the docker and guest agent logs, and keeps showing new lines as they
+   are written, until interrupted with Ctrl-C
+
+When more than one log file is shown, each line is prefixed with the name of
+the file it came from.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fileNames, err := logFileNames(logsSettings.Components)
+		if err != nil {
+			return err
+		}
+		since, err := parseLogsSince(logsSettings.Since, time.Now())
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return showLogs(ctx, os.Stdout, fileNames, since, logsSettings.Follow)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.Flags().BoolVarP(&logsSettings.Follow, "follow", "f", false, "Keep showing new log lines as they are written")
+	logsCmd.Flags().StringSliceVar(&logsSettings.Components, "component", nil,
+		fmt.Sprintf("Only show logs of the given components (%s); may be repeated", strings.Join(sortedLogComponents(), ", ")))
+	logsCmd.Flags().StringVar(&logsSettings.Since, "since", "", "Only show lines newer than a duration (such as 10m) or a RFC 3339 timestamp")
+	if err := logsCmd.RegisterFlagCompletionFunc("component", cobra.FixedCompletions(sortedLogComponents(), cobra.ShellCompDirectiveNoFileComp)); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+}
+
+func sortedLogComponents() []string {
+	var names []string
+	for name := range logComponents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// logFileNames returns the names of the log files of the given components, or
+// of all components if none are given.
+func logFileNames(components []string) ([]string, error) {
+	if len(components) == 0 {
+		components = sortedLogComponents()
+	}
+	var result []string
+	for _, component := range components {
+		names, ok := logComponents[strings.ToLower(component)]
+		if !ok {
+			return nil, fmt.Errorf("invalid component %q: must be one of %s", component, strings.Join(sortedLogComponents(), ", "))
+		}
+		for _, name := range names {
+			if !slices.Contains(result, name) {
+				result = append(result, name)
+			}
+		}
+	}
+	return result, nil
+}
+
+// parseLogsSince parses the --since flag, which is either a duration before
+// now or an absolute timestamp.  An empty value results in the zero time.
+func parseLogsSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q: must be a duration (such as 10m) or a RFC 3339 timestamp", value)
+}
+
+// logTimestampPattern matches the timestamps written by logrus
+// (time="2024-01-02T03:04:05Z"), Electron (2024-01-02T03:04:05.678Z:) and most
+// other components.
+var logTimestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})`)
+
+// logLineFilter drops log lines older than a given time.  Lines without a
+// timestamp (such as continuations of multi-line messages) are kept or dropped
+// along with the preceding line.
+type logLineFilter struct {
+	since   time.Time
+	include bool
+}
+
+func newLogLineFilter(since time.Time) *logLineFilter {
+	return &logLineFilter{since: since, include: since.IsZero()}
+}
+
+// accept returns whether the given line should be shown.
+func (f *logLineFilter) accept(line string) bool {
+	if f.since.IsZero() {
+		return true
+	}
+	// Only look near the start of the line, so that timestamps in the message
+	// itself don't matter.
+	if len(line) > 64 {
+		line = line[:64]
+	}
+	if match := logTimestampPattern.FindString(line); match != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700"} {
+			if timestamp, err := time.Parse(layout, match); err == nil {
+				f.include = !timestamp.Before(f.since)
+				break
+			}
+		}
+	}
+	return f.include
+}
+
+// logWriter writes lines from multiple log files to a single output, with
+// each line prefixed with the name of its file if there is more than one.
+type logWriter struct {
+	output   io.Writer
+	prefixed bool
+	sync.Mutex
+}
+
+func (w *logWriter) writeLine(name, line string) {
+	w.Lock()
+	defer w.Unlock()
+	line = strings.TrimRight(line, "\r\n")
+	if w.prefixed {
+		fmt.Fprintf(w.output, "[%s] %s\n", strings.TrimSuffix(name, ".log"), line)
+	} else {
+		fmt.Fprintln(w.output, line)
+	}
+}
+
+// showLogs writes the contents of the given log files to the output; if
+// follow is set, it then keeps writing new lines until the context is done.
+func showLogs(ctx context.Context, output io.Writer, fileNames []string, since time.Time, follow bool) error {
+	paths, err := p.GetPaths()
+	if err != nil {
+		return err
+	}
+	writer := &logWriter{output: output, prefixed: len(fileNames) > 1}
+	var localFiles, vmFiles []string
+	for _, name := range fileNames {
+		if _, err := os.Stat(filepath.Join(paths.Logs, name)); err == nil {
+			localFiles = append(localFiles, name)
+		} else if runtime.GOOS != "windows" {
+			// On Windows, everything in the VM logs to the host directly.
+			vmFiles = append(vmFiles, name)
+		}
+	}
+	if len(localFiles) == 0 && len(vmFiles) == 0 {
+		return fmt.Errorf("no log files found in %s", paths.Logs)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(localFiles)+1)
+	for i, name := range localFiles {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = tailLogFile(ctx, filepath.Join(paths.Logs, name), newLogLineFilter(since), follow, func(line string) {
+				writer.writeLine(name, line)
+			})
+		}()
+	}
+	if len(vmFiles) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[len(localFiles)] = tailVMLogFiles(ctx, vmFiles, since, follow, writer)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// tailLogFile calls emit for each line of the given file that passes the
+// filter.  If follow is set, it then waits for more lines to be appended,
+// starting over if the file is truncated or replaced, until the context is
+// done.
+func tailLogFile(ctx context.Context, fileName string, filter *logLineFilter, follow bool, emit func(string)) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	var partial string
+	for {
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		partial += chunk
+		if err == nil {
+			if filter.accept(partial) {
+				emit(partial)
+			}
+			partial = ""
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !follow {
+			if partial != "" && filter.accept(partial) {
+				emit(partial)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logPollInterval):
+		}
+		info, err := os.Stat(fileName)
+		if err != nil {
+			// The file may be in the middle of being rotated; try again later.
+			continue
+		}
+		if current, err := file.Stat(); err == nil && (info.Size() < offset || !os.SameFile(info, current)) {
+			// The file was truncated or replaced; start again from the top.
+			newFile, err := os.Open(fileName)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file = newFile
+			reader.Reset(file)
+			offset = 0
+			partial = ""
+		}
+	}
+}
+
+// tailVMLogFiles shows the given log files from /var/log in the VM.
+func tailVMLogFiles(ctx context.Context, fileNames []string, since time.Time, follow bool, writer *logWriter) error {
+	args := []string{"tail", "-n", "+1"}
+	if follow {
+		args = append(args, "-F")
+	}
+	for _, name := range fileNames {
+		// Always include a dummy file so that tail prints file name headers.
+		args = append(args, path.Join("/var/log", name))
+	}
+	args = append(args, "/dev/null")
+	tailCmd, err := newRootShellCommand(args...)
+	if errors.Is(err, errVMNotRunning) {
+		// The reason has already been reported.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	stdout, err := tailCmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := tailCmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = tailCmd.Process.Kill()
+	}()
+
+	filters := make(map[string]*logLineFilter)
+	var current string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "==> ") && strings.HasSuffix(line, " <==") {
+			current = path.Base(strings.TrimSuffix(strings.TrimPrefix(line, "==> "), " <=="))
+			if _, ok := filters[current]; !ok {
+				filters[current] = newLogLineFilter(since)
+			}
+			continue
+		}
+		if filter, ok := filters[current]; ok && filter.accept(line) {
+			writer.writeLine(current, line)
+		}
+	}
+	if err := tailCmd.Wait(); err != nil && ctx.Err() == nil {
+		var exitErr *exec.ExitError
+		// tail exits with an error if any of the files are missing.
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+	}
+	return nil
+}
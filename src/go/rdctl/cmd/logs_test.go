@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFileNames(t *testing.T) {
+	names, err := logFileNames([]string{"guestagent", "K3S"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rancher-desktop-guestagent.log", "k3s.log", "cri-dockerd.log"}, names)
+
+	names, err = logFileNames(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, names, "docker.log")
+	assert.Contains(t, names, "host-switch.log")
+
+	_, err = logFileNames([]string{"kubelet"})
+	assert.ErrorContains(t, err, "invalid component")
+}
+
+func TestParseLogsSince(t *testing.T) {
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	since, err := parseLogsSince("10m", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-10*time.Minute), since)
+
+	since, err = parseLogsSince("2024-01-02T03:04:05Z", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), since)
+
+	since, err = parseLogsSince("", now)
+	assert.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	_, err = parseLogsSince("yesterday", now)
+	assert.ErrorContains(t, err, "invalid --since value")
+}
+
+func TestLogLineFilter(t *testing.T) {
+	filter := newLogLineFilter(time.Date(2024, 5, 6, 7, 0, 0, 0, time.UTC))
+	assert.False(t, filter.accept("leading line without a timestamp"))
+	assert.False(t, filter.accept(`time="2024-05-06T06:59:59Z" level=info msg="old"`))
+	assert.False(t, filter.accept("  continuation of the old message"))
+	assert.True(t, filter.accept(`time="2024-05-06T07:00:01+00:00" level=info msg="new"`))
+	assert.True(t, filter.accept("  continuation of the new message"))
+	assert.True(t, filter.accept("2024-05-06T07:30:00.123Z: electron message"))
+	assert.True(t, filter.accept("2024-05-06T09:00:00+0200 numeric offset"))
+	assert.False(t, filter.accept("2024-05-06T08:00:00+0200 numeric offset"))
+
+	assert.True(t, newLogLineFilter(time.Time{}).accept("anything"))
+}
+
+func TestTailLogFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(fileName, []byte("one\ntwo\nthree"), 0o644))
+
+	t.Run("without follow", func(t *testing.T) {
+		var lines []string
+		err := tailLogFile(context.Background(), fileName, newLogLineFilter(time.Time{}), false, func(line string) {
+			lines = append(lines, line)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"one\n", "two\n", "three"}, lines)
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		oldInterval := logPollInterval
+		logPollInterval = 10 * time.Millisecond
+		t.Cleanup(func() { logPollInterval = oldInterval })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lines := make(chan string, 10)
+		done := make(chan error)
+		go func() {
+			done <- tailLogFile(ctx, fileName, newLogLineFilter(time.Time{}), true, func(line string) {
+				lines <- line
+			})
+		}()
+		assert.Equal(t, "one\n", <-lines)
+		assert.Equal(t, "two\n", <-lines)
+
+		// The partial line is only shown once it is complete.
+		file, err := os.OpenFile(fileName, os.O_APPEND|os.O_WRONLY, 0)
+		require.NoError(t, err)
+		_, err = file.WriteString(" and more\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+		assert.Equal(t, "three and more\n", <-lines)
+
+		// Truncating the file starts over from the top.
+		require.NoError(t, os.WriteFile(fileName, []byte("new\n"), 0o644))
+		assert.Equal(t, "new\n", <-lines)
+
+		cancel()
+		assert.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// outputFormat is the value of the --output flag.
+var outputFormat string
+
+// defineOutputFlag adds the --output flag to a command that renders its
+// output with renderOutput.  This is not a global flag, as commands that
+// don't render anything would silently ignore it, and create-profile has an
+// --output flag of its own.
+func defineOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "",
+		"output format (allowed values: [json, yaml, table])")
+	_ = cmd.RegisterFlagCompletionFunc("output",
+		cobra.FixedCompletions(output.Formats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// outputFormatOrDefault returns the output format selected via --output, or
+// the given default if it was not specified.
+func outputFormatOrDefault(defaultFormat output.Format) (output.Format, error) {
+	return output.ParseFormat(outputFormat, defaultFormat)
+}
+
+// renderOutput writes the value to standard output in the given format.
+func renderOutput(format output.Format, value any) error {
+	return output.Render(os.Stdout, format, value)
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFlag(t *testing.T) {
+	// Commands that render their output accept --output and -o.
+	for _, args := range [][]string{{"version"}, {"snapshot", "list"}, {"disk", "usage"}} {
+		command, _, err := rootCmd.Find(args)
+		require.NoError(t, err)
+		flag := command.Flags().ShorthandLookup("o")
+		if assert.NotNil(t, flag, "rdctl %v should have -o", args) {
+			assert.Equal(t, "output", flag.Name)
+		}
+	}
+	// create-profile has its own --output flag, for the profile format.
+	command, _, err := rootCmd.Find([]string{"create-profile"})
+	require.NoError(t, err)
+	t.Cleanup(func() { outputSettingsFlags.Format = "" })
+	require.NoError(t, command.ParseFlags([]string{"--output", "plist"}))
+	assert.Equal(t, "plist", outputSettingsFlags.Format)
+	assert.Empty(t, outputFormat)
+	// Commands that don't render anything don't accept it.
+	command, _, err = rootCmd.Find([]string{"shutdown"})
+	require.NoError(t, err)
+	assert.Nil(t, command.Flags().Lookup("output"))
+}
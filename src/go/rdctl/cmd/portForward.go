@@ -0,0 +1,287 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var portForwardNamespace string
+
+// portForwardCmd represents the port-forward command
+var portForwardCmd = &cobra.Command{
+	Use:   "port-forward <service|container|pod> <local:remote>",
+	Short: "Forward a local port to a Kubernetes service, pod, or container",
+	Long: `Forward a port on localhost to a port of a Kubernetes service, a Kubernetes pod,
+or a container, until interrupted with Ctrl-C.  The target is given as
+service/<name> (or just <name>), pod/<name>, or container/<name>; the ports are
+given as <local>:<remote>, or a single port to use the same number for both.
+A local port of 0 picks a random free port.  For example:
+
+> rdctl port-forward service/nginx 8080:80
+-- Forwards localhost:8080 to port 80 of the nginx service
+> rdctl port-forward container/my-app 3000
+-- Forwards localhost:3000 to port 3000 of the my-app container, even if that
+   port was not published
+
+Services are forwarded by Rancher Desktop itself, and therefore only while it is
+running; pods and containers are forwarded through the Rancher Desktop VM by
+this command.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, name, err := parsePortForwardTarget(args[0])
+		if err != nil {
+			return err
+		}
+		localPort, remotePort, err := parsePortForwardPorts(args[1])
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if kind == "service" {
+			return forwardService(ctx, name, localPort, remotePort)
+		}
+		return forwardThroughVM(ctx, kind, name, localPort, remotePort)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portForwardCmd)
+	portForwardCmd.Flags().StringVarP(&portForwardNamespace, "namespace", "n", "default", "Kubernetes namespace of the service or pod")
+}
+
+// parsePortForwardTarget splits a target such as "svc/nginx" into its kind
+// (one of "service", "pod", or "container") and name.
+func parsePortForwardTarget(target string) (string, string, error) {
+	kind, name, found := strings.Cut(target, "/")
+	if !found {
+		kind, name = "service", target
+	}
+	switch strings.ToLower(kind) {
+	case "service", "services", "svc":
+		kind = "service"
+	case "pod", "pods", "po":
+		kind = "pod"
+	case "container", "containers":
+		kind = "container"
+	default:
+		return "", "", fmt.Errorf("invalid target %q: must be one of service/<name>, pod/<name>, or container/<name>", target)
+	}
+	if name == "" {
+		return "", "", fmt.Errorf("invalid target %q: missing name", target)
+	}
+	return kind, name, nil
+}
+
+// parsePortForwardPorts parses "<local>:<remote>" or "<port>".
+func parsePortForwardPorts(spec string) (int, string, error) {
+	local, remote, found := strings.Cut(spec, ":")
+	if !found {
+		remote = local
+	}
+	localPort, err := strconv.ParseUint(local, 10, 16)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid local port %q", local)
+	}
+	if remote == "" {
+		return 0, "", fmt.Errorf("invalid remote port in %q", spec)
+	}
+	return int(localPort), remote, nil
+}
+
+// forwardService asks Rancher Desktop to forward the given port of a
+// Kubernetes service, removing the forwarding once the context is done.
+func forwardService(ctx context.Context, service string, localPort int, remotePort string) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	payload := map[string]any{
+		"namespace": portForwardNamespace,
+		"service":   service,
+		"k8sPort":   remotePort,
+		"hostPort":  localPort,
+	}
+	jsonBuffer, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	command := client.VersionCommand("", "port_forwarding")
+	result, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("POST", command, bytes.NewBuffer(jsonBuffer)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forwarding 127.0.0.1:%s -> %s/%s:%s\n", strings.TrimSpace(string(result)), portForwardNamespace, service, remotePort)
+	fmt.Println("Press Ctrl-C to stop.")
+
+	<-ctx.Done()
+
+	query := url.Values{}
+	query.Set("namespace", portForwardNamespace)
+	query.Set("service", service)
+	query.Set("k8sPort", remotePort)
+	command = client.VersionCommand("", "port_forwarding?"+query.Encode())
+	if _, err := client.ProcessRequestForUtility(rdClient.DoRequest("DELETE", command)); err != nil {
+		return fmt.Errorf("failed to remove port forwarding: %w", err)
+	}
+	return nil
+}
+
+// forwardThroughVM forwards connections to a local port to a pod or container,
+// by running nc in the VM for each connection.
+func forwardThroughVM(ctx context.Context, kind, name string, localPort int, remotePort string) error {
+	if _, err := strconv.ParseUint(remotePort, 10, 16); err != nil {
+		return fmt.Errorf("invalid remote port %q: named ports are only supported for services", remotePort)
+	}
+	address, err := lookupTargetAddress(kind, name)
+	if err != nil {
+		return err
+	}
+	relay, err := newShellCommand("nc", address, remotePort)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort)))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Forwarding %s -> %s/%s (%s:%s)\n", listener.Addr(), kind, name, address, remotePort)
+	fmt.Println("Press Ctrl-C to stop.")
+
+	var wg sync.WaitGroup
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relayConnection(ctx, conn, relay)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// relayConnection copies data between a connection and a new instance of the
+// given relay command.
+func relayConnection(ctx context.Context, conn net.Conn, relay *exec.Cmd) {
+	defer conn.Close()
+	command := exec.CommandContext(ctx, relay.Path, relay.Args[1:]...)
+	command.Stdin = conn
+	command.Stderr = os.Stderr
+	stdout, err := command.StdoutPipe()
+	if err != nil {
+		logrus.Errorf("Failed to forward connection from %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+	if err := command.Start(); err != nil {
+		logrus.Errorf("Failed to forward connection from %s: %s", conn.RemoteAddr(), err)
+		return
+	}
+	_, _ = io.Copy(conn, stdout)
+	// The remote end closed the connection; stop waiting for local input.
+	conn.Close()
+	if err := command.Wait(); err != nil && ctx.Err() == nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			logrus.Errorf("Failed to forward connection from %s: %s", conn.RemoteAddr(), err)
+		}
+	}
+}
+
+// lookupTargetAddress returns the IP address of a pod or container, as
+// reachable from within the VM.
+func lookupTargetAddress(kind, name string) (string, error) {
+	var args []string
+	switch kind {
+	case "pod":
+		args = []string{"k3s", "kubectl", "get", "pod", "--namespace", portForwardNamespace, name, "--output", "jsonpath={.status.podIP}"}
+	case "container":
+		args = append(containerEngineCLI(), "container", "inspect", "--format",
+			"{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", name)
+	}
+	command, err := newRootShellCommand(args...)
+	if err != nil {
+		return "", err
+	}
+	command.Stderr = os.Stderr
+	output, err := command.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s %q: %w", kind, name, err)
+	}
+	address, _, _ := strings.Cut(strings.TrimSpace(string(output)), " ")
+	if address == "" {
+		return "", fmt.Errorf("%s %q does not have an IP address", kind, name)
+	}
+	return address, nil
+}
+
+// containerEngineCLI returns the command for the CLI of the configured
+// container engine, defaulting to docker if the settings can't be read.
+func containerEngineCLI() []string {
+	result, err := getListSettings()
+	if err != nil {
+		return []string{"docker"}
+	}
+	var settings struct {
+		ContainerEngine struct {
+			Name string `json:"name"`
+		} `json:"containerEngine"`
+		Containers struct {
+			Namespace string `json:"namespace"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(result, &settings); err == nil && settings.ContainerEngine.Name == "containerd" {
+		if settings.Containers.Namespace != "" {
+			return []string{"nerdctl", "--namespace", settings.Containers.Namespace}
+		}
+		return []string{"nerdctl"}
+	}
+	return []string{"docker"}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortForwardTarget(t *testing.T) {
+	testCases := []struct {
+		target string
+		kind   string
+		name   string
+		err    string
+	}{
+		{target: "nginx", kind: "service", name: "nginx"},
+		{target: "svc/nginx", kind: "service", name: "nginx"},
+		{target: "pod/web-0", kind: "pod", name: "web-0"},
+		{target: "Container/my-app", kind: "container", name: "my-app"},
+		{target: "deployment/web", err: "must be one of"},
+		{target: "pod/", err: "missing name"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.target, func(t *testing.T) {
+			kind, name, err := parsePortForwardTarget(testCase.target)
+			if testCase.err != "" {
+				assert.ErrorContains(t, err, testCase.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.kind, kind)
+			assert.Equal(t, testCase.name, name)
+		})
+	}
+}
+
+func TestParsePortForwardPorts(t *testing.T) {
+	local, remote, err := parsePortForwardPorts("8080:80")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, local)
+	assert.Equal(t, "80", remote)
+
+	local, remote, err = parsePortForwardPorts("3000")
+	assert.NoError(t, err)
+	assert.Equal(t, 3000, local)
+	assert.Equal(t, "3000", remote)
+
+	local, remote, err = parsePortForwardPorts("0:http")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, local)
+	assert.Equal(t, "http", remote)
+
+	_, _, err = parsePortForwardPorts("70000:80")
+	assert.ErrorContains(t, err, "invalid local port")
+	_, _, err = parsePortForwardPorts("80:")
+	assert.ErrorContains(t, err, "invalid remote port")
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// resetOptions selects what `rdctl reset` removes; it matches the body of
+// the reset API.
+type resetOptions struct {
+	Kubernetes bool `json:"kubernetes"`
+	Images     bool `json:"images"`
+	Volumes    bool `json:"volumes"`
+}
+
+var resetFlags resetOptions
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Reset Kubernetes, or remove container images or volumes",
+	Long: `Reset parts of Rancher Desktop while keeping the rest, as a lighter alternative
+to "rdctl factory-reset".  Select what to remove with the flags; anything not
+selected is kept.  With no flags, only the Kubernetes state is removed, keeping
+all container images and volumes so they don't have to be pulled again.
+Unless --kubernetes is given too, --images keeps the containers Kubernetes runs
+and the images they use.  Snapshots are never removed.
+
+> rdctl reset
+-- Resets the Kubernetes cluster, keeping images and volumes
+> rdctl reset --images --volumes
+-- Removes all other containers, images and volumes, keeping the Kubernetes cluster
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return doReset(resetFlags)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resetCmd)
+	resetCmd.Flags().BoolVar(&resetFlags.Kubernetes, "kubernetes", false, "remove the Kubernetes state (the cluster and its workloads)")
+	resetCmd.Flags().BoolVar(&resetFlags.Images, "images", false, "remove all containers and container images, except those used by Kubernetes")
+	resetCmd.Flags().BoolVar(&resetFlags.Volumes, "volumes", false, "remove all container volumes, including named volumes")
+}
+
+// resetPayload returns the body of the reset API request for the options;
+// with nothing selected, only Kubernetes is reset.
+func resetPayload(options resetOptions) ([]byte, error) {
+	if options == (resetOptions{}) {
+		options.Kubernetes = true
+	}
+	return json.Marshal(options)
+}
+
+func doReset(options resetOptions) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	jsonBuffer, err := resetPayload(options)
+	if err != nil {
+		return err
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	command := client.VersionCommand("", "reset")
+	result, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("PUT", command, bytes.NewBuffer(jsonBuffer)))
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(result))
+	return nil
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetPayload(t *testing.T) {
+	for _, testCase := range []struct {
+		args     []string
+		expected string
+	}{
+		{nil, `{"kubernetes":true,"images":false,"volumes":false}`},
+		{[]string{"--kubernetes"}, `{"kubernetes":true,"images":false,"volumes":false}`},
+		{[]string{"--images"}, `{"kubernetes":false,"images":true,"volumes":false}`},
+		{[]string{"--volumes", "--images"}, `{"kubernetes":false,"images":true,"volumes":true}`},
+		{[]string{"--kubernetes", "--images", "--volumes"}, `{"kubernetes":true,"images":true,"volumes":true}`},
+	} {
+		t.Run(testCase.expected, func(t *testing.T) {
+			resetFlags = resetOptions{}
+			t.Cleanup(func() { resetFlags = resetOptions{} })
+			require.NoError(t, resetCmd.ParseFlags(testCase.args))
+			payload, err := resetPayload(resetFlags)
+			require.NoError(t, err)
+			assert.JSONEq(t, testCase.expected, string(payload))
+		})
+	}
+}
@@ -31,7 +31,11 @@ import (
 var setCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Update selected fields in the Rancher Desktop UI and restart the backend.",
-	Long:  `Update selected fields in the Rancher Desktop UI and restart the backend.`,
+	Long: `Update selected fields in the Rancher Desktop UI and restart the backend.
+
+With --dry-run, the changes are validated against the settings schema and the
+locked settings, and the settings that would change are listed, along with
+whether the backend would be restarted or Kubernetes reset; nothing is applied.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := cobra.NoArgs(cmd, args); err != nil {
 			return err
@@ -40,9 +44,13 @@ var setCmd = &cobra.Command{
 	},
 }
 
+var setDryRun bool
+
 func init() {
 	rootCmd.AddCommand(setCmd)
+	defineOutputFlag(setCmd)
 	options.UpdateCommonStartAndSetCommands(setCmd)
+	setCmd.Flags().BoolVar(&setDryRun, "dry-run", false, "Only show what would change, without applying anything")
 }
 
 func doSetCommand(cmd *cobra.Command) error {
@@ -65,6 +73,14 @@ func doSetCommand(cmd *cobra.Command) error {
 		return err
 	}
 
+	if setDryRun {
+		result, err := proposeSettings(rdClient, jsonBuffer)
+		if err != nil {
+			return err
+		}
+		return showSettingsDryRun(result)
+	}
+
 	command := client.VersionCommand("", "settings")
 	buf := bytes.NewBuffer(jsonBuffer)
 	result, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("PUT", command, buf))
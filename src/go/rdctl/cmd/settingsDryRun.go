@@ -0,0 +1,194 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+)
+
+// The effects a settings change can have on the backend, as reported by the
+// propose_settings API.
+const (
+	settingsEffectRestart = "restart"
+	settingsEffectReset   = "reset"
+)
+
+// settingsChange is a setting that would be changed by `rdctl set --dry-run`.
+type settingsChange struct {
+	// The dotted path of the setting, such as "kubernetes.enabled".
+	Path     string `json:"path"`
+	Current  any    `json:"current"`
+	Proposed any    `json:"proposed"`
+	// Effect is "restart" if the backend would be restarted, or "reset" if
+	// Kubernetes would also be reset (deleting its workloads).
+	Effect string `json:"effect,omitempty"`
+}
+
+// settingsDryRun describes what applying some settings would do.
+type settingsDryRun struct {
+	Changes         []settingsChange `json:"changes"`
+	RestartRequired bool             `json:"restartRequired"`
+	ResetRequired   bool             `json:"resetRequired"`
+}
+
+func (d settingsDryRun) Headers() []string {
+	return []string{"SETTING", "CURRENT", "PROPOSED", "EFFECT"}
+}
+
+func (d settingsDryRun) Rows() [][]string {
+	rows := make([][]string, 0, len(d.Changes))
+	for _, change := range d.Changes {
+		rows = append(rows, []string{change.Path, formatSettingValue(change.Current), formatSettingValue(change.Proposed), change.Effect})
+	}
+	return rows
+}
+
+// proposeSettings validates the proposed settings (a JSON document in the
+// same shape as the settings), against the settings schema and the locked
+// settings, and describes what applying them would change; nothing is
+// applied.
+func proposeSettings(rdClient client.RDClient, proposed []byte) (settingsDryRun, error) {
+	current, err := client.ProcessRequestForUtility(rdClient.DoRequest("GET", client.VersionCommand("", "settings")))
+	if err != nil {
+		return settingsDryRun{}, err
+	}
+	command := client.VersionCommand("", "propose_settings")
+	reasons, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("PUT", command, bytes.NewBuffer(proposed)))
+	if err != nil {
+		return settingsDryRun{}, err
+	}
+	return newSettingsDryRun(current, proposed, reasons)
+}
+
+// newSettingsDryRun compares the current settings with the proposed ones, and
+// adds the effects on the backend reported by the propose_settings API.
+func newSettingsDryRun(currentJSON, proposedJSON, reasonsJSON []byte) (settingsDryRun, error) {
+	result := settingsDryRun{Changes: []settingsChange{}}
+	var current, proposed map[string]any
+	if err := json.Unmarshal(currentJSON, &current); err != nil {
+		return result, fmt.Errorf("failed to parse current settings: %w", err)
+	}
+	if err := json.Unmarshal(proposedJSON, &proposed); err != nil {
+		return result, fmt.Errorf("failed to parse proposed settings: %w", err)
+	}
+	reasons := map[string]struct {
+		Current  any    `json:"current"`
+		Desired  any    `json:"desired"`
+		Severity string `json:"severity"`
+	}{}
+	if len(bytes.TrimSpace(reasonsJSON)) > 0 {
+		if err := json.Unmarshal(reasonsJSON, &reasons); err != nil {
+			return result, fmt.Errorf("failed to parse proposed settings result: %w", err)
+		}
+	}
+
+	// The settings version isn't a setting.
+	delete(proposed, "version")
+	result.Changes = compareProposedSettings("", current, proposed, result.Changes)
+	for path, reason := range reasons {
+		index := -1
+		for i, change := range result.Changes {
+			if change.Path == path {
+				index = i
+			}
+		}
+		if index < 0 {
+			// The change implies a change of a setting we didn't see.
+			result.Changes = append(result.Changes, settingsChange{Path: path, Current: reason.Current, Proposed: reason.Desired})
+			index = len(result.Changes) - 1
+		}
+		result.Changes[index].Effect = reason.Severity
+		result.RestartRequired = true
+		if reason.Severity == settingsEffectReset {
+			result.ResetRequired = true
+		}
+	}
+	sort.Slice(result.Changes, func(i, j int) bool { return result.Changes[i].Path < result.Changes[j].Path })
+	return result, nil
+}
+
+// compareProposedSettings appends the proposed settings that differ from the
+// current ones to changes.  Objects are merged into the current settings, so
+// only the settings given in the proposal are compared; arrays replace the
+// current value as a whole.
+func compareProposedSettings(prefix string, current any, proposed map[string]any, changes []settingsChange) []settingsChange {
+	currentMap, _ := current.(map[string]any)
+	keys := make([]string, 0, len(proposed))
+	for key := range proposed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if proposedMap, ok := proposed[key].(map[string]any); ok {
+			// An empty object doesn't change anything.
+			changes = compareProposedSettings(path, currentMap[key], proposedMap, changes)
+		} else if !reflect.DeepEqual(currentMap[key], proposed[key]) {
+			changes = append(changes, settingsChange{Path: path, Current: currentMap[key], Proposed: proposed[key]})
+		}
+	}
+	return changes
+}
+
+// showSettingsDryRun writes the result of proposeSettings in the format
+// selected with --output, or as text if none was given.
+func showSettingsDryRun(result settingsDryRun) error {
+	if outputFormat == "" {
+		printSettingsDryRun(os.Stdout, result)
+		return nil
+	}
+	format, err := outputFormatOrDefault("")
+	if err != nil {
+		return err
+	}
+	return renderOutput(format, result)
+}
+
+func printSettingsDryRun(w io.Writer, result settingsDryRun) {
+	if len(result.Changes) == 0 {
+		fmt.Fprintln(w, "No settings would change.")
+		return
+	}
+	fmt.Fprintln(w, "Settings that would change:")
+	for _, change := range result.Changes {
+		effect := ""
+		if change.Effect != "" {
+			effect = fmt.Sprintf(" (requires %s)", change.Effect)
+		}
+		fmt.Fprintf(w, "  %s: %s -> %s%s\n", change.Path, formatSettingValue(change.Current), formatSettingValue(change.Proposed), effect)
+	}
+	switch {
+	case result.ResetRequired:
+		fmt.Fprintln(w, "Applying them would restart the backend and reset Kubernetes, deleting its workloads.")
+	case result.RestartRequired:
+		fmt.Fprintln(w, "Applying them would restart the backend.")
+	default:
+		fmt.Fprintln(w, "Applying them would not restart the backend.")
+	}
+	fmt.Fprintln(w, "No changes were made.")
+}
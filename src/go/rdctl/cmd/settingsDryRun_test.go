@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dryRunCurrentSettings = `{
+	"version": 14,
+	"kubernetes": {"enabled": true, "version": "1.29.4", "options": {"traefik": true}},
+	"virtualMachine": {"memoryInGB": 4, "numberCPUs": 2},
+	"containerEngine": {"allowedImages": {"enabled": false, "patterns": ["docker.io"]}},
+	"application": {"extensions": {"installed": {"a/b": "1.0"}}}
+}`
+
+func TestNewSettingsDryRun(t *testing.T) {
+	t.Run("no changes", func(t *testing.T) {
+		result, err := newSettingsDryRun([]byte(dryRunCurrentSettings),
+			[]byte(`{"version": 14, "kubernetes": {"enabled": true, "options": {}}, "virtualMachine": {"memoryInGB": 4}}`), []byte(`{}`))
+		require.NoError(t, err)
+		assert.Equal(t, settingsDryRun{Changes: []settingsChange{}}, result)
+	})
+	t.Run("changes", func(t *testing.T) {
+		result, err := newSettingsDryRun([]byte(dryRunCurrentSettings),
+			[]byte(`{
+				"version": 14,
+				"kubernetes": {"version": "1.30.2"},
+				"virtualMachine": {"memoryInGB": 6},
+				"containerEngine": {"allowedImages": {"patterns": ["docker.io", "ghcr.io"]}},
+				"application": {"extensions": {"installed": {"c/d": "2.0"}}}
+			}`),
+			[]byte(`{
+				"kubernetes.version": {"current": "1.29.4", "desired": "1.30.2", "severity": "reset"},
+				"virtualMachine.memoryInGB": {"current": 4, "desired": 6, "severity": "restart"},
+				"kubernetes.port": {"current": 6443, "desired": 6444, "severity": "restart"}
+			}`))
+		require.NoError(t, err)
+		assert.Equal(t, settingsDryRun{
+			Changes: []settingsChange{
+				{Path: "application.extensions.installed.c/d", Proposed: "2.0"},
+				{Path: "containerEngine.allowedImages.patterns", Current: []any{"docker.io"}, Proposed: []any{"docker.io", "ghcr.io"}},
+				{Path: "kubernetes.port", Current: 6443.0, Proposed: 6444.0, Effect: settingsEffectRestart},
+				{Path: "kubernetes.version", Current: "1.29.4", Proposed: "1.30.2", Effect: settingsEffectReset},
+				{Path: "virtualMachine.memoryInGB", Current: 4.0, Proposed: 6.0, Effect: settingsEffectRestart},
+			},
+			RestartRequired: true,
+			ResetRequired:   true,
+		}, result)
+		assert.Equal(t, []string{"kubernetes.version", `"1.29.4"`, `"1.30.2"`, "reset"}, result.Rows()[3])
+		assert.Equal(t, []string{"application.extensions.installed.c/d", "(unset)", `"2.0"`, ""}, result.Rows()[0])
+	})
+	t.Run("invalid proposal", func(t *testing.T) {
+		_, err := newSettingsDryRun([]byte(dryRunCurrentSettings), []byte(`[]`), nil)
+		assert.ErrorContains(t, err, "failed to parse proposed settings")
+	})
+}
+
+func TestPrintSettingsDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	printSettingsDryRun(&buf, settingsDryRun{Changes: []settingsChange{}})
+	assert.Equal(t, "No settings would change.\n", buf.String())
+
+	buf.Reset()
+	printSettingsDryRun(&buf, settingsDryRun{
+		Changes: []settingsChange{
+			{Path: "application.debug", Current: false, Proposed: true},
+			{Path: "virtualMachine.memoryInGB", Current: 4.0, Proposed: 6.0, Effect: settingsEffectRestart},
+		},
+		RestartRequired: true,
+	})
+	assert.Equal(t, `Settings that would change:
+  application.debug: false -> true
+  virtualMachine.memoryInGB: 4 -> 6 (requires restart)
+Applying them would restart the backend.
+No changes were made.
+`, buf.String())
+}
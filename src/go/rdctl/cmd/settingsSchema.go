@@ -0,0 +1,125 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var settingsSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Output the JSON Schema of the settings",
+	Long: `Output the JSON Schema of the settings document, including the type, allowed
+values, and default value of each setting.  This can be used to validate
+deployment profiles, or to help editors complete 'rdctl api' payloads.
+
+If Rancher Desktop is running, settings that are locked by a deployment profile
+are marked as read-only, with "x-rd-locked": true.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatJSON)
+		if err != nil {
+			return err
+		}
+		if format == output.FormatTable {
+			return fmt.Errorf("output format %q is not supported for the settings schema", format)
+		}
+		cmd.SilenceUsage = true
+		schema, err := settingsSchema(getLockedSettings())
+		if err != nil {
+			return err
+		}
+		return renderOutput(format, schema)
+	},
+}
+
+func init() {
+	settingsCmd.AddCommand(settingsSchemaCmd)
+	defineOutputFlag(settingsSchemaCmd)
+}
+
+// getLockedSettings returns the locked settings from the server, or nil if
+// they could not be retrieved (for example, because the app isn't running).
+func getLockedSettings() map[string]any {
+	connectionInfo, err := config.GetConnectionInfo(true)
+	if err != nil || connectionInfo == nil {
+		return nil
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	command := client.VersionCommand("", "settings/locked")
+	result, err := client.ProcessRequestForUtility(rdClient.DoRequest("GET", command))
+	if err != nil {
+		logrus.Debugf("Failed to get locked settings; not marking them in the schema: %s", err)
+		return nil
+	}
+	var locked map[string]any
+	if err := json.Unmarshal(result, &locked); err != nil {
+		logrus.Debugf("Failed to parse locked settings %q: %s", result, err)
+		return nil
+	}
+	return locked
+}
+
+// settingsSchema returns the settings schema, with the given locked settings
+// (in the same shape as the settings, with true for each locked setting)
+// marked as read-only.
+func settingsSchema(locked map[string]any) (map[string]any, error) {
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(options.SettingsJSONSchema), &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse settings schema: %w", err)
+	}
+	if err := markLockedSettings(schema, locked, nil); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func markLockedSettings(schema map[string]any, locked map[string]any, path []string) error {
+	for name, value := range locked {
+		properties, _ := schema["properties"].(map[string]any)
+		property, ok := properties[name].(map[string]any)
+		if !ok {
+			// The locked settings may be for a different version of the
+			// settings; ignore anything that isn't in the schema.
+			logrus.Debugf("Ignoring unknown locked setting %s", strings.Join(append(path, name), "."))
+			continue
+		}
+		switch value := value.(type) {
+		case bool:
+			if value {
+				property["readOnly"] = true
+				property["x-rd-locked"] = true
+			}
+		case map[string]any:
+			if err := markLockedSettings(property, value, append(path, name)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected locked setting %s: %v", strings.Join(append(path, name), "."), value)
+		}
+	}
+	return nil
+}
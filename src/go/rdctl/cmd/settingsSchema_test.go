@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsSchema(t *testing.T) {
+	schema, err := settingsSchema(map[string]any{
+		"containerEngine": map[string]any{
+			"allowedImages": map[string]any{"enabled": true},
+			"name":          false,
+		},
+		"noSuchSetting": true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "object", schema["type"])
+
+	property := func(path ...string) map[string]any {
+		current := schema
+		for _, name := range path {
+			properties, ok := current["properties"].(map[string]any)
+			require.True(t, ok, "no properties at %v", path)
+			current, ok = properties[name].(map[string]any)
+			require.True(t, ok, "missing property %s in %v", name, path)
+		}
+		return current
+	}
+
+	assert.Equal(t, "integer", property("version")["type"])
+	engine := property("containerEngine", "name")
+	assert.Equal(t, "string", engine["type"])
+	assert.Contains(t, engine["enum"], "moby")
+	assert.NotContains(t, engine, "readOnly")
+
+	enabled := property("containerEngine", "allowedImages", "enabled")
+	assert.Equal(t, true, enabled["readOnly"])
+	assert.Equal(t, true, enabled["x-rd-locked"])
+	assert.NotContains(t, property("containerEngine", "allowedImages", "patterns"), "readOnly")
+}
+
+func TestSettingsSchemaUnexpectedLockedValue(t *testing.T) {
+	_, err := settingsSchema(map[string]any{"containerEngine": map[string]any{"name": "moby"}})
+	assert.ErrorContains(t, err, "unexpected locked setting containerEngine.name")
+}
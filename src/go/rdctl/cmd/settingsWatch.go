@@ -0,0 +1,118 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var settingsWatchFilters []string
+
+var settingsWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print settings changes as they happen",
+	Long: `Print a JSON object on its own line whenever a setting changes, until
+interrupted.  Each object has the following fields:
+
+  time    When the change was made
+  source  What made the change: "api", "ui", or "application"
+  path    The dotted path of the setting, such as kubernetes.enabled
+  old     The previous value (absent if the setting was added)
+  new     The new value (absent if the setting was removed)
+
+For example:
+
+> rdctl settings watch --setting kubernetes
+-- Prints changes to kubernetes.enabled, kubernetes.version, and so on`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return watchSettings(os.Stdout)
+	},
+}
+
+func init() {
+	settingsCmd.AddCommand(settingsWatchCmd)
+	settingsWatchCmd.Flags().StringSliceVar(&settingsWatchFilters, "setting", nil,
+		"Only print changes to the given setting or its children; may be repeated")
+}
+
+func watchSettings(w io.Writer) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	response, err := rdClient.DoRequest("GET", client.VersionCommand("", "settings/watch"))
+	if err != nil || response.StatusCode < 200 || response.StatusCode >= 300 {
+		// Let the usual handling report the error; this reads the whole body,
+		// which is fine as it's not a stream in this case.
+		_, err = client.ProcessRequestForUtility(response, err)
+		return err
+	}
+	defer response.Body.Close()
+	return copySettingsChanges(response.Body, w, settingsWatchFilters)
+}
+
+// copySettingsChanges copies the newline-delimited settings changes from the
+// reader to the writer, keeping only changes to the given settings (or all of
+// them, if none are given).
+func copySettingsChanges(r io.Reader, w io.Writer, filters []string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if len(filters) > 0 {
+			var change struct {
+				Path string `json:"path"`
+			}
+			if err := json.Unmarshal(line, &change); err != nil {
+				return fmt.Errorf("failed to parse settings change %q: %w", line, err)
+			}
+			if !matchesSettingFilter(change.Path, filters) {
+				continue
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// matchesSettingFilter returns whether the dotted setting path is one of the
+// filters, or is nested inside one of them.
+func matchesSettingFilter(path string, filters []string) bool {
+	for _, filter := range filters {
+		if path == filter || strings.HasPrefix(path, filter+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopySettingsChanges(t *testing.T) {
+	input := strings.Join([]string{
+		`{"time":"2024-05-06T07:08:09.000Z","source":"api","path":"kubernetes.enabled","old":true,"new":false}`,
+		``,
+		`{"time":"2024-05-06T07:08:09.000Z","source":"api","path":"kubernetesFoo","old":1,"new":2}`,
+		`{"time":"2024-05-06T07:08:10.000Z","source":"ui","path":"application.debug","old":false,"new":true}`,
+	}, "\n")
+
+	var output bytes.Buffer
+	assert.NoError(t, copySettingsChanges(strings.NewReader(input), &output, nil))
+	assert.Equal(t, 3, strings.Count(output.String(), "\n"))
+
+	output.Reset()
+	assert.NoError(t, copySettingsChanges(strings.NewReader(input), &output, []string{"kubernetes", "application.debug"}))
+	assert.Equal(t,
+		`{"time":"2024-05-06T07:08:09.000Z","source":"api","path":"kubernetes.enabled","old":true,"new":false}`+"\n"+
+			`{"time":"2024-05-06T07:08:10.000Z","source":"ui","path":"application.debug","old":false,"new":true}`+"\n",
+		output.String())
+
+	assert.ErrorContains(t, copySettingsChanges(strings.NewReader("not json"), &output, []string{"kubernetes"}), "failed to parse")
+}
@@ -18,7 +18,9 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
@@ -44,14 +46,27 @@ var shellCmd = &cobra.Command{
 -- Runs 'ls -CF' from /tmp on the VM
 > rdctl shell bash -c "cd .. ; pwd"
 -- Usual way of running multiple statements on a single call
+> rdctl shell --user root --workdir /etc --env LC_ALL=C ls -l
+-- Runs 'ls -l' from /etc as root, with LC_ALL set to C
+
+Options must be given before the command; use "--" to run a command whose
+name starts with "-".
+  -u, --user <name>      run the command as the given user in the VM
+  -w, --workdir <dir>    run the command in the given directory in the VM
+  -e, --env <KEY=VALUE>  set an environment variable; may be repeated
 `,
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Do manual flag parsing looking to see if we should give help instead.
-		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		// Do manual flag parsing, as everything after the options belongs to
+		// the command being run.
+		options, args, err := parseShellOptions(args)
+		if err != nil {
+			return err
+		}
+		if options.help {
 			return cmd.Help()
 		}
-		return doShellCommand(cmd, args)
+		return doShellCommand(cmd, options, args)
 	},
 }
 
@@ -59,15 +74,140 @@ func init() {
 	rootCmd.AddCommand(shellCmd)
 }
 
-func doShellCommand(cmd *cobra.Command, args []string) error {
+// shellOptions are the options given to `rdctl shell` before the command.
+type shellOptions struct {
+	user    string
+	workdir string
+	env     []string
+	help    bool
+	// quiet suppresses reporting why the VM isn't running.
+	quiet bool
+}
+
+var envAssignmentRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// parseShellOptions parses the options at the start of the arguments, and
+// returns them along with the remaining arguments (the command to run).
+// Parsing stops at the first argument that isn't a known option.
+func parseShellOptions(args []string) (shellOptions, []string, error) {
+	var options shellOptions
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		if !strings.HasPrefix(name, "--") {
+			// Short options don't use "=".
+			name, value, hasValue = args[0], "", false
+		}
+		switch name {
+		case "--":
+			return options, args[1:], nil
+		case "-h", "--help":
+			options.help = true
+			return options, args[1:], nil
+		case "-u", "--user", "-w", "--workdir", "-e", "--env":
+		default:
+			return options, args, nil
+		}
+		if !hasValue {
+			if len(args) < 2 {
+				return options, nil, fmt.Errorf("option %s requires a value", name)
+			}
+			value = args[1]
+			args = args[1:]
+		}
+		args = args[1:]
+		switch name {
+		case "-u", "--user":
+			if value == "" {
+				return options, nil, errors.New("user name must not be empty")
+			}
+			options.user = value
+		case "-w", "--workdir":
+			if value == "" {
+				return options, nil, errors.New("working directory must not be empty")
+			}
+			options.workdir = value
+		case "-e", "--env":
+			if !envAssignmentRegexp.MatchString(value) {
+				return options, nil, fmt.Errorf("invalid environment variable %q: must be of the form KEY=VALUE", value)
+			}
+			options.env = append(options.env, value)
+		}
+	}
+	return options, args, nil
+}
+
+// wrap returns the command to run in the VM to apply the options to the given
+// command, which may be empty to run an interactive shell.  The working
+// directory is only handled here on Windows; limactl has its own option.
+func (options shellOptions) wrap(goos string, args []string) []string {
+	if options.user == "" && options.workdir == "" && len(options.env) == 0 {
+		return args
+	}
+	if goos == "windows" {
+		// wsl-exec runs the command as root via nsenter, so the user must be
+		// switched inside the namespace.
+		if len(args) == 0 {
+			args = []string{"/bin/sh"}
+		}
+		if options.workdir != "" {
+			args = append([]string{"sh", "-c", `cd "$0" && exec "$@"`, options.workdir}, args...)
+		}
+		if options.user != "" {
+			args = append([]string{"su", "-s", "/bin/sh", options.user, "-c", `exec "$0" "$@"`}, args...)
+		}
+		if len(options.env) > 0 {
+			args = append(append([]string{"env"}, options.env...), args...)
+		}
+		return args
+	}
+	if len(args) == 0 && (options.user != "" || len(options.env) > 0) {
+		// sudo sets $SHELL to the login shell of the target user.
+		args = []string{"sh", "-c", `exec "${SHELL:-/bin/sh}" -l`}
+	}
+	if len(options.env) > 0 {
+		args = append(append([]string{"env"}, options.env...), args...)
+	}
+	if options.user != "" {
+		args = append([]string{"sudo", "-u", options.user, "-H", "--"}, args...)
+	}
+	return args
+}
+
+func doShellCommand(cmd *cobra.Command, options shellOptions, args []string) error {
 	cmd.SilenceUsage = true
+	shellCommand, err := newShellCommandWithOptions(options, args...)
+	if errors.Is(err, errVMNotRunning) {
+		// No further output wanted, so just exit with the desired status.
+		os.Exit(1)
+	} else if err != nil {
+		return err
+	}
+	shellCommand.Stdin = os.Stdin
+	shellCommand.Stdout = os.Stdout
+	shellCommand.Stderr = os.Stderr
+	return shellCommand.Run()
+}
+
+// errVMNotRunning is returned from newShellCommand if the VM is not running;
+// the reason has already been reported to the user.
+var errVMNotRunning = errors.New("the Rancher Desktop VM is not running")
+
+// newShellCommand returns a command that runs the given command in the
+// Rancher Desktop VM.
+func newShellCommand(args ...string) (*exec.Cmd, error) {
+	return newShellCommandWithOptions(shellOptions{}, args...)
+}
+
+// newShellCommandWithOptions is like newShellCommand, but runs the command as
+// specified by the options.
+func newShellCommandWithOptions(options shellOptions, args ...string) (*exec.Cmd, error) {
 	var commandName string
+	args = options.wrap(runtime.GOOS, args)
 	if runtime.GOOS == "windows" {
 		commandName = "wsl"
 		distroName := "rancher-desktop"
-		if !checkWSLIsRunning(distroName) {
-			// No further output wanted, so just exit with the desired status.
-			os.Exit(1)
+		if !checkWSLIsRunning(distroName, options.quiet) {
+			return nil, errVMNotRunning
 		}
 		args = append([]string{
 			"--distribution", distroName,
@@ -76,31 +216,49 @@ func doShellCommand(cmd *cobra.Command, args []string) error {
 	} else {
 		paths, err := p.GetPaths()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if err = directories.SetupLimaHome(paths.AppHome); err != nil {
-			return err
+			return nil, err
 		}
 		commandName, err = directories.GetLimactlPath()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !checkLimaIsRunning(commandName) {
-			// No further output wanted, so just exit with the desired status.
-			os.Exit(1)
+		if !checkLimaIsRunning(commandName, options.quiet) {
+			return nil, errVMNotRunning
 		}
-		args = append([]string{"shell", "0"}, args...)
+		shellArgs := []string{"shell"}
+		if options.workdir != "" {
+			shellArgs = append(shellArgs, "--workdir", options.workdir)
+		}
+		args = append(append(shellArgs, "0"), args...)
 	}
-	shellCommand := exec.Command(commandName, args...)
-	shellCommand.Stdin = os.Stdin
-	shellCommand.Stdout = os.Stdout
-	shellCommand.Stderr = os.Stderr
-	return shellCommand.Run()
+	return exec.Command(commandName, args...), nil
+}
+
+// newRootShellCommand is like newShellCommand, but runs the command as root.
+func newRootShellCommand(args ...string) (*exec.Cmd, error) {
+	return newRootShellCommandWithOptions(shellOptions{}, args...)
+}
+
+// newRootShellCommandWithOptions is like newShellCommandWithOptions, but runs
+// the command as root.
+func newRootShellCommandWithOptions(options shellOptions, args ...string) (*exec.Cmd, error) {
+	if runtime.GOOS != "windows" {
+		// The shell runs as root on Windows, but not in the lima VM.
+		args = append([]string{"sudo"}, args...)
+	}
+	return newShellCommandWithOptions(options, args...)
 }
 
 const restartDirective = "Either run 'rdctl start' or start the Rancher Desktop application first"
 
-func checkLimaIsRunning(commandName string) bool {
+func checkLimaIsRunning(commandName string, quiet bool) bool {
+	w, errorf := io.Writer(os.Stderr), logrus.Errorf
+	if quiet {
+		w, errorf = io.Discard, func(string, ...any) {}
+	}
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 
@@ -108,7 +266,7 @@ func checkLimaIsRunning(commandName string) bool {
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		logrus.Errorf("Failed to run %q: %s\n", cmd, err)
+		errorf("Failed to run %q: %s\n", cmd, err)
 		return false
 	}
 	limaState := strings.TrimRight(stdout.String(), "\n")
@@ -117,32 +275,36 @@ func checkLimaIsRunning(commandName string) bool {
 		return true
 	}
 	if limaState != "" {
-		fmt.Fprintf(os.Stderr,
+		fmt.Fprintf(w,
 			"The Rancher Desktop VM needs to be in state \"Running\" in order to execute 'rdctl shell', but it is currently in state %q.\n%s.\n", limaState, restartDirective)
 		return false
 	}
 	errorMsg := stderr.String()
 	if strings.Contains(errorMsg, "No instance matching 0 found.") {
-		logrus.Errorf("The Rancher Desktop VM needs to be created.\n%s.\n", restartDirective)
+		errorf("The Rancher Desktop VM needs to be created.\n%s.\n", restartDirective)
 	} else if len(errorMsg) > 0 {
-		fmt.Fprintln(os.Stderr, errorMsg)
+		fmt.Fprintln(w, errorMsg)
 	} else {
-		fmt.Fprintln(os.Stderr, "Underlying limactl check failed with no output.")
+		fmt.Fprintln(w, "Underlying limactl check failed with no output.")
 	}
 	return false
 }
 
-func checkWSLIsRunning(distroName string) bool {
+func checkWSLIsRunning(distroName string, quiet bool) bool {
+	w, errorf := io.Writer(os.Stderr), logrus.Errorf
+	if quiet {
+		w, errorf = io.Discard, func(string, ...any) {}
+	}
 	// Ignore error messages; none are expected here
 	rawOutput, err := exec.Command("wsl", "--list", "--verbose").CombinedOutput()
 	if err != nil {
-		logrus.Errorf("Failed to run 'wsl --list --verbose': %s\n", err)
+		errorf("Failed to run 'wsl --list --verbose': %s\n", err)
 		return false
 	}
 	decoder := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
 	output, err := decoder.Bytes(rawOutput)
 	if err != nil {
-		logrus.Errorf("Failed to read WSL output ([% q]...); error: %s\n", rawOutput[:12], err)
+		errorf("Failed to read WSL output ([% q]...); error: %s\n", rawOutput[:12], err)
 		return false
 	}
 	isListed := false
@@ -162,11 +324,11 @@ func checkWSLIsRunning(distroName string) bool {
 		return true
 	}
 	if !isListed {
-		fmt.Fprintf(os.Stderr,
+		fmt.Fprintf(w,
 			"The Rancher Desktop WSL needs to be running in order to execute 'rdctl shell', but it currently is not.\n%s.\n", restartDirective)
 		return false
 	}
-	fmt.Fprintf(os.Stderr,
+	fmt.Fprintf(w,
 		"The Rancher Desktop WSL needs to be in state \"Running\" in order to execute 'rdctl shell', but it is currently in state \"%s\".\n%s.\n", targetState, restartDirective)
 	return false
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShellOptions(t *testing.T) {
+	t.Run("no options", func(t *testing.T) {
+		options, args, err := parseShellOptions([]string{"ls", "-l", "--user", "root"})
+		require.NoError(t, err)
+		assert.Equal(t, shellOptions{}, options)
+		assert.Equal(t, []string{"ls", "-l", "--user", "root"}, args)
+	})
+	t.Run("all options", func(t *testing.T) {
+		options, args, err := parseShellOptions([]string{"-u", "root", "--workdir=/tmp", "-e", "A=1", "--env", "B=x=y", "id", "-u"})
+		require.NoError(t, err)
+		assert.Equal(t, shellOptions{user: "root", workdir: "/tmp", env: []string{"A=1", "B=x=y"}}, options)
+		assert.Equal(t, []string{"id", "-u"}, args)
+	})
+	t.Run("end of options", func(t *testing.T) {
+		options, args, err := parseShellOptions([]string{"--user", "root", "--", "--help"})
+		require.NoError(t, err)
+		assert.Equal(t, shellOptions{user: "root"}, options)
+		assert.Equal(t, []string{"--help"}, args)
+	})
+	t.Run("help", func(t *testing.T) {
+		options, _, err := parseShellOptions([]string{"-w", "/", "--help"})
+		require.NoError(t, err)
+		assert.True(t, options.help)
+	})
+	t.Run("unknown options are part of the command", func(t *testing.T) {
+		options, args, err := parseShellOptions([]string{"-x", "foo"})
+		require.NoError(t, err)
+		assert.Equal(t, shellOptions{}, options)
+		assert.Equal(t, []string{"-x", "foo"}, args)
+	})
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := parseShellOptions([]string{"--user"})
+		assert.ErrorContains(t, err, "option --user requires a value")
+		_, _, err = parseShellOptions([]string{"--user=", "id"})
+		assert.ErrorContains(t, err, "user name must not be empty")
+		_, _, err = parseShellOptions([]string{"-e", "1A=b", "env"})
+		assert.ErrorContains(t, err, "invalid environment variable")
+		_, _, err = parseShellOptions([]string{"-e", "NOVALUE", "env"})
+		assert.ErrorContains(t, err, "invalid environment variable")
+	})
+}
+
+func TestShellOptionsWrap(t *testing.T) {
+	t.Run("no options", func(t *testing.T) {
+		assert.Equal(t, []string{"ls"}, shellOptions{}.wrap("darwin", []string{"ls"}))
+		assert.Empty(t, shellOptions{}.wrap("windows", nil))
+	})
+	t.Run("lima", func(t *testing.T) {
+		options := shellOptions{user: "root", workdir: "/tmp", env: []string{"A=1"}}
+		assert.Equal(t,
+			[]string{"sudo", "-u", "root", "-H", "--", "env", "A=1", "ls", "-l"},
+			options.wrap("darwin", []string{"ls", "-l"}))
+		assert.Equal(t,
+			[]string{"sudo", "-u", "root", "-H", "--", "env", "A=1", "sh", "-c", `exec "${SHELL:-/bin/sh}" -l`},
+			options.wrap("linux", nil))
+		assert.Empty(t, shellOptions{workdir: "/tmp"}.wrap("linux", nil), "limactl handles the working directory")
+	})
+	t.Run("windows", func(t *testing.T) {
+		options := shellOptions{user: "nobody", workdir: "/tmp", env: []string{"A=1"}}
+		assert.Equal(t,
+			[]string{
+				"env", "A=1",
+				"su", "-s", "/bin/sh", "nobody", "-c", `exec "$0" "$@"`,
+				"sh", "-c", `cd "$0" && exec "$@"`, "/tmp",
+				"ls", "-l",
+			},
+			options.wrap("windows", []string{"ls", "-l"}))
+		assert.Equal(t,
+			[]string{"sh", "-c", `cd "$0" && exec "$@"`, "/tmp", "/bin/sh"},
+			shellOptions{workdir: "/tmp"}.wrap("windows", nil))
+	})
+}
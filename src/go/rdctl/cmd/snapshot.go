@@ -28,6 +28,27 @@ func init() {
 	rootCmd.AddCommand(snapshotCmd)
 }
 
+// completeSnapshotNames completes the names of existing snapshots, for
+// commands that take a single snapshot name.
+func completeSnapshotNames(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	snapshots, err := manager.List(false)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	names := make([]string, 0, len(snapshots))
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
 func exitWithJsonOrErrorCondition(e error) error {
 	if outputJsonFormat {
 		exitStatus := 0
@@ -9,8 +9,12 @@ import (
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
 	"github.com/sirupsen/logrus"
@@ -63,6 +67,7 @@ func createSnapshot(args []string) error {
 	if err := manager.ValidateName(name); err != nil {
 		return err
 	}
+	manager.ListImages = listContainerImages
 
 	// Ideally we would not use the deprecated syscall package,
 	// but it works well with all expected scenarios and allows us
@@ -80,7 +85,12 @@ func createSnapshot(args []string) error {
 		return fmt.Errorf("failed to create snapshot: %w", err)
 	}
 
-	// exclude snapshots directory from time machine backups if on macOS
+	return excludeSnapshotsFromBackups(manager)
+}
+
+// excludeSnapshotsFromBackups excludes the snapshots directory from Time
+// Machine backups if on macOS.
+func excludeSnapshotsFromBackups(manager *snapshot.Manager) error {
 	if runtime.GOOS != "darwin" {
 		return nil
 	}
@@ -96,3 +106,37 @@ func createSnapshot(args []string) error {
 	}
 	return nil
 }
+
+// listContainerImages returns the container images in the VM, so that they
+// can be compared by `rdctl snapshot diff`.  This is only possible while the
+// backend is running.
+func listContainerImages() ([]string, error) {
+	connectionInfo, err := config.GetConnectionInfo(true)
+	if err != nil || connectionInfo == nil {
+		return nil, errors.New("the Rancher Desktop application is not running")
+	}
+	state, err := client.NewRDClient(connectionInfo).GetBackendState()
+	if err != nil {
+		return nil, err
+	}
+	if state.VMState != "STARTED" {
+		return nil, fmt.Errorf("the backend is in state %s", state.VMState)
+	}
+	args := append(containerEngineCLI(), "image", "ls", "--format", "{{.Repository}}:{{.Tag}}")
+	command, err := newRootShellCommand(args...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	var images []string
+	for _, image := range strings.Split(string(output), "\n") {
+		image = strings.TrimSpace(image)
+		if image != "" && image != "<none>:<none>" && !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
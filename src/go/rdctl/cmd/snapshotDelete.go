@@ -8,9 +8,10 @@ import (
 )
 
 var snapshotDeleteCmd = &cobra.Command{
-	Use:   "delete <id>",
-	Short: "Delete a snapshot",
-	Args:  cobra.ExactArgs(1),
+	Use:               "delete <id>",
+	Short:             "Delete a snapshot",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSnapshotNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 		err := deleteSnapshot(cmd, args)
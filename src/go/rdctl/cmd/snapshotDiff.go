@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <from> <to>",
+	Short: "Compare two snapshots",
+	Long: `Compare two snapshots, showing the settings that differ, the container images
+added or removed, and the change in the size of the snapshot.  Use this to check
+what would be lost before restoring an older snapshot.
+
+Images are only known for snapshots that were created while Rancher Desktop was
+running.`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeSnapshotNames(cmd, nil, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault("")
+		if err != nil {
+			return err
+		}
+		if format == output.FormatTable {
+			return fmt.Errorf("output format %q is not supported for snapshot differences", format)
+		}
+		cmd.SilenceUsage = true
+		manager, err := snapshot.NewManager()
+		if err != nil {
+			return fmt.Errorf("failed to create snapshot manager: %w", err)
+		}
+		diff, err := manager.Diff(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if format != "" {
+			return renderOutput(format, diff)
+		}
+		printSnapshotDiff(os.Stdout, diff)
+		return nil
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	defineOutputFlag(snapshotDiffCmd)
+}
+
+// formatSettingValue formats a setting value from a snapshot for display.
+func formatSettingValue(value any) string {
+	if value == nil {
+		return "(unset)"
+	}
+	result, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(result)
+}
+
+func printSnapshotDiff(w io.Writer, diff snapshot.Diff) {
+	fmt.Fprintf(w, "Settings changed from %q to %q:\n", diff.From, diff.To)
+	if len(diff.Settings) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, change := range diff.Settings {
+		fmt.Fprintf(w, "  %s: %s -> %s\n", change.Path, formatSettingValue(change.From), formatSettingValue(change.To))
+	}
+
+	fmt.Fprintln(w, "\nImages:")
+	switch {
+	case !diff.ImagesKnown:
+		fmt.Fprintln(w, "  (unknown; the image list was not recorded for both snapshots)")
+	case len(diff.ImagesAdded) == 0 && len(diff.ImagesRemoved) == 0:
+		fmt.Fprintln(w, "  (no changes)")
+	}
+	for _, image := range diff.ImagesAdded {
+		fmt.Fprintf(w, "  + %s\n", image)
+	}
+	for _, image := range diff.ImagesRemoved {
+		fmt.Fprintf(w, "  - %s\n", image)
+	}
+
+	sizeChange := diff.ToSize - diff.FromSize
+	sign := "+"
+	if sizeChange < 0 {
+		sign = "-"
+		sizeChange = -sizeChange
+	}
+	fmt.Fprintf(w, "\nSize: %s -> %s (%s%s)\n",
+		formatBytes(uint64(diff.FromSize)), formatBytes(uint64(diff.ToSize)), sign, formatBytes(uint64(sizeChange)))
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintSnapshotDiff(t *testing.T) {
+	var buf bytes.Buffer
+	printSnapshotDiff(&buf, snapshot.Diff{
+		From: "old",
+		To:   "new",
+		Settings: []snapshot.SettingChange{
+			{Path: "kubernetes.enabled", From: true, To: false},
+			{Path: "kubernetes.version", From: "1.29.1", To: nil},
+		},
+		ImagesKnown:   true,
+		ImagesAdded:   []string{"busybox:latest"},
+		ImagesRemoved: []string{"alpine:3.19"},
+		FromSize:      3 << 30,
+		ToSize:        2 << 30,
+	})
+	assert.Equal(t, `Settings changed from "old" to "new":
+  kubernetes.enabled: true -> false
+  kubernetes.version: "1.29.1" -> (unset)
+
+Images:
+  + busybox:latest
+  - alpine:3.19
+
+Size: 3.00GiB -> 2.00GiB (-1.00GiB)
+`, buf.String())
+
+	buf.Reset()
+	printSnapshotDiff(&buf, snapshot.Diff{From: "a", To: "b", FromSize: 1024, ToSize: 2048})
+	assert.Equal(t, `Settings changed from "a" to "b":
+  (none)
+
+Images:
+  (unknown; the image list was not recorded for both snapshots)
+
+Size: 1.00KiB -> 2.00KiB (+1.00KiB)
+`, buf.String())
+}
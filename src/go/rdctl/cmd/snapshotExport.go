@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotExportCmd = &cobra.Command{
+	Use:   "export <name> <file.tar.zst>",
+	Short: "Export a snapshot to an archive file",
+	Long: `Export a snapshot, including its settings and VM data, to a single compressed
+archive file.  The archive can be imported with "rdctl snapshot import" on
+another machine running the same operating system, or attached to a support
+ticket.`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completeSnapshotNames(cmd, args, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJsonOrErrorCondition(exportSnapshot(args[0], args[1]))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotExportCmd)
+	snapshotExportCmd.Flags().BoolVarP(&outputJsonFormat, "json", "", false, "output json format")
+}
+
+func exportSnapshot(name, fileName string) (err error) {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	// Check the snapshot exists before creating the output file.
+	if _, err := manager.Snapshot(name); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		err = errors.Join(err, file.Close())
+		if err != nil {
+			os.Remove(fileName)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+	if err := manager.Export(ctx, name, file); err != nil {
+		return fmt.Errorf("failed to export snapshot %q: %w", name, err)
+	}
+	return nil
+}
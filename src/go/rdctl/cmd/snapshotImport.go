@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+var snapshotImportName string
+
+var snapshotImportCmd = &cobra.Command{
+	Use:   "import <file.tar.zst>",
+	Short: "Import a snapshot from an archive file",
+	Long: `Import a snapshot from an archive created by "rdctl snapshot export".  The
+checksums of all files in the archive are verified, and archives created on a
+different operating system or by a newer version of Rancher Desktop are
+rejected.  The imported snapshot can then be restored as usual.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJsonOrErrorCondition(importSnapshot(args[0]))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotImportCmd)
+	snapshotImportCmd.Flags().BoolVarP(&outputJsonFormat, "json", "", false, "output json format")
+	snapshotImportCmd.Flags().StringVar(&snapshotImportName, "name", "", "name of the imported snapshot (default: the name it was exported with)")
+}
+
+func importSnapshot(fileName string) error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+	if _, err := manager.Import(ctx, file, snapshotImportName); err != nil {
+		return fmt.Errorf("failed to import snapshot from %q: %w", fileName, err)
+	}
+	return nil
+}
@@ -6,9 +6,9 @@ import (
 	"os"
 	"sort"
 	"strings"
-	"text/tabwriter"
 	"time"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
 	"github.com/spf13/cobra"
 )
@@ -30,23 +30,42 @@ func (snapshots SortableSnapshots) Swap(i, j int) {
 	snapshots[j] = temp
 }
 
+func (snapshots SortableSnapshots) Headers() []string {
+	return []string{"NAME", "CREATED", "DESCRIPTION"}
+}
+
+func (snapshots SortableSnapshots) Rows() [][]string {
+	rows := make([][]string, 0, len(snapshots))
+	for _, aSnapshot := range snapshots {
+		prettyCreated := aSnapshot.Created.Format(time.RFC1123)
+		desc := truncateAtNewlineOrMaxRunes(aSnapshot.Description, 63)
+		rows = append(rows, []string{aSnapshot.Name, prettyCreated, desc})
+	}
+	return rows
+}
+
 var snapshotListCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls"},
 	Short:   "List snapshots",
 	Args:    cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
 		cmd.SilenceUsage = true
-		return exitWithJsonOrErrorCondition(listSnapshot())
+		return exitWithJsonOrErrorCondition(listSnapshot(format))
 	},
 }
 
 func init() {
 	snapshotCmd.AddCommand(snapshotListCmd)
+	defineOutputFlag(snapshotListCmd)
 	snapshotListCmd.Flags().BoolVar(&outputJsonFormat, "json", false, "output json format")
 }
 
-func listSnapshot() error {
+func listSnapshot(format output.Format) error {
 	manager, err := snapshot.NewManager()
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot manager: %w", err)
@@ -59,6 +78,15 @@ func listSnapshot() error {
 	if outputJsonFormat {
 		return jsonOutput(snapshots)
 	}
+	if format != output.FormatTable {
+		if snapshots == nil {
+			snapshots = []snapshot.Snapshot{}
+		}
+		for i := range snapshots {
+			snapshots[i].ID = ""
+		}
+		return renderOutput(format, snapshots)
+	}
 	return tabularOutput(snapshots)
 }
 
@@ -79,15 +107,7 @@ func tabularOutput(snapshots []snapshot.Snapshot) error {
 		fmt.Fprintln(os.Stderr, "No snapshots present.")
 		return nil
 	}
-	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 4, ' ', 0)
-	fmt.Fprintf(writer, "NAME\tCREATED\tDESCRIPTION\n")
-	for _, aSnapshot := range snapshots {
-		prettyCreated := aSnapshot.Created.Format(time.RFC1123)
-		desc := truncateAtNewlineOrMaxRunes(aSnapshot.Description, 63)
-		fmt.Fprintf(writer, "%s\t%s\t%s\n", aSnapshot.Name, prettyCreated, desc)
-	}
-	writer.Flush()
-	return nil
+	return renderOutput(output.FormatTable, SortableSnapshots(snapshots))
 }
 
 // Truncates a string to either the first newline or a maximum number of
@@ -13,9 +13,10 @@ import (
 )
 
 var snapshotRestoreCmd = &cobra.Command{
-	Use:   "restore <id>",
-	Short: "Restore a snapshot",
-	Args:  cobra.ExactArgs(1),
+	Use:               "restore <id>",
+	Short:             "Restore a snapshot",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeSnapshotNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 		return exitWithJsonOrErrorCondition(restoreSnapshot(cmd, args))
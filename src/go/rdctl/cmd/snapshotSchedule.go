@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/runner"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// scheduleSettings mirrors the snapshots.schedule settings.
+type scheduleSettings struct {
+	Enabled         bool `json:"enabled"`
+	IntervalInHours int  `json:"intervalInHours"`
+	Retention       int  `json:"retention"`
+	SkipIfUnchanged bool `json:"skipIfUnchanged"`
+}
+
+// defaultScheduleSettings matches the defaults in config/settings.ts, and is
+// used for settings files that predate scheduled snapshots.
+var defaultScheduleSettings = scheduleSettings{
+	Enabled:         false,
+	IntervalInHours: 24,
+	Retention:       5,
+	SkipIfUnchanged: true,
+}
+
+// scheduleSettingsDocument is the part of the settings that holds the
+// schedule.
+type scheduleSettingsDocument struct {
+	Version   int `json:"version,omitempty"`
+	Snapshots struct {
+		Schedule scheduleSettings `json:"schedule"`
+	} `json:"snapshots"`
+}
+
+// snapshotScheduleStatus is reported by `rdctl snapshot schedule`.
+type snapshotScheduleStatus struct {
+	scheduleSettings
+	LastSnapshot *snapshot.Snapshot `json:"lastSnapshot,omitempty"`
+	NextSnapshot *time.Time         `json:"nextSnapshot,omitempty"`
+}
+
+var snapshotScheduleFlags struct {
+	Enable          bool
+	Disable         bool
+	Interval        time.Duration
+	Retention       int
+	SkipIfUnchanged bool
+}
+
+var snapshotScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Show or change the snapshot schedule",
+	Long: `Show or change the schedule for automatic snapshots.  When enabled, Rancher
+Desktop creates a snapshot at the given interval, and deletes the oldest
+scheduled snapshots so only the given number are kept.  Snapshots created with
+"rdctl snapshot create" are never deleted automatically.
+
+Creating a snapshot stops the backend while the files are copied; to avoid
+unnecessary restarts, scheduled snapshots are skipped if the settings and
+container images haven't changed since the last one (unless disabled with
+--skip-if-unchanged=false).
+
+> rdctl snapshot schedule --enable --interval 12h --retention 3
+-- Creates a snapshot every 12 hours, keeping the three most recent ones
+> rdctl snapshot schedule
+-- Shows the current schedule, and when the next snapshot is due
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if snapshotScheduleFlags.Enable && snapshotScheduleFlags.Disable {
+			return errors.New(`can't specify both "--enable" and "--disable"`)
+		}
+		cmd.SilenceUsage = true
+		return exitWithJsonOrErrorCondition(scheduleSnapshots(cmd))
+	},
+}
+
+var snapshotScheduleRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Create a scheduled snapshot now",
+	Long: `Create a scheduled snapshot now, using the retention and skip-if-unchanged
+settings of the snapshot schedule.  This is run by Rancher Desktop when a
+scheduled snapshot is due, but can also be run manually.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return exitWithJsonOrErrorCondition(runScheduledSnapshot())
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotScheduleCmd)
+	snapshotScheduleCmd.AddCommand(snapshotScheduleRunCmd)
+	flags := snapshotScheduleCmd.Flags()
+	flags.BoolVarP(&outputJsonFormat, "json", "", false, "output json format")
+	flags.BoolVar(&snapshotScheduleFlags.Enable, "enable", false, "enable scheduled snapshots")
+	flags.BoolVar(&snapshotScheduleFlags.Disable, "disable", false, "disable scheduled snapshots")
+	flags.DurationVar(&snapshotScheduleFlags.Interval, "interval", 0, "time between scheduled snapshots, in whole hours (such as 24h)")
+	flags.IntVar(&snapshotScheduleFlags.Retention, "retention", 0, "number of scheduled snapshots to keep")
+	flags.BoolVar(&snapshotScheduleFlags.SkipIfUnchanged, "skip-if-unchanged", false, "skip scheduled snapshots when the settings and images are unchanged")
+	snapshotScheduleRunCmd.Flags().BoolVarP(&outputJsonFormat, "json", "", false, "output json format")
+}
+
+// parseScheduleSettings extracts the schedule from a settings document,
+// using the defaults for any missing fields.
+func parseScheduleSettings(contents []byte) (scheduleSettings, error) {
+	var document scheduleSettingsDocument
+	document.Snapshots.Schedule = defaultScheduleSettings
+	if err := json.Unmarshal(contents, &document); err != nil {
+		return scheduleSettings{}, fmt.Errorf("failed to unmarshal settings: %w", err)
+	}
+	return document.Snapshots.Schedule, nil
+}
+
+// applyScheduleFlags updates the schedule with the flags given on the command
+// line, returning whether anything was specified.
+func applyScheduleFlags(cmd *cobra.Command, settings *scheduleSettings) (bool, error) {
+	flags := cmd.Flags()
+	changed := false
+	if snapshotScheduleFlags.Enable || snapshotScheduleFlags.Disable {
+		settings.Enabled = snapshotScheduleFlags.Enable
+		changed = true
+	}
+	if flags.Changed("interval") {
+		interval := snapshotScheduleFlags.Interval
+		if interval < time.Hour || interval%time.Hour != 0 {
+			return false, fmt.Errorf("invalid interval %s: must be a whole number of hours", interval)
+		}
+		settings.IntervalInHours = int(interval / time.Hour)
+		changed = true
+	}
+	if flags.Changed("retention") {
+		if snapshotScheduleFlags.Retention < 1 {
+			return false, fmt.Errorf("invalid retention %d: must be at least 1", snapshotScheduleFlags.Retention)
+		}
+		settings.Retention = snapshotScheduleFlags.Retention
+		changed = true
+	}
+	if flags.Changed("skip-if-unchanged") {
+		settings.SkipIfUnchanged = snapshotScheduleFlags.SkipIfUnchanged
+		changed = true
+	}
+	return changed, nil
+}
+
+func scheduleSnapshots(cmd *cobra.Command) error {
+	connectionInfo, err := config.GetConnectionInfo(false)
+	if err != nil {
+		return fmt.Errorf("failed to get connection info: %w", err)
+	}
+	rdClient := client.NewRDClient(connectionInfo)
+	command := client.VersionCommand("", "settings")
+	result, err := client.ProcessRequestForUtility(rdClient.DoRequest("GET", command))
+	if err != nil {
+		return err
+	}
+	settings, err := parseScheduleSettings(result)
+	if err != nil {
+		return err
+	}
+	changed, err := applyScheduleFlags(cmd, &settings)
+	if err != nil {
+		return err
+	}
+	if changed {
+		var document scheduleSettingsDocument
+		document.Version = options.CURRENT_SETTINGS_VERSION
+		document.Snapshots.Schedule = settings
+		jsonBuffer, err := json.Marshal(document)
+		if err != nil {
+			return err
+		}
+		if _, err := client.ProcessRequestForUtility(rdClient.DoRequestWithPayload("PUT", command, bytes.NewBuffer(jsonBuffer))); err != nil {
+			return err
+		}
+	}
+
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	scheduled, err := manager.Scheduled()
+	if err != nil {
+		return err
+	}
+	status := newSnapshotScheduleStatus(settings, scheduled, time.Now())
+	if outputJsonFormat {
+		jsonBuffer, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonBuffer))
+		return nil
+	}
+	printSnapshotScheduleStatus(os.Stdout, status)
+	return nil
+}
+
+// newSnapshotScheduleStatus describes the schedule, given the existing
+// scheduled snapshots (oldest first).
+func newSnapshotScheduleStatus(settings scheduleSettings, scheduled []snapshot.Snapshot, now time.Time) snapshotScheduleStatus {
+	status := snapshotScheduleStatus{scheduleSettings: settings}
+	if len(scheduled) > 0 {
+		status.LastSnapshot = &scheduled[len(scheduled)-1]
+	}
+	if settings.Enabled {
+		next := now
+		if status.LastSnapshot != nil {
+			next = status.LastSnapshot.Created.Add(time.Duration(settings.IntervalInHours) * time.Hour)
+		}
+		if next.Before(now) {
+			next = now
+		}
+		status.NextSnapshot = &next
+	}
+	return status
+}
+
+func printSnapshotScheduleStatus(w io.Writer, status snapshotScheduleStatus) {
+	enabled := "disabled"
+	if status.Enabled {
+		enabled = "enabled"
+	}
+	skip := "no"
+	if status.SkipIfUnchanged {
+		skip = "yes"
+	}
+	fmt.Fprintf(w, "Scheduled snapshots: %s\n", enabled)
+	fmt.Fprintf(w, "Interval:            every %d hours\n", status.IntervalInHours)
+	fmt.Fprintf(w, "Retention:           %d snapshots\n", status.Retention)
+	fmt.Fprintf(w, "Skip if unchanged:   %s\n", skip)
+	if status.LastSnapshot != nil {
+		fmt.Fprintf(w, "Last snapshot:       %s (%s)\n", status.LastSnapshot.Name, status.LastSnapshot.Created.Format(time.RFC1123))
+	} else {
+		fmt.Fprintln(w, "Last snapshot:       none")
+	}
+	if status.NextSnapshot != nil {
+		fmt.Fprintf(w, "Next snapshot:       %s\n", status.NextSnapshot.Format(time.RFC1123))
+	}
+}
+
+func runScheduledSnapshot() error {
+	manager, err := snapshot.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot manager: %w", err)
+	}
+	// The application writes the settings file whenever the settings change,
+	// so this works even if the application is not running.
+	settings := defaultScheduleSettings
+	contents, err := os.ReadFile(filepath.Join(manager.Paths.Config, "settings.json"))
+	if err == nil {
+		if settings, err = parseScheduleSettings(contents); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read settings: %w", err)
+	}
+	manager.ListImages = listContainerImages
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGHUP, syscall.SIGTERM)
+	defer stop()
+	result, err := manager.CreateScheduled(ctx, snapshot.ScheduleOptions{
+		Retention:       settings.Retention,
+		SkipIfUnchanged: settings.SkipIfUnchanged,
+	})
+	if errors.Is(err, runner.ErrContextDone) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to create scheduled snapshot: %w", err)
+	}
+	if !result.Skipped {
+		if err := excludeSnapshotsFromBackups(manager); err != nil {
+			return err
+		}
+	}
+	if outputJsonFormat {
+		jsonBuffer, err := json.Marshal(&result)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonBuffer))
+		return nil
+	}
+	if result.Skipped {
+		fmt.Printf("Nothing changed since snapshot %q; skipped creating a new snapshot.\n", result.Snapshot.Name)
+	} else {
+		fmt.Printf("Created snapshot %q.\n", result.Snapshot.Name)
+	}
+	for _, name := range result.Deleted {
+		fmt.Printf("Deleted old scheduled snapshot %q.\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduleSettings(t *testing.T) {
+	settings, err := parseScheduleSettings([]byte(`{"version": 14, "kubernetes": {"enabled": true}}`))
+	require.NoError(t, err)
+	assert.Equal(t, defaultScheduleSettings, settings)
+
+	settings, err = parseScheduleSettings([]byte(`{"snapshots": {"schedule": {"enabled": true, "retention": 2}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, scheduleSettings{Enabled: true, IntervalInHours: 24, Retention: 2, SkipIfUnchanged: true}, settings)
+
+	_, err = parseScheduleSettings([]byte(`not json`))
+	assert.ErrorContains(t, err, "failed to unmarshal settings")
+}
+
+func TestSnapshotScheduleStatus(t *testing.T) {
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	settings := scheduleSettings{Enabled: true, IntervalInHours: 24, Retention: 5, SkipIfUnchanged: true}
+	scheduled := []snapshot.Snapshot{
+		{Name: "scheduled-2024-05-04-120000", Created: now.Add(-48 * time.Hour)},
+		{Name: "scheduled-2024-05-06-060000", Created: now.Add(-6 * time.Hour)},
+	}
+
+	status := newSnapshotScheduleStatus(settings, scheduled, now)
+	require.NotNil(t, status.LastSnapshot)
+	assert.Equal(t, "scheduled-2024-05-06-060000", status.LastSnapshot.Name)
+	require.NotNil(t, status.NextSnapshot)
+	assert.Equal(t, now.Add(18*time.Hour), *status.NextSnapshot)
+
+	var buf bytes.Buffer
+	printSnapshotScheduleStatus(&buf, status)
+	assert.Equal(t, `Scheduled snapshots: enabled
+Interval:            every 24 hours
+Retention:           5 snapshots
+Skip if unchanged:   yes
+Last snapshot:       scheduled-2024-05-06-060000 (Mon, 06 May 2024 06:00:00 UTC)
+Next snapshot:       Tue, 07 May 2024 06:00:00 UTC
+`, buf.String())
+
+	t.Run("overdue snapshots are due now", func(t *testing.T) {
+		status := newSnapshotScheduleStatus(settings, scheduled[:1], now)
+		require.NotNil(t, status.NextSnapshot)
+		assert.Equal(t, now, *status.NextSnapshot)
+	})
+
+	t.Run("disabled schedules have no next snapshot", func(t *testing.T) {
+		settings := settings
+		settings.Enabled = false
+		status := newSnapshotScheduleStatus(settings, nil, now)
+		assert.Nil(t, status.LastSnapshot)
+		assert.Nil(t, status.NextSnapshot)
+		buf.Reset()
+		printSnapshotScheduleStatus(&buf, status)
+		assert.Contains(t, buf.String(), "Scheduled snapshots: disabled\n")
+		assert.Contains(t, buf.String(), "Last snapshot:       none\n")
+	})
+}
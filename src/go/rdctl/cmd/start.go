@@ -17,11 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"runtime"
 	"strings"
+	"time"
 
 	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/utils"
@@ -35,23 +38,41 @@ var startCmd = &cobra.Command{
 	Short: "Start up Rancher Desktop, or update its settings.",
 	Long: `Starts up Rancher Desktop with the specified settings.
 If it's running, behaves the same as 'rdctl set ...'.
+
+With --wait, the command doesn't return until the given part of Rancher Desktop
+is ready: "vm", "container-engine" (the default) or "kubernetes".  It fails if
+that doesn't happen within --wait-timeout.  As the condition is optional, it
+must be given with an equals sign, e.g. --wait=kubernetes.
 `,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkWaitArgs(cmd, args); err != nil {
+			return err
+		}
 		if err := cobra.NoArgs(cmd, args); err != nil {
 			return err
 		}
+		if cmd.Flags().Changed("wait") {
+			if err := validateWaitCondition(waitCondition); err != nil {
+				return err
+			}
+		}
 		return doStartOrSetCommand(cmd)
 	},
 }
 
 var applicationPath string
 var noModalDialogs bool
+var waitCondition string
+var waitTimeout time.Duration
 
 func init() {
 	rootCmd.AddCommand(startCmd)
 	options.UpdateCommonStartAndSetCommands(startCmd)
 	startCmd.Flags().StringVarP(&applicationPath, "path", "p", "", "path to main executable")
 	startCmd.Flags().BoolVarP(&noModalDialogs, "no-modal-dialogs", "", false, "avoid displaying dialog boxes")
+	startCmd.Flags().StringVar(&waitCondition, "wait", "", fmt.Sprintf("wait until the given part is ready; use --wait=<condition> with one of %s", strings.Join(waitConditions, ", ")))
+	startCmd.Flags().Lookup("wait").NoOptDefVal = waitForContainerEngine
+	startCmd.Flags().DurationVar(&waitTimeout, "wait-timeout", 10*time.Minute, "how long to wait with --wait; 0 waits forever")
 }
 
 /**
@@ -68,10 +89,47 @@ func doStartOrSetCommand(cmd *cobra.Command) error {
 			// `--path | -p` is not a valid option for `rdctl set...`
 			return fmt.Errorf("--path %q specified but Rancher Desktop is already running", applicationPath)
 		}
-		return doSetCommand(cmd)
+		// Allow `rdctl start --wait` without any settings to wait for an
+		// application that is already starting.
+		if !cmd.Flags().Changed("wait") || hasSettingsToChange(cmd) {
+			if err := doSetCommand(cmd); err != nil {
+				return err
+			}
+		}
+		return waitForStart(cmd)
 	}
 	cmd.SilenceUsage = true
-	return doStartCommand(cmd)
+	if err := doStartCommand(cmd); err != nil {
+		return err
+	}
+	return waitForStart(cmd)
+}
+
+// hasSettingsToChange returns whether any settings were given on the command
+// line (or they are invalid, so doSetCommand can report the error).
+func hasSettingsToChange(cmd *cobra.Command) bool {
+	changedSettings, err := options.UpdateFieldsForJSON(cmd.Flags())
+	return err != nil || changedSettings != nil
+}
+
+// waitForStart waits for the condition given by --wait, if any.
+func waitForStart(cmd *cobra.Command) error {
+	if !cmd.Flags().Changed("wait") {
+		return nil
+	}
+	cmd.SilenceUsage = true
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if waitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+	}
+	if err := waitForReadiness(ctx, newReadinessChecker(), waitCondition, waitPollInterval); err != nil {
+		return fmt.Errorf("failed waiting for %s: %w", waitCondition, err)
+	}
+	logrus.Infof("Rancher Desktop is ready (%s).", waitCondition)
+	return nil
 }
 
 func doStartCommand(cmd *cobra.Command) error {
@@ -0,0 +1,168 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// The conditions `rdctl start --wait` can wait for; each one implies the
+// ones before it.
+const (
+	waitForVM              = "vm"
+	waitForContainerEngine = "container-engine"
+	waitForKubernetes      = "kubernetes"
+)
+
+var waitConditions = []string{waitForVM, waitForContainerEngine, waitForKubernetes}
+
+// waitPollInterval is how often the readiness conditions are checked.
+const waitPollInterval = time.Second
+
+// errKubernetesDisabled is returned when waiting for Kubernetes, but it is
+// not enabled.
+var errKubernetesDisabled = errors.New("kubernetes is not enabled")
+
+// readinessChecker checks whether parts of Rancher Desktop are ready.
+type readinessChecker struct {
+	// backendState returns the state of the backend; it fails if the
+	// application isn't running (yet).
+	backendState func() (client.BackendState, error)
+	// runInVM runs the given command as root in the VM, failing if the VM
+	// isn't running or the command fails.
+	runInVM func(args ...string) error
+	// containerEngineCLI returns the command used to talk to the container
+	// engine in the VM.
+	containerEngineCLI func() []string
+}
+
+// newReadinessChecker returns a readinessChecker for the running application.
+func newReadinessChecker() readinessChecker {
+	return readinessChecker{
+		backendState: func() (client.BackendState, error) {
+			connectionInfo, err := config.GetConnectionInfo(true)
+			if err != nil {
+				return client.BackendState{}, err
+			}
+			if connectionInfo == nil {
+				return client.BackendState{}, errors.New("the Rancher Desktop application is not running")
+			}
+			return client.NewRDClient(connectionInfo).GetBackendState()
+		},
+		runInVM: func(args ...string) error {
+			command, err := newRootShellCommandWithOptions(shellOptions{quiet: true}, args...)
+			if err != nil {
+				return err
+			}
+			return command.Run()
+		},
+		containerEngineCLI: containerEngineCLI,
+	}
+}
+
+// check returns whether the condition is met, and if not, what it is waiting
+// for.  An error is returned if the condition can never be met.
+func (c readinessChecker) check(condition string) (bool, string, error) {
+	state, err := c.backendState()
+	if err != nil {
+		return false, "waiting for the application to start", nil
+	}
+	switch state.VMState {
+	case "ERROR":
+		return false, "", errors.New("the backend failed to start; see the application logs for details")
+	case "STOPPED", "STOPPING":
+		return false, fmt.Sprintf("waiting for the backend to start (it is %s)", strings.ToLower(state.VMState)), nil
+	}
+	if err := c.runInVM("true"); err != nil {
+		return false, "waiting for the VM to start", nil
+	}
+	if condition == waitForVM {
+		return true, "", nil
+	}
+	if err := c.runInVM(append(c.containerEngineCLI(), "info")...); err != nil {
+		return false, "waiting for the container engine to start", nil
+	}
+	if condition == waitForContainerEngine {
+		return true, "", nil
+	}
+	switch state.VMState {
+	case "DISABLED":
+		return false, "", errKubernetesDisabled
+	case "STARTED":
+		if err := c.runInVM("k3s", "kubectl", "get", "--raw", "/readyz"); err == nil {
+			return true, "", nil
+		}
+	}
+	return false, "waiting for Kubernetes to start", nil
+}
+
+func validateWaitCondition(condition string) error {
+	if !slices.Contains(waitConditions, condition) {
+		return fmt.Errorf("invalid wait condition %q: must be one of %s", condition, strings.Join(waitConditions, ", "))
+	}
+	return nil
+}
+
+// checkWaitArgs catches `rdctl start --wait kubernetes`: the condition of
+// --wait is optional, so it's only taken from `--wait=kubernetes`, and the
+// separate word ends up as a positional argument instead.
+func checkWaitArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 || !cmd.Flags().Changed("wait") || waitCondition != waitForContainerEngine {
+		return nil
+	}
+	if slices.Contains(waitConditions, args[0]) {
+		return fmt.Errorf("unexpected argument %q: use --wait=%s to wait for it", args[0], args[0])
+	}
+	return nil
+}
+
+// waitForReadiness polls until the condition is met, the context is done, or
+// the condition can never be met.
+func waitForReadiness(ctx context.Context, checker readinessChecker, condition string, interval time.Duration) error {
+	lastReason := ""
+	for {
+		ready, reason, err := checker.check(condition)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		if reason != lastReason {
+			logrus.Infof("%s ...", strings.ToUpper(reason[:1])+reason[1:])
+			lastReason = reason
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("timed out %s", reason)
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReadinessChecker returns a readinessChecker that reports the given
+// backend state, and where only the given commands succeed in the VM.
+func fakeReadinessChecker(state string, working ...string) readinessChecker {
+	return readinessChecker{
+		backendState: func() (client.BackendState, error) {
+			if state == "" {
+				return client.BackendState{}, errors.New("connection refused")
+			}
+			return client.BackendState{VMState: state}, nil
+		},
+		runInVM: func(args ...string) error {
+			if slices.Contains(working, args[0]) {
+				return nil
+			}
+			return errors.New("command failed")
+		},
+		containerEngineCLI: func() []string { return []string{"docker"} },
+	}
+}
+
+func TestReadinessCheck(t *testing.T) {
+	testCases := []struct {
+		name      string
+		state     string
+		working   []string
+		condition string
+		ready     bool
+		reason    string
+		err       error
+	}{
+		{"app not running", "", nil, waitForVM, false, "waiting for the application to start", nil},
+		{"backend stopped", "STOPPED", nil, waitForVM, false, "waiting for the backend to start (it is stopped)", nil},
+		{"vm starting", "STARTING", nil, waitForVM, false, "waiting for the VM to start", nil},
+		{"vm running", "STARTING", []string{"true"}, waitForVM, true, "", nil},
+		{"engine starting", "STARTING", []string{"true"}, waitForContainerEngine, false, "waiting for the container engine to start", nil},
+		{"engine running", "STARTING", []string{"true", "docker"}, waitForContainerEngine, true, "", nil},
+		{"kubernetes starting", "STARTING", []string{"true", "docker", "k3s"}, waitForKubernetes, false, "waiting for Kubernetes to start", nil},
+		{"kubernetes not ready", "STARTED", []string{"true", "docker"}, waitForKubernetes, false, "waiting for Kubernetes to start", nil},
+		{"kubernetes ready", "STARTED", []string{"true", "docker", "k3s"}, waitForKubernetes, true, "", nil},
+		{"kubernetes disabled", "DISABLED", []string{"true", "docker"}, waitForKubernetes, false, "", errKubernetesDisabled},
+		{"engine with kubernetes disabled", "DISABLED", []string{"true", "docker"}, waitForContainerEngine, true, "", nil},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			checker := fakeReadinessChecker(testCase.state, testCase.working...)
+			ready, reason, err := checker.check(testCase.condition)
+			assert.ErrorIs(t, err, testCase.err)
+			assert.Equal(t, testCase.ready, ready)
+			assert.Equal(t, testCase.reason, reason)
+		})
+	}
+
+	t.Run("backend error", func(t *testing.T) {
+		_, _, err := fakeReadinessChecker("ERROR").check(waitForVM)
+		assert.ErrorContains(t, err, "the backend failed to start")
+	})
+}
+
+func TestWaitForReadiness(t *testing.T) {
+	t.Run("becomes ready", func(t *testing.T) {
+		checker := fakeReadinessChecker("STARTING", "true")
+		calls := 0
+		backendState := checker.backendState
+		checker.backendState = func() (client.BackendState, error) {
+			calls++
+			if calls < 3 {
+				return client.BackendState{}, errors.New("connection refused")
+			}
+			return backendState()
+		}
+		err := waitForReadiness(context.Background(), checker, waitForVM, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+	t.Run("times out", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := waitForReadiness(ctx, fakeReadinessChecker("STARTING"), waitForVM, time.Millisecond)
+		assert.EqualError(t, err, "timed out waiting for the VM to start")
+	})
+	t.Run("fails", func(t *testing.T) {
+		err := waitForReadiness(context.Background(), fakeReadinessChecker("DISABLED", "true", "docker"), waitForKubernetes, time.Millisecond)
+		assert.ErrorIs(t, err, errKubernetesDisabled)
+	})
+}
+
+func TestValidateWaitCondition(t *testing.T) {
+	for _, condition := range waitConditions {
+		assert.NoError(t, validateWaitCondition(condition))
+	}
+	assert.ErrorContains(t, validateWaitCondition("docker"), "must be one of vm, container-engine, kubernetes")
+}
+
+func TestCheckWaitArgs(t *testing.T) {
+	t.Cleanup(func() {
+		waitCondition = ""
+		startCmd.Flags().Lookup("wait").Changed = false
+	})
+	require.NoError(t, startCmd.ParseFlags([]string{"--wait", "kubernetes"}))
+	assert.Equal(t, waitForContainerEngine, waitCondition)
+	err := checkWaitArgs(startCmd, startCmd.Flags().Args())
+	assert.EqualError(t, err, `unexpected argument "kubernetes": use --wait=kubernetes to wait for it`)
+	assert.NoError(t, checkWaitArgs(startCmd, nil))
+	assert.NoError(t, checkWaitArgs(startCmd, []string{"other"}))
+
+	require.NoError(t, startCmd.ParseFlags([]string{"--wait=kubernetes"}))
+	assert.Equal(t, waitForKubernetes, waitCondition)
+	assert.NoError(t, checkWaitArgs(startCmd, startCmd.Flags().Args()))
+}
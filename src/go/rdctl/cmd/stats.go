@@ -0,0 +1,359 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var statsSettings struct {
+	Watch    bool
+	Interval time.Duration
+	Top      int
+}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show resource usage of the VM and its containers",
+	Long: `Show the CPU, memory, and disk usage of the Rancher Desktop VM, followed by the
+containers using the most CPU.  For example:
+
+> rdctl stats
+-- Shows the current resource usage
+> rdctl stats --watch --top 5
+-- Keeps showing the resource usage of the VM and the top five containers,
+   until interrupted with Ctrl-C
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := outputFormatOrDefault(output.FormatTable)
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return showStats(ctx, format)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	defineOutputFlag(statsCmd)
+	statsCmd.Flags().BoolVarP(&statsSettings.Watch, "watch", "w", false, "Keep refreshing the statistics until interrupted")
+	statsCmd.Flags().DurationVar(&statsSettings.Interval, "interval", 2*time.Second, "How often to refresh the statistics with --watch")
+	statsCmd.Flags().IntVar(&statsSettings.Top, "top", 10, "Number of containers to show; 0 shows all of them")
+}
+
+// vmStats describes the resource usage of the VM.
+type vmStats struct {
+	CPUPercent  float64 `json:"cpuPercent"`
+	MemoryUsed  uint64  `json:"memoryUsed"`
+	MemoryTotal uint64  `json:"memoryTotal"`
+	DiskUsed    uint64  `json:"diskUsed"`
+	DiskTotal   uint64  `json:"diskTotal"`
+}
+
+// containerStats describes the resource usage of one container, as reported
+// by `docker stats` or `nerdctl stats`.
+type containerStats struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpuPercent"`
+	MemUsage   string  `json:"memoryUsage"`
+	MemPercent float64 `json:"memoryPercent"`
+	NetIO      string  `json:"networkIO"`
+	BlockIO    string  `json:"blockIO"`
+	PIDs       string  `json:"pids"`
+}
+
+// statsReport is everything shown by a single run of the stats command.
+type statsReport struct {
+	VM         vmStats          `json:"vm"`
+	Containers []containerStats `json:"containers"`
+}
+
+type containerStatsTable []containerStats
+
+func (t containerStatsTable) Headers() []string {
+	return []string{"CONTAINER ID", "NAME", "CPU %", "MEM USAGE / LIMIT", "MEM %", "NET I/O", "BLOCK I/O", "PIDS"}
+}
+
+func (t containerStatsTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, stats := range t {
+		rows = append(rows, []string{
+			stats.ID, stats.Name,
+			fmt.Sprintf("%.2f%%", stats.CPUPercent), stats.MemUsage, fmt.Sprintf("%.2f%%", stats.MemPercent),
+			stats.NetIO, stats.BlockIO, stats.PIDs,
+		})
+	}
+	return rows
+}
+
+// statsSectionSeparator separates the output of the commands run in the VM.
+const statsSectionSeparator = "--- rdctl stats ---"
+
+// collectStats runs a script in the VM to gather the statistics; the CPU usage
+// of the VM is sampled over one second.
+func collectStats() (statsReport, error) {
+	script := strings.Join([]string{
+		"head -n 1 /proc/stat", "sleep 1", "head -n 1 /proc/stat",
+		"echo '" + statsSectionSeparator + "'",
+		"cat /proc/meminfo",
+		"echo '" + statsSectionSeparator + "'",
+		"df -Pk /var/lib | tail -n 1",
+		"echo '" + statsSectionSeparator + "'",
+		strings.Join(containerEngineCLI(), " ") + " stats --no-stream --format '{{json .}}' 2>/dev/null || true",
+	}, "; ")
+	command, err := newRootShellCommand("sh", "-c", script)
+	if err != nil {
+		return statsReport{}, err
+	}
+	command.Stderr = os.Stderr
+	rawOutput, err := command.Output()
+	if err != nil {
+		return statsReport{}, fmt.Errorf("failed to collect statistics: %w", err)
+	}
+	return parseStats(string(rawOutput))
+}
+
+// parseStats parses the output of the script run by collectStats.
+func parseStats(rawOutput string) (statsReport, error) {
+	var report statsReport
+	sections := strings.Split(strings.ReplaceAll(rawOutput, "\r\n", "\n"), statsSectionSeparator+"\n")
+	if len(sections) != 4 {
+		return report, fmt.Errorf("unexpected output from the VM: %q", rawOutput)
+	}
+	cpuLines := strings.Split(strings.TrimSpace(sections[0]), "\n")
+	if len(cpuLines) != 2 {
+		return report, fmt.Errorf("unexpected CPU statistics: %q", sections[0])
+	}
+	idle0, total0, err := parseCPUSample(cpuLines[0])
+	if err != nil {
+		return report, err
+	}
+	idle1, total1, err := parseCPUSample(cpuLines[1])
+	if err != nil {
+		return report, err
+	}
+	if total1 > total0 {
+		report.VM.CPUPercent = 100 * (1 - float64(idle1-idle0)/float64(total1-total0))
+	}
+	if report.VM.MemoryUsed, report.VM.MemoryTotal, err = parseMeminfo(sections[1]); err != nil {
+		return report, err
+	}
+	if report.VM.DiskUsed, report.VM.DiskTotal, err = parseDiskFree(sections[2]); err != nil {
+		return report, err
+	}
+	if report.Containers, err = parseContainerStats(sections[3]); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// parseCPUSample parses the aggregate "cpu" line of /proc/stat, returning the
+// idle (including I/O wait) and total jiffies.
+func parseCPUSample(line string) (uint64, uint64, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected CPU statistics: %q", line)
+	}
+	var idle, total uint64
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unexpected CPU statistics: %q", line)
+		}
+		total += value
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+	return idle, total, nil
+}
+
+// parseMeminfo returns the used and total memory in bytes from the contents
+// of /proc/meminfo.
+func parseMeminfo(contents string) (uint64, uint64, error) {
+	values := map[string]uint64{}
+	for _, line := range strings.Split(contents, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		kilobytes, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+		if err == nil {
+			values[key] = kilobytes * 1024
+		}
+	}
+	total, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, errors.New("failed to find memory statistics")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return total - min(available, total), total, nil
+}
+
+// parseDiskFree returns the used and total disk space in bytes from a line of
+// `df -Pk` output.
+func parseDiskFree(line string) (uint64, uint64, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return 0, 0, fmt.Errorf("unexpected disk statistics: %q", line)
+	}
+	total, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected disk statistics: %q", line)
+	}
+	used, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unexpected disk statistics: %q", line)
+	}
+	return used * 1024, total * 1024, nil
+}
+
+// parseContainerStats parses the JSON lines output of `docker stats`, sorted
+// by descending CPU usage.
+func parseContainerStats(contents string) ([]containerStats, error) {
+	result := []containerStats{}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var raw struct {
+			ID       string `json:"ID"`
+			Name     string `json:"Name"`
+			CPUPerc  string `json:"CPUPerc"`
+			MemUsage string `json:"MemUsage"`
+			MemPerc  string `json:"MemPerc"`
+			NetIO    string `json:"NetIO"`
+			BlockIO  string `json:"BlockIO"`
+			PIDs     string `json:"PIDs"`
+		}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse container statistics %q: %w", line, err)
+		}
+		result = append(result, containerStats{
+			ID:         raw.ID,
+			Name:       raw.Name,
+			CPUPercent: parsePercent(raw.CPUPerc),
+			MemUsage:   raw.MemUsage,
+			MemPercent: parsePercent(raw.MemPerc),
+			NetIO:      raw.NetIO,
+			BlockIO:    raw.BlockIO,
+			PIDs:       raw.PIDs,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CPUPercent > result[j].CPUPercent })
+	return result, nil
+}
+
+func parsePercent(value string) float64 {
+	result, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	return result
+}
+
+// formatBytes formats a size using binary units, as `docker stats` does.
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value := float64(size)
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB"}
+	suffix := ""
+	for _, suffix = range suffixes {
+		value /= unit
+		if value < unit {
+			break
+		}
+	}
+	return fmt.Sprintf("%.2f%s", value, suffix)
+}
+
+// printStatsTable writes the statistics in human-readable form.
+func printStatsTable(w io.Writer, report statsReport) error {
+	percent := func(used, total uint64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return 100 * float64(used) / float64(total)
+	}
+	fmt.Fprintf(w, "VM CPU:    %.2f%%\n", report.VM.CPUPercent)
+	fmt.Fprintf(w, "VM memory: %s / %s (%.2f%%)\n", formatBytes(report.VM.MemoryUsed), formatBytes(report.VM.MemoryTotal),
+		percent(report.VM.MemoryUsed, report.VM.MemoryTotal))
+	fmt.Fprintf(w, "VM disk:   %s / %s (%.2f%%)\n\n", formatBytes(report.VM.DiskUsed), formatBytes(report.VM.DiskTotal),
+		percent(report.VM.DiskUsed, report.VM.DiskTotal))
+	if len(report.Containers) == 0 {
+		fmt.Fprintln(w, "No containers are running.")
+		return nil
+	}
+	return output.Render(w, output.FormatTable, containerStatsTable(report.Containers))
+}
+
+func showStats(ctx context.Context, format output.Format) error {
+	for {
+		report, err := collectStats()
+		if err != nil {
+			if errors.Is(err, errVMNotRunning) {
+				// The reason has already been reported.
+				os.Exit(1)
+			}
+			return err
+		}
+		if statsSettings.Top > 0 && len(report.Containers) > statsSettings.Top {
+			report.Containers = report.Containers[:statsSettings.Top]
+		}
+		if statsSettings.Watch && format == output.FormatTable {
+			// Clear the screen before each refresh.
+			fmt.Print("\033[H\033[2J")
+		}
+		if format == output.FormatTable {
+			err = printStatsTable(os.Stdout, report)
+		} else {
+			err = renderOutput(format, report)
+		}
+		if err != nil || !statsSettings.Watch {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(statsSettings.Interval):
+		}
+	}
+}
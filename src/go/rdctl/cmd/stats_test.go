@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStats(t *testing.T) {
+	rawOutput := `cpu  100 0 100 700 100 0 0 0 0 0
+cpu  150 0 150 850 150 0 0 0 0 0
+--- rdctl stats ---
+MemTotal:        4096000 kB
+MemFree:          100000 kB
+MemAvailable:    3072000 kB
+--- rdctl stats ---
+/dev/vdb1          103081248  20616250  77221616  22% /var/lib
+--- rdctl stats ---
+{"BlockIO":"0B / 0B","CPUPerc":"0.50%","Container":"abc","ID":"abc","MemPerc":"1.00%","MemUsage":"10MiB / 1GiB","Name":"idle","NetIO":"1kB / 0B","PIDs":"2"}
+{"BlockIO":"1MB / 0B","CPUPerc":"75.25%","Container":"def","ID":"def","MemPerc":"20.00%","MemUsage":"200MiB / 1GiB","Name":"busy","NetIO":"5kB / 1kB","PIDs":"12"}
+`
+	report, err := parseStats(rawOutput)
+	require.NoError(t, err)
+	assert.InDelta(t, 33.33, report.VM.CPUPercent, 0.01)
+	assert.Equal(t, uint64(4096000*1024), report.VM.MemoryTotal)
+	assert.Equal(t, uint64(1024000*1024), report.VM.MemoryUsed)
+	assert.Equal(t, uint64(103081248*1024), report.VM.DiskTotal)
+	assert.Equal(t, uint64(20616250*1024), report.VM.DiskUsed)
+	require.Len(t, report.Containers, 2)
+	assert.Equal(t, "busy", report.Containers[0].Name)
+	assert.InDelta(t, 75.25, report.Containers[0].CPUPercent, 0.001)
+	assert.Equal(t, "idle", report.Containers[1].Name)
+
+	var buf bytes.Buffer
+	require.NoError(t, printStatsTable(&buf, report))
+	assert.Contains(t, buf.String(), "VM CPU:    33.33%\n")
+	assert.Contains(t, buf.String(), "VM memory: 1000.00MiB / 3.91GiB (25.00%)\n")
+	assert.Contains(t, buf.String(), "def             busy    75.25%")
+}
+
+func TestParseStatsNoContainers(t *testing.T) {
+	report, err := parseStats("cpu 1 1 1 1 1\ncpu 1 1 1 1 1\n--- rdctl stats ---\nMemTotal: 1024 kB\nMemFree: 512 kB\n" +
+		"--- rdctl stats ---\n/dev/root 10 5 5 50% /\n--- rdctl stats ---\n")
+	require.NoError(t, err)
+	assert.Zero(t, report.VM.CPUPercent)
+	assert.Equal(t, uint64(512*1024), report.VM.MemoryUsed)
+	assert.Empty(t, report.Containers)
+
+	_, err = parseStats("garbage")
+	assert.ErrorContains(t, err, "unexpected output")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512B", formatBytes(512))
+	assert.Equal(t, "1.50KiB", formatBytes(1536))
+	assert.Equal(t, "2.00GiB", formatBytes(2<<30))
+}
@@ -18,22 +18,63 @@ package cmd
 
 import (
 	"fmt"
+	"runtime"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/output"
 	"github.com/spf13/cobra"
 )
 
+// versionInfo is the output of the version command.
+type versionInfo struct {
+	Client     string `json:"client"`
+	APIVersion string `json:"apiVersion"`
+}
+
+func (v versionInfo) Headers() []string {
+	return []string{"CLIENT", "API VERSION"}
+}
+
+func (v versionInfo) Rows() [][]string {
+	return [][]string{{v.Client, v.APIVersion}}
+}
+
 // showVersionCmd represents the showVersion command
 var showVersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Shows the CLI version.",
-	Long:  `Shows the CLI version.`,
+	Long: `Shows the CLI version.
+
+With --all, the versions of all bundled components (such as k3s, the container
+engines, buildkit, nerdctl, helm and kubectl) are listed as well.  Components in
+the VM can only be queried while Rancher Desktop is running; any component whose
+version can't be determined is reported as unavailable, with the reason.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := fmt.Printf("rdctl client version: %s, targeting server version: %s\n", client.Version, client.ApiVersion)
-		return err
+		if showAllVersions {
+			format, err := outputFormatOrDefault(output.FormatTable)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return renderOutput(format, collectComponentVersions(cmd.Context(), runtime.GOOS, runComponentCommand))
+		}
+		if outputFormat == "" {
+			_, err := fmt.Printf("rdctl client version: %s, targeting server version: %s\n", client.Version, client.ApiVersion)
+			return err
+		}
+		format, err := outputFormatOrDefault("")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		return renderOutput(format, versionInfo{Client: client.Version, APIVersion: client.ApiVersion})
 	},
 }
 
+var showAllVersions bool
+
 func init() {
 	rootCmd.AddCommand(showVersionCmd)
+	defineOutputFlag(showVersionCmd)
+	showVersionCmd.Flags().BoolVar(&showAllVersions, "all", false, "Also show the versions of all bundled components")
 }
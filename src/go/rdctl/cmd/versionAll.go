@@ -0,0 +1,217 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+)
+
+// Where a component runs.
+const (
+	componentOnHost = "host"
+	componentInVM   = "vm"
+)
+
+// componentProbeTimeout limits how long we wait for a single component to
+// report its version.
+const componentProbeTimeout = 30 * time.Second
+
+// componentVersion is the version of one bundled component, as reported by
+// `rdctl version --all`.
+type componentVersion struct {
+	Component string `json:"component"`
+	Version   string `json:"version,omitempty"`
+	Location  string `json:"location"`
+	// Error describes why the version could not be determined.
+	Error string `json:"error,omitempty"`
+}
+
+type componentVersionTable []componentVersion
+
+func (t componentVersionTable) Headers() []string {
+	return []string{"COMPONENT", "VERSION", "LOCATION"}
+}
+
+func (t componentVersionTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, c := range t {
+		version := c.Version
+		if c.Error != "" {
+			version = fmt.Sprintf("unavailable (%s)", c.Error)
+		}
+		rows = append(rows, []string{c.Component, version, c.Location})
+	}
+	return rows
+}
+
+// componentProbe describes how to find the version of a component: run the
+// command, and take the given whitespace-separated field of the first line of
+// its output.
+type componentProbe struct {
+	component string
+	location  string
+	command   []string
+	field     int
+}
+
+// componentProbes lists the bundled components that can report their own
+// versions, in the order they are displayed.
+var componentProbes = []componentProbe{
+	// "Client Version: v1.30.2"
+	{component: "kubectl", location: componentOnHost, command: []string{"kubectl", "version", "--client"}, field: 2},
+	// "v3.15.2+g1a500d5"
+	{component: "helm", location: componentOnHost, command: []string{"helm", "version", "--short"}, field: 0},
+	// "Docker version 27.0.3, build 7d4bcd8"
+	{component: "docker", location: componentOnHost, command: []string{"docker", "--version"}, field: 2},
+	// "k3s version v1.30.2+k3s2 (faaf2f6b)"
+	{component: "k3s", location: componentInVM, command: []string{"k3s", "--version"}, field: 2},
+	// "containerd github.com/containerd/containerd v1.7.17 3a4de459a68952ffb703bbe7f2290861a75b6b67"
+	{component: "containerd", location: componentInVM, command: []string{"containerd", "--version"}, field: 2},
+	// "Docker version 26.1.4, build de5c9cf0b96e4e172b5a6d7a1b32bb12cb00492b"
+	{component: "moby", location: componentInVM, command: []string{"dockerd", "--version"}, field: 2},
+	// "buildkitd github.com/moby/buildkit v0.14.1 ad7c5fc24d29ba3bcd2aa9e4ecc4ab5bd5a0c8b6"
+	{component: "buildkit", location: componentInVM, command: []string{"buildkitd", "--version"}, field: 2},
+	// "nerdctl version 1.7.6"
+	{component: "nerdctl", location: componentInVM, command: []string{"nerdctl", "--version"}, field: 2},
+}
+
+// componentRunner runs a command on the host or in the VM, returning its
+// standard output.
+type componentRunner func(ctx context.Context, location string, command ...string) ([]byte, error)
+
+// runComponentCommand runs host commands from the directory rdctl was
+// installed in (so the bundled tools are used rather than whatever is on the
+// PATH), and VM commands as root in the VM.
+func runComponentCommand(ctx context.Context, location string, command ...string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if location == componentInVM {
+		var err error
+		cmd, err = newRootShellCommandWithOptions(shellOptions{quiet: true}, command...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		executable, err := bundledExecutable(command[0])
+		if err != nil {
+			return nil, err
+		}
+		cmd = exec.CommandContext(ctx, executable, command[1:]...)
+	}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := runWithContext(ctx, cmd); err != nil {
+		if message := firstLine(stderr.String()); message != "" && ctx.Err() == nil {
+			return nil, errors.New(message)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// runWithContext runs the command, killing it if the context is done first;
+// the shell commands for the VM aren't created with a context.
+func runWithContext(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
+
+// bundledExecutable returns the path of the named tool shipped next to rdctl.
+func bundledExecutable(name string) (string, error) {
+	rdctlPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get path to rdctl: %w", err)
+	}
+	rdctlPath, err = filepath.EvalSymlinks(rdctlPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", rdctlPath, err)
+	}
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	executable := filepath.Join(filepath.Dir(rdctlPath), name)
+	if _, err := os.Stat(executable); err != nil {
+		return "", fmt.Errorf("%s is not installed", name)
+	}
+	return executable, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// parseComponentVersion extracts the version from the output of a probe.
+func parseComponentVersion(probe componentProbe, output []byte) (string, error) {
+	fields := strings.Fields(firstLine(string(output)))
+	if probe.field >= len(fields) {
+		return "", fmt.Errorf("unexpected output %q", firstLine(string(output)))
+	}
+	return strings.TrimSuffix(fields[probe.field], ","), nil
+}
+
+// collectComponentVersions returns the versions of all bundled components.
+// Components that can't be queried (e.g. because the VM isn't running) are
+// listed with the reason instead of a version.
+func collectComponentVersions(ctx context.Context, goos string, run componentRunner) componentVersionTable {
+	results := componentVersionTable{
+		{Component: "rdctl", Version: client.Version, Location: componentOnHost},
+	}
+	for _, probe := range componentProbes {
+		result := componentVersion{Component: probe.component, Location: probe.location}
+		probeCtx, cancel := context.WithTimeout(ctx, componentProbeTimeout)
+		output, err := run(probeCtx, probe.location, probe.command...)
+		cancel()
+		if err == nil {
+			result.Version, err = parseComponentVersion(probe, output)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	// The guest agent and wsl-helper don't report their own versions; they
+	// are built from the same source tree as rdctl.
+	results = append(results, componentVersion{Component: "guestagent", Version: client.Version, Location: componentInVM})
+	if goos == "windows" {
+		results = append(results, componentVersion{Component: "wsl-helper", Version: client.Version, Location: componentInVM})
+	}
+	return results
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComponentVersion(t *testing.T) {
+	outputs := map[string]string{
+		"kubectl":    "Client Version: v1.30.2\nKustomize Version: v5.0.4-0.20230601165947-6ce0bf390ce3\n",
+		"helm":       "v3.15.2+g1a500d5\n",
+		"docker":     "Docker version 27.0.3, build 7d4bcd8\n",
+		"k3s":        "k3s version v1.30.2+k3s2 (faaf2f6b)\ngo version go1.22.4\n",
+		"containerd": "containerd github.com/containerd/containerd v1.7.17 3a4de459a68952ffb703bbe7f2290861a75b6b67\n",
+		"moby":       "Docker version 26.1.4, build de5c9cf0b96e4e172b5a6d7a1b32bb12cb00492b\n",
+		"buildkit":   "buildkitd github.com/moby/buildkit v0.14.1 ad7c5fc24d29ba3bcd2aa9e4ecc4ab5bd5a0c8b6\n",
+		"nerdctl":    "nerdctl version 1.7.6\n",
+	}
+	expected := map[string]string{
+		"kubectl":    "v1.30.2",
+		"helm":       "v3.15.2+g1a500d5",
+		"docker":     "27.0.3",
+		"k3s":        "v1.30.2+k3s2",
+		"containerd": "v1.7.17",
+		"moby":       "26.1.4",
+		"buildkit":   "v0.14.1",
+		"nerdctl":    "1.7.6",
+	}
+	for _, probe := range componentProbes {
+		t.Run(probe.component, func(t *testing.T) {
+			version, err := parseComponentVersion(probe, []byte(outputs[probe.component]))
+			require.NoError(t, err)
+			assert.Equal(t, expected[probe.component], version)
+		})
+	}
+	t.Run("unexpected output", func(t *testing.T) {
+		_, err := parseComponentVersion(componentProbe{field: 2}, []byte("\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestCollectComponentVersions(t *testing.T) {
+	run := func(ctx context.Context, location string, command ...string) ([]byte, error) {
+		switch command[0] {
+		case "helm":
+			return []byte("v3.15.2+g1a500d5\n"), nil
+		case "k3s":
+			return nil, errors.New("k3s: not found")
+		}
+		return []byte("tool version 1.0\n"), nil
+	}
+	for _, goos := range []string{"darwin", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			results := collectComponentVersions(context.Background(), goos, run)
+			byName := map[string]componentVersion{}
+			for _, result := range results {
+				byName[result.Component] = result
+			}
+			assert.Equal(t, componentVersion{Component: "rdctl", Version: client.Version, Location: componentOnHost}, results[0])
+			assert.Equal(t, "v3.15.2+g1a500d5", byName["helm"].Version)
+			assert.Equal(t, "1.0", byName["nerdctl"].Version)
+			assert.Equal(t, componentVersion{Component: "k3s", Location: componentInVM, Error: "k3s: not found"}, byName["k3s"])
+			assert.Equal(t, client.Version, byName["guestagent"].Version)
+			_, hasWSLHelper := byName["wsl-helper"]
+			assert.Equal(t, goos == "windows", hasWSLHelper)
+		})
+	}
+	t.Run("table", func(t *testing.T) {
+		table := componentVersionTable{
+			{Component: "helm", Version: "v3.15.2", Location: componentOnHost},
+			{Component: "k3s", Location: componentInVM, Error: "the VM is not running"},
+		}
+		assert.Equal(t, [][]string{
+			{"helm", "v3.15.2", "host"},
+			{"k3s", "unavailable (the VM is not running)", "vm"},
+		}, table.Rows())
+	})
+}
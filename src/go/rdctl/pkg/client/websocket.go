@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+// DialWebSocket opens a WebSocket connection to the given API endpoint.
+func (client *RDClientImpl) DialWebSocket(command string) (*websocket.Conn, error) {
+	url := client.makeURL(client.connectionInfo.Host, client.connectionInfo.Port, command)
+	origin := client.makeURL(client.connectionInfo.Host, client.connectionInfo.Port, "/")
+	wsConfig, err := websocket.NewConfig("ws"+url[len("http"):], origin)
+	if err != nil {
+		return nil, err
+	}
+	// Reuse http.Request to compute the basic authentication header.
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(client.connectionInfo.User, client.connectionInfo.Password)
+	wsConfig.Header = req.Header
+	conn, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", command, err)
+	}
+	return conn, nil
+}
@@ -73,6 +73,7 @@ func DefineGlobalFlags(rootCmd *cobra.Command) {
 	rootCmd.PersistentFlags().StringVar(&connectionSettings.Host, "host", "", "default is 127.0.0.1; most useful for WSL")
 	rootCmd.PersistentFlags().IntVar(&connectionSettings.Port, "port", 0, "overrides the port setting in the config file")
 	rootCmd.PersistentFlags().StringVar(&connectionSettings.Password, "password", "", "overrides the password setting in the config file")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "the rdctl context to use (default is the current context)")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Be verbose")
 }
 
@@ -83,21 +84,41 @@ func DefineGlobalFlags(rootCmd *cobra.Command) {
 func GetConnectionInfo(mayBeMissing bool) (*ConnectionInfo, error) {
 	var settings ConnectionInfo
 
-	if configPath == "" {
-		configPath = DefaultConfigPath
+	current, err := currentContext()
+	if err != nil {
+		return nil, err
+	}
+	path := configPath
+	if path == "" {
+		path = current.ConfigPath
 	}
-	content, readFileError := os.ReadFile(configPath)
+	if path == "" {
+		path = DefaultConfigPath
+	}
+	content, readFileError := os.ReadFile(path)
 	if readFileError != nil {
-		// It is ok if the default config path doesn't exist; the user may have specified the required settings on the commandline.
+		// It is ok if the context's config path doesn't exist; the user may have specified the required settings on the commandline.
 		// But it is an error if the file specified via --config-path can not be read.
-		if configPath != DefaultConfigPath || !errors.Is(readFileError, os.ErrNotExist) {
+		if (configPath != "" && configPath != DefaultConfigPath) || !errors.Is(readFileError, os.ErrNotExist) {
 			return nil, readFileError
 		}
 	} else if err := json.Unmarshal(content, &settings); err != nil {
-		return nil, fmt.Errorf("error parsing config file %q: %w", configPath, err)
+		return nil, fmt.Errorf("error parsing config file %q: %w", path, err)
 	}
 
-	// CLI options override file settings
+	// Context settings override file settings, and CLI options override both
+	if current.Host != "" {
+		settings.Host = current.Host
+	}
+	if current.User != "" {
+		settings.User = current.User
+	}
+	if current.Password != "" {
+		settings.Password = current.Password
+	}
+	if current.Port != 0 {
+		settings.Port = current.Port
+	}
 	if connectionSettings.Host != "" {
 		settings.Host = connectionSettings.Host
 	}
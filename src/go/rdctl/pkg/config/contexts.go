@@ -0,0 +1,148 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultContextName is the name of the context that targets the local
+// Rancher Desktop installation; it always exists, and can't be changed.
+const DefaultContextName = "default"
+
+// Context describes how to connect to one Rancher Desktop instance.  Any
+// settings not given here are read from the instance's config file, and the
+// command-line flags override both.
+type Context struct {
+	// ConfigPath is the path to the rd-engine.json file of the instance; it
+	// defaults to the one of the local installation.
+	ConfigPath string `json:"configPath,omitempty"`
+	Host       string `json:"host,omitempty"`
+	Port       int    `json:"port,omitempty"`
+	User       string `json:"user,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+// Contexts is the content of the contexts file.
+type Contexts struct {
+	// Current is the name of the context used when --context isn't given.
+	Current  string             `json:"current,omitempty"`
+	Contexts map[string]Context `json:"contexts"`
+}
+
+// contextName is the value of the --context flag.
+var contextName string
+
+// ContextsPath returns the path of the file storing the rdctl contexts.
+func ContextsPath() string {
+	return filepath.Join(filepath.Dir(DefaultConfigPath), "rdctl-contexts.json")
+}
+
+// LoadContexts reads the contexts file; it is not an error if it doesn't
+// exist yet.
+func LoadContexts() (*Contexts, error) {
+	contexts := &Contexts{Contexts: map[string]Context{}}
+	content, err := os.ReadFile(ContextsPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return contexts, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(content, contexts); err != nil {
+		return nil, fmt.Errorf("error parsing contexts file %q: %w", ContextsPath(), err)
+	}
+	if contexts.Contexts == nil {
+		contexts.Contexts = map[string]Context{}
+	}
+	return contexts, nil
+}
+
+// Save writes the contexts file.  It is only readable by the user, as it may
+// contain passwords.
+func (c *Contexts) Save() error {
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ContextsPath()), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(ContextsPath(), append(content, '\n'), 0o600)
+}
+
+// Names returns the names of all contexts, including the default one, sorted.
+func (c *Contexts) Names() []string {
+	names := []string{DefaultContextName}
+	for name := range c.Contexts {
+		if name != DefaultContextName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// CurrentName returns the name of the context in use: the one given with
+// --context, else the one selected with `rdctl context use`.
+func (c *Contexts) CurrentName() string {
+	if contextName != "" {
+		return contextName
+	}
+	if c.Current != "" {
+		return c.Current
+	}
+	return DefaultContextName
+}
+
+// Get returns the named context.
+func (c *Contexts) Get(name string) (Context, error) {
+	if name == DefaultContextName {
+		return Context{}, nil
+	}
+	result, ok := c.Contexts[name]
+	if !ok {
+		return Context{}, fmt.Errorf("context %q does not exist", name)
+	}
+	return result, nil
+}
+
+// ContextFromFlags returns a context with the connection settings given on
+// the command line (--config-path, --host, --port, --user and --password).
+func ContextFromFlags() Context {
+	return Context{
+		ConfigPath: configPath,
+		Host:       connectionSettings.Host,
+		Port:       connectionSettings.Port,
+		User:       connectionSettings.User,
+		Password:   connectionSettings.Password,
+	}
+}
+
+// currentContext returns the context in use.
+func currentContext() (Context, error) {
+	contexts, err := LoadContexts()
+	if err != nil {
+		return Context{}, err
+	}
+	return contexts.Get(contexts.CurrentName())
+}
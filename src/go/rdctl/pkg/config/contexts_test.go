@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupContexts points the default config path at a temporary directory, and
+// writes the given contexts there.
+func setupContexts(t *testing.T, contexts *Contexts) string {
+	dir := t.TempDir()
+	savedDefault, savedName, savedPath, savedSettings := DefaultConfigPath, contextName, configPath, connectionSettings
+	t.Cleanup(func() {
+		DefaultConfigPath, contextName, configPath, connectionSettings = savedDefault, savedName, savedPath, savedSettings
+	})
+	DefaultConfigPath = filepath.Join(dir, "rd-engine.json")
+	contextName, configPath, connectionSettings = "", "", ConnectionInfo{}
+	if contexts != nil {
+		require.NoError(t, contexts.Save())
+	}
+	return dir
+}
+
+func writeConfig(t *testing.T, path string, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestGetConnectionInfoWithContexts(t *testing.T) {
+	t.Run("default context", func(t *testing.T) {
+		setupContexts(t, nil)
+		writeConfig(t, DefaultConfigPath, `{"user":"u","password":"p","port":1234}`)
+		info, err := GetConnectionInfo(false)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionInfo{User: "u", Password: "p", Host: "127.0.0.1", Port: 1234}, *info)
+	})
+	t.Run("context config path", func(t *testing.T) {
+		dir := t.TempDir()
+		otherConfig := filepath.Join(dir, "rd-engine.json")
+		setupContexts(t, &Contexts{
+			Current:  "test",
+			Contexts: map[string]Context{"test": {ConfigPath: otherConfig}},
+		})
+		writeConfig(t, DefaultConfigPath, `{"user":"u","password":"p","port":1234}`)
+		writeConfig(t, otherConfig, `{"user":"other","password":"secret","port":5678}`)
+		info, err := GetConnectionInfo(false)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionInfo{User: "other", Password: "secret", Host: "127.0.0.1", Port: 5678}, *info)
+	})
+	t.Run("remote context", func(t *testing.T) {
+		setupContexts(t, &Contexts{
+			Contexts: map[string]Context{"remote": {Host: "10.0.0.2", Port: 6107, User: "r", Password: "rp"}},
+		})
+		contextName = "remote"
+		info, err := GetConnectionInfo(false)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionInfo{User: "r", Password: "rp", Host: "10.0.0.2", Port: 6107}, *info)
+	})
+	t.Run("flags override context", func(t *testing.T) {
+		setupContexts(t, &Contexts{
+			Current:  "remote",
+			Contexts: map[string]Context{"remote": {Host: "10.0.0.2", Port: 6107, User: "r", Password: "rp"}},
+		})
+		connectionSettings.Port = 9999
+		info, err := GetConnectionInfo(false)
+		require.NoError(t, err)
+		assert.Equal(t, ConnectionInfo{User: "r", Password: "rp", Host: "10.0.0.2", Port: 9999}, *info)
+	})
+	t.Run("missing context config is not running", func(t *testing.T) {
+		setupContexts(t, &Contexts{
+			Current:  "test",
+			Contexts: map[string]Context{"test": {ConfigPath: filepath.Join(t.TempDir(), "rd-engine.json")}},
+		})
+		info, err := GetConnectionInfo(true)
+		assert.NoError(t, err)
+		assert.Nil(t, info)
+	})
+	t.Run("unknown context", func(t *testing.T) {
+		setupContexts(t, nil)
+		contextName = "missing"
+		_, err := GetConnectionInfo(true)
+		assert.EqualError(t, err, `context "missing" does not exist`)
+	})
+}
+
+func TestContextNames(t *testing.T) {
+	contexts := &Contexts{Contexts: map[string]Context{"b": {}, "a": {}}}
+	assert.Equal(t, []string{DefaultContextName, "a", "b"}, contexts.Names())
+}
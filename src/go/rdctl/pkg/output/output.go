@@ -0,0 +1,135 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package output renders the results of rdctl commands as JSON, YAML, or a
+// table, so that every command can be used from scripts.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatTable Format = "table"
+)
+
+// Formats lists the names of the supported output formats.
+var Formats = []string{string(FormatJSON), string(FormatYAML), string(FormatTable)}
+
+// ErrTableUnsupported is returned when rendering a value that can't be
+// displayed as a table.
+var ErrTableUnsupported = errors.New("table output is not supported for this command")
+
+// Table is implemented by values that can be displayed as a table.
+type Table interface {
+	// Headers returns the column headings.
+	Headers() []string
+	// Rows returns the cells of each row, in the same order as the headings.
+	Rows() [][]string
+}
+
+// ParseFormat checks the given output format name; an empty name selects the
+// given default.
+func ParseFormat(name string, defaultFormat Format) (Format, error) {
+	if name == "" {
+		return defaultFormat, nil
+	}
+	for _, candidate := range Formats {
+		if strings.EqualFold(name, candidate) {
+			return Format(candidate), nil
+		}
+	}
+	return "", fmt.Errorf("invalid output format %q (allowed values: [%s])", name, strings.Join(Formats, ", "))
+}
+
+// Render writes the value to the writer in the given format.  Values are
+// converted to YAML using their JSON field names, so that both formats have
+// the same structure.  For table output, the value must implement Table.
+func Render(w io.Writer, format Format, value any) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatYAML:
+		return renderYAML(w, value)
+	case FormatTable:
+		table, ok := value.(Table)
+		if !ok {
+			return ErrTableUnsupported
+		}
+		return renderTable(w, table)
+	}
+	return fmt.Errorf("invalid output format %q", format)
+}
+
+// renderYAML writes the value as YAML.  JSON is valid YAML, so the JSON
+// encoding is parsed as YAML to keep the field names and their order; the
+// JSON flow style and quoting are then reset to the usual block style.
+func renderYAML(w io.Writer, value any) error {
+	var jsonBuffer []byte
+	var err error
+	if raw, ok := value.(json.RawMessage); ok {
+		jsonBuffer = raw
+	} else if jsonBuffer, err = json.Marshal(value); err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(jsonBuffer, &node); err != nil {
+		return fmt.Errorf("failed to convert output to YAML: %w", err)
+	}
+	resetYAMLStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+func renderTable(w io.Writer, table Table) error {
+	writer := tabwriter.NewWriter(w, 0, 4, 4, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(table.Headers(), "\t"))
+	for _, row := range table.Rows() {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
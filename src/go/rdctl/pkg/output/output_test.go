@@ -0,0 +1,83 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testValue struct {
+	Name    string   `json:"name"`
+	Count   int      `json:"count"`
+	Tags    []string `json:"tags,omitempty"`
+	Skipped string   `json:"-"`
+}
+
+type testTable []testValue
+
+func (t testTable) Headers() []string {
+	return []string{"NAME", "COUNT"}
+}
+
+func (t testTable) Rows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, v := range t {
+		rows = append(rows, []string{v.Name, strconv.Itoa(v.Count)})
+	}
+	return rows
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("", FormatTable)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatTable, format)
+	format, err = ParseFormat("YAML", FormatTable)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatYAML, format)
+	_, err = ParseFormat("xml", FormatTable)
+	assert.ErrorContains(t, err, `invalid output format "xml"`)
+}
+
+func TestRender(t *testing.T) {
+	values := testTable{
+		{Name: "first", Count: 1, Tags: []string{"a", "b"}, Skipped: "x"},
+		{Name: "second-value", Count: 2},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, FormatJSON, values))
+		assert.JSONEq(t, `[{"name":"first","count":1,"tags":["a","b"]},{"name":"second-value","count":2}]`, buf.String())
+	})
+	t.Run("yaml", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, FormatYAML, values))
+		assert.Equal(t, `- name: first
+  count: 1
+  tags:
+    - a
+    - b
+- name: second-value
+  count: 2
+`, buf.String())
+	})
+	t.Run("yaml from raw JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		raw := json.RawMessage(`{"version": 14, "kubernetes": {"enabled": true, "version": "1.30"}, "name": "true"}`)
+		require.NoError(t, Render(&buf, FormatYAML, raw))
+		// Strings that look like other types must stay quoted.
+		assert.Equal(t, "version: 14\nkubernetes:\n  enabled: true\n  version: \"1.30\"\nname: \"true\"\n", buf.String())
+	})
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Render(&buf, FormatTable, values))
+		assert.Equal(t, "NAME            COUNT\nfirst           1\nsecond-value    2\n", buf.String())
+	})
+	t.Run("table unsupported", func(t *testing.T) {
+		assert.ErrorIs(t, Render(&bytes.Buffer{}, FormatTable, []testValue(values)), ErrTableUnsupported)
+	})
+}
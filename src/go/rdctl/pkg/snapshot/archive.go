@@ -0,0 +1,345 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	options "github.com/rancher-sandbox/rancher-desktop/src/go/rdctl/pkg/options/generated"
+)
+
+// archiveFormatVersion is the version of the snapshot archive layout; it must
+// be incremented whenever older versions of rdctl can't import new archives.
+const archiveFormatVersion = 1
+
+const (
+	// The first entry in an archive, describing its contents.
+	archiveManifestName = "manifest.json"
+	// The last entry in an archive, holding the checksums of the other files.
+	archiveChecksumsName = "checksums.json"
+)
+
+// archiveManifest describes the snapshot in an archive, so that compatibility
+// can be checked before the (possibly large) data is extracted.
+type archiveManifest struct {
+	FormatVersion   int       `json:"formatVersion"`
+	Platform        string    `json:"platform"`
+	SettingsVersion int       `json:"settingsVersion"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Created         time.Time `json:"created"`
+}
+
+// ErrIncompatibleArchive is returned when importing an archive that can't be
+// used on this machine.
+var ErrIncompatibleArchive = errors.New("incompatible snapshot archive")
+
+// contextReader is an io.Reader that stops reading once the context is done,
+// so that long exports and imports can be cancelled.
+type contextReader struct {
+	ctx context.Context
+	io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.Reader.Read(p)
+}
+
+// Export writes the snapshot with the given name to a zstd-compressed tar
+// archive that can be imported on another machine.
+func (manager *Manager) Export(ctx context.Context, name string, w io.Writer) error {
+	snapshot, err := manager.Snapshot(name)
+	if err != nil {
+		return err
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	settings, err := readSnapshotJSON(snapshotDir, "settings.json")
+	if err != nil {
+		return err
+	}
+	manifest := archiveManifest{
+		FormatVersion: archiveFormatVersion,
+		Platform:      runtime.GOOS,
+		Name:          snapshot.Name,
+		Description:   snapshot.Description,
+		Created:       snapshot.Created,
+	}
+	if settingsMap, ok := settings.(map[string]any); ok {
+		if version, ok := settingsMap["version"].(float64); ok {
+			manifest.SettingsVersion = int(version)
+		}
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	compressor, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("failed to create compressor: %w", err)
+	}
+	archive := tar.NewWriter(compressor)
+	if err := writeArchiveJSON(archive, archiveManifestName, manifest); err != nil {
+		return err
+	}
+	checksums := map[string]string{}
+	for _, entry := range entries {
+		// The metadata is regenerated on import, with a new ID.
+		if !entry.Type().IsRegular() || entry.Name() == "metadata.json" || entry.Name() == completeFileName {
+			continue
+		}
+		checksum, err := writeArchiveFile(ctx, archive, filepath.Join(snapshotDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		checksums[entry.Name()] = checksum
+	}
+	if err := writeArchiveJSON(archive, archiveChecksumsName, checksums); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+	return nil
+}
+
+func writeArchiveJSON(archive *tar.Writer, name string, value any) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", name, err)
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(contents)),
+		ModTime: time.Now(),
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := archive.Write(contents); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// writeArchiveFile adds a file to the archive, returning its SHA-256 checksum.
+func writeArchiveFile(ctx context.Context, archive *tar.Writer, filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %q: %w", filePath, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to get info for %q: %w", filePath, err)
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive header for %q: %w", filePath, err)
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return "", fmt.Errorf("failed to write archive header for %q: %w", filePath, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, hash), contextReader{ctx, file}); err != nil {
+		return "", fmt.Errorf("failed to archive %q: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Import creates a new snapshot from an archive written by Export.  If name
+// is empty, the name of the exported snapshot is used.
+func (manager *Manager) Import(ctx context.Context, r io.Reader, name string) (snapshot Snapshot, err error) {
+	decompressor, err := zstd.NewReader(contextReader{ctx, r})
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer decompressor.Close()
+	archive := tar.NewReader(decompressor)
+
+	header, err := archive.Next()
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if header.Name != archiveManifestName {
+		return snapshot, fmt.Errorf("%w: missing %s", ErrIncompatibleArchive, archiveManifestName)
+	}
+	var manifest archiveManifest
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+		return snapshot, fmt.Errorf("failed to read %s: %w", archiveManifestName, err)
+	}
+	if err := checkArchiveManifest(manifest); err != nil {
+		return snapshot, err
+	}
+	if name == "" {
+		name = manifest.Name
+	}
+	if err := manager.ValidateName(name); err != nil {
+		return snapshot, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to generate ID for snapshot: %w", err)
+	}
+	snapshot = Snapshot{
+		Created:     manifest.Created,
+		Name:        name,
+		ID:          id.String(),
+		Description: manifest.Description,
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	defer func() {
+		if err != nil {
+			os.RemoveAll(snapshotDir)
+		}
+	}()
+	if err = manager.writeMetadataFile(snapshot); err != nil {
+		return
+	}
+
+	actualChecksums := map[string]string{}
+	var expectedChecksums map[string]string
+	for {
+		header, err = archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			err = fmt.Errorf("failed to read archive: %w", err)
+			return
+		}
+		if expectedChecksums != nil {
+			err = fmt.Errorf("%w: unexpected file %q after %s", ErrIncompatibleArchive, header.Name, archiveChecksumsName)
+			return
+		}
+		if header.Name == archiveChecksumsName {
+			if err = json.NewDecoder(archive).Decode(&expectedChecksums); err != nil {
+				err = fmt.Errorf("failed to read %s: %w", archiveChecksumsName, err)
+				return
+			}
+			continue
+		}
+		if err = checkArchiveEntry(header); err != nil {
+			return
+		}
+		var checksum string
+		if checksum, err = extractArchiveFile(archive, header, filepath.Join(snapshotDir, header.Name)); err != nil {
+			return
+		}
+		actualChecksums[header.Name] = checksum
+	}
+	if err = verifyArchiveChecksums(expectedChecksums, actualChecksums); err != nil {
+		return
+	}
+	if _, err = os.Stat(filepath.Join(snapshotDir, "settings.json")); err != nil {
+		err = fmt.Errorf("%w: missing settings.json", ErrIncompatibleArchive)
+		return
+	}
+	// (Re)validate the name in case a snapshot with the same name was
+	// created while we were importing.
+	if err = manager.ValidateName(name); err != nil {
+		return
+	}
+	completeFilePath := filepath.Join(snapshotDir, completeFileName)
+	if err = os.WriteFile(completeFilePath, []byte(completeFileContents), 0o644); err != nil {
+		err = fmt.Errorf("failed to write %q: %w", completeFileName, err)
+	}
+	return
+}
+
+// checkArchiveManifest checks that a snapshot with the given manifest can be
+// restored on this machine.
+func checkArchiveManifest(manifest archiveManifest) error {
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > archiveFormatVersion {
+		return fmt.Errorf("%w: archive format version %d is not supported; please upgrade Rancher Desktop",
+			ErrIncompatibleArchive, manifest.FormatVersion)
+	}
+	if manifest.Platform != runtime.GOOS {
+		return fmt.Errorf("%w: the snapshot was exported on %s, and can only be imported on the same platform",
+			ErrIncompatibleArchive, manifest.Platform)
+	}
+	if manifest.SettingsVersion > options.CURRENT_SETTINGS_VERSION {
+		return fmt.Errorf("%w: the snapshot has settings version %d, but this version of Rancher Desktop only supports up to %d",
+			ErrIncompatibleArchive, manifest.SettingsVersion, options.CURRENT_SETTINGS_VERSION)
+	}
+	return nil
+}
+
+// checkArchiveEntry rejects archive entries that are not plain files directly
+// in the snapshot directory.
+func checkArchiveEntry(header *tar.Header) error {
+	if header.Typeflag != tar.TypeReg {
+		return fmt.Errorf("%w: %q is not a regular file", ErrIncompatibleArchive, header.Name)
+	}
+	name := header.Name
+	if name == "" || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || name == ".." ||
+		name == "metadata.json" || name == completeFileName {
+		return fmt.Errorf("%w: invalid file name %q", ErrIncompatibleArchive, name)
+	}
+	return nil
+}
+
+// extractArchiveFile writes the current archive entry to the given path,
+// returning its SHA-256 checksum.
+func extractArchiveFile(archive *tar.Reader, header *tar.Header, filePath string) (string, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+	if err != nil {
+		return "", fmt.Errorf("failed to create %q: %w", filePath, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), archive); err != nil {
+		return "", fmt.Errorf("failed to extract %q: %w", header.Name, err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to extract %q: %w", header.Name, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func verifyArchiveChecksums(expected, actual map[string]string) error {
+	if expected == nil {
+		return fmt.Errorf("%w: missing %s; the archive may be truncated", ErrIncompatibleArchive, archiveChecksumsName)
+	}
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checksum, ok := actual[name]
+		if !ok {
+			return fmt.Errorf("archive is corrupt: missing file %q", name)
+		}
+		if checksum != expected[name] {
+			return fmt.Errorf("archive is corrupt: checksum mismatch for %q", name)
+		}
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			return fmt.Errorf("archive is corrupt: unexpected file %q", name)
+		}
+	}
+	return nil
+}
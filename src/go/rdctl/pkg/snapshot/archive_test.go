@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// writeTestArchive creates an archive with the given entries, in order.
+func writeTestArchive(t *testing.T, entries map[string]string, order []string) *bytes.Buffer {
+	var buf bytes.Buffer
+	compressor, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create compressor: %s", err)
+	}
+	archive := tar.NewWriter(compressor)
+	for _, name := range order {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(entries[name])), ModTime: time.Now()}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write header for %s: %s", name, err)
+		}
+		if _, err := archive.Write([]byte(entries[name])); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("failed to close archive: %s", err)
+	}
+	if err := compressor.Close(); err != nil {
+		t.Fatalf("failed to close compressor: %s", err)
+	}
+	return &buf
+}
+
+func TestExportImport(t *testing.T) {
+	appPaths, testFiles := populateFiles(t, true)
+	manager := newTestManager(appPaths)
+	settingsPath := testFiles["settings.json"].Path
+	if err := os.WriteFile(settingsPath, []byte(`{"version": 10, "kubernetes": {"enabled": true}}`), 0o644); err != nil {
+		t.Fatalf("failed to write settings: %s", err)
+	}
+	original, err := manager.Create(context.Background(), "original", "exported snapshot")
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := manager.Export(context.Background(), "original", &buf); err != nil {
+		t.Fatalf("failed to export snapshot: %s", err)
+	}
+	archiveContents := buf.Bytes()
+
+	t.Run("import with a new name", func(t *testing.T) {
+		imported, err := manager.Import(context.Background(), bytes.NewReader(archiveContents), "imported")
+		if err != nil {
+			t.Fatalf("failed to import snapshot: %s", err)
+		}
+		if imported.ID == original.ID {
+			t.Errorf("expected imported snapshot to have a new ID")
+		}
+		if imported.Description != original.Description || !imported.Created.Equal(original.Created) {
+			t.Errorf("unexpected metadata for imported snapshot: %+v", imported)
+		}
+		if _, err := manager.Snapshot("imported"); err != nil {
+			t.Fatalf("imported snapshot is not complete: %s", err)
+		}
+		originalDir := manager.SnapshotDirectory(original)
+		importedDir := manager.SnapshotDirectory(imported)
+		entries, err := os.ReadDir(originalDir)
+		if err != nil {
+			t.Fatalf("failed to read snapshot directory: %s", err)
+		}
+		for _, entry := range entries {
+			if entry.Name() == "metadata.json" {
+				continue
+			}
+			expected, err := os.ReadFile(filepath.Join(originalDir, entry.Name()))
+			if err != nil {
+				t.Fatalf("failed to read %s: %s", entry.Name(), err)
+			}
+			actual, err := os.ReadFile(filepath.Join(importedDir, entry.Name()))
+			if err != nil {
+				t.Fatalf("failed to read imported %s: %s", entry.Name(), err)
+			}
+			if !bytes.Equal(expected, actual) {
+				t.Errorf("contents of %s differ after import", entry.Name())
+			}
+		}
+	})
+
+	t.Run("import with a name that is in use", func(t *testing.T) {
+		_, err := manager.Import(context.Background(), bytes.NewReader(archiveContents), "")
+		if err == nil {
+			t.Fatalf("expected importing over an existing name to fail")
+		}
+	})
+
+	t.Run("corrupt archives are rejected", func(t *testing.T) {
+		entries := map[string]string{
+			archiveManifestName:  `{"formatVersion": 1, "platform": "` + runtime.GOOS + `", "name": "corrupt"}`,
+			"settings.json":      `{}`,
+			archiveChecksumsName: `{"settings.json": "0000"}`,
+		}
+		archive := writeTestArchive(t, entries, []string{archiveManifestName, "settings.json", archiveChecksumsName})
+		_, err := manager.Import(context.Background(), archive, "")
+		if err == nil {
+			t.Fatalf("expected corrupt archive to be rejected")
+		}
+		if _, err := manager.Snapshot("corrupt"); err == nil {
+			t.Errorf("expected corrupt snapshot to be removed")
+		}
+		snapshots, err := manager.List(true)
+		if err != nil {
+			t.Fatalf("failed to list snapshots: %s", err)
+		}
+		for _, snapshot := range snapshots {
+			if snapshot.Name == "corrupt" {
+				t.Errorf("expected incomplete snapshot to be cleaned up")
+			}
+		}
+	})
+
+	t.Run("incompatible archives are rejected", func(t *testing.T) {
+		manifests := map[string]string{
+			"future format":   `{"formatVersion": 99, "platform": "` + runtime.GOOS + `"}`,
+			"other platform":  `{"formatVersion": 1, "platform": "plan9"}`,
+			"future settings": `{"formatVersion": 1, "platform": "` + runtime.GOOS + `", "settingsVersion": 9999}`,
+		}
+		for description, manifest := range manifests {
+			t.Run(description, func(t *testing.T) {
+				entries := map[string]string{archiveManifestName: manifest}
+				archive := writeTestArchive(t, entries, []string{archiveManifestName})
+				_, err := manager.Import(context.Background(), archive, "incompatible")
+				if !errors.Is(err, ErrIncompatibleArchive) {
+					t.Errorf("expected incompatible archive error, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("unsafe file names are rejected", func(t *testing.T) {
+		for _, name := range []string{"../escape.txt", "nested/file.txt", "metadata.json"} {
+			entries := map[string]string{
+				archiveManifestName: `{"formatVersion": 1, "platform": "` + runtime.GOOS + `"}`,
+				name:                "contents",
+			}
+			archive := writeTestArchive(t, entries, []string{archiveManifestName, name})
+			_, err := manager.Import(context.Background(), archive, "unsafe")
+			if !errors.Is(err, ErrIncompatibleArchive) {
+				t.Errorf("expected %q to be rejected, got %v", name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(appPaths.Snapshots, "escape.txt")); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected file outside of the snapshot not to be written")
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+)
+
+// imagesFileName is the name of the file in the snapshot directory that lists
+// the container images present when the snapshot was created.  It is only
+// written if the image list could be determined at that time.
+const imagesFileName = "images.json"
+
+// SettingChange describes a setting that differs between two snapshots.
+type SettingChange struct {
+	// The dotted path of the setting, such as "kubernetes.enabled".
+	Path string `json:"path"`
+	// The value in the first snapshot; nil if the setting isn't present.
+	From any `json:"from"`
+	// The value in the second snapshot; nil if the setting isn't present.
+	To any `json:"to"`
+}
+
+// Diff describes the differences between two snapshots.
+type Diff struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Settings []SettingChange `json:"settings"`
+	// Whether the images are known for both snapshots; if not, ImagesAdded
+	// and ImagesRemoved are empty.
+	ImagesKnown   bool     `json:"imagesKnown"`
+	ImagesAdded   []string `json:"imagesAdded"`
+	ImagesRemoved []string `json:"imagesRemoved"`
+	// The approximate size of each snapshot, in bytes.
+	FromSize int64 `json:"fromSize"`
+	ToSize   int64 `json:"toSize"`
+}
+
+// Diff compares the snapshots with the given names.
+func (manager *Manager) Diff(fromName, toName string) (Diff, error) {
+	diff := Diff{From: fromName, To: toName, Settings: []SettingChange{}, ImagesAdded: []string{}, ImagesRemoved: []string{}}
+	fromSnapshot, err := manager.Snapshot(fromName)
+	if err != nil {
+		return diff, err
+	}
+	toSnapshot, err := manager.Snapshot(toName)
+	if err != nil {
+		return diff, err
+	}
+	fromDir := manager.SnapshotDirectory(fromSnapshot)
+	toDir := manager.SnapshotDirectory(toSnapshot)
+
+	fromSettings, err := readSnapshotJSON(fromDir, "settings.json")
+	if err != nil {
+		return diff, err
+	}
+	toSettings, err := readSnapshotJSON(toDir, "settings.json")
+	if err != nil {
+		return diff, err
+	}
+	diff.Settings = compareSettings("", fromSettings, toSettings, diff.Settings)
+
+	fromImages, fromErr := readSnapshotImages(fromDir)
+	toImages, toErr := readSnapshotImages(toDir)
+	switch {
+	case fromErr == nil && toErr == nil:
+		diff.ImagesKnown = true
+		for _, image := range toImages {
+			if !slices.Contains(fromImages, image) {
+				diff.ImagesAdded = append(diff.ImagesAdded, image)
+			}
+		}
+		for _, image := range fromImages {
+			if !slices.Contains(toImages, image) {
+				diff.ImagesRemoved = append(diff.ImagesRemoved, image)
+			}
+		}
+	case fromErr != nil && !errors.Is(fromErr, os.ErrNotExist):
+		return diff, fromErr
+	case toErr != nil && !errors.Is(toErr, os.ErrNotExist):
+		return diff, toErr
+	}
+
+	if diff.FromSize, err = directorySize(fromDir); err != nil {
+		return diff, err
+	}
+	if diff.ToSize, err = directorySize(toDir); err != nil {
+		return diff, err
+	}
+	return diff, nil
+}
+
+// writeImagesFile records the given image list in the snapshot directory.
+func (manager *Manager) writeImagesFile(snapshot Snapshot, images []string) error {
+	sorted := slices.Clone(images)
+	sort.Strings(sorted)
+	contents, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal image list: %w", err)
+	}
+	imagesPath := filepath.Join(manager.SnapshotDirectory(snapshot), imagesFileName)
+	if err := os.WriteFile(imagesPath, contents, 0o644); err != nil {
+		return fmt.Errorf("failed to write image list: %w", err)
+	}
+	return nil
+}
+
+func readSnapshotJSON(snapshotDir, name string) (any, error) {
+	filePath := filepath.Join(snapshotDir, name)
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", filePath, err)
+	}
+	var result any
+	if err := json.Unmarshal(contents, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal contents of %q: %w", filePath, err)
+	}
+	return result, nil
+}
+
+func readSnapshotImages(snapshotDir string) ([]string, error) {
+	var images []string
+	contents, err := os.ReadFile(filepath.Join(snapshotDir, imagesFileName))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &images); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal image list of snapshot: %w", err)
+	}
+	return images, nil
+}
+
+// compareSettings appends the differences between two decoded settings
+// documents to changes, recursing into objects; arrays are compared as a
+// whole.
+func compareSettings(prefix string, from, to any, changes []SettingChange) []SettingChange {
+	fromMap, fromIsMap := from.(map[string]any)
+	toMap, toIsMap := to.(map[string]any)
+	if !fromIsMap || !toIsMap {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, SettingChange{Path: prefix, From: from, To: to})
+		}
+		return changes
+	}
+	keys := make([]string, 0, len(fromMap)+len(toMap))
+	for key := range fromMap {
+		keys = append(keys, key)
+	}
+	for key := range toMap {
+		if _, ok := fromMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		changes = compareSettings(path, fromMap[key], toMap[key], changes)
+	}
+	return changes
+}
+
+// directorySize returns the total size of the files in a directory.  This is
+// approximate, as sparse and copy-on-write files may use less space on disk.
+func directorySize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine size of snapshot: %w", err)
+	}
+	return total, nil
+}
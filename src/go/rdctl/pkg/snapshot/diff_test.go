@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	appPaths, testFiles := populateFiles(t, true)
+	manager := newTestManager(appPaths)
+	settingsPath := testFiles["settings.json"].Path
+	writeSettings := func(contents string) {
+		if err := os.WriteFile(settingsPath, []byte(contents), 0o644); err != nil {
+			t.Fatalf("failed to write settings: %s", err)
+		}
+	}
+
+	writeSettings(`{"kubernetes": {"enabled": true, "version": "1.29.1"}, "images": {"namespace": "k8s.io"}}`)
+	manager.ListImages = func() ([]string, error) { return []string{"nginx:latest", "alpine:3.19"}, nil }
+	if _, err := manager.Create(context.Background(), "old", ""); err != nil {
+		t.Fatalf("failed to create first snapshot: %s", err)
+	}
+
+	writeSettings(`{"kubernetes": {"enabled": false, "version": "1.29.1"}, "application": {"debug": true}}`)
+	manager.ListImages = func() ([]string, error) { return []string{"busybox:latest", "nginx:latest"}, nil }
+	if _, err := manager.Create(context.Background(), "new", ""); err != nil {
+		t.Fatalf("failed to create second snapshot: %s", err)
+	}
+
+	diff, err := manager.Diff("old", "new")
+	if err != nil {
+		t.Fatalf("failed to diff snapshots: %s", err)
+	}
+	expectedSettings := []SettingChange{
+		{Path: "application", From: nil, To: map[string]any{"debug": true}},
+		{Path: "images", From: map[string]any{"namespace": "k8s.io"}, To: nil},
+		{Path: "kubernetes.enabled", From: true, To: false},
+	}
+	if !reflect.DeepEqual(diff.Settings, expectedSettings) {
+		t.Errorf("unexpected settings changes: %+v", diff.Settings)
+	}
+	if !diff.ImagesKnown {
+		t.Errorf("expected images to be known")
+	}
+	if !reflect.DeepEqual(diff.ImagesAdded, []string{"busybox:latest"}) {
+		t.Errorf("unexpected added images: %v", diff.ImagesAdded)
+	}
+	if !reflect.DeepEqual(diff.ImagesRemoved, []string{"alpine:3.19"}) {
+		t.Errorf("unexpected removed images: %v", diff.ImagesRemoved)
+	}
+	if diff.FromSize <= 0 || diff.ToSize <= 0 {
+		t.Errorf("unexpected snapshot sizes %d and %d", diff.FromSize, diff.ToSize)
+	}
+
+	t.Run("images are unknown if they could not be listed", func(t *testing.T) {
+		manager.ListImages = func() ([]string, error) { return nil, errors.New("backend is not running") }
+		if _, err := manager.Create(context.Background(), "unknown", ""); err != nil {
+			t.Fatalf("failed to create snapshot: %s", err)
+		}
+		snapshot, err := manager.Snapshot("unknown")
+		if err != nil {
+			t.Fatalf("failed to find snapshot: %s", err)
+		}
+		if _, err := os.Stat(filepath.Join(manager.SnapshotDirectory(snapshot), imagesFileName)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected no image list, got %v", err)
+		}
+		diff, err := manager.Diff("old", "unknown")
+		if err != nil {
+			t.Fatalf("failed to diff snapshots: %s", err)
+		}
+		if diff.ImagesKnown || len(diff.ImagesAdded) > 0 || len(diff.ImagesRemoved) > 0 {
+			t.Errorf("expected images to be unknown: %+v", diff)
+		}
+	})
+
+	t.Run("missing snapshots are reported", func(t *testing.T) {
+		if _, err := manager.Diff("old", "missing"); err == nil {
+			t.Errorf("expected an error for a missing snapshot")
+		}
+	})
+}
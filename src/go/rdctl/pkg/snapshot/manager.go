@@ -26,6 +26,10 @@ type Manager struct {
 	Snapshotter
 	paths.Paths
 	lock.BackendLocker
+	// ListImages, if set, is called before the backend is stopped to record
+	// the container images in new snapshots; errors are ignored, as the
+	// backend may not be running.
+	ListImages func() ([]string, error)
 }
 
 func NewManager() (*Manager, error) {
@@ -119,6 +123,10 @@ func (manager *Manager) writeMetadataFile(snapshot Snapshot) error {
 
 // Create a new snapshot.
 func (manager *Manager) Create(ctx context.Context, name, description string) (snapshot Snapshot, err error) {
+	return manager.create(ctx, name, description, false)
+}
+
+func (manager *Manager) create(ctx context.Context, name, description string, scheduled bool) (snapshot Snapshot, err error) {
 	id, err := uuid.NewRandom()
 	if err != nil {
 		return snapshot, fmt.Errorf("failed to generate ID for snapshot: %w", err)
@@ -128,6 +136,12 @@ func (manager *Manager) Create(ctx context.Context, name, description string) (s
 		Name:        name,
 		ID:          id.String(),
 		Description: description,
+		Scheduled:   scheduled,
+	}
+	var images []string
+	imagesErr := errors.New("image listing is not available")
+	if manager.ListImages != nil {
+		images, imagesErr = manager.ListImages()
 	}
 	action := fmt.Sprintf("Creating snapshot %q", name)
 	if err = manager.Lock(manager.Paths, action); err != nil {
@@ -146,9 +160,15 @@ func (manager *Manager) Create(ctx context.Context, name, description string) (s
 	if err = manager.ValidateName(name); err != nil {
 		return
 	}
-	if err = manager.writeMetadataFile(snapshot); err == nil {
-		err = manager.CreateFiles(ctx, manager.Paths, manager.SnapshotDirectory(snapshot))
+	if err = manager.writeMetadataFile(snapshot); err != nil {
+		return
+	}
+	if imagesErr == nil {
+		if err = manager.writeImagesFile(snapshot, images); err != nil {
+			return
+		}
 	}
+	err = manager.CreateFiles(ctx, manager.Paths, manager.SnapshotDirectory(snapshot))
 	return
 }
 
@@ -0,0 +1,131 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// scheduledSnapshotNameFormat is the time layout used to name scheduled
+// snapshots, so that they sort by creation time.
+const scheduledSnapshotNameFormat = "scheduled-2006-01-02-150405"
+
+// scheduleNow returns the time used to name scheduled snapshots; it is
+// replaced in tests so snapshots can be created in quick succession.
+var scheduleNow = time.Now
+
+// ScheduleOptions control how scheduled snapshots are created.
+type ScheduleOptions struct {
+	// Retention is the number of scheduled snapshots to keep; older ones are
+	// deleted after a new one is created.  Snapshots created manually are
+	// never deleted.
+	Retention int
+	// SkipIfUnchanged skips creating a snapshot if the settings and container
+	// images are the same as in the most recent scheduled snapshot.
+	SkipIfUnchanged bool
+}
+
+// ScheduledResult describes the outcome of CreateScheduled.
+type ScheduledResult struct {
+	// The new snapshot, or the most recent existing one if it was skipped.
+	Snapshot Snapshot `json:"snapshot"`
+	// Whether creating the snapshot was skipped because nothing changed.
+	Skipped bool `json:"skipped"`
+	// The names of the old scheduled snapshots that were deleted.
+	Deleted []string `json:"deleted"`
+}
+
+// Scheduled returns the complete snapshots created by the scheduler, oldest
+// first.
+func (manager *Manager) Scheduled() ([]Snapshot, error) {
+	snapshots, err := manager.List(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	var scheduled []Snapshot
+	for _, snapshot := range snapshots {
+		if snapshot.Scheduled {
+			scheduled = append(scheduled, snapshot)
+		}
+	}
+	sort.Slice(scheduled, func(i, j int) bool {
+		return scheduled[i].Created.Before(scheduled[j].Created)
+	})
+	return scheduled, nil
+}
+
+// CreateScheduled creates a scheduled snapshot, and then deletes the oldest
+// scheduled snapshots in excess of the retention count.
+func (manager *Manager) CreateScheduled(ctx context.Context, options ScheduleOptions) (ScheduledResult, error) {
+	result := ScheduledResult{Deleted: []string{}}
+	if options.Retention < 1 {
+		return result, fmt.Errorf("invalid retention count %d: must be at least 1", options.Retention)
+	}
+	scheduled, err := manager.Scheduled()
+	if err != nil {
+		return result, err
+	}
+	if options.SkipIfUnchanged && len(scheduled) > 0 {
+		latest := scheduled[len(scheduled)-1]
+		if manager.unchangedSince(latest) {
+			result.Snapshot = latest
+			result.Skipped = true
+			return result, nil
+		}
+	}
+
+	now := scheduleNow()
+	description := fmt.Sprintf("Scheduled snapshot created at %s", now.Format(time.RFC1123))
+	snapshot, err := manager.create(ctx, now.Format(scheduledSnapshotNameFormat), description, true)
+	if err != nil {
+		return result, err
+	}
+	result.Snapshot = snapshot
+	scheduled = append(scheduled, snapshot)
+
+	var errs []error
+	for len(scheduled) > options.Retention {
+		if err := manager.Delete(scheduled[0].Name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete old scheduled snapshot %q: %w", scheduled[0].Name, err))
+		} else {
+			result.Deleted = append(result.Deleted, scheduled[0].Name)
+		}
+		scheduled = scheduled[1:]
+	}
+	return result, errors.Join(errs...)
+}
+
+// unchangedSince returns whether the current settings and container images
+// are the same as in the given snapshot.  If either can't be determined, it is
+// assumed that something changed.
+func (manager *Manager) unchangedSince(snapshot Snapshot) bool {
+	if manager.ListImages == nil {
+		return false
+	}
+	snapshotDir := manager.SnapshotDirectory(snapshot)
+	snapshotSettings, err := readSnapshotJSON(snapshotDir, "settings.json")
+	if err != nil {
+		return false
+	}
+	currentSettings, err := readSnapshotJSON(manager.Paths.Config, "settings.json")
+	if err != nil {
+		return false
+	}
+	if len(compareSettings("", snapshotSettings, currentSettings, nil)) > 0 {
+		return false
+	}
+	snapshotImages, err := readSnapshotImages(snapshotDir)
+	if err != nil {
+		return false
+	}
+	currentImages, err := manager.ListImages()
+	if err != nil {
+		return false
+	}
+	currentImages = slices.Clone(currentImages)
+	sort.Strings(currentImages)
+	return slices.Equal(snapshotImages, currentImages)
+}
@@ -0,0 +1,99 @@
+package snapshot
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateScheduled(t *testing.T) {
+	appPaths, testFiles := populateFiles(t, true)
+	manager := newTestManager(appPaths)
+	images := []string{"nginx:latest"}
+	manager.ListImages = func() ([]string, error) { return images, nil }
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	t.Cleanup(func() { scheduleNow = time.Now })
+	scheduleNow = func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+	writeSettings := func(contents string) {
+		if err := os.WriteFile(testFiles["settings.json"].Path, []byte(contents), 0o644); err != nil {
+			t.Fatalf("failed to write settings: %s", err)
+		}
+	}
+	options := ScheduleOptions{Retention: 2, SkipIfUnchanged: true}
+
+	if _, err := manager.Create(context.Background(), "manual", ""); err != nil {
+		t.Fatalf("failed to create manual snapshot: %s", err)
+	}
+	first, err := manager.CreateScheduled(context.Background(), options)
+	if err != nil {
+		t.Fatalf("failed to create scheduled snapshot: %s", err)
+	}
+	if first.Skipped || !first.Snapshot.Scheduled || first.Snapshot.Name != "scheduled-2024-05-06-080809" {
+		t.Errorf("unexpected result for first scheduled snapshot: %+v", first)
+	}
+
+	t.Run("unchanged state is skipped", func(t *testing.T) {
+		result, err := manager.CreateScheduled(context.Background(), options)
+		if err != nil {
+			t.Fatalf("failed to create scheduled snapshot: %s", err)
+		}
+		if !result.Skipped || result.Snapshot.Name != first.Snapshot.Name {
+			t.Errorf("expected snapshot to be skipped: %+v", result)
+		}
+	})
+
+	t.Run("changed images are not skipped", func(t *testing.T) {
+		images = []string{"alpine:3.19", "nginx:latest"}
+		result, err := manager.CreateScheduled(context.Background(), options)
+		if err != nil {
+			t.Fatalf("failed to create scheduled snapshot: %s", err)
+		}
+		if result.Skipped || len(result.Deleted) != 0 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("old snapshots are deleted", func(t *testing.T) {
+		writeSettings(`{"test": "changed settings"}`)
+		result, err := manager.CreateScheduled(context.Background(), options)
+		if err != nil {
+			t.Fatalf("failed to create scheduled snapshot: %s", err)
+		}
+		if result.Skipped || !reflect.DeepEqual(result.Deleted, []string{first.Snapshot.Name}) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		scheduled, err := manager.Scheduled()
+		if err != nil {
+			t.Fatalf("failed to list scheduled snapshots: %s", err)
+		}
+		if len(scheduled) != 2 || scheduled[1].Name != result.Snapshot.Name {
+			t.Errorf("unexpected scheduled snapshots: %+v", scheduled)
+		}
+		if _, err := manager.Snapshot("manual"); err != nil {
+			t.Errorf("manual snapshot should not be deleted: %s", err)
+		}
+	})
+
+	t.Run("skipping requires the image list", func(t *testing.T) {
+		manager.ListImages = nil
+		result, err := manager.CreateScheduled(context.Background(), ScheduleOptions{Retention: 5, SkipIfUnchanged: true})
+		if err != nil {
+			t.Fatalf("failed to create scheduled snapshot: %s", err)
+		}
+		if result.Skipped {
+			t.Errorf("expected snapshot not to be skipped without the image list")
+		}
+	})
+
+	t.Run("retention must be positive", func(t *testing.T) {
+		if _, err := manager.CreateScheduled(context.Background(), ScheduleOptions{}); err == nil {
+			t.Errorf("expected an error for zero retention")
+		}
+	})
+}
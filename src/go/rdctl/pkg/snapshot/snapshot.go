@@ -10,6 +10,9 @@ type Snapshot struct {
 	Name        string    `json:"name"`
 	ID          string    `json:"id,omitempty"`
 	Description string    `json:"description"`
+	// Scheduled is set for snapshots created by the snapshot scheduler; only
+	// these are deleted automatically once they exceed the retention count.
+	Scheduled bool `json:"scheduled,omitempty"`
 }
 
 func (s *Snapshot) getTimeString() string {
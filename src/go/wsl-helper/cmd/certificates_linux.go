@@ -0,0 +1,90 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/certificates"
+)
+
+var certificatesInstallViper = viper.New()
+
+// certificatesCmd represents the `certificates` command.
+var certificatesCmd = &cobra.Command{
+	Use:   "certificates",
+	Short: "Commands for managing trusted certificates",
+}
+
+// certificatesInstallCmd represents the `certificates install` command.
+var certificatesInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install host certificates into the distribution trust store",
+	Long: `Install PEM-encoded certificates (typically the output of the Windows
+"wsl-helper certificates" command) into the trust store of this distribution,
+replacing any certificates previously installed this way; the system bundle is
+regenerated if anything changed.  Installing no certificates removes them all.
+With --containerd, containerd is also configured to trust the certificates for
+all registries.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		var data []byte
+		var err error
+		if file := certificatesInstallViper.GetString("file"); file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read certificates: %w", err)
+		}
+		certs, err := certificates.ParsePEM(data)
+		if err != nil {
+			// Install the certificates we could parse anyway.
+			logrus.WithError(err).Warn("Ignoring invalid certificates")
+		}
+		options := certificates.InstallOptions{}
+		if certificatesInstallViper.GetBool("containerd") {
+			options.ContainerdDir = certificates.DefaultContainerdCertsDir
+		}
+		result, err := certificates.Install(cmd.Context(), certs, options)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	},
+}
+
+func init() {
+	certificatesInstallCmd.Flags().String("file", "-", "File containing PEM-encoded certificates, or - for stdin")
+	certificatesInstallCmd.Flags().Bool("containerd", false, "Also configure containerd to trust the certificates")
+	certificatesInstallViper.AutomaticEnv()
+	if err := certificatesInstallViper.BindPFlags(certificatesInstallCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	certificatesCmd.AddCommand(certificatesInstallCmd)
+	rootCmd.AddCommand(certificatesCmd)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2023 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"os"
+	"os/signal"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/windows"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/certificates"
+)
+
+var certificatesWatchViper = viper.New()
+
+// certificatesWatchCmd represents the `certificates watch` command.
+var certificatesWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch for changes to the system certificates",
+	Long: `Watch for changes to the system certificates, emitting one JSON object
+per line (with the fingerprint and number of certificates) at the start and each
+time the set of trusted certificates changes.  Consumers should then re-export
+the certificates and install them with "certificates install".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, windows.SIGTERM)
+		defer stop()
+
+		encoder := json.NewEncoder(os.Stdout)
+		stores := certificatesWatchViper.GetStringSlice("stores")
+		return certificates.WatchSystemCertificates(ctx, log, stores, func(certs []*x509.Certificate) {
+			event := struct {
+				Fingerprint string `json:"fingerprint"`
+				Count       int    `json:"count"`
+			}{
+				Fingerprint: certificates.Fingerprint(certs),
+				Count:       len(certs),
+			}
+			log.WithField("count", event.Count).Debug("System certificates changed")
+			if err := encoder.Encode(event); err != nil {
+				log.WithError(err).Error("failed to write event")
+			}
+		})
+	},
+}
+
+func init() {
+	certificatesWatchCmd.Flags().StringSlice("stores", []string{"CA", "ROOT"}, "Certificate stores to watch")
+	certificatesWatchViper.AutomaticEnv()
+	if err := certificatesWatchViper.BindPFlags(certificatesWatchCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	certificatesCmd.AddCommand(certificatesWatchCmd)
+}
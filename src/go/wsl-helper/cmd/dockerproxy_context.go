@@ -0,0 +1,63 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var dockerproxyContextViper = viper.New()
+
+// dockerproxyContextCmd is the `wsl-helper docker-proxy context` command.
+var dockerproxyContextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage the rancher-desktop docker CLI context",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to locate home directory: %w", err)
+		}
+		if !dockerproxyContextViper.GetBool("state") {
+			return integration.RemoveDockerContext(homeDir)
+		}
+		endpoint := dockerproxyContextViper.GetString("endpoint")
+		use := dockerproxyContextViper.GetBool("use")
+		return integration.SetupDockerContext(homeDir, endpoint, use)
+	},
+}
+
+func init() {
+	dockerproxyContextCmd.Flags().Bool("state", true, "Whether the context should exist")
+	dockerproxyContextCmd.Flags().String("endpoint", platform.DefaultEndpoint, "Endpoint the docker proxy is listening on")
+	dockerproxyContextCmd.Flags().Bool("use", false, "Make the context the current context")
+	dockerproxyContextViper.AutomaticEnv()
+	if err := dockerproxyContextViper.BindPFlags(dockerproxyContextCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	dockerproxyCmd.AddCommand(dockerproxyContextCmd)
+}
@@ -0,0 +1,96 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+)
+
+// dockerproxyServeReadConfig reads the configuration file given by --config,
+// if any.  The file is YAML (or JSON), with settings named after the command
+// line flags; flags given on the command line take precedence.
+func dockerproxyServeReadConfig() error {
+	configFile := dockerproxyServeViper.GetString("config")
+	if configFile == "" {
+		return nil
+	}
+	dockerproxyServeViper.SetConfigFile(configFile)
+	dockerproxyServeViper.SetConfigType("yaml")
+	if err := dockerproxyServeViper.ReadInConfig(); err != nil {
+		return fmt.Errorf("could not read configuration file %s: %w", configFile, err)
+	}
+	return nil
+}
+
+// dockerproxyServeSetReloadableOptions fills in the options that can be
+// changed while the proxy is running.
+func dockerproxyServeSetReloadableOptions(options *dockerproxy.ServeOptions) error {
+	var err error
+	options.PolicyRules = nil
+	if policyFile := dockerproxyServeViper.GetString("policy-file"); policyFile != "" {
+		options.PolicyRules, err = dockerproxy.LoadPolicyFile(policyFile)
+		if err != nil {
+			return err
+		}
+	}
+	options.EventFilters = nil
+	if dockerproxyServeViper.GetBool("hide-kubernetes-events") {
+		options.EventFilters = append(options.EventFilters, dockerproxy.KubernetesEventFilterRule)
+	}
+	if eventFilterFile := dockerproxyServeViper.GetString("event-filter-file"); eventFilterFile != "" {
+		rules, err := dockerproxy.LoadEventFilterFile(eventFilterFile)
+		if err != nil {
+			return err
+		}
+		options.EventFilters = append(options.EventFilters, rules...)
+	}
+	options.EndpointTimeouts, err = dockerproxy.ParseEndpointTimeouts(dockerproxyServeViper.GetStringMapString("endpoint-timeout"))
+	if err != nil {
+		return err
+	}
+	options.RateLimit = dockerproxyServeViper.GetFloat64("rate-limit")
+	options.RateLimitBurst = dockerproxyServeViper.GetInt("rate-limit-burst")
+	options.RateLimitBypass = dockerproxyServeViper.GetStringSlice("rate-limit-bypass")
+	options.MaxRequestBodySize = dockerproxyServeViper.GetInt64("max-request-body-size") * 1024 * 1024
+	options.RequestTimeout = dockerproxyServeViper.GetDuration("request-timeout")
+	options.RegistryMirrors = dockerproxyServeViper.GetStringMapString("registry-mirror")
+	return nil
+}
+
+// dockerproxyServeSetReload configures the proxy to reload the reloadable
+// options on SIGHUP, or when the configuration, policy, or event filter files
+// change.
+func dockerproxyServeSetReload(options *dockerproxy.ServeOptions) {
+	options.Reload = func() (dockerproxy.ServeOptions, error) {
+		var reloaded dockerproxy.ServeOptions
+		if err := dockerproxyServeReadConfig(); err != nil {
+			return reloaded, err
+		}
+		err := dockerproxyServeSetReloadableOptions(&reloaded)
+		return reloaded, err
+	}
+	options.ReloadWatchFiles = nil
+	for _, key := range []string{"config", "policy-file", "event-filter-file"} {
+		if watchFile := dockerproxyServeViper.GetString(key); watchFile != "" {
+			options.ReloadWatchFiles = append(options.ReloadWatchFiles, watchFile)
+		}
+	}
+}
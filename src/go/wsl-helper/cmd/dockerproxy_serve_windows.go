@@ -17,10 +17,15 @@ limitations under the License.
 package cmd
 
 import (
+	"net"
+	"strconv"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
 
@@ -37,13 +42,67 @@ var dockerproxyServeCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 		cmd.SilenceErrors = true
+		if err := dockerproxyServeReadConfig(); err != nil {
+			return err
+		}
 		endpoint := dockerproxyServeViper.GetString("endpoint")
-		port := dockerproxyServeViper.GetUint32("port")
-		dialer, err := platform.MakeDialer(port)
+		var dialer func() (net.Conn, error)
+		var err error
+		if proxyEndpoint := dockerproxyServeViper.GetString("proxy-endpoint"); proxyEndpoint != "" {
+			dialer, err = platform.MakePipeDialer(proxyEndpoint)
+		} else {
+			dialer, err = platform.MakeDialer(dockerproxyServeViper.GetUint32("port"))
+		}
+		if err != nil {
+			return err
+		}
+		var fallbackDialers []func() (net.Conn, error)
+		for _, fallbackEndpoint := range dockerproxyServeViper.GetIntSlice("fallback-port") {
+			fallbackDialer, err := platform.MakeDialer(uint32(fallbackEndpoint))
+			if err != nil {
+				return err
+			}
+			fallbackDialers = append(fallbackDialers, fallbackDialer)
+		}
+		var authTokens []string
+		if tokenFile := dockerproxyServeViper.GetString("auth-token-file"); tokenFile != "" {
+			authTokens, err = dockerproxy.LoadAuthTokenFile(tokenFile)
+			if err != nil {
+				return err
+			}
+		}
+		options := dockerproxy.ServeOptions{
+			MetricsEndpoint:       dockerproxyServeViper.GetString("metrics-endpoint"),
+			TracingEndpoint:       dockerproxyServeViper.GetString("otlp-endpoint"),
+			AuditLogPath:          dockerproxyServeViper.GetString("audit-log"),
+			AuditLogMaxSize:       dockerproxyServeViper.GetInt64("audit-log-max-size") * 1024 * 1024,
+			AuditLogMaxBackups:    dockerproxyServeViper.GetInt("audit-log-max-backups"),
+			ShutdownTimeout:       dockerproxyServeViper.GetDuration("shutdown-timeout"),
+			CacheTTL:              dockerproxyServeViper.GetDuration("cache-ttl"),
+			ResponseHeaderTimeout: dockerproxyServeViper.GetDuration("response-header-timeout"),
+			PodmanCompat:          dockerproxyServeViper.GetBool("podman-compat"),
+			MaxConnections:        dockerproxyServeViper.GetInt("max-connections"),
+			TLSCertDir:            dockerproxyServeViper.GetString("tls-cert-dir"),
+			TLSVerifyClients:      dockerproxyServeViper.GetBool("tls-verify-clients"),
+			AuthTokens:            authTokens,
+			InsecureTCP:           dockerproxyServeViper.GetBool("insecure"),
+			DialRetries:           dockerproxyServeViper.GetInt("dial-retries"),
+			DialRetryBackoff:      dockerproxyServeViper.GetDuration("dial-retry-backoff"),
+			FallbackDialers:       fallbackDialers,
+			HealthCheckInterval:   dockerproxyServeViper.GetDuration("health-check-interval"),
+			CopyBufferSize:        dockerproxyServeViper.GetInt("copy-buffer-size"),
+			IdleTimeout:           dockerproxyServeViper.GetDuration("idle-timeout"),
+			IdleTimeoutBypass:     dockerproxyServeViper.GetStringSlice("idle-timeout-bypass"),
+		}
+		if err := dockerproxyServeSetReloadableOptions(&options); err != nil {
+			return err
+		}
+		dockerproxyServeSetReload(&options)
+		options.Upstreams, err = dockerproxyServeUpstreams()
 		if err != nil {
 			return err
 		}
-		err = dockerproxy.Serve(endpoint, dialer)
+		err = dockerproxy.Serve(endpoint, dialer, options)
 		if err != nil {
 			return err
 		}
@@ -51,9 +110,72 @@ var dockerproxyServeCmd = &cobra.Command{
 	},
 }
 
+// dockerproxyServeUpstreams returns the additional upstreams to serve, from
+// --upstream; the backend is either a vsock port or a named pipe.
+func dockerproxyServeUpstreams() ([]dockerproxy.Upstream, error) {
+	var upstreams []dockerproxy.Upstream
+	for _, arg := range dockerproxyServeViper.GetStringSlice("upstream") {
+		spec, err := dockerproxy.ParseUpstreamSpec(arg)
+		if err != nil {
+			return nil, err
+		}
+		var dialer func() (net.Conn, error)
+		if port, parseErr := strconv.ParseUint(spec.Backend, 10, 32); parseErr == nil {
+			dialer, err = platform.MakeDialer(uint32(port))
+		} else {
+			dialer, err = platform.MakePipeDialer(spec.Backend)
+		}
+		if err != nil {
+			return nil, err
+		}
+		upstreams = append(upstreams, dockerproxy.Upstream{
+			Kind:      spec.Kind,
+			Endpoint:  spec.Endpoint,
+			Dialer:    dialer,
+			Namespace: dockerproxyServeViper.GetString("containerd-namespace"),
+		})
+	}
+	return upstreams, nil
+}
+
 func init() {
 	dockerproxyServeCmd.Flags().String("endpoint", platform.DefaultEndpoint, "Endpoint to listen on")
 	dockerproxyServeCmd.Flags().Uint32("port", dockerproxy.DefaultPort, "Vsock port docker is listening on")
+	dockerproxyServeCmd.Flags().String("proxy-endpoint", "", "Named pipe endpoint dockerd is listening on, instead of the vsock port")
+	dockerproxyServeCmd.Flags().StringArray("upstream", nil, "Additional backend to serve, as kind:endpoint=backend (kind is containerd or stream; backend is a vsock port or named pipe)")
+	dockerproxyServeCmd.Flags().String("containerd-namespace", "default", "containerd namespace for requests that do not specify one")
+	dockerproxyServeCmd.Flags().String("metrics-endpoint", "", "TCP address to serve Prometheus metrics on (disabled if empty)")
+	dockerproxyServeCmd.Flags().String("otlp-endpoint", "", "OTLP/HTTP URL to export OpenTelemetry traces to (disabled if empty)")
+	dockerproxyServeCmd.Flags().String("audit-log", "", "File to write an audit log of Docker API calls to (disabled if empty)")
+	dockerproxyServeCmd.Flags().Int64("audit-log-max-size", 10, "Size of the audit log, in MiB, at which it is rotated")
+	dockerproxyServeCmd.Flags().Int("audit-log-max-backups", 5, "Number of rotated audit logs to keep")
+	dockerproxyServeCmd.Flags().String("config", "", "YAML file with settings named after these flags; reloaded on change or SIGHUP")
+	dockerproxyServeCmd.Flags().String("policy-file", "", "JSON or YAML file describing requests to reject")
+	dockerproxyServeCmd.Flags().Float64("rate-limit", 0, "Requests per second allowed per client (disabled if zero)")
+	dockerproxyServeCmd.Flags().Int("rate-limit-burst", 50, "Number of requests per client allowed in a burst")
+	dockerproxyServeCmd.Flags().StringSlice("rate-limit-bypass", dockerproxy.DefaultRateLimitBypass, "API paths that are not rate limited")
+	dockerproxyServeCmd.Flags().Duration("shutdown-timeout", 10*time.Second, "Time to wait for connections to finish on shutdown")
+	dockerproxyServeCmd.Flags().Duration("cache-ttl", 0, "Time to cache responses to frequently polled read-only endpoints (disabled if zero)")
+	dockerproxyServeCmd.Flags().Int64("max-request-body-size", 0, "Largest request body, in MiB, for endpoints without streamed uploads (unlimited if zero)")
+	dockerproxyServeCmd.Flags().Duration("response-header-timeout", 0, "Time to wait for dockerd to send response headers (unlimited if zero)")
+	dockerproxyServeCmd.Flags().Duration("request-timeout", 0, "Deadline for requests that do not stream (unlimited if zero)")
+	dockerproxyServeCmd.Flags().StringToString("endpoint-timeout", nil, "Deadlines for specific API paths, overriding --request-timeout (e.g. /images/create=30m)")
+	dockerproxyServeCmd.Flags().Bool("hide-kubernetes-events", false, "Hide events about Kubernetes-managed containers from the events stream")
+	dockerproxyServeCmd.Flags().String("event-filter-file", "", "JSON or YAML file describing events to drop or tag")
+	dockerproxyServeCmd.Flags().StringToString("registry-mirror", nil, "Mirrors to pull images from instead of the given registries (e.g. docker.io=mirror.example.com)")
+	dockerproxyServeCmd.Flags().Int("max-connections", 0, "Maximum number of concurrent client connections (unlimited if zero)")
+	dockerproxyServeCmd.Flags().String("tls-cert-dir", "", "Directory with cert.pem and key.pem to serve TLS with, when listening on tcp:// (reloaded on change)")
+	dockerproxyServeCmd.Flags().Bool("tls-verify-clients", false, "Accept clients with certificates signed by ca.pem in --tls-cert-dir")
+	dockerproxyServeCmd.Flags().String("auth-token-file", "", "File with bearer tokens (one per line) that clients may authenticate with")
+	dockerproxyServeCmd.Flags().Bool("insecure", false, "Allow listening on tcp:// without --tls-cert-dir, sending the bearer tokens unencrypted")
+	dockerproxyServeCmd.Flags().Bool("podman-compat", false, "Translate requests for the podman (libpod) API where possible")
+	dockerproxyServeCmd.Flags().Int("dial-retries", 5, "Number of times to retry connecting to dockerd")
+	dockerproxyServeCmd.Flags().Duration("dial-retry-backoff", 100*time.Millisecond, "Delay before the first retry connecting to dockerd")
+	dockerproxyServeCmd.Flags().IntSlice("fallback-port", nil, "Vsock ports to connect to if dockerd is unavailable on the main port")
+	dockerproxyServeCmd.Flags().Duration("health-check-interval", 5*time.Second, "Interval between health checks when there are fallback endpoints")
+	dockerproxyServeCmd.Flags().Int("copy-buffer-size", utils.DefaultBufferSize, "Size, in bytes, of the buffers used to copy data")
+	dockerproxyServeCmd.Flags().Duration("idle-timeout", 0, "Time after which idle attach/exec connections are closed (disabled if zero)")
+	dockerproxyServeCmd.Flags().StringSlice("idle-timeout-bypass", nil, "API paths that are not closed when idle")
 	dockerproxyServeViper.AutomaticEnv()
 	if err := dockerproxyServeViper.BindPFlags(dockerproxyServeCmd.Flags()); err != nil {
 		logrus.WithError(err).Fatal("Failed to set up flags")
@@ -27,28 +27,9 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
-)
 
-type kubeConfig struct {
-	Clusters []struct {
-		Cluster struct {
-			Server string
-			Extras map[string]interface{} `yaml:",inline"`
-		} `yaml:"cluster"`
-		Name   string                 `yaml:"name"`
-		Extras map[string]interface{} `yaml:",inline"`
-	} `yaml:"clusters"`
-	Contexts []struct {
-		Name   string                 `yaml:"name"`
-		Extras map[string]interface{} `yaml:",inline"`
-	} `yaml:"contexts"`
-	CurrentContext string `yaml:"current-context"`
-	Users          []struct {
-		Name   string                 `yaml:"name"`
-		Extras map[string]interface{} `yaml:",inline"`
-	} `yaml:"users"`
-	Extras map[string]interface{} `yaml:",inline"`
-}
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/kubeconfig"
+)
 
 const kubeConfigExistTimeout = 10 * time.Second
 
@@ -76,7 +57,6 @@ var k3sKubeconfigCmd = &cobra.Command{
 				time.Sleep(time.Second)
 			}
 		}()
-		var err error
 		timeout := time.After(kubeConfigExistTimeout)
 		var configFile *os.File
 		select {
@@ -86,9 +66,8 @@ var k3sKubeconfigCmd = &cobra.Command{
 			break
 		}
 
-		var config kubeConfig
 		defer configFile.Close()
-		err = yaml.NewDecoder(configFile).Decode(&config)
+		config, err := kubeconfig.Decode(configFile)
 		if err != nil {
 			return err
 		}
@@ -23,115 +23,153 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v3"
 	"k8s.io/client-go/util/homedir"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/kubeconfig"
 )
 
 var kubeconfigViper = viper.New()
 
-const rdCluster = "rancher-desktop"
+const rdCluster = kubeconfig.RancherDesktop
 
-// kubeconfigCmd represents the kubeconfig command, used to set up a symlink on
-// the Linux side to point at the Windows-side kubeconfig.  Note that we must
-// pass the kubeconfig path in as an environment variable to take advantage of
-// the path translation capabilities of WSL2 interop.
+// kubeconfigCmd represents the kubeconfig command, used to merge the Rancher
+// Desktop context from the Windows-side kubeconfig into the kubeconfig on the
+// Linux side.  Note that we must pass the kubeconfig path in as an environment
+// variable to take advantage of the path translation capabilities of WSL2
+// interop.
 var kubeconfigCmd = &cobra.Command{
 	Use:   "kubeconfig",
 	Short: "Set up ~/.kube/config in the WSL2 environment",
-	Long:  `This command configures the Kubernetes configuration inside a WSL2 distribution.`,
-	Args:  cobra.ExactArgs(0),
+	Long: `This command configures the Kubernetes configuration inside a WSL2 distribution.
+The rancher-desktop context (and its cluster and user) are merged into the
+existing configuration; other clusters are preserved.  With --cleanup, stale
+rancher-desktop entries (a context referring to a missing cluster or user, or
+a cluster or user no context refers to) are removed from both the Windows and
+the Linux kubeconfigs instead; the live rancher-desktop context is kept unless
+--enable=false is given.  Only entries named exactly rancher-desktop are
+touched.  This is not done as part of the normal sync, and is meant for
+cleaning up after a factory reset or uninstall.`,
+	Args: cobra.ExactArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
-		configPath := kubeconfigViper.GetString("kubeconfig")
+		configPaths := filepath.SplitList(kubeconfigViper.GetString("kubeconfig"))
 		enable := kubeconfigViper.GetBool("enable")
 		verify := kubeconfigViper.GetBool("verify")
 
-		configDir := path.Join(homedir.HomeDir(), ".kube")
-		linkPath := path.Join(configDir, "config")
-		unsupportedConfig, symlinkErr := requireManualSymlink(linkPath)
+		linkPath := path.Join(homedir.HomeDir(), ".kube", "config")
+		targetPaths := filepath.SplitList(kubeconfigViper.GetString("target"))
+		if len(targetPaths) == 0 {
+			targetPaths = []string{linkPath}
+		}
 		if verify {
-			if unsupportedConfig {
-				logrus.Fatalf("kubeConfig: %s contains non-rancher desktop configuration", linkPath)
+			for _, targetPath := range targetPaths {
+				if _, err := kubeconfig.Read(targetPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					logrus.WithError(err).Fatalf("kubeConfig: %s can not be merged", targetPath)
+				}
 			}
-			logrus.Infof("Verified kubeConfig: %s, it only contains Rancher Desktop configuration", linkPath)
+			logrus.Infof("Verified kubeConfig: %s, Rancher Desktop configuration can be merged", targetPaths)
 			os.Exit(0)
 		}
 
-		if configPath == "" {
+		if len(configPaths) == 0 {
 			return errors.New("Windows kubeconfig not supplied")
 		}
 
-		_, err := os.Stat(configPath)
-		if err != nil {
-			return fmt.Errorf("could not open Windows kubeconfig: %w", err)
+		// Earlier versions symlinked the Windows kubeconfig; remove the link, as
+		// we would otherwise write into the Windows kubeconfig.
+		if target, err := os.Readlink(linkPath); err == nil && slices.Contains(configPaths, target) {
+			if err = removeConfig(linkPath); err != nil {
+				return err
+			}
 		}
 
-		if !unsupportedConfig && symlinkErr != nil {
-			return symlinkErr
+		if kubeconfigViper.GetBool("cleanup") {
+			return cleanupConfigs(configPaths, targetPaths, enable)
 		}
 
-		if enable {
-			if unsupportedConfig {
-				// Config contains non-Rancher Desktop configuration
-				return symlinkErr
-			}
-			err = os.Mkdir(configDir, 0o750)
-			if err != nil && !errors.Is(err, os.ErrExist) {
-				// The error already contains the full path, we can't do better.
-				return err
-			}
-			err = os.Symlink(configPath, linkPath)
-			if err != nil {
-				if errors.Is(err, os.ErrExist) {
-					// If it already exists, do nothing; even if it's not a symlink.
-					return nil
-				}
-				return err
-			}
-		} else {
-			// No need to create if we want to remove it
-			target, err := os.Readlink(linkPath)
-			if err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					return nil
-				}
-				return err
-			}
-			if target == configPath {
-				if err = removeConfig(linkPath); err != nil {
+		if !enable {
+			for _, targetPath := range targetPaths {
+				err := kubeconfig.Update(targetPath, func(config *kubeconfig.Config) (bool, error) {
+					return config.Remove(rdCluster), nil
+				})
+				if err != nil {
 					return err
 				}
 			}
+			return nil
 		}
-		return nil
+
+		source, err := readSourceConfig(configPaths)
+		if err != nil {
+			return err
+		}
+		return kubeconfig.Update(kubeconfig.SelectFile(targetPaths, rdCluster), func(config *kubeconfig.Config) (bool, error) {
+			return config.Sync(source, rdCluster)
+		})
 	},
 }
 
-// requireManualSymlink checks the config to determine if it contains a single entry for Contexts, Clusters, and Users.
-// If all three are named 'rancher-desktop', we assume that this configuration was written by Rancher Desktop 1.12,
-// and we can remove it and replace it with a symlink. If a user's config contains Rancher Desktop's specific configuration
-// along with user-provided config, or if it only contains user-provided config, we return a true and an error.
-// This indicates through diagnostics to the user that manual action is required.
-func requireManualSymlink(linkPath string) (bool, error) {
-	// Check to see if config is rancher desktop only
-	if existingConfig, err := readKubeConfig(linkPath); err == nil {
-		if len(existingConfig.Contexts) == 1 && existingConfig.Contexts[0].Name == rdCluster &&
-			len(existingConfig.Clusters) == 1 && existingConfig.Clusters[0].Name == rdCluster &&
-			len(existingConfig.Users) == 1 && existingConfig.Users[0].Name == rdCluster {
-			if err := removeConfig(linkPath); err != nil {
-				return false, err
-			}
-		} else {
-			return true, fmt.Errorf("not overwriting kubeconfig file %s with non-Rancher Desktop contents", linkPath)
+// readSourceConfig reads the first Windows kubeconfig (out of a KUBECONFIG
+// style list) that has the Rancher Desktop context.
+func readSourceConfig(configPaths []string) (*kubeconfig.Config, error) {
+	var errs []error
+	for _, configPath := range configPaths {
+		config, err := kubeconfig.Read(configPath)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if config.HasContext(rdCluster) {
+			return config, nil
 		}
 	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("could not open Windows kubeconfig: %w", err)
+	}
+	return nil, fmt.Errorf("Windows kubeconfig %s does not have a %s context", configPaths, rdCluster)
+}
 
-	return false, nil
+// cleanupConfigs removes stale Rancher Desktop entries from the Windows and
+// Linux kubeconfigs.  The rancher-desktop context is kept in the Linux
+// kubeconfigs (if enabled) only if the Windows kubeconfig still has it.
+func cleanupConfigs(configPaths, targetPaths []string, enable bool) error {
+	keep := ""
+	if enable {
+		if _, err := readSourceConfig(configPaths); err == nil {
+			keep = rdCluster
+		}
+	}
+	cleanup := func(configPath, keep string) error {
+		if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return kubeconfig.Update(configPath, func(config *kubeconfig.Config) (bool, error) {
+			removed := config.Cleanup(keep)
+			if len(removed) > 0 {
+				logrus.WithField("path", configPath).WithField("removed", removed).Info("Removed stale kubeconfig entries")
+			}
+			return len(removed) > 0, nil
+		})
+	}
+	for _, configPath := range configPaths {
+		// The Windows kubeconfig is the source of truth for the live context.
+		if err := cleanup(configPath, rdCluster); err != nil {
+			return err
+		}
+	}
+	for _, targetPath := range targetPaths {
+		if err := cleanup(targetPath, keep); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func removeConfig(configPath string) error {
@@ -142,25 +180,12 @@ func removeConfig(configPath string) error {
 	return nil
 }
 
-func readKubeConfig(configPath string) (kubeConfig, error) {
-	var config kubeConfig
-	configFile, err := os.Open(configPath)
-	if err != nil {
-		return config, fmt.Errorf("could not open kubeconfig file %s: %w", configPath, err)
-	}
-	defer configFile.Close()
-	err = yaml.NewDecoder(configFile).Decode(&config)
-	if err != nil {
-		return config, fmt.Errorf("could not read kubeconfig %s: %w", configPath, err)
-	}
-
-	return config, nil
-}
-
 func init() {
-	kubeconfigCmd.PersistentFlags().Bool("verify", false, "Checks whether the symlinked config contains non-Rancher Desktop configuration.")
+	kubeconfigCmd.PersistentFlags().Bool("verify", false, "Checks whether the Rancher Desktop configuration can be merged into the existing config.")
 	kubeconfigCmd.PersistentFlags().Bool("enable", true, "Set up config file")
+	kubeconfigCmd.PersistentFlags().Bool("cleanup", false, "Remove stale Rancher Desktop entries instead of setting up the config file")
 	kubeconfigCmd.PersistentFlags().String("kubeconfig", "", "Path to Windows kubeconfig, in /mnt/... form.")
+	kubeconfigCmd.PersistentFlags().String("target", "", "KUBECONFIG-style list of files to merge into (default ~/.kube/config).")
 	kubeconfigViper.AutomaticEnv()
 	if err := kubeconfigViper.BindPFlags(kubeconfigCmd.PersistentFlags()); err != nil {
 		logrus.WithError(err).Fatal("Failed to set up flags")
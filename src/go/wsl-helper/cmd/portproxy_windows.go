@@ -0,0 +1,142 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/portproxy"
+)
+
+var (
+	portproxyAddViper    = viper.New()
+	portproxyDeleteViper = viper.New()
+)
+
+// portproxyCmd represents the `portproxy` command.
+var portproxyCmd = &cobra.Command{
+	Use:   "portproxy",
+	Short: "Manage Windows portproxy entries for forwarded ports",
+	Long: `Manage Windows portproxy entries (and matching firewall rules) for ports
+forwarded from containers.  Only entries created by Rancher Desktop are ever
+changed or removed.  These commands require administrator privileges.`,
+}
+
+// portproxyListCmd represents the `portproxy list` command.
+var portproxyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List portproxy entries as JSON",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		entries, err := portproxy.NewManager().List(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if entries == nil {
+			entries = []portproxy.Entry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	},
+}
+
+// portproxyAddCmd represents the `portproxy add` command.
+var portproxyAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Create or update a portproxy entry",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		entry := portproxyEntry(cmd, portproxyAddViper)
+		entry.ConnectAddress = portproxyAddViper.GetString("connect-address")
+		entry.ConnectPort = portproxyAddViper.GetInt("connect-port")
+		if entry.ConnectPort == 0 {
+			entry.ConnectPort = entry.ListenPort
+		}
+		return portproxy.NewManager().Add(cmd.Context(), entry, portproxyAddViper.GetBool("firewall"))
+	},
+}
+
+// portproxyDeleteCmd represents the `portproxy delete` command.
+var portproxyDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a portproxy entry",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return portproxy.NewManager().Delete(cmd.Context(), portproxyEntry(cmd, portproxyDeleteViper))
+	},
+}
+
+// portproxyPruneCmd represents the `portproxy prune` command.
+var portproxyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete all portproxy entries created by Rancher Desktop",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		removed, err := portproxy.NewManager().Prune(cmd.Context())
+		for _, entry := range removed {
+			logrus.Infof("Removed portproxy %s %s:%d", entry.Family, entry.ListenAddress, entry.ListenPort)
+		}
+		return err
+	},
+}
+
+// portproxyEntry returns the entry identified by the command line flags.
+func portproxyEntry(cmd *cobra.Command, v *viper.Viper) portproxy.Entry {
+	return portproxy.Entry{
+		Family:        cmd.Flags().Lookup("family").Value.String(),
+		ListenAddress: v.GetString("listen-address"),
+		ListenPort:    v.GetInt("listen-port"),
+	}
+}
+
+func init() {
+	for _, command := range []*cobra.Command{portproxyAddCmd, portproxyDeleteCmd} {
+		command.Flags().Var(&enumValue{val: "v4tov4", allowed: portproxy.Families}, "family", "Address families to listen on and connect to")
+		command.Flags().String("listen-address", "0.0.0.0", "Address to listen on")
+		command.Flags().Int("listen-port", 0, "Port to listen on")
+		if err := command.MarkFlagRequired("listen-port"); err != nil {
+			logrus.WithError(err).Fatal("Failed to set up flags")
+		}
+	}
+	portproxyAddCmd.Flags().String("connect-address", "", "Address to forward connections to")
+	portproxyAddCmd.Flags().Int("connect-port", 0, "Port to forward connections to (default the listen port)")
+	portproxyAddCmd.Flags().Bool("firewall", true, "Add a firewall rule allowing inbound connections")
+	if err := portproxyAddCmd.MarkFlagRequired("connect-address"); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	for command, v := range map[*cobra.Command]*viper.Viper{
+		portproxyAddCmd:    portproxyAddViper,
+		portproxyDeleteCmd: portproxyDeleteViper,
+	} {
+		v.AutomaticEnv()
+		if err := v.BindPFlags(command.Flags()); err != nil {
+			logrus.WithError(err).Fatal("Failed to set up flags")
+		}
+	}
+	portproxyCmd.AddCommand(portproxyListCmd, portproxyAddCmd, portproxyDeleteCmd, portproxyPruneCmd)
+	rootCmd.AddCommand(portproxyCmd)
+}
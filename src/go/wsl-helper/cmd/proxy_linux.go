@@ -0,0 +1,105 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/proxy"
+)
+
+var proxyApplyViper = viper.New()
+
+// proxyCmd represents the `proxy` command.
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Commands for managing proxy settings",
+}
+
+// proxyApplyCmd represents the `proxy apply` command.
+var proxyApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Export host proxy settings into this distribution",
+	Long: `Export proxy settings (in the JSON form produced by the Windows
+"wsl-helper proxy" command) as HTTP_PROXY, HTTPS_PROXY and NO_PROXY, for login
+shells and for the given container engine services; applying settings without a
+proxy removes them again.  Services must be restarted to pick up changes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		var data []byte
+		var err error
+		if file := proxyApplyViper.GetString("file"); file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read proxy settings: %w", err)
+		}
+		var settings proxy.Settings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("failed to parse proxy settings: %w", err)
+		}
+		result, err := proxy.Apply(settings, proxy.ApplyOptions{
+			ProfilePath:     proxyApplyViper.GetString("profile"),
+			OpenRCServices:  proxyApplyViper.GetStringSlice("openrc-service"),
+			SystemdServices: proxyApplyViper.GetStringSlice("systemd-service"),
+		})
+		if err != nil {
+			return err
+		}
+		if result.Reload {
+			if err := proxyReloadSystemd(cmd.Context()); err != nil {
+				logrus.WithError(err).Warn("Failed to reload systemd configuration")
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	},
+}
+
+// proxyReloadSystemd asks systemd to reload its configuration, so that changed
+// drop-ins take effect on the next service restart.
+func proxyReloadSystemd(ctx context.Context) error {
+	reloadCmd := exec.CommandContext(ctx, "systemctl", "daemon-reload")
+	reloadCmd.Stdout = os.Stderr
+	reloadCmd.Stderr = os.Stderr
+	return reloadCmd.Run()
+}
+
+func init() {
+	proxyApplyCmd.Flags().String("file", "-", "File containing the proxy settings, or - for stdin")
+	proxyApplyCmd.Flags().String("profile", proxy.DefaultProfilePath, "Shell profile script to write, or empty to skip")
+	proxyApplyCmd.Flags().StringSlice("openrc-service", nil, "OpenRC services to configure")
+	proxyApplyCmd.Flags().StringSlice("systemd-service", nil, "systemd services to configure")
+	proxyApplyViper.AutomaticEnv()
+	if err := proxyApplyViper.BindPFlags(proxyApplyCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	proxyCmd.AddCommand(proxyApplyCmd)
+	rootCmd.AddCommand(proxyCmd)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/proxy"
+)
+
+// proxyCmd represents the `proxy` command.
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Show the system proxy settings as JSON",
+	Long: `Show the system proxy settings as JSON, in the form expected by the Linux
+"wsl-helper proxy apply" command.  The Internet Options settings (including
+simple proxy auto-configuration scripts) are used if set, falling back to the
+WinHTTP settings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		settings, err := proxy.GetSystemSettings(cmd.Context(), logrus.NewEntry(logrus.StandardLogger()))
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	},
+}
+
+// proxyWatchCmd represents the `proxy watch` command.
+var proxyWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch for changes to the system proxy settings",
+	Long: `Watch for changes to the system proxy settings, emitting them as one JSON
+object per line at the start and each time they change, including when the
+network changes.  Consumers should pass each line to "wsl-helper proxy apply".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, windows.SIGTERM)
+		defer stop()
+
+		encoder := json.NewEncoder(os.Stdout)
+		return proxy.WatchSystemSettings(ctx, log, func(settings proxy.Settings) {
+			log.WithField("source", settings.Source).Debug("Proxy settings changed")
+			if err := encoder.Encode(settings); err != nil {
+				log.WithError(err).Error("failed to write event")
+			}
+		})
+	},
+}
+
+func init() {
+	proxyCmd.AddCommand(proxyWatchCmd)
+	rootCmd.AddCommand(proxyCmd)
+}
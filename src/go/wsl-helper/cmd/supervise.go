@@ -0,0 +1,100 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/supervisor"
+)
+
+var superviseViper = viper.New()
+
+// superviseCmd represents the `supervise` command.
+var superviseCmd = &cobra.Command{
+	Use:   "supervise [flags] -- command [args...]",
+	Short: "Run a long-running helper, restarting it if it exits",
+	Long: `Run a long-running helper (such as the docker proxy), restarting it with
+exponential backoff whenever it exits.  Events (started, exited, restarting,
+reset, gave-up, stopped) are written to standard output as one JSON object per
+line, so that the host application can display them; the output of the helper
+itself is written to standard error.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		name := superviseViper.GetString("name")
+		if name == "" {
+			name = args[0]
+		}
+		var encoderLock sync.Mutex
+		encoder := json.NewEncoder(os.Stdout)
+		emit := func(event any) {
+			encoderLock.Lock()
+			defer encoderLock.Unlock()
+			if err := encoder.Encode(event); err != nil {
+				log.WithError(err).Error("failed to write event")
+			}
+		}
+
+		reset, err := superviseResetSource(ctx, cmd, log, name, emit)
+		if err != nil {
+			return err
+		}
+		return supervisor.Run(ctx, supervisor.Options{
+			Name:           name,
+			Command:        args,
+			Stdout:         os.Stderr,
+			Stderr:         os.Stderr,
+			InitialBackoff: superviseViper.GetDuration("initial-backoff"),
+			MaxBackoff:     superviseViper.GetDuration("max-backoff"),
+			StableAfter:    superviseViper.GetDuration("stable-after"),
+			MaxRestarts:    superviseViper.GetInt("max-restarts"),
+			StopTimeout:    superviseViper.GetDuration("stop-timeout"),
+			Reset:          reset,
+			Events: func(event supervisor.Event) {
+				log.WithField("event", event.Type).WithField("name", event.Name).Debug("supervisor event")
+				emit(event)
+			},
+		})
+	},
+}
+
+func init() {
+	superviseCmd.Flags().String("name", "", "Name of the helper, for events (default: the command)")
+	superviseCmd.Flags().Duration("initial-backoff", supervisor.DefaultInitialBackoff, "Delay before the first restart")
+	superviseCmd.Flags().Duration("max-backoff", supervisor.DefaultMaxBackoff, "Maximum delay between restarts")
+	superviseCmd.Flags().Duration("stable-after", supervisor.DefaultStableAfter, "Reset the backoff once the helper has run for this long")
+	superviseCmd.Flags().Int("max-restarts", 0, "Give up after this many consecutive restarts (0 for no limit)")
+	superviseCmd.Flags().Duration("stop-timeout", supervisor.DefaultStopTimeout, "How long to wait for the helper to exit before killing it")
+	superviseViper.AutomaticEnv()
+	if err := superviseViper.BindPFlags(superviseCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	rootCmd.AddCommand(superviseCmd)
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// superviseResetSource returns a channel that is signalled when the helper
+// should be restarted to re-establish its sockets; there is no such source
+// outside of Windows.
+func superviseResetSource(ctx context.Context, cmd *cobra.Command, log *logrus.Entry, name string, emit func(any)) (<-chan struct{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,74 @@
+//go:build windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	wslutils "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/wsl-utils"
+)
+
+// superviseResetSource returns a channel that is signalled when the WSL VM
+// starts again after having been stopped (e.g. by `wsl --shutdown`), so that
+// the helper is restarted to re-establish its sockets; this is only done if
+// --watch-vm is given.  Changes in the VM state are also emitted as events.
+func superviseResetSource(ctx context.Context, cmd *cobra.Command, log *logrus.Entry, name string, emit func(any)) (<-chan struct{}, error) {
+	watch, err := cmd.Flags().GetBool("watch-vm")
+	if err != nil || !watch {
+		return nil, err
+	}
+	interval, err := cmd.Flags().GetDuration("watch-vm-interval")
+	if err != nil {
+		return nil, err
+	}
+	reset := make(chan struct{}, 1)
+	go func() {
+		stopped := false
+		err := wslutils.WatchVM(ctx, log, interval, func(running bool) {
+			emit(struct {
+				Time    time.Time `json:"time"`
+				Name    string    `json:"name"`
+				Type    string    `json:"type"`
+				Running bool      `json:"running"`
+			}{Time: time.Now(), Name: name, Type: "vm", Running: running})
+			if !running {
+				stopped = true
+			} else if stopped {
+				stopped = false
+				select {
+				case reset <- struct{}{}:
+				default:
+				}
+			}
+		})
+		if err != nil {
+			log.WithError(err).Error("failed to watch the WSL VM")
+		}
+	}()
+	return reset, nil
+}
+
+func init() {
+	superviseCmd.Flags().Bool("watch-vm", false, "Restart the helper when the WSL VM restarts")
+	superviseCmd.Flags().Duration("watch-vm-interval", 5*time.Second, "How often to check whether the WSL VM is running")
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var wslDrivesViper = viper.New()
+
+// wslDrivesCmd represents the `wsl drives` command.
+var wslDrivesCmd = &cobra.Command{
+	Use:   "drives",
+	Short: "Check that Windows drives are mounted",
+	Long: `Check that Windows drives are mounted (under /mnt, or the automount root
+from /etc/wsl.conf) and accessible; bind mounts from Windows paths fail if they
+are not.  The drives given with --drive are checked, along with any Windows
+drives currently mounted; empty mount points are ignored.  The "check" mode
+only reports; "repair" also tries to remount broken
+drives, and reports whether the distribution must be restarted instead.  With
+--watch, the check is repeated at the given interval, and the report is emitted
+(as one JSON object per line) whenever it changes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		mode := cmd.Flags().Lookup("mode").Value.String()
+		options := integration.DrivesOptions{
+			Drives: wslDrivesViper.GetStringSlice("drive"),
+		}
+		var run func() (integration.DrivesReport, error)
+		switch mode {
+		case "check":
+			run = func() (integration.DrivesReport, error) { return integration.CheckDrives(options) }
+		case "repair":
+			run = func() (integration.DrivesReport, error) { return integration.RepairDrives(cmd.Context(), options) }
+		default:
+			return fmt.Errorf("unknown operation %q", mode)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+
+		if !wslDrivesViper.GetBool("watch") {
+			report, err := run()
+			if err != nil {
+				return err
+			}
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, unix.SIGTERM)
+		defer stop()
+		ticker := time.NewTicker(wslDrivesViper.GetDuration("interval"))
+		defer ticker.Stop()
+		var last *integration.DrivesReport
+		for {
+			report, err := run()
+			if err != nil {
+				logrus.WithError(err).Error("Failed to check drives")
+			} else if last == nil || !reflect.DeepEqual(*last, report) {
+				last = &report
+				if !report.Healthy {
+					logrus.WithField("drives", report.Drives).Warn("Windows drives are not mounted")
+				}
+				if err := encoder.Encode(report); err != nil {
+					logrus.WithError(err).Error("Failed to write report")
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	wslDrivesCmd.Flags().Var(&enumValue{val: "check", allowed: []string{"check", "repair"}}, "mode", "Operation mode")
+	wslDrivesCmd.Flags().StringSlice("drive", nil, "Drive letters expected to be mounted (default c)")
+	wslDrivesCmd.Flags().Bool("watch", false, "Keep checking until interrupted")
+	wslDrivesCmd.Flags().Duration("interval", 30*time.Second, "How often to check when watching")
+	wslDrivesViper.AutomaticEnv()
+	if err := wslDrivesViper.BindPFlags(wslDrivesCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslCmd.AddCommand(wslDrivesCmd)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/unix"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var wslIntegrationDNSViper = viper.New()
+
+// wslIntegrationDNSCmd represents the `wsl integration dns` command.
+var wslIntegrationDNSCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Work around DNS breakage caused by VPNs",
+	Long: `Detect when none of the nameservers in /etc/resolv.conf are reachable
+(usually because a VPN on the host routes them away), and redirect name
+resolution through the host resolver instead.  The "show" mode reports the
+current state; "fix" applies the change, or restores the original configuration
+once the nameservers are reachable again; "revert" restores the original
+configuration unconditionally.  With --watch, "fix" is repeated at the given
+interval, and the original configuration is restored on exit.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		mode := cmd.Flags().Lookup("mode").Value.String()
+		options := integration.DNSOptions{
+			Timeout: wslIntegrationDNSViper.GetDuration("timeout"),
+		}
+		if resolvers := wslIntegrationDNSViper.GetStringSlice("resolver"); len(resolvers) > 0 {
+			options.Resolvers = resolvers
+		}
+		encoder := json.NewEncoder(os.Stdout)
+
+		switch mode {
+		case "show":
+			status, err := integration.CheckDNS(cmd.Context(), options)
+			if err != nil {
+				return err
+			}
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(status)
+		case "revert":
+			status, err := integration.RevertDNS(options)
+			if err != nil {
+				return err
+			}
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(status)
+		case "fix":
+		default:
+			return fmt.Errorf("unknown operation %q", mode)
+		}
+
+		if !wslIntegrationDNSViper.GetBool("watch") {
+			status, err := integration.FixDNS(cmd.Context(), options)
+			if err != nil {
+				return err
+			}
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(status)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, unix.SIGTERM)
+		defer stop()
+		ticker := time.NewTicker(wslIntegrationDNSViper.GetDuration("interval"))
+		defer ticker.Stop()
+		for {
+			status, err := integration.FixDNS(ctx, options)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to check DNS")
+			} else if status.Action != integration.DNSActionNone {
+				if err := encoder.Encode(status); err != nil {
+					logrus.WithError(err).Error("Failed to write status")
+				}
+			}
+			select {
+			case <-ctx.Done():
+				if _, err := integration.RevertDNS(options); err != nil {
+					return err
+				}
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	wslIntegrationDNSCmd.Flags().Var(&enumValue{val: "show", allowed: []string{"show", "fix", "revert"}}, "mode", "Operation mode")
+	wslIntegrationDNSCmd.Flags().StringSlice("resolver", nil, "Host resolver addresses to try (default WSL DNS tunneling and the default gateway)")
+	wslIntegrationDNSCmd.Flags().Duration("timeout", integration.DefaultDNSProbeTimeout, "How long to wait for each nameserver to respond")
+	wslIntegrationDNSCmd.Flags().Bool("watch", false, "Keep checking until interrupted (with --mode=fix)")
+	wslIntegrationDNSCmd.Flags().Duration("interval", 30*time.Second, "How often to check when watching")
+	wslIntegrationDNSViper.AutomaticEnv()
+	if err := wslIntegrationDNSViper.BindPFlags(wslIntegrationDNSCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslIntegrationCmd.AddCommand(wslIntegrationDNSCmd)
+}
@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
 	"github.com/sirupsen/logrus"
@@ -51,6 +52,40 @@ var wslIntegrationDockerPluginCmd = &cobra.Command{
 			return err
 		}
 
+		installDir := filepath.Join(homeDir, ".docker", "cli-plugins")
+		var results []integration.InstallResult
+		if state && wslIntegrationDockerPluginViper.GetBool("install") {
+			wrapperDir := wslIntegrationDockerPluginViper.GetString("wrapper-dir")
+			if wrapperDir == "" {
+				wrapperDir = filepath.Join(homeDir, ".local", "bin")
+			}
+			var wrappers []string
+			if binDir != "" {
+				if _, err := os.Stat(filepath.Join(binDir, "nerdctl")); err == nil {
+					wrappers = append(wrappers, filepath.Join(binDir, "nerdctl"))
+				}
+			}
+			results, err = integration.InstallDockerPlugins(cmd.Context(), integration.PluginInstallOptions{
+				SourceDir:  pluginDir,
+				PluginDir:  installDir,
+				Wrappers:   wrappers,
+				WrapperDir: wrapperDir,
+			})
+		} else {
+			results, err = integration.UninstallDockerPlugins(installDir)
+		}
+		for _, result := range results {
+			logrus.WithFields(logrus.Fields{
+				"name":     result.Name,
+				"path":     result.Path,
+				"version":  result.Version,
+				"previous": result.Previous,
+			}).Infof("docker CLI plugin %s", result.Action)
+		}
+		if err != nil {
+			return err
+		}
+
 		return nil
 	},
 }
@@ -59,6 +94,8 @@ func init() {
 	wslIntegrationDockerPluginCmd.Flags().String("plugin-dir", "", "Full path to plugin directory")
 	wslIntegrationDockerPluginCmd.Flags().String("bin-dir", "", "Full path to bin directory to clean up deprecated links")
 	wslIntegrationDockerPluginCmd.Flags().Bool("state", false, "Desired state")
+	wslIntegrationDockerPluginCmd.Flags().Bool("install", false, "Copy plugins (and the nerdctl wrapper) into the distribution instead of only configuring the plugin directory")
+	wslIntegrationDockerPluginCmd.Flags().String("wrapper-dir", "", "Directory to install the nerdctl wrapper into (default ~/.local/bin)")
 	if err := wslIntegrationDockerPluginCmd.MarkFlagRequired("plugin-dir"); err != nil {
 		logrus.WithError(err).Fatal("Failed to set up flags")
 	}
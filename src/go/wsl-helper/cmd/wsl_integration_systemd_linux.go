@@ -0,0 +1,112 @@
+/*
+Copyright © 2023 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var wslIntegrationSystemdViper = viper.New()
+
+// wslIntegrationSystemdCmd represents the `wsl integration systemd` command.
+var wslIntegrationSystemdCmd = &cobra.Command{
+	Use:   "systemd [-- serve arguments...]",
+	Short: "Manage systemd user units for WSL integration",
+	Long: `Manage systemd user units for Rancher Desktop WSL distro integration.
+In distributions with systemd enabled, the docker socket is provided by a
+systemd user socket unit (which starts the docker socket proxy on demand) rather
+than by a process started for each session.  The socket is in the user's
+runtime directory, and the rancher-desktop docker CLI context is set up to use
+it.  The "show" mode (the default) prints whether systemd is enabled in
+/etc/wsl.conf; "install" and "remove" manage the units and the docker context.
+Any arguments are passed to "docker-proxy serve".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		mode := cmd.Flags().Lookup("mode").Value.String()
+		executable := wslIntegrationSystemdViper.GetString("executable")
+		if executable == "" {
+			var err error
+			if executable, err = os.Executable(); err != nil {
+				return fmt.Errorf("failed to locate wsl-helper: %w", err)
+			}
+		}
+		socket := wslIntegrationSystemdViper.GetString("docker-socket")
+		if socket == "" {
+			socket = integration.SystemdSocket()
+		}
+		options := integration.SystemdOptions{
+			Executable: executable,
+			Args:       args,
+			Socket:     socket,
+		}
+		switch mode {
+		case "show":
+			enabled, err := integration.SystemdEnabled(integration.DefaultWSLConfPath)
+			if err != nil {
+				return err
+			}
+			fmt.Println(enabled)
+			return nil
+		case "install":
+			logrus.Trace("Installing wsl integration systemd units")
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("failed to locate home directory: %w", err)
+			}
+			if err := integration.InstallSystemdUnits(cmd.Context(), options); err != nil {
+				return err
+			}
+			// Nothing serves the default socket any more, so switch to ours.
+			return integration.SetupDockerContext(homeDir, "unix://"+socket, true)
+		case "remove":
+			logrus.Trace("Removing wsl integration systemd units")
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("failed to locate home directory: %w", err)
+			}
+			installed, err := integration.SystemdUnitsInstalled(options)
+			if err != nil || !installed {
+				return err
+			}
+			if err := integration.RemoveSystemdUnits(cmd.Context(), options); err != nil {
+				return err
+			}
+			return integration.RemoveDockerContext(homeDir)
+		default:
+			return fmt.Errorf("unknown operation %q", mode)
+		}
+	},
+}
+
+func init() {
+	wslIntegrationSystemdCmd.Flags().Var(&enumValue{val: "show", allowed: []string{"show", "install", "remove"}}, "mode", "Operation mode")
+	wslIntegrationSystemdCmd.Flags().String("executable", "", "Full path to the wsl-helper to run (default this executable)")
+	wslIntegrationSystemdCmd.Flags().String("docker-socket", "", "Path of the docker socket to listen on (default docker.sock in the user runtime directory)")
+	wslIntegrationSystemdViper.AutomaticEnv()
+	if err := wslIntegrationSystemdViper.BindPFlags(wslIntegrationSystemdCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslIntegrationCmd.AddCommand(wslIntegrationSystemdCmd)
+}
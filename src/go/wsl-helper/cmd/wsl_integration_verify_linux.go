@@ -0,0 +1,83 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var wslIntegrationVerifyViper = viper.New()
+
+// wslIntegrationVerifyCmd represents the `wsl integration verify` command
+var wslIntegrationVerifyCmd = &cobra.Command{
+	Use:   "verify [distro]",
+	Short: "Check that WSL integration is working in this distribution",
+	Long: `Check that Rancher Desktop WSL integration is working in this distribution,
+printing a JSON report with remediation hints.  This must be run inside the
+distribution; if a distribution name is given, it must match.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		distro := os.Getenv("WSL_DISTRO_NAME")
+		if len(args) > 0 {
+			if distro != "" && args[0] != distro {
+				return fmt.Errorf("cannot verify distribution %q from inside %q; run this with `wsl.exe --distribution %s`", args[0], distro, args[0])
+			}
+			distro = args[0]
+		}
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to locate home directory: %w", err)
+		}
+
+		report := integration.Verify(cmd.Context(), integration.VerifyOptions{
+			Distro:       distro,
+			HomeDir:      homeDir,
+			DockerSocket: wslIntegrationVerifyViper.GetString("docker-socket"),
+			PluginDir:    wslIntegrationVerifyViper.GetString("plugin-dir"),
+		})
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+		if !report.Healthy {
+			return errors.New("WSL integration is not working")
+		}
+		return nil
+	},
+}
+
+func init() {
+	wslIntegrationVerifyCmd.Flags().String("docker-socket", integration.DefaultDockerSocket, "Path to the docker socket")
+	wslIntegrationVerifyCmd.Flags().String("plugin-dir", "", "Full path to the docker CLI plugin directory to expect")
+	wslIntegrationVerifyViper.AutomaticEnv()
+	if err := wslIntegrationVerifyViper.BindPFlags(wslIntegrationVerifyCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslIntegrationCmd.AddCommand(wslIntegrationVerifyCmd)
+}
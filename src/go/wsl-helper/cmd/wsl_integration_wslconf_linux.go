@@ -0,0 +1,124 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+var wslIntegrationWSLConfViper = viper.New()
+
+// wslIntegrationWSLConfCmd represents the `wsl integration wsl-conf` command.
+var wslIntegrationWSLConfCmd = &cobra.Command{
+	Use:   "wsl-conf",
+	Short: "Manage /etc/wsl.conf",
+	Long: `Read and edit /etc/wsl.conf, preserving comments and formatting.  The
+"show" mode prints the settings in effect; "apply" makes the given changes
+(keeping a backup of the original file, and doing nothing if they are already in
+effect); "restore" puts back the original file.  Settings are given as
+section.key=value (or just section.key with --unset).  --systemd and --integration
+add the settings needed to run systemd, and for WSL integration respectively.
+The distribution must be restarted for changes to take effect.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		mode := cmd.Flags().Lookup("mode").Value.String()
+		path := wslIntegrationWSLConfViper.GetString("path")
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		switch mode {
+		case "show":
+			conf, err := integration.ReadWSLConf(path)
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(conf.Settings())
+		case "restore":
+			result, err := integration.RestoreWSLConf(path)
+			if err != nil {
+				return err
+			}
+			return encoder.Encode(result)
+		case "apply":
+		default:
+			return fmt.Errorf("unknown operation %q", mode)
+		}
+
+		var settings []integration.WSLConfSetting
+		if wslIntegrationWSLConfViper.GetBool("systemd") {
+			settings = append(settings, integration.WSLConfSystemdSettings...)
+		}
+		if wslIntegrationWSLConfViper.GetBool("integration") {
+			settings = append(settings, integration.WSLConfIntegrationSettings...)
+		}
+		// These are read from the flags directly, as viper would split values
+		// containing commas.
+		setInputs, err := cmd.Flags().GetStringArray("set")
+		if err != nil {
+			return err
+		}
+		unsetInputs, err := cmd.Flags().GetStringArray("unset")
+		if err != nil {
+			return err
+		}
+		for _, input := range setInputs {
+			setting, err := integration.ParseWSLConfSetting(input)
+			if err != nil {
+				return err
+			}
+			if setting.Unset {
+				return fmt.Errorf("invalid setting %q: use --unset to remove settings", input)
+			}
+			settings = append(settings, setting)
+		}
+		for _, input := range unsetInputs {
+			setting, err := integration.ParseWSLConfSetting(input)
+			if err != nil {
+				return err
+			}
+			setting.Value, setting.Unset = "", true
+			settings = append(settings, setting)
+		}
+		result, err := integration.ApplyWSLConf(path, settings)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(result)
+	},
+}
+
+func init() {
+	wslIntegrationWSLConfCmd.Flags().Var(&enumValue{val: "show", allowed: []string{"show", "apply", "restore"}}, "mode", "Operation mode")
+	wslIntegrationWSLConfCmd.Flags().String("path", integration.DefaultWSLConfPath, "Path to the WSL configuration file")
+	wslIntegrationWSLConfCmd.Flags().StringArray("set", nil, "Setting to apply, as section.key=value")
+	wslIntegrationWSLConfCmd.Flags().StringArray("unset", nil, "Setting to remove, as section.key")
+	wslIntegrationWSLConfCmd.Flags().Bool("systemd", false, "Enable systemd")
+	wslIntegrationWSLConfCmd.Flags().Bool("integration", false, "Apply the settings needed for WSL integration")
+	wslIntegrationWSLConfViper.AutomaticEnv()
+	if err := wslIntegrationWSLConfViper.BindPFlags(wslIntegrationWSLConfCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslIntegrationCmd.AddCommand(wslIntegrationWSLConfCmd)
+}
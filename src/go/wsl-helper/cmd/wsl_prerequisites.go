@@ -0,0 +1,49 @@
+//go:build windows
+
+/*
+Copyright © 2023 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	wslutils "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/wsl-utils"
+)
+
+// wslPrerequisitesCmd represents the `wsl prerequisites` command.
+var wslPrerequisitesCmd = &cobra.Command{
+	Use:   "prerequisites",
+	Short: "Check whether the system can run WSL2",
+	Long: `Report the WSL and kernel versions, whether WSL2 and virtualization are
+enabled, the VM limits from .wslconfig, and whether mirrored networking is
+available, as JSON.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(wslutils.GetPrerequisites(cmd.Context(), log))
+	},
+}
+
+func init() {
+	wslCmd.AddCommand(wslPrerequisitesCmd)
+}
@@ -0,0 +1,80 @@
+//go:build windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	wslutils "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/wsl-utils"
+)
+
+var wslRelocateViper = viper.New()
+
+// wslRelocateCmd represents the `wsl relocate` command.
+var wslRelocateCmd = &cobra.Command{
+	Use:   "relocate",
+	Short: "Move the disk image of a WSL distribution",
+	Long: `Move the disk image of a (stopped) Rancher Desktop WSL distribution into
+another directory, for example on a drive with more free space.  Progress is
+written to standard output as one JSON object per line, followed by the result.
+If the distribution cannot be registered at the new location, it is restored to
+its previous location.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		encoder := json.NewEncoder(os.Stdout)
+		emit := func(value any) {
+			if err := encoder.Encode(value); err != nil {
+				log.WithError(err).Error("failed to write progress")
+			}
+		}
+		var progress func(wslutils.RelocateProgress)
+		if wslRelocateViper.GetBool("progress") {
+			progress = func(p wslutils.RelocateProgress) { emit(p) }
+		}
+		result, err := wslutils.RelocateDistro(cmd.Context(), log,
+			wslRelocateViper.GetString("distro"),
+			wslRelocateViper.GetString("target"),
+			progress)
+		if err != nil {
+			return err
+		}
+		emit(result)
+		return nil
+	},
+}
+
+func init() {
+	wslRelocateCmd.Flags().String("distro", wslutils.DefaultRelocateDistro, "Distribution to move")
+	wslRelocateCmd.Flags().String("target", "", "Directory to move the disk image into")
+	wslRelocateCmd.Flags().Bool("progress", true, "Report progress")
+	if err := wslRelocateCmd.MarkFlagRequired("target"); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslRelocateViper.AutomaticEnv()
+	if err := wslRelocateViper.BindPFlags(wslRelocateCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslCmd.AddCommand(wslRelocateCmd)
+}
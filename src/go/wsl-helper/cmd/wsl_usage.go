@@ -0,0 +1,64 @@
+//go:build windows
+
+/*
+Copyright © 2023 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	wslutils "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/wsl-utils"
+)
+
+var wslUsageViper = viper.New()
+
+// wslUsageCmd represents the `wsl usage` command.
+var wslUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report resource usage of the WSL VM",
+	Long: `Report the memory and CPU used by the WSL2 utility VM, and the size of the
+disk images of the Rancher Desktop distributions, as JSON.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		usage, err := wslutils.GetResourceUsage(cmd.Context(), log,
+			wslUsageViper.GetStringSlice("distro"),
+			wslUsageViper.GetDuration("interval"))
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usage)
+	},
+}
+
+func init() {
+	wslUsageCmd.Flags().StringSlice("distro", []string{"rancher-desktop", "rancher-desktop-data"}, "Distributions to report disk usage for")
+	wslUsageCmd.Flags().Duration("interval", time.Second, "Time to sample CPU usage over")
+	wslUsageViper.AutomaticEnv()
+	if err := wslUsageViper.BindPFlags(wslUsageCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslCmd.AddCommand(wslUsageCmd)
+}
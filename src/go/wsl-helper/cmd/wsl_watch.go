@@ -0,0 +1,91 @@
+//go:build windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/signal"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/sys/windows"
+
+	wslutils "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/wsl-utils"
+)
+
+var wslWatchViper = viper.New()
+
+// wslWatchCmd represents the `wsl watch` command.
+var wslWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch for WSL distributions being registered or unregistered",
+	Long: `Watch for WSL distributions being registered or unregistered, emitting
+one JSON object per line for each change.  If --integrate is given, the
+integration state marker is set in newly registered WSL 2 distributions by
+running the given (Linux) wsl-helper executable inside them.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		log := logrus.NewEntry(logrus.StandardLogger())
+		helper := wslWatchViper.GetString("integrate")
+		exclude := wslWatchViper.GetStringSlice("exclude")
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, windows.SIGTERM)
+		defer stop()
+
+		encoder := json.NewEncoder(os.Stdout)
+		return wslutils.WatchDistros(ctx, log, func(event wslutils.DistroEvent) {
+			if slices.Contains(exclude, event.Distro.Name) {
+				return
+			}
+			entry := log.WithField("distro", event.Distro.Name)
+			entry.WithField("event", event.Type).Debug("WSL distribution changed")
+			if event.Type == wslutils.DistroRegistered && helper != "" && event.Distro.Version == 2 {
+				// Don't touch distributions where integration is already known.
+				if event.Distro.Integrated == nil || !*event.Distro.Integrated {
+					err := wslutils.NewWSLRunner().
+						WithStdout(os.Stderr).
+						WithStderr(os.Stderr).
+						Run(ctx, "--distribution", event.Distro.Name, "--exec", helper, "wsl", "integration", "state", "--mode=set")
+					if err != nil {
+						entry.WithError(err).Error("failed to set integration state")
+					} else {
+						integrated := true
+						event.Distro.Integrated = &integrated
+					}
+				}
+			}
+			if err := encoder.Encode(event); err != nil {
+				entry.WithError(err).Error("failed to write event")
+			}
+		})
+	},
+}
+
+func init() {
+	wslWatchCmd.Flags().String("integrate", "", "Path (inside the distribution) to the Linux wsl-helper used to apply integration")
+	wslWatchCmd.Flags().StringSlice("exclude", []string{"rancher-desktop", "rancher-desktop-data"}, "Distributions to ignore")
+	wslWatchViper.AutomaticEnv()
+	if err := wslWatchViper.BindPFlags(wslWatchCmd.Flags()); err != nil {
+		logrus.WithError(err).Fatal("Failed to set up flags")
+	}
+	wslCmd.AddCommand(wslWatchCmd)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certificates is used to enumerate the system certificate authorities
+// on Windows, and to install them into the trust stores of Linux distributions.
+package certificates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Dedupe returns the given certificates with duplicates and expired
+// certificates removed, sorted by their SHA-256 fingerprints so that the
+// result is stable.
+func Dedupe(certs []*x509.Certificate) []*x509.Certificate {
+	now := time.Now()
+	seen := make(map[string]*x509.Certificate, len(certs))
+	for _, cert := range certs {
+		if cert == nil || cert.NotAfter.Before(now) {
+			continue
+		}
+		seen[fingerprint(cert)] = cert
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	result := make([]*x509.Certificate, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, seen[key])
+	}
+	return result
+}
+
+// Fingerprint returns a digest identifying a set of certificates, independent
+// of their order; it can be used to detect whether the set has changed.
+func Fingerprint(certs []*x509.Certificate) string {
+	sums := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		sums = append(sums, fingerprint(cert))
+	}
+	slices.Sort(sums)
+	sums = slices.Compact(sums)
+	hash := sha256.New()
+	for _, sum := range sums {
+		_, _ = hash.Write([]byte(sum))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// fingerprint returns the hex-encoded SHA-256 digest of a certificate.
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// EncodePEM returns the given certificates as PEM-encoded data.
+func EncodePEM(certs []*x509.Certificate) []byte {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		_ = pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
+
+// ParsePEM parses PEM-encoded certificates; blocks that are not certificates
+// are ignored.
+func ParsePEM(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	var errs []error
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse certificate: %w", err))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs, errors.Join(errs...)
+}
@@ -14,8 +14,6 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package certificates is used to enumerate the system certificate authorities
-// on Windows.
 package certificates
 
 import (
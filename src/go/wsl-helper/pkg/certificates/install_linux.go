@@ -0,0 +1,251 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/atomicfile"
+)
+
+const (
+	// DefaultContainerdCertsDir is the default containerd registry host
+	// configuration directory.
+	DefaultContainerdCertsDir = "/etc/containerd/certs.d"
+	// managedPrefix is the file name prefix for certificates we install into a
+	// trust store; any files with this prefix are removed if they are no longer
+	// in the set of certificates to install.
+	managedPrefix = "rancher-desktop-"
+	// containerdBundleName is the name of the CA bundle in the containerd
+	// default host configuration directory.
+	containerdBundleName = "rancher-desktop-ca.crt"
+	// containerdMarker is written at the start of any hosts.toml we manage, so
+	// that we never overwrite one the user has written.
+	containerdMarker = "# Managed by Rancher Desktop; changes will be overwritten.\n"
+)
+
+// TrustStore describes where a Linux distribution keeps locally added trusted
+// root certificates, and how the system bundle is regenerated from them.
+type TrustStore struct {
+	Name   string   // Distributions using this layout, for logging.
+	Dir    string   // Directory holding additional trust anchors.
+	Update []string // Command to regenerate the system bundle.
+}
+
+// DefaultTrustStores lists the known trust store layouts, in detection order.
+var DefaultTrustStores = []TrustStore{
+	{Name: "debian", Dir: "/usr/local/share/ca-certificates", Update: []string{"update-ca-certificates"}},
+	{Name: "fedora", Dir: "/etc/pki/ca-trust/source/anchors", Update: []string{"update-ca-trust", "extract"}},
+	{Name: "opensuse", Dir: "/etc/pki/trust/anchors", Update: []string{"update-ca-certificates"}},
+	{Name: "arch", Dir: "/etc/ca-certificates/trust-source/anchors", Update: []string{"update-ca-trust"}},
+}
+
+// InstallOptions control how certificates are installed.
+type InstallOptions struct {
+	// Stores are the candidate trust store layouts; the first one that exists
+	// is used.  Defaults to DefaultTrustStores.
+	Stores []TrustStore
+	// ContainerdDir is the containerd registry host configuration directory to
+	// install the certificates into; if empty, containerd is not configured.
+	ContainerdDir string
+}
+
+// InstallResult describes the changes made by Install.
+type InstallResult struct {
+	Store      string `json:"store"`      // The trust store layout used.
+	Added      int    `json:"added"`      // Number of certificates added or updated.
+	Removed    int    `json:"removed"`    // Number of certificates removed.
+	Containerd bool   `json:"containerd"` // Whether the containerd configuration changed.
+}
+
+// lookCommand finds the given command; it also looks in the sbin directories,
+// as they are frequently missing from PATH for non-interactive shells.
+func lookCommand(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err == nil || strings.ContainsRune(name, os.PathSeparator) {
+		return path, err
+	}
+	for _, dir := range []string{"/usr/local/sbin", "/usr/sbin", "/sbin"} {
+		if candidate, candidateErr := exec.LookPath(filepath.Join(dir, name)); candidateErr == nil {
+			return candidate, nil
+		}
+	}
+	return "", err
+}
+
+// DetectTrustStore returns the first of the given trust stores whose anchor
+// directory exists and whose update command is available.
+func DetectTrustStore(stores []TrustStore) (*TrustStore, error) {
+	for _, store := range stores {
+		if info, err := os.Stat(store.Dir); err != nil || !info.IsDir() {
+			continue
+		}
+		if _, err := lookCommand(store.Update[0]); err != nil {
+			logrus.WithError(err).Debugf("Skipping %s trust store %s", store.Name, store.Dir)
+			continue
+		}
+		return &store, nil
+	}
+	return nil, errors.New("could not find a supported certificate trust store")
+}
+
+// writeFileIfChanged atomically replaces the file with the given contents,
+// unless it already has those contents.  Returns whether the file was written.
+func writeFileIfChanged(path string, contents []byte, mode os.FileMode) (bool, error) {
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, contents) {
+		return false, nil
+	}
+	if err := atomicfile.WriteFile(path, contents, mode); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Install the given certificates into the system trust store, replacing any
+// certificates previously installed by this function; the system bundle is
+// regenerated if anything changed.  Installing an empty set of certificates
+// removes everything previously installed.
+func Install(ctx context.Context, certs []*x509.Certificate, options InstallOptions) (*InstallResult, error) {
+	stores := options.Stores
+	if stores == nil {
+		stores = DefaultTrustStores
+	}
+	store, err := DetectTrustStore(stores)
+	if err != nil {
+		return nil, err
+	}
+	result := &InstallResult{Store: store.Name}
+	log := logrus.WithField("store", store.Dir)
+
+	certs = Dedupe(certs)
+	desired := make(map[string]*x509.Certificate, len(certs))
+	for _, cert := range certs {
+		desired[managedPrefix+fingerprint(cert)[:16]+".crt"] = cert
+	}
+
+	entries, err := os.ReadDir(store.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store %s: %w", store.Dir, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, managedPrefix) || !strings.HasSuffix(name, ".crt") {
+			continue
+		}
+		if _, ok := desired[name]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(store.Dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale certificate %s: %w", name, err)
+		}
+		log.WithField("file", name).Trace("Removed stale certificate")
+		result.Removed++
+	}
+	for name, cert := range desired {
+		written, err := writeFileIfChanged(filepath.Join(store.Dir, name), EncodePEM([]*x509.Certificate{cert}), 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to install certificate %s: %w", name, err)
+		}
+		if written {
+			log.WithField("file", name).WithField("subject", cert.Subject.String()).Trace("Installed certificate")
+			result.Added++
+		}
+	}
+
+	if result.Added > 0 || result.Removed > 0 {
+		command, err := lookCommand(store.Update[0])
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.CommandContext(ctx, command, store.Update[1:]...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w: %s", strings.Join(store.Update, " "), err, strings.TrimSpace(string(output)))
+		}
+		log.Debugf("Updated trust store: %d added, %d removed", result.Added, result.Removed)
+	}
+
+	if options.ContainerdDir != "" {
+		result.Containerd, err = installContainerd(options.ContainerdDir, certs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// installContainerd configures containerd to trust the given certificates for
+// all registries, via the default host configuration.  If the user has written
+// their own default host configuration, only the bundle is written.  Returns
+// whether anything changed.
+func installContainerd(certsDir string, certs []*x509.Certificate) (bool, error) {
+	dir := filepath.Join(certsDir, "_default")
+	bundlePath := filepath.Join(dir, containerdBundleName)
+	hostsPath := filepath.Join(dir, "hosts.toml")
+
+	hostsContents, err := os.ReadFile(hostsPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to read %s: %w", hostsPath, err)
+	}
+	managed := err != nil || bytes.HasPrefix(hostsContents, []byte(containerdMarker))
+
+	if len(certs) == 0 {
+		changed := false
+		for _, path := range []string{bundlePath, hostsPath} {
+			if path == hostsPath && !managed {
+				continue
+			}
+			if err := os.Remove(path); err == nil {
+				changed = true
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return false, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+		}
+		// Remove the directory if it's now empty; ignore any errors.
+		_ = os.Remove(dir)
+		return changed, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	changed, err := writeFileIfChanged(bundlePath, EncodePEM(certs), 0o644)
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", bundlePath, err)
+	}
+	if !managed {
+		logrus.Warnf("Not modifying user-managed %s; add ca = %q to use host certificates", hostsPath, bundlePath)
+		return changed, nil
+	}
+	hosts := fmt.Sprintf("%sca = %q\n", containerdMarker, bundlePath)
+	hostsChanged, err := writeFileIfChanged(hostsPath, []byte(hosts), 0o644)
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", hostsPath, err)
+	}
+	return changed || hostsChanged, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTrustStore creates a fake trust store whose update command appends to
+// a log file, returning the store and the log file path.
+func setupTrustStore(t *testing.T) (TrustStore, string) {
+	dir := t.TempDir()
+	anchors := filepath.Join(dir, "anchors")
+	require.NoError(t, os.Mkdir(anchors, 0o755))
+	logPath := filepath.Join(dir, "update.log")
+	script := filepath.Join(dir, "update")
+	contents := "#!/bin/sh\necho \"$@\" >> " + logPath + "\n"
+	require.NoError(t, os.WriteFile(script, []byte(contents), 0o755))
+	return TrustStore{Name: "test", Dir: anchors, Update: []string{script, "extract"}}, logPath
+}
+
+// updateCount returns how many times the fake update command was run.
+func updateCount(t *testing.T, logPath string) int {
+	data, err := os.ReadFile(logPath)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return strings.Count(string(data), "extract\n")
+}
+
+func TestDetectTrustStore(t *testing.T) {
+	store, _ := setupTrustStore(t)
+	missing := TrustStore{Name: "missing", Dir: filepath.Join(t.TempDir(), "missing"), Update: store.Update}
+	noCommand := TrustStore{Name: "no-command", Dir: store.Dir, Update: []string{filepath.Join(t.TempDir(), "missing")}}
+
+	result, err := DetectTrustStore([]TrustStore{missing, noCommand, store})
+	require.NoError(t, err)
+	assert.Equal(t, "test", result.Name)
+
+	_, err = DetectTrustStore([]TrustStore{missing, noCommand})
+	assert.Error(t, err)
+}
+
+func TestInstall(t *testing.T) {
+	ctx := context.Background()
+	store, logPath := setupTrustStore(t)
+	containerdDir := filepath.Join(t.TempDir(), "certs.d")
+	options := InstallOptions{Stores: []TrustStore{store}, ContainerdDir: containerdDir}
+	future := time.Now().Add(24 * time.Hour)
+	first := newTestCertificate(t, "first", future)
+	second := newTestCertificate(t, "second", future)
+	hostsPath := filepath.Join(containerdDir, "_default", "hosts.toml")
+	bundlePath := filepath.Join(containerdDir, "_default", containerdBundleName)
+
+	// Unrelated files in the trust store must be preserved.
+	userCert := filepath.Join(store.Dir, "user.crt")
+	require.NoError(t, os.WriteFile(userCert, EncodePEM([]*x509.Certificate{first}), 0o644))
+
+	result, err := Install(ctx, []*x509.Certificate{first, second}, options)
+	require.NoError(t, err)
+	assert.Equal(t, &InstallResult{Store: "test", Added: 2, Containerd: true}, result)
+	assert.Equal(t, 1, updateCount(t, logPath))
+	matches, err := filepath.Glob(filepath.Join(store.Dir, managedPrefix+"*.crt"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+	bundle, err := os.ReadFile(bundlePath)
+	require.NoError(t, err)
+	parsed, err := ParsePEM(bundle)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*x509.Certificate{first, second}, parsed)
+	hosts, err := os.ReadFile(hostsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(hosts), `ca = "`+bundlePath+`"`)
+
+	t.Run("unchanged", func(t *testing.T) {
+		result, err := Install(ctx, []*x509.Certificate{second, first}, options)
+		require.NoError(t, err)
+		assert.Equal(t, &InstallResult{Store: "test"}, result)
+		assert.Equal(t, 1, updateCount(t, logPath), "update should not run if nothing changed")
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		result, err := Install(ctx, []*x509.Certificate{first}, options)
+		require.NoError(t, err)
+		assert.Equal(t, &InstallResult{Store: "test", Removed: 1, Containerd: true}, result)
+		assert.Equal(t, 2, updateCount(t, logPath))
+	})
+
+	t.Run("user hosts.toml", func(t *testing.T) {
+		userHosts := "server = \"https://registry.example\"\n"
+		require.NoError(t, os.WriteFile(hostsPath, []byte(userHosts), 0o644))
+		_, err := Install(ctx, []*x509.Certificate{first, second}, options)
+		require.NoError(t, err)
+		hosts, err := os.ReadFile(hostsPath)
+		require.NoError(t, err)
+		assert.Equal(t, userHosts, string(hosts), "user-managed hosts.toml should not be modified")
+		require.NoError(t, os.Remove(hostsPath))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := Install(ctx, nil, options)
+		require.NoError(t, err)
+		matches, err := filepath.Glob(filepath.Join(store.Dir, managedPrefix+"*"))
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+		assert.FileExists(t, userCert)
+		assert.NoDirExists(t, filepath.Join(containerdDir, "_default"))
+	})
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// watchSettleDelay is how long to wait after a registry change before
+	// enumerating certificates, as changes are often made in batches.
+	watchSettleDelay = 500 * time.Millisecond
+	// watchPollInterval is how often the watcher checks for cancellation while
+	// waiting for registry changes.
+	watchPollInterval = time.Second
+)
+
+var (
+	// kStoreKeysOverride overrides the registry locations watched; the value is
+	// a []storeKey.
+	kStoreKeysOverride = struct{}{}
+	// kListOverride overrides the function used to enumerate certificates; the
+	// value is a func([]string) ([]*x509.Certificate, error).
+	kListOverride = struct{}{}
+)
+
+// storeKey is a registry location that holds system certificate stores; each
+// store is a subkey of it.
+type storeKey struct {
+	root registry.Key
+	path string
+}
+
+// defaultStoreKeys are the registry locations that back the system
+// certificate stores, including those managed by group policy.
+var defaultStoreKeys = []storeKey{
+	{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\SystemCertificates`},
+	{registry.LOCAL_MACHINE, `SOFTWARE\Policies\Microsoft\SystemCertificates`},
+	{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\EnterpriseCertificates`},
+	{registry.CURRENT_USER, `SOFTWARE\Microsoft\SystemCertificates`},
+	{registry.CURRENT_USER, `SOFTWARE\Policies\Microsoft\SystemCertificates`},
+}
+
+// ListSystemCertificates returns the unexpired certificates from the given
+// certificate stores, without duplicates.
+func ListSystemCertificates(storeNames []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for _, storeName := range storeNames {
+		ch, err := GetSystemCertificates(storeName)
+		if err != nil {
+			return nil, err
+		}
+		for entry := range ch {
+			if entry.Err != nil {
+				// Drain the channel so the store gets closed.
+				for range ch {
+				}
+				return nil, entry.Err
+			}
+			certs = append(certs, entry.Cert)
+		}
+	}
+	return Dedupe(certs), nil
+}
+
+// WatchSystemCertificates watches the given certificate stores, calling the
+// handler with the current certificates at the start and whenever they change.
+// This blocks until the context is cancelled.
+func WatchSystemCertificates(ctx context.Context, log *logrus.Entry, storeNames []string, handler func([]*x509.Certificate)) error {
+	roots := defaultStoreKeys
+	if v := ctx.Value(&kStoreKeysOverride); v != nil {
+		roots = v.([]storeKey)
+	}
+	list := ListSystemCertificates
+	if v := ctx.Value(&kListOverride); v != nil {
+		list = v.(func([]string) ([]*x509.Certificate, error))
+	}
+
+	var keys []registry.Key
+	defer func() {
+		for _, key := range keys {
+			_ = key.Close()
+		}
+	}()
+	for _, root := range roots {
+		for _, storeName := range storeNames {
+			path := root.path + `\` + storeName
+			key, err := registry.OpenKey(root.root, path, registry.NOTIFY)
+			if err != nil {
+				if !errors.Is(err, registry.ErrNotExist) {
+					log.WithError(err).WithField("key", path).Debug("Failed to open certificate store key")
+				}
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("failed to find any certificate stores to watch")
+	}
+
+	event, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(event) }()
+
+	known := ""
+	update := func() {
+		certs, err := list(storeNames)
+		if err != nil {
+			log.WithError(err).Error("failed to list system certificates")
+			return
+		}
+		if current := Fingerprint(certs); current != known {
+			known = current
+			handler(certs)
+		}
+	}
+
+	const filter = windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET
+	for {
+		// Ask for notification before listing, so that no changes are missed.
+		for _, key := range keys {
+			if err := windows.RegNotifyChangeKeyValue(windows.Handle(key), true, filter, event, true); err != nil {
+				return fmt.Errorf("failed to watch certificate store: %w", err)
+			}
+		}
+		update()
+		for {
+			result, err := windows.WaitForSingleObject(event, uint32(watchPollInterval.Milliseconds()))
+			if err != nil {
+				return fmt.Errorf("failed waiting for certificate store changes: %w", err)
+			}
+			if ctx.Err() != nil {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return nil
+				}
+				return ctx.Err()
+			}
+			if result == windows.WAIT_OBJECT_0 {
+				break
+			}
+		}
+		log.Trace("certificate store changed")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchSettleDelay):
+		}
+	}
+}
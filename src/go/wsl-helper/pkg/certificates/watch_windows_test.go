@@ -0,0 +1,99 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestWatchSystemCertificates(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, t.Name(), os.Getpid())
+	testKey, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath+`\ROOT`, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\ROOT`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	defer testKey.Close()
+
+	future := time.Now().Add(24 * time.Hour)
+	first := newTestCertificate(t, "first", future)
+	second := newTestCertificate(t, "second", future)
+	var mutex sync.Mutex
+	current := []*x509.Certificate{first}
+	setCerts := func(certs ...*x509.Certificate) {
+		mutex.Lock()
+		current = certs
+		mutex.Unlock()
+		require.NoError(t, testKey.SetStringValue("changed", time.Now().String()))
+	}
+
+	ctx := context.WithValue(context.Background(), &kStoreKeysOverride, []storeKey{{registry.CURRENT_USER, keyPath}})
+	ctx = context.WithValue(ctx, &kListOverride, func(storeNames []string) ([]*x509.Certificate, error) {
+		assert.Equal(t, []string{"ROOT"}, storeNames)
+		mutex.Lock()
+		defer mutex.Unlock()
+		return Dedupe(current), nil
+	})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan []*x509.Certificate, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchSystemCertificates(ctx, logrus.NewEntry(logger), []string{"ROOT"}, func(certs []*x509.Certificate) {
+			changes <- certs
+		})
+	}()
+	next := func() []*x509.Certificate {
+		select {
+		case certs := <-changes:
+			return certs
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for change")
+			return nil
+		}
+	}
+
+	assert.Equal(t, []*x509.Certificate{first}, next())
+
+	// Registry changes that don't change the certificates are not reported.
+	setCerts(first)
+	time.Sleep(2 * watchSettleDelay)
+	assert.Empty(t, changes)
+
+	setCerts(first, second)
+	assert.ElementsMatch(t, []*x509.Certificate{first, second}, next())
+
+	cancel()
+	assert.NoError(t, <-done)
+}
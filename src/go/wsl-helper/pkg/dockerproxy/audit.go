@@ -0,0 +1,255 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// defaultAuditLogMaxSize is the size at which the audit log is rotated, if not
+// otherwise specified.
+const defaultAuditLogMaxSize = 10 * 1024 * 1024
+
+// auditRecord is a single line in the audit log.
+type auditRecord struct {
+	Time       time.Time         `json:"time"`
+	Client     platform.PeerInfo `json:"client"`
+	RemoteAddr string            `json:"remoteAddr,omitempty"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Endpoint   string            `json:"endpoint"`
+	Query      string            `json:"query,omitempty"`
+	Request    *auditRequestBody `json:"request,omitempty"`
+	Status     int               `json:"status"`
+	Upgraded   bool              `json:"upgraded,omitempty"`
+	Duration   float64           `json:"durationSeconds"`
+}
+
+// auditRequestBody is the security-relevant summary of a request body.
+type auditRequestBody struct {
+	Image       string   `json:"image,omitempty"`
+	Cmd         []string `json:"cmd,omitempty"`
+	User        string   `json:"user,omitempty"`
+	Privileged  bool     `json:"privileged,omitempty"`
+	PidMode     string   `json:"pidMode,omitempty"`
+	NetworkMode string   `json:"networkMode,omitempty"`
+	Binds       []string `json:"binds,omitempty"`
+	Mounts      []string `json:"mounts,omitempty"`
+	CapAdd      []string `json:"capAdd,omitempty"`
+	Devices     []string `json:"devices,omitempty"`
+}
+
+// auditedBody is the subset of the container create / exec create request
+// bodies we parse for auditing.
+type auditedBody struct {
+	Image      string
+	Cmd        []string
+	User       string
+	Privileged bool
+	HostConfig struct {
+		Privileged  bool
+		PidMode     string
+		NetworkMode string
+		Binds       []string
+		Mounts      []struct {
+			Type   string
+			Source string
+			Target string
+		}
+		CapAdd  []string
+		Devices []struct {
+			PathOnHost      string
+			PathInContainer string
+		}
+	}
+}
+
+// auditLog writes audit records to a JSON-lines file, rotating it when it
+// gets too large.
+type auditLog struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+	sync.Mutex
+}
+
+// newAuditLog opens the audit log at the given path; if maxSize is not
+// positive, a default is used.  At most maxBackups rotated files are kept.
+func newAuditLog(path string, maxSize int64, maxBackups int) (*auditLog, error) {
+	if maxSize <= 0 {
+		maxSize = defaultAuditLogMaxSize
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("could not create audit log directory: %w", err)
+	}
+	log := &auditLog{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := log.open(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+// open the log file for appending; this should be called with the lock held.
+func (l *auditLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("could not open audit log %s: %w", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("could not stat audit log %s: %w", l.path, err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// rotate the log file; this should be called with the lock held.
+func (l *auditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("could not close audit log %s: %w", l.path, err)
+	}
+	if l.maxBackups < 1 {
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove audit log %s: %w", l.path, err)
+		}
+		return l.open()
+	}
+	for i := l.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not rotate audit log %s: %w", l.path, err)
+		}
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return fmt.Errorf("could not rotate audit log %s: %w", l.path, err)
+	}
+	return l.open()
+}
+
+// write a record to the log.
+func (l *auditLog) write(record *auditRecord) error {
+	buf, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("could not serialize audit record: %w", err)
+	}
+	buf = append(buf, '\n')
+
+	l.Lock()
+	defer l.Unlock()
+	if l.size > 0 && l.size+int64(len(buf)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(buf)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("could not write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close the audit log.
+func (l *auditLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	return l.file.Close()
+}
+
+// wrapHandler returns a handler that records each request passed to the
+// given handler.
+func (l *auditLog) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		record := &auditRecord{
+			Time:       start,
+			Client:     peerFromContext(req.Context()),
+			RemoteAddr: req.RemoteAddr,
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Endpoint:   apiEndpoint(req.URL.Path),
+			Query:      req.URL.RawQuery,
+			Request:    summarizeRequestBody(req),
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			record.Status = recorder.Status()
+			record.Upgraded = recorder.hijacked
+			record.Duration = time.Since(start).Seconds()
+			if err := l.write(record); err != nil {
+				requestLogger(req.Context()).WithError(err).Error("could not write audit log")
+			}
+		}()
+		handler.ServeHTTP(recorder, req)
+	})
+}
+
+// summarizeRequestBody extracts the security-relevant parts of the request
+// body for endpoints that create containers or exec sessions.  The request
+// body is replaced so that it can still be forwarded.
+func summarizeRequestBody(req *http.Request) *auditRequestBody {
+	if req.Method != http.MethodPost {
+		return nil
+	}
+	switch apiEndpoint(req.URL.Path) {
+	case "/containers/create", "/containers/{id}/exec":
+	default:
+		return nil
+	}
+
+	buf, err := peekRequestBody(req)
+	if err != nil || buf == nil {
+		return nil
+	}
+
+	var body auditedBody
+	if err := json.Unmarshal(buf, &body); err != nil {
+		return nil
+	}
+	summary := &auditRequestBody{
+		Image:       body.Image,
+		Cmd:         body.Cmd,
+		User:        body.User,
+		Privileged:  body.Privileged || body.HostConfig.Privileged,
+		PidMode:     body.HostConfig.PidMode,
+		NetworkMode: body.HostConfig.NetworkMode,
+		Binds:       body.HostConfig.Binds,
+		CapAdd:      body.HostConfig.CapAdd,
+	}
+	for _, mount := range body.HostConfig.Mounts {
+		summary.Mounts = append(summary.Mounts, fmt.Sprintf("%s:%s:%s", mount.Type, mount.Source, mount.Target))
+	}
+	for _, device := range body.HostConfig.Devices {
+		summary.Devices = append(summary.Devices, fmt.Sprintf("%s:%s", device.PathOnHost, device.PathInContainer))
+	}
+	return summary
+}
@@ -0,0 +1,95 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAuditRecords(t *testing.T, path string) []auditRecord {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var records []auditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record auditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Run("records container create", func(t *testing.T) {
+		logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+		audit, err := newAuditLog(logPath, 0, 0)
+		require.NoError(t, err)
+		body := `{"Image":"alpine","HostConfig":{"Privileged":true,"Binds":["/:/host"]}}`
+		var forwarded string
+		handler := audit.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			buf, err := io.ReadAll(req.Body)
+			assert.NoError(t, err)
+			forwarded = string(buf)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/v1.41/containers/create?name=evil", strings.NewReader(body))
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		require.NoError(t, audit.Close())
+
+		assert.Equal(t, body, forwarded, "request body should be forwarded unchanged")
+		records := readAuditRecords(t, logPath)
+		require.Len(t, records, 1)
+		record := records[0]
+		assert.Equal(t, "/containers/create", record.Endpoint)
+		assert.Equal(t, "name=evil", record.Query)
+		assert.Equal(t, http.StatusCreated, record.Status)
+		if assert.NotNil(t, record.Request) {
+			assert.Equal(t, "alpine", record.Request.Image)
+			assert.True(t, record.Request.Privileged)
+			assert.Equal(t, []string{"/:/host"}, record.Request.Binds)
+		}
+	})
+	t.Run("rotates", func(t *testing.T) {
+		logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+		audit, err := newAuditLog(logPath, 1, 2)
+		require.NoError(t, err)
+		for _, path := range []string{"/one", "/two", "/three", "/four"} {
+			require.NoError(t, audit.write(&auditRecord{Path: path}))
+		}
+		require.NoError(t, audit.Close())
+
+		assert.Equal(t, "/four", readAuditRecords(t, logPath)[0].Path)
+		assert.Equal(t, "/three", readAuditRecords(t, logPath+".1")[0].Path)
+		assert.Equal(t, "/two", readAuditRecords(t, logPath+".2")[0].Path)
+		assert.NoFileExists(t, logPath+".3")
+	})
+}
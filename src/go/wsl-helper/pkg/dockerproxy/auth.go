@@ -0,0 +1,111 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// authenticator rejects requests from clients that have not presented either
+// a valid bearer token or a verified TLS client certificate.  This is used
+// when listening on TCP, where the socket permissions can't be relied upon.
+type authenticator struct {
+	// tokens are the SHA-256 hashes of the accepted bearer tokens; we compare
+	// hashes so that the comparison takes the same time for all tokens.
+	tokens [][sha256.Size]byte
+	logger logrus.FieldLogger
+}
+
+// newAuthenticator creates an authenticator accepting the given bearer tokens,
+// as well as any verified client certificate.
+func newAuthenticator(tokens []string, logger logrus.FieldLogger) *authenticator {
+	a := &authenticator{logger: logger}
+	for _, token := range tokens {
+		a.tokens = append(a.tokens, sha256.Sum256([]byte(token)))
+	}
+	return a
+}
+
+// LoadAuthTokenFile reads bearer tokens from the given file, one per line;
+// empty lines and lines starting with "#" are ignored.
+func LoadAuthTokenFile(tokenPath string) ([]string, error) {
+	file, err := os.Open(tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read auth token file %s: %w", tokenPath, err)
+	}
+	defer file.Close()
+	var tokens []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read auth token file %s: %w", tokenPath, err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("auth token file %s does not contain any tokens", tokenPath)
+	}
+	return tokens, nil
+}
+
+// authenticated checks if the request has valid credentials.
+func (a *authenticator) authenticated(req *http.Request) bool {
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	hash := sha256.Sum256([]byte(token))
+	valid := 0
+	for _, expected := range a.tokens {
+		valid |= subtle.ConstantTimeCompare(hash[:], expected[:])
+	}
+	return valid == 1
+}
+
+// wrapHandler returns a handler that rejects unauthenticated requests.
+func (a *authenticator) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !a.authenticated(req) {
+			a.logger.
+				WithField("remote_addr", req.RemoteAddr).
+				Warn("rejected unauthenticated request")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="docker-proxy"`)
+			writeErrorResponse(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		// Don't pass the credentials on to the backend.
+		req.Header.Del("Authorization")
+		handler.ServeHTTP(w, req)
+	})
+}
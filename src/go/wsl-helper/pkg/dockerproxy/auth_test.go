@@ -0,0 +1,89 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	var forwarded http.Header
+	handler := newAuthenticator([]string{"secret", "other"}, logger).wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		forwarded = req.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(authorization string, state *tls.ConnectionState) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		req.TLS = state
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve("Bearer secret", nil).Code)
+	assert.Empty(t, forwarded.Get("Authorization"), "credentials should not be forwarded")
+	assert.Equal(t, http.StatusOK, serve("Bearer other", nil).Code)
+
+	missing := serve("", nil)
+	assert.Equal(t, http.StatusUnauthorized, missing.Code)
+	assert.NotEmpty(t, missing.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, serve("Bearer wrong", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("Basic secret", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("", &tls.ConnectionState{}).Code)
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+	assert.Equal(t, http.StatusOK, serve("", verified).Code)
+}
+
+func TestLoadAuthTokenFile(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("# comment\n\n  first \nsecond\n"), 0o600))
+	tokens, err := LoadAuthTokenFile(tokenPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, tokens)
+
+	require.NoError(t, os.WriteFile(tokenPath, []byte("# nothing\n"), 0o600))
+	_, err = LoadAuthTokenFile(tokenPath)
+	assert.Error(t, err)
+}
+
+func TestCheckTCPSecurity(t *testing.T) {
+	endpoint := "tcp://127.0.0.1:2375"
+	assert.ErrorContains(t, checkTCPSecurity(endpoint, &ServeOptions{}, true),
+		"requires authentication tokens or client certificates")
+	assert.ErrorContains(t, checkTCPSecurity(endpoint, &ServeOptions{AuthTokens: []string{"secret"}}, false),
+		"without TLS would send authentication tokens unencrypted")
+	assert.NoError(t, checkTCPSecurity(endpoint, &ServeOptions{AuthTokens: []string{"secret"}, InsecureTCP: true}, false))
+	assert.NoError(t, checkTCPSecurity(endpoint, &ServeOptions{AuthTokens: []string{"secret"}}, true))
+	assert.NoError(t, checkTCPSecurity(endpoint, &ServeOptions{TLSVerifyClients: true}, true))
+}
@@ -0,0 +1,239 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxCachedResponseSize is the largest response body that will be cached.
+const maxCachedResponseSize = 4 * 1024 * 1024
+
+// uncachedHeaders are response headers that describe a single response, and
+// so are not stored in the cache.
+var uncachedHeaders = []string{"Date", requestIDHeader}
+
+// cachedEndpoints are the API endpoints (for GET requests) that may be served
+// from the response cache.
+var cachedEndpoints = map[string]struct{}{
+	"/containers/json": {},
+	"/images/json":     {},
+	"/info":            {},
+}
+
+// responseCache is a short-lived cache of responses to frequently polled
+// read-only endpoints.  It is invalidated whenever a mutating request passes
+// through the proxy, or the backend reports an event; while the backend event
+// stream is not connected, nothing is served from the cache.
+type responseCache struct {
+	ttl time.Duration
+	// watching is set while we are subscribed to backend events.
+	watching atomic.Bool
+
+	entries map[string]*cacheEntry
+	// generation is incremented on every invalidation, so that responses that
+	// were in progress at the time are not cached.
+	generation uint64
+	sync.Mutex
+}
+
+// cacheEntry is a single cached response.
+type cacheEntry struct {
+	header  http.Header
+	status  int
+	body    []byte
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cacheEntry)}
+}
+
+// invalidate discards all cached responses.
+func (c *responseCache) invalidate() {
+	c.Lock()
+	defer c.Unlock()
+	c.entries = make(map[string]*cacheEntry)
+	c.generation++
+}
+
+// lookup returns the cached response for the given key, if it is still fresh,
+// as well as the current generation.
+func (c *responseCache) lookup(key string, now time.Time) (*cacheEntry, uint64) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[key]
+	if ok && now.After(entry.expires) {
+		delete(c.entries, key)
+		entry = nil
+	}
+	return entry, c.generation
+}
+
+// store a response, unless the cache has been invalidated since the given
+// generation.
+func (c *responseCache) store(key string, generation uint64, entry *cacheEntry) {
+	c.Lock()
+	defer c.Unlock()
+	if c.generation == generation {
+		c.entries[key] = entry
+	}
+}
+
+// wrapHandler returns a handler that serves cacheable requests from the cache
+// where possible, and invalidates the cache on mutating requests.
+func (c *responseCache) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			c.invalidate()
+			defer c.invalidate()
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if _, ok := cachedEndpoints[apiEndpoint(req.URL.Path)]; !ok || req.Method != http.MethodGet || !c.watching.Load() {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		key := req.URL.RequestURI()
+		entry, generation := c.lookup(key, time.Now())
+		if entry != nil {
+			requestLogger(req.Context()).Debug("serving response from cache")
+			// Headers set by earlier middleware (such as the request ID) are
+			// for this request, and take precedence.
+			for name, values := range entry.header {
+				if _, ok := w.Header()[name]; !ok {
+					w.Header()[name] = values
+				}
+			}
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+
+		recorder := &cachingResponseWriter{ResponseWriter: w}
+		handler.ServeHTTP(recorder, req)
+		if recorder.status == http.StatusOK && !recorder.overflow {
+			header := w.Header().Clone()
+			for _, name := range uncachedHeaders {
+				header.Del(name)
+			}
+			c.store(key, generation, &cacheEntry{
+				header:  header,
+				status:  recorder.status,
+				body:    recorder.body.Bytes(),
+				expires: time.Now().Add(c.ttl),
+			})
+		}
+	})
+}
+
+// watchEvents subscribes to the backend event stream at the given URL,
+// invalidating the cache on every event, until the context is cancelled.
+func (c *responseCache) watchEvents(ctx context.Context, client *http.Client, eventsURL string) {
+	for {
+		err := c.watchEventsOnce(ctx, client, eventsURL)
+		if ctx.Err() != nil {
+			return
+		}
+		requestLogger(ctx).WithError(err).Debug("backend event stream ended, retrying")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// watchEventsOnce makes a single connection to the backend event stream,
+// returning when it is disconnected.
+func (c *responseCache) watchEventsOnce(ctx context.Context, client *http.Client, eventsURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, eventsURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	// Anything cached before we started watching may be stale.
+	c.invalidate()
+	c.watching.Store(true)
+	defer c.watching.Store(false)
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event json.RawMessage
+		if err := decoder.Decode(&event); err != nil {
+			return err
+		}
+		c.invalidate()
+	}
+}
+
+// cachingResponseWriter is a http.ResponseWriter that keeps a copy of the
+// response body, so that it can be cached.
+type cachingResponseWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *cachingResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 && statusCode >= http.StatusOK {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *cachingResponseWriter) Write(buf []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.overflow {
+		if w.body.Len()+len(buf) > maxCachedResponseSize {
+			w.overflow = true
+			w.body = bytes.Buffer{}
+		} else {
+			w.body.Write(buf)
+		}
+	}
+	return w.ResponseWriter.Write(buf)
+}
+
+func (w *cachingResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *cachingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
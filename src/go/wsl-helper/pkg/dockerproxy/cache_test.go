@@ -0,0 +1,128 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	cache := newResponseCache(time.Minute)
+	calls := 0
+	handler := cache.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+	serve := func(method, path string) string {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, path, http.NoBody))
+		return recorder.Body.String()
+	}
+
+	// Nothing is cached until we are watching for events.
+	assert.Equal(t, `{"call":1}`, serve(http.MethodGet, "/v1.41/containers/json"))
+	assert.Equal(t, `{"call":2}`, serve(http.MethodGet, "/v1.41/containers/json"))
+
+	cache.watching.Store(true)
+	assert.Equal(t, `{"call":3}`, serve(http.MethodGet, "/v1.41/containers/json"))
+	assert.Equal(t, `{"call":3}`, serve(http.MethodGet, "/v1.41/containers/json"))
+	// Different queries are cached separately.
+	assert.Equal(t, `{"call":4}`, serve(http.MethodGet, "/v1.41/containers/json?all=1"))
+	// Other endpoints are not cached.
+	assert.Equal(t, `{"call":5}`, serve(http.MethodGet, "/v1.41/version"))
+	assert.Equal(t, `{"call":6}`, serve(http.MethodGet, "/v1.41/version"))
+	// Mutating requests invalidate the cache.
+	assert.Equal(t, `{"call":7}`, serve(http.MethodPost, "/v1.41/containers/abc/stop"))
+	assert.Equal(t, `{"call":8}`, serve(http.MethodGet, "/v1.41/containers/json"))
+	assert.Equal(t, `{"call":8}`, serve(http.MethodGet, "/v1.41/containers/json"))
+
+	// Entries expire.
+	_, generation := cache.lookup("/expired", time.Now())
+	cache.store("/expired", generation, &cacheEntry{expires: time.Now().Add(-time.Second)})
+	entry, _ := cache.lookup("/expired", time.Now())
+	assert.Nil(t, entry)
+}
+
+func TestResponseCacheHeaders(t *testing.T) {
+	cache := newResponseCache(time.Minute)
+	cache.watching.Store(true)
+	calls := 0
+	handler := cache.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Date", fmt.Sprintf("call %d", calls))
+		_, _ = fmt.Fprintf(w, `{"call":%d}`, calls)
+	}))
+	serve := func(requestID string) http.Header {
+		recorder := httptest.NewRecorder()
+		// Set the request ID the way the logging middleware would.
+		recorder.Header().Set(requestIDHeader, requestID)
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody))
+		return recorder.Header()
+	}
+
+	header := serve("first")
+	assert.Equal(t, "first", header.Get(requestIDHeader))
+	assert.Equal(t, "call 1", header.Get("Date"))
+
+	header = serve("second")
+	assert.Equal(t, 1, calls, "response should be served from the cache")
+	assert.Equal(t, "second", header.Get(requestIDHeader))
+	assert.Empty(t, header.Get("Date"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+}
+
+func TestResponseCacheEvents(t *testing.T) {
+	events := make(chan string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for event := range events {
+			_, _ = fmt.Fprintln(w, event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+	defer close(events)
+
+	cache := newResponseCache(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cache.watchEvents(ctx, server.Client(), server.URL)
+	assert.Eventually(t, cache.watching.Load, 10*time.Second, 10*time.Millisecond)
+
+	_, generation := cache.lookup("/key", time.Now())
+	cache.store("/key", generation, &cacheEntry{expires: time.Now().Add(time.Minute)})
+	entry, _ := cache.lookup("/key", time.Now())
+	assert.NotNil(t, entry)
+
+	events <- `{"Type":"container","Action":"start"}`
+	assert.Eventually(t, func() bool {
+		entry, _ := cache.lookup("/key", time.Now())
+		return entry == nil
+	}, 10*time.Second, 10*time.Millisecond)
+}
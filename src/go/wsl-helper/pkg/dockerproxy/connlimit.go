@@ -0,0 +1,109 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// connLimitWarningInterval is the minimum time between log messages about
+// the connection limit being reached.
+const connLimitWarningInterval = 10 * time.Second
+
+// limitListener is a listener that only allows a limited number of client
+// connections at once; when the limit is reached, new connections are left
+// queued (in the listen backlog) until an existing one is closed.
+type limitListener struct {
+	net.Listener
+	logger logrus.FieldLogger
+	slots  chan struct{}
+	// lastWarning is the time we last logged about the limit, in Unix
+	// nanoseconds.
+	lastWarning atomic.Int64
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func newLimitListener(listener net.Listener, limit int, logger logrus.FieldLogger) *limitListener {
+	return &limitListener{
+		Listener: listener,
+		logger:   logger,
+		slots:    make(chan struct{}, limit),
+		done:     make(chan struct{}),
+	}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		now := time.Now()
+		if last := l.lastWarning.Load(); now.Sub(time.Unix(0, last)) > connLimitWarningInterval &&
+			l.lastWarning.CompareAndSwap(last, now.UnixNano()) {
+			l.logger.WithField("limit", cap(l.slots)).Warn("Connection limit reached, queueing new connections")
+		}
+		select {
+		case l.slots <- struct{}{}:
+		case <-l.done:
+			return nil, net.ErrClosed
+		}
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.slots
+		return nil, err
+	}
+	return &limitedConn{Conn: conn, release: func() { <-l.slots }}, nil
+}
+
+func (l *limitListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// limitedConn is a connection accepted from a limitListener, which frees its
+// slot when closed.
+type limitedConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+func (c *limitedConn) CloseWrite() error {
+	return utils.CloseWrite(c.Conn)
+}
+
+// NetConn returns the underlying connection, so that peer credentials can be
+// looked up.
+func (c *limitedConn) NetConn() net.Conn {
+	return c.Conn
+}
@@ -0,0 +1,97 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+func TestLimitListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener := newLimitListener(inner, 1, logrus.StandardLogger())
+	defer listener.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	dial()
+	first := <-accepted
+	dial()
+	select {
+	case <-accepted:
+		assert.Fail(t, "accepted a connection over the limit")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, first.Close())
+	select {
+	case second := <-accepted:
+		second.Close()
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "queued connection was not accepted")
+	}
+
+	require.NoError(t, listener.Close())
+	_, ok := <-accepted
+	assert.False(t, ok, "Accept should fail once closed")
+}
+
+func TestLimitListenerPeerInfo(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only available on Linux")
+	}
+	socketPath := filepath.Join(t.TempDir(), "limit.sock")
+	inner, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	listener := newLimitListener(inner, 1, logrus.StandardLogger())
+	defer listener.Close()
+	client, err := net.Dial("unix", socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, os.Getuid(), platform.GetPeerInfo(conn).UID)
+}
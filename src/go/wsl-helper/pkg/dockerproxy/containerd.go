@@ -0,0 +1,71 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// containerdNamespaceHeader is the gRPC metadata key containerd uses to select
+// the namespace for a request.
+const containerdNamespaceHeader = "containerd-namespace"
+
+// grpcStatusUnavailable is the gRPC status code for an unavailable service.
+const grpcStatusUnavailable = 14
+
+// newContainerdHandler returns a handler that forwards (HTTP/2 cleartext)
+// gRPC requests to containerd.
+func newContainerdHandler(dialer func() (net.Conn, error), namespace string, logger logrus.FieldLogger) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = "containerd.invalid"
+			if namespace != "" && req.Header.Get(containerdNamespaceHeader) == "" {
+				req.Header.Set(containerdNamespaceHeader, namespace)
+			}
+		},
+		Transport: &http2.Transport{
+			// containerd speaks HTTP/2 without TLS.
+			AllowHTTP: true,
+			DialTLSContext: func(context.Context, string, string, *tls.Config) (net.Conn, error) {
+				return dialer()
+			},
+		},
+		// gRPC streams must be passed on immediately.
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.WithError(err).WithField("method", req.URL.Path).Error("error proxying containerd request")
+			// Report the error the way gRPC clients expect.
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", strconv.Itoa(grpcStatusUnavailable))
+			w.Header().Set("Grpc-Message", "containerd unavailable: "+err.Error())
+			w.WriteHeader(http.StatusOK)
+		},
+	}
+	return h2c.NewHandler(proxy, &http2.Server{})
+}
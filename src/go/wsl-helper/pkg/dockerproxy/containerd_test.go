@@ -0,0 +1,102 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// newH2CClient returns a client that speaks HTTP/2 without TLS to the server.
+func newH2CClient(server *httptest.Server) *http.Client {
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "tcp", server.Listener.Addr().String())
+			},
+		},
+	}
+}
+
+func TestContainerdHandler(t *testing.T) {
+	backend := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, 2, req.ProtoMajor)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		body, _ := io.ReadAll(req.Body)
+		_, _ = w.Write([]byte(req.Header.Get(containerdNamespaceHeader) + ":" + string(body)))
+		w.Header().Set("Grpc-Status", "0")
+	}), &http2.Server{}))
+	defer backend.Close()
+
+	handler := newContainerdHandler(func() (net.Conn, error) {
+		return net.Dial("tcp", backend.Listener.Addr().String())
+	}, "default", logrus.StandardLogger())
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := newH2CClient(server)
+
+	call := func(namespace string) (string, *http.Response) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/containerd.services.version.v1.Version/Version", strings.NewReader("payload"))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/grpc")
+		if namespace != "" {
+			req.Header.Set(containerdNamespaceHeader, namespace)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body), resp
+	}
+
+	body, resp := call("")
+	assert.Equal(t, "default:payload", body)
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	body, _ = call("k8s.io")
+	assert.Equal(t, "k8s.io:payload", body)
+}
+
+func TestContainerdHandlerUnavailable(t *testing.T) {
+	handler := newContainerdHandler(func() (net.Conn, error) {
+		return nil, errors.New("connection refused")
+	}, "", logrus.StandardLogger())
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	resp, err := newH2CClient(server).Post(server.URL+"/containerd.services.version.v1.Version/Version", "application/grpc", http.NoBody)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "14", resp.Header.Get("Grpc-Status"))
+}
@@ -0,0 +1,216 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+)
+
+// deprecationWarningHeader is the response header used to tell clients about
+// deprecated usage, using warning code 299 ("Miscellaneous Persistent
+// Warning") from RFC 7234.
+const deprecationWarningHeader = "Warning"
+
+// minimumAPIVersion is the oldest API version that is not deprecated.
+var minimumAPIVersion = semver.MustParse("1.24")
+
+// deprecation describes a deprecated part of the docker API.
+type deprecation struct {
+	// method is the HTTP method this applies to.
+	method string
+	// endpoint is the API path template (as in the API specification).
+	endpoint string
+	// since is the API version the deprecation applies from; requests that
+	// negotiate an older API version are not warned.
+	since *semver.Version
+	// query is the deprecated query parameter, if any.
+	query string
+	// field is the path to the deprecated field in the JSON request body, if
+	// any; the field is only considered present if it is not a zero value.
+	field []string
+	// message describes the deprecation, and what to use instead.
+	message string
+}
+
+// deprecations is the list of known deprecations that can be detected from
+// requests.
+var deprecations = []deprecation{
+	{
+		method:   http.MethodPost,
+		endpoint: "/containers/create",
+		since:    semver.MustParse("1.44"),
+		field:    []string{"MacAddress"},
+		message:  "the container MacAddress field is deprecated; use NetworkingConfig.EndpointsConfig.<network>.MacAddress instead",
+	},
+	{
+		method:   http.MethodPost,
+		endpoint: "/containers/create",
+		since:    semver.MustParse("1.42"),
+		field:    []string{"HostConfig", "KernelMemory"},
+		message:  "HostConfig.KernelMemory is deprecated and ignored",
+	},
+	{
+		method:   http.MethodPost,
+		endpoint: "/containers/create",
+		since:    semver.MustParse("1.42"),
+		field:    []string{"HostConfig", "KernelMemoryTCP"},
+		message:  "HostConfig.KernelMemoryTCP is deprecated and ignored",
+	},
+	{
+		method:   http.MethodGet,
+		endpoint: "/images/json",
+		since:    semver.MustParse("1.41"),
+		query:    "filter",
+		message:  `the "filter" query parameter is deprecated; use filters={"reference":[...]} instead`,
+	},
+	{
+		method:   http.MethodGet,
+		endpoint: "/images/search",
+		since:    semver.MustParse("1.44"),
+		query:    "filters",
+		field:    []string{"is-automated"},
+		message:  `the "is-automated" search filter is deprecated`,
+	},
+}
+
+// deprecationChecker warns clients (and logs) when requests use deprecated
+// parts of the docker API, so that users find out before an engine upgrade
+// removes them.
+type deprecationChecker struct {
+	negotiator *versionNegotiator
+	// logged is the set of messages that have already been logged as warnings;
+	// repeats are only logged at debug level.
+	logged sync.Map
+}
+
+func newDeprecationChecker(negotiator *versionNegotiator) *deprecationChecker {
+	return &deprecationChecker{negotiator: negotiator}
+}
+
+// check returns messages for each deprecation the request uses.
+func (c *deprecationChecker) check(req *http.Request) []string {
+	var warnings []string
+	version := c.negotiator.maxVersion()
+	if match := apiVersionPattern.FindString(req.URL.Path); match == "" {
+		if req.URL.Path != "/_ping" {
+			warnings = append(warnings, "using the API without a version prefix is deprecated")
+		}
+	} else if requested, err := semver.NewVersion(strings.Trim(match, "/v")); err == nil {
+		if requested.LessThan(minimumAPIVersion) {
+			warnings = append(warnings, fmt.Sprintf("API version %s is deprecated; the minimum supported version is %s",
+				requested.Original(), minimumAPIVersion.Original()))
+		}
+		if requested.LessThan(version) {
+			version = requested
+		}
+	}
+
+	endpoint := apiEndpoint(req.URL.Path)
+	var body map[string]json.RawMessage
+	var bodyLoaded bool
+	for _, entry := range deprecations {
+		if entry.method != req.Method || entry.endpoint != endpoint {
+			continue
+		}
+		if entry.since != nil && version.LessThan(entry.since) {
+			continue
+		}
+		var present bool
+		switch {
+		case entry.query != "":
+			present = queryHasField(req, entry.query, entry.field)
+		case len(entry.field) > 0:
+			if !bodyLoaded {
+				bodyLoaded = true
+				if buf, err := peekRequestBody(req); err == nil {
+					_ = json.Unmarshal(buf, &body)
+				}
+			}
+			present = jsonHasField(body, entry.field)
+		}
+		if present {
+			warnings = append(warnings, entry.message)
+		}
+	}
+	return warnings
+}
+
+// queryHasField checks if the request has the given query parameter; if a
+// field path is given, the parameter is parsed as JSON (as for "filters") and
+// the field must be present.
+func queryHasField(req *http.Request, param string, field []string) bool {
+	query := req.URL.Query()
+	if !query.Has(param) {
+		return false
+	}
+	if len(field) == 0 {
+		return true
+	}
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(query.Get(param)), &parsed); err != nil {
+		return false
+	}
+	return jsonHasField(parsed, field)
+}
+
+// jsonHasField checks if the given field path exists in the JSON object and
+// does not hold a zero value.
+func jsonHasField(object map[string]json.RawMessage, field []string) bool {
+	for i, name := range field {
+		value, ok := object[name]
+		if !ok {
+			return false
+		}
+		if i == len(field)-1 {
+			switch strings.TrimSpace(string(value)) {
+			case "", "null", "0", `""`, "false", "[]", "{}":
+				return false
+			}
+			return true
+		}
+		object = nil
+		if err := json.Unmarshal(value, &object); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// wrapHandler returns a handler that adds warnings to responses for requests
+// using deprecated parts of the API.
+func (c *deprecationChecker) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, message := range c.check(req) {
+			w.Header().Add(deprecationWarningHeader, fmt.Sprintf("299 - %q", message))
+			entry := requestLogger(req.Context()).WithField("deprecation", message)
+			if _, seen := c.logged.LoadOrStore(message, struct{}{}); seen {
+				entry.Debug("request uses deprecated API")
+			} else {
+				entry.Warn("request uses deprecated API")
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
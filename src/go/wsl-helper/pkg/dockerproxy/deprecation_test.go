@@ -0,0 +1,114 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationChecker(t *testing.T) {
+	checker := newDeprecationChecker(newVersionNegotiator(semver.MustParse("1.47")))
+	cases := map[string]struct {
+		method   string
+		path     string
+		body     string
+		expected []string
+	}{
+		"current": {
+			method: http.MethodGet,
+			path:   "/v1.47/info",
+		},
+		"unversioned": {
+			method:   http.MethodGet,
+			path:     "/info",
+			expected: []string{"using the API without a version prefix is deprecated"},
+		},
+		"unversioned ping": {
+			method: http.MethodGet,
+			path:   "/_ping",
+		},
+		"old version": {
+			method:   http.MethodGet,
+			path:     "/v1.23/info",
+			expected: []string{"API version 1.23 is deprecated; the minimum supported version is 1.24"},
+		},
+		"deprecated field": {
+			method:   http.MethodPost,
+			path:     "/v1.44/containers/create",
+			body:     `{"Image":"alpine","MacAddress":"02:42:ac:11:00:02","HostConfig":{"KernelMemory":0}}`,
+			expected: []string{deprecations[0].message},
+		},
+		"deprecated field before deprecation": {
+			method: http.MethodPost,
+			path:   "/v1.43/containers/create",
+			body:   `{"Image":"alpine","MacAddress":"02:42:ac:11:00:02"}`,
+		},
+		"nested field": {
+			method:   http.MethodPost,
+			path:     "/v1.47/containers/create",
+			body:     `{"HostConfig":{"KernelMemoryTCP":1024}}`,
+			expected: []string{deprecations[2].message},
+		},
+		"query parameter": {
+			method:   http.MethodGet,
+			path:     "/v1.41/images/json?filter=alpine",
+			expected: []string{deprecations[3].message},
+		},
+		"query filter": {
+			method:   http.MethodGet,
+			path:     "/v1.47/images/search?term=alpine&filters=" + url.QueryEscape(`{"is-automated":["true"]}`),
+			expected: []string{deprecations[4].message},
+		},
+		"other query filter": {
+			method: http.MethodGet,
+			path:   "/v1.47/images/search?term=alpine&filters=" + url.QueryEscape(`{"is-official":["true"]}`),
+		},
+	}
+	for name, testCase := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, testCase.path, strings.NewReader(testCase.body))
+			assert.Equal(t, testCase.expected, checker.check(req))
+			if testCase.body != "" {
+				// The body must still be available to forward.
+				body, err := io.ReadAll(req.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.body, string(body))
+			}
+		})
+	}
+}
+
+func TestDeprecationCheckerHeader(t *testing.T) {
+	checker := newDeprecationChecker(newVersionNegotiator(semver.MustParse("1.47")))
+	handler := checker.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1.20/info", http.NoBody))
+	assert.Equal(t, []string{`299 - "API version 1.20 is deprecated; the minimum supported version is 1.24"`},
+		recorder.Header().Values(deprecationWarningHeader))
+}
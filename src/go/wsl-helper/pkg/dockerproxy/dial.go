@@ -0,0 +1,76 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// defaultDialRetryBackoff is the delay before the first retry, if none is
+// configured.
+const defaultDialRetryBackoff = 100 * time.Millisecond
+
+// maxDialRetryBackoff is the longest we will wait between attempts to connect
+// to the backend.
+const maxDialRetryBackoff = 5 * time.Second
+
+// backendUnavailableError is returned when the backend could not be reached
+// after retrying.
+type backendUnavailableError struct {
+	// retryAfter is the suggested time for the client to wait before retrying.
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *backendUnavailableError) Error() string {
+	return fmt.Sprintf("docker daemon unavailable: %s", e.err)
+}
+
+func (e *backendUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// retryDialer returns a dialer that retries failed connections to the backend
+// (for example, while dockerd is restarting) the given number of times, with
+// exponential backoff starting at the given delay.  This is safe because
+// nothing has been sent to the backend if we failed to connect.
+func retryDialer(dialer func(context.Context) (net.Conn, error), retries int, backoff time.Duration) func(context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		delay := backoff
+		for attempt := 0; ; attempt++ {
+			conn, err := dialer(ctx)
+			if err == nil {
+				return conn, nil
+			}
+			if attempt >= retries {
+				return nil, &backendUnavailableError{retryAfter: delay, err: err}
+			}
+			requestLogger(ctx).WithError(err).WithField("delay", delay).Debug("failed to connect to backend, retrying")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxDialRetryBackoff)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDialer(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	t.Run("succeeds after retries", func(t *testing.T) {
+		attempts := 0
+		dialer := retryDialer(func(context.Context) (net.Conn, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, errRefused
+			}
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}, 5, time.Millisecond)
+		conn, err := dialer(context.Background())
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("gives up", func(t *testing.T) {
+		attempts := 0
+		dialer := retryDialer(func(context.Context) (net.Conn, error) {
+			attempts++
+			return nil, errRefused
+		}, 2, time.Millisecond)
+		_, err := dialer(context.Background())
+		assert.ErrorIs(t, err, errRefused)
+		var unavailableErr *backendUnavailableError
+		require.ErrorAs(t, err, &unavailableErr)
+		assert.Equal(t, 4*time.Millisecond, unavailableErr.retryAfter)
+		assert.Equal(t, 3, attempts)
+
+		recorder := httptest.NewRecorder()
+		proxyErrorHandler(recorder, httptest.NewRequest(http.MethodGet, "/_ping", http.NoBody), err)
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	})
+	t.Run("stops when cancelled", func(t *testing.T) {
+		dialer := retryDialer(func(context.Context) (net.Conn, error) {
+			return nil, errRefused
+		}, 100, time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := dialer(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,149 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// defaultShutdownTimeout is how long in-flight requests are given to complete
+// when the proxy is shut down, if no timeout is configured.
+const defaultShutdownTimeout = 10 * time.Second
+
+// hijackTracker keeps track of hijacked (upgraded) client connections; these
+// are no longer tracked by the http.Server, so they would otherwise not be
+// waited for (or closed) on shutdown.
+type hijackTracker struct {
+	conns map[*trackedConn]struct{}
+	wg    sync.WaitGroup
+	sync.Mutex
+}
+
+func newHijackTracker() *hijackTracker {
+	return &hijackTracker{conns: make(map[*trackedConn]struct{})}
+}
+
+// wrapHandler returns a handler that tracks any connections it hijacks.
+func (t *hijackTracker) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		handler.ServeHTTP(&trackingResponseWriter{ResponseWriter: w, tracker: t}, req)
+	})
+}
+
+// track a newly hijacked connection.
+func (t *hijackTracker) track(conn net.Conn) net.Conn {
+	tracked := &trackedConn{Conn: conn, tracker: t}
+	t.Lock()
+	t.conns[tracked] = struct{}{}
+	t.wg.Add(1)
+	t.Unlock()
+	return tracked
+}
+
+// untrack a hijacked connection that has been closed.
+func (t *hijackTracker) untrack(conn *trackedConn) {
+	t.Lock()
+	delete(t.conns, conn)
+	t.Unlock()
+	t.wg.Done()
+}
+
+// count returns the number of hijacked connections that are still open.
+func (t *hijackTracker) count() int {
+	t.Lock()
+	defer t.Unlock()
+	return len(t.conns)
+}
+
+// wait for all hijacked connections to be closed; if the context expires
+// first, any remaining connections are forcibly closed.
+func (t *hijackTracker) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.closeAll()
+		return ctx.Err()
+	}
+}
+
+// closeAll forcibly closes all hijacked connections.
+func (t *hijackTracker) closeAll() {
+	t.Lock()
+	conns := make([]*trackedConn, 0, len(t.conns))
+	for conn := range t.conns {
+		conns = append(conns, conn)
+	}
+	t.Unlock()
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+}
+
+// trackingResponseWriter is a http.ResponseWriter that reports hijacked
+// connections to a hijackTracker.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	tracker *hijackTracker
+}
+
+func (w *trackingResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return w.tracker.track(conn), rw, nil
+}
+
+func (w *trackingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// trackedConn is a hijacked connection that is untracked when closed.
+type trackedConn struct {
+	net.Conn
+	tracker *hijackTracker
+	once    sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() { c.tracker.untrack(c) })
+	return err
+}
+
+func (c *trackedConn) CloseWrite() error {
+	return utils.CloseWrite(c.Conn)
+}
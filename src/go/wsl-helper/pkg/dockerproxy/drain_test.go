@@ -0,0 +1,79 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHijackTracker(t *testing.T) {
+	tracker := newHijackTracker()
+	hijacked := make(chan net.Conn, 1)
+	server := httptest.NewServer(tracker.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if assert.NoError(t, err) {
+			hijacked <- conn
+		}
+	})))
+	defer server.Close()
+
+	dial := func() net.Conn {
+		client, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		_, err = io.WriteString(client, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("waits for connections to close", func(t *testing.T) {
+		client := dial()
+		defer client.Close()
+		conn := <-hijacked
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			conn.Close()
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		assert.NoError(t, tracker.wait(ctx))
+	})
+
+	t.Run("closes connections on timeout", func(t *testing.T) {
+		client := dial()
+		defer client.Close()
+		<-hijacked
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, tracker.wait(ctx), context.DeadlineExceeded)
+		// The client should see the connection closed.
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(10*time.Second)))
+		_, err := io.ReadAll(client)
+		assert.NoError(t, err)
+		assert.Empty(t, tracker.conns)
+	})
+}
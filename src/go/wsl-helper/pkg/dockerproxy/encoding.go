@@ -0,0 +1,134 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// decodeResponseBody replaces a compressed response body with its decompressed
+// contents, so that response hooks and body wrappers only ever see plain text.
+// It returns the content encoding that was removed, so that the body can be
+// compressed again for the client; an empty string means the body was not
+// compressed (or was compressed in a way we don't understand, in which case it
+// is left as-is).
+func decodeResponseBody(resp *http.Response) (string, error) {
+	if resp.Request.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return "", nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	var reader io.ReadCloser
+	var err error
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(resp.Body)
+	case "deflate":
+		reader, err = zlib.NewReader(resp.Body)
+	default:
+		return "", nil
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			// An empty body has nothing to decompress.
+			resp.Header.Del("Content-Encoding")
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to decode %s response body: %w", encoding, err)
+	}
+	resp.Body = &decodedBody{Reader: reader, decoder: reader, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return encoding, nil
+}
+
+// decodedBody is a decompressed response body; closing it closes both the
+// decompressor and the underlying body.
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	_ = b.decoder.Close()
+	return b.body.Close()
+}
+
+// encodeResponseBody compresses the response body with the given encoding, as
+// previously returned from decodeResponseBody.  The compressed output is
+// flushed after every read from the body, so that streaming responses are not
+// held up waiting for the compressor to fill a block.  Buffers for reading the
+// body come from the given pool.
+func encodeResponseBody(resp *http.Response, encoding string, pool *utils.BufferPool) {
+	if encoding == "" {
+		return
+	}
+	body := resp.Body
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+		var encoder interface {
+			io.WriteCloser
+			Flush() error
+		}
+		if encoding == "deflate" {
+			encoder = zlib.NewWriter(writer)
+		} else {
+			encoder = gzip.NewWriter(writer)
+		}
+		buf := pool.Get()
+		defer pool.Put(buf)
+		for {
+			n, err := body.Read(buf)
+			if n > 0 {
+				if _, writeErr := encoder.Write(buf[:n]); writeErr != nil {
+					writer.CloseWithError(writeErr)
+					return
+				}
+				if flushErr := encoder.Flush(); flushErr != nil {
+					writer.CloseWithError(flushErr)
+					return
+				}
+			}
+			if err == io.EOF {
+				writer.CloseWithError(encoder.Close())
+				return
+			}
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	resp.Body = &pipedBody{PipeReader: reader, body: body}
+	resp.Header.Set("Content-Encoding", encoding)
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = false
+}
@@ -0,0 +1,107 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+func TestResponseEncoding(t *testing.T) {
+	const content = `{"Id":"abc"}`
+	compressors := map[string]func(io.Writer) io.WriteCloser{
+		"gzip":    func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		"deflate": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+	}
+	decompressors := map[string]func(io.Reader) (io.ReadCloser, error){
+		"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		"deflate": zlib.NewReader,
+	}
+	for encoding, compress := range compressors {
+		t.Run(encoding, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer := compress(&buf)
+			_, err := writer.Write([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, writer.Close())
+			resp := &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        http.Header{"Content-Encoding": {encoding}},
+				Body:          io.NopCloser(&buf),
+				ContentLength: int64(buf.Len()),
+				Request:       httptest.NewRequest(http.MethodGet, "/containers/abc/json", http.NoBody),
+			}
+
+			removed, err := decodeResponseBody(resp)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, removed)
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.EqualValues(t, -1, resp.ContentLength)
+			plain, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(plain))
+
+			// Simulate a response hook rewriting the body.
+			resp.Body = io.NopCloser(strings.NewReader(strings.ToUpper(string(plain))))
+			encodeResponseBody(resp, removed, utils.DefaultBufferPool)
+			assert.Equal(t, encoding, resp.Header.Get("Content-Encoding"))
+			reader, err := decompressors[encoding](resp.Body)
+			require.NoError(t, err)
+			result, err := io.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, strings.ToUpper(content), string(result))
+			assert.NoError(t, resp.Body.Close())
+		})
+	}
+	t.Run("unknown encoding", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Encoding": {"br"}},
+			Body:       io.NopCloser(strings.NewReader("opaque")),
+			Request:    httptest.NewRequest(http.MethodGet, "/containers/abc/json", http.NoBody),
+		}
+		removed, err := decodeResponseBody(resp)
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+		assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	})
+	t.Run("empty body", func(t *testing.T) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Encoding": {"gzip"}},
+			Body:       http.NoBody,
+			Request:    httptest.NewRequest(http.MethodGet, "/containers/abc/json", http.NoBody),
+		}
+		removed, err := decodeResponseBody(resp)
+		require.NoError(t, err)
+		assert.Empty(t, removed)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	})
+}
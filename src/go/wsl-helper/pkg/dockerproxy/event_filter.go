@@ -0,0 +1,170 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EventFilterRule describes events in the /events stream to drop or tag.  An
+// event matches the rule if it matches all of the criteria that are set.
+type EventFilterRule struct {
+	// Type is the type of object the event is about (e.g. "container").
+	Type string `yaml:"type"`
+	// Actions is the list of event actions (e.g. "start", "die") to match.
+	Actions []string `yaml:"actions"`
+	// Attributes are actor attributes (including container labels) that must
+	// be present; a value of "*" matches any value.
+	Attributes map[string]string `yaml:"attributes"`
+	// Drop removes matching events from the stream.
+	Drop bool `yaml:"drop"`
+	// Tag adds the given attributes to matching events.
+	Tag map[string]string `yaml:"tag"`
+}
+
+// KubernetesEventFilterRule drops events about containers managed by
+// Kubernetes (via cri-dockerd), including the pause containers.
+var KubernetesEventFilterRule = EventFilterRule{
+	Type:       "container",
+	Attributes: map[string]string{"io.kubernetes.docker.type": "*"},
+	Drop:       true,
+}
+
+// eventFilterFile is the on-disk format of an event filter file.
+type eventFilterFile struct {
+	Rules []EventFilterRule `yaml:"rules"`
+}
+
+// LoadEventFilterFile reads a JSON or YAML file containing event filter rules.
+func LoadEventFilterFile(filterPath string) ([]EventFilterRule, error) {
+	buf, err := os.ReadFile(filterPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read event filter file %s: %w", filterPath, err)
+	}
+	var config eventFilterFile
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("could not parse event filter file %s: %w", filterPath, err)
+	}
+	return config.Rules, nil
+}
+
+// dockerEvent is the subset of an event message we need to apply filters.
+type dockerEvent struct {
+	Type   string
+	Action string
+	Actor  struct {
+		Attributes map[string]string
+	}
+}
+
+// matches checks if the event matches the rule.
+func (r *EventFilterRule) matches(event *dockerEvent) bool {
+	if r.Type != "" && r.Type != event.Type {
+		return false
+	}
+	if len(r.Actions) > 0 && !slices.Contains(r.Actions, event.Action) {
+		return false
+	}
+	for key, expected := range r.Attributes {
+		actual, ok := event.Actor.Attributes[key]
+		if !ok || (expected != "*" && expected != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// eventFilter applies event filter rules to the /events stream.
+type eventFilter struct {
+	rules []EventFilterRule
+}
+
+// filterResponse is a ResponseHook that filters the body of /events responses.
+func (f *eventFilter) filterResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK || apiEndpoint(resp.Request.URL.Path) != "/events" {
+		return nil
+	}
+	resp.Body = FilterJSONStream(resp.Body, f.filter)
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// filter a single event message, returning nil if it should be dropped.
+func (f *eventFilter) filter(message json.RawMessage) (json.RawMessage, error) {
+	var event dockerEvent
+	if err := json.Unmarshal(message, &event); err != nil {
+		// Pass through anything we don't understand.
+		return message, nil
+	}
+	tags := make(map[string]string)
+	for _, rule := range f.rules {
+		if !rule.matches(&event) {
+			continue
+		}
+		if rule.Drop {
+			return nil, nil
+		}
+		for key, value := range rule.Tag {
+			tags[key] = value
+		}
+	}
+	if len(tags) == 0 {
+		return message, nil
+	}
+
+	// Add the tags to the actor attributes, preserving all other fields.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(message, &fields); err != nil {
+		return message, nil
+	}
+	var actor map[string]json.RawMessage
+	if raw, ok := fields["Actor"]; ok {
+		if err := json.Unmarshal(raw, &actor); err != nil {
+			return message, nil
+		}
+	}
+	if actor == nil {
+		actor = make(map[string]json.RawMessage)
+	}
+	attributes := event.Actor.Attributes
+	if attributes == nil {
+		attributes = make(map[string]string)
+	}
+	for key, value := range tags {
+		attributes[key] = value
+	}
+	var err error
+	if actor["Attributes"], err = json.Marshal(attributes); err != nil {
+		return nil, err
+	}
+	if fields["Actor"], err = json.Marshal(actor); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
@@ -0,0 +1,105 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventFilter(t *testing.T) {
+	filter := &eventFilter{rules: []EventFilterRule{
+		KubernetesEventFilterRule,
+		{Type: "image", Actions: []string{"pull"}, Tag: map[string]string{"rd.source": "proxy"}},
+	}}
+	events := strings.Join([]string{
+		`{"Type":"container","Action":"start","Actor":{"ID":"a","Attributes":{"name":"web"}},"time":1}`,
+		`{"Type":"container","Action":"start","Actor":{"ID":"b","Attributes":{"io.kubernetes.docker.type":"podsandbox"}},"time":2}`,
+		`{"Type":"image","Action":"pull","Actor":{"ID":"alpine","Attributes":{"name":"alpine"}},"time":3}`,
+		`{"Type":"image","Action":"delete","Actor":{"ID":"busybox"},"time":4}`,
+	}, "\n")
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Length": {"1"}},
+		Body:          io.NopCloser(strings.NewReader(events)),
+		ContentLength: 1,
+		Request:       httptest.NewRequest(http.MethodGet, "/v1.41/events", http.NoBody),
+	}
+	require.NoError(t, filter.filterResponse(resp))
+	assert.EqualValues(t, -1, resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+
+	var results []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, result)
+	}
+	require.NoError(t, scanner.Err())
+	require.NoError(t, resp.Body.Close())
+
+	require.Len(t, results, 3)
+	assert.Equal(t, "a", results[0]["Actor"].(map[string]interface{})["ID"])
+	pulled := results[1]["Actor"].(map[string]interface{})
+	assert.Equal(t, "alpine", pulled["ID"])
+	assert.Equal(t, map[string]interface{}{"name": "alpine", "rd.source": "proxy"}, pulled["Attributes"])
+	assert.EqualValues(t, 3, results[1]["time"])
+	assert.Equal(t, "busybox", results[2]["Actor"].(map[string]interface{})["ID"])
+}
+
+func TestEventFilterOtherEndpoints(t *testing.T) {
+	filter := &eventFilter{rules: []EventFilterRule{{Drop: true}}}
+	body := io.NopCloser(strings.NewReader(`{"Type":"container"}`))
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       body,
+		Request:    httptest.NewRequest(http.MethodGet, "/v1.41/containers/json", http.NoBody),
+	}
+	require.NoError(t, filter.filterResponse(resp))
+	assert.Equal(t, body, resp.Body)
+}
+
+func TestLoadEventFilterFile(t *testing.T) {
+	filterPath := filepath.Join(t.TempDir(), "events.yaml")
+	contents := "rules:\n- type: container\n  attributes:\n    com.example.hidden: \"*\"\n  drop: true\n"
+	require.NoError(t, os.WriteFile(filterPath, []byte(contents), 0o600))
+	rules, err := LoadEventFilterFile(filterPath)
+	require.NoError(t, err)
+	assert.Equal(t, []EventFilterRule{{
+		Type:       "container",
+		Attributes: map[string]string{"com.example.hidden": "*"},
+		Drop:       true,
+	}}, rules)
+
+	require.NoError(t, os.WriteFile(filterPath, []byte("rules:\n- hide: true\n"), 0o600))
+	_, err = LoadEventFilterFile(filterPath)
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHealthCheckInterval is how often backends are probed, if no interval
+// is configured.
+const defaultHealthCheckInterval = 5 * time.Second
+
+// backendPool routes connections to the first healthy backend out of a list,
+// probing each backend periodically to detect when it recovers.
+type backendPool struct {
+	backends []*backend
+}
+
+// backend is a single dockerd that we can connect to.
+type backend struct {
+	index   int
+	dialer  func() (net.Conn, error)
+	healthy atomic.Bool
+	// client is used to probe the backend.
+	client *http.Client
+}
+
+// newBackendPool creates a pool of backends, in order of preference; they are
+// all initially assumed to be healthy.
+func newBackendPool(dialers []func() (net.Conn, error)) *backendPool {
+	pool := &backendPool{}
+	for i, dialer := range dialers {
+		b := &backend{index: i, dialer: dialer}
+		b.client = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(context.Context, string, string) (net.Conn, error) {
+					return b.dialer()
+				},
+				DisableKeepAlives: true,
+			},
+			Timeout: 5 * time.Second,
+		}
+		b.healthy.Store(true)
+		pool.backends = append(pool.backends, b)
+	}
+	return pool
+}
+
+// dial connects to the first healthy backend; any backend that fails to
+// connect is marked unhealthy until its next successful probe.  If no backends
+// are believed to be healthy, each is tried in turn anyway.
+func (p *backendPool) dial() (net.Conn, error) {
+	var errs []error
+	for _, wantHealthy := range []bool{true, false} {
+		for _, b := range p.backends {
+			if b.healthy.Load() != wantHealthy {
+				continue
+			}
+			conn, err := b.dialer()
+			if err == nil {
+				b.healthy.Store(true)
+				return conn, nil
+			}
+			b.healthy.Store(false)
+			errs = append(errs, fmt.Errorf("backend %d: %w", b.index, err))
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// probe checks if the backend is responding to API requests.
+func (b *backend) probe(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://proxy.invalid/_ping", http.NoBody)
+	if err != nil {
+		return false
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// run probes all backends at the given interval, until the context is
+// cancelled.
+func (p *backendPool) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, b := range p.backends {
+			healthy := b.probe(ctx)
+			if b.healthy.Swap(healthy) != healthy {
+				requestLogger(ctx).WithField("backend", b.index).WithField("healthy", healthy).Info("backend health changed")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackendPool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	primaryUp := false
+	primaryDials, fallbackDials := 0, 0
+	pool := newBackendPool([]func() (net.Conn, error){
+		func() (net.Conn, error) {
+			primaryDials++
+			if !primaryUp {
+				return nil, errors.New("primary down")
+			}
+			return net.Dial("tcp", server.Listener.Addr().String())
+		},
+		func() (net.Conn, error) {
+			fallbackDials++
+			return net.Dial("tcp", server.Listener.Addr().String())
+		},
+	})
+
+	conn, err := pool.dial()
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, 1, primaryDials)
+	assert.Equal(t, 1, fallbackDials)
+	assert.False(t, pool.backends[0].healthy.Load())
+
+	// The unhealthy primary is not retried until it has been probed.
+	conn, err = pool.dial()
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, 1, primaryDials)
+	assert.Equal(t, 2, fallbackDials)
+
+	primaryUp = true
+	assert.True(t, pool.backends[0].probe(context.Background()))
+	pool.backends[0].healthy.Store(true)
+	conn, err = pool.dial()
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, 3, primaryDials)
+	assert.Equal(t, 2, fallbackDials)
+}
+
+func TestBackendPoolAllDown(t *testing.T) {
+	errDown := errors.New("down")
+	pool := newBackendPool([]func() (net.Conn, error){
+		func() (net.Conn, error) { return nil, errDown },
+		func() (net.Conn, error) { return nil, errDown },
+	})
+	_, err := pool.dial()
+	assert.ErrorIs(t, err, errDown)
+	// Even with no healthy backends, we still try to connect.
+	_, err = pool.dial()
+	assert.ErrorIs(t, err, errDown)
+}
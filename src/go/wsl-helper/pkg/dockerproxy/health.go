@@ -0,0 +1,138 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// healthPath is the path of the proxy's own health endpoint; it is handled by
+// the proxy rather than forwarded to the backend.
+const healthPath = "/__rd/healthz"
+
+// healthResponse is the body of the health endpoint response.
+type healthResponse struct {
+	// Status is "ok" if the backend is reachable, or "unavailable" otherwise.
+	Status string `json:"status"`
+	// ProxyAPIVersion is the maximum API version the proxy supports.
+	ProxyAPIVersion string `json:"proxyApiVersion"`
+	// Backend describes the state of the backend dockerd.
+	Backend healthBackend `json:"backend"`
+}
+
+type healthBackend struct {
+	Reachable  bool   `json:"reachable"`
+	Error      string `json:"error,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	Version    string `json:"version,omitempty"`
+	OS         string `json:"os,omitempty"`
+	Arch       string `json:"arch,omitempty"`
+}
+
+// healthStatusResponse is the body of the health endpoint response for clients
+// that have not authenticated; it does not reveal anything about the backend.
+type healthStatusResponse struct {
+	Status string `json:"status"`
+}
+
+// newHealthHandler returns a handler that reports whether the backend can be
+// reached, so that "proxy down" can be distinguished from "backend down".  If
+// auth is set, the details are only reported to authenticated clients.
+func newHealthHandler(dialer func() (net.Conn, error), auth *authenticator) http.Handler {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) {
+				return dialer()
+			},
+			DisableKeepAlives: true,
+		},
+		Timeout: 5 * time.Second,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		result := healthResponse{
+			Status:          "ok",
+			ProxyAPIVersion: dockerSpec.Info.Version.Original(),
+		}
+		if err := checkBackendHealth(req.Context(), client, &result.Backend); err != nil {
+			result.Status = "unavailable"
+			result.Backend.Error = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if result.Status == "ok" {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if auth != nil && !auth.authenticated(req) {
+			_ = json.NewEncoder(w).Encode(&healthStatusResponse{Status: result.Status})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(&result)
+	})
+}
+
+// checkBackendHealth pings the backend and fetches its version information.
+func checkBackendHealth(ctx context.Context, client *http.Client, result *healthBackend) error {
+	get := func(path string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://proxy.invalid"+path, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned %s", path, resp.Status)
+		}
+		return resp, nil
+	}
+
+	resp, err := get("/_ping")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	result.Reachable = true
+	result.APIVersion = resp.Header.Get("API-Version")
+
+	resp, err = get("/version")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	var version struct {
+		Version string
+		Os      string
+		Arch    string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return fmt.Errorf("could not parse backend version: %w", err)
+	}
+	result.Version = version.Version
+	result.OS = version.Os
+	result.Arch = version.Arch
+	return nil
+}
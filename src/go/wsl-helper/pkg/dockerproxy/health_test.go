@@ -0,0 +1,100 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	t.Run("backend up", func(t *testing.T) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("API-Version", "1.43")
+			if req.URL.Path == "/version" {
+				_, _ = w.Write([]byte(`{"Version":"24.0.7","Os":"linux","Arch":"amd64"}`))
+			}
+		}))
+		defer backend.Close()
+		handler := newHealthHandler(func() (net.Conn, error) {
+			return net.Dial("tcp", backend.Listener.Addr().String())
+		}, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, healthPath, http.NoBody))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var result healthResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		assert.Equal(t, "ok", result.Status)
+		assert.Equal(t, healthBackend{
+			Reachable:  true,
+			APIVersion: "1.43",
+			Version:    "24.0.7",
+			OS:         "linux",
+			Arch:       "amd64",
+		}, result.Backend)
+		assert.NotEmpty(t, result.ProxyAPIVersion)
+	})
+	t.Run("backend down", func(t *testing.T) {
+		handler := newHealthHandler(func() (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		}, nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, healthPath, http.NoBody))
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		var result healthResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		assert.Equal(t, "unavailable", result.Status)
+		assert.False(t, result.Backend.Reachable)
+		assert.Contains(t, result.Backend.Error, "connection refused")
+	})
+	t.Run("authentication", func(t *testing.T) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/version" {
+				_, _ = w.Write([]byte(`{"Version":"24.0.7","Os":"linux","Arch":"amd64"}`))
+			}
+		}))
+		defer backend.Close()
+		handler := newHealthHandler(func() (net.Conn, error) {
+			return net.Dial("tcp", backend.Listener.Addr().String())
+		}, newAuthenticator([]string{"secret"}, logrus.StandardLogger()))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, healthPath, http.NoBody))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String(),
+			"unauthenticated clients should only get the status")
+
+		recorder = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, healthPath, http.NoBody)
+		req.Header.Set("Authorization", "Bearer secret")
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var result healthResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		assert.Equal(t, "24.0.7", result.Backend.Version)
+	})
+}
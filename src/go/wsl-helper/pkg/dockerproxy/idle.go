@@ -0,0 +1,142 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// idleTimeout closes upgraded (hijacked) connections that have not had any
+// data sent in either direction for some time; since all data passes through
+// the client connection, that is the only one that needs to be watched.
+type idleTimeout struct {
+	timeout time.Duration
+	// bypass is the set of API endpoints that have no idle timeout.
+	bypass map[string]struct{}
+}
+
+func newIdleTimeout(timeout time.Duration, bypass []string) *idleTimeout {
+	bypassSet := make(map[string]struct{}, len(bypass))
+	for _, endpoint := range bypass {
+		bypassSet[endpoint] = struct{}{}
+	}
+	return &idleTimeout{timeout: timeout, bypass: bypassSet}
+}
+
+// wrapHandler returns a handler that applies the idle timeout to any
+// connections it hijacks.
+func (t *idleTimeout) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := t.bypass[apiEndpoint(req.URL.Path)]; ok {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		handler.ServeHTTP(&idleTimeoutResponseWriter{ResponseWriter: w, timeout: t, req: req}, req)
+	})
+}
+
+// idleTimeoutResponseWriter is a http.ResponseWriter that applies an idle
+// timeout to hijacked connections.
+type idleTimeoutResponseWriter struct {
+	http.ResponseWriter
+	timeout *idleTimeout
+	req     *http.Request
+}
+
+func (w *idleTimeoutResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *idleTimeoutResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return newIdleConn(conn, w.timeout.timeout, func() {
+		requestLogger(w.req.Context()).WithField("timeout", w.timeout.timeout).Info("closing idle upgraded connection")
+	}), rw, nil
+}
+
+func (w *idleTimeoutResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// idleConn is a connection that is closed once it has been idle for longer
+// than the timeout.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+	// lastActive is the time of the last read or write, in Unix nanoseconds.
+	lastActive atomic.Int64
+	timer      *time.Timer
+	onIdle     func()
+	closeOnce  sync.Once
+}
+
+func newIdleConn(conn net.Conn, timeout time.Duration, onIdle func()) *idleConn {
+	c := &idleConn{Conn: conn, timeout: timeout, onIdle: onIdle}
+	c.lastActive.Store(time.Now().UnixNano())
+	c.timer = time.AfterFunc(timeout, c.check)
+	return c
+}
+
+// check if the connection has been idle for too long, closing it if so and
+// scheduling the next check otherwise.
+func (c *idleConn) check() {
+	idle := time.Since(time.Unix(0, c.lastActive.Load()))
+	if idle < c.timeout {
+		c.timer.Reset(c.timeout - idle)
+		return
+	}
+	c.onIdle()
+	_ = c.Close()
+}
+
+func (c *idleConn) Read(buf []byte) (int, error) {
+	n, err := c.Conn.Read(buf)
+	if n > 0 {
+		c.lastActive.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *idleConn) Write(buf []byte) (int, error) {
+	n, err := c.Conn.Write(buf)
+	if n > 0 {
+		c.lastActive.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *idleConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { c.timer.Stop() })
+	return err
+}
+
+func (c *idleConn) CloseWrite() error {
+	return utils.CloseWrite(c.Conn)
+}
@@ -0,0 +1,81 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// idleExit detects when the proxy has had no client connections for a while,
+// so that it can exit; this is useful when it is socket activated, as it will
+// be started again on the next connection.
+type idleExit struct {
+	timeout time.Duration
+	tracker *hijackTracker
+	// active is the number of client connections being served by the
+	// http.Server (i.e. not including hijacked connections).
+	active atomic.Int64
+}
+
+func newIdleExit(timeout time.Duration, tracker *hijackTracker) *idleExit {
+	return &idleExit{timeout: timeout, tracker: tracker}
+}
+
+// connState is used as http.Server.ConnState to count active connections.
+func (e *idleExit) connState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		e.active.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		e.active.Add(-1)
+	}
+}
+
+// busy checks if there are any client connections.
+func (e *idleExit) busy() bool {
+	return e.active.Load() > 0 || e.tracker.count() > 0
+}
+
+// wait until the proxy has been idle for the timeout, returning true; or until
+// the context is done, returning false.
+func (e *idleExit) wait(ctx context.Context) bool {
+	interval := e.timeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastBusy := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case now := <-ticker.C:
+			if e.busy() {
+				lastBusy = now
+			} else if now.Sub(lastBusy) >= e.timeout {
+				return true
+			}
+		}
+	}
+}
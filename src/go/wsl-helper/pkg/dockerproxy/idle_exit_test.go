@@ -0,0 +1,54 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdleExit(t *testing.T) {
+	tracker := newHijackTracker()
+	idle := newIdleExit(50*time.Millisecond, tracker)
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	idle.connState(server, http.StateNew)
+	assert.True(t, idle.busy())
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	assert.False(t, idle.wait(ctx), "should not exit while a connection is active")
+
+	// Hijacked connections are counted by the tracker instead.
+	idle.connState(server, http.StateHijacked)
+	tracked := tracker.track(server)
+	assert.True(t, idle.busy())
+	assert.NoError(t, tracked.Close())
+	assert.False(t, idle.busy())
+
+	start := time.Now()
+	assert.True(t, idle.wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,58 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdleConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	idled := make(chan struct{})
+	conn := newIdleConn(server, 50*time.Millisecond, func() { close(idled) })
+
+	// Keep the connection active for a while.
+	go func() { _, _ = io.Copy(io.Discard, conn) }()
+	for i := 0; i < 5; i++ {
+		_, err := client.Write([]byte("x"))
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+	}
+	select {
+	case <-idled:
+		assert.Fail(t, "connection closed while active")
+	default:
+	}
+
+	// Stop sending data; the connection should be closed.
+	select {
+	case <-idled:
+	case <-time.After(10 * time.Second):
+		assert.Fail(t, "idle connection was not closed")
+	}
+	_, err := client.Write([]byte("x"))
+	assert.Error(t, err)
+}
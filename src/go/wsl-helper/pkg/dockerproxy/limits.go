@@ -0,0 +1,172 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+)
+
+// unlimitedBodyEndpoints are the API endpoints whose request bodies may be
+// arbitrarily large (build contexts, image tarballs, and so on); they are not
+// subject to the request body size limit.
+var unlimitedBodyEndpoints = map[string]struct{}{
+	"/build":                     {},
+	"/containers/{id}/archive":   {},
+	"/containers/{id}/attach":    {},
+	"/containers/{id}/attach/ws": {},
+	"/exec/{id}/start":           {},
+	"/images/create":             {},
+	"/images/load":               {},
+	"/plugins/create":            {},
+	"/session":                   {},
+}
+
+// longRunningEndpoints are the API endpoints that are expected to take a long
+// time (typically because they stream output); they are not subject to the
+// overall request timeout.
+var longRunningEndpoints = map[string]struct{}{
+	"/build":                     {},
+	"/containers/{id}/attach":    {},
+	"/containers/{id}/attach/ws": {},
+	"/containers/{id}/export":    {},
+	"/containers/{id}/logs":      {},
+	"/containers/{id}/stats":     {},
+	"/containers/{id}/wait":      {},
+	"/events":                    {},
+	"/exec/{id}/start":           {},
+	"/images/create":             {},
+	"/images/get":                {},
+	"/images/load":               {},
+	"/images/{name}/get":         {},
+	"/images/{name}/push":        {},
+	"/plugins/pull":              {},
+	"/plugins/{name}/push":       {},
+	"/plugins/{name}/upgrade":    {},
+	"/services/{id}/logs":        {},
+	"/session":                   {},
+	"/tasks/{id}/logs":           {},
+}
+
+// requestLimits restricts the resources a single request may use.
+type requestLimits struct {
+	// maxBodySize is the largest request body allowed, other than for the
+	// unlimitedBodyEndpoints; if zero, there is no limit.
+	maxBodySize int64
+	// timeout is the overall deadline for handling a request, other than for
+	// upgraded connections and longRunningEndpoints; if zero, there is no
+	// limit.
+	timeout time.Duration
+	// endpointTimeouts override the timeout for matching endpoints, most
+	// specific first.
+	endpointTimeouts []endpointTimeout
+}
+
+// endpointTimeout is a deadline applied to requests matching a path pattern.
+type endpointTimeout struct {
+	pattern string
+	timeout time.Duration
+}
+
+// newEndpointTimeouts validates the given map of path patterns to timeouts (as
+// ServeOptions.EndpointTimeouts), returning them in the order they should be
+// checked: longer (more specific) patterns first.
+func newEndpointTimeouts(timeouts map[string]time.Duration) ([]endpointTimeout, error) {
+	result := make([]endpointTimeout, 0, len(timeouts))
+	for pattern, timeout := range timeouts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid endpoint timeout pattern %q: %w", pattern, err)
+		}
+		if timeout < 0 {
+			return nil, fmt.Errorf("invalid timeout %s for endpoint %q", timeout, pattern)
+		}
+		result = append(result, endpointTimeout{pattern: pattern, timeout: timeout})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].pattern) != len(result[j].pattern) {
+			return len(result[i].pattern) > len(result[j].pattern)
+		}
+		return result[i].pattern < result[j].pattern
+	})
+	return result, nil
+}
+
+// ParseEndpointTimeouts converts a map of path patterns to duration strings
+// (as given on the command line) into the form used by
+// ServeOptions.EndpointTimeouts.
+func ParseEndpointTimeouts(input map[string]string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration, len(input))
+	for pattern, value := range input {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for endpoint %q: %w", pattern, err)
+		}
+		result[pattern] = timeout
+	}
+	return result, nil
+}
+
+// requestTimeout returns the timeout that applies to a request with the given
+// path, and whether any timeout applies at all.
+func (l *requestLimits) requestTimeout(requestPath string) (time.Duration, bool) {
+	endpoint := apiEndpoint(requestPath)
+	unversioned := apiVersionPattern.ReplaceAllString(requestPath, "/")
+	for _, entry := range l.endpointTimeouts {
+		// Patterns may be written against either the request path (e.g.
+		// "/containers/*/wait") or the API template ("/containers/{id}/wait").
+		matched, _ := path.Match(entry.pattern, unversioned)
+		if !matched {
+			matched, _ = path.Match(entry.pattern, endpoint)
+		}
+		if matched {
+			return entry.timeout, entry.timeout > 0
+		}
+	}
+	if _, ok := longRunningEndpoints[endpoint]; ok {
+		return 0, false
+	}
+	return l.timeout, l.timeout > 0
+}
+
+// wrapHandler returns a handler that applies the limits to requests before
+// passing them to the given handler.
+func (l *requestLimits) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := apiEndpoint(req.URL.Path)
+		if _, ok := unlimitedBodyEndpoints[endpoint]; !ok && l.maxBodySize > 0 {
+			if req.ContentLength > l.maxBodySize {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("request body too large (limit %d bytes)", l.maxBodySize))
+				return
+			}
+			req.Body = http.MaxBytesReader(w, req.Body, l.maxBodySize)
+		}
+		upgrade := req.Header.Get("Upgrade") != ""
+		if timeout, ok := l.requestTimeout(req.URL.Path); ok && !upgrade {
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			req = req.WithContext(ctx)
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
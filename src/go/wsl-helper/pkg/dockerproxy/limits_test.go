@@ -0,0 +1,106 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLimits(t *testing.T) {
+	limits := &requestLimits{maxBodySize: 4, timeout: time.Minute}
+	var readErr error
+	var hasDeadline bool
+	handler := limits.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, readErr = io.ReadAll(req.Body)
+		_, hasDeadline = req.Context().Deadline()
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path, body string, contentLength int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.ContentLength = contentLength
+		recorder := httptest.NewRecorder()
+		readErr = nil
+		hasDeadline = false
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("small body", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/v1.41/containers/create", "{}", 2).Code)
+		assert.NoError(t, readErr)
+		assert.True(t, hasDeadline)
+	})
+	t.Run("known large body", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, serve("/v1.41/containers/create", "{ }  ", 5).Code)
+	})
+	t.Run("chunked large body", func(t *testing.T) {
+		serve("/v1.41/containers/create", "{ }  ", -1)
+		var maxBytesErr *http.MaxBytesError
+		assert.ErrorAs(t, readErr, &maxBytesErr)
+	})
+	t.Run("streaming upload", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve("/v1.41/images/load", "a large tarball", -1).Code)
+		assert.NoError(t, readErr)
+		assert.False(t, hasDeadline)
+	})
+}
+
+func TestEndpointTimeouts(t *testing.T) {
+	endpointTimeouts, err := newEndpointTimeouts(map[string]time.Duration{
+		"/containers/*/wait": 0,
+		"/images/create":     30 * time.Minute,
+		"/containers/*":      time.Second,
+	})
+	assert.NoError(t, err)
+	limits := &requestLimits{timeout: 2 * time.Minute, endpointTimeouts: endpointTimeouts}
+	cases := map[string]struct {
+		timeout time.Duration
+		ok      bool
+	}{
+		"/v1.41/containers/abc/wait": {0, false},
+		"/v1.41/images/create":       {30 * time.Minute, true},
+		"/v1.41/containers/json":     {time.Second, true},
+		"/v1.41/containers/abc/logs": {0, false},
+		"/v1.41/info":                {2 * time.Minute, true},
+		"/_ping":                     {2 * time.Minute, true},
+	}
+	for requestPath, expected := range cases {
+		timeout, ok := limits.requestTimeout(requestPath)
+		assert.Equal(t, expected.ok, ok, requestPath)
+		assert.Equal(t, expected.timeout, timeout, requestPath)
+	}
+
+	_, err = newEndpointTimeouts(map[string]time.Duration{"/containers/[": time.Second})
+	assert.Error(t, err)
+}
+
+func TestParseEndpointTimeouts(t *testing.T) {
+	result, err := ParseEndpointTimeouts(map[string]string{"/images/create": "30m", "/containers/*/wait": "0"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"/images/create": 30 * time.Minute, "/containers/*/wait": 0}, result)
+	_, err = ParseEndpointTimeouts(map[string]string{"/images/create": "forever"})
+	assert.Error(t, err)
+}
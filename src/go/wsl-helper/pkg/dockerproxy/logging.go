@@ -0,0 +1,125 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// requestIDHeader is the header carrying the request ID; it is set on both the
+// request to the backend and the response to the client.
+const requestIDHeader = "X-Request-Id"
+
+// requestIDPattern matches request IDs supplied by the client that we are
+// willing to reuse; anything else is replaced with a generated ID.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// loggerContextKey is the context key for the per-request logger.
+type loggerContextKey struct{}
+
+// requestIDContextKey is the context key for the request ID.
+type requestIDContextKey struct{}
+
+// requestLogger returns the logger for the request with the given context; if
+// there is none, the standard logger is used.
+func requestLogger(ctx context.Context) logrus.FieldLogger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(logrus.FieldLogger); ok {
+		return logger
+	}
+	return logrus.StandardLogger()
+}
+
+// requestIDFromContext returns the ID of the request with the given context,
+// or the empty string if there is none.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// setRequestIDHeader is a request hook that passes the request ID on to the
+// backend, so that its logs can be correlated with ours.
+func setRequestIDHeader(req *http.Request) {
+	if id := requestIDFromContext(req.Context()); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+}
+
+// withRequestLogger returns a handler that attaches a logger with fields
+// describing the request to the request context, and logs a summary of the
+// request once it has been handled.  Each request is also assigned an ID
+// (reusing the one from the client, if it sent a valid one), which is logged
+// and returned to the client in the response headers.
+func withRequestLogger(logger logrus.FieldLogger, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		requestID := req.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		entry := logger.WithFields(logrus.Fields{
+			"request_id":  requestID,
+			"remote_addr": req.RemoteAddr,
+			"method":      req.Method,
+			"path":        req.URL.Path,
+		})
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			entry.WithFields(logrus.Fields{
+				"status":   recorder.Status(),
+				"duration": time.Since(start),
+				"upgraded": recorder.hijacked,
+			}).Debug("handled request")
+		}()
+		ctx := context.WithValue(req.Context(), loggerContextKey{}, entry)
+		ctx = context.WithValue(ctx, requestIDContextKey{}, requestID)
+		handler.ServeHTTP(recorder, req.WithContext(ctx))
+	})
+}
+
+// proxyErrorHandler is the ErrorHandler for the reverse proxy; it logs the
+// error against the request and returns an appropriate error to the client.
+func proxyErrorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	requestLogger(req.Context()).WithError(err).Error("error proxying request")
+	var maxBytesErr *http.MaxBytesError
+	var unavailableErr *backendUnavailableError
+	switch {
+	case errors.As(err, &unavailableErr):
+		seconds := max(int(math.Ceil(unavailableErr.retryAfter.Seconds())), 1)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeErrorResponse(w, http.StatusServiceUnavailable, unavailableErr.Error())
+	case errors.As(err, &maxBytesErr):
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("request body too large (limit %d bytes)", maxBytesErr.Limit))
+	case errors.Is(err, context.DeadlineExceeded):
+		writeErrorResponse(w, http.StatusGatewayTimeout, "timed out waiting for the docker daemon")
+	default:
+		writeErrorResponse(w, http.StatusBadGateway, "error communicating with the docker daemon")
+	}
+}
@@ -0,0 +1,91 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	handler := withRequestLogger(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		proxyErrorHandler(w, req, errors.New("backend went away"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody)
+	req.RemoteAddr = "client"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	entries := hook.AllEntries()
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "client", entry.Data["remote_addr"])
+		assert.Equal(t, http.MethodGet, entry.Data["method"])
+		assert.Equal(t, "/v1.41/info", entry.Data["path"])
+	}
+	assert.Equal(t, logrus.ErrorLevel, entries[0].Level)
+	assert.Equal(t, http.StatusBadGateway, entries[1].Data["status"])
+}
+
+func TestRequestID(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	var backendID string
+	handler := withRequestLogger(logger, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		outgoing := req.Clone(req.Context())
+		outgoing.Header = http.Header{}
+		setRequestIDHeader(outgoing)
+		backendID = outgoing.Header.Get(requestIDHeader)
+		proxyErrorHandler(w, req, errors.New("backend went away"))
+	}))
+
+	t.Run("generated", func(t *testing.T) {
+		hook.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody)
+		req.Header.Set(requestIDHeader, "not a valid ID")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		id := recorder.Header().Get(requestIDHeader)
+		require.NotEmpty(t, id)
+		assert.NotEqual(t, "not a valid ID", id)
+		assert.Equal(t, id, backendID)
+		assert.Contains(t, recorder.Body.String(), id)
+		require.NotEmpty(t, hook.AllEntries())
+		assert.Equal(t, id, hook.LastEntry().Data["request_id"])
+	})
+	t.Run("from client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody)
+		req.Header.Set(requestIDHeader, "client-id.1")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, "client-id.1", recorder.Header().Get(requestIDHeader))
+		assert.Equal(t, "client-id.1", backendID)
+	})
+}
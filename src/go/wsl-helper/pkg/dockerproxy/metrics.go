@@ -0,0 +1,239 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// unknownEndpoint is the endpoint label used for requests that do not match
+// any path in the embedded API specification.
+const unknownEndpoint = "unknown"
+
+// proxyMetrics holds the Prometheus collectors for the docker proxy.
+type proxyMetrics struct {
+	registry *prometheus.Registry
+	// requests counts completed requests, by endpoint, method, and status code.
+	requests *prometheus.CounterVec
+	// inFlight is the number of requests currently being handled.
+	inFlight prometheus.Gauge
+	// upgraded counts connections that were upgraded (hijacked), by endpoint.
+	upgraded *prometheus.CounterVec
+	// bytes counts bytes proxied to and from the backend, by direction.
+	bytes *prometheus.CounterVec
+}
+
+// newProxyMetrics creates a new set of metrics, registered against a fresh
+// registry.
+func newProxyMetrics() *proxyMetrics {
+	m := &proxyMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "docker_proxy",
+			Name:      "requests_total",
+			Help:      "Number of Docker API requests handled, by endpoint, method, and status code.",
+		}, []string{"endpoint", "method", "code"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "docker_proxy",
+			Name:      "requests_in_flight",
+			Help:      "Number of Docker API requests currently being handled.",
+		}),
+		upgraded: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "docker_proxy",
+			Name:      "upgraded_connections_total",
+			Help:      "Number of connections upgraded to a raw stream (attach, exec, etc.).",
+		}, []string{"endpoint"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "docker_proxy",
+			Name:      "bytes_total",
+			Help:      "Number of bytes proxied between clients and the backend, by direction.",
+		}, []string{"direction"}),
+	}
+	m.registry.MustRegister(m.requests, m.inFlight, m.upgraded, m.bytes)
+	return m
+}
+
+// serve the metrics on the given TCP address.  This blocks until the server
+// exits.
+func (m *proxyMetrics) serve(endpoint string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              endpoint,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Minute,
+	}
+	logrus.WithField("endpoint", endpoint).Info("Serving metrics")
+	return server.ListenAndServe()
+}
+
+// wrapHandler returns a handler that records metrics about the requests that
+// pass through to the given handler.
+func (m *proxyMetrics) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := apiEndpoint(req.URL.Path)
+		recorder := &statusRecorder{ResponseWriter: w}
+		m.inFlight.Inc()
+		defer func() {
+			m.inFlight.Dec()
+			if recorder.hijacked {
+				m.upgraded.WithLabelValues(endpoint).Inc()
+			}
+			code := strconv.Itoa(recorder.Status())
+			m.requests.WithLabelValues(endpoint, req.Method, code).Inc()
+		}()
+		handler.ServeHTTP(recorder, req)
+	})
+}
+
+// wrapDialer returns a dialer that counts the bytes transferred on the
+// connections it creates.
+func (m *proxyMetrics) wrapDialer(dialer func() (net.Conn, error)) func() (net.Conn, error) {
+	sent := m.bytes.WithLabelValues("to_backend")
+	received := m.bytes.WithLabelValues("from_backend")
+	return func() (net.Conn, error) {
+		conn, err := dialer()
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, sent: sent, received: received}, nil
+	}
+}
+
+// statusRecorder is a http.ResponseWriter that remembers the status code it
+// was given, and whether the connection was hijacked.
+type statusRecorder struct {
+	http.ResponseWriter
+	status   int
+	hijacked bool
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.status == 0 {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(buf []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(buf)
+}
+
+// Flush implements http.Flusher.
+func (r *statusRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements http.Hijacker; a hijacked connection is always reported
+// as having switched protocols.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.hijacked = true
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap is used by http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status returns the status code of the response; if nothing was written, it
+// is reported as 200 (as net/http would).
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// countingConn is a net.Conn that counts the bytes that pass through it.
+type countingConn struct {
+	net.Conn
+	sent     prometheus.Counter
+	received prometheus.Counter
+}
+
+func (c *countingConn) Read(buf []byte) (int, error) {
+	n, err := c.Conn.Read(buf)
+	c.received.Add(float64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(buf []byte) (int, error) {
+	n, err := c.Conn.Write(buf)
+	c.sent.Add(float64(n))
+	return n, err
+}
+
+// apiEndpoints holds the API paths from the embedded specification, used to
+// convert request paths into low-cardinality metric labels.
+var apiEndpoints struct {
+	// simple paths, with no path templating.
+	simple map[string]struct{}
+	// patterns for paths with templating, mapped to the original API path.
+	patterns map[*regexp.Regexp]string
+}
+
+// apiVersionPattern matches the API version prefix of a request path.
+var apiVersionPattern = regexp.MustCompile(`^/v[0-9.]+/`)
+
+// apiEndpoint converts the given request path into the API path template it
+// matches, e.g. "/v1.41/containers/abc/json" becomes "/containers/{id}/json".
+func apiEndpoint(requestPath string) string {
+	if match := apiVersionPattern.FindStringIndex(requestPath); match != nil {
+		requestPath = requestPath[match[1]-1:]
+	}
+	if _, ok := apiEndpoints.simple[requestPath]; ok {
+		return requestPath
+	}
+	for pattern, apiPath := range apiEndpoints.patterns {
+		if pattern.MatchString(requestPath) {
+			return apiPath
+		}
+	}
+	return unknownEndpoint
+}
+
+// initAPIEndpoints fills in apiEndpoints from the given API paths.
+func initAPIEndpoints(paths []string) {
+	apiEndpoints.simple = make(map[string]struct{})
+	apiEndpoints.patterns = make(map[*regexp.Regexp]string)
+	for _, apiPath := range paths {
+		if pattern := convertPattern(apiPath); pattern == nil {
+			apiEndpoints.simple[apiPath] = struct{}{}
+		} else {
+			apiEndpoints.patterns[pattern] = apiPath
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/_ping":                      "/_ping",
+		"/v1.41/containers/json":      "/containers/json",
+		"/v1.41/containers/abc/json":  "/containers/{id}/json",
+		"/containers/abc/attach":      "/containers/{id}/attach",
+		"/v1.41/does/not/exist":       unknownEndpoint,
+		"/v1.41/containers/abc/wait/": unknownEndpoint,
+	}
+	for input, expected := range cases {
+		t.Run(input, func(t *testing.T) {
+			assert.Equal(t, expected, apiEndpoint(input))
+		})
+	}
+}
+
+func TestMetricsWrapHandler(t *testing.T) {
+	metrics := newProxyMetrics()
+	handler := metrics.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1.41/containers/abc/json", http.NoBody)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	counter := metrics.requests.WithLabelValues("/containers/{id}/json", http.MethodGet, "404")
+	assert.Equal(t, 1.0, testutil.ToFloat64(counter))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.inFlight))
+}
+
+func TestStatusRecorderUnwrap(t *testing.T) {
+	// The underlying writer is only reachable through Unwrap.
+	inner := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: &cachingResponseWriter{ResponseWriter: inner}}
+
+	recorder.Flush()
+	assert.True(t, inner.Flushed)
+
+	_, _, err := recorder.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+	assert.False(t, recorder.hijacked)
+}
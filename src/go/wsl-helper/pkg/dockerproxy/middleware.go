@@ -0,0 +1,280 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Middleware wraps the handling of docker API requests; it is given the next
+// handler in the chain, and returns a handler that should (normally) call it.
+type Middleware func(next http.Handler) http.Handler
+
+// RequestHook modifies a request just before it is sent to the backend.
+type RequestHook func(req *http.Request)
+
+// ResponseHook modifies a backend response before it is returned to the
+// client; returning an error causes the client to receive a gateway error.
+type ResponseHook func(resp *http.Response) error
+
+// ResponseHeaderHook modifies the headers of a response just before they are
+// sent to the client.  Unlike a ResponseHook, it also applies to responses
+// generated by the proxy itself (such as errors), but it can't change the
+// body.  It is not called for upgraded (hijacked) connections.
+type ResponseHeaderHook func(req *http.Request, statusCode int, header http.Header)
+
+// apiMatcher selects the requests that a registered middleware or hook applies
+// to.
+type apiMatcher struct {
+	// method is the HTTP method to match; if empty, all methods match.
+	method string
+	// apiPath is the API path (as in the API specification, e.g.
+	// "/containers/{id}/start") to match; if empty, all paths match.
+	apiPath string
+}
+
+func (m apiMatcher) matches(req *http.Request) bool {
+	if m.method != "" && m.method != req.Method {
+		return false
+	}
+	return m.apiPath == "" || m.apiPath == apiEndpoint(req.URL.Path)
+}
+
+// registeredHooks contains the middleware and hooks registered from outside of
+// Serve; these are run in the order they were registered.
+var registeredHooks struct {
+	sync.RWMutex
+	middlewares   []matchedMiddleware
+	requestHooks  []matchedRequestHook
+	responseHooks []matchedResponseHook
+	headerHooks   []matchedResponseHeaderHook
+	bodyWrappers  []matchedBodyWrapper
+}
+
+type matchedMiddleware struct {
+	apiMatcher
+	middleware Middleware
+}
+
+type matchedRequestHook struct {
+	apiMatcher
+	hook RequestHook
+}
+
+type matchedResponseHook struct {
+	apiMatcher
+	hook ResponseHook
+}
+
+type matchedResponseHeaderHook struct {
+	apiMatcher
+	hook ResponseHeaderHook
+}
+
+type matchedBodyWrapper struct {
+	apiMatcher
+	wrapper ResponseBodyWrapper
+}
+
+// RegisterMiddleware adds a middleware to be applied to requests matching the
+// given method and API path (either of which may be empty to match any).
+// Registered middleware runs after the built-in checks (such as policy rules),
+// in the order it was registered; it must be registered before Serve is
+// called.
+func RegisterMiddleware(method, apiPath string, middleware Middleware) {
+	registeredHooks.Lock()
+	defer registeredHooks.Unlock()
+	registeredHooks.middlewares = append(registeredHooks.middlewares, matchedMiddleware{
+		apiMatcher: apiMatcher{method: method, apiPath: apiPath},
+		middleware: middleware,
+	})
+}
+
+// RegisterRequestHook adds a hook to be run on requests matching the given
+// method and API path (either of which may be empty to match any), after the
+// request mungers.
+func RegisterRequestHook(method, apiPath string, hook RequestHook) {
+	registeredHooks.Lock()
+	defer registeredHooks.Unlock()
+	registeredHooks.requestHooks = append(registeredHooks.requestHooks, matchedRequestHook{
+		apiMatcher: apiMatcher{method: method, apiPath: apiPath},
+		hook:       hook,
+	})
+}
+
+// RegisterResponseHook adds a hook to be run on responses to requests matching
+// the given method and API path (either of which may be empty to match any),
+// after the response mungers.
+func RegisterResponseHook(method, apiPath string, hook ResponseHook) {
+	registeredHooks.Lock()
+	defer registeredHooks.Unlock()
+	registeredHooks.responseHooks = append(registeredHooks.responseHooks, matchedResponseHook{
+		apiMatcher: apiMatcher{method: method, apiPath: apiPath},
+		hook:       hook,
+	})
+}
+
+// RegisterResponseHeaderHook adds a hook to modify the headers of responses to
+// requests matching the given method and API path (either of which may be
+// empty to match any), right before they are written.
+func RegisterResponseHeaderHook(method, apiPath string, hook ResponseHeaderHook) {
+	registeredHooks.Lock()
+	defer registeredHooks.Unlock()
+	registeredHooks.headerHooks = append(registeredHooks.headerHooks, matchedResponseHeaderHook{
+		apiMatcher: apiMatcher{method: method, apiPath: apiPath},
+		hook:       hook,
+	})
+}
+
+// registeredMiddlewares returns the registered middleware, each limited to the
+// requests it was registered for.
+func registeredMiddlewares() []Middleware {
+	registeredHooks.RLock()
+	defer registeredHooks.RUnlock()
+	result := make([]Middleware, 0, len(registeredHooks.middlewares))
+	for _, entry := range registeredHooks.middlewares {
+		result = append(result, func(next http.Handler) http.Handler {
+			wrapped := entry.middleware(next)
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if entry.matches(req) {
+					wrapped.ServeHTTP(w, req)
+				} else {
+					next.ServeHTTP(w, req)
+				}
+			})
+		})
+	}
+	return result
+}
+
+// registeredRequestHooks returns the registered request hooks, each limited to
+// the requests it was registered for.
+func registeredRequestHooks() []RequestHook {
+	registeredHooks.RLock()
+	defer registeredHooks.RUnlock()
+	result := make([]RequestHook, 0, len(registeredHooks.requestHooks))
+	for _, entry := range registeredHooks.requestHooks {
+		result = append(result, func(req *http.Request) {
+			if entry.matches(req) {
+				entry.hook(req)
+			}
+		})
+	}
+	return result
+}
+
+// registeredResponseHooks returns the registered response hooks, each limited
+// to the requests it was registered for.
+func registeredResponseHooks() []ResponseHook {
+	registeredHooks.RLock()
+	defer registeredHooks.RUnlock()
+	result := make([]ResponseHook, 0, len(registeredHooks.responseHooks))
+	for _, entry := range registeredHooks.responseHooks {
+		result = append(result, func(resp *http.Response) error {
+			if entry.matches(resp.Request) {
+				return entry.hook(resp)
+			}
+			return nil
+		})
+	}
+	return result
+}
+
+// registeredResponseHeaderHooks returns the registered response header hooks,
+// each limited to the requests it was registered for.
+func registeredResponseHeaderHooks() []ResponseHeaderHook {
+	registeredHooks.RLock()
+	defer registeredHooks.RUnlock()
+	result := make([]ResponseHeaderHook, 0, len(registeredHooks.headerHooks))
+	for _, entry := range registeredHooks.headerHooks {
+		result = append(result, func(req *http.Request, statusCode int, header http.Header) {
+			if entry.matches(req) {
+				entry.hook(req, statusCode, header)
+			}
+		})
+	}
+	return result
+}
+
+// withResponseHeaderHooks returns a middleware that runs the given hooks on
+// the response headers before they are written.
+func withResponseHeaderHooks(hooks []ResponseHeaderHook) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(&headerHookWriter{ResponseWriter: w, req: req, hooks: hooks}, req)
+		})
+	}
+}
+
+// headerHookWriter is a http.ResponseWriter that runs response header hooks
+// when the (final) response headers are written.
+type headerHookWriter struct {
+	http.ResponseWriter
+	req         *http.Request
+	hooks       []ResponseHeaderHook
+	wroteHeader bool
+}
+
+func (w *headerHookWriter) WriteHeader(statusCode int) {
+	// Informational responses (e.g. 100 Continue) are followed by the real
+	// response, so only run the hooks for the final one.
+	if !w.wroteHeader && statusCode >= http.StatusOK {
+		w.wroteHeader = true
+		for _, hook := range w.hooks {
+			hook(w.req, statusCode, w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerHookWriter) Write(buf []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(buf)
+}
+
+func (w *headerHookWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack the connection; the hooks are not run, as the caller writes the
+// response headers itself.
+func (w *headerHookWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *headerHookWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// chainMiddleware wraps the handler in the given middleware; the first
+// middleware is the outermost (i.e. sees the request first).
+func chainMiddleware(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
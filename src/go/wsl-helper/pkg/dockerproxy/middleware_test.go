@@ -0,0 +1,142 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainMiddleware(t *testing.T) {
+	var calls []string
+	named := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, req)
+			})
+		}
+	}
+	handler := chainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	}), named("outer"), named("inner"))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/_ping", http.NoBody))
+	assert.Equal(t, []string{"outer", "inner", "handler"}, calls)
+}
+
+func TestRegisteredHooks(t *testing.T) {
+	registeredHooks.Lock()
+	savedMiddlewares := registeredHooks.middlewares
+	savedRequestHooks := registeredHooks.requestHooks
+	savedResponseHooks := registeredHooks.responseHooks
+	registeredHooks.Unlock()
+	t.Cleanup(func() {
+		registeredHooks.Lock()
+		defer registeredHooks.Unlock()
+		registeredHooks.middlewares = savedMiddlewares
+		registeredHooks.requestHooks = savedRequestHooks
+		registeredHooks.responseHooks = savedResponseHooks
+	})
+
+	var calls []string
+	RegisterMiddleware(http.MethodPost, "/containers/{id}/start", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "start")
+			next.ServeHTTP(w, req)
+		})
+	})
+	RegisterMiddleware("", "", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "all")
+			next.ServeHTTP(w, req)
+		})
+	})
+	RegisterRequestHook("", "/_ping", func(req *http.Request) {
+		calls = append(calls, "ping request")
+	})
+	RegisterResponseHook(http.MethodGet, "", func(resp *http.Response) error {
+		calls = append(calls, "get response")
+		return nil
+	})
+
+	handler := chainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}), registeredMiddlewares()...)
+	serve := func(method, path string) []string {
+		calls = nil
+		req := httptest.NewRequest(method, path, http.NoBody)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		for _, hook := range registeredRequestHooks() {
+			hook(req)
+		}
+		for _, hook := range registeredResponseHooks() {
+			assert.NoError(t, hook(&http.Response{Request: req}))
+		}
+		return calls
+	}
+
+	assert.Equal(t, []string{"start", "all"}, serve(http.MethodPost, "/v1.41/containers/abc/start"))
+	assert.Equal(t, []string{"all"}, serve(http.MethodPost, "/v1.41/containers/abc/stop"))
+	assert.Equal(t, []string{"all", "ping request", "get response"}, serve(http.MethodGet, "/_ping"))
+}
+
+func TestResponseHeaderHooks(t *testing.T) {
+	registeredHooks.Lock()
+	savedHeaderHooks := registeredHooks.headerHooks
+	registeredHooks.Unlock()
+	t.Cleanup(func() {
+		registeredHooks.Lock()
+		defer registeredHooks.Unlock()
+		registeredHooks.headerHooks = savedHeaderHooks
+	})
+
+	var statuses []int
+	RegisterResponseHeaderHook("", "", func(req *http.Request, statusCode int, header http.Header) {
+		statuses = append(statuses, statusCode)
+		header.Del("Docker-Experimental")
+		header.Set("Access-Control-Allow-Origin", "*")
+	})
+	RegisterResponseHeaderHook("", "/_ping", func(req *http.Request, statusCode int, header http.Header) {
+		header.Set("Server", "proxy")
+	})
+
+	handler := chainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Docker-Experimental", "false")
+		w.Header().Set("Server", "Docker")
+		if req.URL.Path == "/v1.41/info" {
+			w.WriteHeader(http.StatusContinue)
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = w.Write([]byte("OK"))
+	}), withResponseHeaderHooks(registeredResponseHeaderHooks()))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/_ping", http.NoBody))
+	assert.Equal(t, []int{http.StatusOK}, statuses)
+	assert.Empty(t, recorder.Header().Get("Docker-Experimental"))
+	assert.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "proxy", recorder.Header().Get("Server"))
+
+	statuses = nil
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1.41/info", http.NoBody))
+	assert.Equal(t, []int{http.StatusNotFound}, statuses, "hooks should only run for the final response")
+	assert.Equal(t, "Docker", recorder.Header().Get("Server"))
+}
@@ -0,0 +1,115 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// containerInspectPath is the API path for inspecting containers.
+const containerInspectPath = "/containers/{id}/json"
+
+// rewriteContainerInspect rewrites the bind mount host paths in the output of
+// GET /containers/{id}/json using any registered path rewriters, so that the
+// client sees the paths it originally asked for.  Only the relevant fields are
+// modified; everything else is passed through untouched.
+func rewriteContainerInspect(message json.RawMessage) (json.RawMessage, error) {
+	var body map[string]json.RawMessage
+	err := json.Unmarshal(message, &body)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse container inspect output: %w", err)
+	}
+	modified := false
+
+	if raw, ok := body["Mounts"]; ok {
+		var mounts []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &mounts); err != nil {
+			return nil, fmt.Errorf("could not parse container mounts: %w", err)
+		}
+		for _, mount := range mounts {
+			var mountType, source string
+			if json.Unmarshal(mount["Type"], &mountType) != nil || mountType != "bind" {
+				continue
+			}
+			if json.Unmarshal(mount["Source"], &source) != nil {
+				continue
+			}
+			rewritten, ok, err := dockerproxy.RewritePath(containerInspectPath, dockerproxy.PathToClient, source)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				mount["Source"], _ = json.Marshal(rewritten)
+				modified = true
+			}
+		}
+		if modified {
+			if body["Mounts"], err = json.Marshal(mounts); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if raw, ok := body["HostConfig"]; ok {
+		var hostConfig map[string]json.RawMessage
+		var binds []string
+		if json.Unmarshal(raw, &hostConfig) == nil && json.Unmarshal(hostConfig["Binds"], &binds) == nil {
+			bindsModified := false
+			for i, bind := range binds {
+				host, _, _, _ := platform.ParseBindString(bind)
+				rewritten, ok, err := dockerproxy.RewritePath(containerInspectPath, dockerproxy.PathToClient, host)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					binds[i] = rewritten + strings.TrimPrefix(bind, host)
+					bindsModified = true
+				}
+			}
+			if bindsModified {
+				hostConfig["Binds"], _ = json.Marshal(binds)
+				if body["HostConfig"], err = json.Marshal(hostConfig); err != nil {
+					return nil, err
+				}
+				modified = true
+			}
+		}
+	}
+
+	if !modified {
+		return message, nil
+	}
+	return json.Marshal(body)
+}
+
+func init() {
+	dockerproxy.RegisterResponseBodyWrapper(http.MethodGet, containerInspectPath, func(body io.ReadCloser, resp *http.Response) io.ReadCloser {
+		if resp.StatusCode != http.StatusOK || !dockerproxy.HasPathRewriters(containerInspectPath, dockerproxy.PathToClient) {
+			return body
+		}
+		return dockerproxy.FilterJSONStream(body, rewriteContainerInspect)
+	})
+}
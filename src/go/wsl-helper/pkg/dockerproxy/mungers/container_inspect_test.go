@@ -0,0 +1,75 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mungers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+)
+
+func TestRewriteContainerInspect(t *testing.T) {
+	const from, to = "/inspect-test-backend/", "/inspect-test-client/"
+	unregister := dockerproxy.RegisterPathRewriter(containerInspectPath, dockerproxy.PathToClient, func(hostPath string) (string, bool, error) {
+		if !strings.HasPrefix(hostPath, from) {
+			return hostPath, false, nil
+		}
+		return to + strings.TrimPrefix(hostPath, from), true, nil
+	})
+	t.Cleanup(unregister)
+
+	input := `{
+		"Id": "abc",
+		"Extra": {"Kept": [1, 2.50]},
+		"HostConfig": {"Binds": ["` + from + `a:/a:ro", "volume:/v"], "Privileged": false},
+		"Mounts": [
+			{"Type": "bind", "Source": "` + from + `b", "Destination": "/b"},
+			{"Type": "volume", "Source": "` + from + `c", "Destination": "/c"}
+		]
+	}`
+	output, err := rewriteContainerInspect(json.RawMessage(input))
+	require.NoError(t, err)
+
+	var result struct {
+		ID         string `json:"Id"`
+		Extra      json.RawMessage
+		HostConfig struct {
+			Binds      []string
+			Privileged bool
+		}
+		Mounts []struct {
+			Type   string
+			Source string
+		}
+	}
+	require.NoError(t, json.Unmarshal(output, &result))
+	assert.Equal(t, "abc", result.ID)
+	assert.JSONEq(t, `{"Kept": [1, 2.50]}`, string(result.Extra))
+	assert.Equal(t, []string{to + "a:/a:ro", "volume:/v"}, result.HostConfig.Binds)
+	assert.Equal(t, to+"b", result.Mounts[0].Source)
+	assert.Equal(t, from+"c", result.Mounts[1].Source, "non-bind mounts should not be rewritten")
+
+	unchanged := json.RawMessage(`{"Id":"def","Mounts":[]}`)
+	output, err = rewriteContainerInspect(unchanged)
+	require.NoError(t, err)
+	assert.Equal(t, string(unchanged), string(output))
+}
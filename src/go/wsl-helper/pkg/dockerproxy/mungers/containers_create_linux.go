@@ -221,6 +221,13 @@ func (b *bindManager) mungeContainersCreateRequest(req *http.Request, contextVal
 	for bindIndex, bind := range body.HostConfig.Binds {
 		logrus.WithField(fmt.Sprintf("bind %d", bindIndex), bind).Trace("got bind")
 		host, container, options, isPath := platform.ParseBindString(bind)
+		rewritten, ok, err := dockerproxy.RewritePath("/containers/create", dockerproxy.PathToBackend, host)
+		if err != nil {
+			return fmt.Errorf("could not rewrite bind path %s: %w", host, err)
+		}
+		if ok {
+			host, isPath = rewritten, true
+		}
 		if !isPath {
 			continue
 		}
@@ -242,6 +249,13 @@ func (b *bindManager) mungeContainersCreateRequest(req *http.Request, contextVal
 			logEntry.Trace("skipping mount of unsupported type")
 			continue
 		}
+		rewritten, ok, err := dockerproxy.RewritePath("/containers/create", dockerproxy.PathToBackend, mount.Source)
+		if err != nil {
+			return fmt.Errorf("could not rewrite mount path %s: %w", mount.Source, err)
+		}
+		if ok {
+			mount.Source = rewritten
+		}
 		if !path.IsAbs(mount.Source) {
 			logEntry.Trace("skipping non-host mount")
 			continue
@@ -253,7 +267,7 @@ func (b *bindManager) mungeContainersCreateRequest(req *http.Request, contextVal
 		mount.Source = path.Join(b.mountRoot, bindKey)
 		// Unlike .HostConfig.Binds, the source for .HostConfig.Mounts must
 		// exist at container create time.
-		err := b.prepareMountPath(target, bindKey)
+		err = b.prepareMountPath(target, bindKey)
 		if err != nil {
 			logEntry.WithError(err).Error("could not prepare mount volume")
 			return err
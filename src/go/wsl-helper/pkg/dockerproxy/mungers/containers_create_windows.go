@@ -49,11 +49,9 @@ func mungeContainersCreate(req *http.Request, contextValue *dockerproxy.RequestC
 	for bindIndex, bind := range body.HostConfig.Binds {
 		logrus.WithField(fmt.Sprintf("bind %d", bindIndex), bind).Debug("got bind")
 		host, container, options, isPath := platform.ParseBindString(bind)
-		if isPath {
-			translated, err := platform.TranslatePathFromClient(host)
-			if err != nil {
-				return fmt.Errorf("could not translate bind path %s: %w", host, err)
-			}
+		if translated, ok, err := translatePathFromClient(host, isPath); err != nil {
+			return fmt.Errorf("could not translate bind path %s: %w", host, err)
+		} else if ok {
 			host = translated
 			modified = true
 		}
@@ -75,13 +73,13 @@ func mungeContainersCreate(req *http.Request, contextValue *dockerproxy.RequestC
 			// We only support bind mounts for now
 			continue
 		}
-		if !platform.IsAbsolutePath(mount.Source) {
-			continue
-		}
-		translated, err := platform.TranslatePathFromClient(mount.Source)
+		translated, ok, err := translatePathFromClient(mount.Source, platform.IsAbsolutePath(mount.Source))
 		if err != nil {
 			return fmt.Errorf("could not translate mount path %s: %w", mount.Source, err)
 		}
+		if !ok {
+			continue
+		}
 		logrus.WithFields(logrus.Fields{
 			"mount":      mount,
 			"translated": translated,
@@ -105,6 +103,25 @@ func mungeContainersCreate(req *http.Request, contextValue *dockerproxy.RequestC
 	return nil
 }
 
+// translatePathFromClient converts a bind mount host path from the client to
+// the path dockerd should use, trying any registered path rewriters before
+// falling back to WSL path translation (only if isPath is set).  This returns
+// false if the path should be used as-is.
+func translatePathFromClient(hostPath string, isPath bool) (string, bool, error) {
+	rewritten, ok, err := dockerproxy.RewritePath("/containers/create", dockerproxy.PathToBackend, hostPath)
+	if err != nil || ok {
+		return rewritten, ok, err
+	}
+	if !isPath {
+		return hostPath, false, nil
+	}
+	translated, err := platform.TranslatePathFromClient(hostPath)
+	if err != nil {
+		return "", false, err
+	}
+	return translated, true, nil
+}
+
 func init() {
 	dockerproxy.RegisterRequestMunger(http.MethodPost, "/containers/create", mungeContainersCreate)
 }
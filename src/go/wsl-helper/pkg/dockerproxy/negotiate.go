@@ -0,0 +1,106 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+)
+
+// versionNegotiator rewrites requests for API versions newer than both the
+// backend and the embedded API specification support, so that newer clients
+// can talk to an older backend rather than getting an error.
+type versionNegotiator struct {
+	// specVersion is the API version of the embedded specification.
+	specVersion *semver.Version
+	// backendVersion is the maximum API version the backend supports, as
+	// reported by /_ping or /version; nil if not yet known.
+	backendVersion *semver.Version
+
+	sync.RWMutex
+}
+
+// newVersionNegotiator creates a version negotiator limited to the given API
+// version (usually that of the embedded specification).
+func newVersionNegotiator(specVersion *semver.Version) *versionNegotiator {
+	return &versionNegotiator{specVersion: specVersion}
+}
+
+// maxVersion returns the highest API version both the proxy and the backend
+// support.
+func (n *versionNegotiator) maxVersion() *semver.Version {
+	n.RLock()
+	defer n.RUnlock()
+	if n.backendVersion != nil && n.backendVersion.LessThan(n.specVersion) {
+		return n.backendVersion
+	}
+	return n.specVersion
+}
+
+// observeResponse records the backend API version from responses to the
+// /_ping and /version endpoints.
+func (n *versionNegotiator) observeResponse(resp *http.Response) {
+	endpoint := apiEndpoint(resp.Request.URL.Path)
+	if endpoint != "/_ping" && endpoint != "/version" {
+		return
+	}
+	backendVersion, err := semver.NewVersion(resp.Header.Get("API-Version"))
+	if err != nil {
+		return
+	}
+	n.Lock()
+	changed := n.backendVersion == nil || !n.backendVersion.Equal(backendVersion)
+	n.backendVersion = backendVersion
+	n.Unlock()
+	if changed {
+		requestLogger(resp.Request.Context()).
+			WithField("backend version", backendVersion).
+			Debug("detected backend API version")
+	}
+}
+
+// rewriteRequest changes the API version prefix of the request path, if it is
+// newer than the supported version, down to the supported version.
+func (n *versionNegotiator) rewriteRequest(req *http.Request) {
+	match := apiVersionPattern.FindString(req.URL.Path)
+	if match == "" {
+		return
+	}
+	requestVersion, err := semver.NewVersion(strings.Trim(match, "/v"))
+	if err != nil {
+		return
+	}
+	maxVersion := n.maxVersion()
+	if !requestVersion.GreaterThan(maxVersion) {
+		return
+	}
+	prefix := fmt.Sprintf("/v%d.%d/", maxVersion.Major(), maxVersion.Minor())
+	req.URL.Path = prefix + strings.TrimPrefix(req.URL.Path, match)
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = prefix + strings.TrimPrefix(req.URL.RawPath, match)
+	}
+	requestLogger(req.Context()).
+		WithField("requested version", requestVersion).
+		WithField("negotiated version", maxVersion).
+		Debug("rewrote request API version")
+}
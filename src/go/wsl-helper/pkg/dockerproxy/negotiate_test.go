@@ -0,0 +1,63 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionNegotiator(t *testing.T) {
+	negotiator := newVersionNegotiator(semver.MustParse("1.45"))
+	rewrite := func(path string) string {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		negotiator.rewriteRequest(req)
+		return req.URL.Path
+	}
+	observe := func(path, version string) {
+		resp := &http.Response{
+			Header:  http.Header{"Api-Version": []string{version}},
+			Request: httptest.NewRequest(http.MethodGet, path, http.NoBody),
+		}
+		negotiator.observeResponse(resp)
+	}
+
+	// Before the backend version is known, requests are limited to the version
+	// of the specification.
+	assert.Equal(t, "/v1.45/containers/json", rewrite("/v1.99/containers/json"))
+	assert.Equal(t, "/v1.41/containers/json", rewrite("/v1.41/containers/json"))
+	assert.Equal(t, "/containers/json", rewrite("/containers/json"))
+
+	// Only /_ping and /version are used to detect the backend version.
+	observe("/v1.45/containers/json", "1.30")
+	assert.Equal(t, "/v1.45/info", rewrite("/v1.46/info"))
+
+	observe("/_ping", "1.43")
+	assert.Equal(t, "/v1.43/info", rewrite("/v1.46/info"))
+	assert.Equal(t, "/v1.43/info", rewrite("/v1.43/info"))
+	assert.Equal(t, "/v1.24/info", rewrite("/v1.24/info"))
+
+	// The specification version is still respected with newer backends.
+	observe("/v1.47/version", "1.47")
+	assert.Equal(t, "/v1.45/info", rewrite("/v1.47/info"))
+}
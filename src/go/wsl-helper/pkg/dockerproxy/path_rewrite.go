@@ -0,0 +1,110 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"slices"
+	"sync"
+)
+
+// PathDirection is the direction in which a bind mount host path is being
+// rewritten.
+type PathDirection int
+
+const (
+	// PathToBackend rewrites paths in requests, from the form the client uses
+	// to the form the docker daemon uses.
+	PathToBackend PathDirection = iota
+	// PathToClient rewrites paths in responses, from the form the docker
+	// daemon uses back to the form the client uses.
+	PathToClient
+)
+
+// PathRewriter rewrites the host path of a bind mount.  If it does not apply
+// to the given path, it should return ok = false, and the next rewriter is
+// tried.
+type PathRewriter func(hostPath string) (rewritten string, ok bool, err error)
+
+// pathRewriters contains the registered rewriters, in registration order.
+var pathRewriters struct {
+	sync.RWMutex
+	entries []*pathRewriterEntry
+}
+
+type pathRewriterEntry struct {
+	// apiPath is the API path the rewriter applies to; if empty, it applies to
+	// all API paths.
+	apiPath   string
+	direction PathDirection
+	rewriter  PathRewriter
+}
+
+// RegisterPathRewriter adds a rule for rewriting bind mount host paths for the
+// given API path (as in the API specification, e.g. "/containers/create"; if
+// empty, all API paths) in the given direction.  Rewriters are tried in the
+// order they were registered, and the first that applies is used; if none
+// apply, the default platform translation (if any) is used.  The returned
+// function removes the rewriter again.
+func RegisterPathRewriter(apiPath string, direction PathDirection, rewriter PathRewriter) (unregister func()) {
+	pathRewriters.Lock()
+	defer pathRewriters.Unlock()
+	entry := &pathRewriterEntry{
+		apiPath:   apiPath,
+		direction: direction,
+		rewriter:  rewriter,
+	}
+	pathRewriters.entries = append(pathRewriters.entries, entry)
+	return func() {
+		pathRewriters.Lock()
+		defer pathRewriters.Unlock()
+		pathRewriters.entries = slices.DeleteFunc(pathRewriters.entries, func(e *pathRewriterEntry) bool {
+			return e == entry
+		})
+	}
+}
+
+// RewritePath applies the registered rewriters for the given API path and
+// direction to the host path; ok is false if none of them applied.
+func RewritePath(apiPath string, direction PathDirection, hostPath string) (rewritten string, ok bool, err error) {
+	pathRewriters.RLock()
+	defer pathRewriters.RUnlock()
+	for _, entry := range pathRewriters.entries {
+		if entry.direction != direction || (entry.apiPath != "" && entry.apiPath != apiPath) {
+			continue
+		}
+		rewritten, ok, err := entry.rewriter(hostPath)
+		if err != nil || ok {
+			return rewritten, ok, err
+		}
+	}
+	return hostPath, false, nil
+}
+
+// HasPathRewriters returns whether any rewriters are registered for the given
+// API path and direction.
+func HasPathRewriters(apiPath string, direction PathDirection) bool {
+	pathRewriters.RLock()
+	defer pathRewriters.RUnlock()
+	for _, entry := range pathRewriters.entries {
+		if entry.direction == direction && (entry.apiPath == "" || entry.apiPath == apiPath) {
+			return true
+		}
+	}
+	return false
+}
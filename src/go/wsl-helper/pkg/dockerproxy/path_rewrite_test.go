@@ -0,0 +1,85 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewritePath(t *testing.T) {
+	pathRewriters.Lock()
+	saved := pathRewriters.entries
+	pathRewriters.entries = nil
+	pathRewriters.Unlock()
+	t.Cleanup(func() {
+		pathRewriters.Lock()
+		defer pathRewriters.Unlock()
+		pathRewriters.entries = saved
+	})
+
+	prefixRewriter := func(from, to string) PathRewriter {
+		return func(hostPath string) (string, bool, error) {
+			if !strings.HasPrefix(hostPath, from) {
+				return hostPath, false, nil
+			}
+			return to + strings.TrimPrefix(hostPath, from), true, nil
+		}
+	}
+	RegisterPathRewriter("/containers/create", PathToBackend, prefixRewriter("/corp/", "/mnt/corp/"))
+	RegisterPathRewriter("", PathToBackend, prefixRewriter("/corp/", "/unused/"))
+	RegisterPathRewriter("", PathToBackend, prefixRewriter("/data/", "/mnt/data/"))
+	unregisterToClient := RegisterPathRewriter("", PathToClient, prefixRewriter("/mnt/corp/", "/corp/"))
+	RegisterPathRewriter("", PathToBackend, func(hostPath string) (string, bool, error) {
+		if hostPath == "/bad" {
+			return "", false, errors.New("bad path")
+		}
+		return hostPath, false, nil
+	})
+
+	cases := []struct {
+		apiPath   string
+		direction PathDirection
+		input     string
+		expected  string
+		ok        bool
+	}{
+		{"/containers/create", PathToBackend, "/corp/share", "/mnt/corp/share", true},
+		{"/other", PathToBackend, "/corp/share", "/unused/share", true},
+		{"/containers/create", PathToBackend, "/data/x", "/mnt/data/x", true},
+		{"/containers/create", PathToBackend, "/home/user", "/home/user", false},
+		{"/containers/{id}/json", PathToClient, "/mnt/corp/share", "/corp/share", true},
+	}
+	for _, testCase := range cases {
+		actual, ok, err := RewritePath(testCase.apiPath, testCase.direction, testCase.input)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, actual, "%+v", testCase)
+		assert.Equal(t, testCase.ok, ok, "%+v", testCase)
+	}
+	_, _, err := RewritePath("/containers/create", PathToBackend, "/bad")
+	assert.Error(t, err)
+
+	assert.True(t, HasPathRewriters("/containers/{id}/json", PathToClient))
+	unregisterToClient()
+	assert.False(t, HasPathRewriters("/containers/{id}/json", PathToClient))
+	assert.True(t, HasPathRewriters("/containers/create", PathToBackend), "other rewriters should be kept")
+}
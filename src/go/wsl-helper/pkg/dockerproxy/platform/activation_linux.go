@@ -0,0 +1,63 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// listenFDsStart is the first file descriptor passed in by systemd socket
+// activation; see sd_listen_fds(3).
+const listenFDsStart = 3
+
+// ActivationListener returns the listener passed in via systemd socket
+// activation, or nil if the process was not socket activated.  The
+// environment variables used for socket activation are removed, so that they
+// are not passed on to child processes.
+func ActivationListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, nil
+	}
+	for _, name := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(name)
+	}
+	if count > 1 {
+		logrus.WithField("count", count).Warn("Multiple sockets passed via socket activation, using the first one")
+	}
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		unix.CloseOnExec(fd)
+	}
+
+	file := os.NewFile(uintptr(listenFDsStart), "LISTEN_FD_3")
+	defer file.Close()
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not use socket activation listener: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// activationChildEnv is set when the test is re-executed as a socket
+// activated process.
+const activationChildEnv = "RD_TEST_ACTIVATION_CHILD"
+
+func TestActivationListener(t *testing.T) {
+	t.Run("not activated", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+		t.Setenv("LISTEN_FDS", "")
+		listener, err := ActivationListener()
+		assert.NoError(t, err)
+		assert.Nil(t, listener)
+	})
+	t.Run("other process", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		t.Setenv("LISTEN_FDS", "1")
+		listener, err := ActivationListener()
+		assert.NoError(t, err)
+		assert.Nil(t, listener)
+	})
+	t.Run("activated", func(t *testing.T) {
+		if os.Getenv(activationChildEnv) != "" {
+			// This is the re-executed test process, with the socket on fd 3.
+			listener, err := ActivationListener()
+			require.NoError(t, err)
+			require.NotNil(t, listener)
+			defer listener.Close()
+			_, ok := os.LookupEnv("LISTEN_FDS")
+			assert.False(t, ok, "LISTEN_FDS should be unset")
+			conn, err := listener.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte("activated"))
+			assert.NoError(t, err)
+			return
+		}
+
+		socketPath := filepath.Join(t.TempDir(), "activation.sock")
+		original, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		defer original.Close()
+		file, err := original.(*net.UnixListener).File()
+		require.NoError(t, err)
+		defer file.Close()
+
+		// Run this test again in a child process, passing the socket where
+		// systemd would put it.  LISTEN_PID must be the PID of the process
+		// that uses the socket, so it is set by a shell that then execs the
+		// test binary in its place.
+		cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ exec "$0" "$@"`,
+			os.Args[0], "-test.run=^TestActivationListener$/^activated$", "-test.v", "-test.count=1")
+		cmd.Env = append(os.Environ(), activationChildEnv+"=1", "LISTEN_FDS=1")
+		cmd.ExtraFiles = []*os.File{file}
+		output := &bytes.Buffer{}
+		cmd.Stdout = output
+		cmd.Stderr = output
+		require.NoError(t, cmd.Start())
+
+		conn, err := net.Dial("unix", socketPath)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetDeadline(time.Now().Add(30*time.Second)))
+		data, err := io.ReadAll(conn)
+		assert.NoError(t, err)
+		assert.Equal(t, "activated", string(data))
+		require.NoError(t, cmd.Wait(), "child output:\n%s", output)
+		assert.Contains(t, output.String(), "--- PASS: TestActivationListener/activated")
+	})
+}
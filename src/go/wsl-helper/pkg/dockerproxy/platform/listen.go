@@ -0,0 +1,53 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// tcpPrefix is the endpoint prefix for listening on a TCP address; this is
+// supported on all platforms, in addition to the platform-specific socket.
+const tcpPrefix = "tcp://"
+
+// listenTCP listens on the TCP address of the given endpoint (e.g.
+// "tcp://127.0.0.1:2375").
+func listenTCP(endpoint string) (net.Listener, error) {
+	addr := strings.TrimPrefix(endpoint, tcpPrefix)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", endpoint, err)
+	}
+	return listener, nil
+}
+
+// ListenOptions are optional settings for the socket created by Listen; they
+// are only used for unix sockets.
+type ListenOptions struct {
+	// Owner is the user name or ID to own the socket; if empty, the owner is
+	// not changed.
+	Owner string
+	// Group is the group name or ID to own the socket (e.g. "docker"); if
+	// empty, the group is not changed.
+	Group string
+	// Mode is the file mode of the socket; if zero, the socket is made
+	// accessible to everyone.
+	Mode os.FileMode
+}
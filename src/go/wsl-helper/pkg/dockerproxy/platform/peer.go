@@ -0,0 +1,59 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"fmt"
+	"net"
+)
+
+// PeerInfo describes the client process on the other end of a connection to
+// the proxy.
+type PeerInfo struct {
+	// PID is the process ID of the client, or zero if unknown.
+	PID int `json:"pid,omitempty"`
+	// UID is the user ID of the client, or -1 if unknown.
+	UID int `json:"uid"`
+}
+
+// UnknownPeer is the PeerInfo used when the client cannot be identified.
+var UnknownPeer = PeerInfo{UID: -1}
+
+func (p PeerInfo) String() string {
+	if p == UnknownPeer {
+		return "unknown"
+	}
+	return fmt.Sprintf("uid=%d,pid=%d", p.UID, p.PID)
+}
+
+// netConner is implemented by connection wrappers (such as *tls.Conn) that
+// can return the connection they wrap.
+type netConner interface {
+	NetConn() net.Conn
+}
+
+// unwrapConn returns the innermost connection of a (possibly) wrapped
+// connection.
+func unwrapConn(conn net.Conn) net.Conn {
+	for {
+		wrapper, ok := conn.(netConner)
+		if !ok {
+			return conn
+		}
+		conn = wrapper.NetConn()
+	}
+}
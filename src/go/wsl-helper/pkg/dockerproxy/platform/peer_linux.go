@@ -0,0 +1,47 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// GetPeerInfo returns information about the client of the given connection,
+// using the peer credentials of the unix socket.
+func GetPeerInfo(conn net.Conn) PeerInfo {
+	unixConn, ok := unwrapConn(conn).(*net.UnixConn)
+	if !ok {
+		return UnknownPeer
+	}
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		logrus.WithError(err).Debug("could not get raw connection for peer credentials")
+		return UnknownPeer
+	}
+	var cred *unix.Ucred
+	controlErr := rawConn.Control(func(fd uintptr) {
+		cred, err = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if controlErr != nil || err != nil {
+		logrus.WithError(err).WithField("control error", controlErr).Debug("could not get peer credentials")
+		return UnknownPeer
+	}
+	return PeerInfo{PID: int(cred.Pid), UID: int(cred.Uid)}
+}
@@ -0,0 +1,28 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"net"
+)
+
+// GetPeerInfo returns information about the client of the given connection.
+// go-winio does not expose the pipe handle, so we can't ask for the client
+// process; clients on Windows are always unknown.
+func GetPeerInfo(conn net.Conn) PeerInfo {
+	return UnknownPeer
+}
@@ -21,8 +21,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/user"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
@@ -48,17 +52,36 @@ func MakeDialer(proxyEndpoint string) (func() (net.Conn, error), error) {
 	return dialer, nil
 }
 
-// Listen on the given Unix socket endpoint.
-func Listen(endpoint string) (net.Listener, error) {
+// socketCheckInterval is how often we check that the socket we are listening
+// on still exists.
+const socketCheckInterval = 10 * time.Second
+
+// Listen on the given Unix socket endpoint.  If the socket file is removed or
+// replaced while we are listening, it is re-created.  TCP endpoints
+// ("tcp://host:port") are also accepted; the options do not apply to them.
+func Listen(endpoint string, options ListenOptions) (net.Listener, error) {
+	if strings.HasPrefix(endpoint, tcpPrefix) {
+		return listenTCP(endpoint)
+	}
 	prefix := "unix://"
 	if !strings.HasPrefix(endpoint, prefix) {
 		return nil, fmt.Errorf("endpoint %s does not start with protocol %s", endpoint, prefix)
 	}
 
 	filepath := endpoint[len(prefix):]
+	listener, err := listenUnix(filepath, options)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", endpoint, err)
+	}
+	return newRecreatingListener(filepath, options, listener), nil
+}
+
+// listenUnix listens on the given socket path, removing any stale socket and
+// setting the ownership and permissions of the new socket.
+func listenUnix(filepath string, options ListenOptions) (*net.UnixListener, error) {
 	addr, err := net.ResolveUnixAddr("unix", filepath)
 	if err != nil {
-		return nil, fmt.Errorf("could not resolve endpoint %s: %w", endpoint, err)
+		return nil, fmt.Errorf("could not resolve socket %s: %w", filepath, err)
 	}
 
 	// First, try to connect to it; if it's connection refused, then the socket
@@ -80,8 +103,10 @@ func Listen(endpoint string) (net.Listener, error) {
 
 	listener, err := net.ListenUnix("unix", addr)
 	if err != nil {
-		return nil, fmt.Errorf("could not listen on %s: %w", endpoint, err)
+		return nil, err
 	}
+	// We remove the socket file ourselves when re-creating it.
+	listener.SetUnlinkOnClose(false)
 
 	success := false
 	defer func() {
@@ -90,22 +115,202 @@ func Listen(endpoint string) (net.Listener, error) {
 		}
 	}()
 
-	var stat unix.Stat_t
-	err = unix.Stat(filepath, &stat)
-	if err != nil {
-		return nil, fmt.Errorf("could not get socket %s permissions: %w", filepath, err)
+	desiredPerms := options.Mode
+	if desiredPerms == 0 {
+		var stat unix.Stat_t
+		err = unix.Stat(filepath, &stat)
+		if err != nil {
+			return nil, fmt.Errorf("could not get socket %s permissions: %w", filepath, err)
+		}
+		desiredPerms = os.FileMode(stat.Mode | 0o777)
 	}
-
-	desiredPerms := os.FileMode(stat.Mode | 0o777)
 	err = os.Chmod(filepath, desiredPerms)
 	if err != nil {
 		return nil, fmt.Errorf("could not change socket %s permissions: %w", filepath, err)
 	}
 
+	if options.Owner != "" || options.Group != "" {
+		uid, gid, err := lookupOwner(options.Owner, options.Group)
+		if err != nil {
+			return nil, err
+		}
+		if err = os.Lchown(filepath, uid, gid); err != nil {
+			return nil, fmt.Errorf("could not change socket %s ownership: %w", filepath, err)
+		}
+	}
+
 	success = true
 	return listener, nil
 }
 
+// lookupOwner converts a user and group (either names or numeric IDs) into
+// numeric IDs; empty values are returned as -1 (i.e. unchanged).
+func lookupOwner(owner, group string) (int, int, error) {
+	uid, gid := -1, -1
+	if owner != "" {
+		id := owner
+		if _, err := strconv.Atoi(owner); err != nil {
+			u, err := user.Lookup(owner)
+			if err != nil {
+				return -1, -1, fmt.Errorf("could not find socket owner %s: %w", owner, err)
+			}
+			id = u.Uid
+		}
+		uid, _ = strconv.Atoi(id)
+	}
+	if group != "" {
+		id := group
+		if _, err := strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return -1, -1, fmt.Errorf("could not find socket group %s: %w", group, err)
+			}
+			id = g.Gid
+		}
+		gid, _ = strconv.Atoi(id)
+	}
+	return uid, gid, nil
+}
+
+// recreatingListener is a listener on a unix socket that re-creates the socket
+// if the file is removed (for example, by a cleanup script) or replaced.
+type recreatingListener struct {
+	filepath string
+	options  ListenOptions
+	conns    chan net.Conn
+	errs     chan error
+	done     chan struct{}
+	addr     net.Addr
+
+	closeOnce sync.Once
+	mu        sync.Mutex
+	current   *net.UnixListener
+	// inode is the inode of the socket file we created.
+	inode uint64
+}
+
+func newRecreatingListener(filepath string, options ListenOptions, listener *net.UnixListener) *recreatingListener {
+	l := &recreatingListener{
+		filepath: filepath,
+		options:  options,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error, 1),
+		done:     make(chan struct{}),
+		addr:     listener.Addr(),
+	}
+	l.use(listener)
+	go l.watch()
+	return l
+}
+
+// use starts accepting connections from the given listener.
+func (l *recreatingListener) use(listener *net.UnixListener) {
+	var stat unix.Stat_t
+	if err := unix.Stat(l.filepath, &stat); err == nil {
+		l.inode = stat.Ino
+	}
+	l.current = listener
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				l.mu.Lock()
+				replaced := l.current != listener
+				l.mu.Unlock()
+				if replaced {
+					return
+				}
+				select {
+				case l.errs <- err:
+				case <-l.done:
+				}
+				return
+			}
+			select {
+			case l.conns <- conn:
+			case <-l.done:
+				conn.Close()
+				return
+			}
+		}
+	}()
+}
+
+// watch periodically checks that the socket still exists, re-creating it if
+// necessary.
+func (l *recreatingListener) watch() {
+	ticker := time.NewTicker(socketCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+		l.check()
+	}
+}
+
+// check that the socket still exists, re-creating it if necessary.
+func (l *recreatingListener) check() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var stat unix.Stat_t
+	err := unix.Stat(l.filepath, &stat)
+	if err == nil && stat.Ino == l.inode {
+		return
+	}
+	logEntry := logrus.WithField("path", l.filepath)
+	if err == nil {
+		// Something else is at the path; only replace dead sockets.
+		if conn, dialErr := net.Dial("unix", l.filepath); dialErr == nil {
+			conn.Close()
+			logEntry.Error("another process is listening on the socket, not re-creating it")
+			l.inode = stat.Ino
+			return
+		}
+	}
+	logEntry.Warn("socket was removed or replaced, re-creating it")
+	listener, err := listenUnix(l.filepath, l.options)
+	if err != nil {
+		logEntry.WithError(err).Error("could not re-create socket")
+		return
+	}
+	old := l.current
+	l.use(listener)
+	old.Close()
+}
+
+func (l *recreatingListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *recreatingListener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		err = l.current.Close()
+		var stat unix.Stat_t
+		if unix.Stat(l.filepath, &stat) == nil && stat.Ino == l.inode {
+			_ = os.Remove(l.filepath)
+		}
+	})
+	return err
+}
+
+func (l *recreatingListener) Addr() net.Addr {
+	return l.addr
+}
+
 // ParseBindString parses a HostConfig.Binds entry, returning the (<host-src> or
 // <volume-name>), <container-dest>, and (optional) <options>.  Additionally, it
 // also returns a boolean indicating if the first argument is a host path.
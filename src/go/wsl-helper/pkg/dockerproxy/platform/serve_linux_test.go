@@ -0,0 +1,79 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package platform
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := Listen("unix://"+socketPath, ListenOptions{
+		Group: strconv.Itoa(os.Getgid()),
+		Mode:  0o660,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	accept := func() {
+		t.Helper()
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				conn.Close()
+			}
+		}()
+		conn, err := net.Dial("unix", socketPath)
+		require.NoError(t, err)
+		conn.Close()
+	}
+	accept()
+
+	// Removing the socket should cause it to be re-created.
+	require.NoError(t, os.Remove(socketPath))
+	listener.(*recreatingListener).check()
+	accept()
+
+	require.NoError(t, listener.Close())
+	assert.NoFileExists(t, socketPath)
+}
+
+func TestLookupOwner(t *testing.T) {
+	uid, gid, err := lookupOwner("", "")
+	require.NoError(t, err)
+	assert.Equal(t, -1, uid)
+	assert.Equal(t, -1, gid)
+
+	uid, gid, err = lookupOwner("0", "root")
+	require.NoError(t, err)
+	assert.Equal(t, 0, uid)
+	assert.Equal(t, 0, gid)
+
+	_, _, err = lookupOwner("no-such-user-for-testing", "")
+	assert.Error(t, err)
+}
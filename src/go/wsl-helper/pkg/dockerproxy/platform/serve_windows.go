@@ -51,6 +51,26 @@ func MakeDialer(port uint32) (func() (net.Conn, error), error) {
 	return dial, nil
 }
 
+// MakePipeDialer returns a dial function that connects to the given Windows
+// named pipe endpoint (e.g. "npipe:////./pipe/docker_engine"), for use with
+// container engines running natively on Windows.
+func MakePipeDialer(endpoint string) (func() (net.Conn, error), error) {
+	const prefix = "npipe://"
+
+	if !strings.HasPrefix(endpoint, prefix) {
+		return nil, fmt.Errorf("endpoint %s does not start with protocol %s", endpoint, prefix)
+	}
+	pipePath := endpoint[len(prefix):]
+	dial := func() (net.Conn, error) {
+		conn, err := winio.DialPipe(pipePath, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not dial named pipe %s: %w", pipePath, err)
+		}
+		return conn, nil
+	}
+	return dial, nil
+}
+
 // dialHvsock creates a net.Conn to a Hyper-V VM running Linux with the given
 // GUID, listening on the given vsock port.
 func dialHvsock(vmGUID hvsock.GUID, port uint32) (net.Conn, error) {
@@ -73,8 +93,13 @@ func dialHvsock(vmGUID hvsock.GUID, port uint32) (net.Conn, error) {
 	return conn, nil
 }
 
-// Listen on the given Windows named pipe endpoint.
-func Listen(endpoint string) (net.Listener, error) {
+// Listen on the given Windows named pipe endpoint.  TCP endpoints
+// ("tcp://host:port") are also accepted.  The options only apply to unix
+// sockets, and are ignored.
+func Listen(endpoint string, _ ListenOptions) (net.Listener, error) {
+	if strings.HasPrefix(endpoint, tcpPrefix) {
+		return listenTCP(endpoint)
+	}
 	const prefix = "npipe://"
 
 	if !strings.HasPrefix(endpoint, prefix) {
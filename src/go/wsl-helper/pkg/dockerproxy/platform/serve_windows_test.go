@@ -17,9 +17,14 @@ limitations under the License.
 package platform
 
 import (
+	"fmt"
+	"io"
 	"testing"
+	"time"
 
+	"github.com/Microsoft/go-winio"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseBindString(t *testing.T) {
@@ -49,3 +54,30 @@ func TestParseBindString(t *testing.T) {
 		})
 	}
 }
+
+func TestMakePipeDialer(t *testing.T) {
+	t.Parallel()
+	_, err := MakePipeDialer("unix:///var/run/docker.sock")
+	assert.Error(t, err)
+
+	pipePath := fmt.Sprintf(`\\.\pipe\rd-test-%d`, time.Now().UnixNano())
+	listener, err := winio.ListenPipe(pipePath, nil)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			_, _ = io.WriteString(conn, "hello")
+			conn.Close()
+		}
+	}()
+
+	dialer, err := MakePipeDialer("npipe://" + pipePath)
+	require.NoError(t, err)
+	conn, err := dialer()
+	require.NoError(t, err)
+	defer conn.Close()
+	buf, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+}
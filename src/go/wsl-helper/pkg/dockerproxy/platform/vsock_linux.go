@@ -39,6 +39,18 @@ func sockaddrToVsock(sa unix.Sockaddr) *vsock.Addr {
 	return nil
 }
 
+// MakeVsockDialer returns a dial function that connects directly to the given
+// vsock CID and port (e.g. a guest agent in a VM), without needing a relay.
+func MakeVsockDialer(cid, port uint32) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		conn, err := vsock.Dial(cid, port)
+		if err != nil {
+			return nil, fmt.Errorf("could not dial vsock %08x.%08x: %w", cid, port, err)
+		}
+		return conn, nil
+	}
+}
+
 // ListenVsockNonBlocking returns a net.Listener which can accept connections on the given port, returning non-blocking connections.
 func ListenVsockNonBlocking(cid, port uint32) (net.Listener, error) {
 	fd, err := syscall.Socket(unix.AF_VSOCK, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
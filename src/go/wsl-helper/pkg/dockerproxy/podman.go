@@ -0,0 +1,157 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// podmanAPIVersion is the libpod API version reported to podman clients.
+const podmanAPIVersion = "4.0.0"
+
+// podmanPrefixPattern matches the libpod prefix of a request path, with an
+// optional API version.
+var podmanPrefixPattern = regexp.MustCompile(`\A(?:/v[0-9][0-9.]*)?/libpod(/.*)\z`)
+
+// podmanEndpoint describes a libpod API endpoint that can be served by
+// forwarding it to the equivalent docker API endpoint.
+type podmanEndpoint struct {
+	method string
+	// libpod is the path of the libpod endpoint, without the prefix; "{id}"
+	// matches a single path segment, and "{name}" (for image names) matches
+	// one or more.
+	libpod string
+	// docker is the path of the docker endpoint, using the same templates.
+	docker string
+	// translateQuery, if set, converts the query parameters.
+	translateQuery func(query url.Values)
+
+	pattern *regexp.Regexp
+}
+
+// podmanEndpoints are the libpod endpoints we support; these are the ones
+// where the request is close enough to the docker API that it can be
+// translated.  Responses are in the docker format, which podman clients mostly
+// accept for these endpoints.
+var podmanEndpoints = []*podmanEndpoint{
+	{method: http.MethodGet, libpod: "/_ping", docker: "/_ping"},
+	{method: http.MethodHead, libpod: "/_ping", docker: "/_ping"},
+	{method: http.MethodGet, libpod: "/version", docker: "/version"},
+	{method: http.MethodGet, libpod: "/info", docker: "/info"},
+	{method: http.MethodGet, libpod: "/events", docker: "/events"},
+	{method: http.MethodGet, libpod: "/containers/json", docker: "/containers/json"},
+	{method: http.MethodGet, libpod: "/containers/{id}/json", docker: "/containers/{id}/json"},
+	{method: http.MethodGet, libpod: "/containers/{id}/logs", docker: "/containers/{id}/logs"},
+	{method: http.MethodGet, libpod: "/containers/{id}/top", docker: "/containers/{id}/top"},
+	{method: http.MethodPost, libpod: "/containers/{id}/start", docker: "/containers/{id}/start"},
+	{method: http.MethodPost, libpod: "/containers/{id}/stop", docker: "/containers/{id}/stop"},
+	{method: http.MethodPost, libpod: "/containers/{id}/restart", docker: "/containers/{id}/restart"},
+	{method: http.MethodPost, libpod: "/containers/{id}/kill", docker: "/containers/{id}/kill"},
+	{method: http.MethodPost, libpod: "/containers/{id}/pause", docker: "/containers/{id}/pause"},
+	{method: http.MethodPost, libpod: "/containers/{id}/unpause", docker: "/containers/{id}/unpause"},
+	{method: http.MethodPost, libpod: "/containers/{id}/wait", docker: "/containers/{id}/wait"},
+	{method: http.MethodPost, libpod: "/containers/{id}/exec", docker: "/containers/{id}/exec"},
+	{method: http.MethodDelete, libpod: "/containers/{id}", docker: "/containers/{id}"},
+	{method: http.MethodPost, libpod: "/exec/{id}/start", docker: "/exec/{id}/start"},
+	{method: http.MethodGet, libpod: "/exec/{id}/json", docker: "/exec/{id}/json"},
+	{method: http.MethodGet, libpod: "/images/json", docker: "/images/json"},
+	{method: http.MethodGet, libpod: "/images/{name}/json", docker: "/images/{name}/json"},
+	{method: http.MethodDelete, libpod: "/images/{name}", docker: "/images/{name}"},
+	{
+		method: http.MethodPost,
+		libpod: "/images/pull",
+		docker: "/images/create",
+		translateQuery: func(query url.Values) {
+			query["fromImage"] = query["reference"]
+			delete(query, "reference")
+		},
+	},
+	{method: http.MethodGet, libpod: "/networks/json", docker: "/networks"},
+	{method: http.MethodGet, libpod: "/volumes/json", docker: "/volumes"},
+}
+
+func init() {
+	for _, endpoint := range podmanEndpoints {
+		pattern := regexp.QuoteMeta(endpoint.libpod)
+		pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{id}"), `(?P<id>[^/]+)`)
+		pattern = strings.ReplaceAll(pattern, regexp.QuoteMeta("{name}"), `(?P<name>.+)`)
+		endpoint.pattern = regexp.MustCompile(`\A` + pattern + `\z`)
+	}
+}
+
+// translate returns the docker path for the given libpod path (without the
+// prefix), or false if the endpoint does not match.
+func (e *podmanEndpoint) translate(method, libpodPath string) (string, bool) {
+	if method != e.method {
+		return "", false
+	}
+	match := e.pattern.FindStringSubmatch(libpodPath)
+	if match == nil {
+		return "", false
+	}
+	result := e.docker
+	for i, name := range e.pattern.SubexpNames() {
+		if name != "" {
+			result = strings.ReplaceAll(result, "{"+name+"}", match[i])
+		}
+	}
+	return result, true
+}
+
+// podmanCompat is a Middleware that translates requests for the libpod API
+// into docker API requests, so that tools that only speak the podman API can
+// be used.  Unsupported libpod endpoints are rejected.
+func podmanCompat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		match := podmanPrefixPattern.FindStringSubmatch(req.URL.Path)
+		if match == nil {
+			next.ServeHTTP(w, req)
+			return
+		}
+		libpodPath := match[1]
+		for _, endpoint := range podmanEndpoints {
+			dockerPath, ok := endpoint.translate(req.Method, libpodPath)
+			if !ok {
+				continue
+			}
+			req = req.Clone(req.Context())
+			req.URL.Path = dockerPath
+			req.URL.RawPath = ""
+			if endpoint.translateQuery != nil {
+				query := req.URL.Query()
+				endpoint.translateQuery(query)
+				req.URL.RawQuery = query.Encode()
+			}
+			req.RequestURI = req.URL.RequestURI()
+			requestLogger(req.Context()).
+				WithField("libpod path", match[0]).
+				WithField("docker path", dockerPath).
+				Debug("translated libpod request")
+			w.Header().Set("Libpod-API-Version", podmanAPIVersion)
+			next.ServeHTTP(w, req)
+			return
+		}
+		writeErrorResponse(w, http.StatusNotImplemented,
+			fmt.Sprintf("libpod endpoint %s %s is not supported", req.Method, libpodPath))
+	})
+}
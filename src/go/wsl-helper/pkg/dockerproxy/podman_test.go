@@ -0,0 +1,72 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPodmanCompat(t *testing.T) {
+	var forwarded *http.Request
+	handler := podmanCompat(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		forwarded = req
+		w.WriteHeader(http.StatusOK)
+	}))
+	cases := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/v4.0.0/libpod/_ping", "/_ping"},
+		{http.MethodGet, "/libpod/containers/json?all=true", "/containers/json?all=true"},
+		{http.MethodPost, "/v4.9.3/libpod/containers/abc/start", "/containers/abc/start"},
+		{http.MethodGet, "/v4.0.0/libpod/images/docker.io/library/alpine:latest/json", "/images/docker.io/library/alpine:latest/json"},
+		{http.MethodPost, "/v4.0.0/libpod/images/pull?reference=docker.io%2Flibrary%2Falpine", "/images/create?fromImage=docker.io%2Flibrary%2Falpine"},
+		{http.MethodGet, "/v4.0.0/libpod/networks/json", "/networks"},
+		{http.MethodGet, "/v1.41/containers/json", "/v1.41/containers/json"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.method+" "+testCase.path, func(t *testing.T) {
+			forwarded = nil
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(testCase.method, testCase.path, http.NoBody))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			if assert.NotNil(t, forwarded) {
+				assert.Equal(t, testCase.expected, forwarded.URL.RequestURI())
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		forwarded = nil
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v4.0.0/libpod/pods/create", http.NoBody))
+		assert.Equal(t, http.StatusNotImplemented, recorder.Code)
+		assert.Nil(t, forwarded)
+	})
+	t.Run("version header", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v4.0.0/libpod/version", http.NoBody))
+		assert.Equal(t, podmanAPIVersion, recorder.Header().Get("Libpod-API-Version"))
+	})
+}
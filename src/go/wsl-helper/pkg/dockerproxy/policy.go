@@ -0,0 +1,291 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/models"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// PolicyRequest is the information about an incoming request that is made
+// available to policy rules.
+type PolicyRequest struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Endpoint is the API path template the request matched, as returned from
+	// the embedded API specification (e.g. "/containers/{id}/start").
+	Endpoint string
+	// Query is the parsed query string of the request.
+	Query url.Values
+	// Container is the body of a POST /containers/create request; it is nil
+	// for all other requests.
+	Container *ContainerCreateBody
+	// Exec is the body of a POST /containers/{id}/exec request; it is nil for
+	// all other requests.
+	Exec *ExecCreateBody
+}
+
+// ContainerCreateBody describes the contents of a /containers/create request.
+type ContainerCreateBody struct {
+	models.ContainerConfig
+	HostConfig models.HostConfig
+}
+
+// ExecCreateBody describes the contents of a /containers/{id}/exec request.
+type ExecCreateBody struct {
+	Cmd        []string
+	User       string
+	Privileged bool
+}
+
+// PolicyRule is a rule that can reject incoming requests before they are
+// forwarded to the backend.
+type PolicyRule interface {
+	// Check the request, returning an error describing why it is denied, or
+	// nil if the request is allowed.
+	Check(req *PolicyRequest) error
+}
+
+// PolicyRuleFunc adapts an ordinary function to a PolicyRule.
+type PolicyRuleFunc func(req *PolicyRequest) error
+
+func (f PolicyRuleFunc) Check(req *PolicyRequest) error {
+	return f(req)
+}
+
+// PolicyConfig is the on-disk format of a policy file (JSON or YAML).
+type PolicyConfig struct {
+	// DenyPrivileged rejects privileged containers and exec sessions.
+	DenyPrivileged bool `yaml:"denyPrivileged"`
+	// DenyHostPID rejects containers that share the host PID namespace.
+	DenyHostPID bool `yaml:"denyHostPID"`
+	// AllowedBindPaths, if not empty, restricts bind mounts to host paths at
+	// or under one of the given paths.
+	AllowedBindPaths []string `yaml:"allowedBindPaths"`
+	// MinHostPort, if set, rejects publishing container ports on host ports
+	// lower than this.
+	MinHostPort int `yaml:"minHostPort"`
+}
+
+// LoadPolicyFile reads a policy file, returning the rules it describes.  As
+// YAML is a superset of JSON, either format is accepted.
+func LoadPolicyFile(policyPath string) ([]PolicyRule, error) {
+	buf, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read policy file %s: %w", policyPath, err)
+	}
+	var config PolicyConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("could not parse policy file %s: %w", policyPath, err)
+	}
+	return config.Rules(), nil
+}
+
+// Rules returns the policy rules described by the configuration.
+func (c *PolicyConfig) Rules() []PolicyRule {
+	var rules []PolicyRule
+	if c.DenyPrivileged {
+		rules = append(rules, PolicyRuleFunc(denyPrivileged))
+	}
+	if c.DenyHostPID {
+		rules = append(rules, PolicyRuleFunc(denyHostPID))
+	}
+	if len(c.AllowedBindPaths) > 0 {
+		rules = append(rules, allowedBindPaths(c.AllowedBindPaths))
+	}
+	if c.MinHostPort > 0 {
+		rules = append(rules, minHostPort(c.MinHostPort))
+	}
+	return rules
+}
+
+func denyPrivileged(req *PolicyRequest) error {
+	if req.Container != nil && req.Container.HostConfig.Privileged {
+		return fmt.Errorf("privileged containers are not allowed")
+	}
+	if req.Exec != nil && req.Exec.Privileged {
+		return fmt.Errorf("privileged exec sessions are not allowed")
+	}
+	return nil
+}
+
+func denyHostPID(req *PolicyRequest) error {
+	if req.Container != nil && req.Container.HostConfig.PidMode == "host" {
+		return fmt.Errorf("sharing the host PID namespace is not allowed")
+	}
+	return nil
+}
+
+// allowedBindPaths restricts the host paths that may be bind mounted.
+type allowedBindPaths []string
+
+func (a allowedBindPaths) Check(req *PolicyRequest) error {
+	if req.Container == nil {
+		return nil
+	}
+	for _, bind := range req.Container.HostConfig.Binds {
+		host, _, _, isPath := platform.ParseBindString(bind)
+		if isPath && !a.allows(host) {
+			return fmt.Errorf("bind mount of %s is not allowed", host)
+		}
+	}
+	for _, mount := range req.Container.HostConfig.Mounts {
+		if mount.Type == "bind" && !a.allows(mount.Source) {
+			return fmt.Errorf("bind mount of %s is not allowed", mount.Source)
+		}
+	}
+	return nil
+}
+
+// allows checks if the given host path is at or under an allowed path.
+func (a allowedBindPaths) allows(hostPath string) bool {
+	hostPath = normalizePolicyPath(hostPath)
+	for _, allowed := range a {
+		allowed = normalizePolicyPath(allowed)
+		if hostPath == allowed || strings.HasPrefix(hostPath, strings.TrimSuffix(allowed, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePolicyPath cleans up a path for comparison; on Windows, paths are
+// compared case-insensitively.
+func normalizePolicyPath(input string) string {
+	result := filepath.Clean(input)
+	if runtime.GOOS == "windows" {
+		result = strings.ToLower(result)
+	}
+	return result
+}
+
+// minHostPort rejects publishing ports below the given host port.
+type minHostPort int
+
+func (m minHostPort) Check(req *PolicyRequest) error {
+	if req.Container == nil {
+		return nil
+	}
+	for containerPort, bindings := range req.Container.HostConfig.PortBindings {
+		for _, binding := range bindings {
+			port, err := strconv.Atoi(binding.HostPort)
+			if err != nil || port == 0 {
+				// An empty host port means an ephemeral port is allocated.
+				continue
+			}
+			if port < int(m) {
+				return fmt.Errorf("publishing %s on host port %d is not allowed (minimum %d)", containerPort, port, int(m))
+			}
+		}
+	}
+	return nil
+}
+
+// policyEnforcer rejects requests that fail any of its rules.
+type policyEnforcer struct {
+	rules []PolicyRule
+}
+
+// wrapHandler returns a handler that checks requests against the policy
+// before passing them to the given handler.
+func (p *policyEnforcer) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		policyReq, err := newPolicyRequest(req)
+		if err == nil {
+			err = p.check(policyReq)
+		}
+		if err != nil {
+			requestLogger(req.Context()).WithError(err).Warn("request denied by policy")
+			writeErrorResponse(w, http.StatusForbidden, fmt.Sprintf("request denied by policy: %s", err))
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// check the request against each rule in turn.
+func (p *policyEnforcer) check(req *PolicyRequest) error {
+	for _, rule := range p.rules {
+		if err := rule.Check(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newPolicyRequest builds a PolicyRequest from an incoming request, parsing
+// the body for the endpoints that have one we understand.
+func newPolicyRequest(req *http.Request) (*PolicyRequest, error) {
+	result := &PolicyRequest{
+		Method:   req.Method,
+		Endpoint: apiEndpoint(req.URL.Path),
+		Query:    req.URL.Query(),
+	}
+	if req.Method != http.MethodPost {
+		return result, nil
+	}
+	var target interface{}
+	switch result.Endpoint {
+	case "/containers/create":
+		result.Container = &ContainerCreateBody{}
+		target = result.Container
+	case "/containers/{id}/exec":
+		result.Exec = &ExecCreateBody{}
+		target = result.Exec
+	default:
+		return result, nil
+	}
+	buf, err := peekRequestBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not read request body: %w", err)
+	}
+	if len(buf) > 0 {
+		if err := json.Unmarshal(buf, target); err != nil {
+			return nil, fmt.Errorf("could not parse request body: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// writeErrorResponse writes an error in the format the docker API uses, so
+// that clients can display the message.  If the response has a request ID, it
+// is included in the message so that users can quote it when reporting bugs.
+func writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		message = fmt.Sprintf("%s (request ID %s)", message, id)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
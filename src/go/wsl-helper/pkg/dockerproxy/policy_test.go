@@ -0,0 +1,144 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPolicyFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+		require.NoError(t, os.WriteFile(policyPath, []byte("denyPrivileged: true\nminHostPort: 1024\n"), 0o600))
+		rules, err := LoadPolicyFile(policyPath)
+		require.NoError(t, err)
+		assert.Len(t, rules, 2)
+	})
+	t.Run("json", func(t *testing.T) {
+		policyPath := filepath.Join(t.TempDir(), "policy.json")
+		require.NoError(t, os.WriteFile(policyPath, []byte(`{"denyHostPID": true, "allowedBindPaths": ["/home"]}`), 0o600))
+		rules, err := LoadPolicyFile(policyPath)
+		require.NoError(t, err)
+		assert.Len(t, rules, 2)
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		policyPath := filepath.Join(t.TempDir(), "policy.yaml")
+		require.NoError(t, os.WriteFile(policyPath, []byte("denyEverything: true\n"), 0o600))
+		_, err := LoadPolicyFile(policyPath)
+		assert.Error(t, err)
+	})
+}
+
+func TestPolicyEnforcer(t *testing.T) {
+	allowedPath := "/home/user"
+	if runtime.GOOS == "windows" {
+		allowedPath = `C:\Users\user`
+	}
+	config := PolicyConfig{
+		DenyPrivileged:   true,
+		DenyHostPID:      true,
+		AllowedBindPaths: []string{allowedPath},
+		MinHostPort:      1024,
+	}
+	enforcer := &policyEnforcer{rules: config.Rules()}
+	handler := enforcer.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	cases := map[string]struct {
+		path     string
+		body     string
+		expected int
+	}{
+		"plain container": {
+			path:     "/v1.41/containers/create",
+			body:     `{"Image":"alpine"}`,
+			expected: http.StatusCreated,
+		},
+		"privileged container": {
+			path:     "/v1.41/containers/create",
+			body:     `{"Image":"alpine","HostConfig":{"Privileged":true}}`,
+			expected: http.StatusForbidden,
+		},
+		"privileged exec": {
+			path:     "/v1.41/containers/abc/exec",
+			body:     `{"Cmd":["sh"],"Privileged":true}`,
+			expected: http.StatusForbidden,
+		},
+		"host pid": {
+			path:     "/v1.41/containers/create",
+			body:     `{"HostConfig":{"PidMode":"host"}}`,
+			expected: http.StatusForbidden,
+		},
+		"low port": {
+			path:     "/v1.41/containers/create",
+			body:     `{"HostConfig":{"PortBindings":{"80/tcp":[{"HostPort":"80"}]}}}`,
+			expected: http.StatusForbidden,
+		},
+		"high port": {
+			path:     "/v1.41/containers/create",
+			body:     `{"HostConfig":{"PortBindings":{"80/tcp":[{"HostPort":"8080"},{"HostPort":""}]}}}`,
+			expected: http.StatusCreated,
+		},
+		"bind mount outside allowlist": {
+			path:     "/v1.41/containers/create",
+			body:     `{"HostConfig":{"Mounts":[{"Type":"bind","Source":"/etc","Target":"/etc"}]}}`,
+			expected: http.StatusForbidden,
+		},
+		"unparsable body": {
+			path:     "/v1.41/containers/create",
+			body:     `{`,
+			expected: http.StatusForbidden,
+		},
+		"other endpoint": {
+			path:     "/v1.41/containers/abc/start",
+			body:     `{`,
+			expected: http.StatusCreated,
+		},
+	}
+	for name, testCase := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, testCase.path, strings.NewReader(testCase.body))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			assert.Equal(t, testCase.expected, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestAllowedBindPaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses unix paths")
+	}
+	allowed := allowedBindPaths{"/home/user/"}
+	assert.True(t, allowed.allows("/home/user"))
+	assert.True(t, allowed.allows("/home/user/project"))
+	assert.True(t, allowed.allows("/home/user/project/../other"))
+	assert.False(t, allowed.allows("/home/username"))
+	assert.False(t, allowed.allows("/home/user/../other"))
+}
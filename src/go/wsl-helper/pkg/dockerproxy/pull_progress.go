@@ -0,0 +1,188 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pullProgressPath is the path of the proxy's own endpoint reporting the
+// progress of in-flight image pulls; it is handled by the proxy rather than
+// forwarded to the backend.
+const pullProgressPath = "/__rd/pulls"
+
+// pullProgress describes the progress of a single in-flight image pull.
+type pullProgress struct {
+	ID      uint64    `json:"id"`
+	Image   string    `json:"image"`
+	Tag     string    `json:"tag,omitempty"`
+	Started time.Time `json:"started"`
+	// Layers is the progress of each layer, keyed by layer ID.
+	Layers map[string]*layerProgress `json:"layers"`
+	// Current and Total are the number of bytes downloaded so far, and the
+	// expected total, across all layers whose size is known.
+	Current int64 `json:"current"`
+	Total   int64 `json:"total"`
+	// Percent is the overall progress, from 0 to 100.
+	Percent float64 `json:"percent"`
+}
+
+// layerProgress describes the progress of a single layer of an image pull.
+type layerProgress struct {
+	Status  string `json:"status"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`
+}
+
+// pullProgressMessage is a message in the output of /images/create.
+type pullProgressMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// pullTracker keeps track of the image pulls going through the proxy.
+type pullTracker struct {
+	pulls  map[uint64]*pullProgress
+	nextID uint64
+	sync.Mutex
+}
+
+func newPullTracker() *pullTracker {
+	return &pullTracker{pulls: make(map[uint64]*pullProgress)}
+}
+
+// observeResponse is a ResponseHook that tracks the progress of image pulls
+// as their output is streamed to the client.
+func (t *pullTracker) observeResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK || apiEndpoint(resp.Request.URL.Path) != "/images/create" {
+		return nil
+	}
+	query := resp.Request.URL.Query()
+	if query.Get("fromImage") == "" {
+		return nil
+	}
+	t.Lock()
+	t.nextID++
+	pull := &pullProgress{
+		ID:      t.nextID,
+		Image:   query.Get("fromImage"),
+		Tag:     query.Get("tag"),
+		Started: time.Now(),
+		Layers:  make(map[string]*layerProgress),
+	}
+	t.pulls[pull.ID] = pull
+	t.Unlock()
+
+	body := FilterJSONStream(resp.Body, func(message json.RawMessage) (json.RawMessage, error) {
+		var progress pullProgressMessage
+		if err := json.Unmarshal(message, &progress); err == nil && progress.ID != "" {
+			t.update(pull, &progress)
+		}
+		return message, nil
+	})
+	resp.Body = &closeNotifier{ReadCloser: body, onClose: func() {
+		t.Lock()
+		delete(t.pulls, pull.ID)
+		t.Unlock()
+	}}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// update the pull with a progress message.
+func (t *pullTracker) update(pull *pullProgress, message *pullProgressMessage) {
+	t.Lock()
+	defer t.Unlock()
+	layer, ok := pull.Layers[message.ID]
+	if !ok {
+		layer = &layerProgress{}
+		pull.Layers[message.ID] = layer
+	}
+	layer.Status = message.Status
+	switch message.Status {
+	case "Downloading":
+		layer.Current = message.ProgressDetail.Current
+		layer.Total = message.ProgressDetail.Total
+	case "Download complete", "Pull complete", "Already exists":
+		layer.Current = layer.Total
+	}
+	pull.Current, pull.Total = 0, 0
+	for _, layer := range pull.Layers {
+		if layer.Total > 0 {
+			pull.Current += layer.Current
+			pull.Total += layer.Total
+		}
+	}
+	if pull.Total > 0 {
+		pull.Percent = float64(pull.Current) * 100 / float64(pull.Total)
+	}
+}
+
+// ServeHTTP reports the in-flight pulls, oldest first.
+func (t *pullTracker) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		writeErrorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	t.Lock()
+	buf, err := json.Marshal(t.snapshot())
+	t.Unlock()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(append(buf, '\n'))
+}
+
+// snapshot returns the in-flight pulls, oldest first; the lock must be held
+// while the result is being used.
+func (t *pullTracker) snapshot() []*pullProgress {
+	result := make([]*pullProgress, 0, len(t.pulls))
+	for _, pull := range t.pulls {
+		result = append(result, pull)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// closeNotifier is a reader that runs a function once it is closed.
+type closeNotifier struct {
+	io.ReadCloser
+	onClose func()
+	once    sync.Once
+}
+
+func (r *closeNotifier) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.onClose)
+	return err
+}
@@ -0,0 +1,95 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullTracker(t *testing.T) {
+	tracker := newPullTracker()
+	reader, writer := io.Pipe()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       reader,
+		Request:    httptest.NewRequest(http.MethodPost, "/v1.41/images/create?fromImage=alpine&tag=latest", http.NoBody),
+	}
+	require.NoError(t, tracker.observeResponse(resp))
+	output := bufio.NewReader(resp.Body)
+
+	getPulls := func() []pullProgress {
+		recorder := httptest.NewRecorder()
+		tracker.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, pullProgressPath, http.NoBody))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var result []pullProgress
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		return result
+	}
+	send := func(message string) {
+		_, err := io.WriteString(writer, message+"\n")
+		require.NoError(t, err)
+		// Wait for the message to be passed through.
+		_, err = output.ReadString('\n')
+		require.NoError(t, err)
+	}
+
+	send(`{"status":"Pulling from library/alpine","id":"latest"}`)
+	send(`{"status":"Downloading","progressDetail":{"current":25,"total":100},"id":"layer1"}`)
+	send(`{"status":"Downloading","progressDetail":{"current":50,"total":300},"id":"layer2"}`)
+	pulls := getPulls()
+	require.Len(t, pulls, 1)
+	assert.Equal(t, "alpine", pulls[0].Image)
+	assert.Equal(t, "latest", pulls[0].Tag)
+	assert.EqualValues(t, 75, pulls[0].Current)
+	assert.EqualValues(t, 400, pulls[0].Total)
+	assert.InDelta(t, 18.75, pulls[0].Percent, 0.001)
+	assert.Equal(t, &layerProgress{Status: "Downloading", Current: 25, Total: 100}, pulls[0].Layers["layer1"])
+
+	send(`{"status":"Pull complete","progressDetail":{},"id":"layer1"}`)
+	pulls = getPulls()
+	require.Len(t, pulls, 1)
+	assert.EqualValues(t, 150, pulls[0].Current)
+
+	require.NoError(t, writer.Close())
+	require.NoError(t, resp.Body.Close())
+	assert.Empty(t, getPulls())
+}
+
+func TestPullTrackerIgnoresOtherRequests(t *testing.T) {
+	tracker := newPullTracker()
+	body := http.NoBody
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       body,
+		Request:    httptest.NewRequest(http.MethodPost, "/v1.41/images/create?fromSrc=-", http.NoBody),
+	}
+	require.NoError(t, tracker.observeResponse(resp))
+	assert.Equal(t, body, resp.Body)
+	assert.Empty(t, tracker.pulls)
+}
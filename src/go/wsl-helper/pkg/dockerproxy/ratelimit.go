@@ -0,0 +1,146 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// rateLimiterIdleTimeout is how long a client's rate limiter is kept after its
+// last request.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// DefaultRateLimitBypass is the list of API endpoints that are not rate
+// limited by default; these are long-lived streams, so each request is not a
+// significant amount of load.
+var DefaultRateLimitBypass = []string{
+	"/_ping",
+	"/events",
+	"/containers/{id}/attach",
+	"/containers/{id}/attach/ws",
+	"/containers/{id}/logs",
+	"/containers/{id}/stats",
+	"/containers/{id}/wait",
+	"/exec/{id}/start",
+	"/session",
+}
+
+// rateLimiter applies a token bucket rate limit to each client.
+type rateLimiter struct {
+	limit  rate.Limit
+	burst  int
+	bypass map[string]struct{}
+
+	clients     map[string]*rateLimiterEntry
+	lastCleanup time.Time
+	sync.Mutex
+}
+
+// rateLimiterEntry is the token bucket for a single client.
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newRateLimiter creates a rate limiter allowing each client the given number
+// of requests per second, with the given burst size.  Requests to the given
+// API endpoints are never limited.
+func newRateLimiter(requestsPerSecond float64, burst int, bypass []string) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	bypassSet := make(map[string]struct{}, len(bypass))
+	for _, endpoint := range bypass {
+		bypassSet[endpoint] = struct{}{}
+	}
+	return &rateLimiter{
+		limit:   rate.Limit(requestsPerSecond),
+		burst:   burst,
+		bypass:  bypassSet,
+		clients: make(map[string]*rateLimiterEntry),
+	}
+}
+
+// clientKey returns the key used to identify the client making the request:
+// the user ID if it's known, or the connection otherwise.
+func clientKey(req *http.Request) string {
+	if peer := peerFromContext(req.Context()); peer != platform.UnknownPeer {
+		return fmt.Sprintf("uid:%d", peer.UID)
+	}
+	return fmt.Sprintf("conn:%d", connIDFromContext(req.Context()))
+}
+
+// reserve a token for the given client, returning how long the client must
+// wait before the request is allowed (zero if it may proceed immediately).
+func (r *rateLimiter) reserve(key string, now time.Time) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	if now.Sub(r.lastCleanup) > time.Minute {
+		for clientKey, entry := range r.clients {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTimeout {
+				delete(r.clients, clientKey)
+			}
+		}
+		r.lastCleanup = now
+	}
+
+	entry, ok := r.clients[key]
+	if !ok {
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(r.limit, r.burst)}
+		r.clients[key] = entry
+	}
+	entry.lastSeen = now
+	reservation := entry.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return rateLimiterIdleTimeout
+	}
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// We reject the request rather than waiting, so give the token back.
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// wrapHandler returns a handler that rejects requests from clients that have
+// exceeded their rate limit.
+func (r *rateLimiter) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if _, ok := r.bypass[apiEndpoint(req.URL.Path)]; !ok {
+			key := clientKey(req)
+			if delay := r.reserve(key, time.Now()); delay > 0 {
+				requestLogger(req.Context()).WithField("client", key).Debug("rate limit exceeded")
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				writeErrorResponse(w, http.StatusTooManyRequests, "too many requests; please retry later")
+				return
+			}
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
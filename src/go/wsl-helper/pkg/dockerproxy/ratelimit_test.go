@@ -0,0 +1,66 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(1, 2, DefaultRateLimitBypass)
+	handler := limiter.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string, uid int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		ctx := context.WithValue(req.Context(), peerContextKey{}, platform.PeerInfo{UID: uid})
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req.WithContext(ctx))
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/v1.41/containers/json", 1000).Code)
+	assert.Equal(t, http.StatusOK, serve("/v1.41/containers/json", 1000).Code)
+	limited := serve("/v1.41/containers/json", 1000)
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "1", limited.Header().Get("Retry-After"))
+
+	// Other clients are not affected.
+	assert.Equal(t, http.StatusOK, serve("/v1.41/containers/json", 1001).Code)
+	// Streaming endpoints are not limited.
+	assert.Equal(t, http.StatusOK, serve("/v1.41/containers/abc/logs", 1000).Code)
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	limiter := newRateLimiter(1, 1, nil)
+	now := time.Now()
+	assert.Zero(t, limiter.reserve("old", now))
+	assert.Zero(t, limiter.reserve("new", now.Add(rateLimiterIdleTimeout)))
+	assert.Zero(t, limiter.reserve("new", now.Add(rateLimiterIdleTimeout+2*time.Minute)))
+	assert.NotContains(t, limiter.clients, "old")
+	assert.Contains(t, limiter.clients, "new")
+}
@@ -0,0 +1,276 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultRegistry is the registry used for image references without one.
+const defaultRegistry = "docker.io"
+
+// registryMirrorKey is the RequestContextValue key for the original image
+// reference of a pull that was redirected to a mirror.
+type registryMirrorKey struct{}
+
+// mirroredPull describes a pull that was redirected to a mirror.
+type mirroredPull struct {
+	// original is the repository the client asked for, e.g. "docker.io/library/alpine".
+	original string
+	// mirrored is the repository actually pulled, e.g. "mirror.example.com/library/alpine".
+	mirrored string
+	// tag is the tag being pulled.
+	tag string
+}
+
+// registryMirror redirects image pulls from some registries to mirrors, and
+// then tags the pulled images with their original names so that clients can
+// refer to them as usual.
+type registryMirror struct {
+	// mirrors maps a registry host (e.g. "docker.io") to the mirror that
+	// should be used instead, optionally with a path prefix (e.g.
+	// "mirror.example.com/dockerhub").
+	mirrors map[string]string
+	client  *http.Client
+}
+
+// newRegistryMirror creates a registry mirror using the given map of
+// registries to mirrors; it uses the dialer to retag pulled images.
+func newRegistryMirror(mirrors map[string]string, dialer func(context.Context) (net.Conn, error)) *registryMirror {
+	normalized := make(map[string]string, len(mirrors))
+	for registry, mirror := range mirrors {
+		normalized[normalizeRegistry(registry)] = strings.TrimSuffix(mirror, "/")
+	}
+	return &registryMirror{
+		mirrors: normalized,
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer(ctx)
+				},
+			},
+		},
+	}
+}
+
+// splitImageReference splits an image reference into the registry host and
+// the remainder, filling in the defaults used by docker.
+func splitImageReference(image string) (string, string) {
+	registry, remainder, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry, remainder = defaultRegistry, image
+	}
+	registry = normalizeRegistry(registry)
+	if registry == defaultRegistry && !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+	return registry, remainder
+}
+
+// normalizeRegistry returns the canonical name of a registry host.
+func normalizeRegistry(registry string) string {
+	registry = strings.ToLower(registry)
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io":
+		return defaultRegistry
+	}
+	return registry
+}
+
+// rewriteRequest is a RequestHook that redirects image pulls to mirrors.
+func (m *registryMirror) rewriteRequest(req *http.Request) {
+	if req.Method != http.MethodPost || apiEndpoint(req.URL.Path) != "/images/create" {
+		return
+	}
+	query := req.URL.Query()
+	image := query.Get("fromImage")
+	if image == "" {
+		// This is an import (fromSrc), not a pull.
+		return
+	}
+	registry, remainder := splitImageReference(image)
+	mirror, ok := m.mirrors[registry]
+	if !ok {
+		return
+	}
+	mirroredImage := mirror + "/" + remainder
+	query.Set("fromImage", mirroredImage)
+	req.URL.RawQuery = query.Encode()
+	m.rewriteAuth(req, registry, mirror)
+
+	logger := requestLogger(req.Context()).
+		WithField("image", image).
+		WithField("mirrored image", mirroredImage)
+	logger.Debug("redirecting image pull to mirror")
+
+	// Remember what we did, so that we can fix up the tags once the pull
+	// completes.  Pulls by digest (or of all tags) are not retagged.
+	tag := query.Get("tag")
+	if tag == "" || strings.Contains(tag, ":") || strings.Contains(remainder, "@") {
+		return
+	}
+	if contextValue, ok := req.Context().Value(requestContext).(*RequestContextValue); ok {
+		(*contextValue)[registryMirrorKey{}] = &mirroredPull{
+			original: registry + "/" + remainder,
+			mirrored: mirroredImage,
+			tag:      tag,
+		}
+	}
+}
+
+// rewriteAuth updates the server address in the X-Registry-Auth header (if
+// any) to refer to the mirror.
+func (m *registryMirror) rewriteAuth(req *http.Request, registry, mirror string) {
+	header := req.Header.Get("X-Registry-Auth")
+	if header == "" {
+		return
+	}
+	buf, err := base64.URLEncoding.DecodeString(header)
+	if err != nil {
+		// The docker CLI sometimes omits padding.
+		buf, err = base64.RawURLEncoding.DecodeString(header)
+	}
+	if err != nil {
+		requestLogger(req.Context()).WithError(err).Debug("could not decode registry auth")
+		return
+	}
+	var auth map[string]interface{}
+	if err := json.Unmarshal(buf, &auth); err != nil {
+		requestLogger(req.Context()).WithError(err).Debug("could not parse registry auth")
+		return
+	}
+	serverAddress, _ := auth["serveraddress"].(string)
+	if serverAddress != "" {
+		parsed, err := url.Parse(serverAddress)
+		host := serverAddress
+		if err == nil && parsed.Host != "" {
+			host = parsed.Host
+		}
+		if normalizeRegistry(host) != registry {
+			// The credentials are for some other registry; leave them alone.
+			return
+		}
+	}
+	auth["serveraddress"], _, _ = strings.Cut(mirror, "/")
+	if buf, err = json.Marshal(auth); err != nil {
+		return
+	}
+	req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(buf))
+}
+
+// observeResponse is a ResponseHook that, for pulls redirected to a mirror,
+// tags the image with its original name once the pull succeeds.
+func (m *registryMirror) observeResponse(resp *http.Response) error {
+	contextValue, ok := resp.Request.Context().Value(requestContext).(*RequestContextValue)
+	if !ok || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	pull, ok := (*contextValue)[registryMirrorKey{}].(*mirroredPull)
+	if !ok {
+		return nil
+	}
+	ctx := resp.Request.Context()
+	var failed atomic.Bool
+	body := FilterJSONStream(resp.Body, func(message json.RawMessage) (json.RawMessage, error) {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(message, &progress); err == nil && progress.Error != "" {
+			failed.Store(true)
+		}
+		return message, nil
+	})
+	resp.Body = &eofNotifier{ReadCloser: body, onEOF: func() {
+		if failed.Load() {
+			return
+		}
+		if err := m.retag(context.WithoutCancel(ctx), pull); err != nil {
+			requestLogger(ctx).WithError(err).
+				WithField("image", pull.original).
+				Error("failed to tag image pulled from mirror")
+		}
+	}}
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+	return nil
+}
+
+// retag tags the mirrored image with the original name, then removes the
+// mirrored tag.
+func (m *registryMirror) retag(ctx context.Context, pull *mirroredPull) error {
+	mirroredRef := pull.mirrored + ":" + pull.tag
+	query := url.Values{"repo": {pull.original}, "tag": {pull.tag}}
+	tagURL := fmt.Sprintf("http://proxy.invalid/images/%s/tag?%s", mirroredRef, query.Encode())
+	if err := m.do(ctx, http.MethodPost, tagURL); err != nil {
+		return fmt.Errorf("failed to tag %s as %s:%s: %w", mirroredRef, pull.original, pull.tag, err)
+	}
+	deleteURL := fmt.Sprintf("http://proxy.invalid/images/%s?noprune=1", mirroredRef)
+	if err := m.do(ctx, http.MethodDelete, deleteURL); err != nil {
+		return fmt.Errorf("failed to remove tag %s: %w", mirroredRef, err)
+	}
+	requestLogger(ctx).
+		WithField("image", pull.original+":"+pull.tag).
+		WithField("mirrored image", mirroredRef).
+		Debug("tagged image pulled from mirror")
+	return nil
+}
+
+// do makes a request to the backend, returning an error unless it succeeds.
+func (m *registryMirror) do(ctx context.Context, method, requestURL string) error {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(buf)))
+	}
+	return nil
+}
+
+// eofNotifier is a reader that runs a function once it reaches the end of its
+// input.
+type eofNotifier struct {
+	io.ReadCloser
+	onEOF func()
+	once  sync.Once
+}
+
+func (r *eofNotifier) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	if err == io.EOF {
+		r.once.Do(r.onEOF)
+	}
+	return n, err
+}
@@ -0,0 +1,130 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitImageReference(t *testing.T) {
+	cases := map[string][2]string{
+		"alpine":                          {"docker.io", "library/alpine"},
+		"alpine:3.19":                     {"docker.io", "library/alpine:3.19"},
+		"rancher/k3s":                     {"docker.io", "rancher/k3s"},
+		"index.docker.io/library/alpine":  {"docker.io", "library/alpine"},
+		"ghcr.io/rancher-sandbox/rd":      {"ghcr.io", "rancher-sandbox/rd"},
+		"localhost/image":                 {"localhost", "image"},
+		"registry.example.com:5000/image": {"registry.example.com:5000", "image"},
+	}
+	for image, expected := range cases {
+		registry, remainder := splitImageReference(image)
+		assert.Equal(t, expected, [2]string{registry, remainder}, image)
+	}
+}
+
+func TestRegistryMirror(t *testing.T) {
+	var lock sync.Mutex
+	var calls []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls = append(calls, req.Method+" "+req.URL.RequestURI())
+	}))
+	defer backend.Close()
+	mirror := newRegistryMirror(map[string]string{"docker.io": "mirror.example.com/hub/"},
+		func(ctx context.Context) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "tcp", backend.Listener.Addr().String())
+		})
+
+	newRequest := func(query string, auth map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/v1.41/images/create?"+query, http.NoBody)
+		if auth != nil {
+			buf, err := json.Marshal(auth)
+			require.NoError(t, err)
+			req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(buf))
+		}
+		return req.WithContext(context.WithValue(req.Context(), requestContext, &RequestContextValue{}))
+	}
+
+	t.Run("mirrored pull", func(t *testing.T) {
+		calls = nil
+		req := newRequest("fromImage=alpine&tag=3.19", map[string]string{
+			"username":      "user",
+			"serveraddress": "https://index.docker.io/v1/",
+		})
+		mirror.rewriteRequest(req)
+		assert.Equal(t, "mirror.example.com/hub/library/alpine", req.URL.Query().Get("fromImage"))
+		assert.Equal(t, "3.19", req.URL.Query().Get("tag"))
+		buf, err := base64.URLEncoding.DecodeString(req.Header.Get("X-Registry-Auth"))
+		require.NoError(t, err)
+		var auth map[string]string
+		require.NoError(t, json.Unmarshal(buf, &auth))
+		assert.Equal(t, map[string]string{"username": "user", "serveraddress": "mirror.example.com"}, auth)
+
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"Pulling"}` + "\n" + `{"status":"Done"}`)),
+			Request:    req,
+		}
+		require.NoError(t, mirror.observeResponse(resp))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"status":"Pulling"}`+"\n"+`{"status":"Done"}`+"\n", string(body))
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, []string{
+			"POST /images/mirror.example.com/hub/library/alpine:3.19/tag?repo=docker.io%2Flibrary%2Falpine&tag=3.19",
+			"DELETE /images/mirror.example.com/hub/library/alpine:3.19?noprune=1",
+		}, calls)
+	})
+	t.Run("failed pull", func(t *testing.T) {
+		calls = nil
+		req := newRequest("fromImage=alpine&tag=latest", nil)
+		mirror.rewriteRequest(req)
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(`{"error":"not found"}`)),
+			Request:    req,
+		}
+		require.NoError(t, mirror.observeResponse(resp))
+		_, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Empty(t, calls)
+	})
+	t.Run("other registry", func(t *testing.T) {
+		req := newRequest("fromImage=ghcr.io%2Fexample%2Fimage&tag=latest", map[string]string{"serveraddress": "ghcr.io"})
+		header := req.Header.Get("X-Registry-Auth")
+		mirror.rewriteRequest(req)
+		assert.Equal(t, "ghcr.io/example/image", req.URL.Query().Get("fromImage"))
+		assert.Equal(t, header, req.Header.Get("X-Registry-Auth"))
+	})
+}
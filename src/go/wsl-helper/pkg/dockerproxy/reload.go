@@ -0,0 +1,201 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reloadDelay is how long to wait after a change in one of the files listed
+// in ServeOptions.ReloadWatchFiles before reloading.
+const reloadDelay = 500 * time.Millisecond
+
+// reloadableConfig holds the parts of the proxy configuration that can be
+// changed while it is running.  Each request uses the configuration that was
+// current when it arrived, so that reloading does not affect requests (and
+// streams) that are in progress.
+type reloadableConfig struct {
+	limiter  *rateLimiter
+	enforcer *policyEnforcer
+	limits   *requestLimits
+	mirror   *registryMirror
+	filter   *eventFilter
+}
+
+// newReloadableConfig creates the reloadable configuration from the given
+// options; the dialer is used by the registry mirror to tag images.
+func newReloadableConfig(options *ServeOptions, dialer func(context.Context) (net.Conn, error)) (*reloadableConfig, error) {
+	endpointTimeouts, err := newEndpointTimeouts(options.EndpointTimeouts)
+	if err != nil {
+		return nil, err
+	}
+	config := &reloadableConfig{}
+	if options.RateLimit > 0 {
+		config.limiter = newRateLimiter(options.RateLimit, options.RateLimitBurst, options.RateLimitBypass)
+	}
+	if len(options.PolicyRules) > 0 {
+		config.enforcer = &policyEnforcer{rules: options.PolicyRules}
+	}
+	if options.MaxRequestBodySize > 0 || options.RequestTimeout > 0 || len(endpointTimeouts) > 0 {
+		config.limits = &requestLimits{
+			maxBodySize:      options.MaxRequestBodySize,
+			timeout:          options.RequestTimeout,
+			endpointTimeouts: endpointTimeouts,
+		}
+	}
+	if len(options.RegistryMirrors) > 0 {
+		config.mirror = newRegistryMirror(options.RegistryMirrors, dialer)
+	}
+	if len(options.EventFilters) > 0 {
+		config.filter = &eventFilter{rules: options.EventFilters}
+	}
+	return config, nil
+}
+
+// reloadableConfigKey is the context key for the reloadable configuration
+// used for the request.
+type reloadableConfigKey struct{}
+
+// configFromContext returns the reloadable configuration used for the request
+// with the given context; the result is never nil.
+func configFromContext(ctx context.Context) *reloadableConfig {
+	if config, ok := ctx.Value(reloadableConfigKey{}).(*reloadableConfig); ok {
+		return config
+	}
+	return &reloadableConfig{}
+}
+
+// configReloader holds the current reloadable configuration.
+type configReloader struct {
+	current atomic.Pointer[reloadableConfig]
+	dialer  func(context.Context) (net.Conn, error)
+	load    func() (ServeOptions, error)
+	logger  logrus.FieldLogger
+	// mu prevents concurrent reloads.
+	mu sync.Mutex
+}
+
+// newConfigReloader creates a reloader with the given initial configuration;
+// load is called to get the new options on reload, and may be nil if the
+// configuration cannot be reloaded.
+func newConfigReloader(initial *reloadableConfig, dialer func(context.Context) (net.Conn, error), load func() (ServeOptions, error), logger logrus.FieldLogger) *configReloader {
+	r := &configReloader{dialer: dialer, load: load, logger: logger}
+	r.current.Store(initial)
+	return r
+}
+
+// reload the configuration; on failure, the existing configuration is kept.
+func (r *configReloader) reload() {
+	if r.load == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	options, err := r.load()
+	if err == nil {
+		var config *reloadableConfig
+		config, err = newReloadableConfig(&options, r.dialer)
+		if err == nil {
+			r.current.Store(config)
+		}
+	}
+	if err != nil {
+		r.logger.WithError(err).Error("could not reload configuration, keeping the existing one")
+		return
+	}
+	r.logger.Info("reloaded configuration")
+}
+
+// run reloads the configuration on SIGHUP, or when any of the given files
+// change, until the context is cancelled.
+func (r *configReloader) run(ctx context.Context, watchFiles []string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+	if len(watchFiles) > 0 {
+		watcher, err := newFileWatcher(watchFiles, reloadDelay, r.logger, r.reload)
+		if err != nil {
+			r.logger.WithError(err).Warn("could not watch configuration files for changes")
+		} else {
+			defer watcher.Close()
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			r.reload()
+		}
+	}
+}
+
+// wrapHandler returns a handler that applies the rate limit, policy, and
+// request limits from the current configuration, and makes the configuration
+// available to the request and response hooks.
+func (r *configReloader) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		config := r.current.Load()
+		next := handler
+		if config.limits != nil {
+			next = config.limits.wrapHandler(next)
+		}
+		if config.enforcer != nil {
+			next = config.enforcer.wrapHandler(next)
+		}
+		if config.limiter != nil {
+			next = config.limiter.wrapHandler(next)
+		}
+		ctx := context.WithValue(req.Context(), reloadableConfigKey{}, config)
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// rewriteRequest is a RequestHook that applies the registry mirrors from the
+// request's configuration.
+func (r *configReloader) rewriteRequest(req *http.Request) {
+	if mirror := configFromContext(req.Context()).mirror; mirror != nil {
+		mirror.rewriteRequest(req)
+	}
+}
+
+// observeResponse is a ResponseHook that applies the registry mirrors and
+// event filters from the request's configuration.
+func (r *configReloader) observeResponse(resp *http.Response) error {
+	config := configFromContext(resp.Request.Context())
+	if config.mirror != nil {
+		if err := config.mirror.observeResponse(resp); err != nil {
+			return err
+		}
+	}
+	if config.filter != nil {
+		return config.filter.filterResponse(resp)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigReloader(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	dialer := func(context.Context) (net.Conn, error) {
+		return nil, errors.New("no backend")
+	}
+	var next ServeOptions
+	var loadErr error
+	load := func() (ServeOptions, error) {
+		return next, loadErr
+	}
+	initial, err := newReloadableConfig(&ServeOptions{}, dialer)
+	require.NoError(t, err)
+	reloader := newConfigReloader(initial, dialer, load, logger)
+
+	var seen *reloadableConfig
+	handler := reloader.wrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		seen = configFromContext(req.Context())
+		w.WriteHeader(http.StatusCreated)
+	}))
+	serve := func() int {
+		body := `{"Image":"alpine","HostConfig":{"Privileged":true}}`
+		req := httptest.NewRequest(http.MethodPost, "/v1.41/containers/create", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, serve())
+	inFlight := seen
+
+	next = ServeOptions{
+		PolicyRules:     []PolicyRule{PolicyRuleFunc(denyPrivileged)},
+		RegistryMirrors: map[string]string{"docker.io": "mirror.example.com"},
+	}
+	reloader.reload()
+	assert.Equal(t, http.StatusForbidden, serve())
+	assert.Nil(t, inFlight.mirror, "existing requests should keep their configuration")
+	assert.NotNil(t, reloader.current.Load().mirror)
+
+	// A failed reload keeps the existing configuration.
+	next = ServeOptions{EndpointTimeouts: map[string]time.Duration{"[": 0}}
+	reloader.reload()
+	assert.Equal(t, http.StatusForbidden, serve())
+	loadErr = errors.New("could not load")
+	next = ServeOptions{}
+	reloader.reload()
+	assert.Equal(t, http.StatusForbidden, serve())
+}
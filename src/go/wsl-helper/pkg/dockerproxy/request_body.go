@@ -0,0 +1,54 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxInspectedBodySize is the largest request body we will read in order to
+// inspect it before forwarding.
+const maxInspectedBodySize = 1024 * 1024
+
+// errBodyTooLarge is returned by peekRequestBody if the body is larger than
+// maxInspectedBodySize.
+var errBodyTooLarge = errors.New("request body too large to inspect")
+
+// peekRequestBody reads the body of the request (up to maxInspectedBodySize)
+// and replaces it so that it can still be forwarded unchanged.
+func peekRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(req.Body, maxInspectedBodySize+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > maxInspectedBodySize {
+		return nil, errBodyTooLarge
+	}
+	return buf, nil
+}
@@ -0,0 +1,116 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ResponseBodyWrapper wraps the body of a backend response, so that it can be
+// filtered or rewritten as it is streamed to the client (rather than being
+// read in full first, as a ResponseHook would have to).  The returned reader
+// is closed in place of the original body, so it must close the original.
+type ResponseBodyWrapper func(body io.ReadCloser, resp *http.Response) io.ReadCloser
+
+// RegisterResponseBodyWrapper adds a wrapper for the bodies of responses to
+// requests matching the given method and API path (either of which may be
+// empty to match any).  Wrappers are applied in the order they are
+// registered, after any response hooks.
+func RegisterResponseBodyWrapper(method, apiPath string, wrapper ResponseBodyWrapper) {
+	registeredHooks.Lock()
+	defer registeredHooks.Unlock()
+	registeredHooks.bodyWrappers = append(registeredHooks.bodyWrappers, matchedBodyWrapper{
+		apiMatcher: apiMatcher{method: method, apiPath: apiPath},
+		wrapper:    wrapper,
+	})
+}
+
+// wrapResponseBody applies the registered body wrappers to the response.
+// If any of them replaces the body, its length may change, so the response is
+// switched to chunked encoding, which also causes it to be flushed to the
+// client as it streams.  Wrappers that return the body as is (because they
+// don't apply to this response) leave the length alone.
+func wrapResponseBody(resp *http.Response) {
+	registeredHooks.RLock()
+	defer registeredHooks.RUnlock()
+	replaced := false
+	for _, entry := range registeredHooks.bodyWrappers {
+		if entry.matches(resp.Request) {
+			body := entry.wrapper(resp.Body, resp)
+			if body != resp.Body {
+				resp.Body = body
+				replaced = true
+			}
+		}
+	}
+	if replaced {
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+	}
+}
+
+// FilterJSONStream returns a reader that passes each JSON value in the given
+// stream (such as the output of /events or /build) through the filter as it
+// arrives, writing the result as a line of JSON; if the filter returns nil,
+// the value is dropped.
+func FilterJSONStream(body io.ReadCloser, filter func(message json.RawMessage) (json.RawMessage, error)) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+		decoder := json.NewDecoder(body)
+		for {
+			var message json.RawMessage
+			if err := decoder.Decode(&message); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				writer.CloseWithError(err)
+				return
+			}
+			result, err := filter(message)
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			if result == nil {
+				continue
+			}
+			if _, err := writer.Write(append(result, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+	return &pipedBody{PipeReader: reader, body: body}
+}
+
+// pipedBody is a response body produced by a goroutine reading from another
+// body (as in FilterJSONStream); closing it also closes the underlying body, so
+// that the goroutine exits.
+type pipedBody struct {
+	*io.PipeReader
+	body io.Closer
+}
+
+func (r *pipedBody) Close() error {
+	_ = r.PipeReader.Close()
+	return r.body.Close()
+}
@@ -0,0 +1,96 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterJSONStream(t *testing.T) {
+	input := `{"status":"start"}` + "\n" + `{"status":"die"}` + "\n" + `{"status":"stop"}`
+	reader := FilterJSONStream(io.NopCloser(strings.NewReader(input)), func(message json.RawMessage) (json.RawMessage, error) {
+		if bytes.Contains(message, []byte("die")) {
+			return nil, nil
+		}
+		return bytes.ToUpper(message), nil
+	})
+	defer reader.Close()
+	output, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, `{"STATUS":"START"}`+"\n"+`{"STATUS":"STOP"}`+"\n", string(output))
+}
+
+func TestWrapResponseBody(t *testing.T) {
+	registeredHooks.Lock()
+	saved := registeredHooks.bodyWrappers
+	registeredHooks.Unlock()
+	t.Cleanup(func() {
+		registeredHooks.Lock()
+		defer registeredHooks.Unlock()
+		registeredHooks.bodyWrappers = saved
+	})
+	RegisterResponseBodyWrapper(http.MethodGet, "/events", func(body io.ReadCloser, resp *http.Response) io.ReadCloser {
+		return io.NopCloser(io.MultiReader(body, strings.NewReader(" wrapped")))
+	})
+	RegisterResponseBodyWrapper(http.MethodGet, "/version", func(body io.ReadCloser, resp *http.Response) io.ReadCloser {
+		return body
+	})
+
+	newResponse := func(path string) *http.Response {
+		return &http.Response{
+			Header:        http.Header{"Content-Length": []string{"4"}},
+			ContentLength: 4,
+			Body:          io.NopCloser(strings.NewReader("body")),
+			Request:       httptest.NewRequest(http.MethodGet, path, http.NoBody),
+		}
+	}
+
+	resp := newResponse("/v1.41/events")
+	wrapResponseBody(resp)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body wrapped", string(body))
+	assert.EqualValues(t, -1, resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+
+	resp = newResponse("/v1.41/info")
+	wrapResponseBody(resp)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+	assert.EqualValues(t, 4, resp.ContentLength)
+
+	// A wrapper that returns the body unchanged keeps the length.
+	resp = newResponse("/v1.41/version")
+	wrapResponseBody(resp)
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+	assert.EqualValues(t, 4, resp.ContentLength)
+	assert.Equal(t, "4", resp.Header.Get("Content-Length"))
+}
@@ -20,10 +20,11 @@ package dockerproxy
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -31,11 +32,14 @@ import (
 	"os/signal"
 	"regexp"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/sirupsen/logrus"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/models"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
 )
@@ -46,37 +50,327 @@ type RequestContextValue map[interface{}]interface{}
 // requestContext is the context key for requestContextValue
 var requestContext = struct{}{}
 
+// peerContextKey is the context key for the platform.PeerInfo of the client
+// connection.
+type peerContextKey struct{}
+
+// peerFromContext returns the client information for the connection the
+// request with the given context arrived on.
+func peerFromContext(ctx context.Context) platform.PeerInfo {
+	if peer, ok := ctx.Value(peerContextKey{}).(platform.PeerInfo); ok {
+		return peer
+	}
+	return platform.UnknownPeer
+}
+
+// connIDContextKey is the context key for the unique ID of the client
+// connection.
+type connIDContextKey struct{}
+
+// connIDFromContext returns the ID of the connection the request with the
+// given context arrived on.
+func connIDFromContext(ctx context.Context) uint64 {
+	id, _ := ctx.Value(connIDContextKey{}).(uint64)
+	return id
+}
+
 type containerInspectResponseBody struct {
 	ID string `json:"Id"`
 }
 
 const dockerAPIVersion = "v1.41.0"
 
+// ServeOptions contains optional settings for the docker proxy.
+type ServeOptions struct {
+	// MetricsEndpoint is the TCP address on which to serve Prometheus metrics;
+	// if empty, metrics are disabled.
+	MetricsEndpoint string
+	// TracingEndpoint is the OTLP/HTTP URL to export OpenTelemetry traces to;
+	// if empty, tracing is disabled.
+	TracingEndpoint string
+	// Logger is used for all log output from the proxy; if nil, the standard
+	// logrus logger is used.
+	Logger logrus.FieldLogger
+	// AuditLogPath is the file to write a JSON-lines audit log of all API
+	// calls to; if empty, auditing is disabled.
+	AuditLogPath string
+	// AuditLogMaxSize is the size, in bytes, at which the audit log is
+	// rotated; if zero, a default is used.
+	AuditLogMaxSize int64
+	// AuditLogMaxBackups is the number of rotated audit logs to keep.
+	AuditLogMaxBackups int
+	// PolicyRules are checked against each request before it is forwarded;
+	// requests that fail any rule are rejected.
+	PolicyRules []PolicyRule
+	// RateLimit is the number of requests per second each client may make; if
+	// zero, requests are not rate limited.
+	RateLimit float64
+	// RateLimitBurst is the number of requests a client may make in a burst
+	// before being rate limited.
+	RateLimitBurst int
+	// RateLimitBypass is the list of API paths (as in the API specification,
+	// e.g. "/containers/{id}/logs") that are never rate limited.
+	RateLimitBypass []string
+	// ShutdownTimeout is how long to wait for in-flight requests and upgraded
+	// connections to finish on shutdown before closing them; if zero, a
+	// default is used.
+	ShutdownTimeout time.Duration
+	// CacheTTL is how long responses to frequently polled read-only endpoints
+	// (such as /containers/json) may be cached; if zero, caching is disabled.
+	CacheTTL time.Duration
+	// MaxRequestBodySize is the largest request body, in bytes, allowed for
+	// endpoints that do not accept streamed uploads; if zero, there is no
+	// limit.
+	MaxRequestBodySize int64
+	// ResponseHeaderTimeout is how long to wait for the backend to send
+	// response headers; if zero, there is no limit.
+	ResponseHeaderTimeout time.Duration
+	// RequestTimeout is the overall deadline for requests that are neither
+	// upgraded nor expected to stream; if zero, there is no limit.
+	RequestTimeout time.Duration
+	// EndpointTimeouts overrides RequestTimeout for requests whose path
+	// (without the API version prefix) matches one of the given patterns, in
+	// the syntax of path.Match (e.g. "/containers/*/wait"); a zero timeout
+	// means no limit.  Where several patterns match, the longest one wins.
+	EndpointTimeouts map[string]time.Duration
+	// EventFilters are rules for dropping or tagging messages in the /events
+	// stream.
+	EventFilters []EventFilterRule
+	// RegistryMirrors maps registry hosts (e.g. "docker.io") to mirrors that
+	// image pulls should be redirected to, optionally with a path prefix (e.g.
+	// "mirror.example.com/dockerhub").  Pulled images are tagged with their
+	// original names.
+	RegistryMirrors map[string]string
+	// PodmanCompat enables translating requests for the libpod (podman) API
+	// into docker API requests, where the two APIs are compatible.
+	PodmanCompat bool
+	// Listener, if set, is used instead of listening on the endpoint; this is
+	// used for socket activation.
+	Listener net.Listener
+	// IdleExitTimeout, if set, causes Serve to return once there have been no
+	// client connections for this long; this is only useful if Listener is
+	// set, so that the proxy is started again on demand.
+	IdleExitTimeout time.Duration
+	// MaxConnections is the maximum number of concurrent client connections;
+	// further connections wait until an existing one is closed.  If zero,
+	// there is no limit.
+	MaxConnections int
+	// Reload, if set, is called to get new options when the proxy receives
+	// SIGHUP, or when one of the ReloadWatchFiles changes.  Only the rate
+	// limits, policy rules, request limits and timeouts, registry mirrors, and
+	// event filters are taken from the new options; they apply to new
+	// requests, without interrupting those already in progress.
+	Reload func() (ServeOptions, error)
+	// ReloadWatchFiles are files (such as a policy file) that cause the
+	// configuration to be reloaded when they change.
+	ReloadWatchFiles []string
+	// TLSCertDir, if set, is a directory containing a TLS certificate
+	// (cert.pem) and key (key.pem) to serve with; this is only supported when
+	// listening on TCP.  The certificate is reloaded whenever the files
+	// change.
+	TLSCertDir string
+	// TLSVerifyClients accepts clients presenting a certificate signed by one
+	// of the certificate authorities in ca.pem in TLSCertDir.
+	TLSVerifyClients bool
+	// AuthTokens are bearer tokens that clients may authenticate with.  When
+	// listening on TCP, either this or TLSVerifyClients is required.
+	AuthTokens []string
+	// InsecureTCP allows listening on TCP without TLS, in which case the
+	// authentication tokens are sent unencrypted.
+	InsecureTCP bool
+	// DialRetries is the number of times to retry connecting to the backend
+	// before failing the request.
+	DialRetries int
+	// DialRetryBackoff is the delay before the first retry; it doubles on each
+	// subsequent attempt.  If zero, a default is used.
+	DialRetryBackoff time.Duration
+	// FallbackDialers are used to connect to alternative backends, in order,
+	// when the primary backend is unavailable.
+	FallbackDialers []func() (net.Conn, error)
+	// HealthCheckInterval is how often backends are probed when there are
+	// fallback backends; if zero, a default is used.
+	HealthCheckInterval time.Duration
+	// CopyBufferSize is the size of the buffers used to copy response bodies
+	// and CONNECT tunnels; if zero, utils.DefaultBufferSize is used.
+	CopyBufferSize int
+	// IdleTimeout is how long an upgraded connection (attach, exec, etc.) may
+	// go without any data in either direction before it is closed; if zero,
+	// there is no limit.
+	IdleTimeout time.Duration
+	// IdleTimeoutBypass is the list of API paths (as in the API specification)
+	// that are never closed for being idle.
+	IdleTimeoutBypass []string
+	// SocketOptions control the ownership and permissions of the socket the
+	// proxy listens on, as well as those of any upstreams.
+	SocketOptions platform.ListenOptions
+	// Upstreams are additional backends (such as containerd or buildkitd) to
+	// serve from the same process, each on its own endpoint.  They are shut
+	// down along with the docker API; connections to them do not count
+	// towards IdleExitTimeout.
+	Upstreams []Upstream
+}
+
+// checkTCPSecurity checks that the options are safe for listening on TCP,
+// where the socket permissions can't be relied upon: clients must
+// authenticate, and tokens may only be sent unencrypted if explicitly allowed.
+func checkTCPSecurity(endpoint string, options *ServeOptions, useTLS bool) error {
+	if len(options.AuthTokens) == 0 && !options.TLSVerifyClients {
+		return fmt.Errorf("listening on %s requires authentication tokens or client certificates", endpoint)
+	}
+	if !useTLS && !options.InsecureTCP {
+		return fmt.Errorf("listening on %s without TLS would send authentication tokens unencrypted; configure a TLS certificate, or explicitly allow insecure connections", endpoint)
+	}
+	return nil
+}
+
 // Serve up the docker proxy at the given endpoint, using the given function to
 // create a connection to the real dockerd.
-func Serve(endpoint string, dialer func() (net.Conn, error)) error {
-	listener, err := platform.Listen(endpoint)
+func Serve(endpoint string, dialer func() (net.Conn, error), options ServeOptions) error {
+	logger := options.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+
+	if len(options.FallbackDialers) > 0 {
+		pool := newBackendPool(append([]func() (net.Conn, error){dialer}, options.FallbackDialers...))
+		interval := options.HealthCheckInterval
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), loggerContextKey{}, logger))
+		defer cancel()
+		go pool.run(ctx, interval)
+		dialer = pool.dial
+	}
+
+	var metrics *proxyMetrics
+	if options.MetricsEndpoint != "" {
+		metrics = newProxyMetrics()
+		go func() {
+			err := metrics.serve(options.MetricsEndpoint)
+			if err != nil {
+				logger.WithError(err).Error("metrics server exited with error")
+			}
+		}()
+	}
+
+	var tracer *proxyTracer
+	if options.TracingEndpoint != "" {
+		var err error
+		tracer, err = newProxyTracer(context.Background(), options.TracingEndpoint)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracer.shutdown(ctx); err != nil {
+				logger.WithError(err).Error("could not flush traces")
+			}
+		}()
+	}
+
+	var audit *auditLog
+	if options.AuditLogPath != "" {
+		var err error
+		audit, err = newAuditLog(options.AuditLogPath, options.AuditLogMaxSize, options.AuditLogMaxBackups)
+		if err != nil {
+			return err
+		}
+		defer audit.Close()
+	}
+
+	var cache *responseCache
+	if options.CacheTTL > 0 {
+		cache = newResponseCache(options.CacheTTL)
+		eventsClient := &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(context.Context, string, string) (net.Conn, error) {
+					return dialer()
+				},
+			},
+		}
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), loggerContextKey{}, logger))
+		defer cancel()
+		go cache.watchEvents(ctx, eventsClient, "http://proxy.invalid/events")
+	}
+
+	if options.TLSVerifyClients && options.TLSCertDir == "" {
+		return errors.New("verifying client certificates requires a TLS certificate directory")
+	}
+	var certs *certReloader
+	if options.TLSCertDir != "" {
+		var err error
+		certs, err = newCertReloader(options.TLSCertDir, options.TLSVerifyClients, logger)
+		if err != nil {
+			return err
+		}
+		defer certs.Close()
+	}
+
+	munger := newRequestMunger()
+	negotiator := newVersionNegotiator(&dockerSpec.Info.Version)
+	countedDialer := dialer
+	if metrics != nil {
+		countedDialer = metrics.wrapDialer(dialer)
+	}
+	backendDialer := func(context.Context) (net.Conn, error) {
+		return countedDialer()
+	}
+	if options.DialRetries > 0 {
+		backoff := options.DialRetryBackoff
+		if backoff <= 0 {
+			backoff = defaultDialRetryBackoff
+		}
+		backendDialer = retryDialer(backendDialer, options.DialRetries, backoff)
+	}
+	if tracer != nil {
+		backendDialer = tracer.wrapDialer(backendDialer)
+	}
+	config, err := newReloadableConfig(&options, backendDialer)
 	if err != nil {
 		return err
 	}
+	reloader := newConfigReloader(config, backendDialer, options.Reload, logger)
 
-	termch := make(chan os.Signal, 1)
-	signal.Notify(termch, os.Interrupt)
-	go func() {
-		<-termch
-		signal.Stop(termch)
-		err := listener.Close()
+	listener := options.Listener
+	if listener == nil {
+		listener, err = platform.Listen(endpoint, options.SocketOptions)
 		if err != nil {
-			logrus.WithError(err).Error("Error closing listener on interrupt")
+			return err
 		}
-	}()
+	}
+	if listener.Addr().Network() == "tcp" {
+		if err := checkTCPSecurity(endpoint, &options, certs != nil); err != nil {
+			listener.Close()
+			return err
+		}
+		if certs == nil {
+			logger.Warn("Listening on TCP without TLS; authentication tokens are sent unencrypted")
+		}
+	}
+	if certs != nil {
+		clientAuth := tls.NoClientCert
+		if options.TLSVerifyClients {
+			clientAuth = tls.RequireAndVerifyClientCert
+			if len(options.AuthTokens) > 0 {
+				// Clients may use either a certificate or a token.
+				clientAuth = tls.VerifyClientCertIfGiven
+			}
+		}
+		tlsListener, err := newTLSListener(listener, certs, clientAuth)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tlsListener
+	}
 
-	logWriter := logrus.StandardLogger().Writer()
-	defer logWriter.Close()
-	munger := newRequestMunger()
-	proxy := &httputil.ReverseProxy{
-		Director: func(req *http.Request) {
-			logrus.WithField("request", req).
+	// requestHooks are run, in order, on each request before it is sent to
+	// the backend.
+	requestHooks := []RequestHook{
+		func(req *http.Request) {
+			requestLogger(req.Context()).
 				WithField("headers", req.Header).
 				WithField("url", req.URL).
 				Debug("got proxy request")
@@ -84,63 +378,253 @@ func Serve(endpoint string, dialer func() (net.Conn, error)) error {
 			// to add scheme and host ("http://proxy.invalid/") to it.
 			req.URL.Scheme = "http"
 			req.URL.Host = "proxy.invalid"
-
+		},
+		negotiator.rewriteRequest,
+		func(req *http.Request) {
 			originalReq := *req
 			originalURL := *req.URL
 			originalReq.URL = &originalURL
 			err := munger.MungeRequest(req, dialer)
 			if err != nil {
-				logrus.WithError(err).
+				requestLogger(req.Context()).WithError(err).
 					WithField("original request", originalReq).
 					WithField("modified request", req).
 					Error("could not munge request")
 			}
 		},
-		Transport: &http.Transport{
-			Dial: func(string, string) (net.Conn, error) {
-				return dialer()
-			},
-			DisableCompression: true, // for debugging
-		},
-		ModifyResponse: func(resp *http.Response) error {
-			logEntry := logrus.WithField("response", resp)
-			defer func() { logEntry.Debug("got backend response") }()
+	}
+	requestHooks = append(requestHooks, reloader.rewriteRequest, setRequestIDHeader)
+	requestHooks = append(requestHooks, registeredRequestHooks()...)
+	if tracer != nil {
+		requestHooks = append(requestHooks, tracer.inject)
+	}
 
+	// responseHooks are run, in order, on each response from the backend; if
+	// any of them fail, the remaining ones are skipped.
+	responseHooks := []ResponseHook{
+		func(resp *http.Response) error {
+			negotiator.observeResponse(resp)
+			return nil
+		},
+		func(resp *http.Response) error {
 			// Check the API version response, and if there is one, make sure
 			// it's not newer than the API version we support.
 			backendVersion, err := semver.NewVersion(resp.Header.Get("API-Version"))
-			if err == nil {
-				logEntry = logEntry.WithField("backend version", backendVersion)
-				if backendVersion.GreaterThan(&dockerSpec.Info.Version) {
-					overrideVersion := fmt.Sprintf("v%s", dockerSpec.Info.Version.Original())
-					resp.Header.Set("API-Version", overrideVersion)
-					logEntry = logEntry.WithField("override version", overrideVersion)
-				}
+			if err == nil && backendVersion.GreaterThan(&dockerSpec.Info.Version) {
+				overrideVersion := fmt.Sprintf("v%s", dockerSpec.Info.Version.Original())
+				resp.Header.Set("API-Version", overrideVersion)
+				requestLogger(resp.Request.Context()).
+					WithField("backend version", backendVersion).
+					WithField("override version", overrideVersion).
+					Debug("overriding backend API version")
 			}
+			return nil
+		},
+		func(resp *http.Response) error {
+			return munger.MungeResponse(resp, dialer)
+		},
+	}
+	pulls := newPullTracker()
+	responseHooks = append(responseHooks, reloader.observeResponse, pulls.observeResponse)
+	responseHooks = append(responseHooks, registeredResponseHooks()...)
 
-			err = munger.MungeResponse(resp, dialer)
+	bufferPool := utils.DefaultBufferPool
+	if options.CopyBufferSize > 0 {
+		bufferPool = utils.NewBufferPool(options.CopyBufferSize)
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			for _, hook := range requestHooks {
+				hook(req)
+			}
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return backendDialer(ctx)
+			},
+			DisableCompression: true, // for debugging
+			// Wait for the backend to accept requests with "Expect:
+			// 100-continue" (e.g. large image loads) before uploading the
+			// body, so that rejected requests fail quickly.  The client gets
+			// its 100 Continue from net/http when the body is first read,
+			// i.e. once the backend has asked for it (or this timed out);
+			// ReverseProxy does not relay the backend's interim response.
+			ExpectContinueTimeout: time.Second,
+			ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			requestLogger(resp.Request.Context()).WithField("response", resp).Debug("got backend response")
+			encoding, err := decodeResponseBody(resp)
 			if err != nil {
 				return err
 			}
+			for _, hook := range responseHooks {
+				if err := hook(resp); err != nil {
+					return err
+				}
+			}
+			wrapResponseBody(resp)
+			encodeResponseBody(resp, encoding, bufferPool)
 			return nil
 		},
-		ErrorLog: log.New(logWriter, "", 0),
+		ErrorHandler: proxyErrorHandler,
+		BufferPool:   bufferPool,
+	}
+
+	// middlewares wrap the proxy, outermost first.
+	tracker := newHijackTracker()
+	middlewares := []Middleware{tracker.wrapHandler}
+	if options.PodmanCompat {
+		// This must come before anything that looks at the API endpoint.
+		middlewares = append(middlewares, podmanCompat)
+	}
+	if headerHooks := registeredResponseHeaderHooks(); len(headerHooks) > 0 {
+		middlewares = append(middlewares, withResponseHeaderHooks(headerHooks))
+	}
+	if metrics != nil {
+		middlewares = append(middlewares, metrics.wrapHandler)
+	}
+	if tracer != nil {
+		middlewares = append(middlewares, tracer.wrapHandler)
+	}
+	middlewares = append(middlewares, func(next http.Handler) http.Handler {
+		return withRequestLogger(logger, next)
+	})
+	middlewares = append(middlewares, newDeprecationChecker(negotiator).wrapHandler)
+	if audit != nil {
+		middlewares = append(middlewares, audit.wrapHandler)
+	}
+	// The rate limit, policy, and request limits come from the reloadable
+	// configuration.
+	middlewares = append(middlewares, reloader.wrapHandler)
+	if options.IdleTimeout > 0 {
+		idle := newIdleTimeout(options.IdleTimeout, options.IdleTimeoutBypass)
+		middlewares = append(middlewares, idle.wrapHandler)
+	}
+	if cache != nil {
+		middlewares = append(middlewares, cache.wrapHandler)
+	}
+	middlewares = append(middlewares, registeredMiddlewares()...)
+
+	connectTunnel := newConnectTunnel(backendDialer, negotiator.rewriteRequest)
+	connectTunnel.bufferPool = bufferPool
+	sessionTunnel := newSessionTunnel(backendDialer, negotiator.rewriteRequest)
+	proxyHandler := chainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), requestContext, &RequestContextValue{})
+		newReq := req.WithContext(ctx)
+		switch {
+		case req.Method == http.MethodConnect:
+			connectTunnel.ServeHTTP(w, newReq)
+		case isSessionRequest(req):
+			sessionTunnel.ServeHTTP(w, newReq)
+		default:
+			proxy.ServeHTTP(w, newReq)
+		}
+	}), middlewares...)
+	// We don't use http.ServeMux here, as it would clean up (and redirect)
+	// request paths that should be forwarded as-is.
+	var protectedHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == pullProgressPath {
+			pulls.ServeHTTP(w, req)
+		} else {
+			proxyHandler.ServeHTTP(w, req)
+		}
+	})
+	var auth *authenticator
+	if len(options.AuthTokens) > 0 || options.TLSVerifyClients {
+		auth = newAuthenticator(options.AuthTokens, logger)
+		protectedHandler = auth.wrapHandler(protectedHandler)
 	}
+	// The health check is always available, so that it can be used without
+	// credentials; unauthenticated clients only get the status.
+	healthHandler := newHealthHandler(dialer, auth)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == healthPath {
+			healthHandler.ServeHTTP(w, req)
+		} else {
+			protectedHandler.ServeHTTP(w, req)
+		}
+	})
 
+	var lastConnID atomic.Uint64
 	server := &http.Server{
 		ReadHeaderTimeout: time.Minute,
-		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-			ctx := context.WithValue(req.Context(), requestContext, &RequestContextValue{})
-			newReq := req.WithContext(ctx)
-			proxy.ServeHTTP(w, newReq)
-		}),
+		Handler:           handler,
+		ConnContext: func(ctx context.Context, conn net.Conn) context.Context {
+			ctx = context.WithValue(ctx, connIDContextKey{}, lastConnID.Add(1))
+			return context.WithValue(ctx, peerContextKey{}, platform.GetPeerInfo(conn))
+		},
 	}
 
-	logrus.WithField("endpoint", endpoint).Info("Listening")
+	shutdownTimeout := options.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	idlech := make(chan struct{})
+	if options.IdleExitTimeout > 0 {
+		idle := newIdleExit(options.IdleExitTimeout, tracker)
+		server.ConnState = idle.connState
+		idleCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			if idle.wait(idleCtx) {
+				logger.WithField("timeout", options.IdleExitTimeout).Info("No connections, exiting")
+				close(idlech)
+			}
+		}()
+	}
+	stopUpstreams, err := serveUpstreams(options.Upstreams, options.SocketOptions, bufferPool, logger)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	shutdownDone := make(chan struct{})
+	termch := make(chan os.Signal, 1)
+	signal.Notify(termch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-termch:
+		case <-idlech:
+		}
+		signal.Stop(termch)
+		defer close(shutdownDone)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		logger.WithField("timeout", shutdownTimeout).Info("Shutting down, waiting for connections to finish")
+		upstreamsDone := make(chan struct{})
+		go func() {
+			defer close(upstreamsDone)
+			stopUpstreams(ctx)
+		}()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.WithError(err).Warn("Timed out waiting for requests, closing connections")
+			_ = server.Close()
+		}
+		if err := tracker.wait(ctx); err != nil {
+			logger.WithError(err).Warn("Timed out waiting for upgraded connections, closing them")
+		}
+		<-upstreamsDone
+	}()
+
+	if options.Reload != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go reloader.run(ctx, options.ReloadWatchFiles)
+	}
+
+	if options.MaxConnections > 0 {
+		listener = newLimitListener(listener, options.MaxConnections, logger)
+	}
+	logger.WithField("endpoint", endpoint).Info("Listening")
 
 	err = server.Serve(listener)
-	if err != nil {
-		logrus.WithError(err).Error("serve exited with error")
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdownDone
+	} else if err != nil {
+		logger.WithError(err).Error("serve exited with error")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		stopUpstreams(ctx)
 	}
 
 	return nil
@@ -157,7 +641,7 @@ type requestMunger struct {
 // newRequestMunger initializes a new requestMunger.
 func newRequestMunger() *requestMunger {
 	return &requestMunger{
-		apiDetectPattern: regexp.MustCompile(`^/v[0-9.]+/`),
+		apiDetectPattern: apiVersionPattern,
 	}
 }
 
@@ -165,7 +649,7 @@ func (m *requestMunger) getRequestPath(req *http.Request) string {
 	// Strip the version string at the start of the request, if it exists.
 	requestPath := req.URL.Path
 	match := m.apiDetectPattern.FindStringIndex(requestPath)
-	logrus.WithFields(logrus.Fields{
+	requestLogger(req.Context()).WithFields(logrus.Fields{
 		"request path": requestPath,
 		"matcher":      m.apiDetectPattern,
 		"match":        match,
@@ -179,7 +663,7 @@ func (m *requestMunger) getRequestPath(req *http.Request) string {
 // MungeRequest modifies a given request in-place.
 func (m *requestMunger) MungeRequest(req *http.Request, dialer func() (net.Conn, error)) error {
 	requestPath := m.getRequestPath(req)
-	logEntry := logrus.WithFields(logrus.Fields{
+	logEntry := requestLogger(req.Context()).WithFields(logrus.Fields{
 		"method": req.Method,
 		"path":   requestPath,
 		"phase":  "request",
@@ -220,7 +704,7 @@ func (m *requestMunger) MungeRequest(req *http.Request, dialer func() (net.Conn,
 
 func (m *requestMunger) MungeResponse(resp *http.Response, dialer func() (net.Conn, error)) error {
 	requestPath := m.getRequestPath(resp.Request)
-	logEntry := logrus.WithFields(logrus.Fields{
+	logEntry := requestLogger(resp.Request.Context()).WithFields(logrus.Fields{
 		"method": resp.Request.Method,
 		"path":   requestPath,
 		"phase":  "response",
@@ -312,6 +796,7 @@ var dockerSpec struct {
 	Info struct {
 		Version semver.Version
 	}
+	Paths map[string]json.RawMessage
 }
 
 // requestMungerFunc is a munger for an incoming request; it also receives an
@@ -449,4 +934,9 @@ func init() {
 	if err != nil {
 		panic("could not parse embedded spec version")
 	}
+	paths := make([]string, 0, len(dockerSpec.Paths))
+	for apiPath := range dockerSpec.Paths {
+		paths = append(paths, apiPath)
+	}
+	initAPIEndpoints(paths)
 }
@@ -0,0 +1,114 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeExpectContinue(t *testing.T) {
+	const body = "image tarball"
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1.41/images/load" {
+			// Reading the body makes the backend send 100 Continue.
+			data, err := io.ReadAll(req.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, _ = fmt.Fprintf(w, "loaded %q", data)
+			return
+		}
+		http.Error(w, "rejected", http.StatusForbidden)
+	}))
+	t.Cleanup(backend.Close)
+
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	serveDone := make(chan error)
+	go func() {
+		serveDone <- Serve("unix://"+socketPath, func() (net.Conn, error) {
+			return net.Dial("tcp", backend.Listener.Addr().String())
+		}, ServeOptions{Listener: listener, Logger: logger})
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		assert.NoError(t, <-serveDone)
+	})
+
+	// sendHeaders sends the request headers without the body, and returns a
+	// reader for the responses.
+	sendHeaders := func(t *testing.T, path string) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("unix", socketPath)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		require.NoError(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+		_, err = fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: docker\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", path, len(body))
+		require.NoError(t, err)
+		return conn, bufio.NewReader(conn)
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		conn, reader := sendHeaders(t, "/v1.41/images/load")
+		// The interim response is written by net/http when the proxy first
+		// reads the body, which happens once the backend asks for it.
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusContinue, resp.StatusCode)
+
+		_, err = io.WriteString(conn, body)
+		require.NoError(t, err)
+		// The interim response from the backend is not passed on as well.
+		resp, err = http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("loaded %q", body), string(data))
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		_, reader := sendHeaders(t, "/v1.41/images/create")
+		// The backend rejects the request without reading the body, so the
+		// client gets the final response without having to send it.
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "rejected", strings.TrimSpace(string(data)))
+	})
+}
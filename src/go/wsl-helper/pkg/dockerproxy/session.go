@@ -0,0 +1,59 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// sessionBufferSize is the size of the buffers used to copy BuildKit session
+// data; this is larger than usual, as the session carries gRPC streams with
+// large frames (such as build context file transfers).
+const sessionBufferSize = 256 * 1024
+
+// sessionBufferPool supplies the buffers for copying BuildKit session data.
+var sessionBufferPool = utils.NewBufferPool(sessionBufferSize)
+
+// isSessionRequest checks if the request is for a BuildKit session, which is
+// upgraded to a long-lived gRPC connection.
+func isSessionRequest(req *http.Request) bool {
+	return req.Method == http.MethodPost &&
+		req.Header.Get("Upgrade") != "" &&
+		apiEndpoint(req.URL.Path) == "/session"
+}
+
+// newSessionTunnel handles BuildKit session requests: rather than going through
+// httputil.ReverseProxy, the upgraded connection is relayed directly, with
+// half-close propagated in each direction and flow control left to the
+// underlying connections.
+func newSessionTunnel(dialer func(context.Context) (net.Conn, error), rewrite func(*http.Request)) *tunnel {
+	return &tunnel{
+		name:   "session",
+		dialer: dialer,
+		accepted: func(statusCode int) bool {
+			return statusCode == http.StatusSwitchingProtocols
+		},
+		rewrite:    rewrite,
+		bufferPool: sessionBufferPool,
+	}
+}
@@ -19,6 +19,7 @@ limitations under the License.
 package dockerproxy
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -32,8 +33,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
 	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
-	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/util"
 )
 
 const (
@@ -177,7 +178,10 @@ func handleConnection(conn net.Conn, dockerPath string) {
 		return
 	}
 	defer dockerConn.Close()
-	err = util.Pipe(conn, dockerConn)
+	result, err := utils.Relay(context.Background(), conn, dockerConn, utils.RelayOptions{})
+	if err == nil {
+		err = result.Err()
+	}
 	if err != nil {
 		logrus.Errorf("error forwarding docker connection: %s", err)
 		return
@@ -0,0 +1,146 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The names of the files in the TLS certificate directory; these match the
+// names the docker CLI uses in DOCKER_CERT_PATH.
+const (
+	tlsCertFile = "cert.pem"
+	tlsKeyFile  = "key.pem"
+	tlsCAFile   = "ca.pem"
+)
+
+// certReloadDelay is how long to wait after a change in the certificate
+// directory before reloading, so that the certificate and key are both
+// replaced before we read them.
+const certReloadDelay = 500 * time.Millisecond
+
+// certReloader serves the TLS certificate from a directory, reloading it
+// whenever the files change so that rotated certificates are picked up
+// without restarting the proxy.
+type certReloader struct {
+	dir    string
+	logger logrus.FieldLogger
+	cert   atomic.Pointer[tls.Certificate]
+	// clientCAs are the certificate authorities for client certificates; this
+	// is only loaded if verifyClients is set.
+	clientCAs     atomic.Pointer[x509.CertPool]
+	verifyClients bool
+	watcher       *fileWatcher
+}
+
+// newCertReloader loads the certificate from the given directory, and starts
+// watching it for changes.  If verifyClients is set, the certificate
+// authorities for client certificates are also loaded.  The returned reloader
+// must be closed when it is no longer needed.
+func newCertReloader(dir string, verifyClients bool, logger logrus.FieldLogger) (*certReloader, error) {
+	r := &certReloader{dir: dir, verifyClients: verifyClients, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	watcher, err := newFileWatcher([]string{dir}, certReloadDelay, logger, r.onChange)
+	if err != nil {
+		return nil, fmt.Errorf("could not watch TLS certificate directory %s: %w", dir, err)
+	}
+	r.watcher = watcher
+	return r, nil
+}
+
+// reload the certificate from disk.
+func (r *certReloader) reload() error {
+	certPath := filepath.Join(r.dir, tlsCertFile)
+	keyPath := filepath.Join(r.dir, tlsKeyFile)
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf("could not load TLS certificate from %s: %w", r.dir, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.verifyClients {
+		caPath := filepath.Join(r.dir, tlsCAFile)
+		buf, err := os.ReadFile(caPath)
+		if err != nil {
+			return fmt.Errorf("could not read client certificate authorities: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("no certificates found in %s", caPath)
+		}
+	}
+	r.cert.Store(&cert)
+	r.clientCAs.Store(clientCAs)
+	return nil
+}
+
+// onChange is called when the certificate directory changes.
+func (r *certReloader) onChange() {
+	if err := r.reload(); err != nil {
+		// Keep serving the previous certificate; we'll try again on the next
+		// change.
+		r.logger.WithError(err).Error("could not reload TLS certificate")
+		return
+	}
+	r.logger.WithField("dir", r.dir).Info("reloaded TLS certificate")
+}
+
+// getCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Close stops watching the certificate directory.
+func (r *certReloader) Close() error {
+	return r.watcher.Close()
+}
+
+// newTLSListener wraps the listener so that clients must connect using TLS,
+// with the certificate from the given directory.  Client certificates are
+// handled as given by clientAuth.  TLS is only supported on TCP listeners.
+func newTLSListener(listener net.Listener, reloader *certReloader, clientAuth tls.ClientAuthType) (net.Listener, error) {
+	if network := listener.Addr().Network(); network != "tcp" {
+		return nil, fmt.Errorf("TLS is not supported when listening on %s", network)
+	}
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.getCertificate,
+		ClientAuth:     clientAuth,
+	}
+	if clientAuth != tls.NoClientCert {
+		// Use the current client certificate authorities for each connection.
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientCAs = reloader.clientCAs.Load()
+			return clientConfig, nil
+		}
+	}
+	return tls.NewListener(listener, config), nil
+}
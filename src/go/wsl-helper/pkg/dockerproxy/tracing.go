@@ -0,0 +1,121 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope name for spans we create.
+const tracerName = "github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy"
+
+// upgradedAttribute is the span attribute set when a connection is upgraded
+// to a raw stream (attach, exec, etc.).
+const upgradedAttribute = attribute.Key("docker_proxy.upgraded")
+
+// proxyTracer creates OpenTelemetry spans for proxied requests.
+type proxyTracer struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// newProxyTracer creates a tracer that exports spans via OTLP over HTTP to the
+// given URL (e.g. "http://localhost:4318").
+func newProxyTracer(ctx context.Context, endpointURL string) (*proxyTracer, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, fmt.Errorf("could not create trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("rancher-desktop-docker-proxy"))),
+	)
+	return &proxyTracer{
+		provider:   provider,
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}, nil
+}
+
+// shutdown flushes any pending spans.
+func (t *proxyTracer) shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// wrapHandler returns a handler that creates a span for each request passed to
+// the given handler, continuing any trace the client sent.
+func (t *proxyTracer) wrapHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		endpoint := apiEndpoint(req.URL.Path)
+		ctx := t.propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := t.tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, endpoint),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.HTTPRoute(endpoint),
+				semconv.URLPath(req.URL.Path),
+			))
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := recorder.Status()
+			span.SetAttributes(
+				semconv.HTTPResponseStatusCode(status),
+				upgradedAttribute.Bool(recorder.hijacked))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			span.End()
+		}()
+		handler.ServeHTTP(recorder, req.WithContext(ctx))
+	})
+}
+
+// inject the trace context from the request's context into its headers, so
+// that it is propagated to the backend.
+func (t *proxyTracer) inject(req *http.Request) {
+	t.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
+
+// wrapDialer returns a dialer that records the time taken to connect to the
+// backend as a child span.
+func (t *proxyTracer) wrapDialer(dialer func(context.Context) (net.Conn, error)) func(context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		ctx, span := t.tracer.Start(ctx, "dial backend", trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		conn, err := dialer(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return conn, err
+	}
+}
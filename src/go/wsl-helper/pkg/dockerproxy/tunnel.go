@@ -0,0 +1,146 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+// tunnel forwards a request to the backend and then, if the backend accepts
+// it, relays raw bytes between the client and the backend connections.  This
+// is used for requests that httputil.ReverseProxy does not handle well.
+type tunnel struct {
+	// name describes the kind of tunnel, for logging.
+	name   string
+	dialer func(context.Context) (net.Conn, error)
+	// accepted checks if the backend response status means that the
+	// connection should be switched to a raw tunnel.
+	accepted func(statusCode int) bool
+	// rewrite, if set, modifies the request before it is forwarded.
+	rewrite func(*http.Request)
+	// bufferPool supplies the buffers used to copy data.
+	bufferPool *utils.BufferPool
+}
+
+// newConnectTunnel handles CONNECT requests; httputil.ReverseProxy would
+// otherwise treat the request as a normal one and any data after the response
+// would be lost.
+func newConnectTunnel(dialer func(context.Context) (net.Conn, error), rewrite func(*http.Request)) *tunnel {
+	return &tunnel{
+		name:   "CONNECT",
+		dialer: dialer,
+		accepted: func(statusCode int) bool {
+			return statusCode >= 200 && statusCode <= 299
+		},
+		rewrite:    rewrite,
+		bufferPool: utils.DefaultBufferPool,
+	}
+}
+
+func (c *tunnel) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := requestLogger(req.Context()).WithField("tunnel", c.name)
+	backend, err := c.dialer(req.Context())
+	if err != nil {
+		proxyErrorHandler(w, req, err)
+		return
+	}
+	defer backend.Close()
+
+	outReq := req.Clone(req.Context())
+	outReq.Body = http.NoBody
+	outReq.ContentLength = 0
+	outReq.Header.Del("Content-Length")
+	if c.rewrite != nil {
+		c.rewrite(outReq)
+	}
+	if err := outReq.Write(backend); err != nil {
+		proxyErrorHandler(w, req, fmt.Errorf("failed to forward %s request: %w", c.name, err))
+		return
+	}
+	backendReader := bufio.NewReader(backend)
+	resp, err := http.ReadResponse(backendReader, outReq)
+	if err != nil {
+		proxyErrorHandler(w, req, fmt.Errorf("failed to read %s response: %w", c.name, err))
+		return
+	}
+	if !c.accepted(resp.StatusCode) {
+		// The backend refused the tunnel; relay the response as-is.
+		defer resp.Body.Close()
+		for key, values := range resp.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+		return
+	}
+
+	client, clientBuf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		logger.WithError(err).Error("failed to hijack connection")
+		writeErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("%s is not supported on this connection", c.name))
+		return
+	}
+	defer client.Close()
+	if _, err := fmt.Fprintf(clientBuf, "HTTP/1.1 %s\r\n", resp.Status); err != nil {
+		return
+	}
+	if err := resp.Header.Write(clientBuf); err != nil {
+		return
+	}
+	if _, err := clientBuf.WriteString("\r\n"); err != nil {
+		return
+	}
+	if err := clientBuf.Flush(); err != nil {
+		return
+	}
+
+	logger.Debug("established tunnel")
+	result, err := utils.Relay(context.Background(),
+		&bufferedConn{Conn: client, reader: clientBuf.Reader},
+		&bufferedConn{Conn: backend, reader: backendReader},
+		utils.RelayOptions{BufferPool: c.bufferPool})
+	if err == nil {
+		err = result.Err()
+	}
+	if err != nil {
+		logger.WithError(err).Debug("tunnel closed with error")
+	}
+}
+
+// bufferedConn is a connection where some data may have already been read
+// into a buffer; reads drain the buffer before reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+	return utils.CloseWrite(c.Conn)
+}
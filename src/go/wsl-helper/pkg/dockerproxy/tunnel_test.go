@@ -0,0 +1,139 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTunnelBackend starts a fake backend that reads a request and passes it to
+// the handler along with the raw connection; it returns a dialer for it.
+func newTunnelBackend(t *testing.T, handle func(req *http.Request, reader *bufio.Reader, conn net.Conn)) func(context.Context) (net.Conn, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				req, err := http.ReadRequest(reader)
+				if err != nil {
+					return
+				}
+				handle(req, reader, conn)
+			}()
+		}
+	}()
+	return func(ctx context.Context) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", listener.Addr().String())
+	}
+}
+
+// openTunnel sends the given request head to the server, returning the
+// connection and the response.
+func openTunnel(t *testing.T, server *httptest.Server, method, head string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	_, err = io.WriteString(conn, head+"\r\n")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: method})
+	require.NoError(t, err)
+	return conn, reader, resp
+}
+
+func TestConnectTunnel(t *testing.T) {
+	// The backend accepts CONNECT to /tunnel and then echoes everything back,
+	// and rejects anything else.
+	dialer := newTunnelBackend(t, func(req *http.Request, reader *bufio.Reader, conn net.Conn) {
+		if req.Method != http.MethodConnect || req.URL.Path != "/tunnel" {
+			_, _ = io.WriteString(conn, "HTTP/1.1 404 Not Found\r\nContent-Length: 9\r\n\r\nnot found")
+			return
+		}
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 OK\r\nX-Tunnel: yes\r\n\r\n")
+		_, _ = io.Copy(conn, reader)
+	})
+	server := httptest.NewServer(newConnectTunnel(dialer, nil))
+	defer server.Close()
+
+	t.Run("accepted", func(t *testing.T) {
+		conn, reader, resp := openTunnel(t, server, http.MethodConnect,
+			"CONNECT /tunnel HTTP/1.1\r\nHost: api.moby.localhost\r\n")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "yes", resp.Header.Get("X-Tunnel"))
+		_, err := io.WriteString(conn, "hello\n")
+		require.NoError(t, err)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "hello\n", line)
+	})
+	t.Run("rejected", func(t *testing.T) {
+		_, _, resp := openTunnel(t, server, http.MethodConnect,
+			"CONNECT /elsewhere HTTP/1.1\r\nHost: api.moby.localhost\r\n")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "not found", string(body))
+	})
+}
+
+func TestSessionTunnel(t *testing.T) {
+	// The backend upgrades the session, reads everything until the client
+	// half-closes its side, and then replies.
+	dialer := newTunnelBackend(t, func(req *http.Request, reader *bufio.Reader, conn net.Conn) {
+		if req.Header.Get("Upgrade") != "h2c" {
+			_, _ = io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+			return
+		}
+		_, _ = io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+		received, err := io.ReadAll(reader)
+		if err != nil {
+			return
+		}
+		_, _ = io.WriteString(conn, "received "+string(received))
+	})
+	server := httptest.NewServer(newSessionTunnel(dialer, nil))
+	defer server.Close()
+
+	conn, reader, resp := openTunnel(t, server, http.MethodPost,
+		"POST /v1.41/session HTTP/1.1\r\nHost: api.moby.localhost\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n")
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "h2c", resp.Header.Get("Upgrade"))
+	_, err := io.WriteString(conn, "build context")
+	require.NoError(t, err)
+	require.NoError(t, conn.(*net.TCPConn).CloseWrite())
+	reply, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "received build context", string(reply))
+}
@@ -0,0 +1,242 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/dockerproxy/platform"
+)
+
+// UpstreamKind is the protocol spoken by an upstream endpoint.
+type UpstreamKind string
+
+const (
+	// UpstreamContainerd is a containerd gRPC endpoint; requests without a
+	// namespace are given the configured default namespace.
+	UpstreamContainerd UpstreamKind = "containerd"
+	// UpstreamStream is forwarded as an opaque byte stream; this is used for
+	// endpoints such as buildkitd that need no munging.
+	UpstreamStream UpstreamKind = "stream"
+)
+
+// Upstream is an additional backend served by the proxy, alongside the docker
+// API, on its own endpoint.
+type Upstream struct {
+	// Kind is the protocol spoken by the backend.
+	Kind UpstreamKind
+	// Endpoint is where to listen for clients.  As clients are not
+	// authenticated, TCP endpoints must be on a loopback address.
+	Endpoint string
+	// Dialer connects to the backend.
+	Dialer func() (net.Conn, error)
+	// Namespace is the default containerd namespace, for UpstreamContainerd.
+	Namespace string
+}
+
+// UpstreamSpec is the textual form of an upstream, before the backend has been
+// resolved into a dialer.
+type UpstreamSpec struct {
+	Kind     UpstreamKind
+	Endpoint string // Endpoint to listen on.
+	Backend  string // Backend to connect to, in a platform-specific format.
+}
+
+// ParseUpstreamSpec parses an upstream in the form "kind:endpoint=backend",
+// e.g. "stream:/run/rd-buildkitd.sock=/run/buildkit/buildkitd.sock".
+func ParseUpstreamSpec(spec string) (UpstreamSpec, error) {
+	kind, rest, ok := strings.Cut(spec, ":")
+	if !ok {
+		return UpstreamSpec{}, fmt.Errorf("upstream %q is not in the form kind:endpoint=backend", spec)
+	}
+	endpoint, backend, ok := strings.Cut(rest, "=")
+	if !ok || endpoint == "" || backend == "" {
+		return UpstreamSpec{}, fmt.Errorf("upstream %q is not in the form kind:endpoint=backend", spec)
+	}
+	switch UpstreamKind(kind) {
+	case UpstreamContainerd, UpstreamStream:
+	default:
+		return UpstreamSpec{}, fmt.Errorf("upstream %q has unknown kind %q", spec, kind)
+	}
+	return UpstreamSpec{Kind: UpstreamKind(kind), Endpoint: endpoint, Backend: backend}, nil
+}
+
+// upstreamServer serves clients of one upstream.
+type upstreamServer interface {
+	Serve(listener net.Listener) error
+	Shutdown(ctx context.Context) error
+}
+
+// newUpstreamServer returns a server for the given upstream.
+func newUpstreamServer(upstream Upstream, pool *utils.BufferPool, logger logrus.FieldLogger) (upstreamServer, error) {
+	switch upstream.Kind {
+	case UpstreamContainerd:
+		return &http.Server{
+			ReadHeaderTimeout: time.Minute,
+			Handler:           newContainerdHandler(upstream.Dialer, upstream.Namespace, logger),
+		}, nil
+	case UpstreamStream:
+		return &streamServer{dialer: upstream.Dialer, pool: pool, logger: logger, tracker: newHijackTracker()}, nil
+	}
+	return nil, fmt.Errorf("unknown upstream kind %q", upstream.Kind)
+}
+
+// serveUpstreams listens on the endpoints of the given upstreams and serves
+// them in the background.  The returned function shuts them all down, waiting
+// for connections to finish until the context expires.
+func serveUpstreams(upstreams []Upstream, socketOptions platform.ListenOptions, pool *utils.BufferPool, logger logrus.FieldLogger) (func(context.Context), error) {
+	type running struct {
+		server upstreamServer
+		done   chan struct{}
+	}
+	var servers []running
+	shutdown := func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, r := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = r.server.Shutdown(ctx)
+				<-r.done
+			}()
+		}
+		wg.Wait()
+	}
+	for _, upstream := range upstreams {
+		entry := logger.WithField("endpoint", upstream.Endpoint).WithField("kind", upstream.Kind)
+		server, err := newUpstreamServer(upstream, pool, entry)
+		if err == nil {
+			var listener net.Listener
+			listener, err = platform.Listen(upstream.Endpoint, socketOptions)
+			if err == nil {
+				err = checkUpstreamListener(upstream.Endpoint, listener)
+				if err != nil {
+					listener.Close()
+				}
+			}
+			if err == nil {
+				r := running{server: server, done: make(chan struct{})}
+				servers = append(servers, r)
+				go func() {
+					defer close(r.done)
+					entry.Info("Listening for upstream requests")
+					if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						entry.WithError(err).Error("upstream proxy exited with error")
+					}
+				}()
+			}
+		}
+		if err != nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			shutdown(ctx)
+			return nil, err
+		}
+	}
+	return shutdown, nil
+}
+
+// checkUpstreamListener checks that an upstream is not exposed to the network.
+// Upstreams are served without authentication or TLS (the protocols they
+// speak have no room for our tokens), so they may only listen on TCP on the
+// loopback interface.
+func checkUpstreamListener(endpoint string, listener net.Listener) error {
+	addr, ok := listener.Addr().(*net.TCPAddr)
+	if !ok || addr.IP.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("upstream endpoint %s must be a local socket or a loopback address, as upstreams are not authenticated", endpoint)
+}
+
+// streamServer forwards connections to the backend without interpreting the
+// data sent over them.
+type streamServer struct {
+	dialer  func() (net.Conn, error)
+	pool    *utils.BufferPool
+	logger  logrus.FieldLogger
+	tracker *hijackTracker
+
+	listener net.Listener
+	closed   bool
+	sync.Mutex
+}
+
+// Serve accepts connections on the listener until Shutdown is called.
+func (s *streamServer) Serve(listener net.Listener) error {
+	s.Lock()
+	if s.closed {
+		s.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	s.listener = listener
+	s.Unlock()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.Lock()
+			closed := s.closed
+			s.Unlock()
+			if closed {
+				return http.ErrServerClosed
+			}
+			return err
+		}
+		go s.handle(s.tracker.track(conn))
+	}
+}
+
+// handle a single client connection.
+func (s *streamServer) handle(conn net.Conn) {
+	backend, err := s.dialer()
+	if err != nil {
+		s.logger.WithError(err).Error("could not connect to upstream backend")
+		conn.Close()
+		return
+	}
+	result, err := utils.Relay(context.Background(), conn, backend, utils.RelayOptions{BufferPool: s.pool})
+	if err == nil {
+		err = result.Err()
+	}
+	if err != nil {
+		s.logger.WithError(err).Debug("upstream connection closed with error")
+	}
+}
+
+// Shutdown stops accepting connections, and waits for existing ones to finish;
+// if the context expires first, they are closed.
+func (s *streamServer) Shutdown(ctx context.Context) error {
+	s.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.Unlock()
+	return s.tracker.wait(ctx)
+}
@@ -0,0 +1,129 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/networking/pkg/utils"
+)
+
+func TestParseUpstreamSpec(t *testing.T) {
+	spec, err := ParseUpstreamSpec("stream:/run/rd-buildkitd.sock=/run/buildkit/buildkitd.sock")
+	require.NoError(t, err)
+	assert.Equal(t, UpstreamSpec{Kind: UpstreamStream, Endpoint: "/run/rd-buildkitd.sock", Backend: "/run/buildkit/buildkitd.sock"}, spec)
+
+	spec, err = ParseUpstreamSpec("containerd:npipe:////./pipe/containerd=1234")
+	require.NoError(t, err)
+	assert.Equal(t, UpstreamSpec{Kind: UpstreamContainerd, Endpoint: "npipe:////./pipe/containerd", Backend: "1234"}, spec)
+
+	for _, invalid := range []string{"", "stream", "stream:/run/a.sock", "stream:=/run/b.sock", "unknown:/run/a.sock=/run/b.sock"} {
+		_, err := ParseUpstreamSpec(invalid)
+		assert.Error(t, err, "%q should be invalid", invalid)
+	}
+}
+
+func TestStreamServer(t *testing.T) {
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	upstream := Upstream{
+		Kind: UpstreamStream,
+		Dialer: func() (net.Conn, error) {
+			return net.Dial("tcp", backend.Addr().String())
+		},
+	}
+	server, err := newUpstreamServer(upstream, utils.DefaultBufferPool, logrus.StandardLogger())
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- server.Serve(listener) }()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	// Shutting down waits for the connection, then closes it once the
+	// context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, server.Shutdown(ctx), context.DeadlineExceeded)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(buf)
+	assert.ErrorIs(t, err, io.EOF, "connection should be closed")
+	assert.Error(t, <-serveDone)
+}
+
+func TestCheckUpstreamListener(t *testing.T) {
+	for _, testCase := range []struct {
+		address string
+		valid   bool
+	}{
+		{"127.0.0.1:0", true},
+		{"[::1]:0", true},
+		{"0.0.0.0:0", false},
+	} {
+		t.Run(testCase.address, func(t *testing.T) {
+			listener, err := net.Listen("tcp", testCase.address)
+			if err != nil {
+				t.Skipf("could not listen on %s: %s", testCase.address, err)
+			}
+			defer listener.Close()
+			err = checkUpstreamListener("tcp://"+testCase.address, listener)
+			if testCase.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, "must be a local socket or a loopback address")
+			}
+		})
+	}
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "upstream.sock"))
+	require.NoError(t, err)
+	defer listener.Close()
+	assert.NoError(t, checkUpstreamListener("unix://upstream.sock", listener))
+}
@@ -0,0 +1,138 @@
+//go:build linux || windows
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerproxy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// fileWatcher calls a function when any of a set of files or directories
+// changes.  The call is delayed slightly, so that a burst of changes (such as
+// replacing a certificate and its key) only results in a single call.
+type fileWatcher struct {
+	watcher  *fsnotify.Watcher
+	logger   logrus.FieldLogger
+	delay    time.Duration
+	onChange func()
+	// files is the set of watched files; changes to other files in their
+	// directories are ignored.  Changes anywhere in watched directories are
+	// always reported.
+	files map[string]struct{}
+	dirs  map[string]struct{}
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+// newFileWatcher starts watching the given paths, which may be either files or
+// directories.  The watcher must be closed when it is no longer needed.
+func newFileWatcher(paths []string, delay time.Duration, logger logrus.FieldLogger, onChange func()) (*fileWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("could not create file watcher: %w", err)
+	}
+	w := &fileWatcher{
+		watcher:  watcher,
+		logger:   logger,
+		delay:    delay,
+		onChange: onChange,
+		files:    make(map[string]struct{}),
+		dirs:     make(map[string]struct{}),
+	}
+	for _, watchPath := range paths {
+		watchPath = filepath.Clean(watchPath)
+		watchDir := watchPath
+		if info, err := os.Stat(watchPath); err == nil && info.IsDir() {
+			w.dirs[watchPath] = struct{}{}
+		} else {
+			// Watch the directory rather than the file, as files are usually
+			// replaced (possibly via a symlink) rather than written to.
+			watchDir = filepath.Dir(watchPath)
+			w.files[watchPath] = struct{}{}
+		}
+		if err := watcher.Add(watchDir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("could not watch %s: %w", watchPath, err)
+		}
+	}
+	go w.watch()
+	return w, nil
+}
+
+// relevant checks if the given event is for a path we are interested in.
+func (w *fileWatcher) relevant(event fsnotify.Event) bool {
+	name := filepath.Clean(event.Name)
+	if _, ok := w.files[name]; ok {
+		return true
+	}
+	if _, ok := w.dirs[filepath.Dir(name)]; ok {
+		return true
+	}
+	// Symlink-based updates (as used for Kubernetes secrets) replace a hidden
+	// entry in the directory instead of the file itself.
+	return filepath.Base(name)[0] == '.'
+}
+
+func (w *fileWatcher) watch() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.relevant(event) {
+				w.schedule()
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.WithError(err).Warn("error watching files")
+		}
+	}
+}
+
+// schedule a call to onChange after the delay; further changes within the
+// delay push the call back.
+func (w *fileWatcher) schedule() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Reset(w.delay)
+		return
+	}
+	w.timer = time.AfterFunc(w.delay, w.onChange)
+}
+
+// Close stops watching for changes.
+func (w *fileWatcher) Close() error {
+	w.mu.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+	return w.watcher.Close()
+}
@@ -0,0 +1,398 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/atomicfile"
+)
+
+const (
+	// DefaultResolvConfPath is the resolver configuration file.
+	DefaultResolvConfPath = "/etc/resolv.conf"
+	// DefaultDNSProbeTimeout is how long to wait for a nameserver to respond.
+	DefaultDNSProbeTimeout = 2 * time.Second
+	// wslTunnelResolver is the address WSL uses for DNS tunneling through the
+	// Windows host resolver.
+	wslTunnelResolver = "10.255.255.254"
+	// resolvConfMarker is the first line of a resolv.conf we have written.
+	resolvConfMarker = "# Generated by Rancher Desktop (wsl-helper)."
+	// resolvConfOriginalPrefix prefixes the original nameservers recorded in a
+	// resolv.conf we have written.
+	resolvConfOriginalPrefix = "# original nameserver "
+)
+
+// DNSAction describes what DNS fix-ups were done.
+type DNSAction string
+
+const (
+	// DNSActionNone means no changes were needed.
+	DNSActionNone DNSAction = "none"
+	// DNSActionApplied means resolution was redirected to the host resolver.
+	DNSActionApplied DNSAction = "applied"
+	// DNSActionReverted means the original configuration was restored.
+	DNSActionReverted DNSAction = "reverted"
+	// DNSActionUnavailable means the configured nameservers are unreachable,
+	// but no host resolver could be reached either.
+	DNSActionUnavailable DNSAction = "unavailable"
+)
+
+// DNSOptions control the DNS fix-ups.
+type DNSOptions struct {
+	// Path is the resolv.conf to manage; defaults to DefaultResolvConfPath.
+	Path string
+	// Resolvers are candidate host resolver addresses, in order of preference;
+	// defaults to the WSL DNS tunneling address and the default gateway.
+	Resolvers []string
+	// Port is the port to probe nameservers on; defaults to 53.
+	Port int
+	// Timeout is how long to wait for each probe; defaults to
+	// DefaultDNSProbeTimeout.
+	Timeout time.Duration
+}
+
+// DNSStatus describes the state of DNS resolution in the distribution.
+type DNSStatus struct {
+	Action DNSAction `json:"action"`
+	// Managed is set if resolv.conf has been rewritten by us.
+	Managed bool `json:"managed"`
+	// Nameservers are the nameservers from the original configuration.
+	Nameservers []string `json:"nameservers"`
+	// Reachable is set if any of the original nameservers respond.
+	Reachable bool `json:"reachable"`
+	// Resolver is the host resolver in use, if any.
+	Resolver string `json:"resolver,omitempty"`
+}
+
+// resolvConf is a parsed resolv.conf file.
+type resolvConf struct {
+	nameservers []string // nameserver entries
+	original    []string // original nameservers, if we wrote this file
+	other       []string // other non-comment lines (search, options, etc.)
+	managed     bool     // whether we wrote this file
+}
+
+// parseResolvConf parses resolv.conf contents.
+func parseResolvConf(data []byte) *resolvConf {
+	result := &resolvConf{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first && line == resolvConfMarker {
+			result.managed = true
+		}
+		first = false
+		if result.managed && strings.HasPrefix(line, resolvConfOriginalPrefix) {
+			result.original = append(result.original, strings.TrimSpace(strings.TrimPrefix(line, resolvConfOriginalPrefix)))
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if fields[0] == "nameserver" && len(fields) > 1 {
+			result.nameservers = append(result.nameservers, fields[1])
+		} else {
+			result.other = append(result.other, line)
+		}
+	}
+	return result
+}
+
+// render the managed resolv.conf using the given resolver.
+func (c *resolvConf) render(resolver string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, resolvConfMarker)
+	fmt.Fprintln(&buf, "# The nameservers below were unreachable (usually because of a VPN), so")
+	fmt.Fprintln(&buf, "# the host resolver is used instead; the original file is restored once")
+	fmt.Fprintln(&buf, "# they are reachable again.")
+	for _, nameserver := range c.original {
+		fmt.Fprintf(&buf, "%s%s\n", resolvConfOriginalPrefix, nameserver)
+	}
+	fmt.Fprintf(&buf, "nameserver %s\n", resolver)
+	for _, line := range c.other {
+		fmt.Fprintln(&buf, line)
+	}
+	return buf.Bytes()
+}
+
+// defaultGateway returns the IPv4 default gateway from /proc/net/route.
+func defaultGateway() (string, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		gateway := make(net.IP, 4)
+		binary.BigEndian.PutUint32(gateway, binary.LittleEndian.Uint32(raw))
+		return gateway.String(), nil
+	}
+	return "", errors.New("no default route found")
+}
+
+// defaultResolvers returns the candidate host resolvers.
+func defaultResolvers() []string {
+	resolvers := []string{wslTunnelResolver}
+	if gateway, err := defaultGateway(); err == nil {
+		resolvers = append(resolvers, gateway)
+	} else {
+		logrus.WithError(err).Debug("Failed to determine default gateway")
+	}
+	return resolvers
+}
+
+// ProbeNameserver checks that the given nameserver answers a query for the
+// root name servers.  Servers that respond with a server failure (for example,
+// because their upstream is unreachable) are considered broken.
+func ProbeNameserver(ctx context.Context, server string, port int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(server, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Query: random ID, recursion desired, one question for ". IN NS".
+	query := make([]byte, 12, 17)
+	if _, err := rand.Read(query[:2]); err != nil {
+		return err
+	}
+	query[2] = 0x01 // RD
+	query[5] = 0x01 // QDCOUNT
+	query = append(query, 0x00, 0x00, 0x02, 0x00, 0x01)
+	if _, err := conn.Write(query); err != nil {
+		return err
+	}
+	response := make([]byte, 512)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return err
+		}
+		if n < 12 || !bytes.Equal(response[:2], query[:2]) || response[2]&0x80 == 0 {
+			// Not a response to our query; keep waiting.
+			continue
+		}
+		switch rcode := response[3] & 0x0f; rcode {
+		case 0, 3: // NOERROR, NXDOMAIN
+			return nil
+		default:
+			return fmt.Errorf("nameserver %s returned error code %d", server, rcode)
+		}
+	}
+}
+
+// firstReachable returns the first of the given nameservers that responds.
+func firstReachable(ctx context.Context, servers []string, options DNSOptions) (string, bool) {
+	for _, server := range servers {
+		err := ProbeNameserver(ctx, server, options.Port, options.Timeout)
+		if err == nil {
+			return server, true
+		}
+		logrus.WithError(err).WithField("nameserver", server).Debug("Nameserver is unreachable")
+	}
+	return "", false
+}
+
+// backupPath returns where the original resolv.conf is kept while it has been
+// replaced.
+func (o DNSOptions) backupPath() string {
+	return o.Path + ".rancher-desktop-backup"
+}
+
+// withDefaults returns a copy of the options with defaults filled in.
+func (o DNSOptions) withDefaults() DNSOptions {
+	if o.Path == "" {
+		o.Path = DefaultResolvConfPath
+	}
+	if o.Port == 0 {
+		o.Port = 53
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultDNSProbeTimeout
+	}
+	return o
+}
+
+// CheckDNS reports the state of DNS resolution without making changes.
+func CheckDNS(ctx context.Context, options DNSOptions) (*DNSStatus, error) {
+	options = options.withDefaults()
+	data, err := os.ReadFile(options.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", options.Path, err)
+	}
+	conf := parseResolvConf(data)
+	status := &DNSStatus{Action: DNSActionNone, Managed: conf.managed, Nameservers: conf.nameservers}
+	if conf.managed {
+		status.Nameservers = conf.original
+		if len(conf.nameservers) > 0 {
+			status.Resolver = conf.nameservers[0]
+		}
+	}
+	_, status.Reachable = firstReachable(ctx, status.Nameservers, options)
+	return status, nil
+}
+
+// FixDNS redirects name resolution to the host resolver if none of the
+// nameservers in resolv.conf are reachable (usually because a VPN on the host
+// routes them away), and restores the original configuration once they are
+// reachable again.
+func FixDNS(ctx context.Context, options DNSOptions) (*DNSStatus, error) {
+	options = options.withDefaults()
+	status, err := CheckDNS(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	if status.Reachable {
+		if status.Managed {
+			if err := restoreResolvConf(options); err != nil {
+				return nil, err
+			}
+			status.Action = DNSActionReverted
+			status.Managed = false
+			status.Resolver = ""
+		}
+		return status, nil
+	}
+
+	resolvers := options.Resolvers
+	if resolvers == nil {
+		resolvers = defaultResolvers()
+	}
+	// Prefer the resolver already in use, and never use one of the broken
+	// nameservers.
+	if status.Resolver != "" {
+		resolvers = append([]string{status.Resolver}, resolvers...)
+	}
+	resolvers = slices.DeleteFunc(slices.Clone(resolvers), func(r string) bool {
+		return slices.Contains(status.Nameservers, r)
+	})
+	resolver, ok := firstReachable(ctx, resolvers, options)
+	if !ok {
+		status.Action = DNSActionUnavailable
+		return status, nil
+	}
+	if status.Managed && resolver == status.Resolver {
+		return status, nil
+	}
+
+	data, err := os.ReadFile(options.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", options.Path, err)
+	}
+	conf := parseResolvConf(data)
+	if !conf.managed {
+		conf.original = conf.nameservers
+		// Move the original aside; this keeps it intact if it's a symlink (for
+		// example, to the systemd-resolved stub configuration).
+		if err := os.Rename(options.Path, options.backupPath()); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %w", options.Path, err)
+		}
+	}
+	if err := writeResolvConf(options.Path, conf.render(resolver)); err != nil {
+		return nil, err
+	}
+	logrus.WithField("resolver", resolver).WithField("nameservers", status.Nameservers).
+		Info("Nameservers are unreachable; using host resolver")
+	status.Action = DNSActionApplied
+	status.Managed = true
+	status.Resolver = resolver
+	return status, nil
+}
+
+// RevertDNS restores the original resolv.conf, if it has been replaced.
+func RevertDNS(options DNSOptions) (*DNSStatus, error) {
+	options = options.withDefaults()
+	data, err := os.ReadFile(options.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", options.Path, err)
+	}
+	conf := parseResolvConf(data)
+	if !conf.managed {
+		return &DNSStatus{Action: DNSActionNone, Nameservers: conf.nameservers}, nil
+	}
+	if err := restoreResolvConf(options); err != nil {
+		return nil, err
+	}
+	return &DNSStatus{Action: DNSActionReverted, Nameservers: conf.original}, nil
+}
+
+// restoreResolvConf moves the original resolv.conf back into place.  If there
+// is no backup (for example, WSL regenerated the file), a resolv.conf with the
+// original nameservers is written instead.
+func restoreResolvConf(options DNSOptions) error {
+	err := os.Rename(options.backupPath(), options.Path)
+	if err == nil {
+		logrus.Info("Restored original resolv.conf")
+		return nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to restore %s: %w", options.Path, err)
+	}
+	data, err := os.ReadFile(options.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", options.Path, err)
+	}
+	conf := parseResolvConf(data)
+	var buf bytes.Buffer
+	for _, nameserver := range conf.original {
+		fmt.Fprintf(&buf, "nameserver %s\n", nameserver)
+	}
+	for _, line := range conf.other {
+		fmt.Fprintln(&buf, line)
+	}
+	return writeResolvConf(options.Path, buf.Bytes())
+}
+
+// writeResolvConf atomically writes resolv.conf.
+func writeResolvConf(path string, contents []byte) error {
+	if err := atomicfile.WriteFile(path, contents, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+// startNameserver runs a fake nameserver on the given address, replying to
+// every query with the given response code; it returns the port used.
+func startNameserver(t *testing.T, address string, rcode byte) int {
+	conn, err := net.ListenPacket("udp", address)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 {
+				continue
+			}
+			response := append([]byte{}, buf[:n]...)
+			response[2] |= 0x80 // QR
+			response[3] = 0x80 | rcode
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestProbeNameserver(t *testing.T) {
+	ctx := context.Background()
+	port := startNameserver(t, "127.0.0.1:0", 0)
+	assert.NoError(t, integration.ProbeNameserver(ctx, "127.0.0.1", port, time.Second))
+
+	failPort := startNameserver(t, "127.0.0.1:0", 2)
+	assert.ErrorContains(t, integration.ProbeNameserver(ctx, "127.0.0.1", failPort, time.Second), "error code 2")
+
+	// Nothing is listening on this address.
+	assert.Error(t, integration.ProbeNameserver(ctx, "127.0.0.2", port, time.Second))
+}
+
+func TestFixDNS(t *testing.T) {
+	ctx := context.Background()
+	port := startNameserver(t, "127.0.0.1:0", 0)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "stub-resolv.conf")
+	original := "# generated by WSL\nnameserver 127.0.0.2\nsearch example.test\n"
+	require.NoError(t, os.WriteFile(target, []byte(original), 0o644))
+	path := filepath.Join(dir, "resolv.conf")
+	require.NoError(t, os.Symlink(target, path))
+	options := integration.DNSOptions{
+		Path:      path,
+		Resolvers: []string{"127.0.0.2", "127.0.0.1"},
+		Port:      port,
+		Timeout:   time.Second,
+	}
+
+	status, err := integration.FixDNS(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, &integration.DNSStatus{
+		Action:      integration.DNSActionApplied,
+		Managed:     true,
+		Nameservers: []string{"127.0.0.2"},
+		Resolver:    "127.0.0.1",
+	}, status)
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "\nnameserver 127.0.0.1\n")
+	assert.Contains(t, string(contents), "\nsearch example.test\n")
+	assert.NotContains(t, string(contents), "\nnameserver 127.0.0.2")
+
+	t.Run("unchanged", func(t *testing.T) {
+		status, err := integration.FixDNS(ctx, options)
+		require.NoError(t, err)
+		assert.Equal(t, integration.DNSActionNone, status.Action)
+		assert.True(t, status.Managed)
+		assert.Equal(t, "127.0.0.1", status.Resolver)
+	})
+
+	t.Run("reverted", func(t *testing.T) {
+		// The VPN disconnected, so the original nameserver works again.
+		startNameserver(t, net.JoinHostPort("127.0.0.2", strconv.Itoa(port)), 0)
+		status, err := integration.FixDNS(ctx, options)
+		require.NoError(t, err)
+		assert.Equal(t, integration.DNSActionReverted, status.Action)
+		assert.False(t, status.Managed)
+		link, err := os.Readlink(path)
+		require.NoError(t, err, "original symlink should be restored")
+		assert.Equal(t, target, link)
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(contents))
+	})
+}
+
+func TestRevertDNS(t *testing.T) {
+	ctx := context.Background()
+	port := startNameserver(t, "127.0.0.1:0", 0)
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	original := "nameserver 127.0.0.2\noptions ndots:1\n"
+	require.NoError(t, os.WriteFile(path, []byte(original), 0o644))
+	options := integration.DNSOptions{
+		Path:      path,
+		Resolvers: []string{"127.0.0.1"},
+		Port:      port,
+		Timeout:   time.Second,
+	}
+
+	status, err := integration.RevertDNS(options)
+	require.NoError(t, err)
+	assert.Equal(t, integration.DNSActionNone, status.Action)
+
+	status, err = integration.FixDNS(ctx, options)
+	require.NoError(t, err)
+	require.Equal(t, integration.DNSActionApplied, status.Action)
+
+	// If the backup went away (e.g. WSL regenerated the file), the original
+	// nameservers are restored from the managed file.
+	require.NoError(t, os.Remove(path+".rancher-desktop-backup"))
+	status, err = integration.RevertDNS(options)
+	require.NoError(t, err)
+	assert.Equal(t, integration.DNSActionReverted, status.Action)
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, original, string(contents))
+}
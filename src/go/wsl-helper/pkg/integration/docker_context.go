@@ -0,0 +1,156 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DockerContextName is the name of the docker CLI context we manage.
+	DockerContextName = "rancher-desktop"
+	currentContextKey = "currentContext"
+)
+
+// dockerContextMeta is the format of a docker CLI context metadata file.
+type dockerContextMeta struct {
+	Name      string
+	Metadata  map[string]any
+	Endpoints map[string]dockerContextEndpoint
+}
+
+type dockerContextEndpoint struct {
+	Host          string
+	SkipTLSVerify bool
+}
+
+// dockerContextDir returns the directory holding the metadata for the named
+// docker CLI context.
+func dockerContextDir(homeDir, name string) string {
+	hash := sha256.Sum256([]byte(name))
+	return filepath.Join(homeDir, ".docker", "contexts", "meta", hex.EncodeToString(hash[:]))
+}
+
+// SetupDockerContext creates (or updates) the rancher-desktop docker CLI
+// context to point at the given endpoint (e.g. "unix:///var/run/docker.sock").
+// If use is set, the context is made the current context; otherwise, it is
+// only made current if the current context no longer exists.
+func SetupDockerContext(homeDir, endpoint string, use bool) error {
+	metaPath := filepath.Join(dockerContextDir(homeDir, DockerContextName), "meta.json")
+	meta := dockerContextMeta{
+		Name:     DockerContextName,
+		Metadata: map[string]any{"Description": "Rancher Desktop moby context"},
+		Endpoints: map[string]dockerContextEndpoint{
+			"docker": {Host: endpoint},
+		},
+	}
+	var existing dockerContextMeta
+	if buf, err := os.ReadFile(metaPath); err == nil && json.Unmarshal(buf, &existing) == nil &&
+		existing.Name == DockerContextName && existing.Endpoints["docker"] == meta.Endpoints["docker"] {
+		// The context is already correct.
+	} else {
+		buf, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to serialize docker context: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create docker context directory: %w", err)
+		}
+		if err := os.WriteFile(metaPath, buf, 0o644); err != nil {
+			return fmt.Errorf("failed to write docker context: %w", err)
+		}
+	}
+
+	config, err := readDockerConfig(homeDir)
+	if err != nil {
+		return err
+	}
+	current, _ := config[currentContextKey].(string)
+	if current == DockerContextName {
+		return nil
+	}
+	if !use && dockerContextExists(homeDir, current) {
+		return nil
+	}
+	config[currentContextKey] = DockerContextName
+	return writeDockerConfig(homeDir, config)
+}
+
+// RemoveDockerContext removes the rancher-desktop docker CLI context; if it is
+// the current context, the current context is reset to the default.
+func RemoveDockerContext(homeDir string) error {
+	if err := os.RemoveAll(dockerContextDir(homeDir, DockerContextName)); err != nil {
+		return fmt.Errorf("failed to remove docker context: %w", err)
+	}
+	config, err := readDockerConfig(homeDir)
+	if err != nil {
+		return err
+	}
+	if current, _ := config[currentContextKey].(string); current != DockerContextName {
+		return nil
+	}
+	delete(config, currentContextKey)
+	return writeDockerConfig(homeDir, config)
+}
+
+// dockerContextExists checks if the named docker CLI context exists; the
+// default context (which has no metadata) always exists.
+func dockerContextExists(homeDir, name string) bool {
+	if name == "" || name == "default" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dockerContextDir(homeDir, name), "meta.json"))
+	return err == nil
+}
+
+// readDockerConfig reads the docker CLI configuration; a missing file is
+// treated as empty.
+func readDockerConfig(homeDir string) (map[string]any, error) {
+	config := make(map[string]any)
+	buf, err := os.ReadFile(filepath.Join(homeDir, ".docker", "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read docker CLI configuration: %w", err)
+	}
+	if err := json.Unmarshal(buf, &config); err != nil {
+		return nil, fmt.Errorf("could not parse docker CLI configuration: %w", err)
+	}
+	return config, nil
+}
+
+// writeDockerConfig writes the docker CLI configuration.
+func writeDockerConfig(homeDir string, config map[string]any) error {
+	configPath := filepath.Join(homeDir, ".docker", "config.json")
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize updated docker CLI configuration: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o755); err != nil {
+		return fmt.Errorf("failed to update docker CLI configuration: could not create parent: %w", err)
+	}
+	if err := os.WriteFile(configPath, buf, 0o644); err != nil {
+		return fmt.Errorf("failed to update docker CLI configuration: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+// contextMetaPath is the metadata path for the rancher-desktop context.
+const contextMetaPath = ".docker/contexts/meta/b547d66a5de60e5f0843aba28283a8875c2ad72e99ba076060ef9ec7c09917c8/meta.json"
+
+func readJSON(t *testing.T, filePath string) map[string]any {
+	buf, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	var result map[string]any
+	require.NoError(t, json.Unmarshal(buf, &result))
+	return result
+}
+
+func writeJSON(t *testing.T, filePath, contents string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+	require.NoError(t, os.WriteFile(filePath, []byte(contents), 0o644))
+}
+
+func TestSetupDockerContext(t *testing.T) {
+	t.Parallel()
+	t.Run("create context", func(t *testing.T) {
+		homeDir := t.TempDir()
+		require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///var/run/docker.sock", false))
+		meta := readJSON(t, filepath.Join(homeDir, contextMetaPath))
+		assert.Equal(t, integration.DockerContextName, meta["Name"])
+		assert.Equal(t, "unix:///var/run/docker.sock", meta["Endpoints"].(map[string]any)["docker"].(map[string]any)["Host"])
+		// With no current context, the default is in use; leave it alone.
+		_, err := os.Stat(filepath.Join(homeDir, ".docker", "config.json"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("update endpoint", func(t *testing.T) {
+		homeDir := t.TempDir()
+		require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///old.sock", false))
+		require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///new.sock", false))
+		meta := readJSON(t, filepath.Join(homeDir, contextMetaPath))
+		assert.Equal(t, "unix:///new.sock", meta["Endpoints"].(map[string]any)["docker"].(map[string]any)["Host"])
+	})
+	t.Run("use context", func(t *testing.T) {
+		homeDir := t.TempDir()
+		configPath := filepath.Join(homeDir, ".docker", "config.json")
+		writeJSON(t, configPath, `{"credsStore": "nothing", "currentContext": "default"}`)
+		require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///var/run/docker.sock", true))
+		assert.Equal(t, map[string]any{"credsStore": "nothing", "currentContext": integration.DockerContextName}, readJSON(t, configPath))
+	})
+	t.Run("repair dangling context", func(t *testing.T) {
+		homeDir := t.TempDir()
+		configPath := filepath.Join(homeDir, ".docker", "config.json")
+		writeJSON(t, configPath, `{"currentContext": "deleted"}`)
+		require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///var/run/docker.sock", false))
+		assert.Equal(t, integration.DockerContextName, readJSON(t, configPath)["currentContext"])
+	})
+}
+
+func TestRemoveDockerContext(t *testing.T) {
+	t.Parallel()
+	homeDir := t.TempDir()
+	configPath := filepath.Join(homeDir, ".docker", "config.json")
+	require.NoError(t, integration.SetupDockerContext(homeDir, "unix:///var/run/docker.sock", true))
+	require.NoError(t, integration.RemoveDockerContext(homeDir))
+	_, err := os.Stat(filepath.Join(homeDir, contextMetaPath))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NotContains(t, readJSON(t, configPath), "currentContext")
+
+	// Removing it again is fine.
+	assert.NoError(t, integration.RemoveDockerContext(homeDir))
+}
@@ -0,0 +1,284 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMountInfoPath is where the mounts of the current process are
+	// listed.
+	DefaultMountInfoPath = "/proc/self/mountinfo"
+	// defaultAutomountRoot is where WSL mounts Windows drives by default.
+	defaultAutomountRoot = "/mnt/"
+)
+
+// driveFilesystems are the filesystem types WSL uses to mount Windows drives.
+var driveFilesystems = []string{"drvfs", "9p", "virtiofs"}
+
+// DriveStatus describes whether a Windows drive is mounted.
+type DriveStatus struct {
+	// Letter is the (lower case) drive letter.
+	Letter string `json:"letter"`
+	// Path is where the drive should be mounted.
+	Path string `json:"path"`
+	// Mounted is set if a Windows drive is mounted at Path.
+	Mounted bool `json:"mounted"`
+	// Accessible is set if the mount can be read.
+	Accessible bool `json:"accessible"`
+	// Repaired is set if the drive was remounted.
+	Repaired bool `json:"repaired,omitempty"`
+	// Error describes why the drive is not usable, or could not be repaired.
+	Error string `json:"error,omitempty"`
+}
+
+// DrivesReport describes whether Windows drives are mounted.
+type DrivesReport struct {
+	// Healthy is set if all expected drives are mounted and accessible.
+	Healthy bool `json:"healthy"`
+	// Automount is false if automatic mounting is disabled in wsl.conf.
+	Automount bool `json:"automount"`
+	// Root is the directory drives are mounted under.
+	Root string `json:"root"`
+	// Drives are the drives checked.
+	Drives []DriveStatus `json:"drives"`
+	// RestartRequired is set if drives could not be repaired, and the
+	// distribution should be restarted (with `wsl --terminate`).
+	RestartRequired bool `json:"restart_required,omitempty"`
+}
+
+// DrivesOptions control which drives CheckDrives and RepairDrives look at.
+type DrivesOptions struct {
+	// Drives are the letters of drives expected to be mounted; drives that
+	// are currently mounted are always checked as well.  Leftover mount points
+	// of drives that no longer exist are ignored.  Defaults to "c".
+	Drives []string
+	// WSLConfPath overrides DefaultWSLConfPath, which is used to find the
+	// automount settings.
+	WSLConfPath string
+	// MountInfoPath overrides DefaultMountInfoPath, for testing.
+	MountInfoPath string
+	// Exec runs the given command (mount or umount); defaults to running it
+	// directly.
+	Exec func(ctx context.Context, name string, args ...string) error
+}
+
+func (o *DrivesOptions) setDefaults() {
+	if len(o.Drives) == 0 {
+		o.Drives = []string{"c"}
+	}
+	if o.WSLConfPath == "" {
+		o.WSLConfPath = DefaultWSLConfPath
+	}
+	if o.MountInfoPath == "" {
+		o.MountInfoPath = DefaultMountInfoPath
+	}
+	if o.Exec == nil {
+		o.Exec = func(ctx context.Context, name string, args ...string) error {
+			cmd := exec.CommandContext(ctx, name, args...)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			return cmd.Run()
+		}
+	}
+}
+
+// automountSettings returns whether automount is enabled, the directory
+// drives are mounted under, and the mount options to use.
+func automountSettings(wslConfPath string) (bool, string, string, error) {
+	conf, err := ReadWSLConf(wslConfPath)
+	if err != nil {
+		return false, "", "", err
+	}
+	enabled := true
+	if value, ok := conf.Get("automount", "enabled"); ok {
+		enabled = !strings.EqualFold(value, "false")
+	}
+	root := defaultAutomountRoot
+	if value, ok := conf.Get("automount", "root"); ok && value != "" {
+		root = strings.Trim(value, `"`)
+	}
+	options, _ := conf.Get("automount", "options")
+	return enabled, root, strings.Trim(options, `"`), nil
+}
+
+// unescapeMountInfo undoes the octal escaping of paths in mountinfo.
+func unescapeMountInfo(input string) string {
+	if !strings.Contains(input, `\`) {
+		return input
+	}
+	var builder strings.Builder
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\\' && i+3 < len(input) {
+			if value, err := strconv.ParseUint(input[i+1:i+4], 8, 8); err == nil {
+				builder.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		builder.WriteByte(input[i])
+	}
+	return builder.String()
+}
+
+// driveMounts returns the mount points of Windows drives.
+func driveMounts(mountInfoPath string) (map[string]bool, error) {
+	file, err := os.Open(mountInfoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer file.Close()
+	mounts := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Fields are: id parent major:minor root mountpoint options
+		// [optional fields...] - fstype source superoptions
+		fields := strings.Fields(scanner.Text())
+		separator := slices.Index(fields, "-")
+		if separator < 5 || separator+1 >= len(fields) {
+			continue
+		}
+		if slices.Contains(driveFilesystems, fields[separator+1]) {
+			mounts[unescapeMountInfo(fields[4])] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	return mounts, nil
+}
+
+// CheckDrives reports whether Windows drives are mounted and accessible.
+func CheckDrives(options DrivesOptions) (DrivesReport, error) {
+	options.setDefaults()
+	enabled, root, _, err := automountSettings(options.WSLConfPath)
+	if err != nil {
+		return DrivesReport{}, err
+	}
+	mounts, err := driveMounts(options.MountInfoPath)
+	if err != nil {
+		return DrivesReport{}, err
+	}
+	report := DrivesReport{Automount: enabled, Root: root, Healthy: true}
+
+	letters := make(map[string]bool)
+	for _, letter := range options.Drives {
+		letters[strings.ToLower(letter)] = true
+	}
+	for path := range mounts {
+		if filepath.Dir(path) == filepath.Clean(root) && len(filepath.Base(path)) == 1 {
+			letters[filepath.Base(path)] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(letters))
+	for letter := range letters {
+		sorted = append(sorted, letter)
+	}
+	slices.Sort(sorted)
+	for _, letter := range sorted {
+		drive := DriveStatus{Letter: letter, Path: filepath.Join(root, letter)}
+		drive.Mounted = mounts[drive.Path]
+		if !drive.Mounted {
+			drive.Error = "not mounted"
+		} else if _, err := os.ReadDir(drive.Path); err != nil {
+			drive.Error = fmt.Sprintf("not accessible: %s", err)
+		} else {
+			drive.Accessible = true
+		}
+		report.Healthy = report.Healthy && drive.Accessible
+		report.Drives = append(report.Drives, drive)
+	}
+	return report, nil
+}
+
+// RepairDrives checks that Windows drives are mounted, and tries to remount
+// any that aren't (or that are mounted but inaccessible).  If that fails, the
+// report says that the distribution must be restarted.  Drives are not
+// mounted if automount is disabled in wsl.conf.
+func RepairDrives(ctx context.Context, options DrivesOptions) (DrivesReport, error) {
+	options.setDefaults()
+	report, err := CheckDrives(options)
+	if err != nil || report.Healthy || !report.Automount {
+		return report, err
+	}
+	_, _, mountOptions, err := automountSettings(options.WSLConfPath)
+	if err != nil {
+		return report, err
+	}
+	repaired := false
+	for i, drive := range report.Drives {
+		if drive.Accessible {
+			continue
+		}
+		if err := repairDrive(ctx, options, drive, mountOptions); err != nil {
+			report.Drives[i].Error = fmt.Sprintf("%s; failed to remount: %s", drive.Error, err)
+			report.RestartRequired = true
+			continue
+		}
+		report.Drives[i].Repaired = true
+		repaired = true
+	}
+	if !repaired {
+		return report, nil
+	}
+
+	// Check again, to make sure the drives are now usable.
+	checked, err := CheckDrives(options)
+	if err != nil {
+		return report, err
+	}
+	for i, drive := range checked.Drives {
+		index := slices.IndexFunc(report.Drives, func(d DriveStatus) bool { return d.Letter == drive.Letter })
+		if index >= 0 {
+			checked.Drives[i].Repaired = report.Drives[index].Repaired
+			if !drive.Accessible && report.Drives[index].Error != "" {
+				checked.Drives[i].Error = report.Drives[index].Error
+			}
+		}
+	}
+	checked.RestartRequired = !checked.Healthy
+	return checked, nil
+}
+
+// repairDrive remounts a single drive.
+func repairDrive(ctx context.Context, options DrivesOptions, drive DriveStatus, mountOptions string) error {
+	if drive.Mounted {
+		// The mount is stale; detach it so we can mount over it.
+		if err := options.Exec(ctx, "umount", "-l", drive.Path); err != nil {
+			return fmt.Errorf("failed to unmount %s: %w", drive.Path, err)
+		}
+	}
+	if err := os.MkdirAll(drive.Path, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	args := []string{"-t", "drvfs", strings.ToUpper(drive.Letter) + ":", drive.Path}
+	if mountOptions != "" {
+		args = append(args, "-o", mountOptions)
+	}
+	return options.Exec(ctx, "mount", args...)
+}
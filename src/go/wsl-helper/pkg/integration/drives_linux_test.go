@@ -0,0 +1,126 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+// setupDrives creates a fake drive root with the given drive directories, and
+// returns options using a wsl.conf and mountinfo with the given mounted drives.
+func setupDrives(t *testing.T, wslConf string, dirs, mounted []string) (integration.DrivesOptions, string) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "mnt")
+	for _, letter := range dirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, letter), 0o755))
+	}
+	options := integration.DrivesOptions{
+		WSLConfPath:   filepath.Join(dir, "wsl.conf"),
+		MountInfoPath: filepath.Join(dir, "mountinfo"),
+	}
+	conf := fmt.Sprintf("[automount]\nroot = %s/\n%s", root, wslConf)
+	require.NoError(t, os.WriteFile(options.WSLConfPath, []byte(conf), 0o644))
+	mountInfo := "22 1 0:21 / / rw,relatime - ext4 /dev/sdc rw\n"
+	for i, letter := range mounted {
+		mountInfo += fmt.Sprintf("%d 22 0:%d / %s rw,noatime - 9p %s: rw,aname=drvfs\n", 30+i, 40+i, filepath.Join(root, letter), strings.ToUpper(letter))
+	}
+	require.NoError(t, os.WriteFile(options.MountInfoPath, []byte(mountInfo), 0o644))
+	return options, root
+}
+
+func TestCheckDrives(t *testing.T) {
+	// The mount point of a removed drive (d) is not checked.
+	options, root := setupDrives(t, "", []string{"c", "d", "data", "e"}, []string{"c", "e"})
+	report, err := integration.CheckDrives(options)
+	require.NoError(t, err)
+	assert.True(t, report.Healthy)
+	assert.True(t, report.Automount)
+	assert.Equal(t, root+"/", report.Root)
+	assert.Equal(t, []integration.DriveStatus{
+		{Letter: "c", Path: filepath.Join(root, "c"), Mounted: true, Accessible: true},
+		{Letter: "e", Path: filepath.Join(root, "e"), Mounted: true, Accessible: true},
+	}, report.Drives)
+
+	// Configured drives are checked even if they are not mounted.
+	options.Drives = []string{"C", "d"}
+	report, err = integration.CheckDrives(options)
+	require.NoError(t, err)
+	assert.False(t, report.Healthy)
+	assert.Equal(t, []integration.DriveStatus{
+		{Letter: "c", Path: filepath.Join(root, "c"), Mounted: true, Accessible: true},
+		{Letter: "d", Path: filepath.Join(root, "d"), Error: "not mounted"},
+		{Letter: "e", Path: filepath.Join(root, "e"), Mounted: true, Accessible: true},
+	}, report.Drives)
+}
+
+func TestRepairDrives(t *testing.T) {
+	t.Run("remount", func(t *testing.T) {
+		options, root := setupDrives(t, "options = \"metadata,uid=1000\"\n", []string{"c"}, nil)
+		var calls []string
+		options.Exec = func(ctx context.Context, name string, args ...string) error {
+			calls = append(calls, name+" "+strings.Join(args, " "))
+			file, err := os.OpenFile(options.MountInfoPath, os.O_APPEND|os.O_WRONLY, 0)
+			require.NoError(t, err)
+			defer file.Close()
+			_, err = fmt.Fprintf(file, "50 22 0:50 / %s rw - 9p C: rw\n", args[3])
+			return err
+		}
+		report, err := integration.RepairDrives(context.Background(), options)
+		require.NoError(t, err)
+		assert.True(t, report.Healthy)
+		assert.False(t, report.RestartRequired)
+		assert.Equal(t, []integration.DriveStatus{
+			{Letter: "c", Path: filepath.Join(root, "c"), Mounted: true, Accessible: true, Repaired: true},
+		}, report.Drives)
+		assert.Equal(t, []string{"mount -t drvfs C: " + filepath.Join(root, "c") + " -o metadata,uid=1000"}, calls)
+	})
+	t.Run("failure", func(t *testing.T) {
+		options, _ := setupDrives(t, "", []string{"c"}, nil)
+		options.Exec = func(ctx context.Context, name string, args ...string) error {
+			return errors.New("mount failed")
+		}
+		report, err := integration.RepairDrives(context.Background(), options)
+		require.NoError(t, err)
+		assert.False(t, report.Healthy)
+		assert.True(t, report.RestartRequired)
+		require.Len(t, report.Drives, 1)
+		assert.Equal(t, "not mounted; failed to remount: mount failed", report.Drives[0].Error)
+	})
+	t.Run("automount disabled", func(t *testing.T) {
+		options, _ := setupDrives(t, "enabled = false\n", []string{"c"}, nil)
+		options.Exec = func(ctx context.Context, name string, args ...string) error {
+			assert.Fail(t, "unexpected command", name)
+			return nil
+		}
+		report, err := integration.RepairDrives(context.Background(), options)
+		require.NoError(t, err)
+		assert.False(t, report.Healthy)
+		assert.False(t, report.Automount)
+		assert.False(t, report.RestartRequired)
+	})
+}
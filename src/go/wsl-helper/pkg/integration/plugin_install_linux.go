@@ -0,0 +1,438 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// pluginManifestName is the file, in the docker CLI plugin directory, that
+	// records the files we installed.
+	pluginManifestName = ".rancher-desktop-managed.json"
+	// pluginMetadataTimeout is how long to wait for a plugin to report its
+	// metadata.
+	pluginMetadataTimeout = 10 * time.Second
+)
+
+// InstallAction describes what was done with a file by InstallDockerPlugins.
+type InstallAction string
+
+const (
+	InstallActionInstalled InstallAction = "installed" // The file was newly installed.
+	InstallActionUpdated   InstallAction = "updated"   // An older version was replaced.
+	InstallActionUnchanged InstallAction = "unchanged" // The installed version is current.
+	InstallActionSkipped   InstallAction = "skipped"   // The file is managed by the user.
+	InstallActionRemoved   InstallAction = "removed"   // The file was uninstalled.
+)
+
+// InstallResult describes the outcome for a single file.
+type InstallResult struct {
+	Name    string        `json:"name"`
+	Path    string        `json:"path"`
+	Action  InstallAction `json:"action"`
+	Version string        `json:"version,omitempty"`
+	// Previous is the version that was installed before, if any.
+	Previous string `json:"previous,omitempty"`
+}
+
+// PluginInstallOptions describes which docker CLI plugins and wrappers to
+// install.
+type PluginInstallOptions struct {
+	// SourceDir is the directory containing the docker CLI plugins shipped with
+	// Rancher Desktop.
+	SourceDir string
+	// PluginDir is the docker CLI plugin directory to install into, typically
+	// ~/.docker/cli-plugins.
+	PluginDir string
+	// Plugins are the names of the plugins to install; if empty, all files
+	// named docker-* in SourceDir are installed.
+	Plugins []string
+	// Wrappers are executables (such as the nerdctl wrapper) to install into
+	// WrapperDir, which must be set if there are any wrappers.
+	Wrappers   []string
+	WrapperDir string
+}
+
+// pluginManifestEntry records a file we installed.
+type pluginManifestEntry struct {
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256"`
+	Version string `json:"version,omitempty"`
+}
+
+// pluginManifest records the files we installed, by name.
+type pluginManifest map[string]pluginManifestEntry
+
+// pluginMetadata is the output of `docker-foo docker-cli-plugin-metadata`.
+type pluginMetadata struct {
+	SchemaVersion string
+	Vendor        string
+	Version       string
+}
+
+// installItem is a single file to be installed.
+type installItem struct {
+	name   string
+	source string
+	dest   string
+	plugin bool
+}
+
+// pluginVersion runs the docker CLI plugin at the given path to determine its
+// version; this also checks that the plugin can be executed.
+func pluginVersion(ctx context.Context, pluginPath string) (*semver.Version, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginMetadataTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, pluginPath, "docker-cli-plugin-metadata").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata for %s: %w", pluginPath, err)
+	}
+	var metadata pluginMetadata
+	if err := json.Unmarshal(output, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata for %s: %w", pluginPath, err)
+	}
+	version, err := semver.NewVersion(strings.TrimPrefix(metadata.Version, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s has invalid version %q: %w", pluginPath, metadata.Version, err)
+	}
+	return version, nil
+}
+
+// fileHash returns the hex-encoded SHA256 hash of the given file.
+func fileHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyExecutable copies the source file to the destination, which must not
+// exist.
+func copyExecutable(source, dest string) error {
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	output, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(output, input); err != nil {
+		output.Close()
+		return err
+	}
+	return output.Close()
+}
+
+func readPluginManifest(pluginDir string) (pluginManifest, error) {
+	manifest := make(pluginManifest)
+	buf, err := os.ReadFile(filepath.Join(pluginDir, pluginManifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+	if err := json.Unmarshal(buf, &manifest); err != nil {
+		// A corrupt manifest means we don't know what we installed; treat
+		// everything as managed by the user.
+		logrus.WithError(err).Warn("Ignoring invalid docker CLI plugin manifest")
+		return make(pluginManifest), nil
+	}
+	return manifest, nil
+}
+
+func writePluginManifest(pluginDir string, manifest pluginManifest) error {
+	manifestPath := filepath.Join(pluginDir, pluginManifestName)
+	if len(manifest) == 0 {
+		if err := os.Remove(manifestPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove plugin manifest: %w", err)
+		}
+		return nil
+	}
+	buf, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize plugin manifest: %w", err)
+	}
+	if err := os.WriteFile(manifestPath, buf, 0o644); err != nil {
+		return fmt.Errorf("failed to write plugin manifest: %w", err)
+	}
+	return nil
+}
+
+// managed returns whether the file at the given path is one we installed,
+// and has not been modified since.
+func (m pluginManifest) managed(name, filePath string) bool {
+	entry, ok := m[name]
+	if !ok || entry.Path != filePath {
+		return false
+	}
+	hash, err := fileHash(filePath)
+	return err == nil && hash == entry.SHA256
+}
+
+func (o *PluginInstallOptions) items() ([]installItem, error) {
+	names := o.Plugins
+	if len(names) == 0 {
+		entries, err := os.ReadDir(o.SourceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list docker CLI plugins: %w", err)
+		}
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), "docker-") && !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	var items []installItem
+	for _, name := range names {
+		items = append(items, installItem{
+			name:   name,
+			source: filepath.Join(o.SourceDir, name),
+			dest:   filepath.Join(o.PluginDir, name),
+			plugin: true,
+		})
+	}
+	if len(o.Wrappers) > 0 && o.WrapperDir == "" {
+		return nil, errors.New("no directory given to install wrappers into")
+	}
+	for _, wrapper := range o.Wrappers {
+		items = append(items, installItem{
+			name:   filepath.Base(wrapper),
+			source: wrapper,
+			dest:   filepath.Join(o.WrapperDir, filepath.Base(wrapper)),
+		})
+	}
+	return items, nil
+}
+
+// InstallDockerPlugins copies the docker CLI plugins and wrappers shipped with
+// Rancher Desktop into the distribution, so that they work without relying on
+// the Windows file system.  Files that were not installed by us (or have been
+// modified since) are left alone, as are plugins whose installed version is
+// at least as new as ours.  Each copied plugin is checked by running it; if
+// any file fails to install, all changes are rolled back.
+func InstallDockerPlugins(ctx context.Context, options PluginInstallOptions) ([]InstallResult, error) {
+	items, err := options.items()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(options.PluginDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create docker CLI plugin directory: %w", err)
+	}
+	manifest, err := readPluginManifest(options.PluginDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// rollbacks undo the changes made so far, in reverse order.
+	var rollbacks []func()
+	rollback := func() {
+		for i := len(rollbacks) - 1; i >= 0; i-- {
+			rollbacks[i]()
+		}
+	}
+	var results []InstallResult
+	for _, item := range items {
+		result, undo, err := installFile(ctx, item, manifest)
+		if err != nil {
+			rollback()
+			return nil, err
+		}
+		if undo != nil {
+			rollbacks = append(rollbacks, undo)
+		}
+		results = append(results, result)
+	}
+	if err := writePluginManifest(options.PluginDir, manifest); err != nil {
+		rollback()
+		return nil, err
+	}
+	for _, item := range items {
+		_, backupPath := installTempPaths(item.dest)
+		_ = os.Remove(backupPath)
+	}
+	return results, nil
+}
+
+// installTempPaths returns the paths used to stage the new copy of the file,
+// and to keep a backup of the old copy.  These are hidden files, so that the
+// docker CLI does not try to load them as plugins.
+func installTempPaths(dest string) (string, string) {
+	dir, name := filepath.Split(dest)
+	return filepath.Join(dir, "."+name+".rd-new"), filepath.Join(dir, "."+name+".rd-backup")
+}
+
+// installFile installs a single file, returning a function to undo the change
+// (nil if nothing was changed).  The manifest is updated to match.
+func installFile(ctx context.Context, item installItem, manifest pluginManifest) (InstallResult, func(), error) {
+	result := InstallResult{Name: item.name, Path: item.dest}
+	log := logrus.WithField("name", item.name)
+
+	var sourceVersion *semver.Version
+	if item.plugin {
+		var err error
+		if sourceVersion, err = pluginVersion(ctx, item.source); err != nil {
+			return result, nil, err
+		}
+		result.Version = sourceVersion.String()
+	}
+	sourceHash, err := fileHash(item.source)
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to read %s: %w", item.source, err)
+	}
+
+	exists := true
+	if _, err := os.Lstat(item.dest); errors.Is(err, os.ErrNotExist) {
+		exists = false
+	} else if err != nil {
+		return result, nil, fmt.Errorf("failed to check %s: %w", item.dest, err)
+	}
+	if exists {
+		if item.plugin {
+			if version, err := pluginVersion(ctx, item.dest); err == nil {
+				result.Previous = version.String()
+			}
+		}
+		if !manifest.managed(item.name, item.dest) {
+			// The user installed their own copy; leave it alone, and forget
+			// about any copy we had installed.
+			log.Debugf("Not replacing %s, which is not managed by Rancher Desktop", item.dest)
+			delete(manifest, item.name)
+			result.Action = InstallActionSkipped
+			return result, nil, nil
+		}
+		if manifest[item.name].SHA256 == sourceHash {
+			result.Action = InstallActionUnchanged
+			return result, nil, nil
+		}
+		if item.plugin && result.Previous != "" {
+			if previous, err := semver.NewVersion(result.Previous); err == nil && previous.GreaterThan(sourceVersion) {
+				// We installed a newer version (e.g. from a newer Rancher
+				// Desktop); don't downgrade.
+				result.Action = InstallActionUnchanged
+				return result, nil, nil
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item.dest), 0o755); err != nil {
+		return result, nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(item.dest), err)
+	}
+	tempPath, backupPath := installTempPaths(item.dest)
+	_ = os.Remove(tempPath)
+	if err := copyExecutable(item.source, tempPath); err != nil {
+		_ = os.Remove(tempPath)
+		return result, nil, fmt.Errorf("failed to copy %s: %w", item.name, err)
+	}
+	if item.plugin {
+		version, err := pluginVersion(ctx, tempPath)
+		if err == nil && !version.Equal(sourceVersion) {
+			err = fmt.Errorf("copied plugin %s reports version %s, expected %s", item.name, version, sourceVersion)
+		}
+		if err != nil {
+			_ = os.Remove(tempPath)
+			return result, nil, err
+		}
+	}
+	if exists {
+		_ = os.Remove(backupPath)
+		if err := os.Rename(item.dest, backupPath); err != nil {
+			_ = os.Remove(tempPath)
+			return result, nil, fmt.Errorf("failed to back up %s: %w", item.dest, err)
+		}
+	}
+	if err := os.Rename(tempPath, item.dest); err != nil {
+		_ = os.Remove(tempPath)
+		if exists {
+			_ = os.Rename(backupPath, item.dest)
+		}
+		return result, nil, fmt.Errorf("failed to install %s: %w", item.dest, err)
+	}
+
+	previousEntry, hadEntry := manifest[item.name]
+	manifest[item.name] = pluginManifestEntry{Path: item.dest, SHA256: sourceHash, Version: result.Version}
+	undo := func() {
+		log.Debugf("Rolling back %s", item.dest)
+		if exists {
+			_ = os.Rename(backupPath, item.dest)
+		} else {
+			_ = os.Remove(item.dest)
+		}
+		if hadEntry {
+			manifest[item.name] = previousEntry
+		} else {
+			delete(manifest, item.name)
+		}
+	}
+	if exists {
+		result.Action = InstallActionUpdated
+	} else {
+		result.Action = InstallActionInstalled
+	}
+	return result, undo, nil
+}
+
+// UninstallDockerPlugins removes the files installed by InstallDockerPlugins,
+// except for any that have since been modified.
+func UninstallDockerPlugins(pluginDir string) ([]InstallResult, error) {
+	manifest, err := readPluginManifest(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(manifest))
+	for name := range manifest {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var results []InstallResult
+	for _, name := range names {
+		entry := manifest[name]
+		if manifest.managed(name, entry.Path) {
+			if err := os.Remove(entry.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return results, fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+			}
+			results = append(results, InstallResult{Name: name, Path: entry.Path, Action: InstallActionRemoved, Version: entry.Version})
+		} else {
+			results = append(results, InstallResult{Name: name, Path: entry.Path, Action: InstallActionSkipped})
+		}
+		delete(manifest, name)
+	}
+	return results, writePluginManifest(pluginDir, manifest)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+// writeFakePlugin writes a docker CLI plugin that reports the given version.
+func writeFakePlugin(t *testing.T, pluginPath, version string) {
+	script := fmt.Sprintf("#!/bin/sh\necho '{\"SchemaVersion\":\"0.1.0\",\"Vendor\":\"test\",\"Version\":\"v%s\"}'\n", version)
+	require.NoError(t, os.WriteFile(pluginPath, []byte(script), 0o755))
+}
+
+func TestInstallDockerPlugins(t *testing.T) {
+	sourceDir := t.TempDir()
+	pluginDir := filepath.Join(t.TempDir(), "cli-plugins")
+	wrapperDir := t.TempDir()
+	wrapper := filepath.Join(t.TempDir(), "nerdctl")
+	require.NoError(t, os.WriteFile(wrapper, []byte("#!/bin/sh\n"), 0o755))
+	writeFakePlugin(t, filepath.Join(sourceDir, "docker-buildx"), "0.10.0")
+	writeFakePlugin(t, filepath.Join(sourceDir, "docker-compose"), "2.20.0")
+	options := integration.PluginInstallOptions{
+		SourceDir:  sourceDir,
+		PluginDir:  pluginDir,
+		Wrappers:   []string{wrapper},
+		WrapperDir: wrapperDir,
+	}
+	actions := func(results []integration.InstallResult) map[string]integration.InstallAction {
+		result := make(map[string]integration.InstallAction)
+		for _, item := range results {
+			result[item.Name] = item.Action
+		}
+		return result
+	}
+	ctx := context.Background()
+
+	results, err := integration.InstallDockerPlugins(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]integration.InstallAction{
+		"docker-buildx":  integration.InstallActionInstalled,
+		"docker-compose": integration.InstallActionInstalled,
+		"nerdctl":        integration.InstallActionInstalled,
+	}, actions(results))
+	assert.FileExists(t, filepath.Join(wrapperDir, "nerdctl"))
+	entries, err := os.ReadDir(pluginDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3, "plugin directory should only have plugins and the manifest")
+
+	// Newer versions are updated, and user-managed plugins are not touched.
+	writeFakePlugin(t, filepath.Join(sourceDir, "docker-compose"), "2.21.0")
+	writeFakePlugin(t, filepath.Join(pluginDir, "docker-buildx"), "0.11.0")
+	results, err = integration.InstallDockerPlugins(ctx, options)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]integration.InstallAction{
+		"docker-buildx":  integration.InstallActionSkipped,
+		"docker-compose": integration.InstallActionUpdated,
+		"nerdctl":        integration.InstallActionUnchanged,
+	}, actions(results))
+	for _, result := range results {
+		if result.Name == "docker-compose" {
+			assert.Equal(t, "2.20.0", result.Previous)
+			assert.Equal(t, "2.21.0", result.Version)
+		}
+	}
+
+	// A broken plugin causes everything to be rolled back.
+	writeFakePlugin(t, filepath.Join(sourceDir, "docker-compose"), "2.22.0")
+	require.NoError(t, os.WriteFile(filepath.Join(sourceDir, "docker-zz"), []byte("#!/bin/sh\nexit 1\n"), 0o755))
+	_, err = integration.InstallDockerPlugins(ctx, options)
+	assert.Error(t, err)
+	output, err := os.ReadFile(filepath.Join(pluginDir, "docker-compose"))
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "v2.21.0")
+	require.NoError(t, os.Remove(filepath.Join(sourceDir, "docker-zz")))
+
+	results, err = integration.UninstallDockerPlugins(pluginDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]integration.InstallAction{
+		"docker-compose": integration.InstallActionRemoved,
+		"nerdctl":        integration.InstallActionRemoved,
+	}, actions(results))
+	assert.NoFileExists(t, filepath.Join(pluginDir, "docker-compose"))
+	assert.NoFileExists(t, filepath.Join(wrapperDir, "nerdctl"))
+	assert.FileExists(t, filepath.Join(pluginDir, "docker-buildx"))
+}
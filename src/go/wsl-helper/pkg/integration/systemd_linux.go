@@ -0,0 +1,287 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// DefaultWSLConfPath is the per-distribution WSL configuration file.
+	DefaultWSLConfPath = "/etc/wsl.conf"
+	// systemdUnitName is the base name of the integration systemd units.
+	systemdUnitName = "rancher-desktop-docker-proxy"
+)
+
+// systemdSocketName is the name of the docker socket in the user's runtime
+// directory; a user systemd instance can't listen on DefaultDockerSocket.
+const systemdSocketName = "docker.sock"
+
+// systemdSocketTemplate is the socket unit that listens for docker clients.
+var systemdSocketTemplate = template.Must(template.New("socket").Parse(`# Managed by Rancher Desktop; do not edit.
+[Unit]
+Description=Rancher Desktop docker socket
+
+[Socket]
+ListenStream={{ .Socket }}
+SocketMode=0600
+RemoveOnStop=true
+
+[Install]
+WantedBy=sockets.target
+`))
+
+// systemdServiceTemplate is the service unit that runs the docker socket proxy
+// when the socket is first connected to.
+var systemdServiceTemplate = template.Must(template.New("service").Parse(`# Managed by Rancher Desktop; do not edit.
+[Unit]
+Description=Rancher Desktop docker socket proxy
+Requires={{ .Name }}.socket
+After={{ .Name }}.socket
+
+[Service]
+ExecStart={{ .ExecStart }}
+Restart=on-failure
+`))
+
+// SystemdOptions describes how the integration systemd user units are
+// installed.
+type SystemdOptions struct {
+	// UnitDir is the directory to install units into; defaults to
+	// SystemdUserUnitDir().
+	UnitDir string
+	// Executable is the (Linux) wsl-helper executable to run.
+	Executable string
+	// Args are extra arguments to `wsl-helper docker-proxy serve`.
+	Args []string
+	// Socket is the path of the docker socket; defaults to SystemdSocket().
+	Socket string
+	// Systemctl runs `systemctl --user` with the given arguments; this is only
+	// called if the user systemd instance is running.  Defaults to running
+	// systemctl.
+	Systemctl func(ctx context.Context, args ...string) error
+	// RuntimeDir is the user's runtime directory, used to detect if the user
+	// systemd instance is running; defaults to UserRuntimeDir().
+	RuntimeDir string
+}
+
+func (o *SystemdOptions) setDefaults() error {
+	if o.UnitDir == "" {
+		unitDir, err := SystemdUserUnitDir()
+		if err != nil {
+			return err
+		}
+		o.UnitDir = unitDir
+	}
+	if o.RuntimeDir == "" {
+		o.RuntimeDir = UserRuntimeDir()
+	}
+	if o.Socket == "" {
+		o.Socket = filepath.Join(o.RuntimeDir, systemdSocketName)
+	}
+	if o.Systemctl == nil {
+		runtimeDir := o.RuntimeDir
+		o.Systemctl = func(ctx context.Context, args ...string) error {
+			cmd := exec.CommandContext(ctx, "systemctl", append([]string{"--user"}, args...)...)
+			// wsl.exe does not always set up the session environment, which
+			// systemctl needs to find the user instance.
+			cmd.Env = append(os.Environ(), "XDG_RUNTIME_DIR="+runtimeDir)
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+			return cmd.Run()
+		}
+	}
+	return nil
+}
+
+// SystemdUserUnitDir returns the directory user systemd units are installed
+// in; this is usually ~/.config/systemd/user.
+func SystemdUserUnitDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate systemd user unit directory: %w", err)
+	}
+	return filepath.Join(configDir, "systemd", "user"), nil
+}
+
+// UserRuntimeDir returns the runtime directory of the current user; this is
+// $XDG_RUNTIME_DIR if set, or /run/user/<uid> otherwise.
+func UserRuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("/run/user", strconv.Itoa(os.Getuid()))
+}
+
+// SystemdSocket returns the default path of the docker socket provided by the
+// integration systemd units.
+func SystemdSocket() string {
+	return filepath.Join(UserRuntimeDir(), systemdSocketName)
+}
+
+// SystemdEnabled reports whether systemd is enabled in the given WSL
+// configuration file (usually DefaultWSLConfPath); that is, if it has
+// `systemd=true` in the `[boot]` section.  A missing file means systemd is
+// not enabled.
+func SystemdEnabled(wslConfPath string) (bool, error) {
+	conf, err := ReadWSLConf(wslConfPath)
+	if err != nil {
+		return false, err
+	}
+	value, _ := conf.Get("boot", "systemd")
+	return strings.EqualFold(value, "true"), nil
+}
+
+// systemdRunning reports whether the user systemd instance is running, given
+// the user's runtime directory.
+func systemdRunning(runtimeDir string) bool {
+	info, err := os.Stat(filepath.Join(runtimeDir, "systemd"))
+	return err == nil && info.IsDir()
+}
+
+// systemdUnitPaths returns the paths of the socket unit, the service unit, and
+// the link enabling the socket unit.
+func systemdUnitPaths(unitDir string) (string, string, string) {
+	return filepath.Join(unitDir, systemdUnitName+".socket"),
+		filepath.Join(unitDir, systemdUnitName+".service"),
+		filepath.Join(unitDir, "sockets.target.wants", systemdUnitName+".socket")
+}
+
+// InstallSystemdUnits installs and enables a systemd user socket unit for the
+// docker socket, along with the service unit running the docker socket proxy
+// when the socket is used.  If the user systemd instance is running, the
+// socket is started immediately; otherwise it will be started the next time
+// the user logs in to the distribution with systemd enabled.
+func InstallSystemdUnits(ctx context.Context, options SystemdOptions) error {
+	if err := options.setDefaults(); err != nil {
+		return err
+	}
+	if options.Executable == "" {
+		return errors.New("no executable given for systemd units")
+	}
+	socketPath, servicePath, linkPath := systemdUnitPaths(options.UnitDir)
+
+	execStart := []string{options.Executable, "docker-proxy", "serve"}
+	execStart = append(execStart, options.Args...)
+	for i, arg := range execStart {
+		if strings.ContainsAny(arg, " \t\"'\\%$") {
+			arg = strings.ReplaceAll(arg, `\`, `\\`)
+			arg = strings.ReplaceAll(arg, `"`, `\"`)
+			arg = strings.ReplaceAll(arg, "%", "%%")
+			arg = strings.ReplaceAll(arg, "$", "$$")
+			execStart[i] = `"` + arg + `"`
+		}
+	}
+	data := map[string]string{
+		"Name":      systemdUnitName,
+		"Socket":    options.Socket,
+		"ExecStart": strings.Join(execStart, " "),
+	}
+	if err := os.MkdirAll(options.UnitDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", options.UnitDir, err)
+	}
+	for path, tmpl := range map[string]*template.Template{
+		socketPath:  systemdSocketTemplate,
+		servicePath: systemdServiceTemplate,
+	} {
+		var builder strings.Builder
+		if err := tmpl.Execute(&builder, data); err != nil {
+			return fmt.Errorf("failed to generate %s: %w", filepath.Base(path), err)
+		}
+		if err := os.WriteFile(path, []byte(builder.String()), integrationFilePermission); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	// Enable the socket by hand, so that it works even if the user systemd
+	// instance is not yet running (because the distribution has not been
+	// restarted since systemd was enabled).
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(linkPath), err)
+	}
+	if err := os.Remove(linkPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to replace %s: %w", linkPath, err)
+	}
+	if err := os.Symlink(filepath.Join("..", filepath.Base(socketPath)), linkPath); err != nil {
+		return fmt.Errorf("failed to enable %s: %w", filepath.Base(socketPath), err)
+	}
+
+	if !systemdRunning(options.RuntimeDir) {
+		return nil
+	}
+	if err := options.Systemctl(ctx, "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	if err := options.Systemctl(ctx, "restart", filepath.Base(socketPath)); err != nil {
+		return fmt.Errorf("failed to start %s: %w", filepath.Base(socketPath), err)
+	}
+	return nil
+}
+
+// SystemdUnitsInstalled reports whether the units installed by
+// InstallSystemdUnits exist.
+func SystemdUnitsInstalled(options SystemdOptions) (bool, error) {
+	if err := options.setDefaults(); err != nil {
+		return false, err
+	}
+	socketPath, _, _ := systemdUnitPaths(options.UnitDir)
+	if _, err := os.Stat(socketPath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to check for %s: %w", socketPath, err)
+	}
+	return true, nil
+}
+
+// RemoveSystemdUnits stops and removes the units installed by
+// InstallSystemdUnits.  It is not an error if they are not installed.
+func RemoveSystemdUnits(ctx context.Context, options SystemdOptions) error {
+	if installed, err := SystemdUnitsInstalled(options); err != nil || !installed {
+		return err
+	}
+	if err := options.setDefaults(); err != nil {
+		return err
+	}
+	socketPath, servicePath, linkPath := systemdUnitPaths(options.UnitDir)
+
+	running := systemdRunning(options.RuntimeDir)
+	if running {
+		err := options.Systemctl(ctx, "stop", filepath.Base(socketPath), filepath.Base(servicePath))
+		if err != nil {
+			return fmt.Errorf("failed to stop systemd units: %w", err)
+		}
+	}
+	for _, path := range []string{linkPath, socketPath, servicePath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	if running {
+		if err := options.Systemctl(ctx, "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+func TestSystemdEnabled(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]bool{
+		"":                                     false,
+		"[boot]\nsystemd=true\n":               true,
+		"[Boot]\n  systemd = True # comment\n": true,
+		"[boot]\nsystemd=false\n":              false,
+		"[network]\nsystemd=true\n":            false,
+		"# [boot]\n# systemd=true\n":           false,
+		"[boot]\nsystemd=true\n[boot]\nsystemd=false\n": false,
+	}
+	for contents, expected := range cases {
+		path := filepath.Join(dir, "wsl.conf")
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+		enabled, err := integration.SystemdEnabled(path)
+		if assert.NoError(t, err, "%q", contents) {
+			assert.Equal(t, expected, enabled, "%q", contents)
+		}
+	}
+	enabled, err := integration.SystemdEnabled(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestSystemdUnits(t *testing.T) {
+	unitDir := filepath.Join(t.TempDir(), "systemd", "user")
+	runtimeDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(runtimeDir, "systemd"), 0o755))
+	var calls []string
+	options := integration.SystemdOptions{
+		UnitDir:    unitDir,
+		Executable: "/mnt/c/Program Files/Rancher Desktop/wsl-helper",
+		Args:       []string{"--verbose"},
+		RuntimeDir: runtimeDir,
+		Systemctl: func(ctx context.Context, args ...string) error {
+			calls = append(calls, strings.Join(args, " "))
+			return nil
+		},
+	}
+	const name = "rancher-desktop-docker-proxy"
+
+	require.NoError(t, integration.InstallSystemdUnits(context.Background(), options))
+	socket, err := os.ReadFile(filepath.Join(unitDir, name+".socket"))
+	require.NoError(t, err)
+	assert.Contains(t, string(socket), "ListenStream="+filepath.Join(runtimeDir, "docker.sock")+"\n")
+	service, err := os.ReadFile(filepath.Join(unitDir, name+".service"))
+	require.NoError(t, err)
+	assert.Contains(t, string(service), `ExecStart="/mnt/c/Program Files/Rancher Desktop/wsl-helper" docker-proxy serve --verbose`+"\n")
+	target, err := os.Readlink(filepath.Join(unitDir, "sockets.target.wants", name+".socket"))
+	require.NoError(t, err)
+	assert.Equal(t, "../"+name+".socket", target)
+	assert.Equal(t, []string{"daemon-reload", "restart " + name + ".socket"}, calls)
+
+	// Installing again should succeed.
+	require.NoError(t, integration.InstallSystemdUnits(context.Background(), options))
+
+	installed, err := integration.SystemdUnitsInstalled(options)
+	require.NoError(t, err)
+	assert.True(t, installed)
+
+	calls = nil
+	require.NoError(t, integration.RemoveSystemdUnits(context.Background(), options))
+	installed, err = integration.SystemdUnitsInstalled(options)
+	require.NoError(t, err)
+	assert.False(t, installed)
+	entries, err := os.ReadDir(unitDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.True(t, entry.IsDir(), "unexpected file %s", entry.Name())
+	}
+	assert.NoFileExists(t, filepath.Join(unitDir, "sockets.target.wants", name+".socket"))
+	assert.Equal(t, []string{"stop " + name + ".socket " + name + ".service", "daemon-reload"}, calls)
+
+	// Without the user systemd instance running, systemctl is not called.
+	calls = nil
+	options.RuntimeDir = t.TempDir()
+	require.NoError(t, integration.InstallSystemdUnits(context.Background(), options))
+	require.NoError(t, integration.RemoveSystemdUnits(context.Background(), options))
+	require.NoError(t, integration.RemoveSystemdUnits(context.Background(), options))
+	assert.Empty(t, calls)
+}
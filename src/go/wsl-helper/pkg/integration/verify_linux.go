@@ -0,0 +1,323 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultDockerSocket is where the docker socket is exposed in integrated
+	// distributions.
+	DefaultDockerSocket = "/var/run/docker.sock"
+	// dockerPingTimeout is how long to wait for the docker socket to respond.
+	dockerPingTimeout = 5 * time.Second
+	// kubeContext is the name of the Rancher Desktop kubeconfig context.
+	kubeContext = "rancher-desktop"
+)
+
+// CheckStatus is the outcome of a single integration check.
+type CheckStatus string
+
+const (
+	CheckPassed  CheckStatus = "passed"
+	CheckWarning CheckStatus = "warning"
+	CheckFailed  CheckStatus = "failed"
+)
+
+// CheckResult is the result of a single integration check.
+type CheckResult struct {
+	Name        string      `json:"name"`                  // The name of the check.
+	Status      CheckStatus `json:"status"`                // The outcome of the check.
+	Message     string      `json:"message"`               // What was found.
+	Remediation string      `json:"remediation,omitempty"` // How to fix any problems.
+}
+
+// VerifyReport is the result of verifying integration in a distribution.
+type VerifyReport struct {
+	Distro  string        `json:"distro"`  // The name of the distribution.
+	Healthy bool          `json:"healthy"` // Whether no checks failed.
+	Checks  []CheckResult `json:"checks"`  // The individual check results.
+}
+
+// VerifyOptions control what Verify checks.
+type VerifyOptions struct {
+	// Distro is the name of the distribution, for the report.
+	Distro string
+	// HomeDir is the home directory of the user.
+	HomeDir string
+	// DockerSocket is the path to the docker socket; if empty,
+	// DefaultDockerSocket is used.
+	DockerSocket string
+	// PluginDir is the Rancher Desktop docker CLI plugin directory; if empty,
+	// the docker CLI configuration is not checked.
+	PluginDir string
+	// MarkerPath overrides the integration marker to check, for testing.
+	MarkerPath string
+	// LookPath overrides exec.LookPath, for testing.
+	LookPath func(string) (string, error)
+}
+
+// Verify checks that Rancher Desktop integration in the current distribution
+// is working, returning a report describing any problems found.
+func Verify(ctx context.Context, options VerifyOptions) VerifyReport {
+	if options.DockerSocket == "" {
+		options.DockerSocket = DefaultDockerSocket
+	}
+	if options.MarkerPath == "" {
+		options.MarkerPath = markerPath
+	}
+	if options.LookPath == nil {
+		options.LookPath = exec.LookPath
+	}
+	report := VerifyReport{
+		Distro: options.Distro,
+		Checks: []CheckResult{
+			checkMarker(options.MarkerPath),
+			checkDockerSocket(ctx, options.DockerSocket),
+			checkExecutables(options.LookPath),
+			checkPluginConfig(options.HomeDir, options.PluginDir),
+			checkPluginSymlinks(options.HomeDir),
+			checkKubeconfig(options.HomeDir),
+		},
+	}
+	report.Healthy = !slices.ContainsFunc(report.Checks, func(check CheckResult) bool {
+		return check.Status == CheckFailed
+	})
+	return report
+}
+
+// checkMarker checks that the distribution is marked as integrated.
+func checkMarker(path string) CheckResult {
+	result := CheckResult{Name: "marker"}
+	if _, err := os.Stat(path); err == nil {
+		result.Status = CheckPassed
+		result.Message = "distribution is marked as integrated"
+	} else if errors.Is(err, fs.ErrNotExist) {
+		result.Status = CheckFailed
+		result.Message = "distribution is not marked as integrated"
+		result.Remediation = "enable WSL integration for this distribution in Rancher Desktop preferences"
+	} else {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("could not check integration marker: %s", err)
+	}
+	return result
+}
+
+// checkDockerSocket checks that the docker daemon responds on the socket.
+func checkDockerSocket(ctx context.Context, socketPath string) CheckResult {
+	result := CheckResult{Name: "docker-socket"}
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("docker socket is missing: %s", err)
+		result.Remediation = "ensure Rancher Desktop is running with the moby container engine, then toggle WSL integration for this distribution"
+		return result
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("%s is not a socket", socketPath)
+		result.Remediation = fmt.Sprintf("remove %s and toggle WSL integration for this distribution", socketPath)
+		return result
+	}
+	client := &http.Client{
+		Timeout: dockerPingTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/_ping", http.NoBody)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = err.Error()
+		return result
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("docker socket is not responding: %s", err)
+		result.Remediation = "restart Rancher Desktop; if that does not help, run `wsl --shutdown` and start it again"
+		return result
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("docker daemon responded with %s", resp.Status)
+		result.Remediation = "check the docker daemon logs in Rancher Desktop"
+		return result
+	}
+	result.Status = CheckPassed
+	result.Message = fmt.Sprintf("docker daemon is reachable at %s", socketPath)
+	return result
+}
+
+// checkExecutables checks that the docker CLI and the nerdctl stub are on the
+// PATH.
+func checkExecutables(lookPath func(string) (string, error)) CheckResult {
+	result := CheckResult{Name: "path"}
+	var missing []string
+	for _, name := range []string{"docker", "nerdctl"} {
+		if _, err := lookPath(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	switch len(missing) {
+	case 0:
+		result.Status = CheckPassed
+		result.Message = "docker and nerdctl are on the PATH"
+	default:
+		result.Status = CheckWarning
+		result.Message = fmt.Sprintf("not found on the PATH: %v", missing)
+		result.Remediation = "install the docker CLI in the distribution, or add the Rancher Desktop resources/linux/bin directory to the PATH"
+	}
+	return result
+}
+
+// checkPluginConfig checks that the docker CLI is configured to load the
+// Rancher Desktop plugins.
+func checkPluginConfig(homeDir, pluginDir string) CheckResult {
+	result := CheckResult{Name: "docker-cli-plugins"}
+	if pluginDir == "" {
+		result.Status = CheckPassed
+		result.Message = "plugin directory not given; skipped"
+		return result
+	}
+	configPath := filepath.Join(homeDir, ".docker", "config.json")
+	remediation := "toggle WSL integration for this distribution to rewrite the docker CLI configuration"
+	configBytes, err := os.ReadFile(configPath)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("could not read docker CLI configuration: %s", err)
+		result.Remediation = remediation
+		return result
+	}
+	var config struct {
+		Dirs []string `json:"cliPluginsExtraDirs"`
+	}
+	if err := json.Unmarshal(configBytes, &config); err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("could not parse docker CLI configuration: %s", err)
+		result.Remediation = fmt.Sprintf("fix or remove %s, then toggle WSL integration for this distribution", configPath)
+		return result
+	}
+	if !slices.Contains(config.Dirs, pluginDir) {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("docker CLI configuration does not include %s", pluginDir)
+		result.Remediation = remediation
+		return result
+	}
+	if _, err := os.Stat(pluginDir); err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("plugin directory is not accessible: %s", err)
+		result.Remediation = "check that Windows drives are mounted in the distribution (see automount in /etc/wsl.conf)"
+		return result
+	}
+	result.Status = CheckPassed
+	result.Message = fmt.Sprintf("docker CLI loads plugins from %s", pluginDir)
+	return result
+}
+
+// checkPluginSymlinks checks for dangling symlinks in the docker CLI plugin
+// directory (for example, left over from older versions).
+func checkPluginSymlinks(homeDir string) CheckResult {
+	result := CheckResult{Name: "symlinks"}
+	pluginDir := filepath.Join(homeDir, ".docker", "cli-plugins")
+	entries, err := os.ReadDir(pluginDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		result.Status = CheckWarning
+		result.Message = fmt.Sprintf("could not read %s: %s", pluginDir, err)
+		return result
+	}
+	var dangling []string
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(pluginDir, entry.Name())); err != nil {
+			dangling = append(dangling, entry.Name())
+		}
+	}
+	if len(dangling) > 0 {
+		result.Status = CheckWarning
+		result.Message = fmt.Sprintf("broken docker CLI plugin symlinks in %s: %v", pluginDir, dangling)
+		result.Remediation = "remove the broken symlinks"
+		return result
+	}
+	result.Status = CheckPassed
+	result.Message = "no broken docker CLI plugin symlinks"
+	return result
+}
+
+// checkKubeconfig checks that the kubeconfig is readable and has the Rancher
+// Desktop context.
+func checkKubeconfig(homeDir string) CheckResult {
+	result := CheckResult{Name: "kubeconfig"}
+	configPath := filepath.Join(homeDir, ".kube", "config")
+	remediation := "toggle WSL integration for this distribution to recreate the kubeconfig"
+	file, err := os.Open(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		result.Status = CheckWarning
+		result.Message = fmt.Sprintf("%s does not exist (or is a broken link)", configPath)
+		result.Remediation = "enable Kubernetes in Rancher Desktop, then " + remediation
+		return result
+	} else if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("could not open kubeconfig: %s", err)
+		result.Remediation = remediation
+		return result
+	}
+	defer file.Close()
+	var config struct {
+		Contexts []struct {
+			Name string `yaml:"name"`
+		} `yaml:"contexts"`
+	}
+	hasContext := false
+	if err := yaml.NewDecoder(file).Decode(&config); err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("could not parse kubeconfig: %s", err)
+		result.Remediation = fmt.Sprintf("fix or remove %s, then %s", configPath, remediation)
+		return result
+	}
+	for _, context := range config.Contexts {
+		hasContext = hasContext || context.Name == kubeContext
+	}
+	if !hasContext {
+		result.Status = CheckWarning
+		result.Message = fmt.Sprintf("kubeconfig does not have a %s context", kubeContext)
+		result.Remediation = remediation
+		return result
+	}
+	result.Status = CheckPassed
+	result.Message = fmt.Sprintf("kubeconfig has a %s context", kubeContext)
+	return result
+}
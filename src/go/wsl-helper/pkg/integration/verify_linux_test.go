@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+func TestVerify(t *testing.T) {
+	homeDir := t.TempDir()
+	pluginDir := t.TempDir()
+	markerPath := filepath.Join(t.TempDir(), "marker")
+	socketPath := filepath.Join(t.TempDir(), "docker.sock")
+	options := integration.VerifyOptions{
+		Distro:       "test",
+		HomeDir:      homeDir,
+		DockerSocket: socketPath,
+		PluginDir:    pluginDir,
+		MarkerPath:   markerPath,
+		LookPath: func(name string) (string, error) {
+			if name == "nerdctl" {
+				return "", errors.New("not found")
+			}
+			return "/usr/bin/" + name, nil
+		},
+	}
+	statuses := func(report integration.VerifyReport) map[string]integration.CheckStatus {
+		result := make(map[string]integration.CheckStatus)
+		for _, check := range report.Checks {
+			result[check.Name] = check.Status
+			if check.Status != integration.CheckPassed {
+				assert.NotEmpty(t, check.Message, check.Name)
+			}
+		}
+		return result
+	}
+
+	t.Run("not integrated", func(t *testing.T) {
+		report := integration.Verify(context.Background(), options)
+		assert.Equal(t, "test", report.Distro)
+		assert.False(t, report.Healthy)
+		assert.Equal(t, map[string]integration.CheckStatus{
+			"marker":             integration.CheckFailed,
+			"docker-socket":      integration.CheckFailed,
+			"path":               integration.CheckWarning,
+			"docker-cli-plugins": integration.CheckFailed,
+			"symlinks":           integration.CheckPassed,
+			"kubeconfig":         integration.CheckWarning,
+		}, statuses(report))
+	})
+
+	t.Run("integrated", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(markerPath, nil, 0o644))
+		require.NoError(t, integration.SetupPluginDirConfig(homeDir, pluginDir, true))
+		require.NoError(t, os.MkdirAll(filepath.Join(homeDir, ".kube"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(homeDir, ".kube", "config"),
+			[]byte("contexts:\n- name: rancher-desktop\n"), 0o600))
+		listener, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+		server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/_ping", r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		})}
+		go func() { _ = server.Serve(listener) }()
+		t.Cleanup(func() { server.Close() })
+
+		report := integration.Verify(context.Background(), options)
+		assert.True(t, report.Healthy)
+		assert.Equal(t, map[string]integration.CheckStatus{
+			"marker":             integration.CheckPassed,
+			"docker-socket":      integration.CheckPassed,
+			"path":               integration.CheckWarning,
+			"docker-cli-plugins": integration.CheckPassed,
+			"symlinks":           integration.CheckPassed,
+			"kubeconfig":         integration.CheckPassed,
+		}, statuses(report))
+	})
+}
@@ -0,0 +1,322 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/atomicfile"
+)
+
+// wslConfNoOriginal is the contents of the backup of a WSL configuration file
+// that did not exist before it was edited.
+const wslConfNoOriginal = "# Rancher Desktop: there was no wsl.conf before it was edited.\n"
+
+// WSLConfIntegrationSettings are the settings that WSL integration relies on:
+// Windows drives must be mounted automatically, and Windows executables must be
+// runnable.
+var WSLConfIntegrationSettings = []WSLConfSetting{
+	{Section: "automount", Key: "enabled", Value: "true"},
+	{Section: "interop", Key: "enabled", Value: "true"},
+}
+
+// WSLConfSystemdSettings are the settings needed to run systemd as the init
+// process.
+var WSLConfSystemdSettings = []WSLConfSetting{
+	{Section: "boot", Key: "systemd", Value: "true"},
+}
+
+// WSLConfSetting is a setting in a WSL configuration file.
+type WSLConfSetting struct {
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	// Unset means the setting should be removed, rather than set to Value.
+	Unset bool `json:"unset,omitempty"`
+}
+
+// String returns the setting in the form accepted by ParseWSLConfSetting.
+func (s WSLConfSetting) String() string {
+	if s.Unset {
+		return s.Section + "." + s.Key
+	}
+	return s.Section + "." + s.Key + "=" + s.Value
+}
+
+// ParseWSLConfSetting parses a setting of the form "section.key=value"; if
+// there is no "=value", the setting is to be unset.
+func ParseWSLConfSetting(input string) (WSLConfSetting, error) {
+	name, value, hasValue := strings.Cut(input, "=")
+	section, key, ok := strings.Cut(strings.TrimSpace(name), ".")
+	if !ok || section == "" || key == "" || strings.ContainsAny(name, "[]#;") {
+		return WSLConfSetting{}, fmt.Errorf("invalid setting %q: expected section.key=value", input)
+	}
+	return WSLConfSetting{
+		Section: section,
+		Key:     key,
+		Value:   strings.TrimSpace(value),
+		Unset:   !hasValue,
+	}, nil
+}
+
+// wslConfLine is a line in a WSL configuration file; section is the
+// (lower-cased) section it is in, and key is the (lower-cased) key it sets, if
+// any.
+type wslConfLine struct {
+	text    string
+	section string
+	key     string
+}
+
+// WSLConf is a WSL configuration file, which can be edited while preserving
+// comments and formatting.
+type WSLConf struct {
+	lines []wslConfLine
+}
+
+// ParseWSLConf parses the contents of a WSL configuration file.
+func ParseWSLConf(data []byte) *WSLConf {
+	conf := &WSLConf{}
+	section := ""
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if text == "" {
+		return conf
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		entry := wslConfLine{text: line}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, ";"):
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			section = strings.ToLower(strings.TrimSpace(trimmed[1 : len(trimmed)-1]))
+		default:
+			if key, _, ok := strings.Cut(trimmed, "="); ok {
+				entry.key = strings.ToLower(strings.TrimSpace(key))
+			}
+		}
+		entry.section = section
+		conf.lines = append(conf.lines, entry)
+	}
+	return conf
+}
+
+// ReadWSLConf reads the WSL configuration file at the given path; a missing
+// file is treated as being empty.
+func ReadWSLConf(path string) (*WSLConf, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read WSL configuration: %w", err)
+	}
+	return ParseWSLConf(data), nil
+}
+
+// Bytes returns the contents of the configuration file.
+func (c *WSLConf) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, line := range c.lines {
+		buf.WriteString(line.text)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// find returns the index of the last line setting the given key, or -1.
+func (c *WSLConf) find(section, key string) int {
+	section, key = strings.ToLower(section), strings.ToLower(key)
+	for i := len(c.lines) - 1; i >= 0; i-- {
+		if c.lines[i].section == section && c.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the value of the given setting; if it is set more than once,
+// the last one wins, as in WSL itself.  Any trailing comment is removed.
+func (c *WSLConf) Get(section, key string) (string, bool) {
+	index := c.find(section, key)
+	if index < 0 {
+		return "", false
+	}
+	_, value, _ := strings.Cut(c.lines[index].text, "=")
+	value, _, _ = strings.Cut(value, "#")
+	return strings.TrimSpace(value), true
+}
+
+// Settings returns the settings in effect, in the order they first appear.
+// Section and key names are lower-cased.
+func (c *WSLConf) Settings() []WSLConfSetting {
+	var result []WSLConfSetting
+	seen := make(map[string]bool)
+	for _, line := range c.lines {
+		name := line.section + "." + line.key
+		if line.key == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		value, _ := c.Get(line.section, line.key)
+		result = append(result, WSLConfSetting{Section: line.section, Key: line.key, Value: value})
+	}
+	return result
+}
+
+// Set changes the given setting, returning whether anything changed.  An
+// existing setting is changed in place (keeping any comment); otherwise, it is
+// added to the end of the section (which is created if needed).
+func (c *WSLConf) Set(section, key, value string) bool {
+	if current, ok := c.Get(section, key); ok && current == value {
+		return false
+	}
+	entry := wslConfLine{
+		text:    key + "=" + value,
+		section: strings.ToLower(section),
+		key:     strings.ToLower(key),
+	}
+	if index := c.find(section, key); index >= 0 {
+		old := c.lines[index].text
+		if _, comment, found := strings.Cut(old, "#"); found && strings.Index(old, "=") < strings.Index(old, "#") {
+			entry.text += " #" + comment
+		}
+		prefix := old[:len(old)-len(strings.TrimLeft(old, " \t"))]
+		entry.text = prefix + entry.text
+		c.lines[index] = entry
+		return true
+	}
+
+	// Insert after the last non-blank line of the last matching section.
+	insert := -1
+	for i, line := range c.lines {
+		if line.section == entry.section && strings.TrimSpace(line.text) != "" {
+			insert = i + 1
+		}
+	}
+	if insert < 0 {
+		if len(c.lines) > 0 && strings.TrimSpace(c.lines[len(c.lines)-1].text) != "" {
+			c.lines = append(c.lines, wslConfLine{section: c.lines[len(c.lines)-1].section})
+		}
+		c.lines = append(c.lines, wslConfLine{text: "[" + section + "]", section: entry.section}, entry)
+		return true
+	}
+	c.lines = append(c.lines[:insert], append([]wslConfLine{entry}, c.lines[insert:]...)...)
+	return true
+}
+
+// Unset removes all occurrences of the given setting, returning whether
+// anything changed.
+func (c *WSLConf) Unset(section, key string) bool {
+	changed := false
+	for index := c.find(section, key); index >= 0; index = c.find(section, key) {
+		c.lines = append(c.lines[:index], c.lines[index+1:]...)
+		changed = true
+	}
+	return changed
+}
+
+// Apply applies the given settings, returning whether anything changed.
+func (c *WSLConf) Apply(settings []WSLConfSetting) bool {
+	changed := false
+	for _, setting := range settings {
+		if setting.Unset {
+			changed = c.Unset(setting.Section, setting.Key) || changed
+		} else {
+			changed = c.Set(setting.Section, setting.Key, setting.Value) || changed
+		}
+	}
+	return changed
+}
+
+// WSLConfResult describes the outcome of ApplyWSLConf or RestoreWSLConf.
+type WSLConfResult struct {
+	// Changed is set if the configuration file was modified; the distribution
+	// must be restarted (with `wsl --terminate`) for this to take effect.
+	Changed bool `json:"changed"`
+	// Backup is the path of the backup of the original file, if any.
+	Backup string `json:"backup,omitempty"`
+}
+
+// wslConfBackupPath returns where the original configuration is kept.
+func wslConfBackupPath(path string) string {
+	return path + ".rancher-desktop-backup"
+}
+
+// ApplyWSLConf applies the given settings to the WSL configuration file at the
+// given path (usually DefaultWSLConfPath).  Nothing is written if the settings
+// are already in effect; otherwise, the file as it was before it was first
+// edited is kept as a backup, so that RestoreWSLConf can undo all changes.
+func ApplyWSLConf(path string, settings []WSLConfSetting) (WSLConfResult, error) {
+	original, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return WSLConfResult{}, fmt.Errorf("failed to read WSL configuration: %w", err)
+	}
+	backupPath := wslConfBackupPath(path)
+	result := WSLConfResult{}
+	if _, err := os.Stat(backupPath); err == nil {
+		result.Backup = backupPath
+	}
+	conf := ParseWSLConf(original)
+	if !conf.Apply(settings) {
+		return result, nil
+	}
+
+	mode := os.FileMode(integrationFilePermission)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if result.Backup == "" {
+		backup := original
+		if !exists {
+			backup = []byte(wslConfNoOriginal)
+		}
+		if err := os.WriteFile(backupPath, backup, mode); err != nil {
+			return result, fmt.Errorf("failed to back up WSL configuration: %w", err)
+		}
+		result.Backup = backupPath
+	}
+	if err := atomicfile.WriteFile(path, conf.Bytes(), mode); err != nil {
+		return result, fmt.Errorf("failed to write WSL configuration: %w", err)
+	}
+	result.Changed = true
+	return result, nil
+}
+
+// RestoreWSLConf restores the WSL configuration file at the given path from
+// the backup made by ApplyWSLConf, if any.
+func RestoreWSLConf(path string) (WSLConfResult, error) {
+	backupPath := wslConfBackupPath(path)
+	backup, err := os.ReadFile(backupPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return WSLConfResult{}, nil
+	} else if err != nil {
+		return WSLConfResult{}, fmt.Errorf("failed to read WSL configuration backup: %w", err)
+	}
+	if string(backup) == wslConfNoOriginal {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return WSLConfResult{}, fmt.Errorf("failed to remove WSL configuration: %w", err)
+		}
+		if err := os.Remove(backupPath); err != nil {
+			return WSLConfResult{}, fmt.Errorf("failed to remove WSL configuration backup: %w", err)
+		}
+	} else if err := os.Rename(backupPath, path); err != nil {
+		return WSLConfResult{}, fmt.Errorf("failed to restore WSL configuration: %w", err)
+	}
+	return WSLConfResult{Changed: true}, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package integration_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/integration"
+)
+
+func TestParseWSLConfSetting(t *testing.T) {
+	setting, err := integration.ParseWSLConfSetting("boot.systemd=true")
+	require.NoError(t, err)
+	assert.Equal(t, integration.WSLConfSetting{Section: "boot", Key: "systemd", Value: "true"}, setting)
+	assert.Equal(t, "boot.systemd=true", setting.String())
+
+	setting, err = integration.ParseWSLConfSetting("automount.options")
+	require.NoError(t, err)
+	assert.Equal(t, integration.WSLConfSetting{Section: "automount", Key: "options", Unset: true}, setting)
+
+	// Values may contain equals signs.
+	setting, err = integration.ParseWSLConfSetting(`automount.options="metadata,uid=1000"`)
+	require.NoError(t, err)
+	assert.Equal(t, `"metadata,uid=1000"`, setting.Value)
+
+	for _, input := range []string{"", "systemd=true", ".systemd=true", "boot.=true", "[boot].systemd=true"} {
+		_, err := integration.ParseWSLConfSetting(input)
+		assert.Error(t, err, "%q", input)
+	}
+}
+
+func TestWSLConfEdit(t *testing.T) {
+	const original = "# Example configuration\n" +
+		"[boot]\n" +
+		"  systemd = false # for now\n" +
+		"\n" +
+		"[Network]\n" +
+		"hostname=example\n" +
+		"\n" +
+		"[interop]\n"
+	conf := integration.ParseWSLConf([]byte(original))
+	value, ok := conf.Get("Boot", "SystemD")
+	assert.True(t, ok)
+	assert.Equal(t, "false", value)
+	_, ok = conf.Get("boot", "command")
+	assert.False(t, ok)
+	assert.Equal(t, []integration.WSLConfSetting{
+		{Section: "boot", Key: "systemd", Value: "false"},
+		{Section: "network", Key: "hostname", Value: "example"},
+	}, conf.Settings())
+
+	assert.False(t, conf.Set("network", "hostname", "example"))
+	assert.True(t, conf.Set("boot", "systemd", "true"))
+	assert.True(t, conf.Set("network", "generateHosts", "false"))
+	assert.True(t, conf.Set("interop", "enabled", "true"))
+	assert.True(t, conf.Set("automount", "enabled", "true"))
+	assert.Equal(t, "# Example configuration\n"+
+		"[boot]\n"+
+		"  systemd=true # for now\n"+
+		"\n"+
+		"[Network]\n"+
+		"hostname=example\n"+
+		"generateHosts=false\n"+
+		"\n"+
+		"[interop]\n"+
+		"enabled=true\n"+
+		"\n"+
+		"[automount]\n"+
+		"enabled=true\n", string(conf.Bytes()))
+
+	assert.True(t, conf.Unset("network", "hostname"))
+	assert.False(t, conf.Unset("network", "hostname"))
+	_, ok = conf.Get("network", "hostname")
+	assert.False(t, ok)
+
+	// Duplicated settings: the last one wins and is the one updated.
+	conf = integration.ParseWSLConf([]byte("[boot]\nsystemd=true\n[boot]\nsystemd=false\n"))
+	value, _ = conf.Get("boot", "systemd")
+	assert.Equal(t, "false", value)
+	assert.True(t, conf.Set("boot", "systemd", "true"))
+	assert.Equal(t, "[boot]\nsystemd=true\n[boot]\nsystemd=true\n", string(conf.Bytes()))
+
+	// CRLF line endings are accepted.
+	conf = integration.ParseWSLConf([]byte("[boot]\r\nsystemd=true\r\n"))
+	value, _ = conf.Get("boot", "systemd")
+	assert.Equal(t, "true", value)
+}
+
+func TestApplyWSLConf(t *testing.T) {
+	settings := append(append([]integration.WSLConfSetting{}, integration.WSLConfSystemdSettings...), integration.WSLConfIntegrationSettings...)
+
+	t.Run("existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "wsl.conf")
+		const original = "[network]\nhostname=example\n"
+		require.NoError(t, os.WriteFile(path, []byte(original), 0o600))
+
+		result, err := integration.ApplyWSLConf(path, settings)
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+		assert.Equal(t, path+".rancher-desktop-backup", result.Backup)
+		enabled, err := integration.SystemdEnabled(path)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		// Applying again is a no-op.
+		result, err = integration.ApplyWSLConf(path, settings)
+		require.NoError(t, err)
+		assert.False(t, result.Changed)
+
+		// Further changes keep the original backup.
+		result, err = integration.ApplyWSLConf(path, []integration.WSLConfSetting{{Section: "network", Key: "hostname", Unset: true}})
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+		backup, err := os.ReadFile(result.Backup)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(backup))
+
+		result, err = integration.RestoreWSLConf(path)
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, original, string(contents))
+		assert.NoFileExists(t, path+".rancher-desktop-backup")
+
+		result, err = integration.RestoreWSLConf(path)
+		require.NoError(t, err)
+		assert.False(t, result.Changed)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "wsl.conf")
+		result, err := integration.ApplyWSLConf(path, settings)
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+		contents, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "[boot]\nsystemd=true\n\n[automount]\nenabled=true\n\n[interop]\nenabled=true\n", string(contents))
+
+		result, err = integration.RestoreWSLConf(path)
+		require.NoError(t, err)
+		assert.True(t, result.Changed)
+		assert.NoFileExists(t, path)
+		assert.NoFileExists(t, path+".rancher-desktop-backup")
+	})
+}
@@ -0,0 +1,166 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/atomicfile"
+)
+
+const (
+	// lockTimeout is how long to wait for another process to release the lock
+	// on a kubeconfig file.
+	lockTimeout = 10 * time.Second
+	// lockRetryInterval is how often to retry taking the lock.
+	lockRetryInterval = 100 * time.Millisecond
+	// lockStaleAge is how old a lock file must be before it is assumed to
+	// have been left behind by a process that exited without unlocking.
+	lockStaleAge = time.Minute
+	// configFilePermission is the mode of newly written kubeconfig files.
+	configFilePermission = 0o600
+)
+
+// lock takes the advisory lock for the given kubeconfig file; this uses the
+// same lock file as kubectl, so that we don't race with `kubectl config`
+// commands.  Lock files older than lockStaleAge are removed.  The returned
+// function releases the lock.
+func lock(configPath string) (func(), error) {
+	lockPath := configPath + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, configFilePermission)
+		if err == nil {
+			lockFile.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock kubeconfig %s: %w", configPath, err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStaleAge {
+			if err := os.Remove(lockPath); err == nil || errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// writeAtomic writes the config to the given path by writing a temporary file
+// and renaming it over the target, so that readers never see a partially
+// written file.  The permissions of an existing file are kept.
+func writeAtomic(configPath string, config *Config) error {
+	mode := os.FileMode(configFilePermission)
+	if info, err := os.Stat(configPath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err := encoder.Encode(config)
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig %s: %w", configPath, err)
+	}
+	if err := atomicfile.WriteFile(configPath, buf.Bytes(), mode); err != nil {
+		return fmt.Errorf("failed to write kubeconfig %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// Update modifies the kubeconfig file at the given path while holding its
+// lock.  The modify function is passed the existing config (which is empty if
+// the file does not exist), and returns whether it was changed; if so, the file
+// is rewritten atomically, or removed if the config has been emptied.
+// Symbolic links are followed, so that the link target is updated.
+func Update(configPath string, modify func(*Config) (bool, error)) error {
+	if resolved, err := filepath.EvalSymlinks(configPath); err == nil {
+		configPath = resolved
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to resolve kubeconfig %s: %w", configPath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0o750); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+	unlock, err := lock(configPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := Read(configPath)
+	exists := true
+	if errors.Is(err, os.ErrNotExist) {
+		config, exists = &Config{}, false
+	} else if err != nil {
+		return err
+	}
+	changed, err := modify(config)
+	if err != nil || !changed {
+		return err
+	}
+	if config.Empty() {
+		if exists {
+			if err := os.Remove(configPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove kubeconfig %s: %w", configPath, err)
+			}
+		}
+		return nil
+	}
+	return writeAtomic(configPath, config)
+}
+
+// SelectFile returns the file, out of a KUBECONFIG-style list of paths, that
+// changes to the named context should be written to: the first file that
+// already has the context, or else the file kubectl would write new entries
+// to (the first file that exists, or the last file if none do).
+func SelectFile(paths []string, name string) string {
+	var existing []string
+	for _, configPath := range paths {
+		if configPath == "" {
+			continue
+		}
+		if _, err := os.Stat(configPath); err != nil {
+			continue
+		}
+		existing = append(existing, configPath)
+		if config, err := Read(configPath); err == nil && config.HasContext(name) {
+			return configPath
+		}
+	}
+	if len(existing) > 0 {
+		return existing[0]
+	}
+	for i := len(paths) - 1; i >= 0; i-- {
+		if paths[i] != "" {
+			return paths[i]
+		}
+	}
+	return ""
+}
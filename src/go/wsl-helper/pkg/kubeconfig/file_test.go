@@ -0,0 +1,115 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "sub", "config")
+	source := decode(t, sourceConfig)
+	merge := func(config *Config) (bool, error) {
+		return true, config.Merge(source, RancherDesktop)
+	}
+
+	require.NoError(t, Update(configPath, merge))
+	info, err := os.Stat(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(configFilePermission), info.Mode().Perm())
+	config, err := Read(configPath)
+	require.NoError(t, err)
+	assert.True(t, config.HasContext(RancherDesktop))
+	assert.NoFileExists(t, configPath+".lock")
+
+	// Symbolic links are followed.
+	linkPath := filepath.Join(dir, "link")
+	require.NoError(t, os.Symlink(configPath, linkPath))
+	require.NoError(t, Update(linkPath, func(config *Config) (bool, error) {
+		config.CurrentContext = "changed"
+		return true, nil
+	}))
+	config, err = Read(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "changed", config.CurrentContext)
+
+	// Removing everything removes the file.
+	require.NoError(t, Update(configPath, func(config *Config) (bool, error) {
+		return config.Remove(RancherDesktop), nil
+	}))
+	assert.NoFileExists(t, configPath)
+
+	// Unparsable files are not overwritten.
+	require.NoError(t, os.WriteFile(configPath, []byte("clusters: {"), 0o600))
+	assert.Error(t, Update(configPath, merge))
+	contents, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "clusters: {", string(contents))
+}
+
+func TestLock(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	unlock, err := lock(configPath)
+	require.NoError(t, err)
+	assert.FileExists(t, configPath+".lock")
+	done := make(chan error)
+	go func() {
+		unlock, err := lock(configPath)
+		if err == nil {
+			unlock()
+		}
+		done <- err
+	}()
+	unlock()
+	assert.NoError(t, <-done)
+}
+
+func TestLockStale(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(configPath+".lock", nil, 0o600))
+	old := time.Now().Add(-2 * lockStaleAge)
+	require.NoError(t, os.Chtimes(configPath+".lock", old, old))
+	unlock, err := lock(configPath)
+	require.NoError(t, err)
+	unlock()
+	assert.NoFileExists(t, configPath+".lock")
+}
+
+func TestSelectFile(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	missing := filepath.Join(dir, "missing")
+
+	assert.Equal(t, missing, SelectFile([]string{missing}, RancherDesktop))
+	assert.Equal(t, second, SelectFile([]string{first, "", second}, RancherDesktop))
+
+	require.NoError(t, os.WriteFile(second, []byte(userConfig), 0o600))
+	assert.Equal(t, second, SelectFile([]string{first, second, missing}, RancherDesktop))
+	require.NoError(t, os.WriteFile(first, []byte(userConfig), 0o600))
+	assert.Equal(t, first, SelectFile([]string{first, second}, RancherDesktop))
+
+	require.NoError(t, os.WriteFile(second, []byte(sourceConfig), 0o600))
+	assert.Equal(t, second, SelectFile([]string{first, second}, RancherDesktop))
+}
@@ -0,0 +1,268 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeconfig reads, merges, and safely rewrites kubeconfig files, so
+// that the Rancher Desktop context can be managed alongside the user's own
+// clusters.
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RancherDesktop is the name of the cluster, user, and context Rancher Desktop
+// manages.
+const RancherDesktop = "rancher-desktop"
+
+// Config is a kubeconfig file.  Fields that are not listed are kept, so that
+// they can be written back unchanged.
+type Config struct {
+	APIVersion     string         `yaml:"apiVersion,omitempty"`
+	Kind           string         `yaml:"kind,omitempty"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	CurrentContext string         `yaml:"current-context"`
+	Users          []NamedUser    `yaml:"users"`
+	Extras         map[string]any `yaml:",inline"`
+}
+
+// NamedCluster is a cluster entry in a kubeconfig file.
+type NamedCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server string         `yaml:"server"`
+		Extras map[string]any `yaml:",inline"`
+	} `yaml:"cluster"`
+	Extras map[string]any `yaml:",inline"`
+}
+
+// NamedContext is a context entry in a kubeconfig file.
+type NamedContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster string         `yaml:"cluster"`
+		User    string         `yaml:"user"`
+		Extras  map[string]any `yaml:",inline"`
+	} `yaml:"context"`
+	Extras map[string]any `yaml:",inline"`
+}
+
+// NamedUser is a user entry in a kubeconfig file.
+type NamedUser struct {
+	Name   string         `yaml:"name"`
+	User   map[string]any `yaml:"user"`
+	Extras map[string]any `yaml:",inline"`
+}
+
+// Decode a kubeconfig; an empty input results in an empty config.
+func Decode(r io.Reader) (*Config, error) {
+	var config Config
+	if err := yaml.NewDecoder(r).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Read the kubeconfig file at the given path.
+func Read(configPath string) (*Config, error) {
+	configFile, err := os.Open(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open kubeconfig file %s: %w", configPath, err)
+	}
+	defer configFile.Close()
+	config, err := Decode(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read kubeconfig %s: %w", configPath, err)
+	}
+	return config, nil
+}
+
+// Empty returns whether the config has no clusters, contexts, or users.
+func (c *Config) Empty() bool {
+	return len(c.Clusters) == 0 && len(c.Contexts) == 0 && len(c.Users) == 0
+}
+
+// HasContext returns whether the config has a context with the given name.
+func (c *Config) HasContext(name string) bool {
+	return slices.ContainsFunc(c.Contexts, func(entry NamedContext) bool { return entry.Name == name })
+}
+
+// lookup returns the named context, along with the cluster and user it refers
+// to.
+func (c *Config) lookup(name string) (*NamedContext, *NamedCluster, *NamedUser, error) {
+	index := slices.IndexFunc(c.Contexts, func(entry NamedContext) bool { return entry.Name == name })
+	if index < 0 {
+		return nil, nil, nil, fmt.Errorf("context %q not found", name)
+	}
+	context := &c.Contexts[index]
+	clusterIndex := slices.IndexFunc(c.Clusters, func(entry NamedCluster) bool { return entry.Name == context.Context.Cluster })
+	if clusterIndex < 0 {
+		return nil, nil, nil, fmt.Errorf("cluster %q for context %q not found", context.Context.Cluster, name)
+	}
+	userIndex := slices.IndexFunc(c.Users, func(entry NamedUser) bool { return entry.Name == context.Context.User })
+	if userIndex < 0 {
+		return nil, nil, nil, fmt.Errorf("user %q for context %q not found", context.Context.User, name)
+	}
+	return context, &c.Clusters[clusterIndex], &c.Users[userIndex], nil
+}
+
+// Merge copies the named context, along with the cluster and user it refers
+// to, from the source config, replacing any existing entries with the same
+// names.  If the config has no current context, the merged context is made
+// current.
+func (c *Config) Merge(source *Config, name string) error {
+	context, cluster, user, err := source.lookup(name)
+	if err != nil {
+		return err
+	}
+	c.Contexts = replaceEntry(c.Contexts, *context, func(entry NamedContext) string { return entry.Name })
+	c.Clusters = replaceEntry(c.Clusters, *cluster, func(entry NamedCluster) string { return entry.Name })
+	c.Users = replaceEntry(c.Users, *user, func(entry NamedUser) string { return entry.Name })
+	if c.CurrentContext == "" {
+		c.CurrentContext = name
+	}
+	if c.APIVersion == "" {
+		c.APIVersion = "v1"
+	}
+	if c.Kind == "" {
+		c.Kind = "Config"
+	}
+	return nil
+}
+
+// Sync brings the named context up to date with the source config, returning
+// whether anything changed.  If the existing entries differ from the source
+// only in the server address (because the VM address or port changed), only
+// the server address is updated, so that anything else the user has changed
+// (such as the context namespace) is kept; otherwise, the entries are merged
+// as with Merge.
+func (c *Config) Sync(source *Config, name string) (bool, error) {
+	sourceContext, sourceCluster, sourceUser, err := source.lookup(name)
+	if err != nil {
+		return false, err
+	}
+	context, cluster, user, err := c.lookup(name)
+	if err == nil &&
+		context.Context.Cluster == sourceContext.Context.Cluster &&
+		context.Context.User == sourceContext.Context.User &&
+		reflect.DeepEqual(user, sourceUser) {
+		withServer := *cluster
+		withServer.Cluster.Server = sourceCluster.Cluster.Server
+		if reflect.DeepEqual(&withServer, sourceCluster) {
+			if cluster.Cluster.Server == sourceCluster.Cluster.Server {
+				return false, nil
+			}
+			cluster.Cluster.Server = sourceCluster.Cluster.Server
+			return true, nil
+		}
+	}
+	return true, c.Merge(source, name)
+}
+
+// Remove the named context, as well as the cluster and user it refers to if
+// no other context uses them.  Returns whether the context existed.
+func (c *Config) Remove(name string) bool {
+	index := slices.IndexFunc(c.Contexts, func(entry NamedContext) bool { return entry.Name == name })
+	if index < 0 {
+		return false
+	}
+	context := c.Contexts[index]
+	c.Contexts = slices.Delete(c.Contexts, index, index+1)
+	if !slices.ContainsFunc(c.Contexts, func(entry NamedContext) bool { return entry.Context.Cluster == context.Context.Cluster }) {
+		c.Clusters = slices.DeleteFunc(c.Clusters, func(entry NamedCluster) bool { return entry.Name == context.Context.Cluster })
+	}
+	if !slices.ContainsFunc(c.Contexts, func(entry NamedContext) bool { return entry.Context.User == context.Context.User }) {
+		c.Users = slices.DeleteFunc(c.Users, func(entry NamedUser) bool { return entry.Name == context.Context.User })
+	}
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+	return true
+}
+
+// isRancherDesktop returns whether the cluster, user, or context name is the
+// one Rancher Desktop creates.  Other names (even ones that start with
+// "rancher-desktop") may belong to the user, so they are never touched.
+func isRancherDesktop(name string) bool {
+	return name == RancherDesktop
+}
+
+// Cleanup removes stale Rancher Desktop entries: the Rancher Desktop context
+// unless it is keep (which may be empty to remove it) and refers to an
+// existing cluster and user, as well as the Rancher Desktop cluster and user
+// if no context refers to them.  Entries not created by Rancher Desktop are
+// never removed.  If the current context is removed, keep is made current.
+// Returns the removed entries, as kind/name.
+func (c *Config) Cleanup(keep string) []string {
+	var removed []string
+	currentRemoved := false
+	for _, context := range slices.Clone(c.Contexts) {
+		if !isRancherDesktop(context.Name) {
+			continue
+		}
+		if context.Name == keep {
+			if _, _, _, err := c.lookup(context.Name); err == nil {
+				continue
+			}
+		}
+		clusters, users := len(c.Clusters), len(c.Users)
+		currentRemoved = currentRemoved || c.CurrentContext == context.Name
+		c.Remove(context.Name)
+		removed = append(removed, "context/"+context.Name)
+		if len(c.Clusters) < clusters {
+			removed = append(removed, "cluster/"+context.Context.Cluster)
+		}
+		if len(c.Users) < users {
+			removed = append(removed, "user/"+context.Context.User)
+		}
+	}
+	c.Clusters = slices.DeleteFunc(c.Clusters, func(entry NamedCluster) bool {
+		if !isRancherDesktop(entry.Name) || slices.ContainsFunc(c.Contexts, func(context NamedContext) bool { return context.Context.Cluster == entry.Name }) {
+			return false
+		}
+		removed = append(removed, "cluster/"+entry.Name)
+		return true
+	})
+	c.Users = slices.DeleteFunc(c.Users, func(entry NamedUser) bool {
+		if !isRancherDesktop(entry.Name) || slices.ContainsFunc(c.Contexts, func(context NamedContext) bool { return context.Context.User == entry.Name }) {
+			return false
+		}
+		removed = append(removed, "user/"+entry.Name)
+		return true
+	})
+	if currentRemoved && c.HasContext(keep) {
+		c.CurrentContext = keep
+	}
+	return removed
+}
+
+// replaceEntry replaces the entry in the list with the same name as the given
+// one, or appends it if there is none.
+func replaceEntry[T any](list []T, item T, name func(T) string) []T {
+	index := slices.IndexFunc(list, func(entry T) bool { return name(entry) == name(item) })
+	if index < 0 {
+		return append(list, item)
+	}
+	list[index] = item
+	return list
+}
@@ -0,0 +1,191 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sourceConfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: rancher-desktop
+  cluster:
+    server: https://127.0.0.1:6443
+    certificate-authority-data: abc
+contexts:
+- name: rancher-desktop
+  context:
+    cluster: rancher-desktop
+    user: rancher-desktop
+current-context: rancher-desktop
+users:
+- name: rancher-desktop
+  user:
+    token: secret
+preferences: {}
+`
+
+const userConfig = `
+clusters:
+- name: rancher-desktop
+  cluster:
+    server: https://127.0.0.1:1234
+- name: other
+  cluster:
+    server: https://example.com
+contexts:
+- name: other
+  context:
+    cluster: other
+    user: other
+    namespace: default
+current-context: other
+users:
+- name: other
+  user:
+    token: other-secret
+`
+
+func decode(t *testing.T, contents string) *Config {
+	config, err := Decode(strings.NewReader(contents))
+	require.NoError(t, err)
+	return config
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("into empty config", func(t *testing.T) {
+		config := decode(t, "")
+		require.NoError(t, config.Merge(decode(t, sourceConfig), RancherDesktop))
+		assert.Equal(t, RancherDesktop, config.CurrentContext)
+		assert.Equal(t, "v1", config.APIVersion)
+		assert.True(t, config.HasContext(RancherDesktop))
+		require.Len(t, config.Clusters, 1)
+		assert.Equal(t, "abc", config.Clusters[0].Cluster.Extras["certificate-authority-data"])
+	})
+	t.Run("preserves other entries", func(t *testing.T) {
+		config := decode(t, userConfig)
+		require.NoError(t, config.Merge(decode(t, sourceConfig), RancherDesktop))
+		assert.Equal(t, "other", config.CurrentContext)
+		require.Len(t, config.Clusters, 2)
+		assert.Equal(t, "https://127.0.0.1:6443", config.Clusters[0].Cluster.Server, "existing cluster should be replaced")
+		assert.Equal(t, "https://example.com", config.Clusters[1].Cluster.Server)
+		require.Len(t, config.Contexts, 2)
+		assert.Equal(t, "default", config.Contexts[0].Context.Extras["namespace"])
+		assert.Len(t, config.Users, 2)
+	})
+	t.Run("missing context", func(t *testing.T) {
+		assert.Error(t, decode(t, "").Merge(decode(t, userConfig), RancherDesktop))
+	})
+}
+
+func TestSync(t *testing.T) {
+	source := decode(t, sourceConfig)
+	t.Run("into empty config", func(t *testing.T) {
+		config := decode(t, "")
+		changed, err := config.Sync(source, RancherDesktop)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.True(t, config.HasContext(RancherDesktop))
+	})
+	t.Run("unchanged", func(t *testing.T) {
+		config := decode(t, sourceConfig)
+		changed, err := config.Sync(source, RancherDesktop)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+	t.Run("server changed", func(t *testing.T) {
+		config := decode(t, sourceConfig)
+		config.Contexts[0].Context.Extras = map[string]any{"namespace": "custom"}
+		config.Clusters[0].Cluster.Server = "https://127.0.0.1:1234"
+		changed, err := config.Sync(source, RancherDesktop)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "https://127.0.0.1:6443", config.Clusters[0].Cluster.Server)
+		assert.Equal(t, "custom", config.Contexts[0].Context.Extras["namespace"], "context changes should be kept")
+	})
+	t.Run("credentials changed", func(t *testing.T) {
+		config := decode(t, sourceConfig)
+		config.Contexts[0].Context.Extras = map[string]any{"namespace": "custom"}
+		config.Users[0].User["token"] = "old"
+		changed, err := config.Sync(source, RancherDesktop)
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "secret", config.Users[0].User["token"])
+		assert.Empty(t, config.Contexts[0].Context.Extras["namespace"], "context should be replaced")
+	})
+}
+
+func TestRemove(t *testing.T) {
+	config := decode(t, userConfig)
+	require.NoError(t, config.Merge(decode(t, sourceConfig), RancherDesktop))
+	config.CurrentContext = RancherDesktop
+	assert.True(t, config.Remove(RancherDesktop))
+	assert.False(t, config.Remove(RancherDesktop))
+	assert.Empty(t, config.CurrentContext)
+	require.Len(t, config.Clusters, 1)
+	assert.Equal(t, "other", config.Clusters[0].Name)
+	assert.Len(t, config.Contexts, 1)
+	assert.Len(t, config.Users, 1)
+
+	config = decode(t, sourceConfig)
+	assert.True(t, config.Remove(RancherDesktop))
+	assert.True(t, config.Empty())
+}
+
+func TestCleanup(t *testing.T) {
+	config := decode(t, userConfig)
+	require.NoError(t, config.Merge(decode(t, sourceConfig), RancherDesktop))
+	// Entries that merely start with "rancher-desktop" belong to the user.
+	config.Contexts = append(config.Contexts, NamedContext{Name: "rancher-desktop-mine"})
+	config.Contexts[len(config.Contexts)-1].Context.Cluster = "rancher-desktop-mine"
+	config.Contexts[len(config.Contexts)-1].Context.User = "missing"
+	config.Clusters = append(config.Clusters, NamedCluster{Name: "rancher-desktop-mine"})
+	config.Users = append(config.Users, NamedUser{Name: "rancher-desktop-unused"})
+	assert.Empty(t, config.Cleanup(RancherDesktop))
+
+	// A rancher-desktop context referring to a missing cluster is stale.
+	config.Clusters = slices.DeleteFunc(config.Clusters, func(entry NamedCluster) bool { return entry.Name == RancherDesktop })
+	config.CurrentContext = RancherDesktop
+	assert.Equal(t, []string{"context/rancher-desktop", "user/rancher-desktop"}, config.Cleanup(RancherDesktop))
+	assert.Empty(t, config.CurrentContext)
+	var names []string
+	for _, context := range config.Contexts {
+		names = append(names, context.Name)
+	}
+	assert.Equal(t, []string{"other", "rancher-desktop-mine"}, names)
+	assert.Len(t, config.Clusters, 2)
+	assert.Len(t, config.Users, 2)
+
+	// A rancher-desktop cluster or user no context refers to is stale.
+	config.Clusters = append(config.Clusters, NamedCluster{Name: RancherDesktop})
+	config.Users = append(config.Users, NamedUser{Name: RancherDesktop})
+	assert.Equal(t, []string{"cluster/rancher-desktop", "user/rancher-desktop"}, config.Cleanup(RancherDesktop))
+
+	// Without anything to keep, the rancher-desktop entries are removed.
+	config = decode(t, userConfig)
+	require.NoError(t, config.Merge(decode(t, sourceConfig), RancherDesktop))
+	assert.Equal(t, []string{"context/rancher-desktop", "cluster/rancher-desktop", "user/rancher-desktop"}, config.Cleanup(""))
+	assert.Len(t, config.Contexts, 1)
+	assert.Equal(t, "other", config.Contexts[0].Name)
+}
@@ -0,0 +1,357 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package portproxy manages Windows `netsh interface portproxy` entries, and
+// the matching firewall rules, for ports forwarded from containers.  Entries
+// created here are recorded in a separate registry key, so that entries
+// created by the user (or other software) are never modified or removed.
+package portproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// portProxyKeyPath is the registry key (under HKEY_LOCAL_MACHINE) where
+	// Windows stores portproxy entries.
+	portProxyKeyPath = `SYSTEM\CurrentControlSet\Services\PortProxy`
+	// ownershipKeyPath is the registry key (under HKEY_LOCAL_MACHINE) where we
+	// record the portproxy entries we created.
+	ownershipKeyPath = `SOFTWARE\Rancher Desktop\PortProxy`
+	// firewallRulePrefix is the prefix for the names of the firewall rules we
+	// create.
+	firewallRulePrefix = "Rancher Desktop portproxy"
+)
+
+// Families are the supported portproxy address families.
+var Families = []string{"v4tov4", "v4tov6", "v6tov4", "v6tov6"}
+
+// ErrNotOwned is returned when trying to change an entry that was not created
+// by Rancher Desktop.
+var ErrNotOwned = errors.New("portproxy entry was not created by Rancher Desktop")
+
+// Entry is a single portproxy entry.
+type Entry struct {
+	Family         string `json:"family"`
+	ListenAddress  string `json:"listenAddress"`
+	ListenPort     int    `json:"listenPort"`
+	ConnectAddress string `json:"connectAddress"`
+	ConnectPort    int    `json:"connectPort"`
+	// Owned is whether the entry was created by Rancher Desktop.
+	Owned bool `json:"owned"`
+}
+
+// valueName is the name of the registry value for the entry.
+func (e Entry) valueName() string {
+	return fmt.Sprintf("%s/%d", e.ListenAddress, e.ListenPort)
+}
+
+// valueData is the contents of the registry value for the entry.
+func (e Entry) valueData() string {
+	return fmt.Sprintf("%s/%d", e.ConnectAddress, e.ConnectPort)
+}
+
+// ledgerName is the name of the registry value recording ownership.
+func (e Entry) ledgerName() string {
+	return e.Family + "/" + e.valueName()
+}
+
+// firewallRuleName is the name of the firewall rule allowing the entry.
+func (e Entry) firewallRuleName() string {
+	return fmt.Sprintf("%s %s %s:%d", firewallRulePrefix, e.Family, e.ListenAddress, e.ListenPort)
+}
+
+// Manager manages portproxy entries.
+type Manager struct {
+	root         registry.Key
+	portProxyKey string
+	ownershipKey string
+	// netsh runs netsh.exe with the given arguments.
+	netsh func(ctx context.Context, args ...string) error
+}
+
+// NewManager returns a manager for the system portproxy configuration; most
+// operations require administrator privileges.
+func NewManager() *Manager {
+	return &Manager{
+		root:         registry.LOCAL_MACHINE,
+		portProxyKey: portProxyKeyPath,
+		ownershipKey: ownershipKeyPath,
+		netsh:        runNetsh,
+	}
+}
+
+// runNetsh runs netsh.exe with the given arguments.
+func runNetsh(ctx context.Context, args ...string) error {
+	systemDir, err := windows.GetSystemDirectory()
+	if err != nil {
+		return fmt.Errorf("failed to get system directory: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, filepath.Join(systemDir, "netsh.exe"), args...)
+	cmd.SysProcAttr = &windows.SysProcAttr{HideWindow: true}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("netsh %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// parseAddress splits a registry "address/port" string.
+func parseAddress(value string) (string, int, error) {
+	index := strings.LastIndex(value, "/")
+	if index < 0 {
+		return "", 0, fmt.Errorf("invalid portproxy address %q", value)
+	}
+	port, err := strconv.Atoi(value[index+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid portproxy port %q: %w", value, err)
+	}
+	return value[:index], port, nil
+}
+
+// readLedger returns the ownership records, mapping entries to their
+// connect address.
+func (m *Manager) readLedger() (map[string]string, error) {
+	ledger := make(map[string]string)
+	key, err := registry.OpenKey(m.root, m.ownershipKey, registry.QUERY_VALUE)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open portproxy ownership key: %w", err)
+	}
+	defer key.Close()
+	names, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read portproxy ownership key: %w", err)
+	}
+	for _, name := range names {
+		if value, _, err := key.GetStringValue(name); err == nil {
+			ledger[name] = value
+		}
+	}
+	return ledger, nil
+}
+
+// List returns the existing portproxy entries for TCP.
+func (m *Manager) List(ctx context.Context) ([]Entry, error) {
+	ledger, err := m.readLedger()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, family := range Families {
+		key, err := registry.OpenKey(m.root, m.portProxyKey+`\`+family+`\tcp`, registry.QUERY_VALUE)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to open portproxy %s key: %w", family, err)
+		}
+		names, err := key.ReadValueNames(0)
+		if err != nil {
+			key.Close()
+			return nil, fmt.Errorf("failed to read portproxy %s key: %w", family, err)
+		}
+		for _, name := range names {
+			value, _, err := key.GetStringValue(name)
+			if err != nil {
+				continue
+			}
+			entry := Entry{Family: family}
+			if entry.ListenAddress, entry.ListenPort, err = parseAddress(name); err != nil {
+				continue
+			}
+			if entry.ConnectAddress, entry.ConnectPort, err = parseAddress(value); err != nil {
+				continue
+			}
+			// The entry is only ours if it has not been changed since.
+			if owner, ok := ledger[entry.ledgerName()]; ok && owner == value {
+				entry.Owned = true
+			}
+			entries = append(entries, entry)
+		}
+		key.Close()
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if a.ListenPort != b.ListenPort {
+			return a.ListenPort - b.ListenPort
+		}
+		return strings.Compare(a.ledgerName(), b.ledgerName())
+	})
+	return entries, nil
+}
+
+// find returns the entry with the same family and listen address as the given
+// one, if any.
+func (m *Manager) find(ctx context.Context, entry Entry) (*Entry, error) {
+	entries, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range entries {
+		if existing.ledgerName() == entry.ledgerName() {
+			return &existing, nil
+		}
+	}
+	return nil, nil
+}
+
+// Add creates (or updates) a portproxy entry, along with a firewall rule
+// allowing inbound connections to the listen port if firewall is set.  An
+// existing entry for the same listen address that was not created by Rancher
+// Desktop results in ErrNotOwned.
+func (m *Manager) Add(ctx context.Context, entry Entry, firewall bool) error {
+	if !slices.Contains(Families, entry.Family) {
+		return fmt.Errorf("unknown portproxy family %q", entry.Family)
+	}
+	existing, err := m.find(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !existing.Owned {
+		return fmt.Errorf("%w: %s", ErrNotOwned, entry.ledgerName())
+	}
+
+	// Record ownership first, so that a failure part way does not leave an
+	// entry we can't clean up.
+	key, _, err := registry.CreateKey(m.root, m.ownershipKey, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to create portproxy ownership key: %w", err)
+	}
+	defer key.Close()
+	if err := key.SetStringValue(entry.ledgerName(), entry.valueData()); err != nil {
+		return fmt.Errorf("failed to record portproxy ownership: %w", err)
+	}
+
+	err = m.netsh(ctx, "interface", "portproxy", "add", entry.Family,
+		"listenaddress="+entry.ListenAddress, "listenport="+strconv.Itoa(entry.ListenPort),
+		"connectaddress="+entry.ConnectAddress, "connectport="+strconv.Itoa(entry.ConnectPort),
+		"protocol=tcp")
+	if err != nil {
+		if existing == nil {
+			_ = key.DeleteValue(entry.ledgerName())
+		} else {
+			_ = key.SetStringValue(entry.ledgerName(), existing.valueData())
+		}
+		return err
+	}
+	// Always replace the firewall rule, in case the port changed.
+	_ = m.netsh(ctx, "advfirewall", "firewall", "delete", "rule", "name="+entry.firewallRuleName())
+	if firewall {
+		err = m.netsh(ctx, "advfirewall", "firewall", "add", "rule", "name="+entry.firewallRuleName(),
+			"dir=in", "action=allow", "protocol=TCP", "localport="+strconv.Itoa(entry.ListenPort))
+		if err != nil {
+			return fmt.Errorf("failed to add firewall rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete removes a portproxy entry created by Rancher Desktop, along with its
+// firewall rule; only the family, listen address and listen port are used to
+// identify the entry.  It is an error to remove an entry not created by
+// Rancher Desktop; removing one that does not exist is not an error.
+func (m *Manager) Delete(ctx context.Context, entry Entry) error {
+	existing, err := m.find(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return m.forget(entry)
+	}
+	if !existing.Owned {
+		return fmt.Errorf("%w: %s", ErrNotOwned, entry.ledgerName())
+	}
+	err = m.netsh(ctx, "interface", "portproxy", "delete", entry.Family,
+		"listenaddress="+entry.ListenAddress, "listenport="+strconv.Itoa(entry.ListenPort),
+		"protocol=tcp")
+	if err != nil {
+		return err
+	}
+	_ = m.netsh(ctx, "advfirewall", "firewall", "delete", "rule", "name="+entry.firewallRuleName())
+	return m.forget(entry)
+}
+
+// forget removes the ownership record for the entry.
+func (m *Manager) forget(entry Entry) error {
+	return m.forgetName(entry.ledgerName())
+}
+
+// forgetName removes the ownership record with the given name.
+func (m *Manager) forgetName(name string) error {
+	key, err := registry.OpenKey(m.root, m.ownershipKey, registry.SET_VALUE)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open portproxy ownership key: %w", err)
+	}
+	defer key.Close()
+	if err := key.DeleteValue(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove portproxy ownership record: %w", err)
+	}
+	return nil
+}
+
+// Prune removes all portproxy entries created by Rancher Desktop, returning
+// the entries removed.
+func (m *Manager) Prune(ctx context.Context) ([]Entry, error) {
+	entries, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	var errs []error
+	for _, entry := range entries {
+		if !entry.Owned {
+			continue
+		}
+		if err := m.Delete(ctx, entry); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, entry)
+	}
+	// Drop records for entries that have been removed by other means.
+	ledger, err := m.readLedger()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for name, value := range ledger {
+		if !slices.ContainsFunc(entries, func(entry Entry) bool {
+			return entry.ledgerName() == name && entry.valueData() == value
+		}) {
+			family, valueName, _ := strings.Cut(name, "/")
+			stale := Entry{Family: family}
+			if stale.ListenAddress, stale.ListenPort, err = parseAddress(valueName); err == nil {
+				_ = m.netsh(ctx, "advfirewall", "firewall", "delete", "rule", "name="+stale.firewallRuleName())
+			}
+			if err := m.forgetName(name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return removed, errors.Join(errs...)
+}
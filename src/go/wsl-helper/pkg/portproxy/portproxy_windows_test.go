@@ -0,0 +1,123 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package portproxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+// newTestManager returns a manager using a scratch registry key, with netsh
+// emulated by editing the registry directly.
+func newTestManager(t *testing.T) (*Manager, *[]string) {
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, t.Name(), os.Getpid())
+	t.Cleanup(func() {
+		for _, family := range Families {
+			_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\PortProxy\`+family+`\tcp`)
+			_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\PortProxy\`+family)
+		}
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\PortProxy`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\Ownership`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	var firewall []string
+	manager := &Manager{
+		root:         registry.CURRENT_USER,
+		portProxyKey: keyPath + `\PortProxy`,
+		ownershipKey: keyPath + `\Ownership`,
+	}
+	manager.netsh = func(ctx context.Context, args ...string) error {
+		params := make(map[string]string)
+		for _, arg := range args {
+			if key, value, ok := strings.Cut(arg, "="); ok {
+				params[key] = value
+			}
+		}
+		switch args[0] {
+		case "interface":
+			key, _, err := registry.CreateKey(manager.root, manager.portProxyKey+`\`+args[3]+`\tcp`, registry.ALL_ACCESS)
+			if err != nil {
+				return err
+			}
+			defer key.Close()
+			name := params["listenaddress"] + "/" + params["listenport"]
+			if args[2] == "add" {
+				return key.SetStringValue(name, params["connectaddress"]+"/"+params["connectport"])
+			}
+			return key.DeleteValue(name)
+		case "advfirewall":
+			firewall = append(firewall, args[3]+" "+params["name"])
+		}
+		return nil
+	}
+	return manager, &firewall
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	manager, firewall := newTestManager(t)
+
+	// An entry created by the user.
+	userEntry := Entry{Family: "v4tov4", ListenAddress: "0.0.0.0", ListenPort: 80, ConnectAddress: "10.0.0.1", ConnectPort: 80}
+	require.NoError(t, manager.netsh(ctx, "interface", "portproxy", "add", "v4tov4",
+		"listenaddress=0.0.0.0", "listenport=80", "connectaddress=10.0.0.1", "connectport=80"))
+
+	entry := Entry{Family: "v4tov4", ListenAddress: "0.0.0.0", ListenPort: 8080, ConnectAddress: "172.17.0.2", ConnectPort: 80}
+	require.NoError(t, manager.Add(ctx, entry, true))
+	assert.Equal(t, []string{
+		"delete Rancher Desktop portproxy v4tov4 0.0.0.0:8080",
+		"add Rancher Desktop portproxy v4tov4 0.0.0.0:8080",
+	}, *firewall)
+
+	entries, err := manager.List(ctx)
+	require.NoError(t, err)
+	owned := entry
+	owned.Owned = true
+	assert.Equal(t, []Entry{userEntry, owned}, entries)
+
+	// Entries created by the user can't be changed.
+	assert.ErrorIs(t, manager.Add(ctx, userEntry, false), ErrNotOwned)
+	assert.ErrorIs(t, manager.Delete(ctx, userEntry), ErrNotOwned)
+
+	// Once changed by the user, our entries are no longer owned.
+	require.NoError(t, manager.netsh(ctx, "interface", "portproxy", "add", "v4tov4",
+		"listenaddress=0.0.0.0", "listenport=8080", "connectaddress=172.17.0.3", "connectport=80"))
+	entries, err = manager.List(ctx)
+	require.NoError(t, err)
+	assert.False(t, entries[1].Owned)
+	require.NoError(t, manager.netsh(ctx, "interface", "portproxy", "add", "v4tov4",
+		"listenaddress=0.0.0.0", "listenport=8080", "connectaddress=172.17.0.2", "connectport=80"))
+
+	*firewall = nil
+	removed, err := manager.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{owned}, removed)
+	assert.Equal(t, []string{"delete Rancher Desktop portproxy v4tov4 0.0.0.0:8080"}, *firewall)
+	entries, err = manager.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{userEntry}, entries)
+
+	// Deleting entries that do not exist is fine.
+	assert.NoError(t, manager.Delete(ctx, entry))
+}
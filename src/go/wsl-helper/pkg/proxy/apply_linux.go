@@ -0,0 +1,206 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher-sandbox/rancher-desktop/src/go/wsl-helper/pkg/atomicfile"
+)
+
+const (
+	// DefaultProfilePath is the shell profile script used to export the proxy
+	// settings to login shells.
+	DefaultProfilePath = "/etc/profile.d/rancher-desktop-proxy.sh"
+	// DefaultOpenRCConfDir is the directory holding OpenRC service
+	// configuration files.
+	DefaultOpenRCConfDir = "/etc/conf.d"
+	// DefaultSystemdUnitDir is the directory holding local systemd units and
+	// drop-ins.
+	DefaultSystemdUnitDir = "/etc/systemd/system"
+
+	// managedHeader is the first line of files we manage entirely.
+	managedHeader = "# Managed by Rancher Desktop; do not edit.\n"
+	// blockBegin and blockEnd delimit the part of files we manage partially.
+	blockBegin = "# BEGIN rancher-desktop proxy\n"
+	blockEnd   = "# END rancher-desktop proxy\n"
+	// systemdDropInName is the name of the systemd drop-in we manage.
+	systemdDropInName = "rancher-desktop-proxy.conf"
+)
+
+// ApplyOptions describes where to apply proxy settings.
+type ApplyOptions struct {
+	// ProfilePath is the shell profile script to write; if empty, no profile
+	// script is written.
+	ProfilePath string
+	// OpenRCServices are OpenRC services (such as docker or containerd) whose
+	// configuration should export the settings.
+	OpenRCServices []string
+	// OpenRCConfDir overrides DefaultOpenRCConfDir.
+	OpenRCConfDir string
+	// SystemdServices are systemd services which should have the settings in
+	// their environment.
+	SystemdServices []string
+	// SystemdUnitDir overrides DefaultSystemdUnitDir.
+	SystemdUnitDir string
+}
+
+// ApplyResult describes the changes made by Apply.
+type ApplyResult struct {
+	// Changed are the files that were written or removed.
+	Changed []string `json:"changed"`
+	// Reload is set if the systemd configuration must be reloaded.
+	Reload bool `json:"reload"`
+}
+
+// Apply writes the proxy settings into the given locations, removing any
+// previously applied settings if no proxy is configured.  Services must be
+// restarted for changes to take effect.
+func Apply(settings Settings, options ApplyOptions) (ApplyResult, error) {
+	var result ApplyResult
+	record := func(path string, changed bool, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+		if changed {
+			result.Changed = append(result.Changed, path)
+		}
+		return nil
+	}
+
+	if options.ProfilePath != "" {
+		var contents []byte
+		if !settings.IsZero() {
+			contents = []byte(managedHeader + shellExports(settings))
+		}
+		changed, err := replaceFile(options.ProfilePath, contents)
+		if err := record(options.ProfilePath, changed, err); err != nil {
+			return result, err
+		}
+	}
+
+	confDir := options.OpenRCConfDir
+	if confDir == "" {
+		confDir = DefaultOpenRCConfDir
+	}
+	for _, service := range options.OpenRCServices {
+		path := filepath.Join(confDir, service)
+		changed, err := replaceBlock(path, shellExports(settings))
+		if err := record(path, changed, err); err != nil {
+			return result, err
+		}
+	}
+
+	unitDir := options.SystemdUnitDir
+	if unitDir == "" {
+		unitDir = DefaultSystemdUnitDir
+	}
+	for _, service := range options.SystemdServices {
+		if !strings.Contains(service, ".") {
+			service += ".service"
+		}
+		path := filepath.Join(unitDir, service+".d", systemdDropInName)
+		var contents []byte
+		if !settings.IsZero() {
+			contents = []byte(managedHeader + "[Service]\n" + systemdEnvironment(settings))
+		}
+		changed, err := replaceFile(path, contents)
+		if err := record(path, changed, err); err != nil {
+			return result, err
+		}
+		result.Reload = result.Reload || changed
+	}
+
+	return result, nil
+}
+
+// shellExports returns the settings as shell export statements.
+func shellExports(settings Settings) string {
+	var builder strings.Builder
+	for _, variable := range settings.Environment() {
+		name, value, _ := strings.Cut(variable, "=")
+		fmt.Fprintf(&builder, "export %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+	}
+	return builder.String()
+}
+
+// systemdEnvironment returns the settings as systemd Environment= directives.
+func systemdEnvironment(settings Settings) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%")
+	var builder strings.Builder
+	for _, variable := range settings.Environment() {
+		fmt.Fprintf(&builder, "Environment=\"%s\"\n", replacer.Replace(variable))
+	}
+	return builder.String()
+}
+
+// replaceFile writes the file if its contents differ, or removes it if the
+// contents are empty, returning whether anything changed.
+func replaceFile(path string, contents []byte) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	exists := err == nil
+	if len(contents) == 0 {
+		if !exists {
+			return false, nil
+		}
+		return true, os.Remove(path)
+	}
+	if exists && bytes.Equal(existing, contents) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return false, err
+	}
+	return true, atomicfile.WriteFile(path, contents, 0o644)
+}
+
+// replaceBlock replaces the managed block of the given file with the given
+// contents (adding the block if missing, or removing it if the contents are
+// empty), leaving the rest of the file alone.
+func replaceBlock(path, contents string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	existing := string(data)
+	updated := existing
+	if begin := strings.Index(existing, blockBegin); begin >= 0 {
+		end := strings.Index(existing[begin:], blockEnd)
+		if end < 0 {
+			return false, errors.New("unterminated rancher-desktop proxy block")
+		}
+		updated = existing[:begin] + existing[begin+end+len(blockEnd):]
+	}
+	if contents != "" {
+		if updated != "" && !strings.HasSuffix(updated, "\n") {
+			updated += "\n"
+		}
+		updated += blockBegin + contents + blockEnd
+	}
+	if updated == existing {
+		return false, nil
+	}
+	return replaceFile(path, []byte(updated))
+}
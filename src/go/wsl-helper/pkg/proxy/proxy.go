@@ -0,0 +1,223 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package proxy is used to read the Windows proxy settings, and to export them
+// into Linux distributions and the container engines running in them.
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Source describes where proxy settings were read from.
+type Source string
+
+const (
+	// SourceNone indicates that no proxy is configured.
+	SourceNone Source = ""
+	// SourceInternet indicates the manual proxy from the Internet Options
+	// (per-user) settings.
+	SourceInternet Source = "internet"
+	// SourcePAC indicates a proxy auto-configuration script.
+	SourcePAC Source = "pac"
+	// SourceWinHTTP indicates the machine-wide WinHTTP proxy settings.
+	SourceWinHTTP Source = "winhttp"
+)
+
+// ErrPACUnsupported is returned when a proxy auto-configuration script is too
+// complex to be expressed as environment variables.
+var ErrPACUnsupported = errors.New("proxy auto-configuration script is not supported")
+
+// Settings are proxy settings, in the form used by the conventional
+// environment variables.
+type Settings struct {
+	HTTPProxy  string   `json:"http_proxy,omitempty"`
+	HTTPSProxy string   `json:"https_proxy,omitempty"`
+	NoProxy    []string `json:"no_proxy,omitempty"`
+	Source     Source   `json:"source,omitempty"`
+}
+
+// IsZero returns whether no proxy is configured.
+func (s Settings) IsZero() bool {
+	return s.HTTPProxy == "" && s.HTTPSProxy == ""
+}
+
+// Equal returns whether the two settings would result in the same environment.
+func (s Settings) Equal(other Settings) bool {
+	return slices.Equal(s.Environment(), other.Environment())
+}
+
+// Environment returns the environment variables for the settings, in both the
+// upper and lower case forms, as NAME=value strings in a stable order.  No
+// variables are returned if no proxy is configured.
+func (s Settings) Environment() []string {
+	if s.IsZero() {
+		return nil
+	}
+	var result []string
+	add := func(name, value string) {
+		if value != "" {
+			result = append(result, name+"="+value, strings.ToLower(name)+"="+value)
+		}
+	}
+	add("HTTP_PROXY", s.HTTPProxy)
+	add("HTTPS_PROXY", s.HTTPSProxy)
+	add("NO_PROXY", strings.Join(s.NoProxy, ","))
+	return result
+}
+
+// ParseProxyServer converts a Windows proxy server string and bypass list into
+// settings.  The server is either a single "host:port" used for all schemes,
+// or a list of "scheme=host:port" entries separated by semicolons; the bypass
+// list is separated by semicolons, and may contain wildcards and "<local>".
+func ParseProxyServer(server, bypass string) Settings {
+	var settings Settings
+	for _, entry := range splitList(server) {
+		scheme, address, found := strings.Cut(entry, "=")
+		if !found {
+			address := proxyURL(entry)
+			settings.HTTPProxy, settings.HTTPSProxy = address, address
+			continue
+		}
+		switch strings.ToLower(scheme) {
+		case "http":
+			settings.HTTPProxy = proxyURL(address)
+		case "https":
+			settings.HTTPSProxy = proxyURL(address)
+		}
+	}
+	if settings.HTTPSProxy == "" {
+		// Windows uses the HTTP proxy for HTTPS if none is given.
+		settings.HTTPSProxy = settings.HTTPProxy
+	}
+	for _, entry := range splitList(bypass) {
+		for _, converted := range convertBypass(entry) {
+			if !slices.Contains(settings.NoProxy, converted) {
+				settings.NoProxy = append(settings.NoProxy, converted)
+			}
+		}
+	}
+	return settings
+}
+
+// splitList splits a Windows proxy list, which may be separated by semicolons
+// or whitespace.
+func splitList(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return r == ';' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+}
+
+// proxyURL returns the URL to use for the given proxy address, which may or
+// may not have a scheme.
+func proxyURL(address string) string {
+	if address == "" || strings.Contains(address, "://") {
+		return address
+	}
+	return "http://" + address
+}
+
+// convertBypass converts an entry in a Windows proxy bypass list to the
+// equivalent NO_PROXY entries.
+func convertBypass(entry string) []string {
+	if strings.EqualFold(entry, "<local>") {
+		// This means host names without dots; the closest approximation is the
+		// loopback addresses.
+		return []string{"localhost", "127.0.0.1", "::1"}
+	}
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return []string{entry}
+	}
+	if cidr, ok := wildcardCIDR(entry); ok {
+		return []string{cidr}
+	}
+	if strings.HasPrefix(entry, "*.") {
+		return []string{entry[1:]}
+	}
+	if strings.Contains(entry, "*") {
+		// Wildcards elsewhere can't be expressed.
+		return nil
+	}
+	return []string{entry}
+}
+
+// wildcardCIDR converts an IPv4 address with trailing wildcard octets (e.g.
+// "10.*" or "192.168.*.*") to the equivalent CIDR.
+func wildcardCIDR(entry string) (string, bool) {
+	parts := strings.Split(entry, ".")
+	if len(parts) > 4 || parts[len(parts)-1] != "*" {
+		return "", false
+	}
+	octets := make([]string, 4)
+	prefix := 0
+	for i := range octets {
+		if i >= len(parts) || parts[i] == "*" {
+			octets[i] = "0"
+			continue
+		}
+		if prefix != i*8 || net.ParseIP(parts[i]+".0.0.0") == nil {
+			return "", false
+		}
+		octets[i] = parts[i]
+		prefix += 8
+	}
+	if prefix == 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s/%d", strings.Join(octets, "."), prefix), true
+}
+
+// pacResultPattern matches proxy results in a proxy auto-configuration
+// script, e.g. "PROXY proxy.example.com:8080".
+var pacResultPattern = regexp.MustCompile(`\b(PROXY|HTTPS|DIRECT)\b\s*([^\s;"']*)`)
+
+// ParsePAC extracts settings from simple proxy auto-configuration scripts,
+// where every non-direct result uses the same proxy.  Hosts for which the
+// script returns DIRECT are not detected; the given bypass list is used
+// instead.  An error wrapping ErrPACUnsupported is returned for scripts using
+// more than one proxy.
+func ParsePAC(script, bypass string) (Settings, error) {
+	var proxies []string
+	for _, match := range pacResultPattern.FindAllStringSubmatch(script, -1) {
+		var address string
+		switch match[1] {
+		case "PROXY":
+			address = proxyURL(match[2])
+		case "HTTPS":
+			address = "https://" + match[2]
+		default:
+			continue
+		}
+		if match[2] != "" && !slices.Contains(proxies, address) {
+			proxies = append(proxies, address)
+		}
+	}
+	switch len(proxies) {
+	case 0:
+		return Settings{}, nil
+	case 1:
+		settings := ParseProxyServer("", bypass)
+		settings.HTTPProxy, settings.HTTPSProxy = proxies[0], proxies[0]
+		settings.Source = SourcePAC
+		return settings, nil
+	}
+	return Settings{}, fmt.Errorf("%w: found proxies %s", ErrPACUnsupported, strings.Join(proxies, ", "))
+}
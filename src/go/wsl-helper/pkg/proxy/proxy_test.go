@@ -0,0 +1,109 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProxyServer(t *testing.T) {
+	cases := map[string]struct {
+		server, bypass string
+		expected       Settings
+	}{
+		"empty": {},
+		"single": {
+			server:   "proxy.example.com:3128",
+			expected: Settings{HTTPProxy: "http://proxy.example.com:3128", HTTPSProxy: "http://proxy.example.com:3128"},
+		},
+		"per scheme": {
+			server:   "http=web:80;https=secure:443;socks=socks:1080",
+			expected: Settings{HTTPProxy: "http://web:80", HTTPSProxy: "http://secure:443"},
+		},
+		"http only": {
+			server:   "http=web:80",
+			expected: Settings{HTTPProxy: "http://web:80", HTTPSProxy: "http://web:80"},
+		},
+		"with scheme": {
+			server:   "https://proxy:8443",
+			expected: Settings{HTTPProxy: "https://proxy:8443", HTTPSProxy: "https://proxy:8443"},
+		},
+		"bypass": {
+			server: "proxy:3128",
+			bypass: "*.corp.example.com;intranet; 10.*;192.168.*.*;172.16.0.0/12;web*.example.com;<local>;localhost",
+			expected: Settings{
+				HTTPProxy:  "http://proxy:3128",
+				HTTPSProxy: "http://proxy:3128",
+				NoProxy: []string{
+					".corp.example.com", "intranet", "10.0.0.0/8", "192.168.0.0/16",
+					"172.16.0.0/12", "localhost", "127.0.0.1", "::1",
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ParseProxyServer(tc.server, tc.bypass))
+		})
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	assert.Empty(t, Settings{NoProxy: []string{"localhost"}}.Environment())
+	settings := Settings{HTTPProxy: "http://web:80", HTTPSProxy: "http://secure:443", NoProxy: []string{"localhost", ".example.com"}}
+	assert.Equal(t, []string{
+		"HTTP_PROXY=http://web:80", "http_proxy=http://web:80",
+		"HTTPS_PROXY=http://secure:443", "https_proxy=http://secure:443",
+		"NO_PROXY=localhost,.example.com", "no_proxy=localhost,.example.com",
+	}, settings.Environment())
+	assert.True(t, settings.Equal(Settings{HTTPProxy: "http://web:80", HTTPSProxy: "http://secure:443", NoProxy: []string{"localhost", ".example.com"}, Source: SourcePAC}))
+	assert.False(t, settings.Equal(Settings{HTTPProxy: "http://web:80"}))
+}
+
+func TestParsePAC(t *testing.T) {
+	t.Run("single proxy", func(t *testing.T) {
+		settings, err := ParsePAC(`function FindProxyForURL(url, host) {
+			if (isPlainHostName(host) || dnsDomainIs(host, ".corp.example.com")) {
+				return "DIRECT";
+			}
+			if (isInNet(host, "10.0.0.0", "255.0.0.0")) return 'DIRECT';
+			return "PROXY proxy.example.com:8080; DIRECT";
+		}`, "<local>")
+		require.NoError(t, err)
+		assert.Equal(t, Settings{
+			HTTPProxy:  "http://proxy.example.com:8080",
+			HTTPSProxy: "http://proxy.example.com:8080",
+			NoProxy:    []string{"localhost", "127.0.0.1", "::1"},
+			Source:     SourcePAC,
+		}, settings)
+	})
+	t.Run("direct", func(t *testing.T) {
+		settings, err := ParsePAC(`function FindProxyForURL(url, host) { return "DIRECT"; }`, "")
+		require.NoError(t, err)
+		assert.True(t, settings.IsZero())
+	})
+	t.Run("multiple proxies", func(t *testing.T) {
+		_, err := ParsePAC(`function FindProxyForURL(url, host) {
+			if (shExpMatch(host, "*.example.com")) return "PROXY one:8080";
+			return "PROXY two:8080";
+		}`, "")
+		assert.ErrorIs(t, err, ErrPACUnsupported)
+	})
+}
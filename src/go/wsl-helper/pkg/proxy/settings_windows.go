@@ -0,0 +1,380 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// pacFetchTimeout is how long to wait for a proxy auto-configuration
+	// script to download.
+	pacFetchTimeout = 10 * time.Second
+	// pacMaxSize is the largest proxy auto-configuration script we will read.
+	pacMaxSize = 1024 * 1024
+	// watchSettleDelay is how long to wait after a change before reading the
+	// settings, as changes are often made in batches.
+	watchSettleDelay = 500 * time.Millisecond
+	// watchPollInterval is how often the watcher checks for cancellation while
+	// waiting for changes.
+	watchPollInterval = time.Second
+)
+
+// Flags in the WinHTTP settings.
+const (
+	winHTTPFlagProxy = 0x02
+)
+
+var (
+	// kInternetSettingsKeyOverride overrides the registry location of the
+	// Internet Options proxy settings; the value is a registryKey.
+	kInternetSettingsKeyOverride = struct{}{}
+	// kWinHTTPKeyOverride overrides the registry location of the WinHTTP proxy
+	// settings; the value is a registryKey.
+	kWinHTTPKeyOverride = struct{}{}
+)
+
+// registryKey is a registry location.
+type registryKey struct {
+	root registry.Key
+	path string
+}
+
+var (
+	// defaultInternetSettingsKey holds the per-user Internet Options settings.
+	defaultInternetSettingsKey = registryKey{registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`}
+	// defaultWinHTTPKey holds the machine-wide WinHTTP settings (as set by
+	// `netsh winhttp set proxy`).
+	defaultWinHTTPKey = registryKey{registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows\CurrentVersion\Internet Settings\Connections`}
+)
+
+// keysFromContext returns the registry locations to read settings from.
+func keysFromContext(ctx context.Context) (internet, winHTTP registryKey) {
+	internet, winHTTP = defaultInternetSettingsKey, defaultWinHTTPKey
+	if v := ctx.Value(&kInternetSettingsKeyOverride); v != nil {
+		internet = v.(registryKey)
+	}
+	if v := ctx.Value(&kWinHTTPKeyOverride); v != nil {
+		winHTTP = v.(registryKey)
+	}
+	return
+}
+
+// GetSystemSettings returns the proxy settings of the system.  The Internet
+// Options settings are used if any (preferring the proxy auto-configuration
+// script over the manual settings, as Windows does), falling back to the
+// WinHTTP settings.
+func GetSystemSettings(ctx context.Context, log *logrus.Entry) (Settings, error) {
+	internetKey, winHTTPKey := keysFromContext(ctx)
+
+	settings, err := readInternetSettings(ctx, log, internetKey)
+	if err != nil || !settings.IsZero() {
+		return settings, err
+	}
+
+	key, err := registry.OpenKey(winHTTPKey.root, winHTTPKey.path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return Settings{}, nil
+		}
+		return Settings{}, fmt.Errorf("failed to open WinHTTP settings: %w", err)
+	}
+	defer key.Close()
+	data, _, err := key.GetBinaryValue("WinHttpSettings")
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return Settings{}, nil
+		}
+		return Settings{}, fmt.Errorf("failed to read WinHTTP settings: %w", err)
+	}
+	return parseWinHTTPSettings(data)
+}
+
+// readInternetSettings reads the Internet Options proxy settings.
+func readInternetSettings(ctx context.Context, log *logrus.Entry, location registryKey) (Settings, error) {
+	key, err := registry.OpenKey(location.root, location.path, registry.QUERY_VALUE)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotExist) {
+			return Settings{}, nil
+		}
+		return Settings{}, fmt.Errorf("failed to open Internet settings: %w", err)
+	}
+	defer key.Close()
+
+	getString := func(name string) string {
+		value, _, err := key.GetStringValue(name)
+		if err != nil && !errors.Is(err, registry.ErrNotExist) {
+			log.WithError(err).WithField("value", name).Debug("failed to read Internet setting")
+		}
+		return value
+	}
+	bypass := getString("ProxyOverride")
+
+	if pacURL := getString("AutoConfigURL"); pacURL != "" {
+		script, err := fetchPAC(ctx, pacURL)
+		if err == nil {
+			var settings Settings
+			settings, err = ParsePAC(script, bypass)
+			if err == nil {
+				return settings, nil
+			}
+		}
+		// Windows falls back to the manual settings if the script can't be
+		// used, so do the same.
+		log.WithError(err).WithField("url", pacURL).Warn("Ignoring proxy auto-configuration script")
+	}
+
+	enabled, _, err := key.GetIntegerValue("ProxyEnable")
+	if err != nil || enabled == 0 {
+		return Settings{}, nil
+	}
+	settings := ParseProxyServer(getString("ProxyServer"), bypass)
+	if !settings.IsZero() {
+		settings.Source = SourceInternet
+	}
+	return settings, nil
+}
+
+// parseWinHTTPSettings parses the WinHttpSettings registry value, which is a
+// serialized WINHTTP_PROXY_INFO: a header of version, counter, and flags,
+// followed by the length-prefixed proxy server and bypass list.
+func parseWinHTTPSettings(data []byte) (Settings, error) {
+	const headerSize = 12
+	if len(data) < headerSize {
+		return Settings{}, fmt.Errorf("WinHTTP settings too short (%d bytes)", len(data))
+	}
+	flags := binary.LittleEndian.Uint32(data[8:])
+	data = data[headerSize:]
+	readString := func() (string, error) {
+		if len(data) == 0 {
+			return "", nil
+		}
+		if len(data) < 4 {
+			return "", errors.New("WinHTTP settings truncated")
+		}
+		length := binary.LittleEndian.Uint32(data)
+		if uint64(len(data)-4) < uint64(length) {
+			return "", errors.New("WinHTTP settings truncated")
+		}
+		value := string(data[4 : 4+length])
+		data = data[4+length:]
+		return value, nil
+	}
+	server, err := readString()
+	if err != nil {
+		return Settings{}, err
+	}
+	bypass, err := readString()
+	if err != nil {
+		return Settings{}, err
+	}
+	if flags&winHTTPFlagProxy == 0 {
+		return Settings{}, nil
+	}
+	settings := ParseProxyServer(server, bypass)
+	if !settings.IsZero() {
+		settings.Source = SourceWinHTTP
+	}
+	return settings, nil
+}
+
+// fetchPAC downloads the proxy auto-configuration script at the given URL.
+func fetchPAC(ctx context.Context, pacURL string) (string, error) {
+	parsed, err := url.Parse(pacURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	var reader io.ReadCloser
+	switch parsed.Scheme {
+	case "file":
+		path := parsed.Path
+		if parsed.Host != "" {
+			path = `\\` + parsed.Host + path
+		} else if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+			// file:///C:/path
+			path = path[1:]
+		}
+		reader, err = os.Open(path)
+		if err != nil {
+			return "", err
+		}
+	case "http", "https":
+		ctx, cancel := context.WithTimeout(ctx, pacFetchTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pacURL, http.NoBody)
+		if err != nil {
+			return "", err
+		}
+		// The script must not be fetched through the proxy it describes.
+		client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return "", fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		reader = resp.Body
+	default:
+		return "", fmt.Errorf("unsupported URL scheme %q", parsed.Scheme)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, pacMaxSize))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+var (
+	// networkChangeLock protects networkChangeEvents.
+	networkChangeLock sync.Mutex
+	// networkChangeEvents are the events to signal when the network changes.
+	networkChangeEvents = make(map[windows.Handle]struct{})
+	// networkChangeCallback is the callback for NotifyIpInterfaceChange; as
+	// callbacks can't be freed, only one is created.
+	networkChangeCallback = sync.OnceValue(func() uintptr {
+		return windows.NewCallback(func(callerContext, row uintptr, notificationType uint32) uintptr {
+			networkChangeLock.Lock()
+			defer networkChangeLock.Unlock()
+			for event := range networkChangeEvents {
+				_ = windows.SetEvent(event)
+			}
+			return 0
+		})
+	})
+)
+
+// notifyNetworkChange arranges for the event to be signalled whenever a
+// network interface changes; the returned function stops this.
+func notifyNetworkChange(event windows.Handle) (func(), error) {
+	networkChangeLock.Lock()
+	networkChangeEvents[event] = struct{}{}
+	networkChangeLock.Unlock()
+	remove := func() {
+		networkChangeLock.Lock()
+		delete(networkChangeEvents, event)
+		networkChangeLock.Unlock()
+	}
+	var handle windows.Handle
+	if err := windows.NotifyIpInterfaceChange(windows.AF_UNSPEC, networkChangeCallback(), nil, false, &handle); err != nil {
+		remove()
+		return nil, err
+	}
+	return func() {
+		_ = windows.CancelMibChangeNotify2(handle)
+		remove()
+	}, nil
+}
+
+// WatchSystemSettings watches the system proxy settings, calling the handler
+// with the current settings at the start and whenever they change.  The
+// settings are re-read when the registry settings change, and when the network
+// changes (as this may change the proxy auto-configuration script).  This
+// blocks until the context is cancelled.
+func WatchSystemSettings(ctx context.Context, log *logrus.Entry, handler func(Settings)) error {
+	internetKey, winHTTPKey := keysFromContext(ctx)
+
+	var keys []registry.Key
+	defer func() {
+		for _, key := range keys {
+			_ = key.Close()
+		}
+	}()
+	for _, location := range []registryKey{internetKey, winHTTPKey} {
+		key, err := registry.OpenKey(location.root, location.path, registry.NOTIFY)
+		if err != nil {
+			if !errors.Is(err, registry.ErrNotExist) {
+				log.WithError(err).WithField("key", location.path).Debug("Failed to open proxy settings key")
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	event, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(event) }()
+
+	if stopNotify, err := notifyNetworkChange(event); err != nil {
+		log.WithError(err).Warn("Failed to watch for network changes")
+	} else {
+		defer stopNotify()
+	}
+
+	first := true
+	var known Settings
+	update := func() {
+		settings, err := GetSystemSettings(ctx, log)
+		if err != nil {
+			log.WithError(err).Error("failed to read proxy settings")
+			return
+		}
+		if first || !settings.Equal(known) || settings.Source != known.Source {
+			first, known = false, settings
+			handler(settings)
+		}
+	}
+
+	const filter = windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET
+	for {
+		// Ask for notification before reading, so that no changes are missed.
+		for _, key := range keys {
+			if err := windows.RegNotifyChangeKeyValue(windows.Handle(key), true, filter, event, true); err != nil {
+				return fmt.Errorf("failed to watch proxy settings: %w", err)
+			}
+		}
+		update()
+		for {
+			result, err := windows.WaitForSingleObject(event, uint32(watchPollInterval.Milliseconds()))
+			if err != nil {
+				return fmt.Errorf("failed waiting for proxy settings changes: %w", err)
+			}
+			if ctx.Err() != nil {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return nil
+				}
+				return ctx.Err()
+			}
+			if result == windows.WAIT_OBJECT_0 {
+				break
+			}
+		}
+		log.Trace("proxy settings or network changed")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchSettleDelay):
+		}
+	}
+}
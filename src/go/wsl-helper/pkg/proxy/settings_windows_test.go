@@ -0,0 +1,127 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+// makeWinHTTPSettings serializes WinHTTP proxy settings.
+func makeWinHTTPSettings(flags uint32, server, bypass string) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, 0x28)
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, flags)
+	for _, value := range []string{server, bypass} {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(value)))
+		data = append(data, value...)
+	}
+	return data
+}
+
+func TestParseWinHTTPSettings(t *testing.T) {
+	settings, err := parseWinHTTPSettings(makeWinHTTPSettings(0x03, "proxy:3128", "<local>"))
+	require.NoError(t, err)
+	assert.Equal(t, Settings{
+		HTTPProxy:  "http://proxy:3128",
+		HTTPSProxy: "http://proxy:3128",
+		NoProxy:    []string{"localhost", "127.0.0.1", "::1"},
+		Source:     SourceWinHTTP,
+	}, settings)
+
+	settings, err = parseWinHTTPSettings(makeWinHTTPSettings(0x01, "", ""))
+	require.NoError(t, err)
+	assert.True(t, settings.IsZero())
+
+	_, err = parseWinHTTPSettings(makeWinHTTPSettings(0x03, "proxy:3128", "")[:16])
+	assert.Error(t, err)
+}
+
+// createTestKey creates a registry key for the test, removed on cleanup.
+func createTestKey(t *testing.T, name string) (registry.Key, registryKey) {
+	path := fmt.Sprintf(`Software\RancherDesktopTest\%s-%s-%d`, t.Name(), name, os.Getpid())
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, path, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = key.Close()
+		_ = registry.DeleteKey(registry.CURRENT_USER, path)
+	})
+	return key, registryKey{registry.CURRENT_USER, path}
+}
+
+func TestWatchSystemSettings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	internetKey, internetLocation := createTestKey(t, "internet")
+	winHTTPKey, winHTTPLocation := createTestKey(t, "winhttp")
+	ctx := context.WithValue(context.Background(), &kInternetSettingsKeyOverride, internetLocation)
+	ctx = context.WithValue(ctx, &kWinHTTPKeyOverride, winHTTPLocation)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes := make(chan Settings, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchSystemSettings(ctx, logrus.NewEntry(logger), func(settings Settings) {
+			changes <- settings
+		})
+	}()
+	next := func() Settings {
+		select {
+		case settings := <-changes:
+			return settings
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for change")
+			return Settings{}
+		}
+	}
+
+	assert.True(t, next().IsZero())
+
+	require.NoError(t, winHTTPKey.SetBinaryValue("WinHttpSettings", makeWinHTTPSettings(0x03, "machine:3128", "")))
+	assert.Equal(t, Settings{HTTPProxy: "http://machine:3128", HTTPSProxy: "http://machine:3128", Source: SourceWinHTTP}, next())
+
+	// The per-user settings take precedence.
+	require.NoError(t, internetKey.SetStringValue("ProxyServer", "user:8080"))
+	require.NoError(t, internetKey.SetStringValue("ProxyOverride", "*.example.com"))
+	require.NoError(t, internetKey.SetDWordValue("ProxyEnable", 1))
+	assert.Equal(t, Settings{
+		HTTPProxy:  "http://user:8080",
+		HTTPSProxy: "http://user:8080",
+		NoProxy:    []string{".example.com"},
+		Source:     SourceInternet,
+	}, next())
+
+	// Unrelated changes are not reported.
+	require.NoError(t, internetKey.SetStringValue("unrelated", time.Now().String()))
+	time.Sleep(2 * watchSettleDelay)
+	assert.Empty(t, changes)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package supervisor keeps long-running helper processes (such as the docker
+// socket proxy) running, restarting them with exponential backoff when they
+// exit.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	// DefaultInitialBackoff is the delay before the first restart.
+	DefaultInitialBackoff = time.Second
+	// DefaultMaxBackoff is the longest delay between restarts.
+	DefaultMaxBackoff = time.Minute
+	// DefaultStableAfter is how long a process must run before it is
+	// considered healthy, resetting the backoff.
+	DefaultStableAfter = time.Minute
+	// DefaultStopTimeout is how long to wait for a process to exit after
+	// asking it to stop, before killing it.
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// EventType describes what happened to a supervised process.
+type EventType string
+
+const (
+	// EventStarted is emitted when the process has been started.
+	EventStarted EventType = "started"
+	// EventExited is emitted when the process exits unexpectedly.
+	EventExited EventType = "exited"
+	// EventRestarting is emitted before waiting to restart the process.
+	EventRestarting EventType = "restarting"
+	// EventReset is emitted when the process is restarted because its
+	// environment was reset (e.g. the WSL VM was shut down).
+	EventReset EventType = "reset"
+	// EventGaveUp is emitted when the process has exited too many times.
+	EventGaveUp EventType = "gave-up"
+	// EventStopped is emitted once the process has been stopped on request.
+	EventStopped EventType = "stopped"
+)
+
+// Event is a change in the state of a supervised process.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Name     string    `json:"name"`
+	Type     EventType `json:"type"`
+	PID      int       `json:"pid,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Delay is how long until the process is restarted, for EventRestarting.
+	Delay time.Duration `json:"delay,omitempty"`
+	// Restarts is the number of restarts since the process was last stable.
+	Restarts int `json:"restarts"`
+}
+
+// Options describe the process to supervise.
+type Options struct {
+	// Name identifies the process in events.
+	Name string
+	// Command is the executable to run, followed by its arguments.
+	Command []string
+	// Stdout and Stderr receive the output of the process; if nil, the output
+	// of the supervisor is used.
+	Stdout, Stderr io.Writer
+	// InitialBackoff is the delay before the first restart; it doubles on
+	// each subsequent restart, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest delay between restarts.
+	MaxBackoff time.Duration
+	// StableAfter is how long the process must run for the backoff to be
+	// reset.
+	StableAfter time.Duration
+	// MaxRestarts is the number of consecutive restarts (without the process
+	// becoming stable) after which to give up; if zero, there is no limit.
+	MaxRestarts int
+	// StopTimeout is how long to wait for the process to exit when stopping
+	// it, before it is killed.
+	StopTimeout time.Duration
+	// Reset receives a value whenever the environment of the process has been
+	// reset (for example, the VM it talks to has restarted); the process is
+	// then restarted immediately, so that it re-establishes its sockets.
+	Reset <-chan struct{}
+	// Events, if set, is called for every change in the process state.
+	Events func(Event)
+}
+
+// withDefaults returns a copy of the options with defaults filled in.
+func (o Options) withDefaults() Options {
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = DefaultInitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.StableAfter <= 0 {
+		o.StableAfter = DefaultStableAfter
+	}
+	if o.StopTimeout <= 0 {
+		o.StopTimeout = DefaultStopTimeout
+	}
+	if o.Stdout == nil {
+		o.Stdout = os.Stdout
+	}
+	if o.Stderr == nil {
+		o.Stderr = os.Stderr
+	}
+	if o.Events == nil {
+		o.Events = func(Event) {}
+	}
+	return o
+}
+
+// Run the process until the context is cancelled, restarting it whenever it
+// exits.  Returns nil once the process has been stopped because the context
+// was cancelled, or an error if it could not be kept running.
+func Run(ctx context.Context, options Options) error {
+	if len(options.Command) == 0 {
+		return errors.New("no command to supervise")
+	}
+	options = options.withDefaults()
+	emit := func(event Event) {
+		event.Time = time.Now()
+		event.Name = options.Name
+		options.Events(event)
+	}
+
+	restarts := 0
+	backoff := options.InitialBackoff
+	for {
+		cmd := exec.Command(options.Command[0], options.Command[1:]...)
+		cmd.Stdout = options.Stdout
+		cmd.Stderr = options.Stderr
+		started := time.Now()
+		if err := cmd.Start(); err != nil {
+			emit(Event{Type: EventExited, Error: err.Error(), Restarts: restarts})
+		} else {
+			emit(Event{Type: EventStarted, PID: cmd.Process.Pid, Restarts: restarts})
+			done := make(chan error, 1)
+			go func() { done <- cmd.Wait() }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				stop(cmd, done, options.StopTimeout)
+				emit(Event{Type: EventStopped, PID: cmd.Process.Pid, Restarts: restarts})
+				return nil
+			case <-options.Reset:
+				stop(cmd, done, options.StopTimeout)
+				emit(Event{Type: EventReset, PID: cmd.Process.Pid, Restarts: restarts})
+				restarts, backoff = 0, options.InitialBackoff
+				continue
+			}
+
+			event := Event{Type: EventExited, PID: cmd.Process.Pid, Restarts: restarts}
+			code := cmd.ProcessState.ExitCode()
+			event.ExitCode = &code
+			if err != nil {
+				event.Error = err.Error()
+			}
+			emit(event)
+			if time.Since(started) >= options.StableAfter {
+				restarts, backoff = 0, options.InitialBackoff
+			}
+		}
+
+		if options.MaxRestarts > 0 && restarts >= options.MaxRestarts {
+			emit(Event{Type: EventGaveUp, Restarts: restarts})
+			return fmt.Errorf("%s exited %d times, giving up", options.Name, restarts+1)
+		}
+		emit(Event{Type: EventRestarting, Delay: backoff, Restarts: restarts})
+		select {
+		case <-ctx.Done():
+			emit(Event{Type: EventStopped, Restarts: restarts})
+			return nil
+		case <-options.Reset:
+			restarts, backoff = 0, options.InitialBackoff
+			continue
+		case <-time.After(backoff):
+		}
+		restarts++
+		backoff = min(backoff*2, options.MaxBackoff)
+	}
+}
+
+// stop asks the process to exit, killing it if it does not do so in time.
+func stop(cmd *exec.Cmd, done <-chan error, timeout time.Duration) {
+	if err := terminate(cmd.Process); err != nil {
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		<-done
+	}
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package supervisor
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectEvents returns options that record events to a channel.
+func collectEvents(command ...string) (Options, chan Event) {
+	events := make(chan Event, 100)
+	return Options{
+		Name:           "test",
+		Command:        command,
+		Stdout:         io.Discard,
+		Stderr:         io.Discard,
+		InitialBackoff: 10 * time.Millisecond,
+		StopTimeout:    time.Second,
+		Events:         func(event Event) { events <- event },
+	}, events
+}
+
+// nextEvent waits for the next event.
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestRunRestarts(t *testing.T) {
+	options, events := collectEvents("/bin/sh", "-c", "exit 3")
+	options.MaxRestarts = 2
+	err := Run(context.Background(), options)
+	assert.ErrorContains(t, err, "giving up")
+	close(events)
+
+	var types []EventType
+	for event := range events {
+		assert.Equal(t, "test", event.Name)
+		types = append(types, event.Type)
+		if event.Type == EventExited {
+			require.NotNil(t, event.ExitCode)
+			assert.Equal(t, 3, *event.ExitCode)
+		}
+	}
+	assert.Equal(t, []EventType{
+		EventStarted, EventExited, EventRestarting,
+		EventStarted, EventExited, EventRestarting,
+		EventStarted, EventExited, EventGaveUp,
+	}, types)
+}
+
+func TestRunStop(t *testing.T) {
+	options, events := collectEvents("/bin/sleep", "60")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, options) }()
+
+	event := nextEvent(t, events)
+	require.Equal(t, EventStarted, event.Type)
+	assert.NotZero(t, event.PID)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, EventStopped, nextEvent(t, events).Type)
+}
+
+func TestRunReset(t *testing.T) {
+	options, events := collectEvents("/bin/sleep", "60")
+	reset := make(chan struct{})
+	options.Reset = reset
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, options) }()
+
+	first := nextEvent(t, events)
+	require.Equal(t, EventStarted, first.Type)
+	reset <- struct{}{}
+	assert.Equal(t, EventReset, nextEvent(t, events).Type)
+	second := nextEvent(t, events)
+	assert.Equal(t, EventStarted, second.Type)
+	assert.NotEqual(t, first.PID, second.PID)
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,216 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// lxssKeyPath is the registry key (under HKEY_CURRENT_USER) where WSL
+	// records the registered distributions.
+	lxssKeyPath = `Software\Microsoft\Windows\CurrentVersion\Lxss`
+	// lxssStateInstalled is the registry State value for distributions that
+	// have finished installing.
+	lxssStateInstalled = 1
+	// integrationMarker is the file (relative to the root of a distribution)
+	// that marks it as being integrated with Rancher Desktop; this must match
+	// the integration package.
+	integrationMarker = ".rancher-desktop-integration"
+)
+
+var (
+	// kLxssKeyOverride is a context key to override the registry key to read
+	// distributions from, for testing.
+	kLxssKeyOverride = &struct{}{}
+	// kDistroRootOverride is a context key to override how the root of a
+	// running distribution is found, for testing.
+	kDistroRootOverride = &struct{}{}
+)
+
+// DistroState describes whether a distribution is running.
+type DistroState string
+
+const (
+	DistroStateRunning    DistroState = "running"
+	DistroStateStopped    DistroState = "stopped"
+	DistroStateInstalling DistroState = "installing"
+)
+
+// DistroInfo describes a registered WSL distribution.
+type DistroInfo struct {
+	Name    string      `json:"name"`    // The name of the distribution.
+	Version int         `json:"version"` // The WSL version (1 or 2) of the distribution.
+	State   DistroState `json:"state"`   // Whether the distribution is running.
+	Default bool        `json:"default"` // Whether this is the default distribution.
+	// Integrated is whether Rancher Desktop integration is enabled for the
+	// distribution; this is only known for running distributions (as checking
+	// a stopped distribution would start it), and is nil otherwise.
+	Integrated *bool `json:"integrated"`
+}
+
+// ListDistros returns the registered WSL distributions, sorted by name.
+func ListDistros(ctx context.Context, log *logrus.Entry) ([]DistroInfo, error) {
+	keyPath := lxssKeyPath
+	if v := ctx.Value(&kLxssKeyOverride); v != nil {
+		keyPath = v.(string)
+	}
+	lxssKey, err := registry.OpenKey(registry.CURRENT_USER, keyPath, registry.READ)
+	if errors.Is(err, registry.ErrNotExist) {
+		return []DistroInfo{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open WSL registry key: %w", err)
+	}
+	defer lxssKey.Close()
+
+	defaultID, _, err := lxssKey.GetStringValue("DefaultDistribution")
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read default WSL distribution: %w", err)
+	}
+	ids, err := lxssKey.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WSL distributions: %w", err)
+	}
+	running, err := listRunningDistros(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+
+	distros := make([]DistroInfo, 0, len(ids))
+	for _, id := range ids {
+		distro, err := readDistro(lxssKey, id)
+		if err != nil {
+			log.WithError(err).WithField("id", id).Debug("skipping unreadable WSL distribution")
+			continue
+		}
+		distro.Default = strings.EqualFold(id, defaultID)
+		if distro.State != DistroStateInstalling {
+			distro.State = DistroStateStopped
+			if _, ok := running[strings.ToLower(distro.Name)]; ok {
+				distro.State = DistroStateRunning
+				distro.Integrated = isIntegrated(ctx, distro.Name, log)
+			}
+		}
+		distros = append(distros, distro)
+	}
+	sort.Slice(distros, func(i, j int) bool {
+		return strings.ToLower(distros[i].Name) < strings.ToLower(distros[j].Name)
+	})
+	return distros, nil
+}
+
+// isDistroRegistered returns whether a distribution with the given name is
+// registered, without checking whether it is running.
+func isDistroRegistered(ctx context.Context, name string) (bool, error) {
+	keyPath := lxssKeyPath
+	if v := ctx.Value(&kLxssKeyOverride); v != nil {
+		keyPath = v.(string)
+	}
+	lxssKey, err := registry.OpenKey(registry.CURRENT_USER, keyPath, registry.READ)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to open WSL registry key: %w", err)
+	}
+	defer lxssKey.Close()
+	ids, err := lxssKey.ReadSubKeyNames(-1)
+	if err != nil {
+		return false, fmt.Errorf("failed to list WSL distributions: %w", err)
+	}
+	for _, id := range ids {
+		if distro, err := readDistro(lxssKey, id); err == nil && strings.EqualFold(distro.Name, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readDistro reads the registry information about a single distribution.
+func readDistro(lxssKey registry.Key, id string) (DistroInfo, error) {
+	key, err := registry.OpenKey(lxssKey, id, registry.QUERY_VALUE)
+	if err != nil {
+		return DistroInfo{}, err
+	}
+	defer key.Close()
+	name, _, err := key.GetStringValue("DistributionName")
+	if err != nil {
+		return DistroInfo{}, fmt.Errorf("failed to read distribution name: %w", err)
+	}
+	distro := DistroInfo{Name: name, Version: 1}
+	if version, _, err := key.GetIntegerValue("Version"); err == nil {
+		distro.Version = int(version)
+	}
+	if state, _, err := key.GetIntegerValue("State"); err == nil && state != lxssStateInstalled {
+		distro.State = DistroStateInstalling
+	}
+	return distro, nil
+}
+
+// listRunningDistros returns the (lower-cased) names of running distributions.
+func listRunningDistros(ctx context.Context, log *logrus.Entry) (map[string]struct{}, error) {
+	newRunnerFunc := NewWSLRunner
+	if f := ctx.Value(&kWSLExeOverride); f != nil {
+		newRunnerFunc = f.(func() WSLRunner)
+	}
+	output := &bytes.Buffer{}
+	err := newRunnerFunc().WithStdout(output).WithStderr(os.Stderr).Run(ctx, "--list", "--running", "--quiet")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// wsl.exe exits with an error when there are no running distributions.
+		log.WithError(err).Trace("wsl --list --running exited")
+		return map[string]struct{}{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error running wsl --list --running: %w", err)
+	}
+	result := make(map[string]struct{})
+	for _, line := range strings.Split(output.String(), "\n") {
+		if name := strings.TrimSpace(strings.Trim(line, "\x00")); name != "" {
+			result[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	return result, nil
+}
+
+// isIntegrated checks for the integration marker in a running distribution.
+func isIntegrated(ctx context.Context, name string, log *logrus.Entry) *bool {
+	root := `\\wsl$\` + name
+	if f := ctx.Value(&kDistroRootOverride); f != nil {
+		root = f.(func(string) string)(name)
+	}
+	_, err := os.Stat(filepath.Join(root, integrationMarker))
+	switch {
+	case err == nil:
+		result := true
+		return &result
+	case errors.Is(err, os.ErrNotExist):
+		result := false
+		return &result
+	}
+	log.WithError(err).WithField("distro", name).Debug("could not check integration marker")
+	return nil
+}
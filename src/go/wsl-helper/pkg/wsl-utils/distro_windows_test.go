@@ -0,0 +1,86 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestListDistros(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// Set up a fake Lxss registry key.
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, t.Name(), os.Getpid())
+	lxssKey, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{1}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{2}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{3}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	defer lxssKey.Close()
+	require.NoError(t, lxssKey.SetStringValue("DefaultDistribution", "{2}"))
+	for id, values := range map[string]struct {
+		name    string
+		version uint32
+		state   uint32
+	}{
+		"{1}": {name: "Alpine", version: 1, state: lxssStateInstalled},
+		"{2}": {name: "Ubuntu", version: 2, state: lxssStateInstalled},
+		"{3}": {name: "Debian", version: 2, state: 3},
+	} {
+		key, _, err := registry.CreateKey(lxssKey, id, registry.ALL_ACCESS)
+		require.NoError(t, err)
+		assert.NoError(t, key.SetStringValue("DistributionName", values.name))
+		assert.NoError(t, key.SetDWordValue("Version", values.version))
+		assert.NoError(t, key.SetDWordValue("State", values.state))
+		assert.NoError(t, key.Close())
+	}
+
+	// Ubuntu is running and integrated.
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, integrationMarker), nil, 0o644))
+	ctx := context.WithValue(context.Background(), &kLxssKeyOverride, keyPath)
+	ctx = context.WithValue(ctx, &kDistroRootOverride, func(string) string { return root })
+	var runner *wslRunnerImpl
+	ctx, runner = mockRun(ctx, func(ctx context.Context, args ...string) error {
+		assert.EqualValues(t, []string{"--list", "--running", "--quiet"}, args)
+		_, err := io.WriteString(runner.stdout, "Ubuntu\r\n")
+		return err
+	})
+
+	distros, err := ListDistros(ctx, logrus.NewEntry(logger))
+	require.NoError(t, err)
+	integrated := true
+	assert.Equal(t, []DistroInfo{
+		{Name: "Alpine", Version: 1, State: DistroStateStopped},
+		{Name: "Debian", Version: 2, State: DistroStateInstalling},
+		{Name: "Ubuntu", Version: 2, State: DistroStateRunning, Default: true, Integrated: &integrated},
+	}, distros)
+}
@@ -0,0 +1,295 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// pfVirtFirmwareEnabled is the processor feature indicating that
+	// virtualization is enabled in the firmware.
+	pfVirtFirmwareEnabled = 21
+	// vmcomputeKeyPath is the registry key for the Host Compute Service, which
+	// is installed with the Virtual Machine Platform feature needed for WSL2.
+	vmcomputeKeyPath = `SYSTEM\CurrentControlSet\Services\vmcompute`
+	// computeSystemKeyPath lists the running Hyper-V compute systems (such as
+	// the WSL2 utility VM).
+	computeSystemKeyPath = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\HostComputeService\VolatileStore\ComputeSystem`
+	// mirroredNetworkingMinBuild is the first Windows build supporting
+	// mirrored networking mode.
+	mirroredNetworkingMinBuild = 22621
+)
+
+var (
+	dllKernel32               = windows.NewLazySystemDLL("kernel32.dll")
+	isProcessorFeaturePresent = dllKernel32.NewProc("IsProcessorFeaturePresent")
+	globalMemoryStatusEx      = dllKernel32.NewProc("GlobalMemoryStatusEx")
+
+	// mirroredNetworkingMinimum is the first WSL version supporting mirrored
+	// networking mode.
+	mirroredNetworkingMinimum = PackageVersion{Major: 2}
+	// kWSLConfigOverride is a context key to override the path to .wslconfig,
+	// for testing.
+	kWSLConfigOverride = &struct{}{}
+	// kPrerequisiteProbeOverride is a context key to override the system
+	// queries in GetPrerequisites, for testing.
+	kPrerequisiteProbeOverride = &struct{}{}
+)
+
+// memoryStatusEx corresponds to the MEMORYSTATUSEX structure.
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// WSLConfig describes the settings in the user's .wslconfig that affect
+// Rancher Desktop.
+type WSLConfig struct {
+	Path        string `json:"path"`                 // The path of the .wslconfig file.
+	Exists      bool   `json:"exists"`               // Whether the file exists.
+	Memory      string `json:"memory,omitempty"`     // The raw memory setting.
+	MemoryLimit uint64 `json:"memory_limit"`         // The effective VM memory limit, in bytes.
+	Processors  int    `json:"processors,omitempty"` // The processor count setting.
+	Swap        string `json:"swap,omitempty"`       // The raw swap setting.
+	NetworkMode string `json:"networking_mode"`      // The networking mode ("nat" by default).
+	Error       string `json:"error,omitempty"`      // Any error parsing the file.
+}
+
+// MirroredNetworking describes whether WSL mirrored networking mode can be
+// used.
+type MirroredNetworking struct {
+	Available bool   `json:"available"`        // Whether mirrored networking is available.
+	Enabled   bool   `json:"enabled"`          // Whether .wslconfig enables mirrored networking.
+	Reason    string `json:"reason,omitempty"` // Why mirrored networking is unavailable.
+}
+
+// Prerequisites describes whether the host can run Rancher Desktop.
+type Prerequisites struct {
+	WSL                   *WSLInfo  `json:"wsl"`                    // The installed WSL, if known.
+	WSL2Enabled           bool      `json:"wsl2_enabled"`           // Whether the Virtual Machine Platform is installed.
+	VirtualizationEnabled bool      `json:"virtualization_enabled"` // Whether virtualization is available.
+	WSLConfig             WSLConfig `json:"wslconfig"`              // The user's .wslconfig.
+	TotalMemory           uint64    `json:"total_memory"`           // Total physical memory, in bytes.
+	WindowsBuild          uint32    `json:"windows_build"`          // The Windows build number.
+
+	MirroredNetworking MirroredNetworking `json:"mirrored_networking"`
+	Errors             []string           `json:"errors,omitempty"` // Errors encountered while probing.
+}
+
+// prerequisiteProbes are the system queries used to determine prerequisites;
+// they can be replaced for testing.
+type prerequisiteProbes struct {
+	wslInfo          func(ctx context.Context, log *logrus.Entry) (*WSLInfo, error)
+	vmPlatform       func() bool
+	firmwareVirt     func() bool
+	hypervisorActive func() bool
+	totalMemory      func() (uint64, error)
+	buildNumber      func() uint32
+}
+
+var defaultPrerequisiteProbes = prerequisiteProbes{
+	wslInfo: GetWSLInfo,
+	vmPlatform: func() bool {
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, vmcomputeKeyPath, registry.QUERY_VALUE)
+		if err != nil {
+			return false
+		}
+		key.Close()
+		return true
+	},
+	firmwareVirt: func() bool {
+		rv, _, _ := isProcessorFeaturePresent.Call(uintptr(pfVirtFirmwareEnabled))
+		return rv != 0
+	},
+	hypervisorActive: func() bool {
+		// If any compute system is running, the hypervisor must be active;
+		// IsProcessorFeaturePresent reports false in that case.
+		key, err := registry.OpenKey(registry.LOCAL_MACHINE, computeSystemKeyPath, registry.ENUMERATE_SUB_KEYS)
+		if err != nil {
+			return false
+		}
+		defer key.Close()
+		names, err := key.ReadSubKeyNames(1)
+		return err == nil && len(names) > 0
+	},
+	totalMemory: func() (uint64, error) {
+		status := memoryStatusEx{}
+		status.Length = uint32(unsafe.Sizeof(status))
+		rv, _, err := globalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+		if rv == 0 {
+			return 0, fmt.Errorf("failed to get memory status: %w", err)
+		}
+		return status.TotalPhys, nil
+	},
+	buildNumber: func() uint32 {
+		return windows.RtlGetVersion().BuildNumber
+	},
+}
+
+// parseMemorySize parses a .wslconfig size such as "8GB"; a number without a
+// unit is in bytes.
+func parseMemorySize(value string) (uint64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := uint64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier uint64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", value, err)
+	}
+	return number * multiplier, nil
+}
+
+// parseWSLConfig reads the [wsl2] section of a .wslconfig file.
+func parseWSLConfig(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != "wsl2" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, "#")
+		values[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return values, scanner.Err()
+}
+
+// readWSLConfig reads the user's .wslconfig; by default, WSL2 allows the VM
+// to use half the host memory.
+func readWSLConfig(ctx context.Context, totalMemory uint64) WSLConfig {
+	config := WSLConfig{NetworkMode: "nat", MemoryLimit: totalMemory / 2}
+	if v := ctx.Value(&kWSLConfigOverride); v != nil {
+		config.Path = v.(string)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		config.Path = filepath.Join(home, ".wslconfig")
+	} else {
+		config.Error = err.Error()
+		return config
+	}
+	file, err := os.Open(config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config
+	} else if err != nil {
+		config.Error = err.Error()
+		return config
+	}
+	defer file.Close()
+	config.Exists = true
+	values, err := parseWSLConfig(file)
+	if err != nil {
+		config.Error = err.Error()
+		return config
+	}
+	var errs []error
+	if memory, ok := values["memory"]; ok {
+		config.Memory = memory
+		if limit, err := parseMemorySize(memory); err != nil {
+			errs = append(errs, fmt.Errorf("memory: %w", err))
+		} else {
+			config.MemoryLimit = limit
+		}
+	}
+	if processors, ok := values["processors"]; ok {
+		if config.Processors, err = strconv.Atoi(processors); err != nil {
+			errs = append(errs, fmt.Errorf("processors: %w", err))
+		}
+	}
+	config.Swap = values["swap"]
+	if mode, ok := values["networkingmode"]; ok {
+		config.NetworkMode = strings.ToLower(mode)
+	}
+	if err := errors.Join(errs...); err != nil {
+		config.Error = err.Error()
+	}
+	return config
+}
+
+// GetPrerequisites probes the system for the features Rancher Desktop needs.
+// Failures to probe individual items are reported in the Errors field, rather
+// than being returned.
+func GetPrerequisites(ctx context.Context, log *logrus.Entry) *Prerequisites {
+	probes := defaultPrerequisiteProbes
+	if v := ctx.Value(&kPrerequisiteProbeOverride); v != nil {
+		probes = v.(prerequisiteProbes)
+	}
+	result := &Prerequisites{}
+	var err error
+	if result.WSL, err = probes.wslInfo(ctx, log); err != nil {
+		log.WithError(err).Debug("failed to get WSL info")
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to get WSL info: %s", err))
+	}
+	result.WSL2Enabled = probes.vmPlatform()
+	result.VirtualizationEnabled = probes.firmwareVirt() || probes.hypervisorActive()
+	if result.TotalMemory, err = probes.totalMemory(); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.WSLConfig = readWSLConfig(ctx, result.TotalMemory)
+	result.WindowsBuild = probes.buildNumber()
+
+	result.MirroredNetworking.Enabled = result.WSLConfig.NetworkMode == "mirrored"
+	switch {
+	case result.WindowsBuild < mirroredNetworkingMinBuild:
+		result.MirroredNetworking.Reason = fmt.Sprintf("requires Windows build %d or later", mirroredNetworkingMinBuild)
+	case result.WSL == nil || result.WSL.Inbox || result.WSL.Version.Less(mirroredNetworkingMinimum):
+		result.MirroredNetworking.Reason = fmt.Sprintf("requires WSL version %s or later", mirroredNetworkingMinimum)
+	default:
+		result.MirroredNetworking.Available = true
+	}
+	return result
+}
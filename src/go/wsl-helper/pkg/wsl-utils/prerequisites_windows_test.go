@@ -0,0 +1,98 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	for input, expected := range map[string]uint64{
+		"8GB":    8 << 30,
+		"512mb":  512 << 20,
+		"2 G":    2 << 30,
+		"1024":   1024,
+		"1TB":    1 << 40,
+		"4096KB": 4 << 20,
+	} {
+		actual, err := parseMemorySize(input)
+		if assert.NoError(t, err, input) {
+			assert.Equal(t, expected, actual, input)
+		}
+	}
+	_, err := parseMemorySize("lots")
+	assert.Error(t, err)
+}
+
+func TestGetPrerequisites(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	probes := prerequisiteProbes{
+		wslInfo: func(context.Context, *logrus.Entry) (*WSLInfo, error) {
+			return &WSLInfo{Installed: true, Version: PackageVersion{Major: 2, Minor: 1}}, nil
+		},
+		vmPlatform:       func() bool { return true },
+		firmwareVirt:     func() bool { return false },
+		hypervisorActive: func() bool { return true },
+		totalMemory:      func() (uint64, error) { return 16 << 30, nil },
+		buildNumber:      func() uint32 { return 22631 },
+	}
+	configPath := filepath.Join(t.TempDir(), ".wslconfig")
+	ctx := context.WithValue(context.Background(), &kPrerequisiteProbeOverride, probes)
+	ctx = context.WithValue(ctx, &kWSLConfigOverride, configPath)
+
+	t.Run("defaults", func(t *testing.T) {
+		result := GetPrerequisites(ctx, logrus.NewEntry(logger))
+		assert.True(t, result.WSL2Enabled)
+		assert.True(t, result.VirtualizationEnabled)
+		assert.False(t, result.WSLConfig.Exists)
+		assert.Equal(t, uint64(8<<30), result.WSLConfig.MemoryLimit)
+		assert.Equal(t, "nat", result.WSLConfig.NetworkMode)
+		assert.Equal(t, MirroredNetworking{Available: true}, result.MirroredNetworking)
+		assert.Empty(t, result.Errors)
+	})
+	t.Run("with config", func(t *testing.T) {
+		contents := "[wsl2]\r\nmemory=4GB # comment\r\nprocessors=2\r\nnetworkingMode=mirrored\r\n[experimental]\r\nmemory=1GB\r\n"
+		require.NoError(t, os.WriteFile(configPath, []byte(contents), 0o644))
+		result := GetPrerequisites(ctx, logrus.NewEntry(logger))
+		assert.Equal(t, WSLConfig{
+			Path:        configPath,
+			Exists:      true,
+			Memory:      "4GB",
+			MemoryLimit: 4 << 30,
+			Processors:  2,
+			NetworkMode: "mirrored",
+		}, result.WSLConfig)
+		assert.Equal(t, MirroredNetworking{Available: true, Enabled: true}, result.MirroredNetworking)
+	})
+	t.Run("old windows", func(t *testing.T) {
+		probes := probes
+		probes.buildNumber = func() uint32 { return 19045 }
+		ctx := context.WithValue(ctx, &kPrerequisiteProbeOverride, probes)
+		result := GetPrerequisites(ctx, logrus.NewEntry(logger))
+		assert.False(t, result.MirroredNetworking.Available)
+		assert.NotEmpty(t, result.MirroredNetworking.Reason)
+	})
+}
@@ -0,0 +1,292 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// DefaultRelocateDistro is the distribution that holds container storage,
+	// and is therefore the one users will want to move.
+	DefaultRelocateDistro = "rancher-desktop-data"
+	// relocateProgressInterval is how often progress is reported while the
+	// disk image is being copied.
+	relocateProgressInterval = time.Second
+)
+
+// ErrDistroRunning is returned when trying to relocate a running distribution.
+var ErrDistroRunning = errors.New("distribution is running; stop Rancher Desktop first")
+
+// kRelocateProgressIntervalOverride is a context key to override how often
+// relocation progress is reported, for testing.
+var kRelocateProgressIntervalOverride = &struct{}{}
+
+// RelocateStep describes which part of the relocation is in progress.
+type RelocateStep string
+
+const (
+	RelocateStepExport   RelocateStep = "export"   // Copying the disk image to the new location.
+	RelocateStepImport   RelocateStep = "import"   // Registering the distribution at the new location.
+	RelocateStepRollback RelocateStep = "rollback" // Restoring the distribution after a failure.
+	RelocateStepDone     RelocateStep = "done"     // The relocation is complete.
+)
+
+// RelocateProgress describes the progress of a relocation.
+type RelocateProgress struct {
+	Step   RelocateStep `json:"step"`            // The current step.
+	Copied uint64       `json:"copied"`          // Bytes of the disk image copied so far.
+	Total  uint64       `json:"total"`           // Size of the disk image, in bytes.
+	Error  string       `json:"error,omitempty"` // The error that caused a rollback.
+}
+
+// RelocateResult describes a completed relocation.
+type RelocateResult struct {
+	Distro string `json:"distro"` // The name of the distribution.
+	From   string `json:"from"`   // The previous path of the disk image.
+	To     string `json:"to"`     // The new path of the disk image.
+	// Warning describes a problem that didn't stop the relocation, such as
+	// failing to confirm the new location afterwards.
+	Warning string `json:"warning,omitempty"`
+}
+
+// RelocateDistro moves the disk image of the given (stopped) WSL2 distribution
+// into the target directory.  The distribution is exported to the new location
+// and then re-registered there; if that fails after the original has been
+// unregistered, it is imported back to its previous location from the copy.  The progress
+// callback, if given, is called periodically.
+func RelocateDistro(
+	ctx context.Context,
+	log *logrus.Entry,
+	distro, target string,
+	progress func(RelocateProgress),
+) (*RelocateResult, error) {
+	if progress == nil {
+		progress = func(RelocateProgress) {}
+	}
+	source, err := distroDiskPath(ctx, distro)
+	if err != nil {
+		return nil, err
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target directory: %w", err)
+	}
+	destination := filepath.Join(target, vhdxDefaultFileName)
+	if strings.EqualFold(filepath.Clean(source), destination) {
+		return &RelocateResult{Distro: distro, From: source, To: destination}, nil
+	}
+	running, err := listRunningDistros(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := running[strings.ToLower(distro)]; ok {
+		return nil, fmt.Errorf("cannot relocate %s: %w", distro, ErrDistroRunning)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to examine disk image of %s: %w", distro, err)
+	}
+	total := uint64(info.Size())
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create target directory: %w", err)
+	}
+	if _, err := os.Stat(destination); err == nil {
+		return nil, fmt.Errorf("%s already exists", destination)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to examine %s: %w", destination, err)
+	}
+	if free, err := diskFreeSpace(target); err != nil {
+		log.WithError(err).Debug("failed to check free disk space")
+	} else if free < total {
+		return nil, fmt.Errorf("not enough space in %s: %d bytes needed, %d available", target, total, free)
+	}
+
+	log.WithFields(logrus.Fields{"distro": distro, "from": source, "to": destination}).Info("exporting distribution")
+	err = watchCopy(ctx, destination, total, RelocateStepExport, progress, func() error {
+		return runWSL(ctx, "--export", distro, destination, "--vhd")
+	})
+	if err != nil {
+		_ = os.Remove(destination)
+		return nil, fmt.Errorf("failed to export %s: %w", distro, err)
+	}
+
+	progress(RelocateProgress{Step: RelocateStepImport, Copied: total, Total: total})
+	if err := runWSL(ctx, "--unregister", distro); err != nil {
+		// The original is still registered; just discard the copy.
+		_ = os.Remove(destination)
+		return nil, fmt.Errorf("failed to unregister %s: %w", distro, err)
+	}
+	importErr := runWSL(ctx, "--import-in-place", distro, destination)
+	if importErr == nil {
+		// The distribution is now registered at the new location, so this is
+		// a success even if we can't confirm the path afterwards.
+		result := &RelocateResult{Distro: distro, From: source, To: destination}
+		if newPath, err := distroDiskPath(ctx, distro); err != nil {
+			log.WithError(err).Warn("failed to check the location of the relocated distribution")
+			result.Warning = fmt.Sprintf("failed to check the location of %s: %s", distro, err)
+		} else {
+			if !strings.EqualFold(filepath.Clean(newPath), destination) {
+				log.WithField("path", newPath).Warn("distribution was imported to an unexpected location")
+			}
+			result.To = newPath
+		}
+		progress(RelocateProgress{Step: RelocateStepDone, Copied: total, Total: total})
+		return result, nil
+	}
+
+	log.WithError(importErr).Error("failed to import distribution at new location, restoring")
+	progress(RelocateProgress{Step: RelocateStepRollback, Total: total, Error: importErr.Error()})
+	// The original registration is gone, and with it the original disk image,
+	// so the copy is now the only one.  The failed import may still have
+	// registered the distribution using that copy; unregistering it would
+	// delete the copy, so set it aside first.
+	restoreFrom := destination
+	registered, err := isDistroRegistered(ctx, distro)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s at %s (%w), and failed to check whether it is registered; its disk image is at %s: %w",
+			distro, target, importErr, destination, err)
+	}
+	if registered {
+		backup := destination + ".bak"
+		if err := copyFile(destination, backup); err != nil {
+			return nil, fmt.Errorf("failed to import %s at %s (%w); it is registered with its disk image at %s, but could not be restored: %w",
+				distro, target, importErr, destination, err)
+		}
+		if err := runWSL(ctx, "--unregister", distro); err != nil {
+			_ = os.Remove(backup)
+			return nil, fmt.Errorf("failed to import %s at %s (%w); it is registered with its disk image at %s, but could not be restored: %w",
+				distro, target, importErr, destination, err)
+		}
+		restoreFrom = backup
+	}
+	err = watchCopy(ctx, source, total, RelocateStepRollback, progress, func() error {
+		return runWSL(ctx, "--import", distro, filepath.Dir(source), restoreFrom, "--vhd")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s at %s (%w), and failed to restore it; a copy is at %s: %w",
+			distro, target, importErr, restoreFrom, err)
+	}
+	// If the copy was set aside, the unregister already removed the original.
+	for _, path := range []string{restoreFrom, destination} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.WithError(err).WithField("path", path).Warn("failed to remove copy of disk image")
+		}
+	}
+	return nil, fmt.Errorf("failed to import %s at %s; restored it to its previous location: %w", distro, target, importErr)
+}
+
+// copyFile copies the file at source to a new file at destination; on
+// failure, any partial copy is removed.
+func copyFile(source, destination string) (err error) {
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	output, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(destination)
+		}
+	}()
+	if _, err := io.Copy(output, input); err != nil {
+		return err
+	}
+	return output.Sync()
+}
+
+// watchCopy runs the given function, reporting its progress by watching the
+// size of the file being written.
+func watchCopy(
+	ctx context.Context,
+	path string,
+	total uint64,
+	step RelocateStep,
+	progress func(RelocateProgress),
+	fn func() error,
+) error {
+	interval := relocateProgressInterval
+	if v := ctx.Value(&kRelocateProgressIntervalOverride); v != nil {
+		interval = v.(time.Duration)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			if info, err := os.Stat(path); err == nil {
+				progress(RelocateProgress{Step: step, Copied: min(uint64(info.Size()), total), Total: total})
+			}
+		}
+	}
+}
+
+// runWSL runs wsl.exe with the given arguments, including its output in any
+// error returned.
+func runWSL(ctx context.Context, args ...string) error {
+	newRunnerFunc := NewWSLRunner
+	if f := ctx.Value(&kWSLExeOverride); f != nil {
+		newRunnerFunc = f.(func() WSLRunner)
+	}
+	output := &bytes.Buffer{}
+	if err := newRunnerFunc().WithStdout(output).WithStderr(output).Run(ctx, args...); err != nil {
+		if message := strings.TrimSpace(strings.ReplaceAll(output.String(), "\x00", "")); message != "" {
+			return fmt.Errorf("wsl %s: %w: %s", args[0], err, message)
+		}
+		return fmt.Errorf("wsl %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// diskFreeSpace returns the space available to the current user on the volume
+// containing the given directory.
+func diskFreeSpace(dir string) (uint64, error) {
+	dirPtr, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(dirPtr, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
@@ -0,0 +1,259 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+// fakeRelocateWSL emulates the wsl.exe commands used when relocating a
+// distribution, using a fake Lxss registry key with a single distribution.
+// As with the real thing, unregistering the distribution deletes its disk.
+type fakeRelocateWSL struct {
+	t         *testing.T
+	distroKey string
+	commands  []string
+	// failImport causes `wsl --import-in-place` to fail.
+	failImport bool
+	// registerOnFailure causes a failing `wsl --import-in-place` to register
+	// the distribution anyway.
+	registerOnFailure bool
+	// hideBasePath causes `wsl --import-in-place` to register the
+	// distribution without recording its location.
+	hideBasePath bool
+}
+
+func (f *fakeRelocateWSL) run(ctx context.Context, args ...string) error {
+	f.commands = append(f.commands, strings.Join(args, " "))
+	switch args[0] {
+	case "--list":
+		// No distributions are running.
+		return &exec.ExitError{}
+	case "--export":
+		return os.WriteFile(args[2], []byte("disk image"), 0o644)
+	case "--unregister":
+		return f.unregister(ctx)
+	case "--import-in-place":
+		if f.failImport {
+			if f.registerOnFailure {
+				if err := f.register(filepath.Dir(args[2])); err != nil {
+					return err
+				}
+			}
+			return errors.New("import failed")
+		}
+		if err := f.register(filepath.Dir(args[2])); err != nil {
+			return err
+		}
+		if f.hideBasePath {
+			return f.deleteValue("BasePath")
+		}
+		return nil
+	case "--import":
+		contents, err := os.ReadFile(args[3])
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(args[2], vhdxDefaultFileName), contents, 0o644); err != nil {
+			return err
+		}
+		return f.register(args[2])
+	}
+	f.t.Errorf("unexpected wsl command %q", args)
+	return errors.New("unexpected command")
+}
+
+func (f *fakeRelocateWSL) register(basePath string) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, f.distroKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	if err := key.SetStringValue("DistributionName", DefaultRelocateDistro); err != nil {
+		return err
+	}
+	return key.SetStringValue("BasePath", `\\?\`+basePath)
+}
+
+func (f *fakeRelocateWSL) unregister(ctx context.Context) error {
+	registered, err := isDistroRegistered(ctx, DefaultRelocateDistro)
+	if err != nil {
+		return err
+	}
+	if !registered {
+		return errors.New("distribution not found")
+	}
+	if path, err := distroDiskPath(ctx, DefaultRelocateDistro); err == nil {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return f.deleteValue("DistributionName")
+}
+
+func (f *fakeRelocateWSL) deleteValue(name string) error {
+	key, err := registry.OpenKey(registry.CURRENT_USER, f.distroKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer key.Close()
+	return key.DeleteValue(name)
+}
+
+// setupRelocate creates a fake distribution, returning the context to use and
+// the path to its disk image.
+func setupRelocate(t *testing.T) (context.Context, *fakeRelocateWSL, string) {
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, strings.ReplaceAll(t.Name(), "/", "-"), os.Getpid())
+	lxssKey, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{1}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	defer lxssKey.Close()
+	basePath := t.TempDir()
+	key, _, err := registry.CreateKey(lxssKey, "{1}", registry.ALL_ACCESS)
+	require.NoError(t, err)
+	assert.NoError(t, key.SetStringValue("DistributionName", DefaultRelocateDistro))
+	assert.NoError(t, key.SetStringValue("BasePath", `\\?\`+basePath))
+	assert.NoError(t, key.Close())
+	source := filepath.Join(basePath, vhdxDefaultFileName)
+	require.NoError(t, os.WriteFile(source, []byte("disk image"), 0o644))
+
+	fake := &fakeRelocateWSL{t: t, distroKey: keyPath + `\{1}`}
+	ctx := context.WithValue(context.Background(), &kLxssKeyOverride, keyPath)
+	ctx = context.WithValue(ctx, &kRelocateProgressIntervalOverride, time.Millisecond)
+	ctx, _ = mockRun(ctx, fake.run)
+	return ctx, fake, source
+}
+
+func TestRelocateDistro(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	log := logrus.NewEntry(logger)
+
+	t.Run("success", func(t *testing.T) {
+		ctx, fake, source := setupRelocate(t)
+		target := t.TempDir()
+		destination := filepath.Join(target, vhdxDefaultFileName)
+		var steps []RelocateStep
+		result, err := RelocateDistro(ctx, log, DefaultRelocateDistro, target, func(p RelocateProgress) {
+			steps = append(steps, p.Step)
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &RelocateResult{Distro: DefaultRelocateDistro, From: source, To: destination}, result)
+		assert.Equal(t, []string{
+			"--list --running --quiet",
+			"--export rancher-desktop-data " + destination + " --vhd",
+			"--unregister rancher-desktop-data",
+			"--import-in-place rancher-desktop-data " + destination,
+		}, fake.commands)
+		assert.Contains(t, steps, RelocateStepDone)
+		assert.FileExists(t, destination)
+	})
+	t.Run("rollback", func(t *testing.T) {
+		ctx, fake, source := setupRelocate(t)
+		fake.failImport = true
+		target := t.TempDir()
+		destination := filepath.Join(target, vhdxDefaultFileName)
+		var steps []RelocateStep
+		_, err := RelocateDistro(ctx, log, DefaultRelocateDistro, target, func(p RelocateProgress) {
+			steps = append(steps, p.Step)
+		})
+		assert.ErrorContains(t, err, "restored it to its previous location")
+		assert.Equal(t, []string{
+			"--list --running --quiet",
+			"--export rancher-desktop-data " + destination + " --vhd",
+			"--unregister rancher-desktop-data",
+			"--import-in-place rancher-desktop-data " + destination,
+			"--import rancher-desktop-data " + filepath.Dir(source) + " " + destination + " --vhd",
+		}, fake.commands)
+		assert.Contains(t, steps, RelocateStepRollback)
+		assert.NotContains(t, steps, RelocateStepDone)
+		assert.FileExists(t, source)
+		assert.NoFileExists(t, destination, "copy should be removed after rollback")
+		path, err := distroDiskPath(ctx, DefaultRelocateDistro)
+		require.NoError(t, err)
+		assert.Equal(t, source, path)
+	})
+	t.Run("rollback after registering", func(t *testing.T) {
+		ctx, fake, source := setupRelocate(t)
+		fake.failImport = true
+		fake.registerOnFailure = true
+		target := t.TempDir()
+		destination := filepath.Join(target, vhdxDefaultFileName)
+		backup := destination + ".bak"
+		_, err := RelocateDistro(ctx, log, DefaultRelocateDistro, target, nil)
+		assert.ErrorContains(t, err, "restored it to its previous location")
+		assert.Equal(t, []string{
+			"--list --running --quiet",
+			"--export rancher-desktop-data " + destination + " --vhd",
+			"--unregister rancher-desktop-data",
+			"--import-in-place rancher-desktop-data " + destination,
+			"--unregister rancher-desktop-data",
+			"--import rancher-desktop-data " + filepath.Dir(source) + " " + backup + " --vhd",
+		}, fake.commands, "the disk image should be set aside before unregistering")
+		assert.FileExists(t, source)
+		assert.NoFileExists(t, destination)
+		assert.NoFileExists(t, backup)
+		path, err := distroDiskPath(ctx, DefaultRelocateDistro)
+		require.NoError(t, err)
+		assert.Equal(t, source, path)
+	})
+	t.Run("unknown new location", func(t *testing.T) {
+		ctx, fake, source := setupRelocate(t)
+		fake.hideBasePath = true
+		target := t.TempDir()
+		destination := filepath.Join(target, vhdxDefaultFileName)
+		result, err := RelocateDistro(ctx, log, DefaultRelocateDistro, target, nil)
+		require.NoError(t, err, "a successful import should not be rolled back")
+		assert.Equal(t, source, result.From)
+		assert.Equal(t, destination, result.To)
+		assert.Contains(t, result.Warning, "failed to check the location")
+		assert.NotContains(t, fake.commands[len(fake.commands)-1], "--unregister")
+		assert.FileExists(t, destination)
+	})
+	t.Run("same location", func(t *testing.T) {
+		ctx, fake, source := setupRelocate(t)
+		result, err := RelocateDistro(ctx, log, DefaultRelocateDistro, filepath.Dir(source), nil)
+		require.NoError(t, err)
+		assert.Equal(t, source, result.To)
+		assert.Empty(t, fake.commands)
+	})
+	t.Run("existing file", func(t *testing.T) {
+		ctx, fake, _ := setupRelocate(t)
+		target := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(target, vhdxDefaultFileName), nil, 0o644))
+		_, err := RelocateDistro(ctx, log, DefaultRelocateDistro, target, nil)
+		assert.ErrorContains(t, err, "already exists")
+		assert.NotContains(t, fake.commands, "--unregister rancher-desktop-data")
+	})
+}
@@ -0,0 +1,333 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// vhdxRegionTableOffset is the location of the (first) VHDX region table.
+	vhdxRegionTableOffset = 192 * 1024
+	// vhdxDefaultFileName is the name of the disk image of a WSL2 distribution
+	// if it is not recorded in the registry.
+	vhdxDefaultFileName = "ext4.vhdx"
+)
+
+var (
+	getProcessMemoryInfo = dllKernel32.NewProc("K32GetProcessMemoryInfo")
+
+	// vmProcessNames are the names of the host processes that represent the
+	// memory used by the WSL2 utility VM.
+	vmProcessNames = []string{"vmmemWSL", "vmmem"}
+	// vhdxMetadataRegion identifies the metadata region in a VHDX file.
+	vhdxMetadataRegion = windows.GUID{Data1: 0x8B7CA206, Data2: 0x4790, Data3: 0x4B9A, Data4: [8]byte{0xB8, 0xFE, 0x57, 0x5F, 0x05, 0x0F, 0x88, 0x6E}}
+	// vhdxVirtualDiskSize identifies the virtual disk size metadata item.
+	vhdxVirtualDiskSize = windows.GUID{Data1: 0x2FA54224, Data2: 0xCD1B, Data3: 0x4876, Data4: [8]byte{0xB2, 0x11, 0x5D, 0xBE, 0xD8, 0x3B, 0xF4, 0xB8}}
+)
+
+// processMemoryCounters corresponds to the PROCESS_MEMORY_COUNTERS structure.
+type processMemoryCounters struct {
+	Cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+// VMUsage describes the resources used by the WSL2 utility VM.
+type VMUsage struct {
+	Process    string  `json:"process"`     // The name of the host process representing the VM.
+	PID        uint32  `json:"pid"`         // The process ID of that process.
+	WorkingSet uint64  `json:"working_set"` // Host memory currently used by the VM, in bytes.
+	Committed  uint64  `json:"committed"`   // Host memory committed to the VM, in bytes.
+	CPUPercent float64 `json:"cpu_percent"` // CPU usage, as a percentage of all host processors.
+}
+
+// DiskUsage describes the disk image of a WSL2 distribution.
+type DiskUsage struct {
+	Distro      string `json:"distro"`          // The name of the distribution.
+	Path        string `json:"path"`            // The path to the disk image.
+	VirtualSize uint64 `json:"virtual_size"`    // The maximum size of the disk, in bytes.
+	FileSize    uint64 `json:"file_size"`       // Host disk space used by the image, in bytes.
+	Error       string `json:"error,omitempty"` // Any error examining the disk image.
+}
+
+// ResourceUsage describes the resources used by WSL.
+type ResourceUsage struct {
+	// VM is the usage of the utility VM; nil if it is not running.
+	VM *VMUsage `json:"vm"`
+	// MemoryLimit is the VM memory limit, in bytes, from .wslconfig.
+	MemoryLimit uint64      `json:"memory_limit"`
+	Disks       []DiskUsage `json:"disks"`
+}
+
+// findVMProcess returns the process ID and name of the WSL2 utility VM
+// process, or zero if it is not running.
+func findVMProcess() (uint32, string, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to list processes: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(snapshot) }()
+	found := make(map[string]uint32)
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		name := strings.TrimSuffix(strings.ToLower(windows.UTF16ToString(entry.ExeFile[:])), ".exe")
+		found[name] = entry.ProcessID
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return 0, "", fmt.Errorf("failed to list processes: %w", err)
+	}
+	for _, name := range vmProcessNames {
+		if pid, ok := found[strings.ToLower(name)]; ok {
+			return pid, name, nil
+		}
+	}
+	return 0, "", nil
+}
+
+// processCPUTime returns the total CPU time used by the process.
+func processCPUTime(process windows.Handle) (time.Duration, error) {
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0, fmt.Errorf("failed to get process times: %w", err)
+	}
+	// Filetime values are in units of 100ns.
+	total := uint64(kernel.HighDateTime)<<32 | uint64(kernel.LowDateTime)
+	total += uint64(user.HighDateTime)<<32 | uint64(user.LowDateTime)
+	return time.Duration(total * 100), nil
+}
+
+// getVMUsage samples the resource usage of the utility VM over the given
+// interval; returns nil if the VM is not running.
+func getVMUsage(ctx context.Context, interval time.Duration) (*VMUsage, error) {
+	pid, name, err := findVMProcess()
+	if err != nil || pid == 0 {
+		return nil, err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s process: %w", name, err)
+	}
+	defer func() { _ = windows.CloseHandle(process) }()
+
+	usage := &VMUsage{Process: name, PID: pid}
+	startCPU, err := processCPUTime(process)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(interval):
+	}
+	endCPU, err := processCPUTime(process)
+	if err != nil {
+		return nil, err
+	}
+	if elapsed := time.Since(start); elapsed > 0 {
+		usage.CPUPercent = float64(endCPU-startCPU) / float64(elapsed) / float64(runtime.NumCPU()) * 100
+	}
+
+	counters := processMemoryCounters{}
+	counters.Cb = uint32(unsafe.Sizeof(counters))
+	rv, _, err := getProcessMemoryInfo.Call(
+		uintptr(process),
+		uintptr(unsafe.Pointer(&counters)),
+		uintptr(counters.Cb),
+	)
+	if rv == 0 {
+		return nil, fmt.Errorf("failed to get %s memory usage: %w", name, err)
+	}
+	usage.WorkingSet = uint64(counters.WorkingSetSize)
+	usage.Committed = uint64(counters.PagefileUsage)
+	return usage, nil
+}
+
+// readVHDXVirtualSize reads the virtual disk size from the metadata of a VHDX
+// file.
+func readVHDXVirtualSize(r io.ReaderAt) (uint64, error) {
+	signature := make([]byte, 8)
+	if _, err := r.ReadAt(signature, 0); err != nil {
+		return 0, fmt.Errorf("failed to read VHDX signature: %w", err)
+	}
+	if !bytes.Equal(signature, []byte("vhdxfile")) {
+		return 0, errors.New("not a VHDX file")
+	}
+
+	// Find the metadata region in the region table.
+	header := make([]byte, 16)
+	if _, err := r.ReadAt(header, vhdxRegionTableOffset); err != nil {
+		return 0, fmt.Errorf("failed to read VHDX region table: %w", err)
+	}
+	if !bytes.Equal(header[:4], []byte("regi")) {
+		return 0, errors.New("invalid VHDX region table")
+	}
+	var metadataOffset int64 = -1
+	entry := make([]byte, 32)
+	for i := range binary.LittleEndian.Uint32(header[8:]) {
+		if _, err := r.ReadAt(entry, vhdxRegionTableOffset+16+int64(i)*32); err != nil {
+			return 0, fmt.Errorf("failed to read VHDX region table: %w", err)
+		}
+		if guidFromBytes(entry[:16]) == vhdxMetadataRegion {
+			metadataOffset = int64(binary.LittleEndian.Uint64(entry[16:]))
+			break
+		}
+	}
+	if metadataOffset < 0 {
+		return 0, errors.New("VHDX metadata region not found")
+	}
+
+	// Find the virtual disk size in the metadata table.
+	if _, err := r.ReadAt(header, metadataOffset); err != nil {
+		return 0, fmt.Errorf("failed to read VHDX metadata table: %w", err)
+	}
+	if !bytes.Equal(header[:8], []byte("metadata")) {
+		return 0, errors.New("invalid VHDX metadata table")
+	}
+	for i := range binary.LittleEndian.Uint16(header[10:]) {
+		if _, err := r.ReadAt(entry, metadataOffset+32+int64(i)*32); err != nil {
+			return 0, fmt.Errorf("failed to read VHDX metadata table: %w", err)
+		}
+		if guidFromBytes(entry[:16]) == vhdxVirtualDiskSize {
+			value := make([]byte, 8)
+			offset := metadataOffset + int64(binary.LittleEndian.Uint32(entry[16:]))
+			if _, err := r.ReadAt(value, offset); err != nil {
+				return 0, fmt.Errorf("failed to read VHDX virtual disk size: %w", err)
+			}
+			return binary.LittleEndian.Uint64(value), nil
+		}
+	}
+	return 0, errors.New("VHDX virtual disk size not found")
+}
+
+// guidFromBytes decodes a GUID in its (mixed-endian) binary form.
+func guidFromBytes(buf []byte) windows.GUID {
+	guid := windows.GUID{
+		Data1: binary.LittleEndian.Uint32(buf[0:]),
+		Data2: binary.LittleEndian.Uint16(buf[4:]),
+		Data3: binary.LittleEndian.Uint16(buf[6:]),
+	}
+	copy(guid.Data4[:], buf[8:16])
+	return guid
+}
+
+// distroDiskPath returns the path to the disk image of the named WSL2
+// distribution.
+func distroDiskPath(ctx context.Context, name string) (string, error) {
+	keyPath := lxssKeyPath
+	if v := ctx.Value(&kLxssKeyOverride); v != nil {
+		keyPath = v.(string)
+	}
+	lxssKey, err := registry.OpenKey(registry.CURRENT_USER, keyPath, registry.READ)
+	if err != nil {
+		return "", fmt.Errorf("failed to open WSL registry key: %w", err)
+	}
+	defer lxssKey.Close()
+	ids, err := lxssKey.ReadSubKeyNames(-1)
+	if err != nil {
+		return "", fmt.Errorf("failed to list WSL distributions: %w", err)
+	}
+	for _, id := range ids {
+		key, err := registry.OpenKey(lxssKey, id, registry.QUERY_VALUE)
+		if err != nil {
+			continue
+		}
+		distroName, _, err := key.GetStringValue("DistributionName")
+		if err != nil || !strings.EqualFold(distroName, name) {
+			key.Close()
+			continue
+		}
+		basePath, _, err := key.GetStringValue("BasePath")
+		if err != nil {
+			key.Close()
+			return "", fmt.Errorf("failed to read location of distribution %s: %w", name, err)
+		}
+		fileName, _, err := key.GetStringValue("VhdFileName")
+		if err != nil {
+			fileName = vhdxDefaultFileName
+		}
+		key.Close()
+		return filepath.Join(strings.TrimPrefix(basePath, `\\?\`), fileName), nil
+	}
+	return "", fmt.Errorf("distribution %s not found", name)
+}
+
+// getDiskUsage examines the disk image of the named distribution.
+func getDiskUsage(ctx context.Context, name string) DiskUsage {
+	usage := DiskUsage{Distro: name}
+	var err error
+	if usage.Path, err = distroDiskPath(ctx, name); err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	file, err := os.Open(usage.Path)
+	if err != nil {
+		usage.Error = err.Error()
+		return usage
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil {
+		usage.FileSize = uint64(info.Size())
+	}
+	if usage.VirtualSize, err = readVHDXVirtualSize(file); err != nil {
+		usage.Error = err.Error()
+	}
+	return usage
+}
+
+// GetResourceUsage reports the resources used by the WSL2 utility VM (sampling
+// CPU usage over the given interval), as well as the disk images of the given
+// distributions.
+func GetResourceUsage(ctx context.Context, log *logrus.Entry, distros []string, interval time.Duration) (*ResourceUsage, error) {
+	result := &ResourceUsage{Disks: []DiskUsage{}}
+	var err error
+	if result.VM, err = getVMUsage(ctx, interval); err != nil {
+		return nil, err
+	}
+	totalMemory, err := defaultPrerequisiteProbes.totalMemory()
+	if err != nil {
+		log.WithError(err).Debug("failed to get total memory")
+	}
+	result.MemoryLimit = readWSLConfig(ctx, totalMemory).MemoryLimit
+	for _, distro := range distros {
+		result.Disks = append(result.Disks, getDiskUsage(ctx, distro))
+	}
+	return result, nil
+}
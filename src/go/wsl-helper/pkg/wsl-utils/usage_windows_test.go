@@ -0,0 +1,92 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// makeVHDX returns the start of a VHDX file with the given virtual size.
+func makeVHDX(virtualSize uint64) []byte {
+	const metadataOffset = 1024 * 1024
+	buf := make([]byte, metadataOffset+64*1024)
+	putGUID := func(offset int, guid windows.GUID) {
+		binary.LittleEndian.PutUint32(buf[offset:], guid.Data1)
+		binary.LittleEndian.PutUint16(buf[offset+4:], guid.Data2)
+		binary.LittleEndian.PutUint16(buf[offset+6:], guid.Data3)
+		copy(buf[offset+8:], guid.Data4[:])
+	}
+	copy(buf, "vhdxfile")
+	copy(buf[vhdxRegionTableOffset:], "regi")
+	binary.LittleEndian.PutUint32(buf[vhdxRegionTableOffset+8:], 2)
+	// The first region is something else (the BAT).
+	putGUID(vhdxRegionTableOffset+16, windows.GUID{Data1: 0x2DC27766})
+	putGUID(vhdxRegionTableOffset+48, vhdxMetadataRegion)
+	binary.LittleEndian.PutUint64(buf[vhdxRegionTableOffset+64:], metadataOffset)
+	copy(buf[metadataOffset:], "metadata")
+	binary.LittleEndian.PutUint16(buf[metadataOffset+10:], 2)
+	putGUID(metadataOffset+32, windows.GUID{Data1: 0xCAA16737})
+	putGUID(metadataOffset+64, vhdxVirtualDiskSize)
+	binary.LittleEndian.PutUint32(buf[metadataOffset+80:], 0x10000)
+	binary.LittleEndian.PutUint64(buf[metadataOffset+0x10000:], virtualSize)
+	return buf
+}
+
+func TestGetDiskUsage(t *testing.T) {
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, t.Name(), os.Getpid())
+	lxssKey, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{1}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	defer lxssKey.Close()
+	basePath := t.TempDir()
+	key, _, err := registry.CreateKey(lxssKey, "{1}", registry.ALL_ACCESS)
+	require.NoError(t, err)
+	assert.NoError(t, key.SetStringValue("DistributionName", "rancher-desktop-data"))
+	assert.NoError(t, key.SetStringValue("BasePath", `\\?\`+basePath))
+	assert.NoError(t, key.Close())
+	contents := makeVHDX(1 << 40)
+	require.NoError(t, os.WriteFile(filepath.Join(basePath, vhdxDefaultFileName), contents, 0o644))
+
+	ctx := context.WithValue(context.Background(), &kLxssKeyOverride, keyPath)
+	assert.Equal(t, DiskUsage{
+		Distro:      "rancher-desktop-data",
+		Path:        filepath.Join(basePath, vhdxDefaultFileName),
+		VirtualSize: 1 << 40,
+		FileSize:    uint64(len(contents)),
+	}, getDiskUsage(ctx, "rancher-desktop-data"))
+
+	usage := getDiskUsage(ctx, "missing")
+	assert.NotEmpty(t, usage.Error)
+
+	require.NoError(t, os.WriteFile(filepath.Join(basePath, vhdxDefaultFileName), []byte("not a disk"), 0o644))
+	usage = getDiskUsage(ctx, "rancher-desktop-data")
+	assert.Equal(t, uint64(10), usage.FileSize)
+	assert.NotEmpty(t, usage.Error)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// kVMRunningOverride overrides the check for whether the WSL VM is running;
+// the value is a func() (bool, error).
+var kVMRunningOverride = struct{}{}
+
+// WatchVM polls whether the WSL2 utility VM is running, calling the handler
+// with the current state at the start and whenever it changes (for example,
+// after `wsl --shutdown`).  This blocks until the context is cancelled.
+func WatchVM(ctx context.Context, log *logrus.Entry, interval time.Duration, handler func(running bool)) error {
+	isRunning := func() (bool, error) {
+		pid, _, err := findVMProcess()
+		return pid != 0, err
+	}
+	if v := ctx.Value(&kVMRunningOverride); v != nil {
+		isRunning = v.(func() (bool, error))
+	}
+	first := true
+	var known bool
+	for {
+		running, err := isRunning()
+		if err != nil {
+			log.WithError(err).Debug("failed to check if the WSL VM is running")
+		} else if first || running != known {
+			first, known = false, running
+			handler(running)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
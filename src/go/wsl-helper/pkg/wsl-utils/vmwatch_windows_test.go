@@ -0,0 +1,65 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchVM(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	var running atomic.Bool
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), &kVMRunningOverride, func() (bool, error) {
+		return running.Load(), nil
+	}))
+	defer cancel()
+
+	states := make(chan bool, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchVM(ctx, logrus.NewEntry(logger), 10*time.Millisecond, func(state bool) {
+			states <- state
+		})
+	}()
+	next := func() bool {
+		select {
+		case state := <-states:
+			return state
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "timed out waiting for VM state")
+			return false
+		}
+	}
+
+	assert.False(t, next(), "initial state should be reported")
+	running.Store(true)
+	assert.True(t, next())
+	running.Store(false)
+	assert.False(t, next())
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, states, "unchanged state should not be reported")
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// watchSettleDelay is how long to wait after a registry change before
+	// listing distributions, as WSL makes several changes when registering.
+	watchSettleDelay = 500 * time.Millisecond
+	// watchPollInterval is how often the watcher checks for cancellation while
+	// waiting for registry changes.
+	watchPollInterval = time.Second
+)
+
+// DistroEventType describes a change to the registered distributions.
+type DistroEventType string
+
+const (
+	// DistroRegistered is emitted when a distribution is registered (and has
+	// finished installing).
+	DistroRegistered DistroEventType = "registered"
+	// DistroUnregistered is emitted when a distribution is unregistered.
+	DistroUnregistered DistroEventType = "unregistered"
+)
+
+// DistroEvent is a change to the registered distributions.
+type DistroEvent struct {
+	Type   DistroEventType `json:"type"`   // What happened.
+	Distro DistroInfo      `json:"distro"` // The distribution affected.
+}
+
+// WatchDistros watches the WSL registry key for distribution registration
+// changes, calling the handler for each distribution that is registered or
+// unregistered.  Distributions registered at the start are reported as
+// registered.  This blocks until the context is cancelled.
+func WatchDistros(ctx context.Context, log *logrus.Entry, handler func(DistroEvent)) error {
+	keyPath := lxssKeyPath
+	if v := ctx.Value(&kLxssKeyOverride); v != nil {
+		keyPath = v.(string)
+	}
+	// Create the key if needed, so that we can watch it even if WSL has never
+	// registered a distribution.
+	key, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.NOTIFY|registry.READ)
+	if err != nil {
+		return fmt.Errorf("failed to open WSL registry key: %w", err)
+	}
+	defer key.Close()
+	event, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create event: %w", err)
+	}
+	defer func() { _ = windows.CloseHandle(event) }()
+
+	known := make(map[string]DistroInfo)
+	update := func() {
+		distros, err := ListDistros(ctx, log)
+		if err != nil {
+			log.WithError(err).Error("failed to list WSL distributions")
+			return
+		}
+		current := make(map[string]DistroInfo, len(distros))
+		for _, distro := range distros {
+			if distro.State == DistroStateInstalling {
+				continue
+			}
+			current[distro.Name] = distro
+			if _, ok := known[distro.Name]; !ok {
+				handler(DistroEvent{Type: DistroRegistered, Distro: distro})
+			}
+		}
+		for name, distro := range known {
+			if _, ok := current[name]; !ok {
+				handler(DistroEvent{Type: DistroUnregistered, Distro: distro})
+			}
+		}
+		known = current
+	}
+
+	const filter = windows.REG_NOTIFY_CHANGE_NAME | windows.REG_NOTIFY_CHANGE_LAST_SET
+	for {
+		// Ask for notification before listing, so that no changes are missed.
+		if err := windows.RegNotifyChangeKeyValue(windows.Handle(key), true, filter, event, true); err != nil {
+			return fmt.Errorf("failed to watch WSL registry key: %w", err)
+		}
+		update()
+		for {
+			result, err := windows.WaitForSingleObject(event, uint32(watchPollInterval.Milliseconds()))
+			if err != nil {
+				return fmt.Errorf("failed waiting for WSL registry changes: %w", err)
+			}
+			if ctx.Err() != nil {
+				if errors.Is(ctx.Err(), context.Canceled) {
+					return nil
+				}
+				return ctx.Err()
+			}
+			if result == windows.WAIT_OBJECT_0 {
+				break
+			}
+		}
+		log.Trace("WSL registry key changed")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchSettleDelay):
+		}
+	}
+}
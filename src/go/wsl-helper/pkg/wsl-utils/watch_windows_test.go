@@ -0,0 +1,98 @@
+/*
+Copyright © 2024 SUSE LLC
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wslutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestWatchDistros(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	keyPath := fmt.Sprintf(`Software\RancherDesktopTest\%s-%d`, t.Name(), os.Getpid())
+	lxssKey, _, err := registry.CreateKey(registry.CURRENT_USER, keyPath, registry.ALL_ACCESS)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{1}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath+`\{2}`)
+		_ = registry.DeleteKey(registry.CURRENT_USER, keyPath)
+	})
+	defer lxssKey.Close()
+	addDistro := func(id, name string, state uint32) {
+		key, _, err := registry.CreateKey(lxssKey, id, registry.ALL_ACCESS)
+		require.NoError(t, err)
+		assert.NoError(t, key.SetStringValue("DistributionName", name))
+		assert.NoError(t, key.SetDWordValue("Version", 2))
+		assert.NoError(t, key.SetDWordValue("State", state))
+		assert.NoError(t, key.Close())
+	}
+	addDistro("{1}", "Alpine", lxssStateInstalled)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), &kLxssKeyOverride, keyPath))
+	defer cancel()
+	ctx, _ = mockRun(ctx, func(ctx context.Context, args ...string) error {
+		return nil
+	})
+	events := make(chan DistroEvent, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchDistros(ctx, logrus.NewEntry(logger), func(event DistroEvent) {
+			events <- event
+		})
+	}()
+	next := func() DistroEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for event")
+			return DistroEvent{}
+		}
+	}
+
+	event := next()
+	assert.Equal(t, DistroRegistered, event.Type)
+	assert.Equal(t, "Alpine", event.Distro.Name)
+
+	// Distributions that are still installing are only reported once done.
+	addDistro("{2}", "Ubuntu", 3)
+	time.Sleep(2 * watchSettleDelay)
+	assert.Empty(t, events)
+	addDistro("{2}", "Ubuntu", lxssStateInstalled)
+	event = next()
+	assert.Equal(t, DistroRegistered, event.Type)
+	assert.Equal(t, "Ubuntu", event.Distro.Name)
+
+	require.NoError(t, registry.DeleteKey(lxssKey, "{1}"))
+	event = next()
+	assert.Equal(t, DistroUnregistered, event.Type)
+	assert.Equal(t, "Alpine", event.Distro.Name)
+
+	cancel()
+	assert.NoError(t, <-done)
+}